@@ -0,0 +1,266 @@
+// Package auth verifies bearer tokens issued by an external identity
+// provider against its published JSON Web Key Set.
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// minRefreshInterval limits how often unknown key IDs can force a
+	// refetch, so forged kids cannot flood the identity provider
+	minRefreshInterval = 10 * time.Second
+	// maxJWKSSize caps the key set response body
+	maxJWKSSize = 1 << 20 // 1MB
+)
+
+// ErrKeyNotFound is returned when no signing key matches a token's key ID
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// KeySource resolves the public key for a token's key ID
+type KeySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// JWKSCache fetches a JSON Web Key Set and caches its keys. Keys are
+// refetched after the TTL, or earlier when a token names an unknown key ID,
+// which picks up key rotation at the identity provider. Fetches run without
+// holding the cache: stale keys are served meanwhile, and callers with no
+// usable key share the one fetch in progress.
+type JWKSCache struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	refresh     *jwksRefresh
+}
+
+// jwksRefresh is a key set fetch in progress
+type jwksRefresh struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKSCache creates a key cache for the given JWKS URL
+func NewJWKSCache(url string, ttl time.Duration, client *http.Client) *JWKSCache {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &JWKSCache{
+		url:    url,
+		ttl:    ttl,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Key returns the public key with the given key ID
+func (c *JWKSCache) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	key, found := c.keys[kid]
+	if found && c.now().Sub(c.fetchedAt) < c.ttl {
+		c.mu.Unlock()
+		return key, nil
+	}
+
+	// Refetch when stale or on an unknown kid, but not more than once per
+	// minRefreshInterval
+	if c.refresh == nil && (c.keys == nil || c.now().Sub(c.lastAttempt) >= minRefreshInterval) {
+		c.lastAttempt = c.now()
+		c.refresh = &jwksRefresh{done: make(chan struct{})}
+		// The fetch outlives the caller starting it, bounded by the client
+		// timeout, as other callers may be waiting for it
+		go c.runRefresh(context.WithoutCancel(ctx), c.refresh)
+	}
+	refresh := c.refresh
+	c.mu.Unlock()
+
+	// Keep serving the stale key while the key set is refetched, or while
+	// the provider is unreachable
+	if found {
+		return key, nil
+	}
+	if refresh == nil {
+		return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+	}
+
+	select {
+	case <-refresh.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	c.mu.Lock()
+	key, found = c.keys[kid]
+	c.mu.Unlock()
+	if found {
+		return key, nil
+	}
+	if refresh.err != nil {
+		return nil, refresh.err
+	}
+	return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+}
+
+// runRefresh fetches the key set and swaps it in
+func (c *JWKSCache) runRefresh(ctx context.Context, refresh *jwksRefresh) {
+	keys, err := c.fetch(ctx)
+
+	c.mu.Lock()
+	if err == nil {
+		c.keys = keys
+		c.fetchedAt = c.now()
+	}
+	refresh.err = err
+	c.refresh = nil
+	c.mu.Unlock()
+	close(refresh.done)
+}
+
+func (c *JWKSCache) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	return ParseJWKS(body)
+}
+
+// jsonWebKey is the subset of RFC 7517 fields needed for RSA and EC keys
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses a JSON Web Key Set into public keys by key ID. Keys
+// that are not signing keys or use unsupported types are skipped.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		var err error
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaKey()
+		case "EC":
+			key, err = jwk.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jsonWebKey) ecKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	var check ecdh.Curve
+	switch k.Crv {
+	case "P-256":
+		curve, check = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, check = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, check = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parsing the uncompressed point rejects coordinates off the curve
+	size := (curve.Params().BitSize + 7) / 8
+	if len(x.Bytes()) > size || len(y.Bytes()) > size {
+		return nil, errors.New("invalid EC coordinates")
+	}
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	x.FillBytes(point[1 : 1+size])
+	y.FillBytes(point[1+size:])
+	if _, err := check.NewPublicKey(point); err != nil {
+		return nil, fmt.Errorf("invalid EC point: %w", err)
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
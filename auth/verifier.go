@@ -0,0 +1,305 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"documents-worker/config"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is wrapped by every verification failure
+var ErrInvalidToken = errors.New("invalid token")
+
+// Principal is the verified caller of a request
+type Principal struct {
+	Subject string   `json:"sub"`
+	Issuer  string   `json:"iss"`
+	Roles   []string `json:"roles"`
+	// Permissions are the fine-grained grants of the caller, e.g. scopes
+	Permissions []string               `json:"permissions"`
+	Claims      map[string]interface{} `json:"-"`
+}
+
+// HasRole reports whether the principal holds any of the given roles
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, held := range p.Roles {
+		for _, role := range roles {
+			if held == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether the principal holds any of the given
+// permissions
+func (p *Principal) HasPermission(permissions ...string) bool {
+	for _, held := range p.Permissions {
+		for _, permission := range permissions {
+			if held == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// signingMethod describes one accepted JWS algorithm
+type signingMethod struct {
+	hash    crypto.Hash
+	isECDSA bool
+	keySize int // EC coordinate size in bytes
+}
+
+// Only asymmetric algorithms are accepted: a token claiming "none" or an
+// HMAC algorithm can never be verified with a published public key.
+var signingMethods = map[string]signingMethod{
+	"RS256": {hash: crypto.SHA256},
+	"RS384": {hash: crypto.SHA384},
+	"RS512": {hash: crypto.SHA512},
+	"ES256": {hash: crypto.SHA256, isECDSA: true, keySize: 32},
+	"ES384": {hash: crypto.SHA384, isECDSA: true, keySize: 48},
+	"ES512": {hash: crypto.SHA512, isECDSA: true, keySize: 66},
+}
+
+// Verifier checks tokens from an external identity provider: signature
+// against the provider's keys, then issuer, audience and validity window
+type Verifier struct {
+	issuer      string
+	audience    string
+	clockSkew   time.Duration
+	rolesClaim  string
+	roleMapping map[string]string
+
+	permissionsClaim  string
+	permissionMapping map[string]string
+	keys              KeySource
+	now               func() time.Time
+}
+
+// NewVerifier creates a verifier backed by a cached JWKS from the config
+func NewVerifier(cfg *config.AuthConfig) (*Verifier, error) {
+	if cfg.JWKSURL == "" {
+		return nil, errors.New("auth: JWKS URL is required")
+	}
+	if cfg.Issuer == "" {
+		return nil, errors.New("auth: issuer is required")
+	}
+	return NewVerifierWithKeys(cfg, NewJWKSCache(cfg.JWKSURL, cfg.JWKSCacheTTL, nil)), nil
+}
+
+// NewVerifierWithKeys creates a verifier that resolves keys from keys
+func NewVerifierWithKeys(cfg *config.AuthConfig, keys KeySource) *Verifier {
+	rolesClaim := cfg.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+	permissionsClaim := cfg.PermissionsClaim
+	if permissionsClaim == "" {
+		permissionsClaim = "permissions"
+	}
+	return &Verifier{
+		issuer:      cfg.Issuer,
+		audience:    cfg.Audience,
+		clockSkew:   cfg.ClockSkew,
+		rolesClaim:  rolesClaim,
+		roleMapping: cfg.RoleMapping,
+
+		permissionsClaim:  permissionsClaim,
+		permissionMapping: cfg.PermissionMapping,
+		keys:              keys,
+		now:               time.Now,
+	}
+}
+
+// Verify validates a compact JWS token and returns its principal
+func (v *Verifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: bad header", ErrInvalidToken)
+	}
+
+	method, ok := signingMethods[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	key, err := v.keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad signature encoding", ErrInvalidToken)
+	}
+	if err := verifySignature(method, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: bad claims", ErrInvalidToken)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, _ := claims["sub"].(string)
+	return &Principal{
+		Subject:     subject,
+		Issuer:      v.issuer,
+		Roles:       v.mapRoles(claims),
+		Permissions: v.mapPermissions(claims),
+		Claims:      claims,
+	}, nil
+}
+
+// verifySignature checks that the key type matches the algorithm, so an
+// RSA key can never verify an ES token and vice versa
+func verifySignature(method signingMethod, key crypto.PublicKey, signed string, signature []byte) error {
+	hasher := method.hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	if method.isECDSA {
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || (ecKey.Curve.Params().BitSize+7)/8 != method.keySize {
+			return errors.New("key does not match algorithm")
+		}
+		if len(signature) != 2*method.keySize {
+			return errors.New("bad signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:method.keySize])
+		s := new(big.Int).SetBytes(signature[method.keySize:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("key does not match algorithm")
+	}
+	if err := rsa.VerifyPKCS1v15(rsaKey, method.hash, digest, signature); err != nil {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (v *Verifier) checkClaims(claims map[string]interface{}) error {
+	now := v.now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("missing exp")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(v.clockSkew)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not yet valid")
+	}
+
+	if iss, _ := claims["iss"].(string); iss != v.issuer {
+		return fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if v.audience != "" && !containsAudience(claims["aud"], v.audience) {
+		return errors.New("unexpected audience")
+	}
+	return nil
+}
+
+// containsAudience accepts aud as a single string or a list of strings
+func containsAudience(aud interface{}, audience string) bool {
+	switch value := aud.(type) {
+	case string:
+		return value == audience
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mapRoles reads the roles claim and translates it through the role mapping
+func (v *Verifier) mapRoles(claims map[string]interface{}) []string {
+	return mapClaim(claims, v.rolesClaim, v.roleMapping)
+}
+
+// mapPermissions reads the permissions claim and translates it through the
+// permission mapping
+func (v *Verifier) mapPermissions(claims map[string]interface{}) []string {
+	return mapClaim(claims, v.permissionsClaim, v.permissionMapping)
+}
+
+// mapClaim reads a list claim (a dotted path into nested objects, holding
+// either a list or a space-separated string) and translates its values
+// through mapping; when mapping is set, unmapped values are dropped
+func mapClaim(claims map[string]interface{}, path string, mapping map[string]string) []string {
+	var value interface{} = claims
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+
+	var external []string
+	switch values := value.(type) {
+	case string:
+		external = strings.Fields(values)
+	case []interface{}:
+		for _, item := range values {
+			if s, ok := item.(string); ok {
+				external = append(external, s)
+			}
+		}
+	}
+
+	if len(mapping) == 0 {
+		return external
+	}
+
+	var mapped []string
+	seen := make(map[string]bool)
+	for _, item := range external {
+		if internal, ok := mapping[item]; ok && !seen[internal] {
+			seen[internal] = true
+			mapped = append(mapped, internal)
+		}
+	}
+	return mapped
+}
+
+func decodeSegment(segment string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
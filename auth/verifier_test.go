@@ -0,0 +1,372 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"documents-worker/config"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIdP serves a JWKS and signs tokens with the matching private keys
+type mockIdP struct {
+	mu      sync.Mutex
+	rsaKeys map[string]*rsa.PrivateKey
+	ecKeys  map[string]*ecdsa.PrivateKey
+	fetches int32
+	server  *httptest.Server
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	idp := &mockIdP{
+		rsaKeys: make(map[string]*rsa.PrivateKey),
+		ecKeys:  make(map[string]*ecdsa.PrivateKey),
+	}
+	idp.addRSAKey(t, "rsa-1")
+	idp.addECKey(t, "ec-1")
+
+	idp.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&idp.fetches, 1)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(idp.jwks())
+	}))
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) addRSAKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp.mu.Lock()
+	idp.rsaKeys[kid] = key
+	idp.mu.Unlock()
+}
+
+func (idp *mockIdP) addECKey(t *testing.T, kid string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	idp.mu.Lock()
+	idp.ecKeys[kid] = key
+	idp.mu.Unlock()
+}
+
+func (idp *mockIdP) jwks() map[string]interface{} {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	var keys []map[string]string
+	for kid, key := range idp.rsaKeys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+			"n": b64(key.N.Bytes()),
+			"e": b64(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	for kid, key := range idp.ecKeys {
+		x, y := make([]byte, 32), make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		keys = append(keys, map[string]string{
+			"kty": "EC", "kid": kid, "use": "sig", "crv": "P-256",
+			"x": b64(x), "y": b64(y),
+		})
+	}
+	// Encryption keys must be ignored
+	keys = append(keys, map[string]string{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"})
+	return map[string]interface{}{"keys": keys}
+}
+
+func (idp *mockIdP) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		sig, err := rsa.SignPKCS1v15(rand.Reader, idp.rsaKeys[kid], crypto.SHA256, digest[:])
+		require.NoError(t, err)
+		signature = sig
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, idp.ecKeys[kid], digest[:])
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	default:
+		signature = []byte("unsigned")
+	}
+	return signed + "." + b64(signature)
+}
+
+func testAuthConfig(url string) *config.AuthConfig {
+	return &config.AuthConfig{
+		Enabled:      true,
+		Issuer:       "https://idp.example.com/",
+		Audience:     "documents-worker",
+		JWKSURL:      url,
+		JWKSCacheTTL: time.Hour,
+		ClockSkew:    time.Minute,
+		RolesClaim:   "realm_access.roles",
+		RoleMapping:  map[string]string{"doc-admins": "admin", "doc-readers": "reader"},
+
+		PermissionsClaim:  "scope",
+		PermissionMapping: map[string]string{"documents:write": "convert", "documents:read": "download"},
+	}
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":          "https://idp.example.com/",
+		"aud":          []string{"account", "documents-worker"},
+		"sub":          "user-42",
+		"exp":          time.Now().Add(time.Hour).Unix(),
+		"realm_access": map[string]interface{}{"roles": []string{"doc-admins", "offline_access"}},
+		"scope":        "openid documents:write",
+	}
+}
+
+func TestVerifierAcceptsSupportedAlgorithms(t *testing.T) {
+	idp := newMockIdP(t)
+	verifier, err := NewVerifier(testAuthConfig(idp.server.URL))
+	require.NoError(t, err)
+
+	for _, tc := range []struct{ alg, kid string }{{"RS256", "rsa-1"}, {"ES256", "ec-1"}} {
+		t.Run(tc.alg, func(t *testing.T) {
+			principal, err := verifier.Verify(context.Background(), idp.sign(t, tc.alg, tc.kid, validClaims()))
+			require.NoError(t, err)
+			assert.Equal(t, "user-42", principal.Subject)
+			assert.Equal(t, []string{"admin"}, principal.Roles, "unmapped roles are dropped")
+			assert.True(t, principal.HasRole("admin"))
+			assert.False(t, principal.HasRole("reader"))
+			assert.Equal(t, []string{"convert"}, principal.Permissions, "unmapped permissions are dropped")
+			assert.True(t, principal.HasPermission("convert"))
+			assert.False(t, principal.HasPermission("download"))
+		})
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&idp.fetches), "keys are cached")
+}
+
+func TestVerifierRejectsInvalidTokens(t *testing.T) {
+	idp := newMockIdP(t)
+	verifier, err := NewVerifier(testAuthConfig(idp.server.URL))
+	require.NoError(t, err)
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tamperedParts := strings.Split(idp.sign(t, "RS256", "rsa-1", validClaims()), ".")
+	other, _ := json.Marshal(with("sub", "admin"))
+	tampered := tamperedParts[0] + "." + base64.RawURLEncoding.EncodeToString(other) + "." + tamperedParts[2]
+
+	unknownHeader, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "missing"})
+	unknownKid := base64.RawURLEncoding.EncodeToString(unknownHeader) + "." + tamperedParts[1] + "." + tamperedParts[2]
+
+	tests := map[string]string{
+		"expired":        idp.sign(t, "RS256", "rsa-1", with("exp", time.Now().Add(-time.Hour).Unix())),
+		"missing exp":    idp.sign(t, "RS256", "rsa-1", with("exp", nil)),
+		"not yet valid":  idp.sign(t, "RS256", "rsa-1", with("nbf", time.Now().Add(time.Hour).Unix())),
+		"wrong issuer":   idp.sign(t, "RS256", "rsa-1", with("iss", "https://evil.example.com/")),
+		"wrong audience": idp.sign(t, "RS256", "rsa-1", with("aud", "other-service")),
+		"alg none":       idp.sign(t, "none", "rsa-1", validClaims()),
+		"alg HS256":      idp.sign(t, "HS256", "rsa-1", validClaims()),
+		"unknown kid":    unknownKid,
+		"tampered":       tampered,
+		"malformed":      "not-a-token",
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := verifier.Verify(context.Background(), token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestVerifierRejectsKeyTypeMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	verifier, err := NewVerifier(testAuthConfig(idp.server.URL))
+	require.NoError(t, err)
+
+	// An ES256 signature presented with the RSA key's kid
+	token := idp.sign(t, "ES256", "ec-1", validClaims())
+	parts := strings.Split(token, ".")
+	header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": "rsa-1"})
+	token = base64.RawURLEncoding.EncodeToString(header) + "." + parts[1] + "." + parts[2]
+
+	_, err = verifier.Verify(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestJWKSCacheRefreshesOnRotation(t *testing.T) {
+	idp := newMockIdP(t)
+	cache := NewJWKSCache(idp.server.URL, time.Hour, nil)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	verifier := NewVerifierWithKeys(testAuthConfig(idp.server.URL), cache)
+
+	_, err := verifier.Verify(context.Background(), idp.sign(t, "RS256", "rsa-1", validClaims()))
+	require.NoError(t, err)
+
+	// The IdP rotates in a new key; the first token using it arrives within
+	// the refresh interval and is rejected without hammering the IdP
+	idp.addRSAKey(t, "rsa-2")
+	rotated := idp.sign(t, "RS256", "rsa-2", validClaims())
+	_, err = verifier.Verify(context.Background(), rotated)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&idp.fetches))
+
+	now = now.Add(minRefreshInterval)
+	_, err = verifier.Verify(context.Background(), rotated)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&idp.fetches))
+}
+
+func TestJWKSCacheServesStaleKeysWhenIdPIsDown(t *testing.T) {
+	idp := newMockIdP(t)
+	cache := NewJWKSCache(idp.server.URL, time.Minute, nil)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	_, err := cache.Key(context.Background(), "rsa-1")
+	require.NoError(t, err)
+
+	idp.server.Close()
+	now = now.Add(time.Hour)
+	key, err := cache.Key(context.Background(), "rsa-1")
+	require.NoError(t, err)
+	assert.NotNil(t, key)
+}
+
+// blockingJWKS serves idp's key set once release is closed, counting the
+// fetches that arrive
+func blockingJWKS(t *testing.T, idp *mockIdP, release chan struct{}) (*httptest.Server, *int32) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		json.NewEncoder(w).Encode(idp.jwks())
+	}))
+	t.Cleanup(server.Close)
+	return server, &fetches
+}
+
+func TestJWKSCacheServesStaleKeysDuringRefresh(t *testing.T) {
+	idp := newMockIdP(t)
+	release := make(chan struct{})
+	server, fetches := blockingJWKS(t, idp, release)
+	defer close(release)
+
+	cache := NewJWKSCache(server.URL, time.Minute, nil)
+	cache.keys, _ = ParseJWKS(mustJSON(t, idp.jwks()))
+	cache.fetchedAt = time.Now().Add(-time.Hour)
+
+	// The refresh hangs, but the stale key is served without waiting on it
+	for i := 0; i < 3; i++ {
+		key, err := cache.Key(context.Background(), "rsa-1")
+		require.NoError(t, err)
+		assert.NotNil(t, key)
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(fetches) == 1 }, 5*time.Second, time.Millisecond)
+}
+
+func TestJWKSCacheSharesOneFetch(t *testing.T) {
+	idp := newMockIdP(t)
+	release := make(chan struct{})
+	server, fetches := blockingJWKS(t, idp, release)
+	cache := NewJWKSCache(server.URL, time.Hour, nil)
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := cache.Key(context.Background(), "ec-1")
+			assert.NoError(t, err)
+			assert.NotNil(t, key)
+		}()
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(fetches) == 1 }, 5*time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(fetches))
+
+	// A caller giving up does not wait for the fetch
+	hang := make(chan struct{})
+	defer close(hang)
+	server, _ = blockingJWKS(t, idp, hang)
+	cache = NewJWKSCache(server.URL, time.Hour, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := cache.Key(ctx, "ec-1")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func mustJSON(t *testing.T, value interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+	return data
+}
+
+func TestRolesWithoutMapping(t *testing.T) {
+	cfg := testAuthConfig("")
+	cfg.RoleMapping = nil
+	cfg.RolesClaim = "scope"
+	verifier := NewVerifierWithKeys(cfg, nil)
+
+	roles := verifier.mapRoles(map[string]interface{}{"scope": "documents:read documents:write"})
+	assert.Equal(t, []string{"documents:read", "documents:write"}, roles)
+	assert.Nil(t, verifier.mapRoles(map[string]interface{}{}))
+}
+
+func TestPermissionsWithoutMapping(t *testing.T) {
+	cfg := testAuthConfig("")
+	cfg.PermissionMapping = nil
+	cfg.PermissionsClaim = ""
+	verifier := NewVerifierWithKeys(cfg, nil)
+
+	permissions := verifier.mapPermissions(map[string]interface{}{"permissions": []interface{}{"documents:read", 7}})
+	assert.Equal(t, []string{"documents:read"}, permissions, "defaults to the permissions claim")
+	assert.Nil(t, verifier.mapPermissions(map[string]interface{}{"scope": "documents:read"}))
+}
+
+func TestNewVerifierRequiresConfig(t *testing.T) {
+	_, err := NewVerifier(&config.AuthConfig{Issuer: "https://idp.example.com/"})
+	assert.Error(t, err)
+	_, err = NewVerifier(&config.AuthConfig{JWKSURL: "https://idp.example.com/jwks"})
+	assert.Error(t, err)
+}
@@ -15,6 +15,7 @@ type FileCache struct {
 	ttl        time.Duration
 	maxSize    int64
 	cleanupAge time.Duration
+	metrics    *Metrics
 }
 
 // NewFileCache creates a new file cache instance
@@ -27,6 +28,7 @@ func NewFileCache(directory string, ttl time.Duration, maxSize int64, cleanupAge
 		ttl:        ttl,
 		maxSize:    maxSize,
 		cleanupAge: cleanupAge,
+		metrics:    NewMetrics(),
 	}
 }
 
@@ -37,7 +39,7 @@ func (fc *FileCache) GenerateCacheKey(operation string, params ...string) string
 	for _, param := range params {
 		h.Write([]byte(param))
 	}
-	return fmt.Sprintf("%x", h.Sum(nil))
+	return operationKey(operation, fmt.Sprintf("%x", h.Sum(nil)))
 }
 
 // Get retrieves a cached file
@@ -47,15 +49,19 @@ func (fc *FileCache) Get(key string) (string, bool) {
 	// Check if file exists
 	info, err := os.Stat(cachePath)
 	if err != nil {
+		fc.metrics.RecordMiss(operationFromKey(key))
 		return "", false
 	}
 
 	// Check if file is still valid (TTL)
 	if time.Since(info.ModTime()) > fc.ttl {
 		os.Remove(cachePath)
+		fc.metrics.RecordEviction(operationFromKey(key))
+		fc.metrics.RecordMiss(operationFromKey(key))
 		return "", false
 	}
 
+	fc.metrics.RecordHit(operationFromKey(key))
 	return cachePath, true
 }
 
@@ -76,8 +82,12 @@ func (fc *FileCache) Set(key string, sourcePath string) error {
 	}
 	defer cacheFile.Close()
 
-	_, err = io.Copy(cacheFile, sourceFile)
-	return err
+	if _, err := io.Copy(cacheFile, sourceFile); err != nil {
+		return err
+	}
+
+	fc.metrics.RecordSet(operationFromKey(key))
+	return nil
 }
 
 // Delete removes a file from cache
@@ -94,7 +104,9 @@ func (fc *FileCache) Clean() error {
 		}
 
 		if !info.IsDir() && time.Since(info.ModTime()) > fc.cleanupAge {
-			os.Remove(path)
+			if os.Remove(path) == nil {
+				fc.metrics.RecordEviction(operationFromKey(info.Name()))
+			}
 		}
 
 		return nil
@@ -118,6 +130,32 @@ func (fc *FileCache) Size() (int64, error) {
 	return size, err
 }
 
+// Usage returns the number of entries and bytes stored per operation
+func (fc *FileCache) Usage() (map[string]OperationStats, error) {
+	usage := make(map[string]OperationStats)
+
+	err := filepath.Walk(fc.directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			operation := operationFromKey(info.Name())
+			u := usage[operation]
+			u.Entries++
+			u.Bytes += info.Size()
+			usage[operation] = u
+		}
+		return nil
+	})
+
+	return usage, err
+}
+
+// Metrics returns the cache metrics collector
+func (fc *FileCache) Metrics() *Metrics {
+	return fc.metrics
+}
+
 // EnforceMaxSize removes oldest files if cache exceeds max size
 func (fc *FileCache) EnforceMaxSize() error {
 	currentSize, err := fc.Size()
@@ -173,6 +211,7 @@ func (fc *FileCache) EnforceMaxSize() error {
 
 		if err := os.Remove(file.path); err == nil {
 			currentSize -= file.size
+			fc.metrics.RecordEviction(operationFromKey(filepath.Base(file.path)))
 		}
 	}
 
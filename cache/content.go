@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ContentKey builds a cache key from a hash of the input content, so
+// identical inputs hit the cache whatever their file name or mtime
+func (cm *CacheManager) ContentKey(processType string, contentHash []byte, options interface{}) string {
+	hasher := sha256.New()
+	hasher.Write(contentHash)
+	hasher.Write([]byte(processType))
+	if options != nil {
+		optionsJSON, _ := json.Marshal(options)
+		hasher.Write(optionsJSON)
+	}
+	return operationKey(processType, hex.EncodeToString(hasher.Sum(nil))[:32])
+}
+
+// Store copies an output file into the cache directory and records it
+// under cacheKey. The caller keeps ownership of outputPath.
+func (cm *CacheManager) Store(cacheKey, processType, outputPath string, metadata map[string]interface{}) error {
+	if !cm.enabled {
+		return nil
+	}
+
+	cachedPath := filepath.Join(cm.cacheDir, cacheKey+filepath.Ext(outputPath))
+	if err := cm.CopyFile(outputPath, cachedPath); err != nil {
+		return fmt.Errorf("failed to copy output into cache: %w", err)
+	}
+	return cm.Set(cacheKey, "", cachedPath, processType, metadata)
+}
+
+// LiveFiles returns the entry and output files of unexpired cache entries.
+// Any other file in the cache directory is an orphan left by a crash.
+func (cm *CacheManager) LiveFiles() (map[string]bool, error) {
+	live := make(map[string]bool)
+	if !cm.enabled {
+		return live, nil
+	}
+
+	entries, err := filepath.Glob(filepath.Join(cm.cacheDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, entryPath := range entries {
+		data, err := os.ReadFile(entryPath)
+		if err != nil {
+			continue
+		}
+		var entry CacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil || now.After(entry.ExpiresAt) {
+			continue
+		}
+		live[absPath(entryPath)] = true
+		live[absPath(entry.OutputPath)] = true
+	}
+	return live, nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
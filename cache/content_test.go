@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentKey(t *testing.T) {
+	cm := NewCacheManager(t.TempDir(), time.Hour, true)
+	hash := sha256.Sum256([]byte("pdf content"))
+	other := sha256.Sum256([]byte("other content"))
+
+	key := cm.ContentKey("pdf_thumbnail", hash[:], map[string]int{"page": 1, "size": 256})
+	assert.True(t, strings.HasPrefix(key, "pdf_thumbnail_"))
+	assert.Equal(t, "pdf_thumbnail", operationFromKey(key))
+	assert.Equal(t, key, cm.ContentKey("pdf_thumbnail", hash[:], map[string]int{"page": 1, "size": 256}))
+
+	assert.NotEqual(t, key, cm.ContentKey("pdf_thumbnail", other[:], map[string]int{"page": 1, "size": 256}))
+	assert.NotEqual(t, key, cm.ContentKey("pdf_thumbnail", hash[:], map[string]int{"page": 2, "size": 256}))
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	cm := NewCacheManager(dir, time.Hour, true)
+
+	output := filepath.Join(t.TempDir(), "thumb.png")
+	require.NoError(t, os.WriteFile(output, []byte("png-bytes"), 0644))
+
+	hash := sha256.Sum256([]byte("pdf content"))
+	key := cm.ContentKey("pdf_thumbnail", hash[:], nil)
+	require.NoError(t, cm.Store(key, "pdf_thumbnail", output, map[string]interface{}{"page": 1}))
+
+	// The cached copy outlives the caller's output file
+	require.NoError(t, os.Remove(output))
+
+	entry, err := cm.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, key+".png"), entry.OutputPath)
+	data, err := os.ReadFile(entry.OutputPath)
+	require.NoError(t, err)
+	assert.Equal(t, "png-bytes", string(data))
+
+	disabled := NewCacheManager(t.TempDir(), time.Hour, false)
+	assert.NoError(t, disabled.Store(key, "pdf_thumbnail", "/nonexistent", nil))
+}
+
+func TestLiveFiles(t *testing.T) {
+	dir := t.TempDir()
+	cm := NewCacheManager(dir, time.Hour, true)
+
+	output := filepath.Join(t.TempDir(), "thumb.png")
+	require.NoError(t, os.WriteFile(output, []byte("png-bytes"), 0644))
+	key := cm.ContentKey("pdf_thumbnail", []byte("hash"), nil)
+	require.NoError(t, cm.Store(key, "pdf_thumbnail", output, nil))
+
+	orphan := filepath.Join(dir, "pdf_thumbnail_orphan.png")
+	require.NoError(t, os.WriteFile(orphan, []byte("left behind"), 0644))
+
+	live, err := cm.LiveFiles()
+	require.NoError(t, err)
+	assert.True(t, live[filepath.Join(dir, key+".json")])
+	assert.True(t, live[filepath.Join(dir, key+".png")])
+	assert.False(t, live[orphan])
+}
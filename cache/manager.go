@@ -80,13 +80,17 @@ func (m *Manager) GetCacheStats() map[string]interface{} {
 	}
 
 	size, _ := m.fileCache.Size()
+	if usage, err := m.fileCache.Usage(); err == nil {
+		m.fileCache.Metrics().SetUsage(usage)
+	}
 
 	return map[string]interface{}{
-		"enabled":   true,
-		"size":      size,
-		"directory": m.fileCache.directory,
-		"ttl":       m.fileCache.ttl.String(),
-		"max_size":  m.fileCache.maxSize,
+		"enabled":    true,
+		"size":       size,
+		"directory":  m.fileCache.directory,
+		"ttl":        m.fileCache.ttl.String(),
+		"max_size":   m.fileCache.maxSize,
+		"operations": m.fileCache.Metrics().Snapshot(),
 	}
 }
 
@@ -16,6 +16,8 @@ type CacheManager struct {
 	cacheDir string
 	ttl      time.Duration
 	enabled  bool
+	metrics  *Metrics
+	l1       *MemoryCache // optional in-process copy of entry files
 }
 
 // CacheEntry represents a cached item
@@ -40,6 +42,7 @@ func NewCacheManager(cacheDir string, ttl time.Duration, enabled bool) *CacheMan
 		cacheDir: cacheDir,
 		ttl:      ttl,
 		enabled:  enabled,
+		metrics:  NewMetrics(),
 	}
 }
 
@@ -85,7 +88,7 @@ func (cm *CacheManager) GetCacheKey(filePath string, processType string, options
 		hasher.Write(optionsJSON)
 	}
 	
-	return hex.EncodeToString(hasher.Sum(nil)), nil
+	return operationKey(processType, hex.EncodeToString(hasher.Sum(nil))), nil
 }
 
 // Get retrieves a cached result if it exists and is valid
@@ -95,35 +98,42 @@ func (cm *CacheManager) Get(cacheKey string) (*CacheEntry, error) {
 	}
 	
 	entryPath := filepath.Join(cm.cacheDir, cacheKey+".json")
+	operation := operationFromKey(cacheKey)
 	
-	// Check if entry exists
-	if _, err := os.Stat(entryPath); os.IsNotExist(err) {
+	// Read entry, from the L1 cache when it holds a copy
+	data, err := cm.readEntry(cacheKey, entryPath)
+	if os.IsNotExist(err) {
+		cm.metrics.RecordMiss(operation)
 		return nil, fmt.Errorf("cache miss")
 	}
-	
-	// Read entry
-	data, err := os.ReadFile(entryPath)
 	if err != nil {
+		cm.metrics.RecordMiss(operation)
 		return nil, err
 	}
 	
 	var entry CacheEntry
 	if err := json.Unmarshal(data, &entry); err != nil {
+		cm.metrics.RecordMiss(operation)
 		return nil, err
 	}
 	
 	// Check if expired
 	if time.Now().After(entry.ExpiresAt) {
 		cm.Delete(cacheKey)
+		cm.metrics.RecordEviction(operation)
+		cm.metrics.RecordMiss(operation)
 		return nil, fmt.Errorf("cache expired")
 	}
 	
 	// Check if output file still exists
 	if _, err := os.Stat(entry.OutputPath); os.IsNotExist(err) {
 		cm.Delete(cacheKey)
+		cm.metrics.RecordEviction(operation)
+		cm.metrics.RecordMiss(operation)
 		return nil, fmt.Errorf("cached file missing")
 	}
 	
+	cm.metrics.RecordHit(operation)
 	return &entry, nil
 }
 
@@ -158,7 +168,15 @@ func (cm *CacheManager) Set(cacheKey, inputPath, outputPath, processType string,
 		return err
 	}
 	
-	return os.WriteFile(entryPath, data, 0644)
+	if err := os.WriteFile(entryPath, data, 0644); err != nil {
+		return err
+	}
+	if cm.l1 != nil {
+		cm.l1.Set(cacheKey, data, cm.ttl)
+	}
+	
+	cm.metrics.RecordSet(operationFromKey(cacheKey))
+	return nil
 }
 
 // Delete removes a cache entry
@@ -168,6 +186,9 @@ func (cm *CacheManager) Delete(cacheKey string) error {
 	}
 	
 	entryPath := filepath.Join(cm.cacheDir, cacheKey+".json")
+	if cm.l1 != nil {
+		cm.l1.Delete(cacheKey)
+	}
 	
 	// Read entry to get output path
 	if data, err := os.ReadFile(entryPath); err == nil {
@@ -210,6 +231,7 @@ func (cm *CacheManager) CleanExpired() error {
 		if now.After(entry.ExpiresAt) {
 			if err := cm.Delete(entry.Key); err == nil {
 				cleaned++
+				cm.metrics.RecordEviction(operationFromKey(entry.Key))
 			}
 		}
 	}
@@ -244,6 +266,7 @@ func (cm *CacheManager) GetStats() map[string]interface{} {
 	now := time.Now()
 	expired := 0
 	totalSize := int64(0)
+	usage := make(map[string]OperationStats)
 	
 	for _, entryPath := range entries {
 		data, err := os.ReadFile(entryPath)
@@ -258,18 +281,48 @@ func (cm *CacheManager) GetStats() map[string]interface{} {
 		
 		totalSize += entry.FileSize
 		
+		u := usage[operationFromKey(entry.Key)]
+		u.Entries++
+		u.Bytes += entry.FileSize
+		usage[operationFromKey(entry.Key)] = u
+		
 		if now.After(entry.ExpiresAt) {
 			expired++
 		}
 	}
 	
+	cm.metrics.SetUsage(usage)
+	
 	stats["total_entries"] = len(entries)
 	stats["total_size"] = totalSize
 	stats["expired"] = expired
+	stats["operations"] = cm.metrics.Snapshot()
+	if cm.l1 != nil {
+		stats["l1"] = cm.l1.Stats()
+	}
 	
 	return stats
 }
 
+// Metrics returns the cache metrics collector
+func (cm *CacheManager) Metrics() *Metrics {
+	return cm.metrics
+}
+
+// WritePrometheus refreshes usage gauges and writes cache metrics in
+// Prometheus text exposition format. L1 lookups are reported under their
+// own operation, such as l1-lru, next to the per-operation totals.
+func (cm *CacheManager) WritePrometheus(w io.Writer) error {
+	cm.GetStats()
+	snapshot := cm.metrics.Snapshot()
+	if cm.l1 != nil {
+		for operation, stats := range cm.l1.Metrics().Snapshot() {
+			snapshot[operation] = stats
+		}
+	}
+	return writePrometheus(w, snapshot)
+}
+
 // WarmupCache pre-processes common file types for better performance
 func (cm *CacheManager) WarmupCache(commonFiles []string, processor func(string) error) error {
 	if !cm.enabled {
@@ -0,0 +1,384 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"documents-worker/config"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// EvictionPolicy selects which entry the L1 cache drops when it is full
+type EvictionPolicy string
+
+const (
+	// PolicyLRU evicts the least recently used entry
+	PolicyLRU EvictionPolicy = "lru"
+	// PolicyLFU evicts the least frequently used entry
+	PolicyLFU EvictionPolicy = "lfu"
+	// PolicyTTL ignores access patterns and evicts the entry closest to expiry
+	PolicyTTL EvictionPolicy = "ttl"
+)
+
+// MemoryCacheConfig holds L1 cache limits. Zero limits disable that bound.
+type MemoryCacheConfig struct {
+	Policy     EvictionPolicy
+	MaxEntries int
+	MaxBytes   int64
+	TTL        time.Duration
+}
+
+// MemoryCacheStats summarizes L1 cache state and effectiveness
+type MemoryCacheStats struct {
+	Policy     EvictionPolicy `json:"policy"`
+	Entries    int            `json:"entries"`
+	Bytes      int64          `json:"bytes"`
+	MaxEntries int            `json:"max_entries"`
+	MaxBytes   int64          `json:"max_bytes"`
+	Hits       int64          `json:"hits"`
+	Misses     int64          `json:"misses"`
+	Evictions  int64          `json:"evictions"`
+	HitRatio   float64        `json:"hit_ratio"`
+}
+
+// flight is the computation of a missing value, shared by every caller
+// asking for it meanwhile
+type flight struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// errComputePanicked is returned to callers sharing a computation that
+// panicked
+var errComputePanicked = errors.New("cache value computation panicked")
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+	hits      int64
+	element   *list.Element
+}
+
+// MemoryCache is a bounded in-process cache with a configurable eviction policy
+type MemoryCache struct {
+	mu      sync.Mutex
+	config  MemoryCacheConfig
+	entries map[string]*memoryEntry
+	order   *list.List // front = most recently used (LRU) or inserted
+	bytes   int64
+	metrics *Metrics
+	now     func() time.Time
+
+	// flights are the computations in progress; flightMu is taken before mu
+	flightMu sync.Mutex
+	flights  map[string]*flight
+}
+
+// NewMemoryCache creates a new L1 cache
+func NewMemoryCache(cfg MemoryCacheConfig) (*MemoryCache, error) {
+	if cfg.Policy == "" {
+		cfg.Policy = PolicyLRU
+	}
+
+	switch cfg.Policy {
+	case PolicyLRU, PolicyLFU, PolicyTTL:
+	default:
+		return nil, fmt.Errorf("unsupported eviction policy: %s", cfg.Policy)
+	}
+
+	if cfg.Policy == PolicyTTL && cfg.TTL <= 0 {
+		return nil, fmt.Errorf("ttl eviction policy requires a positive TTL")
+	}
+
+	return &MemoryCache{
+		config:  cfg,
+		entries: make(map[string]*memoryEntry),
+		order:   list.New(),
+		metrics: NewMetrics(),
+		now:     time.Now,
+		flights: make(map[string]*flight),
+	}, nil
+}
+
+// NewMemoryCacheFromConfig creates an L1 cache from the service configuration
+func NewMemoryCacheFromConfig(cfg *config.CacheConfig) (*MemoryCache, error) {
+	return NewMemoryCache(MemoryCacheConfig{
+		Policy:     EvictionPolicy(cfg.L1Policy),
+		MaxEntries: cfg.L1MaxEntries,
+		MaxBytes:   cfg.L1MaxBytes,
+		TTL:        cfg.L1TTL,
+	})
+}
+
+// label is the metrics operation label for this cache's policy
+func (mc *MemoryCache) label() string {
+	return "l1-" + string(mc.config.Policy)
+}
+
+// Get returns a cached value
+func (mc *MemoryCache) Get(key string) ([]byte, bool) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	entry, ok := mc.entries[key]
+	if !ok {
+		mc.metrics.RecordMiss(mc.label())
+		return nil, false
+	}
+
+	if mc.expired(entry) {
+		mc.remove(entry)
+		mc.metrics.RecordEviction(mc.label())
+		mc.metrics.RecordMiss(mc.label())
+		return nil, false
+	}
+
+	entry.hits++
+	if mc.config.Policy == PolicyLRU {
+		mc.order.MoveToFront(entry.element)
+	}
+
+	mc.metrics.RecordHit(mc.label())
+	return entry.value, true
+}
+
+// Set stores a value. A zero ttl uses the configured default.
+// Values larger than MaxBytes are not cached.
+func (mc *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if existing, ok := mc.entries[key]; ok {
+		mc.remove(existing)
+	}
+
+	size := int64(len(value))
+	if mc.config.MaxBytes > 0 && size > mc.config.MaxBytes {
+		return
+	}
+
+	if ttl <= 0 {
+		ttl = mc.config.TTL
+	}
+
+	entry := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expiresAt = mc.now().Add(ttl)
+	}
+	entry.element = mc.order.PushFront(entry)
+	mc.entries[key] = entry
+	mc.bytes += size
+
+	mc.metrics.RecordSet(mc.label())
+	mc.enforceLimits(entry)
+}
+
+// Delete removes a value
+func (mc *MemoryCache) Delete(key string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if entry, ok := mc.entries[key]; ok {
+		mc.remove(entry)
+	}
+}
+
+// GetOrCompute returns the cached value of key, computing and storing it
+// with fn on a miss. Concurrent callers missing the same key share a single
+// call of fn and all get its result or error; errors are not cached. A
+// caller whose ctx is done stops waiting, but fn is not interrupted. The
+// returned value is shared and must not be modified.
+func (mc *MemoryCache) GetOrCompute(ctx context.Context, key string, ttl time.Duration, fn func() ([]byte, error)) ([]byte, error) {
+	mc.flightMu.Lock()
+	if f, ok := mc.flights[key]; ok {
+		mc.flightMu.Unlock()
+		select {
+		case <-f.done:
+			return f.value, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if value, ok := mc.Get(key); ok {
+		mc.flightMu.Unlock()
+		return value, nil
+	}
+	f := &flight{done: make(chan struct{})}
+	mc.flights[key] = f
+	mc.flightMu.Unlock()
+
+	// The value is stored before the flight ends, so later callers find one
+	// or the other
+	completed := false
+	defer func() {
+		if !completed {
+			f.err = errComputePanicked
+		}
+		mc.flightMu.Lock()
+		delete(mc.flights, key)
+		mc.flightMu.Unlock()
+		close(f.done)
+	}()
+
+	f.value, f.err = fn()
+	completed = true
+	if f.err == nil {
+		mc.Set(key, f.value, ttl)
+	}
+	return f.value, f.err
+}
+
+// Len returns the number of cached entries
+func (mc *MemoryCache) Len() int {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return len(mc.entries)
+}
+
+// Stats returns L1 cache statistics
+func (mc *MemoryCache) Stats() MemoryCacheStats {
+	mc.mu.Lock()
+	entries, bytes := len(mc.entries), mc.bytes
+	mc.mu.Unlock()
+
+	ops := mc.metrics.Snapshot()[mc.label()]
+
+	return MemoryCacheStats{
+		Policy:     mc.config.Policy,
+		Entries:    entries,
+		Bytes:      bytes,
+		MaxEntries: mc.config.MaxEntries,
+		MaxBytes:   mc.config.MaxBytes,
+		Hits:       ops.Hits,
+		Misses:     ops.Misses,
+		Evictions:  ops.Evictions,
+		HitRatio:   ops.HitRatio,
+	}
+}
+
+// Metrics returns the cache metrics collector, labeled by policy
+func (mc *MemoryCache) Metrics() *Metrics {
+	mc.mu.Lock()
+	mc.metrics.SetUsage(map[string]OperationStats{
+		mc.label(): {Entries: int64(len(mc.entries)), Bytes: mc.bytes},
+	})
+	mc.mu.Unlock()
+	return mc.metrics
+}
+
+// expired reports whether an entry has passed its TTL
+func (mc *MemoryCache) expired(entry *memoryEntry) bool {
+	return !entry.expiresAt.IsZero() && mc.now().After(entry.expiresAt)
+}
+
+// remove deletes an entry. Callers must hold mc.mu.
+func (mc *MemoryCache) remove(entry *memoryEntry) {
+	mc.order.Remove(entry.element)
+	delete(mc.entries, entry.key)
+	mc.bytes -= int64(len(entry.value))
+}
+
+// overLimit reports whether the cache exceeds its bounds
+func (mc *MemoryCache) overLimit() bool {
+	if mc.config.MaxEntries > 0 && len(mc.entries) > mc.config.MaxEntries {
+		return true
+	}
+	return mc.config.MaxBytes > 0 && mc.bytes > mc.config.MaxBytes
+}
+
+// enforceLimits drops expired entries first, then evicts by policy until
+// the cache fits its bounds. The entry being inserted is never evicted.
+// Callers must hold mc.mu.
+func (mc *MemoryCache) enforceLimits(keep *memoryEntry) {
+	if !mc.overLimit() {
+		return
+	}
+
+	for e := mc.order.Back(); e != nil; {
+		prev := e.Prev()
+		if entry := e.Value.(*memoryEntry); mc.expired(entry) {
+			mc.remove(entry)
+			mc.metrics.RecordEviction(mc.label())
+		}
+		e = prev
+	}
+
+	for mc.overLimit() {
+		victim := mc.victim(keep)
+		if victim == nil {
+			return
+		}
+		mc.remove(victim)
+		mc.metrics.RecordEviction(mc.label())
+	}
+}
+
+// victim selects the next entry to evict according to the policy,
+// skipping keep
+func (mc *MemoryCache) victim(keep *memoryEntry) *memoryEntry {
+	var victim *memoryEntry
+
+	// Walk from the back so ties go to the oldest entry
+	for e := mc.order.Back(); e != nil; e = e.Prev() {
+		entry := e.Value.(*memoryEntry)
+		if entry == keep {
+			continue
+		}
+		if victim == nil {
+			victim = entry
+			if mc.config.Policy == PolicyLRU {
+				break
+			}
+			continue
+		}
+
+		switch mc.config.Policy {
+		case PolicyLFU:
+			if entry.hits < victim.hits {
+				victim = entry
+			}
+		case PolicyTTL:
+			if entry.expiresAt.Before(victim.expiresAt) {
+				victim = entry
+			}
+		}
+	}
+
+	return victim
+}
+
+// WithL1 puts an in-process cache in front of the manager's entry files, so
+// repeated lookups skip the disk. Entries stay authoritative on disk; expiry
+// and missing outputs are still checked on every Get.
+func (cm *CacheManager) WithL1(l1 *MemoryCache) *CacheManager {
+	cm.l1 = l1
+	return cm
+}
+
+// L1 returns the in-process cache, nil when none is configured
+func (cm *CacheManager) L1() *MemoryCache {
+	return cm.l1
+}
+
+// readEntry returns the raw entry, filling the L1 cache on a disk read
+func (cm *CacheManager) readEntry(cacheKey, entryPath string) ([]byte, error) {
+	if cm.l1 != nil {
+		if data, ok := cm.l1.Get(cacheKey); ok {
+			return data, nil
+		}
+	}
+
+	data, err := os.ReadFile(entryPath)
+	if err != nil {
+		return nil, err
+	}
+	if cm.l1 != nil {
+		cm.l1.Set(cacheKey, data, 0)
+	}
+	return data, nil
+}
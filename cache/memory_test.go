@@ -0,0 +1,363 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"documents-worker/config"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMemoryCache(t *testing.T, cfg MemoryCacheConfig) (*MemoryCache, *time.Time) {
+	mc, err := NewMemoryCache(cfg)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mc.now = func() time.Time { return now }
+	return mc, &now
+}
+
+func TestMemoryCacheLRU(t *testing.T) {
+	mc, _ := newTestMemoryCache(t, MemoryCacheConfig{Policy: PolicyLRU, MaxEntries: 2})
+
+	mc.Set("a", []byte("1"), 0)
+	mc.Set("b", []byte("2"), 0)
+
+	// Touch a so b becomes least recently used
+	_, ok := mc.Get("a")
+	require.True(t, ok)
+
+	mc.Set("c", []byte("3"), 0)
+
+	_, ok = mc.Get("b")
+	assert.False(t, ok, "least recently used entry should be evicted")
+	_, ok = mc.Get("a")
+	assert.True(t, ok)
+	_, ok = mc.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), mc.Stats().Evictions)
+}
+
+func TestMemoryCacheLFU(t *testing.T) {
+	mc, _ := newTestMemoryCache(t, MemoryCacheConfig{Policy: PolicyLFU, MaxEntries: 2})
+
+	mc.Set("a", []byte("1"), 0)
+	mc.Set("b", []byte("2"), 0)
+
+	// a is used more often but b is used more recently
+	mc.Get("a")
+	mc.Get("a")
+	mc.Get("b")
+
+	mc.Set("c", []byte("3"), 0)
+
+	_, ok := mc.Get("b")
+	assert.False(t, ok, "least frequently used entry should be evicted")
+	_, ok = mc.Get("a")
+	assert.True(t, ok)
+	_, ok = mc.Get("c")
+	assert.True(t, ok, "newly inserted entry must survive its own insertion")
+}
+
+func TestMemoryCacheTTLPolicy(t *testing.T) {
+	mc, now := newTestMemoryCache(t, MemoryCacheConfig{Policy: PolicyTTL, MaxEntries: 2, TTL: time.Minute})
+
+	mc.Set("long", []byte("1"), time.Hour)
+	mc.Set("short", []byte("2"), 10*time.Second)
+
+	// Access patterns are ignored by the TTL policy
+	mc.Get("short")
+	mc.Get("short")
+
+	mc.Set("c", []byte("3"), 0)
+
+	_, ok := mc.Get("short")
+	assert.False(t, ok, "entry closest to expiry should be evicted")
+	_, ok = mc.Get("long")
+	assert.True(t, ok)
+
+	*now = now.Add(2 * time.Minute)
+	_, ok = mc.Get("c")
+	assert.False(t, ok, "entry should expire after the default TTL")
+	_, ok = mc.Get("long")
+	assert.True(t, ok)
+}
+
+func TestMemoryCacheMaxBytes(t *testing.T) {
+	mc, _ := newTestMemoryCache(t, MemoryCacheConfig{Policy: PolicyLRU, MaxBytes: 10})
+
+	mc.Set("a", []byte("12345"), 0)
+	mc.Set("b", []byte("12345"), 0)
+	mc.Set("c", []byte("123"), 0)
+
+	assert.Equal(t, 2, mc.Len())
+	_, ok := mc.Get("a")
+	assert.False(t, ok)
+
+	// Values larger than the whole cache are not stored
+	mc.Set("huge", make([]byte, 11), 0)
+	_, ok = mc.Get("huge")
+	assert.False(t, ok)
+
+	stats := mc.Stats()
+	assert.Equal(t, int64(8), stats.Bytes)
+	assert.Equal(t, PolicyLRU, stats.Policy)
+}
+
+func TestMemoryCacheExpiredEntriesEvictedFirst(t *testing.T) {
+	mc, now := newTestMemoryCache(t, MemoryCacheConfig{Policy: PolicyLFU, MaxEntries: 2})
+
+	mc.Set("stale", []byte("1"), time.Second)
+	mc.Set("hot", []byte("2"), time.Hour)
+	mc.Get("stale")
+	mc.Get("stale")
+
+	*now = now.Add(time.Minute)
+	mc.Set("new", []byte("3"), 0)
+
+	_, ok := mc.Get("hot")
+	assert.True(t, ok, "expired entries are purged before policy eviction")
+	assert.Equal(t, 2, mc.Len())
+}
+
+func TestMemoryCacheStatsAndMetrics(t *testing.T) {
+	mc, _ := newTestMemoryCache(t, MemoryCacheConfig{Policy: PolicyLFU})
+
+	mc.Set("a", []byte("abc"), 0)
+	mc.Get("a")
+	mc.Get("missing")
+
+	stats := mc.Stats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, 0.5, stats.HitRatio)
+
+	snapshot := mc.Metrics().Snapshot()
+	assert.Equal(t, int64(1), snapshot["l1-lfu"].Entries)
+	assert.Equal(t, int64(3), snapshot["l1-lfu"].Bytes)
+}
+
+func TestNewMemoryCacheValidation(t *testing.T) {
+	_, err := NewMemoryCache(MemoryCacheConfig{Policy: "fifo"})
+	assert.Error(t, err)
+
+	_, err = NewMemoryCache(MemoryCacheConfig{Policy: PolicyTTL})
+	assert.Error(t, err)
+
+	mc, err := NewMemoryCacheFromConfig(&config.CacheConfig{
+		L1Policy:     "",
+		L1MaxEntries: 10,
+		L1MaxBytes:   1024,
+		L1TTL:        time.Minute,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, PolicyLRU, mc.Stats().Policy)
+	assert.Equal(t, 10, mc.Stats().MaxEntries)
+}
+
+func TestCacheManagerWithL1(t *testing.T) {
+	dir := t.TempDir()
+	l1, err := NewMemoryCache(MemoryCacheConfig{MaxEntries: 10})
+	require.NoError(t, err)
+	cm := NewCacheManager(dir, time.Hour, true).WithL1(l1)
+
+	output := filepath.Join(dir, "output.txt")
+	require.NoError(t, os.WriteFile(output, []byte("output"), 0644))
+
+	key := operationKey("ocr", "abc123")
+	require.NoError(t, cm.Set(key, output, output, "ocr", nil))
+	assert.Equal(t, 1, l1.Len())
+
+	// The entry file is no longer read once the L1 holds a copy
+	require.NoError(t, os.Remove(filepath.Join(dir, key+".json")))
+	entry, err := cm.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, output, entry.OutputPath)
+	assert.Equal(t, int64(1), l1.Stats().Hits)
+	assert.Equal(t, int64(1), cm.GetStats()["l1"].(MemoryCacheStats).Hits)
+
+	// Outputs removed behind the cache's back are still detected
+	require.NoError(t, os.Remove(output))
+	_, err = cm.Get(key)
+	assert.Error(t, err)
+	assert.Zero(t, l1.Len())
+
+	// Disk reads fill the L1
+	require.NoError(t, os.WriteFile(output, []byte("output"), 0644))
+	require.NoError(t, NewCacheManager(dir, time.Hour, true).Set(key, output, output, "ocr", nil))
+	_, err = cm.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, 1, l1.Len())
+
+	require.NoError(t, cm.Delete(key))
+	assert.Zero(t, l1.Len())
+}
+
+func TestCacheManagerExportsL1Metrics(t *testing.T) {
+	dir := t.TempDir()
+	l1, err := NewMemoryCache(MemoryCacheConfig{MaxEntries: 10})
+	require.NoError(t, err)
+	cm := NewCacheManager(dir, time.Hour, true).WithL1(l1)
+
+	output := filepath.Join(dir, "output.txt")
+	require.NoError(t, os.WriteFile(output, []byte("output"), 0644))
+	key := operationKey("ocr", "abc123")
+	require.NoError(t, NewCacheManager(dir, time.Hour, true).Set(key, output, output, "ocr", nil))
+
+	// A disk hit fills the L1, which serves the second lookup
+	for i := 0; i < 2; i++ {
+		_, err := cm.Get(key)
+		require.NoError(t, err)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, cm.WritePrometheus(&buf))
+	out := buf.String()
+	assert.Contains(t, out, `documents_worker_cache_hits_total{operation="ocr"} 2`)
+	assert.Contains(t, out, `documents_worker_cache_hits_total{operation="l1-lru"} 1`)
+	assert.Contains(t, out, `documents_worker_cache_misses_total{operation="l1-lru"} 1`)
+	assert.Contains(t, out, `documents_worker_cache_entries{operation="l1-lru"} 1`)
+	assert.Equal(t, 1, strings.Count(out, "# TYPE documents_worker_cache_hits_total"))
+}
+
+func TestMemoryCacheGetOrComputeRunsOnce(t *testing.T) {
+	mc, _ := newTestMemoryCache(t, MemoryCacheConfig{MaxEntries: 10})
+
+	const callers = 50
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func() ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	var started, finished sync.WaitGroup
+	results := make([][]byte, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		started.Add(1)
+		finished.Add(1)
+		go func(i int) {
+			defer finished.Done()
+			started.Done()
+			results[i], errs[i] = mc.GetOrCompute(context.Background(), "key", 0, fn)
+		}(i)
+	}
+	started.Wait()
+	// Let the callers reach the computation before it ends
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	finished.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for i := range results {
+		require.NoError(t, errs[i])
+		assert.Equal(t, []byte("value"), results[i])
+	}
+
+	value, ok := mc.Get("key")
+	require.True(t, ok)
+	assert.Equal(t, []byte("value"), value)
+
+	// Hits do not compute again
+	_, err := mc.GetOrCompute(context.Background(), "key", 0, fn)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestMemoryCacheGetOrComputeSharesErrors(t *testing.T) {
+	mc, _ := newTestMemoryCache(t, MemoryCacheConfig{MaxEntries: 10})
+
+	const callers = 10
+	failure := errors.New("conversion failed")
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fn := func() ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return nil, failure
+	}
+
+	var finished sync.WaitGroup
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		finished.Add(1)
+		go func(i int) {
+			defer finished.Done()
+			_, errs[i] = mc.GetOrCompute(context.Background(), "key", 0, fn)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	finished.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, err := range errs {
+		assert.ErrorIs(t, err, failure)
+	}
+
+	// Errors are not cached, so the next caller computes again
+	_, ok := mc.Get("key")
+	assert.False(t, ok)
+	value, err := mc.GetOrCompute(context.Background(), "key", 0, func() ([]byte, error) {
+		return []byte("value"), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+}
+
+func TestMemoryCacheGetOrComputeWaiterCanceled(t *testing.T) {
+	mc, _ := newTestMemoryCache(t, MemoryCacheConfig{MaxEntries: 10})
+
+	computing := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan []byte)
+	go func() {
+		value, _ := mc.GetOrCompute(context.Background(), "key", 0, func() ([]byte, error) {
+			close(computing)
+			<-release
+			return []byte("value"), nil
+		})
+		done <- value
+	}()
+	<-computing
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := mc.GetOrCompute(ctx, "key", 0, func() ([]byte, error) {
+		t.Error("waiter must not compute")
+		return nil, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The computation goes on for the other callers
+	close(release)
+	assert.Equal(t, []byte("value"), <-done)
+}
+
+func TestMemoryCacheGetOrComputePanic(t *testing.T) {
+	mc, _ := newTestMemoryCache(t, MemoryCacheConfig{MaxEntries: 10})
+
+	assert.Panics(t, func() {
+		mc.GetOrCompute(context.Background(), "key", 0, func() ([]byte, error) {
+			panic("boom")
+		})
+	})
+
+	// The key is not left computing
+	value, err := mc.GetOrCompute(context.Background(), "key", 0, func() ([]byte, error) {
+		return []byte("value"), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultOperation labels keys that carry no operation prefix
+const defaultOperation = "default"
+
+// OperationStats holds cache counters and usage for a single operation
+type OperationStats struct {
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	Sets      int64   `json:"sets"`
+	Evictions int64   `json:"evictions"`
+	Entries   int64   `json:"entries"`
+	Bytes     int64   `json:"bytes"`
+	HitRatio  float64 `json:"hit_ratio"`
+}
+
+// Metrics tracks cache effectiveness per operation
+type Metrics struct {
+	mu         sync.Mutex
+	operations map[string]*OperationStats
+}
+
+// NewMetrics creates an empty metrics collector
+func NewMetrics() *Metrics {
+	return &Metrics{
+		operations: make(map[string]*OperationStats),
+	}
+}
+
+// stats returns the counters for an operation, creating them if needed.
+// Callers must hold m.mu.
+func (m *Metrics) stats(operation string) *OperationStats {
+	if operation == "" {
+		operation = defaultOperation
+	}
+	s, ok := m.operations[operation]
+	if !ok {
+		s = &OperationStats{}
+		m.operations[operation] = s
+	}
+	return s
+}
+
+// RecordHit counts a cache hit
+func (m *Metrics) RecordHit(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(operation).Hits++
+}
+
+// RecordMiss counts a cache miss
+func (m *Metrics) RecordMiss(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(operation).Misses++
+}
+
+// RecordSet counts a cache write
+func (m *Metrics) RecordSet(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(operation).Sets++
+}
+
+// RecordEviction counts an entry removed by expiry or size enforcement
+func (m *Metrics) RecordEviction(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats(operation).Evictions++
+}
+
+// SetUsage replaces the entry count and byte usage gauges. Operations not
+// present in usage are reported as empty.
+func (m *Metrics) SetUsage(usage map[string]OperationStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.operations {
+		s.Entries = 0
+		s.Bytes = 0
+	}
+	for operation, u := range usage {
+		s := m.stats(operation)
+		s.Entries = u.Entries
+		s.Bytes = u.Bytes
+	}
+}
+
+// Snapshot returns a copy of the current per-operation statistics
+func (m *Metrics) Snapshot() map[string]OperationStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]OperationStats, len(m.operations))
+	for operation, s := range m.operations {
+		copied := *s
+		if lookups := s.Hits + s.Misses; lookups > 0 {
+			copied.HitRatio = float64(s.Hits) / float64(lookups)
+		}
+		snapshot[operation] = copied
+	}
+	return snapshot
+}
+
+// WritePrometheus writes the metrics in Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	return writePrometheus(w, m.Snapshot())
+}
+
+// writePrometheus writes per-operation statistics, which may come from
+// several Metrics with distinct operations
+func writePrometheus(w io.Writer, snapshot map[string]OperationStats) error {
+	operations := make([]string, 0, len(snapshot))
+	for operation := range snapshot {
+		operations = append(operations, operation)
+	}
+	sort.Strings(operations)
+
+	series := []struct {
+		name  string
+		kind  string
+		help  string
+		value func(OperationStats) float64
+	}{
+		{"documents_worker_cache_hits_total", "counter", "Cache lookups that returned an entry.", func(s OperationStats) float64 { return float64(s.Hits) }},
+		{"documents_worker_cache_misses_total", "counter", "Cache lookups that found no valid entry.", func(s OperationStats) float64 { return float64(s.Misses) }},
+		{"documents_worker_cache_sets_total", "counter", "Entries written to the cache.", func(s OperationStats) float64 { return float64(s.Sets) }},
+		{"documents_worker_cache_evictions_total", "counter", "Entries removed by expiry or size limits.", func(s OperationStats) float64 { return float64(s.Evictions) }},
+		{"documents_worker_cache_entries", "gauge", "Entries currently stored.", func(s OperationStats) float64 { return float64(s.Entries) }},
+		{"documents_worker_cache_bytes", "gauge", "Bytes currently stored.", func(s OperationStats) float64 { return float64(s.Bytes) }},
+		{"documents_worker_cache_hit_ratio", "gauge", "Hits divided by lookups.", func(s OperationStats) float64 { return s.HitRatio }},
+	}
+
+	for _, metric := range series {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind); err != nil {
+			return err
+		}
+		for _, operation := range operations {
+			if _, err := fmt.Fprintf(w, "%s{operation=%q} %g\n", metric.name, operation, metric.value(snapshot[operation])); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// operationKey prefixes a hashed key with its operation so that the
+// operation can be recovered for metrics, e.g. on eviction
+func operationKey(operation, hash string) string {
+	return sanitizeOperation(operation) + "_" + hash
+}
+
+// operationFromKey extracts the operation label from a cache key
+func operationFromKey(key string) string {
+	key = strings.TrimSuffix(key, ".json")
+	if i := strings.LastIndex(key, "_"); i > 0 {
+		return key[:i]
+	}
+	return defaultOperation
+}
+
+// sanitizeOperation makes an operation name safe for file names and labels
+func sanitizeOperation(operation string) string {
+	if operation == "" {
+		return defaultOperation
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '-'
+		}
+	}, operation)
+}
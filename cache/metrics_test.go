@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsCounters(t *testing.T) {
+	m := NewMetrics()
+
+	m.RecordHit("thumbnail")
+	m.RecordHit("thumbnail")
+	m.RecordMiss("thumbnail")
+	m.RecordSet("thumbnail")
+	m.RecordMiss("ocr")
+	m.RecordEviction("ocr")
+	m.RecordHit("")
+
+	snapshot := m.Snapshot()
+
+	require.Contains(t, snapshot, "thumbnail")
+	assert.Equal(t, int64(2), snapshot["thumbnail"].Hits)
+	assert.Equal(t, int64(1), snapshot["thumbnail"].Misses)
+	assert.Equal(t, int64(1), snapshot["thumbnail"].Sets)
+	assert.InDelta(t, 2.0/3.0, snapshot["thumbnail"].HitRatio, 0.0001)
+
+	assert.Equal(t, int64(1), snapshot["ocr"].Evictions)
+	assert.Equal(t, 0.0, snapshot["ocr"].HitRatio)
+
+	assert.Equal(t, int64(1), snapshot[defaultOperation].Hits)
+}
+
+func TestMetricsUsage(t *testing.T) {
+	m := NewMetrics()
+	m.SetUsage(map[string]OperationStats{"ocr": {Entries: 3, Bytes: 300}})
+	m.SetUsage(map[string]OperationStats{"thumbnail": {Entries: 1, Bytes: 10}})
+
+	snapshot := m.Snapshot()
+	assert.Equal(t, int64(0), snapshot["ocr"].Entries, "stale usage must be reset")
+	assert.Equal(t, int64(1), snapshot["thumbnail"].Entries)
+	assert.Equal(t, int64(10), snapshot["thumbnail"].Bytes)
+}
+
+func TestMetricsWritePrometheus(t *testing.T) {
+	m := NewMetrics()
+	m.RecordHit("pdf-thumbnail")
+	m.RecordMiss("ocr")
+
+	var buf bytes.Buffer
+	require.NoError(t, m.WritePrometheus(&buf))
+
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE documents_worker_cache_hits_total counter")
+	assert.Contains(t, out, `documents_worker_cache_hits_total{operation="pdf-thumbnail"} 1`)
+	assert.Contains(t, out, `documents_worker_cache_misses_total{operation="ocr"} 1`)
+	assert.Contains(t, out, `documents_worker_cache_hit_ratio{operation="pdf-thumbnail"} 1`)
+}
+
+func TestOperationFromKey(t *testing.T) {
+	assert.Equal(t, "image-convert", operationFromKey(operationKey("image/convert", "abc123")))
+	assert.Equal(t, "ocr_text", operationFromKey(operationKey("ocr_text", "abc123")))
+	assert.Equal(t, "ocr", operationFromKey("ocr_abc123.json"))
+	assert.Equal(t, defaultOperation, operationFromKey("health-check"))
+}
+
+func TestCacheManagerRecordsMetrics(t *testing.T) {
+	dir := t.TempDir()
+	cm := NewCacheManager(dir, time.Hour, true)
+
+	input := filepath.Join(dir, "input.txt")
+	output := filepath.Join(dir, "output.txt")
+	require.NoError(t, os.WriteFile(input, []byte("input"), 0644))
+	require.NoError(t, os.WriteFile(output, []byte("output"), 0644))
+
+	key, err := cm.GetCacheKey(input, "ocr", nil)
+	require.NoError(t, err)
+
+	_, err = cm.Get(key)
+	assert.Error(t, err)
+
+	require.NoError(t, cm.Set(key, input, output, "ocr", nil))
+
+	_, err = cm.Get(key)
+	require.NoError(t, err)
+
+	stats := cm.GetStats()
+	operations := stats["operations"].(map[string]OperationStats)
+
+	assert.Equal(t, int64(1), operations["ocr"].Hits)
+	assert.Equal(t, int64(1), operations["ocr"].Misses)
+	assert.Equal(t, int64(1), operations["ocr"].Sets)
+	assert.Equal(t, int64(1), operations["ocr"].Entries)
+	assert.Equal(t, int64(len("output")), operations["ocr"].Bytes)
+}
+
+func TestCacheManagerRecordsExpiry(t *testing.T) {
+	dir := t.TempDir()
+	cm := NewCacheManager(dir, -time.Second, true)
+
+	output := filepath.Join(dir, "output.txt")
+	require.NoError(t, os.WriteFile(output, []byte("output"), 0644))
+
+	key := operationKey("thumbnail", "deadbeef")
+	require.NoError(t, cm.Set(key, output, output, "thumbnail", nil))
+
+	_, err := cm.Get(key)
+	assert.Error(t, err)
+
+	snapshot := cm.Metrics().Snapshot()
+	assert.Equal(t, int64(1), snapshot["thumbnail"].Evictions)
+	assert.Equal(t, int64(1), snapshot["thumbnail"].Misses)
+}
+
+func TestFileCacheRecordsMetrics(t *testing.T) {
+	dir := t.TempDir()
+	fc := NewFileCache(filepath.Join(dir, "cache"), time.Hour, 1024, time.Hour)
+
+	source := filepath.Join(dir, "result.png")
+	require.NoError(t, os.WriteFile(source, []byte("png"), 0644))
+
+	key := fc.GenerateCacheKey("thumbnail", "a", "b")
+	_, ok := fc.Get(key)
+	assert.False(t, ok)
+
+	require.NoError(t, fc.Set(key, source))
+	_, ok = fc.Get(key)
+	assert.True(t, ok)
+
+	usage, err := fc.Usage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), usage["thumbnail"].Entries)
+
+	snapshot := fc.Metrics().Snapshot()
+	assert.Equal(t, int64(1), snapshot["thumbnail"].Hits)
+	assert.Equal(t, int64(1), snapshot["thumbnail"].Misses)
+	assert.Equal(t, int64(1), snapshot["thumbnail"].Sets)
+}
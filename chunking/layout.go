@@ -0,0 +1,314 @@
+package chunking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Layout defines how saved chunks are organized into directories
+type Layout string
+
+const (
+	// LayoutFlat writes every chunk into the output directory
+	LayoutFlat Layout = "flat"
+	// LayoutBatch writes ChunksPerDir chunks into each batch_NNN directory
+	LayoutBatch Layout = "batch"
+	// LayoutSource writes the chunks into a directory named after the source
+	LayoutSource Layout = "source"
+	// LayoutSection writes the chunks into a directory named after the
+	// heading they appear under
+	LayoutSection Layout = "section"
+)
+
+// DefaultNameTemplate names chunk files chunk_001, chunk_002, ...
+const DefaultNameTemplate = "chunk_{id}"
+
+// DefaultChunksPerDir is the batch size of LayoutBatch
+const DefaultChunksPerDir = 100
+
+// FormatJSONL saves every chunk as one line of JSONLFileName instead of a
+// file per chunk
+const FormatJSONL = "jsonl"
+
+// JSONLFileName is the file FormatJSONL chunks are saved to
+const JSONLFileName = "chunks.jsonl"
+
+// SaveOptions controls where SaveChunksWithOptions writes each chunk
+type SaveOptions struct {
+	Layout       Layout
+	ChunksPerDir int
+	// NameTemplate is the file name without extension. {id} is the zero
+	// padded chunk ID, {source} the source file name and {section} the
+	// heading the chunk appears under.
+	NameTemplate string
+	// Overwrite replaces existing files. Otherwise a file that already
+	// exists, e.g. from another source chunked into the same tree, is kept
+	// and the chunk is written under a numbered name next to it.
+	Overwrite bool
+}
+
+// withDefaults fills in zero values
+func (o SaveOptions) withDefaults() SaveOptions {
+	if o.Layout == "" {
+		o.Layout = LayoutFlat
+	}
+	if o.ChunksPerDir <= 0 {
+		o.ChunksPerDir = DefaultChunksPerDir
+	}
+	if o.NameTemplate == "" {
+		o.NameTemplate = DefaultNameTemplate
+	}
+	return o
+}
+
+// chunkExtensions maps output formats to chunk file extensions
+var chunkExtensions = map[string]string{
+	"":         ".txt",
+	"auto":     ".txt",
+	"txt":      ".txt",
+	"text":     ".txt",
+	"md":       ".md",
+	"markdown": ".md",
+	"json":     ".json",
+}
+
+// ChunkPaths returns the path of every chunk relative to the output
+// directory, in chunk order. Paths repeated within the result get a
+// numbered suffix.
+func ChunkPaths(result *ChunkResult, opts SaveOptions) ([]string, error) {
+	opts = opts.withDefaults()
+	if strings.EqualFold(result.Format, FormatJSONL) {
+		return nil, fmt.Errorf("jsonl chunks are saved to a single %s file", JSONLFileName)
+	}
+	ext, ok := chunkExtensions[strings.ToLower(result.Format)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported chunk output format %q (supported: txt, md, json, jsonl, auto)", result.Format)
+	}
+
+	source := slug(strings.TrimSuffix(result.Source, filepath.Ext(result.Source)))
+	paths := make([]string, len(result.Chunks))
+	seen := make(map[string]bool, len(result.Chunks))
+	for i, chunk := range result.Chunks {
+		section := slug(chunkSection(chunk))
+		name := strings.NewReplacer(
+			"{id}", fmt.Sprintf("%03d", chunk.ID),
+			"{source}", source,
+			"{section}", section,
+		).Replace(opts.NameTemplate)
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("invalid chunk name template %q", opts.NameTemplate)
+		}
+
+		var dir string
+		switch opts.Layout {
+		case LayoutFlat:
+		case LayoutBatch:
+			dir = fmt.Sprintf("batch_%03d", i/opts.ChunksPerDir+1)
+		case LayoutSource:
+			dir = source
+		case LayoutSection:
+			dir = section
+		default:
+			return nil, fmt.Errorf("unsupported chunk layout %q (supported: flat, batch, source, section)", opts.Layout)
+		}
+
+		path := filepath.Join(dir, name+ext)
+		for n := 2; seen[path]; n++ {
+			path = filepath.Join(dir, fmt.Sprintf("%s_%d%s", name, n, ext))
+		}
+		seen[path] = true
+		paths[i] = path
+	}
+	return paths, nil
+}
+
+// SaveChunksWithOptions saves chunks under outputDir using the layout and
+// naming in opts. JSON chunks are written with their metadata; JSONL chunks
+// are written to JSONLFileName in outputDir, whatever the layout.
+func (s *Service) SaveChunksWithOptions(ctx context.Context, result *ChunkResult, outputDir string, opts SaveOptions) error {
+	if strings.EqualFold(result.Format, FormatJSONL) {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		return s.SaveChunksJSONL(ctx, result, filepath.Join(outputDir, JSONLFileName), opts.Overwrite)
+	}
+
+	paths, err := ChunkPaths(result, opts)
+	if err != nil {
+		return err
+	}
+
+	for i, chunk := range result.Chunks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		data, err := EncodeChunk(chunk, paths[i])
+		if err != nil {
+			return err
+		}
+
+		filePath := filepath.Join(outputDir, paths[i])
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		if err := writeChunkFile(filePath, data, opts.Overwrite); err != nil {
+			return fmt.Errorf("failed to write chunk %d: %w", chunk.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// EncodeChunk returns the file contents for a chunk saved at path: the
+// chunk with its metadata for .json files, its content otherwise
+func EncodeChunk(chunk Chunk, path string) ([]byte, error) {
+	if filepath.Ext(path) != ".json" {
+		return []byte(chunk.Content), nil
+	}
+	data, err := json.MarshalIndent(chunk, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode chunk %d: %w", chunk.ID, err)
+	}
+	return data, nil
+}
+
+// ChunkRecord is one line of the JSONL output
+type ChunkRecord struct {
+	// Index is the position of the chunk in the output, from 0
+	Index  int    `json:"index"`
+	ID     int    `json:"id"`
+	Text   string `json:"text"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Tokens int    `json:"tokens"`
+	Size   int    `json:"size"`
+	// Source is the chunked file name, empty for inline content
+	Source   string                 `json:"source,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// EncodeJSONL returns the chunks as JSON lines, one ChunkRecord per chunk
+func EncodeJSONL(result *ChunkResult) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	for i, chunk := range result.Chunks {
+		record := ChunkRecord{
+			Index:    i,
+			ID:       chunk.ID,
+			Text:     chunk.Content,
+			Start:    chunk.Start,
+			End:      chunk.End,
+			Tokens:   chunk.Tokens,
+			Size:     chunk.Size,
+			Source:   result.Source,
+			Metadata: chunk.Metadata,
+		}
+		if err := encoder.Encode(record); err != nil {
+			return nil, fmt.Errorf("failed to encode chunk %d: %w", chunk.ID, err)
+		}
+	}
+	return buffer.Bytes(), nil
+}
+
+// SaveChunksJSONL writes the chunks as JSON lines to path. Without
+// overwrite an existing file is kept and a numbered one written next to it.
+func (s *Service) SaveChunksJSONL(ctx context.Context, result *ChunkResult, path string, overwrite bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := EncodeJSONL(result)
+	if err != nil {
+		return err
+	}
+	if err := writeChunkFile(path, data, overwrite); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// writeChunkFile writes data to path, or next to it under a numbered name
+// when path exists and must not be overwritten
+func writeChunkFile(path string, data []byte, overwrite bool) error {
+	if overwrite {
+		return os.WriteFile(path, data, 0644)
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if errors.Is(err, os.ErrExist) {
+			path = fmt.Sprintf("%s_%d%s", base, n, ext)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+}
+
+// chunkSection returns the heading recorded for the chunk, if any
+func chunkSection(chunk Chunk) string {
+	section, _ := chunk.Metadata["section"].(string)
+	return section
+}
+
+var (
+	headingPattern = regexp.MustCompile(`(?m)^#{1,6}[ \t]+(.+?)[ \t#]*$`)
+	slugPattern    = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// slug turns a file or heading name into a safe path element
+func slug(name string) string {
+	name = strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(name) > 64 {
+		name = strings.TrimRight(name[:64], "-")
+	}
+	if name == "" {
+		return "untitled"
+	}
+	return name
+}
+
+// heading is a Markdown heading and its offset in the content
+type heading struct {
+	offset int
+	title  string
+	// level is only known for headings of markdownHeadings
+	level int
+}
+
+// headings returns the Markdown headings of content in order
+func headings(content string) []heading {
+	var found []heading
+	for _, match := range headingPattern.FindAllStringSubmatchIndex(content, -1) {
+		found = append(found, heading{offset: match[0], title: content[match[2]:match[3]]})
+	}
+	return found
+}
+
+// sectionAt returns the title of the last heading at or before offset
+func sectionAt(found []heading, offset int) string {
+	section := ""
+	for _, h := range found {
+		if h.offset > offset {
+			break
+		}
+		section = h.title
+	}
+	return section
+}
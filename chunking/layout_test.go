@@ -0,0 +1,178 @@
+package chunking
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testChunkResult(source string) *ChunkResult {
+	return &ChunkResult{
+		Source: source,
+		Chunks: []Chunk{
+			{ID: 1, Content: "intro text", Metadata: map[string]interface{}{"section": "Getting Started"}},
+			{ID: 2, Content: "more intro", Metadata: map[string]interface{}{"section": "Getting Started"}},
+			{ID: 3, Content: "api text", Metadata: map[string]interface{}{"section": "API / Reference"}},
+		},
+		TotalChunks: 3,
+	}
+}
+
+func TestChunkPathsLayouts(t *testing.T) {
+	result := testChunkResult("User Guide.md")
+
+	tests := []struct {
+		name string
+		opts SaveOptions
+		want []string
+	}{
+		{"flat", SaveOptions{}, []string{"chunk_001.txt", "chunk_002.txt", "chunk_003.txt"}},
+		{"batch", SaveOptions{Layout: LayoutBatch, ChunksPerDir: 2},
+			[]string{"batch_001/chunk_001.txt", "batch_001/chunk_002.txt", "batch_002/chunk_003.txt"}},
+		{"source", SaveOptions{Layout: LayoutSource},
+			[]string{"user-guide/chunk_001.txt", "user-guide/chunk_002.txt", "user-guide/chunk_003.txt"}},
+		{"section", SaveOptions{Layout: LayoutSection},
+			[]string{"getting-started/chunk_001.txt", "getting-started/chunk_002.txt", "api-reference/chunk_003.txt"}},
+		{"template", SaveOptions{NameTemplate: "{source}-{section}-{id}"},
+			[]string{"user-guide-getting-started-001.txt", "user-guide-getting-started-002.txt", "user-guide-api-reference-003.txt"}},
+		{"repeated names", SaveOptions{NameTemplate: "{section}"},
+			[]string{"getting-started.txt", "getting-started_2.txt", "api-reference.txt"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			paths, err := ChunkPaths(result, tc.opts)
+			require.NoError(t, err)
+			for i := range tc.want {
+				tc.want[i] = filepath.FromSlash(tc.want[i])
+			}
+			assert.Equal(t, tc.want, paths)
+		})
+	}
+}
+
+func TestChunkPathsUseOutputFormat(t *testing.T) {
+	result := testChunkResult("")
+
+	result.Format = "md"
+	paths, err := ChunkPaths(result, SaveOptions{Layout: LayoutSource})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("untitled", "chunk_001.md"), paths[0])
+
+	result.Format = "pdf"
+	_, err = ChunkPaths(result, SaveOptions{})
+	assert.ErrorContains(t, err, "unsupported chunk output format")
+
+	result.Format = ""
+	_, err = ChunkPaths(result, SaveOptions{Layout: "tree"})
+	assert.ErrorContains(t, err, "unsupported chunk layout")
+	_, err = ChunkPaths(result, SaveOptions{NameTemplate: "../{id}"})
+	assert.ErrorContains(t, err, "invalid chunk name template")
+}
+
+func TestSaveChunksKeepsFilesFromOtherSources(t *testing.T) {
+	dir := t.TempDir()
+	service := NewService()
+	ctx := context.Background()
+
+	first, second := testChunkResult("a.md"), testChunkResult("b.md")
+	second.Chunks[0].Content = "from b"
+	require.NoError(t, service.SaveChunksWithOptions(ctx, first, dir, SaveOptions{}))
+	require.NoError(t, service.SaveChunksWithOptions(ctx, second, dir, SaveOptions{}))
+
+	data, err := os.ReadFile(filepath.Join(dir, "chunk_001.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "intro text", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "chunk_001_2.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "from b", string(data))
+
+	// SaveChunks keeps replacing the files of earlier runs
+	require.NoError(t, service.SaveChunks(ctx, second, dir))
+	data, err = os.ReadFile(filepath.Join(dir, "chunk_001.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "from b", string(data))
+}
+
+func TestSaveChunksWritesJSONWithMetadata(t *testing.T) {
+	dir := t.TempDir()
+	result := testChunkResult("a.md")
+	result.Format = "json"
+
+	require.NoError(t, NewService().SaveChunksWithOptions(context.Background(), result, dir, SaveOptions{Layout: LayoutSection}))
+
+	data, err := os.ReadFile(filepath.Join(dir, "api-reference", "chunk_003.json"))
+	require.NoError(t, err)
+	var chunk Chunk
+	require.NoError(t, json.Unmarshal(data, &chunk))
+	assert.Equal(t, "api text", chunk.Content)
+	assert.Equal(t, "API / Reference", chunk.Metadata["section"])
+}
+
+func TestChunkDocumentRecordsSections(t *testing.T) {
+	content := "# Install\n\nRun the installer and follow the prompts.\n\n# Usage\n\nStart the worker with the serve command."
+	result, err := NewService().ChunkDocument(context.Background(), content, TypeMarkdown,
+		ChunkConfig{Method: MethodSmart, ChunkSize: 60, Overlap: 0})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Chunks)
+
+	first, last := result.Chunks[0], result.Chunks[len(result.Chunks)-1]
+	assert.Equal(t, "Install", first.Metadata["section"])
+	assert.Equal(t, "Usage", last.Metadata["section"])
+}
+
+func TestChunkOffsetsPointIntoSource(t *testing.T) {
+	config := ChunkConfig{Method: MethodToken, ChunkSize: 16, Overlap: 3}
+	result, err := NewService().ChunkDocument(context.Background(), tokenTestText, TypeText, config)
+	require.NoError(t, err)
+	require.Greater(t, len(result.Chunks), 1)
+
+	source := []rune(tokenTestText)
+	for i, chunk := range result.Chunks {
+		require.GreaterOrEqual(t, chunk.Start, 0, "chunk %d", i)
+		assert.Equal(t, chunk.Content, string(source[chunk.Start:chunk.End]), "chunk %d", i)
+		if i > 0 {
+			assert.Less(t, chunk.Start, result.Chunks[i-1].End, "chunk %d overlaps the previous one", i)
+		}
+	}
+	assert.Equal(t, 0, result.Chunks[0].Start)
+	assert.Equal(t, len(source), result.Chunks[len(result.Chunks)-1].End)
+}
+
+func TestSaveChunksWritesJSONL(t *testing.T) {
+	dir := t.TempDir()
+	result, err := NewService().ChunkDocument(context.Background(), tokenTestText, TypeText,
+		ChunkConfig{Method: MethodToken, ChunkSize: 16, OutputFormat: FormatJSONL})
+	require.NoError(t, err)
+	result.Source = "notes.txt"
+
+	require.NoError(t, NewService().SaveChunksWithOptions(context.Background(), result, dir, SaveOptions{Layout: LayoutBatch}))
+
+	data, err := os.ReadFile(filepath.Join(dir, JSONLFileName))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	require.Len(t, lines, len(result.Chunks))
+
+	source := []rune(tokenTestText)
+	for i, line := range lines {
+		var record ChunkRecord
+		require.NoError(t, json.Unmarshal([]byte(line), &record), "line %d", i)
+		assert.Equal(t, i, record.Index)
+		assert.Equal(t, "notes.txt", record.Source)
+		assert.Equal(t, string(source[record.Start:record.End]), record.Text)
+		assert.Equal(t, "token", record.Metadata["method"])
+		if i > 0 {
+			var previous ChunkRecord
+			require.NoError(t, json.Unmarshal([]byte(lines[i-1]), &previous))
+			assert.Empty(t, strings.TrimSpace(string(source[previous.End:record.Start])), "only whitespace between chunks")
+		}
+	}
+
+	_, err = ChunkPaths(result, SaveOptions{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,212 @@
+package chunking
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/textsplitter"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// markdownParser parses CommonMark with GitHub tables
+var markdownParser = goldmark.New(goldmark.WithExtensions(extension.Table)).Parser()
+
+// markdownBlock is a heading, a top-level block or a list item of a
+// Markdown document. A block runs from the line it starts on up to the
+// start of the next block.
+type markdownBlock struct {
+	start int
+	// level is the heading level, 0 for other blocks
+	level int
+	title string
+	// atomic blocks are never split: code, tables, HTML and list items
+	atomic bool
+}
+
+// markdownBlocks returns the blocks of source in order. The first block
+// starts at 0 so the blocks cover the whole source.
+func markdownBlocks(source []byte) []markdownBlock {
+	doc := markdownParser.Parse(text.NewReader(source))
+
+	var blocks []markdownBlock
+	add := func(n ast.Node, block markdownBlock) {
+		block.start = nodeStart(n, source)
+		// Blocks without text, such as thematic breaks, stay part of the
+		// block before them
+		if block.start < 0 || (len(blocks) > 0 && block.start <= blocks[len(blocks)-1].start) {
+			return
+		}
+		blocks = append(blocks, block)
+	}
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		switch node := n.(type) {
+		case *ast.Heading:
+			add(n, markdownBlock{level: node.Level, title: headingTitle(node, source)})
+		case *ast.List:
+			for item := node.FirstChild(); item != nil; item = item.NextSibling() {
+				add(item, markdownBlock{atomic: true})
+			}
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock, *east.Table:
+			add(n, markdownBlock{atomic: true})
+		default:
+			add(n, markdownBlock{})
+		}
+	}
+	if len(blocks) > 0 {
+		blocks[0].start = 0
+	}
+	return blocks
+}
+
+// nodeStart returns the offset of the line n starts on, or -1 when n has
+// no text
+func nodeStart(n ast.Node, source []byte) int {
+	start := -1
+	ast.Walk(n, func(child ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || child.Type() != ast.TypeBlock {
+			return ast.WalkContinue, nil
+		}
+		pos := -1
+		if fenced, ok := child.(*ast.FencedCodeBlock); ok {
+			pos = fenceStart(fenced, source)
+		} else if child.Lines().Len() > 0 {
+			pos = child.Lines().At(0).Start
+		}
+		if pos >= 0 && (start < 0 || pos < start) {
+			start = pos
+		}
+		return ast.WalkContinue, nil
+	})
+	if start < 0 {
+		return -1
+	}
+	return lineStart(source, start)
+}
+
+// fenceStart returns the offset of the opening fence of a code block,
+// whose lines only hold the code
+func fenceStart(block *ast.FencedCodeBlock, source []byte) int {
+	if block.Info != nil {
+		return block.Info.Segment.Start
+	}
+	if block.Lines().Len() > 0 {
+		if first := lineStart(source, block.Lines().At(0).Start); first > 0 {
+			return lineStart(source, first-1)
+		}
+	}
+	return -1
+}
+
+// lineStart returns the offset of the line pos is on
+func lineStart(source []byte, pos int) int {
+	for pos > 0 && source[pos-1] != '\n' {
+		pos--
+	}
+	return pos
+}
+
+// headingTitle returns the text of a heading as written
+func headingTitle(h *ast.Heading, source []byte) string {
+	lines := make([]string, h.Lines().Len())
+	for i := range lines {
+		segment := h.Lines().At(i)
+		lines[i] = strings.TrimSpace(string(segment.Value(source)))
+	}
+	return strings.Join(lines, " ")
+}
+
+// markdownHeadings returns the headings of content in order. Unlike
+// headings it skips lines that only look like headings, such as comments
+// in code blocks.
+func markdownHeadings(content string) []heading {
+	var found []heading
+	for _, block := range markdownBlocks([]byte(content)) {
+		if block.level > 0 {
+			found = append(found, heading{offset: block.start, title: block.title, level: block.level})
+		}
+	}
+	return found
+}
+
+// headingPathAt returns the titles of the headings enclosing offset,
+// outermost first
+func headingPathAt(found []heading, offset int) []string {
+	var path []heading
+	for _, h := range found {
+		if h.offset > offset {
+			break
+		}
+		for len(path) > 0 && path[len(path)-1].level >= h.level {
+			path = path[:len(path)-1]
+		}
+		path = append(path, h)
+	}
+	titles := make([]string, len(path))
+	for i, h := range path {
+		titles[i] = h.title
+	}
+	return titles
+}
+
+// markdownSplitter splits Markdown into sections at its headings and packs
+// the blocks of each section into chunks of up to size characters. A
+// section holding only headings is kept with the section after it. Atomic
+// blocks are never split, even when larger than size; other blocks that are
+// larger are split by fallback.
+type markdownSplitter struct {
+	size     int
+	fallback textsplitter.TextSplitter
+}
+
+// SplitText implements textsplitter.TextSplitter
+func (s markdownSplitter) SplitText(content string) ([]string, error) {
+	blocks := markdownBlocks([]byte(content))
+
+	var chunks []string
+	// The chunk being built starts at start; body tells whether it holds
+	// more than headings
+	start, body := 0, false
+	for i, block := range blocks {
+		end := len(content)
+		if i+1 < len(blocks) {
+			end = blocks[i+1].start
+		}
+
+		if block.level > 0 {
+			if body {
+				chunks = append(chunks, content[start:block.start])
+				start, body = block.start, false
+			}
+			continue
+		}
+
+		if body && textLength(content[start:end]) > s.size {
+			chunks = append(chunks, content[start:block.start])
+			start, body = block.start, false
+		}
+		if !block.atomic && textLength(content[start:end]) > s.size {
+			pieces, err := s.fallback.SplitText(content[start:end])
+			if err != nil {
+				return nil, err
+			}
+			chunks = append(chunks, pieces...)
+			start = end
+			continue
+		}
+		body = true
+	}
+	if start < len(content) {
+		chunks = append(chunks, content[start:])
+	}
+	return chunks, nil
+}
+
+// textLength is the length of text in characters, without surrounding
+// whitespace
+func textLength(text string) int {
+	return utf8.RuneCountInString(strings.TrimSpace(text))
+}
@@ -0,0 +1,111 @@
+package chunking
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func chunkGuide(t *testing.T, size int) (string, *ChunkResult) {
+	content, err := os.ReadFile("testdata/guide.md")
+	require.NoError(t, err)
+	result, err := NewService().ChunkDocument(context.Background(), string(content), TypeMarkdown,
+		ChunkConfig{Method: MethodMarkdown, ChunkSize: size, Overlap: 10})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Chunks)
+	return string(content), result
+}
+
+// fencedBlock returns the first fenced code block of content, fences included
+func fencedBlock(t *testing.T, content string) string {
+	start := strings.Index(content, "```")
+	require.GreaterOrEqual(t, start, 0)
+	end := strings.Index(content[start+3:], "```")
+	require.GreaterOrEqual(t, end, 0)
+	return content[start : start+3+end+3]
+}
+
+func TestMarkdownChunksNeverSplitCodeBlocks(t *testing.T) {
+	content, result := chunkGuide(t, 120)
+	code := fencedBlock(t, content)
+	require.Greater(t, len(code), 120)
+
+	var holding []Chunk
+	for _, chunk := range result.Chunks {
+		if strings.Contains(chunk.Content, "```") {
+			holding = append(holding, chunk)
+		}
+	}
+	require.Len(t, holding, 1, "the code block is in a single chunk")
+	assert.Contains(t, holding[0].Content, code)
+	assert.Equal(t, []string{"Worker Guide", "Installation", "Building"}, holding[0].Metadata["heading_path"])
+	assert.Equal(t, "Building", holding[0].Metadata["section"])
+}
+
+func TestMarkdownChunksFollowHeadings(t *testing.T) {
+	content, result := chunkGuide(t, 120)
+
+	// Chunks start at or after a heading and end before the next one; the
+	// first one also holds the empty section before it
+	found := markdownHeadings(content)
+	var table, list *Chunk
+	for i, chunk := range result.Chunks {
+		for _, h := range found {
+			inside := h.offset > chunk.Start && h.offset < chunk.End
+			assert.False(t, i > 0 && inside, "chunk %d spans %q", i, h.title)
+		}
+		if strings.Contains(chunk.Content, "| tesseract") {
+			table = &result.Chunks[i]
+		}
+		if strings.Contains(chunk.Content, "- Start the server") {
+			list = &result.Chunks[i]
+		}
+	}
+
+	require.NotNil(t, table)
+	assert.Contains(t, table.Content, "| vips      | Image conversion  |", "the table is whole")
+	assert.Equal(t, []string{"Worker Guide", "Installation", "Requirements"}, table.Metadata["heading_path"])
+
+	require.NotNil(t, list)
+	assert.Equal(t, []string{"Worker Guide", "Usage"}, list.Metadata["heading_path"])
+
+	// The empty top-level section is kept with the one after it
+	assert.True(t, strings.HasPrefix(result.Chunks[0].Content, "# Worker Guide\n\n## Installation"))
+}
+
+func TestMarkdownChunksSplitLargeParagraphs(t *testing.T) {
+	content, result := chunkGuide(t, 120)
+
+	paragraph := content[strings.Index(content, "Chunking splits"):]
+	paragraph = strings.TrimSpace(paragraph)
+	var pieces []Chunk
+	for _, chunk := range result.Chunks {
+		if strings.Contains(paragraph, chunk.Content) {
+			pieces = append(pieces, chunk)
+		}
+	}
+	require.Greater(t, len(pieces), 1)
+	for _, piece := range pieces {
+		assert.LessOrEqual(t, len([]rune(piece.Content)), 120)
+		assert.Equal(t, []string{"Worker Guide", "Usage"}, piece.Metadata["heading_path"])
+	}
+
+	// Large sections fit whole in large chunks
+	_, whole := chunkGuide(t, 2000)
+	assert.Len(t, whole.Chunks, 4, "one chunk per section")
+}
+
+func TestMarkdownHeadingsSkipCodeComments(t *testing.T) {
+	content, err := os.ReadFile("testdata/guide.md")
+	require.NoError(t, err)
+
+	var titles []string
+	for _, h := range markdownHeadings(string(content)) {
+		titles = append(titles, h.title)
+	}
+	assert.Equal(t, []string{"Worker Guide", "Installation", "Requirements", "Building", "Usage"}, titles)
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/tmc/langchaingo/textsplitter"
@@ -55,6 +56,10 @@ func isNavigationLink(text string) bool {
 
 // ChunkDocument chunks document content
 func (s *Service) ChunkDocument(ctx context.Context, content string, docType DocumentType, config ChunkConfig) (*ChunkResult, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Preprocess content based on document type
 	processedContent, err := s.preprocessContent(content, docType)
 	if err != nil {
@@ -64,8 +69,13 @@ func (s *Service) ChunkDocument(ctx context.Context, content string, docType Doc
 	// Clean content for RAG
 	cleanContent := s.cleanContentForRAG(processedContent)
 
+	tokenizer, err := NewTokenizer(config.Encoding, config.CharsPerToken)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create appropriate text splitter
-	splitter, err := s.createTextSplitter(config, docType)
+	splitter, err := s.createTextSplitter(config, docType, tokenizer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create text splitter: %w", err)
 	}
@@ -76,22 +86,51 @@ func (s *Service) ChunkDocument(ctx context.Context, content string, docType Doc
 		return nil, fmt.Errorf("failed to split text: %w", err)
 	}
 
-	// Filter and create chunk objects
+	// Filter and create chunk objects, noting the heading each chunk
+	// starts under. Markdown chunks are noted with the path of headings
+	// they are in.
+	found := headings(cleanContent)
+	if config.Method == MethodMarkdown {
+		found = markdownHeadings(cleanContent)
+	}
+	offset := 0
+	runeOffset := 0
+	totalTokens := 0
 	var resultChunks []Chunk
 	for i, chunk := range chunks {
 		cleanChunk := strings.TrimSpace(chunk)
+		start, end := -1, -1
+		if pos := strings.Index(cleanContent[offset:], cleanChunk); pos >= 0 {
+			runeOffset += utf8.RuneCountInString(cleanContent[offset : offset+pos])
+			offset += pos
+			start, end = runeOffset, runeOffset+utf8.RuneCountInString(cleanChunk)
+		}
 		if len(cleanChunk) < 10 { // Skip very small chunks
 			continue
 		}
 
+		metadata := map[string]interface{}{
+			"document_type": string(docType),
+			"method":        string(config.Method),
+		}
+		if config.Method == MethodMarkdown {
+			if path := headingPathAt(found, offset+len(cleanChunk)-1); len(path) > 0 {
+				metadata["heading_path"] = path
+				metadata["section"] = path[len(path)-1]
+			}
+		} else if section := sectionAt(found, offset); section != "" {
+			metadata["section"] = section
+		}
+		tokens := CountTokens(tokenizer, cleanChunk)
+		totalTokens += tokens
 		resultChunks = append(resultChunks, Chunk{
-			ID:      i + 1,
-			Content: cleanChunk,
-			Size:    len(cleanChunk),
-			Metadata: map[string]interface{}{
-				"document_type": string(docType),
-				"method":        string(config.Method),
-			},
+			ID:       i + 1,
+			Content:  cleanChunk,
+			Size:     len(cleanChunk),
+			Tokens:   tokens,
+			Start:    start,
+			End:      end,
+			Metadata: metadata,
 		})
 	}
 
@@ -111,6 +150,8 @@ func (s *Service) ChunkDocument(ctx context.Context, content string, docType Doc
 		TotalChunks:  len(resultChunks),
 		AverageSize:  avgSize,
 		OriginalSize: totalSize,
+		TotalTokens:  totalTokens,
+		Format:       config.OutputFormat,
 	}, nil
 }
 
@@ -124,25 +165,21 @@ func (s *Service) ChunkFromFile(ctx context.Context, filePath string, config Chu
 	// Determine document type from file extension
 	docType := s.determineDocumentType(filePath)
 
-	return s.ChunkDocument(ctx, string(content), docType, config)
+	result, err := s.ChunkDocument(ctx, string(content), docType, config)
+	if err != nil {
+		return nil, err
+	}
+	result.Source = filepath.Base(filePath)
+	return result, nil
 }
 
-// SaveChunks saves chunks to output directory
+// SaveChunks saves chunks flat into the output directory, replacing files
+// from earlier runs
 func (s *Service) SaveChunks(ctx context.Context, result *ChunkResult, outputDir string) error {
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
-
-	for _, chunk := range result.Chunks {
-		filename := fmt.Sprintf("chunk_%03d.txt", chunk.ID)
-		filePath := filepath.Join(outputDir, filename)
-
-		if err := os.WriteFile(filePath, []byte(chunk.Content), 0644); err != nil {
-			return fmt.Errorf("failed to write chunk %d: %w", chunk.ID, err)
-		}
-	}
-
-	return nil
+	return s.SaveChunksWithOptions(ctx, result, outputDir, SaveOptions{Layout: LayoutFlat, Overwrite: true})
 }
 
 // preprocessContent preprocesses content based on document type
@@ -194,8 +231,19 @@ func (s *Service) cleanContentForRAG(content string) string {
 }
 
 // createTextSplitter creates appropriate text splitter
-func (s *Service) createTextSplitter(config ChunkConfig, docType DocumentType) (textsplitter.TextSplitter, error) {
+func (s *Service) createTextSplitter(config ChunkConfig, docType DocumentType, tokenizer Tokenizer) (textsplitter.TextSplitter, error) {
 	switch config.Method {
+	case MethodToken:
+		return tokenSplitter{tokenizer: tokenizer, size: config.ChunkSize, overlap: config.Overlap}, nil
+	case MethodMarkdown:
+		return markdownSplitter{
+			size: config.ChunkSize,
+			fallback: textsplitter.NewRecursiveCharacter(
+				textsplitter.WithChunkSize(config.ChunkSize),
+				textsplitter.WithChunkOverlap(config.Overlap),
+				textsplitter.WithSeparators([]string{"\n\n", "\n", ". ", " ", ""}),
+			),
+		}, nil
 	case MethodRecursive:
 		return textsplitter.NewRecursiveCharacter(
 			textsplitter.WithChunkSize(config.ChunkSize),
@@ -0,0 +1,136 @@
+package chunking
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+)
+
+// Token estimators of MethodToken. Exact BPE vocabularies are downloaded at
+// run time, so tokens are estimated offline instead.
+const (
+	// EncodingApprox splits text the way GPT tokenizers pre-tokenize it,
+	// into words with their leading space, numbers of up to three digits
+	// and punctuation runs. Short pieces are one token; long ones, which
+	// BPE breaks up, count a token per five characters.
+	EncodingApprox = "approx"
+	// EncodingChars counts a token per CharsPerToken characters
+	EncodingChars = "chars"
+)
+
+// DefaultCharsPerToken is the ratio of EncodingChars, about right for
+// English text and GPT tokenizers
+const DefaultCharsPerToken = 4.0
+
+// Piece lengths of EncodingApprox: pieces up to approxWordRunes long are
+// one token, longer ones are split every approxTokenRunes
+const (
+	approxWordRunes  = 8
+	approxTokenRunes = 5
+)
+
+var pretokenPattern = regexp.MustCompile(`'(?:s|t|re|ve|m|ll|d)| ?\p{L}+| ?\p{N}{1,3}| ?[^\s\p{L}\p{N}]+|\s+`)
+
+// Tokenizer estimates how an LLM tokenizer splits text
+type Tokenizer interface {
+	// Spans returns the byte ranges of the tokens of text, in order. The
+	// spans cover the text without gaps.
+	Spans(text string) [][2]int
+}
+
+// NewTokenizer returns the estimator for encoding; empty means
+// EncodingApprox. charsPerToken is used by EncodingChars, with
+// DefaultCharsPerToken when it is not positive.
+func NewTokenizer(encoding string, charsPerToken float64) (Tokenizer, error) {
+	switch encoding {
+	case "", EncodingApprox:
+		return approxTokenizer{}, nil
+	case EncodingChars:
+		if charsPerToken <= 0 {
+			charsPerToken = DefaultCharsPerToken
+		}
+		return charsTokenizer{ratio: charsPerToken}, nil
+	default:
+		return nil, fmt.Errorf("unsupported token encoding %q (supported: approx, chars)", encoding)
+	}
+}
+
+// CountTokens returns the number of tokens of text
+func CountTokens(tokenizer Tokenizer, text string) int {
+	return len(tokenizer.Spans(text))
+}
+
+type approxTokenizer struct{}
+
+func (approxTokenizer) Spans(text string) [][2]int {
+	var spans [][2]int
+	for _, piece := range pretokenPattern.FindAllStringIndex(text, -1) {
+		if utf8.RuneCountInString(text[piece[0]:piece[1]]) <= approxWordRunes {
+			spans = append(spans, [2]int{piece[0], piece[1]})
+			continue
+		}
+		spans = appendRuneSpans(spans, text, piece[0], piece[1], approxTokenRunes)
+	}
+	return spans
+}
+
+// appendRuneSpans splits text[start:end] into spans of at most n runes
+func appendRuneSpans(spans [][2]int, text string, start, end, n int) [][2]int {
+	spanStart, runes := start, 0
+	for i := start; i < end; {
+		_, size := utf8.DecodeRuneInString(text[i:end])
+		i += size
+		runes++
+		if runes == n || i == end {
+			spans = append(spans, [2]int{spanStart, i})
+			spanStart, runes = i, 0
+		}
+	}
+	return spans
+}
+
+type charsTokenizer struct {
+	ratio float64
+}
+
+// Spans gives token i the runes from i*ratio up to (i+1)*ratio
+func (t charsTokenizer) Spans(text string) [][2]int {
+	var spans [][2]int
+	spanStart, runes, token := 0, 0, 1
+	for i := 0; i < len(text); {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		runes++
+		if float64(runes) >= float64(token)*t.ratio || i == len(text) {
+			spans = append(spans, [2]int{spanStart, i})
+			spanStart = i
+			token++
+		}
+	}
+	return spans
+}
+
+// tokenSplitter splits text into chunks of size tokens, each starting
+// overlap tokens before the end of the previous one
+type tokenSplitter struct {
+	tokenizer Tokenizer
+	size      int
+	overlap   int
+}
+
+// SplitText implements textsplitter.TextSplitter
+func (s tokenSplitter) SplitText(text string) ([]string, error) {
+	spans := s.tokenizer.Spans(text)
+	var chunks []string
+	for start := 0; start < len(spans); start += s.size - s.overlap {
+		end := start + s.size
+		if end > len(spans) {
+			end = len(spans)
+		}
+		chunks = append(chunks, text[spans[start][0]:spans[end-1][1]])
+		if end == len(spans) {
+			break
+		}
+	}
+	return chunks, nil
+}
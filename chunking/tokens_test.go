@@ -0,0 +1,83 @@
+package chunking
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tokenTestText = `Retrieval pipelines embed documents in chunks. Each chunk must fit the
+embedding model's context, which is measured in tokens rather than characters.
+
+Sizing chunks by characters wastes context on short words and overflows it on
+long numbers like 1234567890 or identifiers such as internationalization_2024.`
+
+func TestTokenizerSpansCoverText(t *testing.T) {
+	for _, encoding := range []string{EncodingApprox, EncodingChars} {
+		tokenizer, err := NewTokenizer(encoding, 3.5)
+		require.NoError(t, err)
+
+		var rebuilt strings.Builder
+		end := 0
+		for _, span := range tokenizer.Spans(tokenTestText + " çğü") {
+			assert.Equal(t, end, span[0], encoding)
+			rebuilt.WriteString((tokenTestText + " çğü")[span[0]:span[1]])
+			end = span[1]
+		}
+		assert.Equal(t, tokenTestText+" çğü", rebuilt.String(), encoding)
+	}
+
+	approx, _ := NewTokenizer("", 0)
+	assert.Equal(t, 4, CountTokens(approx, "Hello, world!"))
+	chars, _ := NewTokenizer(EncodingChars, 0)
+	assert.Equal(t, 3, CountTokens(chars, "twelve chars"))
+
+	_, err := NewTokenizer("cl100k_base", 0)
+	assert.Error(t, err)
+}
+
+func TestTokenSplitterOverlap(t *testing.T) {
+	tokenizer, _ := NewTokenizer(EncodingApprox, 0)
+	splitter := tokenSplitter{tokenizer: tokenizer, size: 12, overlap: 4}
+
+	chunks, err := splitter.SplitText(tokenTestText)
+	require.NoError(t, err)
+	require.Greater(t, len(chunks), 3)
+
+	for i, chunk := range chunks {
+		spans := tokenizer.Spans(chunk)
+		assert.LessOrEqual(t, len(spans), 12)
+		if i == 0 {
+			continue
+		}
+		// The last overlap tokens of the previous chunk start this one
+		previous := chunks[i-1]
+		previousSpans := tokenizer.Spans(previous)
+		tail := previous[previousSpans[len(previousSpans)-4][0]:]
+		assert.True(t, strings.HasPrefix(chunk, tail), "chunk %d starts with %q", i, tail)
+	}
+	assert.True(t, strings.HasSuffix(tokenTestText, chunks[len(chunks)-1]))
+}
+
+func TestChunkDocumentByTokens(t *testing.T) {
+	config := ChunkConfig{Method: MethodToken, ChunkSize: 16, Overlap: 3}
+	result, err := NewService().ChunkDocument(context.Background(), tokenTestText, TypeText, config)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Chunks)
+
+	tokenizer, _ := NewTokenizer("", 0)
+	total := 0
+	for _, chunk := range result.Chunks {
+		assert.LessOrEqual(t, chunk.Tokens, 16)
+		assert.Equal(t, CountTokens(tokenizer, chunk.Content), chunk.Tokens)
+		assert.Equal(t, len(chunk.Content), chunk.Size)
+		total += chunk.Tokens
+	}
+	assert.Equal(t, total, result.TotalTokens)
+
+	_, err = NewService().ChunkDocument(context.Background(), tokenTestText, TypeText, ChunkConfig{Method: MethodToken, ChunkSize: 16, Encoding: "gpt2"})
+	assert.Error(t, err)
+}
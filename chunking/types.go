@@ -1,6 +1,9 @@
 package chunking
 
-import "context"
+import (
+	"context"
+	"documents-worker/validation"
+)
 
 // ChunkMethod defines the chunking strategy
 type ChunkMethod string
@@ -10,6 +13,12 @@ const (
 	MethodSemantic  ChunkMethod = "semantic"
 	MethodSmart     ChunkMethod = "smart"
 	MethodText      ChunkMethod = "text"
+	// MethodToken sizes chunks and their overlap in estimated LLM tokens
+	MethodToken ChunkMethod = "token"
+	// MethodMarkdown splits Markdown at its headings and never splits code
+	// blocks, tables or list items. Overlap only applies where a section
+	// block is too large and split by size.
+	MethodMarkdown ChunkMethod = "markdown"
 )
 
 // DocumentType defines the input document type
@@ -22,20 +31,43 @@ const (
 	TypeText     DocumentType = "text"
 )
 
-// ChunkConfig holds configuration for chunking
+// ChunkConfig holds configuration for chunking. ChunkSize and Overlap are
+// in characters, or in tokens for MethodToken.
 type ChunkConfig struct {
 	Method             ChunkMethod
 	ChunkSize          int
 	Overlap            int
 	OutputFormat       string
 	PreserveFormatting bool
+	// Encoding is the token estimator: approx (default) or chars
+	Encoding      string
+	CharsPerToken float64
+}
+
+// Validate checks the chunking parameters and reports every violation at once
+func (c ChunkConfig) Validate() error {
+	return validation.New().
+		OneOf("method", string(c.Method),
+			string(MethodRecursive), string(MethodSemantic), string(MethodSmart), string(MethodText), string(MethodToken), string(MethodMarkdown)).
+		Check(c.ChunkSize > 0, "chunk_size", "min", c.ChunkSize, "must be greater than 0").
+		Min("overlap", c.Overlap, 0).
+		Check(c.Overlap < c.ChunkSize || c.ChunkSize <= 0, "overlap", "less_than", c.Overlap, "must be smaller than chunk_size").
+		OneOf("encoding", c.Encoding, EncodingApprox, EncodingChars).
+		Check(c.CharsPerToken >= 0, "chars_per_token", "min", c.CharsPerToken, "must not be negative").
+		Err()
 }
 
 // Chunk represents a single document chunk
 type Chunk struct {
-	ID       int                    `json:"id"`
-	Content  string                 `json:"content"`
-	Size     int                    `json:"size"`
+	ID      int    `json:"id"`
+	Content string `json:"content"`
+	Size    int    `json:"size"`
+	Tokens  int    `json:"tokens"`
+	// Start and End are the character offsets of the chunk in the chunked
+	// text, which is the source after RAG cleanup. Overlapping chunks have
+	// overlapping ranges. Both are -1 when the chunk could not be located.
+	Start    int                    `json:"start"`
+	End      int                    `json:"end"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
@@ -45,6 +77,12 @@ type ChunkResult struct {
 	TotalChunks  int     `json:"total_chunks"`
 	AverageSize  float64 `json:"average_size"`
 	OriginalSize int     `json:"original_size"`
+	// TotalTokens is the estimated token count of every chunk together
+	TotalTokens int `json:"total_tokens"`
+	// Source is the name of the chunked file, empty for inline content
+	Source string `json:"source,omitempty"`
+	// Format is the OutputFormat chunk files are saved in
+	Format string `json:"format,omitempty"`
 }
 
 // DocumentChunker interface for document chunking
@@ -52,4 +90,6 @@ type DocumentChunker interface {
 	ChunkDocument(ctx context.Context, content string, docType DocumentType, config ChunkConfig) (*ChunkResult, error)
 	ChunkFromFile(ctx context.Context, filePath string, config ChunkConfig) (*ChunkResult, error)
 	SaveChunks(ctx context.Context, result *ChunkResult, outputDir string) error
+	SaveChunksWithOptions(ctx context.Context, result *ChunkResult, outputDir string, opts SaveOptions) error
+	SaveChunksJSONL(ctx context.Context, result *ChunkResult, path string, overwrite bool) error
 }
@@ -0,0 +1,22 @@
+package chunking
+
+import (
+	"documents-worker/validation"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkConfigValidate(t *testing.T) {
+	assert.NoError(t, ChunkConfig{Method: MethodRecursive, ChunkSize: 1000, Overlap: 200}.Validate())
+
+	err := ChunkConfig{Method: "sentences", ChunkSize: 100, Overlap: 100}.Validate()
+	var validationErr *validation.Error
+	require.True(t, errors.As(err, &validationErr))
+	require.Len(t, validationErr.Violations, 2)
+	assert.Equal(t, "method", validationErr.Violations[0].Field)
+	assert.Equal(t, "overlap", validationErr.Violations[1].Field)
+	assert.Equal(t, "less_than", validationErr.Violations[1].Rule)
+}
@@ -0,0 +1,248 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"documents-worker/internal/core/domain"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Config holds client configuration
+type Config struct {
+	BaseURL string
+	// Timeout limits each attempt of a request; a context deadline limits
+	// the request as a whole, retries included
+	Timeout time.Duration
+
+	// MaxRetries is the number of times a failed request is retried. Reads
+	// are retried on network errors and 429 or 5xx responses, document
+	// submits on 429 or 5xx responses only. Zero disables retries.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled for each
+	// one after it unless the server sends Retry-After. Zero means
+	// DefaultRetryBackoff.
+	RetryBackoff time.Duration
+	// RetryUploads also retries multipart uploads, which the server may
+	// process more than once
+	RetryUploads bool
+}
+
+// Client is a Go client for the Documents Worker HTTP API
+type Client struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// APIError represents an error response returned by the server
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Message    string `json:"error"`
+	Details    string `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("server returned %d: %s (%s)", e.StatusCode, e.Message, e.Details)
+	}
+	return fmt.Sprintf("server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// NewClient creates a new API client
+func NewClient(cfg Config) *Client {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost:3001"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	return &Client{
+		config:     cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// Health returns the server health status
+func (c *Client) Health(ctx context.Context) (*domain.HealthStatus, error) {
+	health := retryIdempotent
+	health.answer = http.StatusServiceUnavailable
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/health", nil, "", health)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// A degraded server answers 503 with a regular health body
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, decodeError(resp)
+	}
+
+	var status domain.HealthStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode health status: %w", err)
+	}
+	return &status, nil
+}
+
+// GetQueueStats returns queue statistics
+func (c *Client) GetQueueStats(ctx context.Context) (*domain.QueueStats, error) {
+	var stats domain.QueueStats
+	if err := c.getJSON(ctx, "/api/v1/stats/queue", &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// ProcessDocument submits a document processing request
+func (c *Client) ProcessDocument(ctx context.Context, req *domain.ProcessingRequest) (*domain.ProcessingResult, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/documents/process", body, "application/json", retrySubmit)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+
+	var result domain.ProcessingResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode processing result: %w", err)
+	}
+	return &result, nil
+}
+
+// GetJob retrieves a job by ID
+func (c *Client) GetJob(ctx context.Context, jobID string) (*domain.ProcessingJob, error) {
+	var job domain.ProcessingJob
+	if err := c.getJSON(ctx, "/api/v1/jobs/"+jobID, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ConvertImage uploads an image and returns the converted output.
+// The caller is responsible for closing the returned reader.
+func (c *Client) ConvertImage(ctx context.Context, input io.Reader, filename, outputFormat string) (io.ReadCloser, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if err := writer.WriteField("output_format", outputFormat); err != nil {
+		return nil, fmt.Errorf("failed to write form field: %w", err)
+	}
+
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := io.Copy(part, input); err != nil {
+		return nil, fmt.Errorf("failed to copy input: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize form: %w", err)
+	}
+
+	policy := retryNever
+	if c.config.RetryUploads {
+		policy = retrySubmit
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/api/v1/process/image/convert", body.Bytes(), writer.FormDataContentType(), policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+
+	return resp.Body, nil
+}
+
+// getJSON performs a GET request and decodes a JSON response
+func (c *Client) getJSON(ctx context.Context, path string, target interface{}) error {
+	resp, err := c.do(ctx, http.MethodGet, path, nil, "", retryIdempotent)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// do executes an HTTP request against the server, retrying the failures
+// policy allows up to MaxRetries times. The last response is returned when
+// retries run out, including when ctx ends before the next one could.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, contentType string, policy retryPolicy) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, body, contentType)
+		if attempt >= c.config.MaxRetries || !policy.retryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+		if !sleep(ctx, retryDelay(resp, c.config.RetryBackoff, attempt+1)) {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+	}
+}
+
+// send builds and executes a single HTTP request
+func (c *Client) send(ctx context.Context, method, path string, body []byte, contentType string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.config.BaseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// decodeError converts a non-success response into an APIError
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+	return apiErr
+}
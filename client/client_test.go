@@ -0,0 +1,304 @@
+package client
+
+import (
+	"context"
+	"documents-worker/internal/core/domain"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/health", r.URL.Path)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(domain.HealthStatus{Status: "degraded"})
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{BaseURL: server.URL + "/"})
+	status, err := c.Health(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "degraded", status.Status)
+}
+
+func TestProcessDocument(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var req domain.ProcessingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "doc-1", req.DocumentID)
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(domain.ProcessingResult{JobID: "job-1", Status: domain.JobStatusPending})
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{BaseURL: server.URL})
+	result, err := c.ProcessDocument(context.Background(), &domain.ProcessingRequest{
+		DocumentID: "doc-1",
+		Type:       domain.ProcessingTypeOCR,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "job-1", result.JobID)
+}
+
+func TestConvertImage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "webp", r.FormValue("output_format"))
+
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		defer file.Close()
+		assert.Equal(t, "input.png", header.Filename)
+
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "png-bytes", string(data))
+
+		w.Write([]byte("webp-bytes"))
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{BaseURL: server.URL})
+	out, err := c.ConvertImage(context.Background(), strings.NewReader("png-bytes"), "input.png", "webp")
+	require.NoError(t, err)
+	defer out.Close()
+
+	data, err := io.ReadAll(out)
+	require.NoError(t, err)
+	assert.Equal(t, "webp-bytes", string(data))
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"Job not found","details":"missing"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{BaseURL: server.URL})
+	_, err := c.GetJob(context.Background(), "nope")
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "Job not found", apiErr.Message)
+	assert.Equal(t, "missing", apiErr.Details)
+}
+
+// failingServer fails the first failures requests with status, then
+// serves the request with handler
+func failingServer(t *testing.T, failures int32, status int, handler http.HandlerFunc) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":"try again"}`))
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetriesReads(t *testing.T) {
+	server, requests := failingServer(t, 2, http.StatusBadGateway, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(domain.ProcessingJob{ID: "job-1"})
+	})
+
+	c := NewClient(Config{BaseURL: server.URL, MaxRetries: 3, RetryBackoff: time.Millisecond})
+	job, err := c.GetJob(context.Background(), "job-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "job-1", job.ID)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestRetriesGiveUp(t *testing.T) {
+	server, requests := failingServer(t, 10, http.StatusInternalServerError, nil)
+
+	c := NewClient(Config{BaseURL: server.URL, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	_, err := c.GetQueueStats(context.Background())
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Equal(t, "try again", apiErr.Message)
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestRetriesOffByDefault(t *testing.T) {
+	server, requests := failingServer(t, 1, http.StatusServiceUnavailable, nil)
+
+	c := NewClient(Config{BaseURL: server.URL})
+	_, err := c.GetJob(context.Background(), "job-1")
+
+	require.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRetriesSkipClientErrors(t *testing.T) {
+	server, requests := failingServer(t, 1, http.StatusBadRequest, nil)
+
+	c := NewClient(Config{BaseURL: server.URL, MaxRetries: 3, RetryBackoff: time.Millisecond})
+	_, err := c.GetJob(context.Background(), "job-1")
+
+	require.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRetriesDegradedHealth(t *testing.T) {
+	server, requests := failingServer(t, 1, http.StatusTooManyRequests, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(domain.HealthStatus{Status: "degraded"})
+	})
+
+	c := NewClient(Config{BaseURL: server.URL, MaxRetries: 3, RetryBackoff: time.Millisecond})
+	status, err := c.Health(context.Background())
+
+	// 503 is the answer of a degraded server, not a failure
+	require.NoError(t, err)
+	assert.Equal(t, "degraded", status.Status)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestRetriesSubmitResendsBody(t *testing.T) {
+	server, requests := failingServer(t, 1, http.StatusTooManyRequests, func(w http.ResponseWriter, r *http.Request) {
+		var req domain.ProcessingRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "doc-1", req.DocumentID)
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(domain.ProcessingResult{JobID: "job-1"})
+	})
+
+	c := NewClient(Config{BaseURL: server.URL, MaxRetries: 1, RetryBackoff: time.Millisecond})
+	result, err := c.ProcessDocument(context.Background(), &domain.ProcessingRequest{DocumentID: "doc-1"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "job-1", result.JobID)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestRetriesUploadsOnlyWhenEnabled(t *testing.T) {
+	convert := func(w http.ResponseWriter, r *http.Request) {
+		file, _, err := r.FormFile("file")
+		require.NoError(t, err)
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "png-bytes", string(data))
+		w.Write([]byte("webp-bytes"))
+	}
+
+	server, requests := failingServer(t, 1, http.StatusServiceUnavailable, convert)
+	c := NewClient(Config{BaseURL: server.URL, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	_, err := c.ConvertImage(context.Background(), strings.NewReader("png-bytes"), "input.png", "webp")
+	require.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+
+	server, requests = failingServer(t, 1, http.StatusServiceUnavailable, convert)
+	c = NewClient(Config{BaseURL: server.URL, MaxRetries: 2, RetryBackoff: time.Millisecond, RetryUploads: true})
+	out, err := c.ConvertImage(context.Background(), strings.NewReader("png-bytes"), "input.png", "webp")
+	require.NoError(t, err)
+	defer out.Close()
+	data, _ := io.ReadAll(out)
+	assert.Equal(t, "webp-bytes", string(data))
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestRetriesNetworkErrors(t *testing.T) {
+	// Nothing listens on the address of a closed server
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	var attempts atomic.Int32
+	c := NewClient(Config{BaseURL: server.URL, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	c.httpClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		attempts.Add(1)
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	_, err := c.GetJob(context.Background(), "job-1")
+	require.Error(t, err)
+	assert.Equal(t, int32(3), attempts.Load())
+
+	// Submits may have reached the server, so they are not retried
+	attempts.Store(0)
+	_, err = c.ProcessDocument(context.Background(), &domain.ProcessingRequest{DocumentID: "doc-1"})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestRetriesStopAtContextDeadline(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NewClient(Config{BaseURL: server.URL, MaxRetries: 5, RetryBackoff: time.Millisecond})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.GetJob(ctx, "job-1")
+
+	// Waiting the minute the server asks for would outlast the deadline
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	delay, ok := retryAfter("3", now)
+	require.True(t, ok)
+	assert.Equal(t, 3*time.Second, delay)
+
+	delay, ok = retryAfter(now.Add(5*time.Second).Format(http.TimeFormat), now)
+	require.True(t, ok)
+	assert.Equal(t, 5*time.Second, delay)
+
+	delay, ok = retryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now)
+	require.True(t, ok)
+	assert.Zero(t, delay)
+
+	for _, value := range []string{"", "-1", "soon"} {
+		_, ok = retryAfter(value, now)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempt := 1; attempt <= 3; attempt++ {
+		full := 100 * time.Millisecond << (attempt - 1)
+		delay := retryDelay(nil, 100*time.Millisecond, attempt)
+		assert.GreaterOrEqual(t, delay, full/2)
+		assert.LessOrEqual(t, delay, full)
+	}
+	assert.LessOrEqual(t, retryDelay(nil, time.Second, 40), maxRetryDelay)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryBackoff is the delay before the first retry when retries are
+// enabled without a backoff
+const DefaultRetryBackoff = 500 * time.Millisecond
+
+// maxRetryDelay caps the delay between two attempts, Retry-After included
+const maxRetryDelay = 30 * time.Second
+
+// retryPolicy tells which failures of a request are retried
+type retryPolicy struct {
+	// network retries requests that got no response, which the server may
+	// still have acted on
+	network bool
+	// status retries 429 and 5xx responses
+	status bool
+	// answer is a status returned as is, such as the 503 of a degraded
+	// health check
+	answer int
+}
+
+var (
+	// Reads are retried on any transient failure
+	retryIdempotent = retryPolicy{network: true, status: true}
+	// Submits are retried when the server turned them down
+	retrySubmit = retryPolicy{status: true}
+	retryNever  = retryPolicy{}
+)
+
+// retryable tells whether the outcome of an attempt is worth retrying
+func (p retryPolicy) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return p.network
+	}
+	if !p.status || resp.StatusCode == p.answer {
+		return false
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// retryDelay returns the delay before retry attempt, counted from 1: the
+// server's Retry-After when given, otherwise backoff doubled on each attempt
+// with jitter, so that clients failing together do not retry together
+func retryDelay(resp *http.Response, backoff time.Duration, attempt int) time.Duration {
+	if resp != nil {
+		if delay, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return min(delay, maxRetryDelay)
+		}
+	}
+	delay := maxRetryDelay
+	if shift := attempt - 1; shift < 16 && backoff<<shift < maxRetryDelay {
+		delay = backoff << shift
+	}
+	// Wait between half and all of the delay
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryAfter parses a Retry-After header, either seconds or an HTTP date
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for delay unless ctx is done first. It does not wait when
+// ctx's deadline comes before the delay ends, as the retry could not finish.
+func sleep(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"documents-worker/cache"
 	"documents-worker/config"
 	"documents-worker/internal/adapters/primary/cli"
 	adapters "documents-worker/internal/adapters/secondary"
 	"documents-worker/internal/adapters/secondary/processors"
+	"documents-worker/internal/adapters/secondary/repository"
 	"documents-worker/internal/core/ports"
 	"documents-worker/internal/core/services"
+	"documents-worker/libreoffice"
 	"documents-worker/queue"
+	"documents-worker/redisclient"
 	"log"
 	"os"
 
@@ -29,30 +33,40 @@ var (
 func main() {
 	// Load configuration
 	cfg := config.Load()
+	libreoffice.Configure(cfg.External.LibreOfficeMaxConcurrent, cfg.External.LibreOfficeProfileDir, libreoffice.RetryPolicy{
+		Retries:    cfg.External.LibreOfficeRetries,
+		Backoff:    cfg.External.LibreOfficeRetryBackoff,
+		RunTimeout: cfg.External.LibreOfficeRunTimeout,
+	})
 
-	// Initialize Redis queue (optional for CLI)
+	// Initialize Redis queue and job records (optional for CLI)
 	var queueAdapter ports.Queue
+	var jobRepo ports.JobRepository
 	if cfg.Redis.Host != "" {
-		redisQueue, err := queue.NewRedisQueue(&cfg.Redis, &cfg.Worker)
+		redisClient, err := redisclient.New(&cfg.Redis)
 		if err != nil {
 			log.Printf("⚠️  Redis not available, continuing without queue support: %v", err)
 		} else {
-			defer redisQueue.Close()
+			defer redisClient.Close()
+			jobRepo = repository.NewRedisJobRepository(redisClient, &cfg.Worker)
+			redisQueue := queue.NewRedisQueueWithClient(redisClient, &cfg.Worker).
+				WithStatusListener(adapters.JobRecordListener(jobRepo))
 			queueAdapter = adapters.NewQueueAdapter(redisQueue)
 		}
 	}
 
 	// Initialize processors
-	imageProcessor := processors.NewVipsImageProcessor()
-	videoProcessor := processors.NewFFmpegVideoProcessor()
-	pdfProcessor := processors.NewPlaywrightPDFProcessor(&cfg.External)
+	imageProcessor := processors.NewVipsImageProcessor(&cfg.Limits)
+	videoProcessor := processors.NewFFmpegVideoProcessor(&cfg.Limits)
+	cacheManager := cache.NewCacheManager(cfg.Cache.Directory, cfg.Cache.TTL, cfg.Cache.Enabled)
+	pdfProcessor := processors.NewPlaywrightPDFProcessor(&cfg.External, &cfg.Limits, cacheManager, &cfg.Fetch)
 	ocrProcessor := processors.NewTesseractOCRProcessor(&cfg.OCR, &cfg.External)
-	textExtractor := processors.NewMultiTextExtractor(&cfg.External)
+	textExtractor := processors.NewMultiTextExtractor(&cfg.External, &cfg.OCR)
 
 	// Initialize core services (CLI doesn't need all services)
 	documentService := services.NewDocumentService(
 		nil, // documentRepo
-		nil, // jobRepo
+		jobRepo,
 		nil, // fileStorage
 		queueAdapter,
 		imageProcessor,
@@ -2,14 +2,27 @@ package main
 
 import (
 	"context"
+	"documents-worker/auth"
 	"documents-worker/cache"
 	"documents-worker/config"
 	"documents-worker/health"
 	"documents-worker/internal/adapters/primary/http"
 	adapters "documents-worker/internal/adapters/secondary"
+	"documents-worker/internal/adapters/secondary/postprocess"
 	"documents-worker/internal/adapters/secondary/processors"
+	"documents-worker/internal/adapters/secondary/repository"
+	"documents-worker/internal/adapters/secondary/storage"
+	"documents-worker/internal/core/ports"
 	"documents-worker/internal/core/services"
+	"documents-worker/libreoffice"
+	"documents-worker/maintenance"
+	"documents-worker/memory"
 	"documents-worker/queue"
+	"documents-worker/quota"
+	"documents-worker/recorder"
+	"documents-worker/redisclient"
+	"documents-worker/shutdown"
+	"documents-worker/validation"
 	"log"
 	"os"
 	"os/signal"
@@ -17,11 +30,13 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/redis/go-redis/v9"
 )
 
+// apiVersion is the version reported in the OpenAPI document
+const apiVersion = "1.0.0"
+
 func main() {
 	// Load configuration
 	cfg := config.Load()
@@ -30,31 +45,74 @@ func main() {
 	log.Printf("📍 Environment: %s", cfg.Server.Environment)
 	log.Printf("🌐 Port: %s", cfg.Server.Port)
 
+	// Office conversions share one bounded pool of LibreOffice profiles
+	libreoffice.Configure(cfg.External.LibreOfficeMaxConcurrent, cfg.External.LibreOfficeProfileDir, libreoffice.RetryPolicy{
+		Retries:    cfg.External.LibreOfficeRetries,
+		Backoff:    cfg.External.LibreOfficeRetryBackoff,
+		RunTimeout: cfg.External.LibreOfficeRunTimeout,
+	})
+	if cfg.External.LibreOfficeWarmUp {
+		go warmUpLibreOffice(&cfg.External)
+	}
+
 	// Initialize dependencies
-	redisQueue, err := queue.NewRedisQueue(&cfg.Redis, &cfg.Worker)
+	// A single pooled Redis client is shared by every Redis-backed subsystem
+	redisClient, err := redisclient.New(&cfg.Redis)
 	if err != nil {
-		log.Fatalf("❌ Failed to initialize Redis queue: %v", err)
+		log.Fatalf("❌ Failed to connect to Redis: %v", err)
+	}
+	// Job records follow every status change the queue makes
+	jobRepo := repository.NewRedisJobRepository(redisClient, &cfg.Worker)
+	redisQueue := queue.NewRedisQueueWithClient(redisClient, &cfg.Worker).
+		WithStatusListener(adapters.JobRecordListener(jobRepo))
+
+	// Jobs queued by versions before the hash-tagged queue keys
+	if moved, err := redisQueue.MigrateLegacyQueues(context.Background()); err != nil {
+		log.Printf("⚠️  Failed to migrate legacy queued jobs: %v", err)
+	} else if moved > 0 {
+		log.Printf("📦 Moved %d queued jobs to the hash-tagged queue keys", moved)
 	}
-	defer redisQueue.Close()
 
 	cacheManager := cache.NewCacheManager(cfg.Cache.Directory, cfg.Cache.TTL, cfg.Cache.Enabled)
+	if cfg.Cache.Enabled && cfg.Cache.L1Enabled {
+		l1, err := cache.NewMemoryCacheFromConfig(&cfg.Cache)
+		if err != nil {
+			log.Fatalf("❌ Invalid L1 cache configuration: %v", err)
+		}
+		cacheManager.WithL1(l1)
+	}
 
 	// Create adapters for legacy components
 	queueAdapter := adapters.NewQueueAdapter(redisQueue)
 	cacheAdapter := adapters.NewCacheAdapter(cacheManager)
 
 	// Initialize processors (secondary adapters)
-	imageProcessor := processors.NewVipsImageProcessor()
-	videoProcessor := processors.NewFFmpegVideoProcessor()
-	pdfProcessor := processors.NewPlaywrightPDFProcessor(&cfg.External)
+	imageProcessor := processors.NewVipsImageProcessor(&cfg.Limits)
+	videoProcessor := processors.NewFFmpegVideoProcessor(&cfg.Limits)
+	pdfProcessor := processors.NewPlaywrightPDFProcessor(&cfg.External, &cfg.Limits, cacheManager, &cfg.Fetch)
 	ocrProcessor := processors.NewTesseractOCRProcessor(&cfg.OCR, &cfg.External)
-	textExtractor := processors.NewMultiTextExtractor(&cfg.External)
+	textExtractor := processors.NewMultiTextExtractor(&cfg.External, &cfg.OCR)
+
+	// Processing outputs are kept in object storage when a backend is set
+	var fileStorage ports.FileStorage
+	switch cfg.Storage.Backend {
+	case "":
+	case "s3":
+		s3Storage, err := storage.NewS3Storage(&cfg.Storage)
+		if err != nil {
+			log.Fatalf("❌ Invalid S3 storage configuration: %v", err)
+		}
+		fileStorage = s3Storage
+		log.Printf("🪣 Storing outputs in bucket %s", cfg.Storage.Bucket)
+	default:
+		log.Fatalf("❌ Unknown storage backend: %s", cfg.Storage.Backend)
+	}
 
 	// Initialize core services
 	documentService := services.NewDocumentService(
 		nil, // documentRepo - would be implemented for persistence
-		nil, // jobRepo - would be implemented for persistence
-		nil, // fileStorage - would be implemented for file storage
+		jobRepo,
+		fileStorage,
 		queueAdapter,
 		imageProcessor,
 		videoProcessor,
@@ -64,10 +122,17 @@ func main() {
 		nil, // eventPublisher - would be implemented for events
 	)
 
+	// Conversion results are handed to the configured post-processors
+	postProcessors, err := postprocess.Build(cfg.PostProcess.Names, cfg.PostProcess.Settings)
+	if err != nil {
+		log.Fatalf("❌ Failed to configure post-processors: %v", err)
+	}
+	documentService = services.WithPostProcessors(documentService, cfg.PostProcess.FailOnError, postProcessors...)
+
 	healthService := services.NewHealthService(
 		queueAdapter,
 		cacheAdapter,
-		nil, // fileStorage
+		fileStorage,
 		imageProcessor,
 		videoProcessor,
 		pdfProcessor,
@@ -76,34 +141,137 @@ func main() {
 
 	queueService := services.NewQueueService(queueAdapter)
 
+	// Spooled uploads and queued jobs are protected from orphan cleanup
+	activeUploads := maintenance.NewTracker()
+
 	// Initialize HTTP adapter (primary adapter)
-	httpHandler := http.NewDocumentHandler(documentService, healthService, queueService)
+	uploads := http.UploadConfig{
+		TempDir:       cfg.Server.TempDir,
+		MaxFileSize:   cfg.Limits.MaxFileSize,
+		Tracker:       activeUploads,
+		VerifyContent: cfg.Limits.VerifyContent,
+		SniffSize:     cfg.Limits.SniffSize,
+		ScanFailOpen:  cfg.Validation.ScanFailOpen,
+	}
+	if cfg.Validation.ScanForMalware {
+		uploads.Scanner = validation.NewClamAVScanner(cfg.Validation.ClamdAddress, cfg.Validation.ClamdTimeout)
+		log.Printf("🛡️ Scanning uploads for malware with clamd at %s", cfg.Validation.ClamdAddress)
+	}
+	httpHandler := http.NewDocumentHandler(documentService, healthService, queueService, uploads)
+	httpHandler.SetMaxJobWait(cfg.Server.MaxJobWait)
+
+	var maintenanceScheduler *maintenance.Scheduler
+	if cfg.Maintenance.Enabled {
+		maintenanceScheduler = newMaintenanceScheduler(cfg, redisClient, redisQueue, cacheManager, activeUploads)
+		maintenanceScheduler.Start()
+	}
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		ErrorHandler: func(c *fiber.Ctx, err error) error {
-			code := fiber.StatusInternalServerError
-			if e, ok := err.(*fiber.Error); ok {
-				code = e.Code
-			}
-			return c.Status(code).JSON(fiber.Map{
-				"error":   err.Error(),
-				"code":    code,
-				"success": false,
-			})
-		},
+		ErrorHandler: http.ErrorHandler,
+		// Bodies above BodyLimit are streamed instead of rejected, so large
+		// uploads are spooled to disk by the handlers
+		BodyLimit:                    cfg.Server.BodyLimit,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 	})
 
 	// Middleware
 	app.Use(recover.New())
-	app.Use(logger.New(logger.Config{
-		Format: "${time} ${method} ${path} ${status} ${latency}\n",
-	}))
-	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders: "Origin,Content-Type,Accept,Authorization",
-	}))
+	app.Use(http.RequestLogger(cfg.Logging, os.Stdout))
+	corsHandler, err := http.CORS(cfg.Security)
+	if err != nil {
+		log.Fatalf("❌ Invalid CORS configuration: %v", err)
+	}
+	app.Use(corsHandler)
+
+	// Tokens from the external identity provider guard everything but health
+	var verifier *auth.Verifier
+	if cfg.Auth.Enabled {
+		verifier, err = auth.NewVerifier(&cfg.Auth)
+		if err != nil {
+			log.Fatalf("❌ Failed to configure authentication: %v", err)
+		}
+		for _, prefix := range []string{"/api/v1/documents", "/api/v1/jobs", "/api/v1/process", "/api/v1/stats"} {
+			app.Use(prefix, http.RequireAuth(verifier))
+		}
+		log.Printf("🔐 Token verification enabled for issuer %s", cfg.Auth.Issuer)
+	}
+
+	// Failed requests are recorded for replay. Recordings hold customer
+	// documents, so they are served only to administrators.
+	if cfg.Recorder.Enabled {
+		requestRecorder, err := recorder.New(recorder.Config{
+			Directory:     cfg.Recorder.Directory,
+			MaxInputSize:  cfg.Recorder.MaxInputSize,
+			Retention:     cfg.Recorder.Retention,
+			MaxRecordings: cfg.Recorder.MaxRecordings,
+			RedactFields:  cfg.Recorder.RedactFields,
+		})
+		if err != nil {
+			log.Fatalf("❌ Failed to configure request recorder: %v", err)
+		}
+		for _, prefix := range []string{"/api/v1/documents", "/api/v1/process"} {
+			app.Use(prefix, http.RecordFailures(requestRecorder))
+		}
+		if verifier != nil {
+			app.Use("/api/v1/recordings", http.RequireAuth(verifier), http.RequireRole("admin"))
+			http.NewRecordingHandler(requestRecorder).SetupRoutes(app)
+		} else {
+			log.Printf("⚠️  Recordings are served only with authentication enabled; read them from %s", cfg.Recorder.Directory)
+		}
+		log.Printf("📼 Recording failed requests to %s", cfg.Recorder.Directory)
+	}
+
+	// Requests of callers holding the debug role are logged in detail on
+	// the selected routes
+	if cfg.DebugCapture.Enabled {
+		if verifier == nil {
+			log.Printf("⚠️  Debug capture needs authentication to check the %q role; disabled", cfg.DebugCapture.Role)
+		} else {
+			debugCapture := http.NewDebugCapture(cfg.DebugCapture, os.Stdout)
+			for _, prefix := range cfg.DebugCapture.Routes {
+				app.Use(prefix, debugCapture.Handler())
+			}
+			log.Printf("🔍 Capturing requests of %q callers on %v", cfg.DebugCapture.Role, cfg.DebugCapture.Routes)
+		}
+	}
+
+	// Processing requests are held, then shed, while memory runs short
+	var memoryShedder *http.MemoryShedder
+	var memoryGauge *memory.Gauge
+	if cfg.Limits.MemoryPressurePercent > 0 {
+		gauge := memory.NewGauge(memory.DetectSampler(uint64(cfg.Limits.MemoryLimit)), float64(cfg.Limits.MemoryPressurePercent)/100)
+		if err := gauge.Validate(); err != nil {
+			log.Printf("⚠️  Memory pressure shedding disabled: %v", err)
+		} else {
+			gauge.Start(250 * time.Millisecond)
+			memoryGauge = gauge
+			memoryShedder = http.NewMemoryShedder(gauge, cfg.Limits.MemoryPressureMaxWait)
+			for _, prefix := range []string{"/api/v1/documents/process", "/api/v1/process"} {
+				app.Use(prefix, memoryShedder.Handler())
+			}
+			log.Printf("🧠 Shedding processing requests above %d%% of %d bytes", cfg.Limits.MemoryPressurePercent, gauge.Sample().Limit)
+		}
+	}
+
+	// Monthly quotas are counted per tenant, so they need authenticated callers
+	if cfg.Quota.Enabled {
+		if verifier == nil {
+			log.Fatalf("❌ Quotas require authentication; set AUTH_ENABLED=true")
+		}
+		limiter := quota.NewLimiter(quota.NewRedisStore(redisClient), quota.Usage{
+			Requests:     cfg.Quota.MonthlyRequests,
+			Bytes:        cfg.Quota.MonthlyBytes,
+			VideoSeconds: int64(cfg.Quota.MonthlyVideoTime.Seconds()),
+		})
+		for _, prefix := range []string{"/api/v1/documents/process", "/api/v1/process"} {
+			app.Use(prefix, http.EnforceQuota(limiter, cfg.Quota.TenantClaim))
+		}
+		app.Use("/api/v1/quota", http.RequireAuth(verifier))
+		http.NewQuotaHandler(limiter, cfg.Quota.TenantClaim).SetupRoutes(app)
+		log.Printf("📏 Monthly quotas enabled per %q claim", cfg.Quota.TenantClaim)
+	}
 
 	// Setup routes
 	httpHandler.SetupRoutes(app)
@@ -119,6 +287,59 @@ func main() {
 		return c.Status(httpStatus).JSON(status)
 	})
 
+	// Every subsystem in one document with an overall verdict. Only Redis
+	// and the queue are critical; the rest degrade the service. The details
+	// describe the deployment, so they need a token when auth is enabled;
+	// load balancers probe /health.
+	statusAggregator := health.NewAggregator(3*time.Second).
+		Add("redis", true, health.RedisCheck(redisClient)).
+		Add("queue", true, health.QueueCheck(redisQueue)).
+		Add("tools", false, health.ToolsCheck(healthChecker)).
+		Add("memory", false, health.MemoryCheck(memoryGauge)).
+		Add("libreoffice", false, health.LibreOfficeCheck(nil)).
+		Add("cluster", false, health.ClusterCheck(cfg.Redis.Mode, maintenanceScheduler))
+	if verifier != nil {
+		app.Get("/health/status", http.RequireAuth(verifier), statusAggregator.Handler)
+	} else {
+		app.Get("/health/status", statusAggregator.Handler)
+	}
+
+	// Cache effectiveness metrics in Prometheus text format
+	app.Get("/metrics/cache", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return cacheManager.WritePrometheus(c)
+	})
+
+	// Orphaned file cleanup metrics in Prometheus text format
+	app.Get("/metrics/maintenance", func(c *fiber.Ctx) error {
+		if maintenanceScheduler == nil {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return maintenanceScheduler.Metrics().WritePrometheus(c)
+	})
+
+	// Requests canceled by client disconnects in Prometheus text format
+	app.Get("/metrics/requests", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return httpHandler.WritePrometheus(c)
+	})
+
+	// Memory pressure and shedding in Prometheus text format
+	app.Get("/metrics/memory", func(c *fiber.Ctx) error {
+		if memoryShedder == nil {
+			return c.SendStatus(fiber.StatusNotFound)
+		}
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return memoryShedder.WritePrometheus(c)
+	})
+
+	// OpenAPI document generated from the handler descriptions
+	app.Get("/openapi.json", http.OpenAPIHandler(apiVersion))
+	if cfg.Server.SwaggerUI {
+		app.Get("/docs", http.SwaggerUIHandler("/openapi.json"))
+	}
+
 	// Start server in goroutine
 	go func() {
 		log.Printf("🌐 HTTP Server starting on port %s", cfg.Server.Port)
@@ -134,13 +355,84 @@ func main() {
 
 	log.Println("🛑 Shutting down server...")
 
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := app.ShutdownWithContext(ctx); err != nil {
-		log.Printf("❌ Server shutdown error: %v", err)
+	// Graceful shutdown: stop accepting requests and drain those in flight,
+	// stop background work, then close the connections they used
+	sequence := shutdown.NewSequence(cfg.Server.ShutdownTimeout).
+		Add("http", app.ShutdownWithContext)
+	if maintenanceScheduler != nil {
+		sequence.AddFunc("maintenance", maintenanceScheduler.Stop)
+	}
+	if memoryGauge != nil {
+		sequence.AddFunc("memory", memoryGauge.Stop)
 	}
+	sequence.
+		Add("queue", func(ctx context.Context) error { return redisQueue.Close() }).
+		Add("redis", func(ctx context.Context) error { return redisClient.Close() })
 
+	failed := false
+	for _, result := range sequence.Run(context.Background()) {
+		failed = failed || result.Err != nil
+	}
+	if failed {
+		log.Println("⚠️  Server stopped with shutdown errors")
+		return
+	}
 	log.Println("✅ Server stopped")
 }
+
+// newMaintenanceScheduler sweeps this instance's temp directory and, on the
+// elected leader, the cache directory shared by all instances
+func newMaintenanceScheduler(cfg *config.Config, client redis.UniversalClient, redisQueue *queue.RedisQueue,
+	cacheManager *cache.CacheManager, uploads *maintenance.Tracker) *maintenance.Scheduler {
+	tempPatterns := cfg.Maintenance.TempPatterns
+	if len(tempPatterns) == 0 {
+		tempPatterns = maintenance.DefaultTempPatterns
+	}
+
+	// Cache files are kept while a live entry references them; the set is
+	// refreshed after expired entries are removed, before each sweep
+	var liveCacheFiles map[string]bool
+	var liveCacheErr error
+
+	targets := []maintenance.Target{{
+		Name:     "temp",
+		Dir:      cfg.Server.TempDir,
+		MaxAge:   cfg.Maintenance.TempMaxAge,
+		Patterns: tempPatterns,
+	}}
+	if cfg.Cache.Enabled {
+		targets = append(targets, maintenance.Target{
+			Name:   "cache",
+			Dir:    cfg.Cache.Directory,
+			MaxAge: cfg.Maintenance.CacheMaxAge,
+			Shared: true,
+			Keep: func(path string) bool {
+				return liveCacheErr != nil || liveCacheFiles == nil || liveCacheFiles[path]
+			},
+		})
+	}
+
+	leader := maintenance.NewRedisLeader(client, cfg.Worker.QueueName+":maintenance:leader", cfg.Maintenance.LeaderTTL)
+	scheduler := maintenance.NewScheduler(cfg.Maintenance.Interval, leader,
+		[]maintenance.PathSource{redisQueue, uploads}, targets...)
+	scheduler.BeforeSweep = func() {
+		cacheManager.CleanExpired()
+		liveCacheFiles, liveCacheErr = cacheManager.LiveFiles()
+	}
+	return scheduler
+}
+
+// warmUpLibreOffice creates the soffice profiles in the background so the
+// first office conversion does not pay for the cold start. A missing or
+// broken LibreOffice only costs a log line here; conversions report it.
+func warmUpLibreOffice(cfg *config.ExternalConfig) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.LibreOfficeWarmUpTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := libreoffice.Default().WarmUp(ctx, cfg.LibreOfficePath); err != nil {
+		log.Printf("⚠️ LibreOffice warm-up failed: %v", err)
+		return
+	}
+	log.Printf("📝 LibreOffice warmed up in %v", time.Since(start).Round(time.Millisecond))
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +16,19 @@ type Config struct {
 	External ExternalConfig
 	OCR      OCRConfig
 	Cache    CacheConfig
+	Limits   LimitsConfig
+	Auth     AuthConfig
+	Security SecurityConfig
+
+	Maintenance  MaintenanceConfig
+	Logging      LoggingConfig
+	DebugCapture DebugCaptureConfig
+	Recorder     RecorderConfig
+	Quota        QuotaConfig
+	PostProcess  PostProcessConfig
+	Fetch        FetchConfig
+	Storage      StorageConfig
+	Validation   ValidationConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -24,14 +38,49 @@ type ServerConfig struct {
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
 	Environment  string
+
+	// BodyLimit is the largest request body buffered in memory; larger
+	// bodies are streamed and uploads are spooled to TempDir
+	BodyLimit int
+	TempDir   string
+
+	// SwaggerUI serves a Swagger UI page for /openapi.json at /docs
+	SwaggerUI bool
+
+	// MaxJobWait caps the wait query parameter of job submissions; keep it
+	// below WriteTimeout. Zero disables waiting.
+	MaxJobWait time.Duration
+
+	// ShutdownTimeout bounds the whole shutdown: draining requests, stopping
+	// background work and closing connections. Zero waits for all of it.
+	ShutdownTimeout time.Duration
 }
 
 // RedisConfig holds Redis connection configuration
 type RedisConfig struct {
+	// Mode selects the topology: standalone, sentinel or cluster
+	Mode     string
 	Host     string
 	Port     string
 	Password string
 	DB       int
+
+	// Sentinel settings
+	MasterName       string
+	SentinelAddrs    []string
+	SentinelPassword string
+
+	// Cluster seed nodes
+	ClusterAddrs []string
+
+	// Connection pool tuning (zero values use go-redis defaults)
+	PoolSize        int
+	MinIdleConns    int
+	PoolTimeout     time.Duration
+	ConnMaxIdleTime time.Duration
+	DialTimeout     time.Duration
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
 }
 
 // WorkerConfig holds worker pool configuration
@@ -45,20 +94,40 @@ type WorkerConfig struct {
 	ScaleDownThreshold int64
 	CheckInterval      time.Duration
 	ScaleDelay         time.Duration
+	// JobRetention is how long job records are kept for status queries;
+	// queued jobs themselves expire after a day
+	JobRetention time.Duration
 }
 
 // ExternalConfig holds external tools configuration
 type ExternalConfig struct {
-	VipsEnabled       bool
-	FFmpegPath        string
-	LibreOfficePath   string
-	MutoolPath        string
-	TesseractPath     string
-	PyMuPDFScript     string
-	WkHtmlToPdfPath   string
-	PandocPath        string
-	NodeJSPath        string // Path to Node.js for Playwright
-	PlaywrightEnabled bool   // Enable Playwright PDF generation
+	VipsEnabled     bool
+	FFmpegPath      string
+	LibreOfficePath string
+	// LibreOfficeMaxConcurrent bounds simultaneous soffice processes; each
+	// gets its own user profile under LibreOfficeProfileDir
+	LibreOfficeMaxConcurrent int
+	LibreOfficeProfileDir    string
+	// LibreOfficeRetries extra attempts are made after a failed soffice run,
+	// waiting LibreOfficeRetryBackoff before the first and doubling it after.
+	// LibreOfficeRunTimeout kills a hanging run so it can be retried.
+	LibreOfficeRetries      int
+	LibreOfficeRetryBackoff time.Duration
+	LibreOfficeRunTimeout   time.Duration
+	// LibreOfficeWarmUp starts soffice once per profile at server startup so
+	// the first conversion does not pay for the cold start
+	LibreOfficeWarmUp        bool
+	LibreOfficeWarmUpTimeout time.Duration
+	MutoolPath               string
+	TesseractPath            string
+	PyMuPDFScript            string
+	WkHtmlToPdfPath          string
+	PandocPath               string
+	PdftkPath                string
+	NodeJSPath               string // Path to Node.js for Playwright
+	PlaywrightEnabled        bool   // Enable Playwright PDF generation
+	// PDFURLTimeout bounds rendering a remote URL to PDF, including loading it
+	PDFURLTimeout time.Duration
 }
 
 // OCRConfig holds OCR processing configuration
@@ -66,6 +135,52 @@ type OCRConfig struct {
 	Language string
 	DPI      int
 	PSM      int
+	// AutoRotate turns scanned pages upright and deskews them before OCR
+	AutoRotate bool
+}
+
+// LimitsConfig holds upload and output size limits. Per-operation limits
+// override MaxOutputSize when set; zero disables the check.
+type LimitsConfig struct {
+	MaxFileSize int64
+	// VerifyContent rejects uploads whose magic number does not match
+	// their extension or declared type; SniffSize is how many leading bytes
+	// are read for it
+	VerifyContent bool
+	SniffSize     int
+
+	MaxOutputSize      int64
+	MaxImageOutputSize int64
+	MaxVideoOutputSize int64
+	MaxPDFOutputSize   int64
+
+	// MemoryPressurePercent is the share of the memory limit at which
+	// processing requests are held and then shed with 503; 0 disables it
+	MemoryPressurePercent int
+	// MemoryLimit overrides the detected limit (cgroup memory.max or
+	// GOMEMLIMIT) in bytes
+	MemoryLimit int64
+	// MemoryPressureMaxWait is how long a request is held for pressure to
+	// ease before it is rejected
+	MemoryPressureMaxWait time.Duration
+}
+
+// OutputLimit returns the maximum output size in bytes for an operation
+// (image, video or pdf)
+func (l LimitsConfig) OutputLimit(operation string) int64 {
+	var limit int64
+	switch operation {
+	case "image":
+		limit = l.MaxImageOutputSize
+	case "video":
+		limit = l.MaxVideoOutputSize
+	case "pdf":
+		limit = l.MaxPDFOutputSize
+	}
+	if limit > 0 {
+		return limit
+	}
+	return l.MaxOutputSize
 }
 
 // CacheConfig holds cache configuration
@@ -75,6 +190,193 @@ type CacheConfig struct {
 	MaxSize    int64
 	Directory  string
 	CleanupAge time.Duration
+
+	// In-process L1 cache
+	L1Enabled    bool
+	L1Policy     string // lru, lfu or ttl
+	L1MaxEntries int
+	L1MaxBytes   int64
+	L1TTL        time.Duration
+}
+
+// SecurityConfig holds the cross-origin policy for browser clients
+type SecurityConfig struct {
+	// AllowedOrigins are exact origins such as "https://app.example.com";
+	// "*" allows any origin. With no origins or patterns any origin is allowed.
+	AllowedOrigins []string
+	// AllowedOriginPatterns are regular expressions matched against the
+	// whole origin, e.g. `https://[a-z0-9-]+\.example\.com`
+	AllowedOriginPatterns []string
+
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are response headers readable by browser scripts
+	ExposedHeaders []string
+
+	// AllowCredentials lets browsers send cookies and authorization headers;
+	// it cannot be combined with a wildcard origin
+	AllowCredentials bool
+	// PreflightMaxAge is how long browsers may cache a preflight response
+	PreflightMaxAge time.Duration
+}
+
+// LoggingConfig holds request logging settings
+type LoggingConfig struct {
+	// SuccessSampleRate logs one in N successful requests; errors and
+	// client errors are always logged. Values below 2 log every request.
+	SuccessSampleRate int
+}
+
+// DebugCaptureConfig holds settings for logging what requests on selected
+// routes asked for, to debug failing operations without verbose logging
+// everywhere. Only requests of callers holding Role are captured, so it
+// requires authentication.
+type DebugCaptureConfig struct {
+	Enabled bool
+	// Routes are the path prefixes captured, e.g. /api/v1/process/image
+	Routes []string
+	Role   string
+	// SampleRate captures one in N eligible requests
+	SampleRate int
+	// MaxPerMinute caps the captured requests per minute
+	MaxPerMinute int
+	// MaxEntrySize caps the bytes of one logged entry
+	MaxEntrySize int
+	// RedactFields are field, header and query names redacted on top of
+	// the built-in credential names
+	RedactFields []string
+}
+
+// RecorderConfig holds settings for recording failed requests for replay.
+// Recordings include uploaded documents, so keep the limits tight.
+type RecorderConfig struct {
+	Enabled   bool
+	Directory string
+	// MaxInputSize is the largest upload or body kept with a recording
+	MaxInputSize  int64
+	Retention     time.Duration
+	MaxRecordings int
+	// RedactFields are field, header and query names never stored, on top
+	// of the built-in credential names
+	RedactFields []string
+}
+
+// QuotaConfig holds monthly processing limits applied to every tenant.
+// Zero limits are unlimited. Quotas require authentication.
+type QuotaConfig struct {
+	Enabled          bool
+	MonthlyRequests  int64
+	MonthlyBytes     int64
+	MonthlyVideoTime time.Duration
+	// TenantClaim is the token claim identifying the tenant; callers
+	// without it are counted by subject
+	TenantClaim string
+}
+
+// PostProcessConfig selects the post-processors run on conversion results
+type PostProcessConfig struct {
+	// Names lists registered post-processors in the order they run
+	Names []string
+	// Settings are name-prefixed options, e.g. cdn.bucket=assets
+	Settings map[string]string
+	// FailOnError fails the conversion when a post-processor fails;
+	// otherwise the failure is logged and the result still returned
+	FailOnError bool
+}
+
+// FetchConfig holds the guardrails for downloading remote inputs
+type FetchConfig struct {
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds the wait for headers and between reads
+	ReadTimeout time.Duration
+	Timeout     time.Duration
+	MaxSize     int64
+	// AllowedContentTypes restricts downloads, e.g. text/html or image/*
+	AllowedContentTypes []string
+	Retries             int
+	RetryBackoff        time.Duration
+	// AllowPrivateNetworks lets remote inputs reach loopback, private and
+	// link-local addresses
+	AllowPrivateNetworks bool
+}
+
+// StorageConfig selects where processing outputs are persisted. Only the
+// s3 backend exists; an empty backend keeps outputs off object storage.
+type StorageConfig struct {
+	Backend string
+	// Endpoint is the S3 or MinIO URL; empty uses the regional AWS endpoint
+	Endpoint        string
+	Bucket          string
+	Region          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses buckets in the path, as MinIO requires, instead
+	// of the host name
+	PathStyle     bool
+	PresignExpiry time.Duration
+	Timeout       time.Duration
+}
+
+// ValidationConfig holds upload checks beyond their size and type
+type ValidationConfig struct {
+	// ScanForMalware scans uploads with the clamd at ClamdAddress
+	// (host:port or unix:///path) before they are processed
+	ScanForMalware bool
+	ClamdAddress   string
+	ClamdTimeout   time.Duration
+	// ScanFailOpen lets uploads through when clamd cannot scan them;
+	// otherwise they are rejected
+	ScanFailOpen bool
+}
+
+// MaintenanceConfig holds settings for the periodic cleanup of orphaned
+// temp and cache files
+type MaintenanceConfig struct {
+	Enabled  bool
+	Interval time.Duration
+
+	// TempMaxAge is how long an unused temp file is kept; it must exceed
+	// the longest expected job
+	TempMaxAge time.Duration
+	// TempPatterns limit temp cleanup to these file name globs; empty uses
+	// the names created by the processors
+	TempPatterns []string
+	// CacheMaxAge is how long a cache file without a live entry is kept
+	CacheMaxAge time.Duration
+
+	// LeaderTTL is the lease of the instance that sweeps the shared cache
+	// directory; a crashed leader is replaced after at most this long
+	LeaderTTL time.Duration
+}
+
+// AuthConfig holds verification settings for tokens issued by an external
+// identity provider (Auth0, Keycloak, ...)
+type AuthConfig struct {
+	Enabled  bool
+	Issuer   string
+	Audience string
+	JWKSURL  string
+
+	// JWKSCacheTTL is how long fetched signing keys are used before the key
+	// set is fetched again; unknown key IDs trigger an early refresh
+	JWKSCacheTTL time.Duration
+	// ClockSkew is the leeway allowed on exp and nbf
+	ClockSkew time.Duration
+
+	// RolesClaim is the dotted path of the claim listing the caller's roles,
+	// e.g. "roles" or Keycloak's "realm_access.roles"
+	RolesClaim string
+	// RoleMapping maps identity provider roles to internal roles; when set,
+	// unmapped roles are dropped
+	RoleMapping map[string]string
+
+	// PermissionsClaim is the dotted path of the claim listing the caller's
+	// permissions, e.g. "permissions" or "scope"
+	PermissionsClaim string
+	// PermissionMapping maps identity provider permissions to internal ones;
+	// when set, unmapped permissions are dropped
+	PermissionMapping map[string]string
 }
 
 // Load reads configuration from environment variables and returns Config
@@ -86,12 +388,32 @@ func Load() *Config {
 			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 30*time.Second),
 			IdleTimeout:  getDurationEnv("SERVER_IDLE_TIMEOUT", 120*time.Second),
 			Environment:  getEnv("ENVIRONMENT", "development"),
+			BodyLimit:    getIntEnv("SERVER_BODY_LIMIT", 4*1024*1024), // 4MB
+			TempDir:      getEnv("TEMP_DIR", os.TempDir()),
+			SwaggerUI:    getBoolEnv("SWAGGER_UI_ENABLED", false),
+			MaxJobWait:   getDurationEnv("JOB_MAX_WAIT", 20*time.Second),
+
+			ShutdownTimeout: getDurationEnv("SHUTDOWN_TIMEOUT", 30*time.Second),
 		},
 		Redis: RedisConfig{
+			Mode:     getEnv("REDIS_MODE", "standalone"),
 			Host:     getEnv("REDIS_HOST", "localhost"),
 			Port:     getEnv("REDIS_PORT", "6379"),
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getIntEnv("REDIS_DB", 0),
+
+			MasterName:       getEnv("REDIS_MASTER_NAME", ""),
+			SentinelAddrs:    getListEnv("REDIS_SENTINEL_ADDRS"),
+			SentinelPassword: getEnv("REDIS_SENTINEL_PASSWORD", ""),
+			ClusterAddrs:     getListEnv("REDIS_CLUSTER_ADDRS"),
+
+			PoolSize:        getIntEnv("REDIS_POOL_SIZE", 20),
+			MinIdleConns:    getIntEnv("REDIS_MIN_IDLE_CONNS", 2),
+			PoolTimeout:     getDurationEnv("REDIS_POOL_TIMEOUT", 4*time.Second),
+			ConnMaxIdleTime: getDurationEnv("REDIS_CONN_MAX_IDLE_TIME", 30*time.Minute),
+			DialTimeout:     getDurationEnv("REDIS_DIAL_TIMEOUT", 5*time.Second),
+			ReadTimeout:     getDurationEnv("REDIS_READ_TIMEOUT", 3*time.Second),
+			WriteTimeout:    getDurationEnv("REDIS_WRITE_TIMEOUT", 3*time.Second),
 		},
 		Worker: WorkerConfig{
 			MaxConcurrency:     getIntEnv("WORKER_MAX_CONCURRENCY", 10),
@@ -103,23 +425,35 @@ func Load() *Config {
 			ScaleDownThreshold: int64(getIntEnv("WORKER_SCALE_DOWN_THRESHOLD", 2)),
 			CheckInterval:      getDurationEnv("WORKER_CHECK_INTERVAL", 10*time.Second),
 			ScaleDelay:         getDurationEnv("WORKER_SCALE_DELAY", 30*time.Second),
+			JobRetention:       getDurationEnv("JOB_RETENTION", 7*24*time.Hour),
 		},
 		External: ExternalConfig{
-			VipsEnabled:       getBoolEnv("VIPS_ENABLED", true),
-			FFmpegPath:        getEnv("FFMPEG_PATH", "ffmpeg"),
-			LibreOfficePath:   getEnv("LIBREOFFICE_PATH", "soffice"),
-			MutoolPath:        getEnv("MUTOOL_PATH", "mutool"),
-			TesseractPath:     getEnv("TESSERACT_PATH", "tesseract"),
-			PyMuPDFScript:     getEnv("PYMUPDF_SCRIPT", "./scripts"),
-			WkHtmlToPdfPath:   getEnv("WKHTMLTOPDF_PATH", "wkhtmltopdf"),
-			PandocPath:        getEnv("PANDOC_PATH", "pandoc"),
-			NodeJSPath:        getEnv("NODEJS_PATH", "node"),
-			PlaywrightEnabled: getBoolEnv("PLAYWRIGHT_ENABLED", true),
+			VipsEnabled:              getBoolEnv("VIPS_ENABLED", true),
+			FFmpegPath:               getEnv("FFMPEG_PATH", "ffmpeg"),
+			LibreOfficePath:          getEnv("LIBREOFFICE_PATH", "soffice"),
+			LibreOfficeMaxConcurrent: getIntEnv("LIBREOFFICE_MAX_CONCURRENT", 2),
+			LibreOfficeProfileDir:    getEnv("LIBREOFFICE_PROFILE_DIR", ""),
+			LibreOfficeRetries:       getIntEnv("LIBREOFFICE_RETRIES", 2),
+			LibreOfficeRetryBackoff:  getDurationEnv("LIBREOFFICE_RETRY_BACKOFF", 500*time.Millisecond),
+			LibreOfficeRunTimeout:    getDurationEnv("LIBREOFFICE_RUN_TIMEOUT", 0),
+			LibreOfficeWarmUp:        getBoolEnv("LIBREOFFICE_WARMUP", true),
+			LibreOfficeWarmUpTimeout: getDurationEnv("LIBREOFFICE_WARMUP_TIMEOUT", time.Minute),
+			MutoolPath:               getEnv("MUTOOL_PATH", "mutool"),
+			TesseractPath:            getEnv("TESSERACT_PATH", "tesseract"),
+			PyMuPDFScript:            getEnv("PYMUPDF_SCRIPT", "./scripts"),
+			WkHtmlToPdfPath:          getEnv("WKHTMLTOPDF_PATH", "wkhtmltopdf"),
+			PandocPath:               getEnv("PANDOC_PATH", "pandoc"),
+			PdftkPath:                getEnv("PDFTK_PATH", "pdftk"),
+			NodeJSPath:               getEnv("NODEJS_PATH", "node"),
+			PlaywrightEnabled:        getBoolEnv("PLAYWRIGHT_ENABLED", true),
+			PDFURLTimeout:            getDurationEnv("PDF_URL_TIMEOUT", time.Minute),
 		},
 		OCR: OCRConfig{
 			Language: getEnv("OCR_LANGUAGE", "tur+eng"),
 			DPI:      getIntEnv("OCR_DPI", 300),
 			PSM:      getIntEnv("OCR_PSM", 1),
+			// Off by default: it costs an extra tesseract run per page
+			AutoRotate: getBoolEnv("OCR_AUTO_ROTATE", false),
 		},
 		Cache: CacheConfig{
 			Enabled:    getBoolEnv("CACHE_ENABLED", true),
@@ -127,6 +461,116 @@ func Load() *Config {
 			MaxSize:    getInt64Env("CACHE_MAX_SIZE", 1024*1024*1024), // 1GB
 			Directory:  getEnv("CACHE_DIRECTORY", "./cache"),
 			CleanupAge: getDurationEnv("CACHE_CLEANUP_AGE", 7*24*time.Hour), // 7 days
+
+			L1Enabled:    getBoolEnv("CACHE_L1_ENABLED", true),
+			L1Policy:     getEnv("CACHE_L1_POLICY", "lru"),
+			L1MaxEntries: getIntEnv("CACHE_L1_MAX_ENTRIES", 1000),
+			L1MaxBytes:   getInt64Env("CACHE_L1_MAX_BYTES", 64*1024*1024), // 64MB
+			L1TTL:        getDurationEnv("CACHE_L1_TTL", 10*time.Minute),
+		},
+		Limits: LimitsConfig{
+			MaxFileSize:   getInt64Env("MAX_FILE_SIZE", 500*1024*1024), // 500MB
+			VerifyContent: getBoolEnv("VERIFY_UPLOAD_CONTENT", true),
+			SniffSize:     getIntEnv("CONTENT_SNIFF_SIZE", 4096),
+
+			MaxOutputSize:      getInt64Env("MAX_OUTPUT_SIZE", 200*1024*1024),      // 200MB
+			MaxImageOutputSize: getInt64Env("MAX_IMAGE_OUTPUT_SIZE", 50*1024*1024), // 50MB
+			MaxVideoOutputSize: getInt64Env("MAX_VIDEO_OUTPUT_SIZE", 0),
+			MaxPDFOutputSize:   getInt64Env("MAX_PDF_OUTPUT_SIZE", 0),
+
+			MemoryPressurePercent: getIntEnv("MEMORY_PRESSURE_PERCENT", 90),
+			MemoryLimit:           getInt64Env("MEMORY_LIMIT", 0),
+			MemoryPressureMaxWait: getDurationEnv("MEMORY_PRESSURE_MAX_WAIT", 2*time.Second),
+		},
+		Auth: AuthConfig{
+			Enabled:      getBoolEnv("AUTH_ENABLED", false),
+			Issuer:       getEnv("AUTH_ISSUER", ""),
+			Audience:     getEnv("AUTH_AUDIENCE", ""),
+			JWKSURL:      getEnv("AUTH_JWKS_URL", ""),
+			JWKSCacheTTL: getDurationEnv("AUTH_JWKS_CACHE_TTL", time.Hour),
+			ClockSkew:    getDurationEnv("AUTH_CLOCK_SKEW", time.Minute),
+			RolesClaim:   getEnv("AUTH_ROLES_CLAIM", "roles"),
+			RoleMapping:  getMapEnv("AUTH_ROLE_MAPPING"),
+
+			PermissionsClaim:  getEnv("AUTH_PERMISSIONS_CLAIM", "permissions"),
+			PermissionMapping: getMapEnv("AUTH_PERMISSION_MAPPING"),
+		},
+		Security: SecurityConfig{
+			AllowedOrigins:        getListEnv("CORS_ALLOWED_ORIGINS"),
+			AllowedOriginPatterns: getListEnv("CORS_ALLOWED_ORIGIN_PATTERNS"),
+			AllowedMethods:        getListEnvDefault("CORS_ALLOWED_METHODS", "GET", "POST", "PUT", "DELETE", "OPTIONS"),
+			AllowedHeaders:        getListEnvDefault("CORS_ALLOWED_HEADERS", "Origin", "Content-Type", "Accept", "Authorization"),
+			ExposedHeaders:        getListEnv("CORS_EXPOSED_HEADERS"),
+			AllowCredentials:      getBoolEnv("CORS_ALLOW_CREDENTIALS", false),
+			PreflightMaxAge:       getDurationEnv("CORS_MAX_AGE", 10*time.Minute),
+		},
+		Logging: LoggingConfig{
+			SuccessSampleRate: getIntEnv("LOG_SUCCESS_SAMPLE_RATE", 1),
+		},
+		DebugCapture: DebugCaptureConfig{
+			Enabled:      getBoolEnv("DEBUG_CAPTURE_ENABLED", false),
+			Routes:       getListEnv("DEBUG_CAPTURE_ROUTES"),
+			Role:         getEnv("DEBUG_CAPTURE_ROLE", "debug"),
+			SampleRate:   getIntEnv("DEBUG_CAPTURE_SAMPLE_RATE", 1),
+			MaxPerMinute: getIntEnv("DEBUG_CAPTURE_MAX_PER_MINUTE", 60),
+			MaxEntrySize: getIntEnv("DEBUG_CAPTURE_MAX_ENTRY_SIZE", 8*1024),
+			RedactFields: getListEnv("DEBUG_CAPTURE_REDACT_FIELDS"),
+		},
+		Recorder: RecorderConfig{
+			Enabled:       getBoolEnv("RECORDER_ENABLED", false),
+			Directory:     getEnv("RECORDER_DIRECTORY", "./recordings"),
+			MaxInputSize:  getInt64Env("RECORDER_MAX_INPUT_SIZE", 25*1024*1024), // 25MB
+			Retention:     getDurationEnv("RECORDER_RETENTION", 72*time.Hour),
+			MaxRecordings: getIntEnv("RECORDER_MAX_RECORDINGS", 100),
+			RedactFields:  getListEnv("RECORDER_REDACT_FIELDS"),
+		},
+		Quota: QuotaConfig{
+			Enabled:          getBoolEnv("QUOTA_ENABLED", false),
+			MonthlyRequests:  getInt64Env("QUOTA_MONTHLY_REQUESTS", 0),
+			MonthlyBytes:     getInt64Env("QUOTA_MONTHLY_BYTES", 0),
+			MonthlyVideoTime: getDurationEnv("QUOTA_MONTHLY_VIDEO_TIME", 0),
+			TenantClaim:      getEnv("QUOTA_TENANT_CLAIM", "tenant"),
+		},
+		PostProcess: PostProcessConfig{
+			Names:       getListEnv("POSTPROCESSORS"),
+			Settings:    getMapEnv("POSTPROCESS_SETTINGS"),
+			FailOnError: getBoolEnv("POSTPROCESS_FAIL_ON_ERROR", false),
+		},
+		Fetch: FetchConfig{
+			ConnectTimeout:       getDurationEnv("FETCH_CONNECT_TIMEOUT", 10*time.Second),
+			ReadTimeout:          getDurationEnv("FETCH_READ_TIMEOUT", 30*time.Second),
+			Timeout:              getDurationEnv("FETCH_TIMEOUT", 2*time.Minute),
+			MaxSize:              getInt64Env("FETCH_MAX_SIZE", 50*1024*1024), // 50MB
+			AllowedContentTypes:  getListEnv("FETCH_ALLOWED_CONTENT_TYPES"),
+			Retries:              getIntEnv("FETCH_RETRIES", 2),
+			RetryBackoff:         getDurationEnv("FETCH_RETRY_BACKOFF", 500*time.Millisecond),
+			AllowPrivateNetworks: getBoolEnv("FETCH_ALLOW_PRIVATE_NETWORKS", false),
+		},
+		Storage: StorageConfig{
+			Backend:         getEnv("STORAGE_BACKEND", ""),
+			Endpoint:        getEnv("S3_ENDPOINT", ""),
+			Bucket:          getEnv("S3_BUCKET", ""),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Prefix:          getEnv("S3_PREFIX", ""),
+			AccessKeyID:     getEnv("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnv("S3_SECRET_ACCESS_KEY", ""),
+			PathStyle:       getBoolEnv("S3_FORCE_PATH_STYLE", false),
+			PresignExpiry:   getDurationEnv("S3_PRESIGN_EXPIRY", 15*time.Minute),
+			Timeout:         getDurationEnv("S3_TIMEOUT", time.Minute),
+		},
+		Validation: ValidationConfig{
+			ScanForMalware: getBoolEnv("SCAN_FOR_MALWARE", false),
+			ClamdAddress:   getEnv("CLAMD_ADDRESS", "localhost:3310"),
+			ClamdTimeout:   getDurationEnv("CLAMD_TIMEOUT", 30*time.Second),
+			ScanFailOpen:   getBoolEnv("CLAMD_FAIL_OPEN", false),
+		},
+		Maintenance: MaintenanceConfig{
+			Enabled:      getBoolEnv("MAINTENANCE_ENABLED", true),
+			Interval:     getDurationEnv("MAINTENANCE_INTERVAL", time.Hour),
+			TempMaxAge:   getDurationEnv("MAINTENANCE_TEMP_MAX_AGE", 6*time.Hour),
+			TempPatterns: getListEnv("MAINTENANCE_TEMP_PATTERNS"),
+			CacheMaxAge:  getDurationEnv("MAINTENANCE_CACHE_MAX_AGE", time.Hour),
+			LeaderTTL:    getDurationEnv("MAINTENANCE_LEADER_TTL", 2*time.Hour),
 		},
 	}
 }
@@ -140,6 +584,48 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getListEnv(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getListEnvDefault is getListEnv with a fallback for unset variables
+func getListEnvDefault(key string, defaultValue ...string) []string {
+	if items := getListEnv(key); items != nil {
+		return items
+	}
+	return defaultValue
+}
+
+// getMapEnv parses "key=value,key=value" pairs
+func getMapEnv(key string) map[string]string {
+	items := getListEnv(key)
+	if len(items) == 0 {
+		return nil
+	}
+
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok {
+			log.Printf("Warning: Invalid entry for %s: %s, expected key=value", key, item)
+			continue
+		}
+		values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return values
+}
+
 func getIntEnv(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -207,6 +693,12 @@ func (c *Config) Validate() error {
 		// VIPS is optional, so we don't fail if not found
 	}
 
+	if c.Server.TempDir != "" {
+		if err := os.MkdirAll(c.Server.TempDir, 0755); err != nil {
+			log.Printf("Warning: Failed to create temp directory %s: %v", c.Server.TempDir, err)
+		}
+	}
+
 	// Cache directory validation
 	if c.Cache.Enabled {
 		if err := os.MkdirAll(c.Cache.Directory, 0755); err != nil {
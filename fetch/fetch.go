@@ -0,0 +1,317 @@
+// Package fetch downloads remote inputs with guardrails: connect, read and
+// overall timeouts, a download size cap, allowed content types, retries of
+// transient failures and a refusal to connect to internal addresses.
+// Downloads are streamed to a temp file.
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrTooLarge is wrapped when a download exceeds the size cap
+	ErrTooLarge = errors.New("remote input too large")
+	// ErrContentType is wrapped when the response has a disallowed type
+	ErrContentType = errors.New("remote input content type not allowed")
+	// ErrStalled is wrapped when no data arrives within the read timeout
+	ErrStalled = errors.New("remote input stalled")
+	// ErrBlockedAddress is wrapped when a host resolves to an internal address
+	ErrBlockedAddress = errors.New("remote input address not allowed")
+)
+
+// StatusError reports an unsuccessful HTTP status
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("fetching %s returned status %d", e.URL, e.StatusCode)
+}
+
+// Config holds the fetch guardrails. Zero values fall back to defaults.
+type Config struct {
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds the wait for response headers and between reads
+	ReadTimeout time.Duration
+	// Timeout bounds a whole attempt
+	Timeout time.Duration
+	MaxSize int64
+	// AllowedContentTypes are media types such as text/html or image/*;
+	// empty allows any
+	AllowedContentTypes []string
+	// Retries is the number of extra attempts after network errors, 429
+	// and 5xx responses
+	Retries      int
+	RetryBackoff time.Duration
+	TempDir      string
+	// AllowPrivateNetworks lets downloads reach loopback, private,
+	// link-local and other internal addresses. Off by default, so a URL
+	// cannot be used to read internal services or cloud metadata.
+	AllowPrivateNetworks bool
+}
+
+// Defaults applied to zero Config values
+const (
+	DefaultConnectTimeout = 10 * time.Second
+	DefaultReadTimeout    = 30 * time.Second
+	DefaultTimeout        = 2 * time.Minute
+	DefaultMaxSize        = 50 * 1024 * 1024
+	DefaultRetryBackoff   = 500 * time.Millisecond
+)
+
+// Result is a completed download. The caller removes Path.
+type Result struct {
+	Path        string
+	ContentType string
+	Size        int64
+	// URL is the final URL after redirects
+	URL string
+}
+
+// Fetcher downloads remote inputs
+type Fetcher struct {
+	config Config
+	client *http.Client
+}
+
+// New creates a fetcher with its own transport
+func New(cfg Config) *Fetcher {
+	if cfg.ConnectTimeout <= 0 {
+		cfg.ConnectTimeout = DefaultConnectTimeout
+	}
+	if cfg.ReadTimeout <= 0 {
+		cfg.ReadTimeout = DefaultReadTimeout
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = DefaultMaxSize
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = DefaultRetryBackoff
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.ConnectTimeout}
+	// A proxy would make the connection on our behalf, out of reach of the
+	// address check, so downloads guarded by it go direct
+	var proxy func(*http.Request) (*url.URL, error)
+	if cfg.AllowPrivateNetworks {
+		proxy = http.ProxyFromEnvironment
+	} else {
+		dialer.Control = guardAddress
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.ConnectTimeout,
+		ResponseHeaderTimeout: cfg.ReadTimeout,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	return &Fetcher{config: cfg, client: &http.Client{Transport: transport}}
+}
+
+// Config returns the effective configuration
+func (f *Fetcher) Config() Config {
+	return f.config
+}
+
+// Fetch downloads rawURL to a temp file, retrying transient failures
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Result, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid remote input URL %q: only http and https URLs are supported", rawURL)
+	}
+
+	backoff := f.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		result, err := f.attempt(ctx, rawURL)
+		if err == nil || attempt >= f.config.Retries || !retryable(err) || ctx.Err() != nil {
+			return result, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func (f *Fetcher) attempt(ctx context.Context, rawURL string) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	// Reject from the headers, before any of the body is read
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{URL: rawURL, StatusCode: resp.StatusCode}
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !f.allowed(contentType) {
+		return nil, fmt.Errorf("%w: %q from %s", ErrContentType, contentType, rawURL)
+	}
+	if resp.ContentLength > f.config.MaxSize {
+		return nil, fmt.Errorf("%w: %d bytes from %s exceed %d", ErrTooLarge, resp.ContentLength, rawURL, f.config.MaxSize)
+	}
+
+	file, err := os.CreateTemp(f.config.TempDir, "fetch-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	body := &idleReader{reader: resp.Body, timeout: f.config.ReadTimeout, cancel: cancel}
+	size, err := io.Copy(file, io.LimitReader(body, f.config.MaxSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case body.stalled():
+		err = fmt.Errorf("%w: no data from %s for %s", ErrStalled, rawURL, f.config.ReadTimeout)
+	case err != nil:
+		err = fmt.Errorf("failed to download %s: %w", rawURL, err)
+	case size > f.config.MaxSize:
+		err = fmt.Errorf("%w: download from %s exceeds %d bytes", ErrTooLarge, rawURL, f.config.MaxSize)
+	}
+	body.stop()
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return &Result{
+		Path:        file.Name(),
+		ContentType: contentType,
+		Size:        size,
+		URL:         resp.Request.URL.String(),
+	}, nil
+}
+
+// allowed matches the media type against the allowed types, which may end
+// in /* to allow a whole family
+func (f *Fetcher) allowed(contentType string) bool {
+	if len(f.config.AllowedContentTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, allowed := range f.config.AllowedContentTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mediaType {
+			return true
+		}
+		if family, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, family+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable reports whether another attempt may succeed
+func retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode == http.StatusTooManyRequests || status.StatusCode >= 500
+	}
+	return !errors.Is(err, ErrTooLarge) && !errors.Is(err, ErrContentType) && !errors.Is(err, ErrBlockedAddress)
+}
+
+// guardAddress refuses connections to internal addresses. It runs on the
+// resolved address of every dial, redirects included, so a host cannot
+// pass a lookup and then rebind to an internal address.
+func guardAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || blocked(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, internal to providers
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// blocked reports whether ip is internal: loopback, private, link-local
+// (which holds cloud metadata endpoints such as 169.254.169.254), shared
+// address space, multicast or unspecified
+func blocked(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ip[0] == 0 || sharedAddressSpace.Contains(ip) {
+			return true
+		}
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// idleReader cancels the download when a read makes no progress within the
+// timeout, so slow-drip responses cannot hold a worker until the overall
+// timeout
+type idleReader struct {
+	reader  io.Reader
+	timeout time.Duration
+	cancel  context.CancelFunc
+
+	once    sync.Once
+	timer   *time.Timer
+	mu      sync.Mutex
+	expired bool
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	r.once.Do(func() {
+		r.timer = time.AfterFunc(r.timeout, func() {
+			r.mu.Lock()
+			r.expired = true
+			r.mu.Unlock()
+			r.cancel()
+		})
+	})
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
+func (r *idleReader) stalled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.expired
+}
+
+func (r *idleReader) stop() {
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+}
@@ -0,0 +1,227 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFetcher(t *testing.T, cfg Config) *Fetcher {
+	t.Helper()
+	cfg.TempDir = t.TempDir()
+	// Test servers listen on loopback
+	cfg.AllowPrivateNetworks = true
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = time.Millisecond
+	}
+	return New(cfg)
+}
+
+func assertNoTempFiles(t *testing.T, f *Fetcher) {
+	t.Helper()
+	entries, err := os.ReadDir(f.Config().TempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "partial downloads must be removed")
+}
+
+func TestFetchStreamsToTempFile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html>ok</html>"))
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(t, Config{AllowedContentTypes: []string{"text/html"}})
+	result, err := f.Fetch(context.Background(), server.URL+"/page")
+	require.NoError(t, err)
+	defer os.Remove(result.Path)
+
+	data, err := os.ReadFile(result.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "<html>ok</html>", string(data))
+	assert.Equal(t, int64(15), result.Size)
+	assert.Equal(t, server.URL+"/page", result.URL)
+	assert.Equal(t, "text/html; charset=utf-8", result.ContentType)
+}
+
+func TestFetchRejectsDeclaredOversizedResponseBeforeReading(t *testing.T) {
+	var written atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(10<<20))
+		w.WriteHeader(http.StatusOK)
+		chunk := make([]byte, 32*1024)
+		for i := 0; i < 320; i++ {
+			n, err := w.Write(chunk)
+			written.Add(int64(n))
+			if err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(t, Config{MaxSize: 1024, Retries: 3})
+	_, err := f.Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assert.Less(t, written.Load(), int64(10<<20), "the body must not be downloaded")
+	assertNoTempFiles(t, f)
+}
+
+func TestFetchStopsUndeclaredOversizedResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Flushing before the body forces chunked encoding without a length
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		w.Write([]byte(strings.Repeat("x", 4096)))
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(t, Config{MaxSize: 1024})
+	_, err := f.Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	assertNoTempFiles(t, f)
+}
+
+func TestFetchRejectsDisallowedContentType(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("binary"))
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(t, Config{AllowedContentTypes: []string{"text/html", "image/*"}, Retries: 2})
+	_, err := f.Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrContentType)
+	assert.Equal(t, int64(1), requests.Load(), "type errors are not retried")
+
+	assert.True(t, f.allowed("image/png"))
+	assert.False(t, f.allowed("imagex/png"))
+}
+
+func TestFetchAbortsStalledResponse(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(t, Config{ReadTimeout: 50 * time.Millisecond, Timeout: 10 * time.Second})
+	start := time.Now()
+	_, err := f.Fetch(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrStalled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assertNoTempFiles(t, f)
+}
+
+func TestFetchTimesOutWaitingForHeaders(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(t, Config{ReadTimeout: 50 * time.Millisecond})
+	start := time.Now()
+	_, err := f.Fetch(context.Background(), server.URL)
+	assert.ErrorContains(t, err, "timeout awaiting response headers")
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestFetchRetriesTransientFailures(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(t, Config{Retries: 2})
+	result, err := f.Fetch(context.Background(), server.URL)
+	require.NoError(t, err)
+	os.Remove(result.Path)
+	assert.Equal(t, int64(3), requests.Load())
+}
+
+func TestFetchDoesNotRetryClientErrors(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	f := newTestFetcher(t, Config{Retries: 2})
+	_, err := f.Fetch(context.Background(), server.URL)
+	var status *StatusError
+	require.ErrorAs(t, err, &status)
+	assert.Equal(t, http.StatusNotFound, status.StatusCode)
+	assert.Equal(t, int64(1), requests.Load())
+}
+
+func TestFetchRejectsUnsupportedSchemes(t *testing.T) {
+	f := newTestFetcher(t, Config{})
+	for _, rawURL := range []string{"file:///etc/passwd", "ftp://example.com/a", "not a url", "http://"} {
+		_, err := f.Fetch(context.Background(), rawURL)
+		assert.ErrorContains(t, err, "only http and https", rawURL)
+	}
+}
+
+func TestFetchRefusesInternalAddresses(t *testing.T) {
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+
+	f := New(Config{TempDir: t.TempDir(), Retries: 2, RetryBackoff: time.Millisecond})
+	_, err := f.Fetch(context.Background(), server.URL)
+	assert.True(t, errors.Is(err, ErrBlockedAddress), "got %v", err)
+	assert.Zero(t, requests.Load())
+	assertNoTempFiles(t, f)
+
+	// Host names are checked on the address they resolve to
+	_, err = f.Fetch(context.Background(), strings.Replace(server.URL, "127.0.0.1", "localhost", 1))
+	assert.True(t, errors.Is(err, ErrBlockedAddress), "got %v", err)
+	assert.Zero(t, requests.Load())
+}
+
+func TestBlockedAddresses(t *testing.T) {
+	for _, address := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"100.64.0.1", "0.0.0.0", "224.0.0.1", "::1", "::", "fd00:ec2::254",
+		"fe80::1", "::ffff:127.0.0.1", "::ffff:169.254.169.254",
+	} {
+		assert.True(t, blocked(net.ParseIP(address)), address)
+	}
+	for _, address := range []string{"93.184.216.34", "8.8.8.8", "100.128.0.1", "2606:2800:220:1::1"} {
+		assert.False(t, blocked(net.ParseIP(address)), address)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"documents-worker/config"
 	"documents-worker/queue"
 	"os/exec"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,6 +14,7 @@ import (
 type HealthChecker struct {
 	config           *config.Config
 	queue            *queue.RedisQueue
+	cacheMu          sync.Mutex
 	cachedServices   map[string]ServiceInfo
 	lastServiceCheck time.Time
 	serviceCheckTTL  time.Duration
@@ -94,6 +96,9 @@ func (h *HealthChecker) GetHealthStatus() HealthStatus {
 }
 
 func (h *HealthChecker) checkServicesWithCache(status *HealthStatus) {
+	h.cacheMu.Lock()
+	defer h.cacheMu.Unlock()
+
 	// Check if we need to refresh cached services
 	if time.Since(h.lastServiceCheck) > h.serviceCheckTTL || len(h.cachedServices) == 0 {
 		h.refreshServiceCache()
@@ -0,0 +1,289 @@
+package health
+
+import (
+	"context"
+	"documents-worker/libreoffice"
+	"documents-worker/maintenance"
+	"documents-worker/memory"
+	"documents-worker/queue"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// Component states, from best to worst
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusDown     = "down"
+)
+
+// SlowRedisPing is the ping latency above which Redis degrades the service
+var SlowRedisPing = 250 * time.Millisecond
+
+// statusRank orders component states so the worst one wins
+var statusRank = map[string]int{StatusOK: 0, StatusDegraded: 1, StatusDown: 2}
+
+// Component is the state of one subsystem in the aggregated status
+type Component struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Critical components take the whole service down when they are down;
+	// other components only degrade it
+	Critical bool                   `json:"critical"`
+	Message  string                 `json:"message,omitempty"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Duration string                 `json:"duration"`
+}
+
+// AggregateStatus is the state of every subsystem and the overall verdict
+type AggregateStatus struct {
+	Status     string      `json:"status"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Uptime     string      `json:"uptime"`
+	Components []Component `json:"components"`
+}
+
+// Check reports the state of a subsystem. Name and Critical are filled in
+// by the aggregator.
+type Check func(ctx context.Context) Component
+
+type registeredCheck struct {
+	name     string
+	critical bool
+	check    Check
+}
+
+// Aggregator runs the registered checks concurrently and combines them
+// into one verdict
+type Aggregator struct {
+	timeout time.Duration
+	checks  []registeredCheck
+}
+
+// NewAggregator creates an aggregator giving each check at most timeout
+func NewAggregator(timeout time.Duration) *Aggregator {
+	return &Aggregator{timeout: timeout}
+}
+
+// Add registers a check under name. Critical checks that report down take
+// the overall status down.
+func (a *Aggregator) Add(name string, critical bool, check Check) *Aggregator {
+	a.checks = append(a.checks, registeredCheck{name: name, critical: critical, check: check})
+	return a
+}
+
+// Status runs every check and returns the components sorted by name
+func (a *Aggregator) Status(ctx context.Context) AggregateStatus {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	components := make([]Component, len(a.checks))
+	var wg sync.WaitGroup
+	for i, registered := range a.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started := time.Now()
+			component := registered.check(ctx)
+			if _, known := statusRank[component.Status]; !known {
+				component.Status = StatusDown
+			}
+			component.Name = registered.name
+			component.Critical = registered.critical
+			component.Duration = time.Since(started).String()
+			components[i] = component
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(components, func(i, j int) bool { return components[i].Name < components[j].Name })
+	return AggregateStatus{
+		Status:     Overall(components),
+		Timestamp:  time.Now(),
+		Uptime:     time.Since(startTime).String(),
+		Components: components,
+	}
+}
+
+// Overall returns the verdict for a set of components: down when a critical
+// component is down, degraded when any other component is not ok, ok
+// otherwise
+func Overall(components []Component) string {
+	verdict := StatusOK
+	for _, component := range components {
+		status := component.Status
+		if status == StatusDown && !component.Critical {
+			status = StatusDegraded
+		}
+		if statusRank[status] > statusRank[verdict] {
+			verdict = status
+		}
+	}
+	return verdict
+}
+
+// Handler serves the aggregated status. Only a down verdict answers 503,
+// so degraded instances stay in rotation.
+func (a *Aggregator) Handler(c *fiber.Ctx) error {
+	status := a.Status(c.UserContext())
+	code := fiber.StatusOK
+	if status.Status == StatusDown {
+		code = fiber.StatusServiceUnavailable
+	}
+	return c.Status(code).JSON(status)
+}
+
+// RedisCheck pings Redis and reports the latency. Slow responses degrade
+// the service.
+func RedisCheck(client redis.UniversalClient) Check {
+	return func(ctx context.Context) Component {
+		if client == nil {
+			return Component{Status: StatusDown, Message: "Redis client not initialized"}
+		}
+		started := time.Now()
+		if err := client.Ping(ctx).Err(); err != nil {
+			return Component{Status: StatusDown, Message: err.Error()}
+		}
+		latency := time.Since(started)
+		component := Component{
+			Status:  StatusOK,
+			Details: map[string]interface{}{"latency_ms": float64(latency.Microseconds()) / 1000},
+		}
+		if latency >= SlowRedisPing {
+			component.Status = StatusDegraded
+			component.Message = fmt.Sprintf("ping took %s", latency.Round(time.Millisecond))
+		}
+		return component
+	}
+}
+
+// QueueCheck reads the job queue statistics
+func QueueCheck(redisQueue *queue.RedisQueue) Check {
+	return func(ctx context.Context) Component {
+		if redisQueue == nil {
+			return Component{Status: StatusDown, Message: "Queue not initialized"}
+		}
+		stats, err := redisQueue.GetQueueStats(ctx)
+		if err != nil {
+			return Component{Status: StatusDown, Message: err.Error()}
+		}
+		details := make(map[string]interface{}, len(stats))
+		for name, value := range stats {
+			details[name] = value
+		}
+		return Component{Status: StatusOK, Details: details}
+	}
+}
+
+// ToolsCheck reports the external tools found by the health checker. Tools
+// that are disabled in the configuration are listed but not counted as
+// missing.
+func ToolsCheck(h *HealthChecker) Check {
+	return func(ctx context.Context) Component {
+		status := HealthStatus{Services: make(map[string]ServiceInfo)}
+		h.checkServicesWithCache(&status)
+
+		component := Component{Status: StatusOK, Details: make(map[string]interface{}, len(status.Services))}
+		var missing []string
+		for name, service := range status.Services {
+			component.Details[name] = service.Status
+			if !service.Available && service.Status != "disabled" {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			component.Status = StatusDegraded
+			component.Message = fmt.Sprintf("unavailable: %v", missing)
+		}
+		return component
+	}
+}
+
+// MemoryCheck reports memory use against the limit. A nil gauge means
+// pressure tracking is disabled; the Go heap is reported instead.
+func MemoryCheck(gauge *memory.Gauge) Check {
+	return func(ctx context.Context) Component {
+		if gauge == nil {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			return Component{
+				Status:  StatusOK,
+				Message: "memory pressure tracking disabled",
+				Details: map[string]interface{}{
+					"heap_alloc_bytes": stats.HeapAlloc,
+					"sys_bytes":        stats.Sys,
+					"ratio":            float64(stats.HeapAlloc) / float64(stats.Sys),
+				},
+			}
+		}
+		sample := gauge.Sample()
+		component := Component{
+			Status: StatusOK,
+			Details: map[string]interface{}{
+				"used_bytes":  sample.Used,
+				"limit_bytes": sample.Limit,
+				"ratio":       sample.Ratio(),
+				"threshold":   gauge.Threshold(),
+			},
+		}
+		if gauge.UnderPressure() {
+			component.Status = StatusDegraded
+			component.Message = "memory under pressure, processing requests are being shed"
+		}
+		return component
+	}
+}
+
+// LibreOfficeCheck reports the usage of the LibreOffice pool, the
+// process-wide one when pool is nil. A saturated pool queues new
+// conversions, so it degrades the service.
+func LibreOfficeCheck(pool *libreoffice.Pool) Check {
+	return func(ctx context.Context) Component {
+		current := pool
+		if current == nil {
+			current = libreoffice.Default()
+		}
+		active, capacity := current.Active(), current.Capacity()
+		component := Component{
+			Status: StatusOK,
+			Details: map[string]interface{}{
+				"active":   active,
+				"capacity": capacity,
+			},
+		}
+		if active >= capacity {
+			component.Status = StatusDegraded
+			component.Message = "all LibreOffice slots are busy"
+		}
+		return component
+	}
+}
+
+// ClusterCheck reports how this instance takes part in the deployment: the
+// Redis mode and whether it leads the shared maintenance. A nil scheduler
+// means maintenance is disabled.
+func ClusterCheck(redisMode string, scheduler *maintenance.Scheduler) Check {
+	if redisMode == "" {
+		redisMode = "standalone"
+	}
+	return func(ctx context.Context) Component {
+		host, _ := os.Hostname()
+		details := map[string]interface{}{
+			"redis_mode":          redisMode,
+			"host":                host,
+			"maintenance_enabled": scheduler != nil,
+		}
+		if scheduler != nil {
+			details["maintenance_leader"] = scheduler.Metrics().Leader()
+		}
+		return Component{Status: StatusOK, Details: details}
+	}
+}
@@ -0,0 +1,262 @@
+package health
+
+import (
+	"bufio"
+	"context"
+	"documents-worker/config"
+	"documents-worker/libreoffice"
+	"documents-worker/memory"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedCheck reports the given state
+func fixedCheck(status, message string) Check {
+	return func(ctx context.Context) Component {
+		return Component{Status: status, Message: message}
+	}
+}
+
+func TestOverallVerdict(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []Component
+		expected   string
+	}{
+		{"no components", nil, StatusOK},
+		{"all ok", []Component{{Status: StatusOK, Critical: true}, {Status: StatusOK}}, StatusOK},
+		{"optional degraded", []Component{{Status: StatusOK, Critical: true}, {Status: StatusDegraded}}, StatusDegraded},
+		{"critical degraded", []Component{{Status: StatusDegraded, Critical: true}, {Status: StatusOK}}, StatusDegraded},
+		{"optional down", []Component{{Status: StatusOK, Critical: true}, {Status: StatusDown}}, StatusDegraded},
+		{"critical down", []Component{{Status: StatusDown, Critical: true}, {Status: StatusOK}}, StatusDown},
+		{"critical down wins over degraded", []Component{{Status: StatusDegraded}, {Status: StatusDown, Critical: true}, {Status: StatusDown}}, StatusDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Overall(tt.components))
+		})
+	}
+}
+
+func TestAggregatorStatus(t *testing.T) {
+	aggregator := NewAggregator(time.Second).
+		Add("tools", false, fixedCheck(StatusDegraded, "unavailable: [vips]")).
+		Add("redis", true, fixedCheck(StatusOK, "")).
+		Add("memory", false, fixedCheck("unknown", "")).
+		Add("queue", true, func(ctx context.Context) Component {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			return Component{Status: StatusOK, Details: map[string]interface{}{"pending": int64(3)}}
+		})
+
+	status := aggregator.Status(context.Background())
+	assert.Equal(t, StatusDegraded, status.Status)
+	require.Len(t, status.Components, 4)
+
+	names := make([]string, len(status.Components))
+	for i, component := range status.Components {
+		names[i] = component.Name
+		assert.NotEmpty(t, component.Duration)
+	}
+	assert.Equal(t, []string{"memory", "queue", "redis", "tools"}, names)
+
+	// Unknown states count as down
+	assert.Equal(t, StatusDown, status.Components[0].Status)
+	assert.False(t, status.Components[0].Critical)
+	assert.True(t, status.Components[1].Critical)
+	assert.Equal(t, int64(3), status.Components[1].Details["pending"])
+	assert.Equal(t, "unavailable: [vips]", status.Components[3].Message)
+}
+
+func TestAggregatorHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		redis    string
+		tools    string
+		expected string
+		code     int
+	}{
+		{"healthy", StatusOK, StatusOK, StatusOK, fiber.StatusOK},
+		{"degraded stays in rotation", StatusOK, StatusDown, StatusDegraded, fiber.StatusOK},
+		{"critical component down", StatusDown, StatusOK, StatusDown, fiber.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aggregator := NewAggregator(time.Second).
+				Add("redis", true, fixedCheck(tt.redis, "")).
+				Add("tools", false, fixedCheck(tt.tools, ""))
+			app := fiber.New()
+			app.Get("/health/status", aggregator.Handler)
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/health/status", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, resp.StatusCode)
+
+			var status AggregateStatus
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+			assert.Equal(t, tt.expected, status.Status)
+			assert.Len(t, status.Components, 2)
+		})
+	}
+}
+
+func TestComponentChecks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("redis unreachable", func(t *testing.T) {
+		client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+		defer client.Close()
+		component := RedisCheck(client)(ctx)
+		assert.Equal(t, StatusDown, component.Status)
+		assert.NotEmpty(t, component.Message)
+	})
+
+	t.Run("queue not initialized", func(t *testing.T) {
+		assert.Equal(t, StatusDown, QueueCheck(nil)(ctx).Status)
+	})
+
+	t.Run("tools", func(t *testing.T) {
+		cfg := getTestHealthConfig()
+		cfg.External.TesseractPath = "/nonexistent/tesseract"
+		component := ToolsCheck(NewHealthChecker(cfg, nil))(ctx)
+		assert.Equal(t, StatusDegraded, component.Status)
+		assert.Equal(t, "unavailable: [tesseract]", component.Message)
+		assert.Equal(t, "disabled", component.Details["vips"])
+		assert.Equal(t, "available", component.Details["ffmpeg"])
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		untracked := MemoryCheck(nil)(ctx)
+		assert.Equal(t, StatusOK, untracked.Status)
+		assert.NotZero(t, untracked.Details["heap_alloc_bytes"])
+		assert.Greater(t, untracked.Details["ratio"], 0.0)
+		assert.LessOrEqual(t, untracked.Details["ratio"], 1.0)
+
+		sample := memory.Sample{Used: 50, Limit: 100}
+		gauge := memory.NewGauge(func() (memory.Sample, error) { return sample, nil }, 0.9)
+		gauge.Refresh()
+		component := MemoryCheck(gauge)(ctx)
+		assert.Equal(t, StatusOK, component.Status)
+		assert.Equal(t, 0.5, component.Details["ratio"])
+
+		sample.Used = 95
+		gauge.Refresh()
+		assert.Equal(t, StatusDegraded, MemoryCheck(gauge)(ctx).Status)
+	})
+
+	t.Run("libreoffice", func(t *testing.T) {
+		component := LibreOfficeCheck(libreoffice.NewPool(2, t.TempDir()))(ctx)
+		assert.Equal(t, StatusOK, component.Status)
+		assert.Equal(t, 0, component.Details["active"])
+		assert.Equal(t, 2, component.Details["capacity"])
+	})
+
+	t.Run("cluster", func(t *testing.T) {
+		component := ClusterCheck("", nil)(ctx)
+		assert.Equal(t, StatusOK, component.Status)
+		assert.Equal(t, "standalone", component.Details["redis_mode"])
+		assert.Equal(t, false, component.Details["maintenance_enabled"])
+		assert.NotContains(t, component.Details, "maintenance_leader")
+	})
+}
+
+// pingServer answers PING like Redis after delay and rejects every other
+// command, which makes the client skip the handshake
+func pingServer(t *testing.T, delay time.Duration) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					// Commands arrive as arrays of bulk strings
+					header, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					count, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+					var command []string
+					for i := 0; i < count; i++ {
+						if _, err := reader.ReadString('\n'); err != nil {
+							return
+						}
+						arg, err := reader.ReadString('\n')
+						if err != nil {
+							return
+						}
+						command = append(command, strings.TrimSpace(arg))
+					}
+					reply := "-ERR unknown command\r\n"
+					if len(command) > 0 && strings.EqualFold(command[0], "ping") {
+						time.Sleep(delay)
+						reply = "+PONG\r\n"
+					}
+					conn.Write([]byte(reply))
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRedisCheckLatency(t *testing.T) {
+	defer func(previous time.Duration) { SlowRedisPing = previous }(SlowRedisPing)
+	SlowRedisPing = 50 * time.Millisecond
+
+	check := func(delay time.Duration) Component {
+		client := redis.NewClient(&redis.Options{Addr: pingServer(t, delay), MaxRetries: -1})
+		defer client.Close()
+		return RedisCheck(client)(context.Background())
+	}
+
+	fast := check(0)
+	assert.Equal(t, StatusOK, fast.Status)
+	assert.Less(t, fast.Details["latency_ms"], 50.0)
+
+	slow := check(100 * time.Millisecond)
+	assert.Equal(t, StatusDegraded, slow.Status)
+	assert.GreaterOrEqual(t, slow.Details["latency_ms"], 100.0)
+	assert.Contains(t, slow.Message, "ping took")
+}
+
+// The aggregated verdict over a mix of real checks
+func TestAggregatorMixedComponents(t *testing.T) {
+	cfg := &config.Config{External: config.ExternalConfig{
+		FFmpegPath:      "echo",
+		LibreOfficePath: "echo",
+		MutoolPath:      "/nonexistent/mutool",
+		TesseractPath:   "echo",
+	}}
+
+	status := NewAggregator(time.Second).
+		Add("queue", true, QueueCheck(nil)).
+		Add("tools", false, ToolsCheck(NewHealthChecker(cfg, nil))).
+		Status(context.Background())
+	assert.Equal(t, StatusDown, status.Status)
+
+	status = NewAggregator(time.Second).
+		Add("memory", true, MemoryCheck(nil)).
+		Add("tools", false, ToolsCheck(NewHealthChecker(cfg, nil))).
+		Status(context.Background())
+	assert.Equal(t, StatusDegraded, status.Status)
+}
@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"documents-worker/client"
+	"documents-worker/loadtest"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// getBenchCommand returns the bench command
+func (cli *CLI) getBenchCommand() *cobra.Command {
+	benchCmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Run an end-to-end load test against a server",
+		Long: `Drive a running Documents Worker server with concurrent requests and report
+throughput, latency percentiles and error rates.
+
+Targets:
+  convert  upload --file to the image conversion endpoint (default)
+  health   call the health endpoint`,
+		Example: `  documents-worker bench --file sample.jpg --concurrency 8 --duration 30s
+  documents-worker bench --target health --requests 1000 --output json`,
+		RunE: cli.runBench,
+	}
+
+	benchCmd.Flags().String("server", "http://localhost:3001", "Server base URL")
+	benchCmd.Flags().String("target", "convert", "Endpoint to exercise (convert, health)")
+	benchCmd.Flags().StringP("file", "f", "", "Sample file to upload")
+	benchCmd.Flags().String("format", "webp", "Output format for image conversion")
+	benchCmd.Flags().IntP("concurrency", "c", 4, "Number of concurrent clients")
+	benchCmd.Flags().DurationP("duration", "d", 10*time.Second, "Test duration")
+	benchCmd.Flags().IntP("requests", "n", 0, "Stop after this many requests (0 = unlimited)")
+	benchCmd.Flags().Duration("timeout", 60*time.Second, "Per-request timeout")
+	benchCmd.Flags().StringP("output", "o", "text", "Report format (text, json)")
+
+	return benchCmd
+}
+
+// runBench handles the bench command
+func (cli *CLI) runBench(cmd *cobra.Command, args []string) error {
+	server, _ := cmd.Flags().GetString("server")
+	target, _ := cmd.Flags().GetString("target")
+	filePath, _ := cmd.Flags().GetString("file")
+	format, _ := cmd.Flags().GetString("format")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	duration, _ := cmd.Flags().GetDuration("duration")
+	requests, _ := cmd.Flags().GetInt("requests")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	output, _ := cmd.Flags().GetString("output")
+
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unsupported output format: %s", output)
+	}
+
+	c := client.NewClient(client.Config{BaseURL: server, Timeout: timeout})
+
+	var op loadtest.Operation
+	switch target {
+	case "convert":
+		if filePath == "" {
+			return fmt.Errorf("--file is required for the convert target")
+		}
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read sample file: %w", err)
+		}
+		filename := filepath.Base(filePath)
+
+		op = func(ctx context.Context) error {
+			out, err := c.ConvertImage(ctx, bytes.NewReader(data), filename, format)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+			_, err = io.Copy(io.Discard, out)
+			return err
+		}
+	case "health":
+		op = func(ctx context.Context) error {
+			_, err := c.Health(ctx)
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported bench target: %s", target)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if output == "text" {
+		fmt.Printf("🚀 Benchmarking %s (%s) with %d clients for %s...\n", server, target, concurrency, duration)
+	}
+
+	report := loadtest.Run(ctx, loadtest.Config{
+		Concurrency: concurrency,
+		Duration:    duration,
+		MaxRequests: requests,
+	}, op)
+
+	if output == "json" {
+		data, err := report.JSON()
+		if err != nil {
+			return fmt.Errorf("failed to format report: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Printf("\n📊 Results:\n%s", report.String())
+	return nil
+}
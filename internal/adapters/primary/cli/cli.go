@@ -7,6 +7,8 @@ import (
 	"documents-worker/config"
 	"documents-worker/internal/core/domain"
 	"documents-worker/internal/core/ports"
+	"documents-worker/ocr"
+	"documents-worker/packaging"
 	"documents-worker/pdfgen"
 	"documents-worker/utils"
 	"encoding/json"
@@ -57,10 +59,13 @@ func (cli *CLI) GetRootCommand() *cobra.Command {
 Features:
 - Convert images between formats (JPEG, PNG, WEBP, AVIF)
 - Generate PDF from HTML
+- List and fill PDF form fields
+- Compare images (SSIM, pixel difference, diff image)
 - Extract text from documents
 - Perform OCR on images and PDFs
-- Generate video thumbnails
-- Process documents in batch`,
+- Generate video thumbnails and PDF cover previews
+- Process documents in batch
+- Replay recorded failed requests`,
 		Version: "1.0.0",
 	}
 
@@ -71,6 +76,12 @@ Features:
 	rootCmd.AddCommand(cli.getThumbnailCommand())
 	rootCmd.AddCommand(cli.getHealthCommand())
 	rootCmd.AddCommand(cli.getStatsCommand())
+	rootCmd.AddCommand(cli.getJobCommand())
+	rootCmd.AddCommand(cli.getFormCommand())
+	rootCmd.AddCommand(cli.getPDFCommand())
+	rootCmd.AddCommand(cli.getCompareCommand())
+	rootCmd.AddCommand(cli.getBenchCommand())
+	rootCmd.AddCommand(cli.getReplayCommand())
 
 	return rootCmd
 }
@@ -94,6 +105,10 @@ func (cli *CLI) getConvertCommand() *cobra.Command {
 	imageCmd.Flags().Int("width", 0, "Output width (0 = maintain aspect ratio)")
 	imageCmd.Flags().Int("height", 0, "Output height (0 = maintain aspect ratio)")
 	imageCmd.Flags().Int("quality", 85, "Output quality (1-100)")
+	imageCmd.Flags().Int("target-size", 0, "Target output size in bytes; quality is adjusted to fit (0 = disabled)")
+	imageCmd.Flags().Bool("progressive", false, "Progressive JPEG / interlaced PNG output")
+	imageCmd.Flags().String("metadata", "", "Metadata policy (keep-all, strip-all, allowlist, denylist)")
+	imageCmd.Flags().StringSlice("metadata-tags", nil, "Tags for allowlist/denylist (copyright, artist, title, comment, date, gps, camera, software, orientation, icc)")
 
 	// PDF generation
 	pdfCmd := &cobra.Command{
@@ -106,6 +121,11 @@ func (cli *CLI) getConvertCommand() *cobra.Command {
 	pdfCmd.Flags().String("page-size", "A4", "Page size (A4, A3, Letter, etc.)")
 	pdfCmd.Flags().String("orientation", "portrait", "Page orientation (portrait, landscape)")
 	pdfCmd.Flags().Bool("url", false, "Input is a URL instead of file")
+	pdfCmd.Flags().String("margin", "", "Margin on every side, e.g. 10mm (URL input)")
+	pdfCmd.Flags().Int("quality", 0, "Image quality 1-100 (URL input)")
+	pdfCmd.Flags().String("watermark", "", "Watermark text drawn on every page (URL input)")
+	pdfCmd.Flags().String("watermark-position", pdfgen.WatermarkDiagonal, "Watermark position (diagonal, center)")
+	pdfCmd.Flags().Float64("watermark-opacity", pdfgen.DefaultWatermarkOpacity, "Watermark opacity between 0 and 1")
 
 	// Document chunking
 	chunkCmd := &cobra.Command{
@@ -117,20 +137,30 @@ Supported chunking methods:
 - Text-based: Split by paragraphs, sentences, or character count
 - PDF: Split by pages or page ranges  
 - Office: Split by slides (PowerPoint), sheets (Excel), or pages (Word)
-- Smart: Intelligent content-aware splitting`,
+- Smart: Intelligent content-aware splitting
+- Markdown: Split at headings, keeping code blocks, tables and list items whole`,
 		Args: cobra.ExactArgs(2),
 		RunE: cli.chunkDocument,
 	}
-	chunkCmd.Flags().String("method", "smart", "Chunking method (text, semantic, recursive, smart)")
+	chunkCmd.Flags().String("method", "smart", "Chunking method (text, semantic, recursive, smart, token, markdown)")
 	chunkCmd.Flags().Int("size", 256, "Chunk size in characters (for text-based methods)")
-	chunkCmd.Flags().Int("overlap", 20, "Overlap between chunks in characters")
+	chunkCmd.Flags().Int("overlap", 20, "Overlap between chunks in characters, or tokens for the token method")
+	chunkCmd.Flags().Int("token-size", 0, "Chunk size in estimated LLM tokens; selects the token method")
+	chunkCmd.Flags().String("encoding", chunking.EncodingApprox, "Token estimator (approx, chars)")
+	chunkCmd.Flags().Float64("chars-per-token", chunking.DefaultCharsPerToken, "Characters per token (for the chars encoding)")
 	chunkCmd.Flags().Int("pages-per-chunk", 5, "Pages per chunk (for pages method)")
-	chunkCmd.Flags().String("format", "auto", "Output format (txt, md, pdf, auto)")
+	chunkCmd.Flags().String("format", "auto", "Chunk file format (txt, md, json, jsonl, auto); jsonl writes every chunk with its offsets to a single chunks.jsonl")
+	chunkCmd.Flags().String("layout", "flat", "Output directory layout (flat, batch, source, section)")
+	chunkCmd.Flags().Int("chunks-per-dir", chunking.DefaultChunksPerDir, "Chunks per directory (for batch layout)")
+	chunkCmd.Flags().String("name", chunking.DefaultNameTemplate, "Chunk file name template ({id}, {source}, {section})")
+	chunkCmd.Flags().Bool("overwrite", true, "Replace existing chunk files instead of writing numbered copies")
 	chunkCmd.Flags().Bool("preserve-formatting", true, "Preserve original formatting")
+	chunkCmd.Flags().String("package", "", "Write the chunks into a single archive at output instead of a directory (zip)")
 
 	convertCmd.AddCommand(imageCmd)
 	convertCmd.AddCommand(pdfCmd)
 	convertCmd.AddCommand(chunkCmd)
+	convertCmd.AddCommand(cli.getStreamCommand())
 
 	return convertCmd
 }
@@ -145,6 +175,9 @@ func (cli *CLI) getOCRCommand() *cobra.Command {
 		RunE:  cli.performOCR,
 	}
 	ocrCmd.Flags().String("lang", "eng", "OCR language (eng, tur, fra, etc.)")
+	ocrCmd.Flags().String("output-format", "text", "Output format (text, hocr, pdf); pdf is a searchable PDF")
+	ocrCmd.Flags().Float64("min-confidence", 0, "Drop words recognized with a lower confidence, between 0 and 1 (text output)")
+	ocrCmd.AddCommand(cli.getDeskewCommand())
 
 	return ocrCmd
 }
@@ -158,6 +191,7 @@ func (cli *CLI) getExtractCommand() *cobra.Command {
 		Args:  cobra.ExactArgs(2),
 		RunE:  cli.extractText,
 	}
+	extractCmd.AddCommand(cli.getOutlineCommand())
 
 	return extractCmd
 }
@@ -166,13 +200,15 @@ func (cli *CLI) getExtractCommand() *cobra.Command {
 func (cli *CLI) getThumbnailCommand() *cobra.Command {
 	thumbnailCmd := &cobra.Command{
 		Use:   "thumbnail [input] [output]",
-		Short: "Generate thumbnails from images or videos",
-		Long:  "Generate thumbnail images from image or video files",
+		Short: "Generate thumbnails from images, videos or PDFs",
+		Long:  "Generate thumbnail images from image or video files, or a cover preview of a PDF page",
 		Args:  cobra.ExactArgs(2),
 		RunE:  cli.generateThumbnail,
 	}
 	thumbnailCmd.Flags().Int("size", 200, "Thumbnail size (width/height)")
 	thumbnailCmd.Flags().Int("time", 0, "Time offset for video thumbnail (seconds)")
+	thumbnailCmd.Flags().Int("page", 1, "Page to render for PDF thumbnails")
+	thumbnailCmd.AddCommand(cli.getContactSheetCommand())
 
 	return thumbnailCmd
 }
@@ -201,6 +237,17 @@ func (cli *CLI) getStatsCommand() *cobra.Command {
 	return statsCmd
 }
 
+// getJobCommand returns the job status command
+func (cli *CLI) getJobCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "job [id]",
+		Short: "Show the status of a job",
+		Long:  "Show the stored status, result and error of a submitted job",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cli.showJob,
+	}
+}
+
 // convertImage handles image conversion
 func (cli *CLI) convertImage(cmd *cobra.Command, args []string) error {
 	inputPath := args[0]
@@ -211,6 +258,10 @@ func (cli *CLI) convertImage(cmd *cobra.Command, args []string) error {
 	width, _ := cmd.Flags().GetInt("width")
 	height, _ := cmd.Flags().GetInt("height")
 	quality, _ := cmd.Flags().GetInt("quality")
+	targetSize, _ := cmd.Flags().GetInt("target-size")
+	progressive, _ := cmd.Flags().GetBool("progressive")
+	metadataPolicy, _ := cmd.Flags().GetString("metadata")
+	metadataTags, _ := cmd.Flags().GetStringSlice("metadata-tags")
 
 	// Open input file
 	inputFile, err := os.Open(inputPath)
@@ -229,10 +280,22 @@ func (cli *CLI) convertImage(cmd *cobra.Command, args []string) error {
 	if height > 0 {
 		params["height"] = height
 	}
+	if targetSize > 0 {
+		params["target_size"] = targetSize
+	}
+	if progressive {
+		params["progressive"] = true
+	}
+	if metadataPolicy != "" {
+		params["metadata_policy"] = metadataPolicy
+		params["metadata_tags"] = metadataTags
+	}
 
 	// Convert image
 	fmt.Printf("Converting %s to %s format...\n", inputPath, outputFormat)
-	result, err := cli.documentService.ConvertImage(context.Background(), inputFile, outputFormat, params)
+	details := domain.NewOutputDetails()
+	ctx := domain.WithOutputDetails(context.Background(), details)
+	result, err := cli.documentService.ConvertImage(ctx, inputFile, outputFormat, params)
 	if err != nil {
 		return fmt.Errorf("failed to convert image: %w", err)
 	}
@@ -250,6 +313,11 @@ func (cli *CLI) convertImage(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Printf("✅ Image converted successfully: %s\n", outputPath)
+	detail, _ := details.Get(domain.OutputDetailTargetSize)
+	if search, ok := detail.(map[string]interface{}); ok {
+		fmt.Printf("🎯 Target %v bytes: %v bytes at quality %v (target met: %v)\n",
+			search["target_size"], search["achieved_size"], search["achieved_quality"], search["target_met"])
+	}
 	return nil
 }
 
@@ -262,11 +330,21 @@ func (cli *CLI) generatePDF(cmd *cobra.Command, args []string) error {
 	pageSize, _ := cmd.Flags().GetString("page-size")
 	orientation, _ := cmd.Flags().GetString("orientation")
 	isURL, _ := cmd.Flags().GetBool("url")
+	margin, _ := cmd.Flags().GetString("margin")
+	quality, _ := cmd.Flags().GetInt("quality")
+	watermark, _ := cmd.Flags().GetString("watermark")
+	watermarkPosition, _ := cmd.Flags().GetString("watermark-position")
+	watermarkOpacity, _ := cmd.Flags().GetFloat64("watermark-opacity")
 
 	// Prepare parameters
 	params := map[string]interface{}{
-		"page_size":   pageSize,
-		"orientation": orientation,
+		"page_size":          pageSize,
+		"orientation":        orientation,
+		"margin":             margin,
+		"quality":            quality,
+		"watermark":          watermark,
+		"watermark_position": watermarkPosition,
+		"watermark_opacity":  watermarkOpacity,
 	}
 
 	var result io.Reader
@@ -274,8 +352,10 @@ func (cli *CLI) generatePDF(cmd *cobra.Command, args []string) error {
 
 	if isURL {
 		fmt.Printf("Generating PDF from URL: %s...\n", input)
-		// For URL, we would need a different method
-		return fmt.Errorf("URL to PDF conversion not implemented in this example")
+		result, err = cli.generatePDFFromURL(input, params)
+		if err != nil {
+			return fmt.Errorf("failed to generate PDF: %w", err)
+		}
 	} else {
 		// Determine input file type by extension first
 		ext := strings.ToLower(filepath.Ext(input))
@@ -344,6 +424,18 @@ func (cli *CLI) performOCR(cmd *cobra.Command, args []string) error {
 
 	// Get flags
 	language, _ := cmd.Flags().GetString("lang")
+	outputFormat, _ := cmd.Flags().GetString("output-format")
+	minConfidence, _ := cmd.Flags().GetFloat64("min-confidence")
+	format, err := ocr.ParseOutputFormat(outputFormat)
+	if err != nil {
+		return err
+	}
+	if minConfidence < 0 || minConfidence > 1 {
+		return fmt.Errorf("min-confidence must be between 0 and 1")
+	}
+	if minConfidence > 0 && format != ocr.OutputText {
+		return fmt.Errorf("min-confidence only applies to text output")
+	}
 
 	// Open input file
 	inputFile, err := os.Open(inputPath)
@@ -352,6 +444,43 @@ func (cli *CLI) performOCR(cmd *cobra.Command, args []string) error {
 	}
 	defer inputFile.Close()
 
+	if format != ocr.OutputText {
+		fmt.Printf("Performing OCR on %s (language: %s, output: %s)...\n", inputPath, language, format)
+		result, err := cli.documentService.PerformOCROutput(context.Background(), inputFile, language, string(format))
+		if err != nil {
+			return fmt.Errorf("failed to perform OCR: %w", err)
+		}
+
+		// Save output
+		outputFile, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer outputFile.Close()
+
+		if _, err := io.Copy(outputFile, result); err != nil {
+			return fmt.Errorf("failed to save output: %w", err)
+		}
+		fmt.Printf("✅ OCR completed successfully: %s\n", outputPath)
+		return nil
+	}
+
+	if minConfidence > 0 {
+		fmt.Printf("Performing OCR on %s (language: %s, min confidence: %.2f)...\n", inputPath, language, minConfidence)
+		result, err := cli.documentService.PerformOCRDetailed(context.Background(), inputFile, language)
+		if err != nil {
+			return fmt.Errorf("failed to perform OCR: %w", err)
+		}
+		kept := result.Filter(minConfidence)
+		if err := os.WriteFile(outputPath, []byte(kept.Text), 0644); err != nil {
+			return fmt.Errorf("failed to save output: %w", err)
+		}
+
+		fmt.Printf("✅ OCR completed successfully: %s\n", outputPath)
+		fmt.Printf("📄 Kept %d of %d words (average confidence %.2f)\n", len(kept.Words), len(result.Words), result.Confidence)
+		return nil
+	}
+
 	fmt.Printf("Performing OCR on %s (language: %s)...\n", inputPath, language)
 	text, err := cli.documentService.PerformOCR(context.Background(), inputFile, language)
 	if err != nil {
@@ -421,6 +550,7 @@ func (cli *CLI) generateThumbnail(cmd *cobra.Command, args []string) error {
 	// Get flags
 	size, _ := cmd.Flags().GetInt("size")
 	timeOffset, _ := cmd.Flags().GetInt("time")
+	page, _ := cmd.Flags().GetInt("page")
 
 	// Open input file
 	inputFile, err := os.Open(inputPath)
@@ -429,16 +559,22 @@ func (cli *CLI) generateThumbnail(cmd *cobra.Command, args []string) error {
 	}
 	defer inputFile.Close()
 
-	// Prepare parameters
-	params := map[string]interface{}{
-		"size": size,
-	}
-	if timeOffset > 0 {
-		params["time_offset"] = timeOffset
-	}
+	var result io.Reader
+	if mimeType, _ := utils.DetectMimeTypeFromFile(inputPath); utils.IsPdfDocument(mimeType) {
+		fmt.Printf("Generating thumbnail of page %d from %s (size: %dx%d)...\n", page, inputPath, size, size)
+		result, err = cli.documentService.GeneratePDFThumbnail(context.Background(), inputFile, page, size)
+	} else {
+		// Prepare parameters
+		params := map[string]interface{}{
+			"size": size,
+		}
+		if timeOffset > 0 {
+			params["time_offset"] = timeOffset
+		}
 
-	fmt.Printf("Generating thumbnail from %s (size: %dx%d)...\n", inputPath, size, size)
-	result, err := cli.documentService.GenerateThumbnail(context.Background(), inputFile, params)
+		fmt.Printf("Generating thumbnail from %s (size: %dx%d)...\n", inputPath, size, size)
+		result, err = cli.documentService.GenerateThumbnail(context.Background(), inputFile, params)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to generate thumbnail: %w", err)
 	}
@@ -510,6 +646,27 @@ func (cli *CLI) showStats(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// showJob prints a job as JSON
+func (cli *CLI) showJob(cmd *cobra.Command, args []string) error {
+	if cli.queueService == nil {
+		return fmt.Errorf("job status requires Redis")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, err := cli.documentService.GetJob(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("failed to get job: %w", err)
+	}
+
+	jobJSON, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format job: %w", err)
+	}
+	fmt.Println(string(jobJSON))
+	return nil
+}
+
 // Helper functions for PDF generation
 
 // generatePDFFromHTML generates PDF from HTML file
@@ -751,6 +908,64 @@ func (cli *CLI) generatePDFFromOffice(input string, params map[string]interface{
 	return pdfFile, nil
 }
 
+// generatePDFFromURL renders a web page to PDF, with Playwright when it is
+// enabled and wkhtmltopdf otherwise or when Playwright fails
+func (cli *CLI) generatePDFFromURL(input string, params map[string]interface{}) (io.Reader, error) {
+	if _, err := pdfgen.ValidatePDFURL(input); err != nil {
+		return nil, err
+	}
+
+	pdfGenerator := pdfgen.NewPDFGenerator(&cli.config.External)
+	options := &pdfgen.GenerationOptions{
+		PageSize:    "A4",
+		Orientation: "portrait",
+	}
+	if pageSize, ok := params["page_size"].(string); ok {
+		options.PageSize = pageSize
+	}
+	if orientation, ok := params["orientation"].(string); ok {
+		options.Orientation = orientation
+	}
+	if margin, ok := params["margin"].(string); ok && margin != "" {
+		options.Margins = map[string]string{"top": margin, "right": margin, "bottom": margin, "left": margin}
+	}
+	if quality, ok := params["quality"].(int); ok {
+		options.Quality = quality
+	}
+	if watermark, ok := params["watermark"].(string); ok {
+		options.Watermark = watermark
+	}
+	if position, ok := params["watermark_position"].(string); ok {
+		options.WatermarkPosition = position
+	}
+	if opacity, ok := params["watermark_opacity"].(float64); ok {
+		options.WatermarkOpacity = opacity
+	}
+
+	var result *pdfgen.GenerationResult
+	var err error
+	if cli.config.External.PlaywrightEnabled {
+		result, err = pdfGenerator.GenerateFromURLWithPlaywright(input, options)
+		if err != nil {
+			fmt.Printf("⚠️ Playwright failed, falling back to wkhtmltopdf: %v\n", err)
+		}
+	}
+	if result == nil {
+		result, err = pdfGenerator.GenerateFromURL(input, options)
+		if err != nil {
+			return nil, fmt.Errorf("URL to PDF conversion failed: %w", err)
+		}
+	}
+
+	// The PDF is read into memory so the temp file can be removed right away
+	pdfData, err := os.ReadFile(result.OutputPath)
+	os.Remove(result.OutputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read generated PDF: %w", err)
+	}
+	return bytes.NewReader(pdfData), nil
+}
+
 // getFileTypeFromExtension determines file type from extension
 func (cli *CLI) getFileTypeFromExtension(ext string) string {
 	switch ext {
@@ -799,9 +1014,39 @@ func (cli *CLI) chunkDocument(cmd *cobra.Command, args []string) error {
 	overlap, _ := cmd.Flags().GetInt("overlap")
 	outputFormat, _ := cmd.Flags().GetString("format")
 	preserveFormatting, _ := cmd.Flags().GetBool("preserve-formatting")
+	packageOption, _ := cmd.Flags().GetString("package")
+	layout, _ := cmd.Flags().GetString("layout")
+	chunksPerDir, _ := cmd.Flags().GetInt("chunks-per-dir")
+	nameTemplate, _ := cmd.Flags().GetString("name")
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+	tokenSize, _ := cmd.Flags().GetInt("token-size")
+	encoding, _ := cmd.Flags().GetString("encoding")
+	charsPerToken, _ := cmd.Flags().GetFloat64("chars-per-token")
+	if tokenSize > 0 {
+		method = string(chunking.MethodToken)
+		chunkSize = tokenSize
+	}
+	unit := "chars"
+	if method == string(chunking.MethodToken) {
+		unit = "tokens"
+	}
+	saveOptions := chunking.SaveOptions{
+		Layout:       chunking.Layout(layout),
+		ChunksPerDir: chunksPerDir,
+		NameTemplate: nameTemplate,
+		Overwrite:    overwrite,
+	}
+
+	packaged, err := packaging.Requested(packageOption)
+	if err != nil {
+		return err
+	}
+	if packaged && strings.EqualFold(outputFormat, chunking.FormatJSONL) {
+		return fmt.Errorf("--package cannot be used with the jsonl format, which is already a single file")
+	}
 
 	fmt.Printf("🔄 Chunking document: %s\n", input)
-	fmt.Printf("📐 Method: %s, Chunk size: %d chars, Overlap: %d chars\n", method, chunkSize, overlap)
+	fmt.Printf("📐 Method: %s, Chunk size: %d %s, Overlap: %d %s\n", method, chunkSize, unit, overlap, unit)
 	fmt.Printf("📁 Output directory: %s\n", outputDir)
 
 	// Create chunking service
@@ -814,6 +1059,8 @@ func (cli *CLI) chunkDocument(cmd *cobra.Command, args []string) error {
 		Overlap:            overlap,
 		OutputFormat:       outputFormat,
 		PreserveFormatting: preserveFormatting,
+		Encoding:           encoding,
+		CharsPerToken:      charsPerToken,
 	}
 
 	// Chunk the document
@@ -823,15 +1070,55 @@ func (cli *CLI) chunkDocument(cmd *cobra.Command, args []string) error {
 	}
 
 	// Save chunks
-	if err := chunkingService.SaveChunks(context.Background(), result, outputDir); err != nil {
+	if packaged {
+		if err := writeChunkArchive(result, outputDir, saveOptions); err != nil {
+			return err
+		}
+	} else if err := chunkingService.SaveChunksWithOptions(context.Background(), result, outputDir, saveOptions); err != nil {
 		return fmt.Errorf("failed to save chunks: %w", err)
 	}
 
 	fmt.Printf("✅ Successfully created %d chunks in %s\n", result.TotalChunks, outputDir)
-	fmt.Printf("📊 Average chunk size: %.0f characters\n", result.AverageSize)
+	fmt.Printf("📊 Average chunk size: %.0f characters, %d tokens in total\n", result.AverageSize, result.TotalTokens)
 	fmt.Printf("📈 Compression ratio: %.1f%% (original: %d chars)\n",
 		float64(result.TotalChunks)*result.AverageSize/float64(result.OriginalSize)*100,
 		result.OriginalSize)
 
 	return nil
 }
+
+// writeChunkArchive writes the chunks and a manifest into a zip archive,
+// using the same file names as SaveChunks. Archives are always flat.
+func writeChunkArchive(result *chunking.ChunkResult, outputPath string, opts chunking.SaveOptions) error {
+	opts.Layout = chunking.LayoutFlat
+	paths, err := chunking.ChunkPaths(result, opts)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]packaging.Entry, len(result.Chunks))
+	for i, chunk := range result.Chunks {
+		data, err := chunking.EncodeChunk(chunk, paths[i])
+		if err != nil {
+			return err
+		}
+		contentType := "text/plain"
+		if filepath.Ext(paths[i]) == ".json" {
+			contentType = "application/json"
+		}
+		entries[i] = packaging.BytesEntry(paths[i], data, contentType)
+		entries[i].Metadata = chunk.Metadata
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := packaging.WriteZip(out, "chunk", entries); err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("failed to write chunk archive: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"documents-worker/media"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// getCompareCommand returns the image compare command
+func (cli *CLI) getCompareCommand() *cobra.Command {
+	compareCmd := &cobra.Command{
+		Use:   "compare [image-a] [image-b]",
+		Short: "Compare two images",
+		Long: `Compare two images and report their similarity (SSIM and the percentage of
+differing pixels). A diff image marks the differing pixels in the highlight color.
+Images of different sizes are compared after scaling the second one with VIPS.`,
+		Example: `  documents-worker compare original.png converted.webp --diff diff.png
+  documents-worker compare a.jpg b.jpg --threshold 0.05 --highlight "#ff00ff"`,
+		Args: cobra.ExactArgs(2),
+		RunE: cli.compareImages,
+	}
+	compareCmd.Flags().String("diff", "", "Write the diff image (PNG) to this path")
+	compareCmd.Flags().Float64("threshold", media.DefaultCompareThreshold, "Channel difference (0-1) above which a pixel counts as different")
+	compareCmd.Flags().String("highlight", "#ff0000", "Color of differing pixels in the diff image")
+
+	return compareCmd
+}
+
+// compareImages handles the compare command
+func (cli *CLI) compareImages(cmd *cobra.Command, args []string) error {
+	diffPath, _ := cmd.Flags().GetString("diff")
+	threshold, _ := cmd.Flags().GetFloat64("threshold")
+	highlight, _ := cmd.Flags().GetString("highlight")
+
+	highlightColor, err := media.ParseHexColor(highlight)
+	if err != nil {
+		return err
+	}
+
+	diffFile, result, err := media.CompareImages(args[0], args[1], media.CompareOptions{
+		Threshold:      threshold,
+		HighlightColor: highlightColor,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compare images: %w", err)
+	}
+	diffFile.Close()
+	defer os.Remove(diffFile.Name())
+
+	if diffPath != "" {
+		if err := copyFileTo(diffFile.Name(), diffPath); err != nil {
+			return err
+		}
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format comparison result: %w", err)
+	}
+
+	fmt.Println(string(resultJSON))
+	return nil
+}
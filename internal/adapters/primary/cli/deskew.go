@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"documents-worker/ocr"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// getDeskewCommand returns the ocr deskew command
+func (cli *CLI) getDeskewCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "deskew [input] [output.png]",
+		Short: "Turn a scanned page upright and straighten it",
+		Long: `Correct the orientation of a scanned PNG or JPEG page without running OCR.
+Quarter turns are detected with tesseract's orientation detection and small
+skews from the text lines. The applied rotation and skew are printed as JSON.`,
+		Example: `  documents-worker ocr deskew scan.jpg upright.png`,
+		Args:    cobra.ExactArgs(2),
+		RunE:    cli.deskewImage,
+	}
+}
+
+// deskewImage handles the ocr deskew command
+func (cli *CLI) deskewImage(cmd *cobra.Command, args []string) error {
+	processor := ocr.NewOCRProcessor(&cli.config.OCR, &cli.config.External)
+	corrected, orientation, err := processor.CorrectOrientation(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to correct orientation: %w", err)
+	}
+	defer os.Remove(corrected)
+
+	if err := copyFileTo(corrected, args[1]); err != nil {
+		return err
+	}
+
+	orientationJSON, err := json.MarshalIndent(orientation, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format orientation: %w", err)
+	}
+	fmt.Println(string(orientationJSON))
+	return nil
+}
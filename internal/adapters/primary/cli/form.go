@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"documents-worker/pdfgen"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// getFormCommand returns the PDF form command
+func (cli *CLI) getFormCommand() *cobra.Command {
+	formCmd := &cobra.Command{
+		Use:   "form",
+		Short: "Read and fill PDF forms",
+		Long:  "List the AcroForm fields of a PDF and fill them from key/value data",
+	}
+
+	fieldsCmd := &cobra.Command{
+		Use:   "fields [input]",
+		Short: "List the form fields of a PDF",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cli.listFormFields,
+	}
+
+	fillCmd := &cobra.Command{
+		Use:   "fill [input] [output]",
+		Short: "Fill the form fields of a PDF",
+		Long: `Fill the form fields of a PDF from a JSON object of field names to values
+and/or repeated --set name=value flags. Values from --set override --data.`,
+		Example: `  documents-worker form fill form.pdf filled.pdf --data values.json --flatten
+  documents-worker form fill form.pdf filled.pdf --set full_name="Ada Lovelace" --set subscribe=Yes`,
+		Args: cobra.ExactArgs(2),
+		RunE: cli.fillForm,
+	}
+	fillCmd.Flags().String("data", "", "JSON file with field values")
+	fillCmd.Flags().StringArray("set", nil, "Field value as name=value (repeatable)")
+	fillCmd.Flags().Bool("flatten", false, "Flatten the form so it is no longer editable")
+	fillCmd.Flags().Bool("allow-unknown", false, "Do not fail on values for fields the PDF does not have")
+
+	formCmd.AddCommand(fieldsCmd)
+	formCmd.AddCommand(fillCmd)
+
+	return formCmd
+}
+
+// listFormFields handles the form fields command
+func (cli *CLI) listFormFields(cmd *cobra.Command, args []string) error {
+	generator := pdfgen.NewPDFGenerator(&cli.config.External)
+
+	fields, err := generator.ListFormFields(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to list form fields: %w", err)
+	}
+
+	fieldsJSON, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format form fields: %w", err)
+	}
+
+	fmt.Println(string(fieldsJSON))
+	return nil
+}
+
+// fillForm handles the form fill command
+func (cli *CLI) fillForm(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath := args[1]
+
+	dataPath, _ := cmd.Flags().GetString("data")
+	sets, _ := cmd.Flags().GetStringArray("set")
+	flatten, _ := cmd.Flags().GetBool("flatten")
+	allowUnknown, _ := cmd.Flags().GetBool("allow-unknown")
+
+	values := make(map[string]string)
+	if dataPath != "" {
+		data, err := os.ReadFile(dataPath)
+		if err != nil {
+			return fmt.Errorf("failed to read form data: %w", err)
+		}
+		if err := json.Unmarshal(data, &values); err != nil {
+			return fmt.Errorf("form data must be a JSON object of strings: %w", err)
+		}
+	}
+	for _, set := range sets {
+		name, value, ok := strings.Cut(set, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid --set value %q, expected name=value", set)
+		}
+		values[name] = value
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("no field values provided, use --data or --set")
+	}
+
+	fmt.Printf("Filling %d form fields in %s...\n", len(values), inputPath)
+
+	generator := pdfgen.NewPDFGenerator(&cli.config.External)
+	result, err := generator.FillForm(inputPath, values, &pdfgen.FillFormOptions{
+		Flatten:            flatten,
+		AllowUnknownFields: allowUnknown,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fill form: %w", err)
+	}
+	defer os.Remove(result.OutputPath)
+
+	if err := copyFileTo(result.OutputPath, outputPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Filled form saved to: %s\n", outputPath)
+	return nil
+}
+
+// copyFileTo copies a generated file to its final destination
+func copyFileTo(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open generated file: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
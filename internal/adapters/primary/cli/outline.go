@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"documents-worker/textextractor"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// getOutlineCommand returns the extract outline command
+func (cli *CLI) getOutlineCommand() *cobra.Command {
+	outlineCmd := &cobra.Command{
+		Use:   "outline [input.pdf]",
+		Short: "Print the outline (bookmarks) of a PDF",
+		Long: `Print the bookmark tree of a PDF as JSON, with the title, level and target
+page of every entry. PDFs without bookmarks print an empty list.`,
+		Example: `  documents-worker extract outline report.pdf
+  documents-worker extract outline locked.pdf --password secret`,
+		Args: cobra.ExactArgs(1),
+		RunE: cli.extractOutline,
+	}
+	outlineCmd.Flags().String("password", "", "Password of an encrypted PDF")
+
+	return outlineCmd
+}
+
+// extractOutline handles the extract outline command
+func (cli *CLI) extractOutline(cmd *cobra.Command, args []string) error {
+	password, _ := cmd.Flags().GetString("password")
+
+	outline, err := textextractor.NewTextExtractor(&cli.config.External).WithPassword(password).ExtractOutline(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to extract outline: %w", err)
+	}
+
+	outlineJSON, err := json.MarshalIndent(outline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format outline: %w", err)
+	}
+	fmt.Println(string(outlineJSON))
+	return nil
+}
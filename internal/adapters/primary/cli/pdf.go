@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"documents-worker/pdfgen"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// getPDFCommand returns the pdf command
+func (cli *CLI) getPDFCommand() *cobra.Command {
+	pdfCmd := &cobra.Command{
+		Use:   "pdf",
+		Short: "Work with existing PDFs",
+	}
+
+	mergeCmd := &cobra.Command{
+		Use:   "merge [input.pdf]... [output.pdf]",
+		Short: "Merge PDFs into one document",
+		Long: `Concatenate PDFs, all of their pages in the order given, into a single
+document with mutool merge. The last argument is the output.`,
+		Example: `  documents-worker pdf merge cover.pdf report.pdf appendix.pdf combined.pdf`,
+		Args:    cobra.MinimumNArgs(2),
+		RunE:    cli.mergePDFs,
+	}
+
+	splitCmd := &cobra.Command{
+		Use:   "split [input.pdf] [output_dir]",
+		Short: "Split a PDF by page ranges",
+		Long: `Extract each page range of a PDF into its own file in the output directory,
+named after the input and the range, e.g. report_pages_1-3.pdf.`,
+		Example: `  documents-worker pdf split report.pdf parts --ranges 1-3,4-6
+  documents-worker pdf split report.pdf parts --ranges 1,2,5-9`,
+		Args: cobra.ExactArgs(2),
+		RunE: cli.splitPDF,
+	}
+	splitCmd.Flags().String("ranges", "", "Comma separated page ranges, e.g. 1-3,4-6")
+	splitCmd.MarkFlagRequired("ranges")
+
+	pdfCmd.AddCommand(mergeCmd)
+	pdfCmd.AddCommand(splitCmd)
+
+	return pdfCmd
+}
+
+// mergePDFs handles the pdf merge command
+func (cli *CLI) mergePDFs(cmd *cobra.Command, args []string) error {
+	inputs, output := args[:len(args)-1], args[len(args)-1]
+
+	fmt.Printf("Merging %d PDFs into %s...\n", len(inputs), output)
+	generator := pdfgen.NewPDFGenerator(&cli.config.External)
+	result, err := generator.MergePDFs(inputs, output)
+	if err != nil {
+		return fmt.Errorf("failed to merge PDFs: %w", err)
+	}
+
+	fmt.Printf("✅ Merged PDF saved to: %s (%d pages)\n", output, result.PageCount)
+	return nil
+}
+
+// splitPDF handles the pdf split command
+func (cli *CLI) splitPDF(cmd *cobra.Command, args []string) error {
+	rangesFlag, _ := cmd.Flags().GetString("ranges")
+	ranges, err := pdfgen.ParsePageRanges(rangesFlag)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Splitting %s into %d parts...\n", args[0], len(ranges))
+	generator := pdfgen.NewPDFGenerator(&cli.config.External)
+	results, err := generator.SplitPDF(args[0], ranges, args[1])
+	if err != nil {
+		return fmt.Errorf("failed to split PDF: %w", err)
+	}
+
+	for _, result := range results {
+		fmt.Printf("✅ Pages %s saved to: %s\n", result.Metadata["pages"], result.OutputPath)
+	}
+	return nil
+}
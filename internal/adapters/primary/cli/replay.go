@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"context"
+	"documents-worker/recorder"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// getReplayCommand returns the replay command
+func (cli *CLI) getReplayCommand() *cobra.Command {
+	replayCmd := &cobra.Command{
+		Use:   "replay [recording]",
+		Short: "Re-submit a recorded failed request to a server",
+		Long: `Re-submit a request recorded by a server running with RECORDER_ENABLED.
+
+The recording is either an ID under --dir or the path of a recording
+directory, such as an extracted download from /api/v1/recordings/{id}.
+Redacted fields (passwords, tokens) are left out unless supplied with --field.`,
+		Example: `  documents-worker replay 20261016T101500.123456-1a2b3c4d
+  documents-worker replay ./recording --server http://localhost:3001 --field password=secret -o out.webp`,
+		Args: cobra.ExactArgs(1),
+		RunE: cli.replay,
+	}
+
+	replayCmd.Flags().String("dir", cli.config.Recorder.Directory, "Directory holding recordings")
+	replayCmd.Flags().String("server", "http://localhost:3001", "Server base URL")
+	replayCmd.Flags().String("token", "", "Bearer token for the server")
+	replayCmd.Flags().StringToString("field", nil, "Form field to set, e.g. --field password=secret")
+	replayCmd.Flags().StringP("output", "o", "", "Write the response body to this file")
+	replayCmd.Flags().Duration("timeout", 5*time.Minute, "Request timeout")
+
+	return replayCmd
+}
+
+// replay handles the replay command
+func (cli *CLI) replay(cmd *cobra.Command, args []string) error {
+	dir, _ := cmd.Flags().GetString("dir")
+	server, _ := cmd.Flags().GetString("server")
+	token, _ := cmd.Flags().GetString("token")
+	fields, _ := cmd.Flags().GetStringToString("field")
+	output, _ := cmd.Flags().GetString("output")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	recording, inputPath, err := openRecording(dir, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("📼 Replaying %s %s (recorded %s with status %d)\n",
+		recording.Method, recording.Path, recording.RecordedAt.Format(time.RFC3339), recording.Status)
+	for _, name := range recording.RedactedFields() {
+		if _, ok := fields[name]; !ok {
+			fmt.Printf("⚠️  Field %q was redacted and is left out; supply it with --field %s=...\n", name, name)
+		}
+	}
+
+	headers := make(map[string]string)
+	if token != "" {
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := recorder.Replay(ctx, http.DefaultClient, server, recording, inputPath, fields, headers)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body io.Writer = io.Discard
+	var preview strings.Builder
+	if output != "" {
+		file, err := os.Create(output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		body = file
+	} else if resp.StatusCode >= http.StatusBadRequest {
+		body = &preview
+	}
+
+	size, err := io.Copy(body, resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	fmt.Printf("📊 Status %d (%d bytes) in %s\n", resp.StatusCode, size, time.Since(start).Round(time.Millisecond))
+	if preview.Len() > 0 {
+		fmt.Println(preview.String())
+	}
+	if output != "" {
+		fmt.Printf("✅ Response written to %s\n", output)
+	}
+	return nil
+}
+
+// openRecording resolves an ID in dir or a recording directory path
+func openRecording(dir, ref string) (*recorder.Recording, string, error) {
+	if info, err := os.Stat(ref); err == nil && info.IsDir() {
+		return recorder.Open(ref)
+	}
+
+	recording, inputPath, err := recorder.Open(filepath.Join(dir, filepath.Base(ref)))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load recording %s: %w", ref, err)
+	}
+	return recording, inputPath, nil
+}
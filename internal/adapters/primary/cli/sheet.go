@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"documents-worker/media"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// getContactSheetCommand returns the thumbnail sheet command
+func (cli *CLI) getContactSheetCommand() *cobra.Command {
+	sheetCmd := &cobra.Command{
+		Use:   "sheet [video] [output.jpg]",
+		Short: "Generate a contact sheet of video frames",
+		Long: `Generate a single JPEG holding rows x cols frames taken at even intervals
+across the whole video, for preview UIs. The interval is derived from the
+probed duration, so short clips are covered end to end too.`,
+		Example: `  documents-worker thumbnail sheet talk.mp4 sheet.jpg
+  documents-worker thumbnail sheet clip.mov sheet.jpg --rows 2 --cols 5 --thumb-width 240`,
+		Args: cobra.ExactArgs(2),
+		RunE: cli.generateContactSheet,
+	}
+	sheetCmd.Flags().Int("rows", 3, "Rows of frames")
+	sheetCmd.Flags().Int("cols", 3, "Columns of frames")
+	sheetCmd.Flags().Int("thumb-width", 320, "Width of each frame in pixels")
+
+	return sheetCmd
+}
+
+// generateContactSheet handles the thumbnail sheet command
+func (cli *CLI) generateContactSheet(cmd *cobra.Command, args []string) error {
+	rows, _ := cmd.Flags().GetInt("rows")
+	cols, _ := cmd.Flags().GetInt("cols")
+	thumbWidth, _ := cmd.Flags().GetInt("thumb-width")
+
+	fmt.Printf("Generating %dx%d contact sheet from %s...\n", cols, rows, args[0])
+	sheet, err := media.GenerateContactSheet(args[0], rows, cols, thumbWidth)
+	if err != nil {
+		return fmt.Errorf("failed to generate contact sheet: %w", err)
+	}
+	sheet.Close()
+	defer os.Remove(sheet.Name())
+
+	if err := copyFileTo(sheet.Name(), args[1]); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Contact sheet generated successfully: %s\n", args[1])
+	return nil
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"context"
+	"documents-worker/media"
+	"documents-worker/packaging"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// getStreamCommand returns the adaptive streaming command
+func (cli *CLI) getStreamCommand() *cobra.Command {
+	streamCmd := &cobra.Command{
+		Use:   "stream [video] [output]",
+		Short: "Package a video for adaptive streaming (HLS/DASH)",
+		Long: `Encode the video once per step of a bitrate ladder and write the renditions
+as segments with an HLS master playlist or a DASH manifest. Steps taller than
+the source are skipped, so videos are never upscaled. Every file the manifest
+names is checked before the package is written.`,
+		Example: `  documents-worker convert stream talk.mp4 talk-hls/
+  documents-worker convert stream talk.mp4 talk.zip --format dash --package zip
+  documents-worker convert stream clip.mov clip/ --ladder 720:2800,480:1400 --segment 4`,
+		Args: cobra.ExactArgs(2),
+		RunE: cli.generateStream,
+	}
+	streamCmd.Flags().String("format", media.StreamFormatHLS, "Package format (hls, dash)")
+	streamCmd.Flags().String("ladder", "", "Renditions as height:kbps pairs, e.g. 1080:5000,720:2800 (default 1080p to 360p)")
+	streamCmd.Flags().Int("segment", media.DefaultStreamSegmentSeconds, "Segment duration in seconds")
+	streamCmd.Flags().Int("audio-bitrate", 128, "Audio bitrate in kbps")
+	streamCmd.Flags().String("package", "", "Write the package into a single archive at output instead of a directory (zip)")
+
+	return streamCmd
+}
+
+// generateStream handles the convert stream command
+func (cli *CLI) generateStream(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	ladderOption, _ := cmd.Flags().GetString("ladder")
+	segment, _ := cmd.Flags().GetInt("segment")
+	audioBitrate, _ := cmd.Flags().GetInt("audio-bitrate")
+	packageOption, _ := cmd.Flags().GetString("package")
+
+	packaged, err := packaging.Requested(packageOption)
+	if err != nil {
+		return err
+	}
+	ladder, err := media.ParseStreamLadder(ladderOption)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Packaging %s as %s...\n", args[0], strings.ToUpper(format))
+	pkg, err := media.GenerateStreamPackage(context.Background(), args[0], media.StreamOptions{
+		Format:           format,
+		Ladder:           ladder,
+		SegmentSeconds:   segment,
+		AudioBitrateKbps: audioBitrate,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to package stream: %w", err)
+	}
+	defer os.RemoveAll(pkg.Dir)
+
+	if packaged {
+		err = writeStreamArchive(pkg, args[1])
+	} else {
+		err = copyStreamFiles(pkg, args[1])
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Stream packaged successfully: %s (%d files, manifest %s)\n", args[1], len(pkg.Files), pkg.Manifest)
+	return nil
+}
+
+// copyStreamFiles copies the package into outputDir
+func copyStreamFiles(pkg *media.StreamPackage, outputDir string) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for _, name := range pkg.Files {
+		if err := copyFileTo(filepath.Join(pkg.Dir, name), filepath.Join(outputDir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeStreamArchive writes the package and a manifest into a zip archive.
+// Package files are flat, so their names survive the archive unchanged.
+func writeStreamArchive(pkg *media.StreamPackage, outputPath string) error {
+	entries := make([]packaging.Entry, len(pkg.Files))
+	for i, name := range pkg.Files {
+		entries[i] = packaging.FileEntry(name, filepath.Join(pkg.Dir, name), streamContentType(name))
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := packaging.WriteZip(out, "stream", entries); err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("failed to write stream archive: %w", err)
+	}
+	return nil
+}
+
+// streamContentType returns the media type of a package file
+func streamContentType(name string) string {
+	switch filepath.Ext(name) {
+	case ".m3u8":
+		return "application/vnd.apple.mpegurl"
+	case ".mpd":
+		return "application/dash+xml"
+	case ".ts":
+		return "video/mp2t"
+	case ".m4s":
+		return "video/iso.segment"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"documents-worker/auth"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// principalKey is the c.Locals key holding the verified *auth.Principal
+const principalKey = "principal"
+
+// RequireAuth rejects requests without a valid bearer token from the
+// configured identity provider and stores the caller's principal
+func RequireAuth(verifier *auth.Verifier) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer`)
+			return fiber.NewError(fiber.StatusUnauthorized, "Missing bearer token")
+		}
+
+		principal, err := verifier.Verify(c.Context(), strings.TrimSpace(token))
+		if err != nil {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		}
+
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+// RequireRole allows only principals holding one of the given roles. It
+// must run after RequireAuth.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := PrincipalFrom(c)
+		if principal == nil || !principal.HasRole(roles...) {
+			return fiber.NewError(fiber.StatusForbidden, "Insufficient role")
+		}
+		return c.Next()
+	}
+}
+
+// RequirePermission allows only principals holding one of the given
+// permissions. It must run after RequireAuth.
+func RequirePermission(permissions ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := PrincipalFrom(c)
+		if principal == nil || !principal.HasPermission(permissions...) {
+			return fiber.NewError(fiber.StatusForbidden, "Insufficient permission")
+		}
+		return c.Next()
+	}
+}
+
+// PrincipalFrom returns the verified caller, or nil on unauthenticated routes
+func PrincipalFrom(c *fiber.Ctx) *auth.Principal {
+	principal, _ := c.Locals(principalKey).(*auth.Principal)
+	return principal
+}
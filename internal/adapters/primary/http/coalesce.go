@@ -0,0 +1,178 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"documents-worker/internal/core/domain"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// coalescedCall is an in-flight or completed call shared by identical requests
+type coalescedCall struct {
+	done    chan struct{}
+	val     []byte
+	err     error
+	waiters int
+
+	// refs counts callers still interested in the result; the execution is
+	// canceled once every one of them has gone away
+	refs   int
+	cancel context.CancelFunc
+
+	// users counts callers that have not finished with the result yet;
+	// cleanup runs on a successful result once the last one has
+	users   int
+	cleanup func(val []byte)
+
+	// details are the output details recorded by the execution, handed to
+	// every waiter
+	details *domain.OutputDetails
+}
+
+// requestCoalescer collapses concurrent identical requests into a single
+// execution whose result is delivered to every waiter
+type requestCoalescer struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+// newRequestCoalescer creates an empty coalescer
+func newRequestCoalescer() *requestCoalescer {
+	return &requestCoalescer{
+		calls: make(map[string]*coalescedCall),
+	}
+}
+
+// Do executes fn once per key among concurrent callers. shared reports
+// whether the result was produced by another caller's execution.
+//
+// The context passed to fn is canceled only when every caller's ctx is done,
+// so one client going away does not fail the others sharing its work. A
+// waiter whose ctx is done stops waiting and returns ctx.Err(). Output
+// details recorded by the execution are merged into each waiter's.
+func (g *requestCoalescer) Do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) (val []byte, err error, shared bool) {
+	val, err, shared, release := g.do(ctx, key, fn, nil)
+	release()
+	return val, err, shared
+}
+
+// DoFile is Do for results written to a file. fn returns the path of its
+// output, and every caller gets its own handle on it, so large results are
+// streamed rather than held in memory. The file is removed once every
+// caller has opened it or gone away.
+func (g *requestCoalescer) DoFile(ctx context.Context, key string, fn func(ctx context.Context) (string, error)) (file *os.File, err error, shared bool) {
+	val, err, shared, release := g.do(ctx, key, func(ctx context.Context) ([]byte, error) {
+		path, err := fn(ctx)
+		return []byte(path), err
+	}, func(val []byte) { os.Remove(string(val)) })
+	defer release()
+
+	if err != nil {
+		return nil, err, shared
+	}
+	file, err = os.Open(string(val))
+	return file, err, shared
+}
+
+// do runs or joins the call for key. The caller invokes release when it is
+// done with the result; cleanup, if set by the executing caller, runs on a
+// successful result after the last release.
+func (g *requestCoalescer) do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error), cleanup func(val []byte)) (val []byte, err error, shared bool, release func()) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		call.waiters++
+		call.refs++
+		call.users++
+		g.mu.Unlock()
+
+		release = func() { g.release(call) }
+		stop := context.AfterFunc(ctx, func() { g.leave(call) })
+		defer stop()
+		select {
+		case <-call.done:
+			domain.OutputDetailsFrom(ctx).Merge(call.details)
+			return call.val, call.err, true, release
+		case <-ctx.Done():
+			return nil, ctx.Err(), true, release
+		}
+	}
+
+	workCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	call := &coalescedCall{done: make(chan struct{}), refs: 1, users: 1, cancel: cancel, cleanup: cleanup,
+		details: domain.OutputDetailsFrom(ctx)}
+	g.calls[key] = call
+	g.mu.Unlock()
+	release = func() { g.release(call) }
+
+	stop := context.AfterFunc(ctx, func() { g.leave(call) })
+
+	// Release waiters even if fn panics
+	defer func() {
+		stop()
+		cancel()
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.val, call.err = fn(workCtx)
+	return call.val, call.err, false, release
+}
+
+// release marks a caller as done with the result of call, cleaning it up
+// after the last one. The executing caller releases only after fn has
+// returned, so the result is complete by then.
+func (g *requestCoalescer) release(call *coalescedCall) {
+	g.mu.Lock()
+	call.users--
+	last := call.users == 0
+	g.mu.Unlock()
+
+	if last && call.err == nil && call.cleanup != nil {
+		call.cleanup(call.val)
+	}
+}
+
+// leave drops a caller's interest in call, canceling the execution when it
+// was the last one
+func (g *requestCoalescer) leave(call *coalescedCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if call.refs--; call.refs == 0 {
+		call.cancel()
+	}
+}
+
+// waiting returns the number of callers currently waiting on another's
+// execution, across all keys
+func (g *requestCoalescer) waiting() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	total := 0
+	for _, call := range g.calls {
+		total += call.waiters
+	}
+	return total
+}
+
+// coalesceKey builds a content-addressed key from the operation, the input
+// bytes (or their digest) and the processing options
+func coalesceKey(operation string, content []byte, options ...interface{}) string {
+	h := sha256.New()
+	h.Write([]byte(operation))
+	h.Write([]byte{0})
+	h.Write(content)
+	for _, option := range options {
+		h.Write([]byte{0})
+		// encoding/json sorts map keys, so equal options hash equally
+		data, _ := json.Marshal(option)
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
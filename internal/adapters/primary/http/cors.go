@@ -0,0 +1,80 @@
+package http
+
+import (
+	"documents-worker/config"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// CORS builds the cross-origin middleware. Origins are matched against the
+// exact list first and then against the patterns; requests from other
+// origins get no CORS headers, so browsers reject them. Configuration errors
+// are returned rather than discovered on the first request.
+func CORS(cfg config.SecurityConfig) (fiber.Handler, error) {
+	allowAll := len(cfg.AllowedOrigins) == 0 && len(cfg.AllowedOriginPatterns) == 0
+	exact := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		normalized, err := normalizeOrigin(origin)
+		if err != nil {
+			return nil, err
+		}
+		exact[normalized] = true
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(cfg.AllowedOriginPatterns))
+	for _, pattern := range cfg.AllowedOriginPatterns {
+		// Anchor so a pattern cannot match part of an attacker's origin
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid CORS origin pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	if allowAll && cfg.AllowCredentials {
+		return nil, fmt.Errorf("CORS credentials cannot be allowed for every origin; list the allowed origins")
+	}
+
+	corsConfig := cors.Config{
+		AllowMethods:     strings.Join(cfg.AllowedMethods, ","),
+		AllowHeaders:     strings.Join(cfg.AllowedHeaders, ","),
+		ExposeHeaders:    strings.Join(cfg.ExposedHeaders, ","),
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.PreflightMaxAge.Seconds()),
+	}
+	if allowAll {
+		corsConfig.AllowOrigins = "*"
+	} else {
+		corsConfig.AllowOriginsFunc = func(origin string) bool {
+			if exact[origin] {
+				return true
+			}
+			for _, re := range patterns {
+				if re.MatchString(origin) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return cors.New(corsConfig), nil
+}
+
+// normalizeOrigin validates a configured origin and lowercases it the way
+// the middleware lowercases the Origin header
+func normalizeOrigin(origin string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return "", fmt.Errorf("invalid CORS origin %q: expected scheme://host[:port]", origin)
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
@@ -0,0 +1,234 @@
+package http
+
+import (
+	"documents-worker/config"
+	"documents-worker/recorder"
+	"encoding/json"
+	"io"
+	"mime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// debugEntryKey is the c.Locals key of the debug entry filled by spoolUpload
+const debugEntryKey = "debug.entry"
+
+// Limits of the parts of a debug entry
+const (
+	maxDebugBody     = 4 * 1024
+	maxDebugResponse = 1024
+	maxDebugValue    = 256
+)
+
+// DebugEntry is one captured request. It describes the upload but never
+// holds its content.
+type DebugEntry struct {
+	Time     time.Time         `json:"time"`
+	Method   string            `json:"method"`
+	Path     string            `json:"path"`
+	Query    string            `json:"query,omitempty"`
+	Subject  string            `json:"subject,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	Body     json.RawMessage   `json:"body,omitempty"`
+	Upload   *recorder.Input   `json:"upload,omitempty"`
+	Status   int               `json:"status"`
+	Duration string            `json:"duration"`
+	// Response describes the response; its body is kept for errors only
+	Response DebugResponse `json:"response"`
+	// Truncated is set when parts were dropped to fit the size cap
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// DebugResponse describes the response to a captured request
+type DebugResponse struct {
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+	Error       string `json:"error,omitempty"`
+}
+
+// upload records the description and fields of a spooled upload
+func (e *DebugEntry) upload(upload *spooledUpload, field string) {
+	e.Fields = upload.Fields
+	e.Upload = &recorder.Input{Field: field, Filename: upload.Filename, Size: upload.Size}
+}
+
+// debugEntryFrom returns the request's debug entry when it is captured
+func debugEntryFrom(c *fiber.Ctx) *DebugEntry {
+	entry, _ := c.Locals(debugEntryKey).(*DebugEntry)
+	return entry
+}
+
+// DebugCapture logs structured entries describing requests of callers
+// holding the configured role: their parameters, form fields or JSON body,
+// and the upload's name and size. Credentials and e-mail addresses are
+// redacted. Requests are sampled, entries are rate limited and capped in
+// size, so capture can stay on for a route while a failure is debugged.
+type DebugCapture struct {
+	config   config.DebugCaptureConfig
+	redactor *recorder.Redactor
+	out      io.Writer
+
+	requests atomic.Uint64
+	mu       sync.Mutex
+	window   time.Time
+	logged   int
+	now      func() time.Time
+}
+
+// NewDebugCapture creates a capture writing one JSON line per entry to out
+func NewDebugCapture(cfg config.DebugCaptureConfig, out io.Writer) *DebugCapture {
+	if cfg.MaxEntrySize <= 0 {
+		cfg.MaxEntrySize = 8 * 1024
+	}
+	return &DebugCapture{
+		config:   cfg,
+		redactor: recorder.NewRedactor(cfg.RedactFields),
+		out:      out,
+		now:      time.Now,
+	}
+}
+
+// Handler captures the requests of its route. It must run after
+// RequireAuth; other callers' requests are never captured.
+func (d *DebugCapture) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := PrincipalFrom(c)
+		if principal == nil || !principal.HasRole(d.config.Role) || !d.sampled() {
+			return c.Next()
+		}
+
+		start := d.now()
+		entry := &DebugEntry{Time: start, Method: c.Method(), Path: c.Path(), Subject: principal.Subject}
+		c.Locals(debugEntryKey, entry)
+
+		// Resolve handler errors here so the captured status is the one sent
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				c.Status(fiber.StatusInternalServerError)
+			}
+		}
+
+		if !d.allow() {
+			return nil
+		}
+		d.complete(c, entry, start)
+		d.write(entry)
+		return nil
+	}
+}
+
+// sampled picks one in SampleRate requests
+func (d *DebugCapture) sampled() bool {
+	if d.config.SampleRate <= 1 {
+		return true
+	}
+	return d.requests.Add(1)%uint64(d.config.SampleRate) == 1
+}
+
+// allow counts an entry against the per-minute cap
+func (d *DebugCapture) allow() bool {
+	if d.config.MaxPerMinute <= 0 {
+		return true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	if now.Sub(d.window) >= time.Minute {
+		d.window = now
+		d.logged = 0
+	}
+	if d.logged >= d.config.MaxPerMinute {
+		return false
+	}
+	d.logged++
+	return true
+}
+
+// complete fills in the request and response, redacted
+func (d *DebugCapture) complete(c *fiber.Ctx, entry *DebugEntry, start time.Time) {
+	headers := make(map[string]string)
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		headers[string(key)] = string(value)
+	})
+	entry.Headers = d.redactor.Headers(headers)
+	entry.Query = d.redactor.Query(string(c.Request().URI().QueryString()))
+	entry.Fields = d.redactor.Fields(entry.Fields)
+
+	// Only JSON bodies are kept; uploads are described by spoolUpload
+	mediaType, _, _ := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if entry.Upload == nil && mediaType == fiber.MIMEApplicationJSON {
+		body := c.Body()
+		switch {
+		case len(body) > maxDebugBody:
+			entry.Truncated = true
+		case len(body) > 0:
+			// Bodies that do not parse are left out rather than logged masked
+			if redacted := d.redactor.JSON(body); json.Valid(redacted) {
+				entry.Body = redacted
+			}
+		}
+	}
+
+	entry.Status = c.Response().StatusCode()
+	entry.Duration = d.now().Sub(start).Round(time.Microsecond).String()
+	entry.Response = DebugResponse{
+		ContentType: string(c.Response().Header.ContentType()),
+		Size:        len(c.Response().Body()),
+	}
+	if entry.Status >= fiber.StatusBadRequest {
+		response := c.Response().Body()
+		if len(response) > maxDebugResponse {
+			response = response[:maxDebugResponse]
+		}
+		entry.Response.Error = d.redactor.Text(string(response))
+	}
+}
+
+// write logs the entry, dropping its largest parts until it fits the size
+// cap
+func (d *DebugCapture) write(entry *DebugEntry) {
+	shrink := []func(){
+		func() { entry.Body = nil },
+		func() {
+			entry.Fields = truncateValues(entry.Fields)
+			entry.Headers = truncateValues(entry.Headers)
+		},
+		func() { entry.Headers = nil },
+		func() { entry.Fields = nil },
+		func() { entry.Response.Error = "" },
+	}
+
+	data, err := json.Marshal(entry)
+	for i := 0; err == nil && len(data) >= d.config.MaxEntrySize && i < len(shrink); i++ {
+		shrink[i]()
+		entry.Truncated = true
+		data, err = json.Marshal(entry)
+	}
+	if err != nil {
+		log.Errorf("Failed to encode debug entry: %v", err)
+		return
+	}
+	if len(data) >= d.config.MaxEntrySize {
+		log.Warnf("Debug entry for %s %s exceeds %d bytes, dropped", entry.Method, entry.Path, d.config.MaxEntrySize)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.out.Write(append(data, '\n'))
+}
+
+// truncateValues shortens long values
+func truncateValues(values map[string]string) map[string]string {
+	for name, value := range values {
+		if len(value) > maxDebugValue {
+			values[name] = value[:maxDebugValue] + "..."
+		}
+	}
+	return values
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// disconnectPollInterval is how often an idle connection is checked for a
+// client that has gone away while its request is being processed
+var disconnectPollInterval = 250 * time.Millisecond
+
+// clientContext returns a context that is canceled when the client closes
+// its connection, so processing stops instead of running to completion for
+// nobody. fasthttp does not report disconnects on its own, so the socket is
+// polled until the returned stop function is called.
+//
+// The context derives from the user context rather than the fasthttp
+// request context, whose Done channel races with server shutdown.
+func (h *DocumentHandler) clientContext(c *fiber.Ctx) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(c.UserContext())
+	conn := c.Context().Conn()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(disconnectPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			closed, ok := peerClosed(conn)
+			if !ok {
+				return
+			}
+			if closed {
+				h.disconnects.Add(1)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// CanceledByDisconnect returns how many requests had their processing
+// canceled because the client disconnected
+func (h *DocumentHandler) CanceledByDisconnect() int64 {
+	return h.disconnects.Load()
+}
+
+// WritePrometheus writes the handler counters in the Prometheus text format
+func (h *DocumentHandler) WritePrometheus(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP documents_worker_requests_canceled_total Requests whose processing was canceled because the client disconnected.\n# TYPE documents_worker_requests_canceled_total counter\ndocuments_worker_requests_canceled_total{reason=\"client_disconnect\"} %d\n", h.CanceledByDisconnect())
+	return err
+}
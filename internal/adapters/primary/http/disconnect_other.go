@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package http
+
+import "net"
+
+// peerClosed cannot detect disconnects on this platform
+func peerClosed(conn net.Conn) (closed, ok bool) {
+	return false, false
+}
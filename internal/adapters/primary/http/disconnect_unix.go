@@ -0,0 +1,45 @@
+//go:build linux || darwin
+
+package http
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// peerClosed peeks at the socket without consuming anything and reports
+// whether the client has closed the connection. ok is false when the
+// connection does not expose its file descriptor.
+func peerClosed(conn net.Conn) (closed, ok bool) {
+	sc, isSyscallConn := conn.(syscall.Conn)
+	if !isSyscallConn {
+		return false, false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false, false
+	}
+
+	var n int
+	var peekErr error
+	buf := make([]byte, 1)
+	err = raw.Read(func(fd uintptr) bool {
+		n, _, peekErr = syscall.Recvfrom(int(fd), buf, syscall.MSG_PEEK|syscall.MSG_DONTWAIT)
+		return true
+	})
+	if err != nil {
+		// The connection was closed on our side
+		return true, true
+	}
+
+	switch {
+	case errors.Is(peekErr, syscall.EAGAIN), errors.Is(peekErr, syscall.EWOULDBLOCK), errors.Is(peekErr, syscall.EINTR):
+		return false, true
+	case peekErr != nil:
+		return true, true
+	}
+	// A readable socket with nothing to read is at EOF; pipelined data
+	// means the client is still there
+	return n == 0, true
+}
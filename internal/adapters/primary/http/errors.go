@@ -0,0 +1,37 @@
+package http
+
+import (
+	"documents-worker/validation"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// apiError is the body ErrorHandler writes; the OpenAPI document describes
+// it as the Error schema
+type apiError struct {
+	Error      string                 `json:"error"`
+	Code       int                    `json:"code"`
+	Success    bool                   `json:"success"`
+	Violations []validation.Violation `json:"violations,omitempty"`
+}
+
+// ErrorHandler renders errors returned from handlers as JSON. Validation
+// errors are reported as 400 with a violation per failed field.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var validationErr *validation.Error
+	if errors.As(err, &validationErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(apiError{
+			Error:      "Validation failed",
+			Code:       fiber.StatusBadRequest,
+			Violations: validationErr.Violations,
+		})
+	}
+
+	code := fiber.StatusInternalServerError
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		code = fiberErr.Code
+	}
+	return c.Status(code).JSON(apiError{Error: err.Error(), Code: code})
+}
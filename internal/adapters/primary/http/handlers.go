@@ -1,8 +1,22 @@
 package http
 
 import (
+	"context"
 	"documents-worker/internal/core/domain"
 	"documents-worker/internal/core/ports"
+	"documents-worker/media"
+	"documents-worker/packaging"
+	"documents-worker/utils"
+	"documents-worker/validation"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -12,6 +26,10 @@ type DocumentHandler struct {
 	documentService ports.DocumentService
 	healthService   ports.HealthService
 	queueService    ports.QueueService
+	coalescer       *requestCoalescer
+	uploads         UploadConfig
+	disconnects     atomic.Int64
+	maxJobWait      time.Duration
 }
 
 // NewDocumentHandler creates a new document handler
@@ -19,11 +37,14 @@ func NewDocumentHandler(
 	documentService ports.DocumentService,
 	healthService ports.HealthService,
 	queueService ports.QueueService,
+	uploads UploadConfig,
 ) *DocumentHandler {
 	return &DocumentHandler{
 		documentService: documentService,
 		healthService:   healthService,
 		queueService:    queueService,
+		coalescer:       newRequestCoalescer(),
+		uploads:         uploads,
 	}
 }
 
@@ -33,9 +54,67 @@ type ProcessDocumentRequest struct {
 	Type       domain.ProcessingType  `json:"type" validate:"required"`
 	Parameters map[string]interface{} `json:"parameters,omitempty"`
 	Priority   int                    `json:"priority,omitempty"`
+	// Metadata is echoed in the job status, the result and job events
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
-// ProcessDocument handles document processing requests
+// Validate checks the request fields and reports every violation at once
+func (r *ProcessDocumentRequest) Validate() error {
+	v := validation.New().
+		Check(len(r.Metadata) <= domain.MaxMetadataEntries, "metadata", "max_entries", len(r.Metadata),
+			fmt.Sprintf("must not have more than %d entries", domain.MaxMetadataEntries))
+	for key, value := range r.Metadata {
+		v.Check(domain.ValidMetadataKey(key), "metadata", "key", key,
+			fmt.Sprintf("keys must be at most %d letters, digits or _ . : -", domain.MaxMetadataKeyLen))
+		v.Check(len(value) <= domain.MaxMetadataValueLen, "metadata."+key, "max_length", len(value),
+			fmt.Sprintf("must not exceed %d bytes", domain.MaxMetadataValueLen))
+	}
+	return v.
+		Required("document_id", r.DocumentID).
+		Required("type", string(r.Type)).
+		OneOf("type", string(r.Type),
+			string(domain.ProcessingTypeOCR),
+			string(domain.ProcessingTypeImageConvert),
+			string(domain.ProcessingTypeVideoConvert),
+			string(domain.ProcessingTypePDFGenerate),
+			string(domain.ProcessingTypeTextExtract),
+			string(domain.ProcessingTypeThumbnail),
+		).
+		Min("priority", r.Priority, 0).
+		Err()
+}
+
+// SetMaxJobWait caps how long job submissions may wait for the result;
+// zero, the default, disables waiting
+func (h *DocumentHandler) SetMaxJobWait(maxWait time.Duration) {
+	h.maxJobWait = maxWait
+}
+
+// jobWait parses the wait query parameter, a duration such as 5s or a
+// number of seconds, capped at the configured maximum
+func (h *DocumentHandler) jobWait(c *fiber.Ctx) (time.Duration, error) {
+	value := c.Query("wait")
+	if value == "" || h.maxJobWait <= 0 {
+		return 0, nil
+	}
+
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.ParseFloat(value, 64)
+		if convErr != nil {
+			return 0, validation.New().Check(false, "wait", "duration", value, "must be a duration such as 5s or a number of seconds").Err()
+		}
+		wait = time.Duration(seconds * float64(time.Second))
+	}
+	if err := validation.New().Check(wait >= 0, "wait", "min", value, "must not be negative").Err(); err != nil {
+		return 0, err
+	}
+	return min(wait, h.maxJobWait), nil
+}
+
+// ProcessDocument handles document processing requests. With ?wait=5s the
+// request waits up to that long for the job: a finished job is returned with
+// 200, one still running is returned for polling with 202 as without wait.
 func (h *DocumentHandler) ProcessDocument(c *fiber.Ctx) error {
 	var req ProcessDocumentRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -45,11 +124,21 @@ func (h *DocumentHandler) ProcessDocument(c *fiber.Ctx) error {
 		})
 	}
 
+	req.Metadata = domain.CleanMetadata(req.Metadata)
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	wait, err := h.jobWait(c)
+	if err != nil {
+		return err
+	}
+
 	processingReq := &domain.ProcessingRequest{
 		DocumentID: req.DocumentID,
 		Type:       req.Type,
 		Parameters: req.Parameters,
 		Priority:   req.Priority,
+		Metadata:   req.Metadata,
 	}
 
 	result, err := h.documentService.ProcessDocument(c.Context(), processingReq)
@@ -59,6 +148,16 @@ func (h *DocumentHandler) ProcessDocument(c *fiber.Ctx) error {
 			"details": err.Error(),
 		})
 	}
+	meterVideoJob(c.UserContext(), h.documentService, processingReq)
+
+	if wait > 0 {
+		ctx, stop := h.clientContext(c)
+		job, err := h.documentService.WaitForJob(ctx, result.JobID, wait)
+		stop()
+		if err == nil && job.Status.IsTerminal() {
+			return c.JSON(job)
+		}
+	}
 
 	return c.Status(fiber.StatusAccepted).JSON(result)
 }
@@ -103,6 +202,35 @@ func (h *DocumentHandler) GetJob(c *fiber.Ctx) error {
 	return c.JSON(job)
 }
 
+// GetJobResult downloads the stored output of a completed job, redirecting
+// to a presigned URL when the storage issues them
+func (h *DocumentHandler) GetJobResult(c *fiber.Ctx) error {
+	output, err := h.documentService.GetJobResult(c.Context(), c.Params("jobId"))
+	switch {
+	case errors.Is(err, domain.ErrJobNotCompleted):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Job has not completed",
+		})
+	case errors.Is(err, domain.ErrJobNotFound), errors.Is(err, domain.ErrResultNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "Job result not found",
+			"details": err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to get job result",
+			"details": err.Error(),
+		})
+	}
+
+	if output.URL != "" {
+		return c.Redirect(output.URL, fiber.StatusFound)
+	}
+	c.Type(path.Ext(output.Key))
+	c.Set("Content-Disposition", "attachment; filename=\""+path.Base(output.Key)+"\"")
+	return c.SendStream(output.Content)
+}
+
 // GetJobsByDocument retrieves all jobs for a document
 func (h *DocumentHandler) GetJobsByDocument(c *fiber.Ctx) error {
 	documentID := c.Params("id")
@@ -125,42 +253,125 @@ func (h *DocumentHandler) GetJobsByDocument(c *fiber.Ctx) error {
 
 // ConvertImageRequest represents an image conversion request
 type ConvertImageRequest struct {
-	OutputFormat string                 `json:"output_format" validate:"required"`
-	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+	OutputFormat string `json:"output_format" form:"output_format" validate:"required"`
+	// Page selects a page of a multi-page TIFF, or "all" to stack every
+	// page into one image
+	Page string `json:"page,omitempty" form:"page"`
+	// TargetSize is the largest output size in bytes; the highest quality
+	// that fits is chosen
+	TargetSize string                 `json:"target_size,omitempty" form:"target_size"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
 }
 
-// ConvertImage handles image conversion requests
-func (h *DocumentHandler) ConvertImage(c *fiber.Ctx) error {
-	var req ConvertImageRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid request body",
-			"details": err.Error(),
-		})
+// Validate checks the request fields and reports every violation at once
+func (r *ConvertImageRequest) Validate() error {
+	v := validation.New().
+		Required("output_format", r.OutputFormat).
+		OneOf("output_format", r.OutputFormat, "jpg", "jpeg", "png", "webp", "avif")
+	if r.Page != "" && r.Page != "all" {
+		page, err := strconv.Atoi(r.Page)
+		v.Check(err == nil && page >= 1, "page", "page", r.Page, "must be a page number from 1 or all")
+	}
+	if r.TargetSize != "" {
+		size, err := strconv.Atoi(r.TargetSize)
+		v.Check(err == nil && size >= 1, "target_size", "min", r.TargetSize, "must be a size in bytes of at least 1")
 	}
+	return v.Err()
+}
 
-	// Get file from multipart form
-	file, err := c.FormFile("file")
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "No file provided",
-			"details": err.Error(),
-		})
+// parameters maps the page selection and target size onto processor
+// parameters
+func (r *ConvertImageRequest) parameters() map[string]interface{} {
+	params := make(map[string]interface{})
+	switch r.Page {
+	case "":
+	case "all":
+		params["all_pages"] = true
+	default:
+		page, _ := strconv.Atoi(r.Page)
+		params["page"] = page
+	}
+	if r.TargetSize != "" {
+		size, _ := strconv.Atoi(r.TargetSize)
+		params["target_size"] = size
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// targetSizeHeaders name the response headers reporting the outcome of a
+// target size search
+var targetSizeHeaders = map[string]string{
+	"target_size":      "X-Target-Size",
+	"achieved_size":    "X-Achieved-Size",
+	"achieved_quality": "X-Achieved-Quality",
+	"target_met":       "X-Target-Met",
+}
+
+// setOutputHeaders reports the output details of a conversion as response
+// headers
+func setOutputHeaders(c *fiber.Ctx, details *domain.OutputDetails) {
+	detail, _ := details.Get(domain.OutputDetailTargetSize)
+	result, ok := detail.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for key, header := range targetSizeHeaders {
+		if value, ok := result[key]; ok {
+			c.Set(header, fmt.Sprint(value))
+		}
 	}
+}
 
-	// Open file
-	src, err := file.Open()
+// ConvertImage handles image conversion requests
+func (h *DocumentHandler) ConvertImage(c *fiber.Ctx) error {
+	// Stream the upload to disk so large files never sit in memory
+	upload, err := spoolUpload(c, "file", h.uploads)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to open file",
-			"details": err.Error(),
-		})
+		return err
 	}
-	defer src.Close()
+	defer upload.Release()
 
-	// Convert image
-	result, err := h.documentService.ConvertImage(c.Context(), src, req.OutputFormat, req.Parameters)
+	req := ConvertImageRequest{
+		OutputFormat: upload.Fields["output_format"],
+		Page:         strings.ToLower(strings.TrimSpace(upload.Fields["page"])),
+		TargetSize:   strings.TrimSpace(upload.Fields["target_size"]),
+	}
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	req.Parameters = req.parameters()
+
+	ctx, stop := h.clientContext(c)
+	defer stop()
+	details := domain.NewOutputDetails()
+	ctx = domain.WithOutputDetails(ctx, details)
+
+	// Identical concurrent requests share a single conversion
+	key := coalesceKey("image_convert", upload.Hash, req.OutputFormat, req.Parameters)
+	result, err, _ := h.coalescer.DoFile(ctx, key, func(ctx context.Context) (string, error) {
+		input, err := upload.Reader()
+		if err != nil {
+			return "", err
+		}
+		output, err := h.documentService.ConvertImage(ctx, input, req.OutputFormat, req.Parameters)
+		if err != nil {
+			return "", err
+		}
+		return outputFile(output)
+	})
 	if err != nil {
+		var tooLarge *utils.OutputTooLargeError
+		if errors.As(err, &tooLarge) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":   "Output exceeds maximum size",
+				"details": err.Error(),
+				"size":    tooLarge.Size,
+				"limit":   tooLarge.Limit,
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "Failed to convert image",
 			"details": err.Error(),
@@ -182,10 +393,303 @@ func (h *DocumentHandler) ConvertImage(c *fiber.Ctx) error {
 
 	c.Set("Content-Type", contentType)
 	c.Set("Content-Disposition", "attachment; filename=\"converted."+req.OutputFormat+"\"")
+	setOutputHeaders(c, details)
 
 	return c.SendStream(result)
 }
 
+// ConvertVideoRequest represents a video conversion request
+type ConvertVideoRequest struct {
+	OutputFormat string `json:"output_format" form:"output_format" validate:"required"`
+}
+
+// Validate checks the request fields and reports every violation at once
+func (r *ConvertVideoRequest) Validate() error {
+	return validation.New().
+		Required("output_format", r.OutputFormat).
+		OneOf("output_format", r.OutputFormat, "webm", "gif", "webp").
+		Err()
+}
+
+// ConvertVideo handles video conversion requests. The length of the input
+// is charged to the caller's video quota once the conversion succeeds.
+func (h *DocumentHandler) ConvertVideo(c *fiber.Ctx) error {
+	upload, err := spoolUpload(c, "file", h.uploads)
+	if err != nil {
+		return err
+	}
+	defer upload.Release()
+
+	req := ConvertVideoRequest{OutputFormat: upload.Fields["output_format"]}
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	ctx, stop := h.clientContext(c)
+	defer stop()
+
+	// Identical concurrent requests share a single conversion; only the
+	// request that runs it is charged for the video time
+	key := coalesceKey("video_convert", upload.Hash, req.OutputFormat)
+	result, err, _ := h.coalescer.DoFile(ctx, key, func(ctx context.Context) (string, error) {
+		input, err := upload.Reader()
+		if err != nil {
+			return "", err
+		}
+		output, err := h.documentService.ConvertVideo(ctx, input, req.OutputFormat, nil)
+		if err != nil {
+			return "", err
+		}
+		return outputFile(output)
+	})
+	if err != nil {
+		var tooLarge *utils.OutputTooLargeError
+		if errors.As(err, &tooLarge) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error":   "Output exceeds maximum size",
+				"details": err.Error(),
+				"size":    tooLarge.Size,
+				"limit":   tooLarge.Limit,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to convert video",
+			"details": err.Error(),
+		})
+	}
+
+	contentType := "video/webm"
+	switch req.OutputFormat {
+	case "gif":
+		contentType = "image/gif"
+	case "webp":
+		contentType = "image/webp"
+	}
+
+	c.Set("Content-Type", contentType)
+	c.Set("Content-Disposition", "attachment; filename=\"converted."+req.OutputFormat+"\"")
+
+	return c.SendStream(result)
+}
+
+// PDFThumbnailRequest represents a PDF cover thumbnail request
+type PDFThumbnailRequest struct {
+	Page int `json:"page" form:"page"`
+	Size int `json:"size" form:"size"`
+
+	invalid []string
+}
+
+// parsePDFThumbnailRequest reads the page and size fields, defaulting to
+// the first page at the default size
+func parsePDFThumbnailRequest(fields map[string]string) PDFThumbnailRequest {
+	req := PDFThumbnailRequest{Page: 1, Size: media.DefaultPDFThumbnailSize}
+	for _, field := range []struct {
+		name   string
+		target *int
+	}{{"page", &req.Page}, {"size", &req.Size}} {
+		raw := strings.TrimSpace(fields[field.name])
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			req.invalid = append(req.invalid, field.name)
+			continue
+		}
+		*field.target = value
+	}
+	return req
+}
+
+// Validate checks the request fields and reports every violation at once
+func (r *PDFThumbnailRequest) Validate() error {
+	v := validation.New()
+	for _, field := range r.invalid {
+		v.Check(false, field, "integer", nil, "must be an integer")
+	}
+	return v.
+		Min("page", r.Page, 1).
+		Range("size", r.Size, 1, media.MaxPDFThumbnailSize).
+		Err()
+}
+
+// GeneratePDFThumbnail renders a page of an uploaded PDF (the cover by
+// default) as a PNG preview
+func (h *DocumentHandler) GeneratePDFThumbnail(c *fiber.Ctx) error {
+	upload, err := spoolUpload(c, "file", h.uploads)
+	if err != nil {
+		return err
+	}
+	defer upload.Release()
+
+	req := parsePDFThumbnailRequest(upload.Fields)
+	if err := req.Validate(); err != nil {
+		return err
+	}
+
+	ctx, stop := h.clientContext(c)
+	defer stop()
+
+	key := coalesceKey("pdf_thumbnail", upload.Hash, req.Page, req.Size)
+	result, err, _ := h.coalescer.DoFile(ctx, key, func(ctx context.Context) (string, error) {
+		input, err := upload.Reader()
+		if err != nil {
+			return "", err
+		}
+		output, err := h.documentService.GeneratePDFThumbnail(ctx, input, req.Page, req.Size)
+		if err != nil {
+			return "", err
+		}
+		return outputFile(output)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to generate PDF thumbnail",
+			"details": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", "image/png")
+	c.Set("Content-Disposition", fmt.Sprintf("inline; filename=\"page_%d.png\"", req.Page))
+
+	return c.SendStream(result)
+}
+
+// ExtractTextPages extracts the text of every page of an uploaded PDF. With
+// package=zip the pages are streamed back as a zip of text files. With
+// mode=auto scanned pages are OCRed; otherwise they are flagged as
+// requiring OCR.
+func (h *DocumentHandler) ExtractTextPages(c *fiber.Ctx) error {
+	upload, err := spoolUpload(c, "file", h.uploads)
+	if err != nil {
+		return err
+	}
+	defer upload.Release()
+
+	packaged, err := packageOption(c, upload.Fields)
+	if err != nil {
+		return err
+	}
+	mode, err := extractionMode(c, upload.Fields)
+	if err != nil {
+		return err
+	}
+
+	input, err := upload.Reader()
+	if err != nil {
+		return err
+	}
+	ctx, stop := h.clientContext(c)
+	defer stop()
+
+	pages, err := h.documentService.ExtractTextPages(ctx, input, mode)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to extract text",
+			"details": err.Error(),
+		})
+	}
+
+	requiresOCR := false
+	for _, page := range pages {
+		requiresOCR = requiresOCR || page.RequiresOCR
+	}
+
+	if !packaged {
+		return c.JSON(fiber.Map{
+			"pages":        pages,
+			"total_pages":  len(pages),
+			"requires_ocr": requiresOCR,
+		})
+	}
+
+	entries := make([]packaging.Entry, len(pages))
+	for i, page := range pages {
+		entries[i] = packaging.BytesEntry(fmt.Sprintf("page_%03d.txt", page.Page), []byte(page.Text), "text/plain")
+		entries[i].Metadata = map[string]interface{}{"page": page.Page}
+		if page.Source != "" {
+			entries[i].Metadata["source"] = page.Source
+		}
+		if page.RequiresOCR {
+			entries[i].Metadata["requires_ocr"] = true
+		}
+	}
+	return sendPackage(c, "pages.zip", "text_pages", entries)
+}
+
+// ExtractPDFOutline returns the outline (bookmark) tree of an uploaded PDF
+// with the title, level and target page of each entry. PDFs without
+// bookmarks return an empty outline.
+func (h *DocumentHandler) ExtractPDFOutline(c *fiber.Ctx) error {
+	upload, err := spoolUpload(c, "file", h.uploads)
+	if err != nil {
+		return err
+	}
+	defer upload.Release()
+
+	input, err := upload.Reader()
+	if err != nil {
+		return err
+	}
+	ctx, stop := h.clientContext(c)
+	defer stop()
+
+	outline, err := h.documentService.ExtractPDFOutline(ctx, input)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to extract outline",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"outline":     outline,
+		"has_outline": len(outline) > 0,
+	})
+}
+
+// extractionMode reads the mode form field or query parameter, text by
+// default
+func extractionMode(c *fiber.Ctx, fields map[string]string) (domain.ExtractionMode, error) {
+	mode := strings.ToLower(fields["mode"])
+	if mode == "" {
+		mode = strings.ToLower(c.Query("mode"))
+	}
+	if mode == "" {
+		return domain.ExtractionModeText, nil
+	}
+
+	err := validation.New().
+		OneOf("mode", mode, string(domain.ExtractionModeText), string(domain.ExtractionModeAuto)).
+		Err()
+	return domain.ExtractionMode(mode), err
+}
+
+// outputFile returns the path of a processor output so it can be shared
+// between coalesced requests. Temp file outputs are closed and used in
+// place; other readers are copied to a temp file.
+func outputFile(output io.Reader) (string, error) {
+	if file, ok := output.(*os.File); ok {
+		file.Close()
+		return file.Name(), nil
+	}
+	if closer, ok := output.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	file, err := os.CreateTemp("", "output-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, output); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write output: %w", err)
+	}
+	return file.Name(), nil
+}
+
 // HealthCheck handles health check requests
 func (h *DocumentHandler) HealthCheck(c *fiber.Ctx) error {
 	health, err := h.healthService.GetHealthStatus(c.Context())
@@ -234,10 +738,15 @@ func (h *DocumentHandler) SetupRoutes(app *fiber.App) {
 	// Job endpoints
 	jobs := api.Group("/jobs")
 	jobs.Get("/:jobId", h.GetJob)
+	jobs.Get("/:jobId/result", h.GetJobResult)
 
 	// Processing endpoints
 	processing := api.Group("/process")
 	processing.Post("/image/convert", h.ConvertImage)
+	processing.Post("/video/convert", h.ConvertVideo)
+	processing.Post("/text/pages", h.ExtractTextPages)
+	processing.Post("/pdf/thumbnail", h.GeneratePDFThumbnail)
+	processing.Post("/pdf/outline", h.ExtractPDFOutline)
 	// Add more processing endpoints here
 }
 
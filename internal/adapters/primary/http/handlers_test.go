@@ -0,0 +1,1670 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"documents-worker/auth"
+	"documents-worker/config"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
+	"documents-worker/memory"
+	"documents-worker/packaging"
+	"documents-worker/quota"
+	"documents-worker/recorder"
+	"documents-worker/validation"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDocumentService overrides the service methods a test needs; calling
+// any other method panics on the nil embedded interface
+type fakeDocumentService struct {
+	ports.DocumentService
+	convertImage     func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error)
+	convertVideo     func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error)
+	extractTextPages func(ctx context.Context, input io.Reader, mode domain.ExtractionMode) ([]domain.PageText, error)
+	extractOutline   func(ctx context.Context, input io.Reader) ([]domain.OutlineItem, error)
+	pdfThumbnail     func(ctx context.Context, input io.Reader, page, size int) (io.Reader, error)
+	processDocument  func(ctx context.Context, req *domain.ProcessingRequest) (*domain.ProcessingResult, error)
+	getDocument      func(ctx context.Context, id string) (*domain.Document, error)
+	getJobResult     func(ctx context.Context, jobID string) (*domain.JobOutput, error)
+	waitForJob       func(ctx context.Context, jobID string, maxWait time.Duration) (*domain.ProcessingJob, error)
+}
+
+func (f *fakeDocumentService) ProcessDocument(ctx context.Context, req *domain.ProcessingRequest) (*domain.ProcessingResult, error) {
+	return f.processDocument(ctx, req)
+}
+
+func (f *fakeDocumentService) GetDocument(ctx context.Context, id string) (*domain.Document, error) {
+	if f.getDocument == nil {
+		return nil, domain.ErrDocumentNotFound
+	}
+	return f.getDocument(ctx, id)
+}
+
+func (f *fakeDocumentService) GetJobResult(ctx context.Context, jobID string) (*domain.JobOutput, error) {
+	return f.getJobResult(ctx, jobID)
+}
+
+func (f *fakeDocumentService) WaitForJob(ctx context.Context, jobID string, maxWait time.Duration) (*domain.ProcessingJob, error) {
+	return f.waitForJob(ctx, jobID, maxWait)
+}
+
+func (f *fakeDocumentService) GeneratePDFThumbnail(ctx context.Context, input io.Reader, page, size int) (io.Reader, error) {
+	return f.pdfThumbnail(ctx, input, page, size)
+}
+
+func (f *fakeDocumentService) ExtractTextPages(ctx context.Context, input io.Reader, mode domain.ExtractionMode) ([]domain.PageText, error) {
+	return f.extractTextPages(ctx, input, mode)
+}
+
+func (f *fakeDocumentService) ExtractPDFOutline(ctx context.Context, input io.Reader) ([]domain.OutlineItem, error) {
+	return f.extractOutline(ctx, input)
+}
+
+func (f *fakeDocumentService) ConvertImage(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+	return f.convertImage(ctx, input, outputFormat, params)
+}
+
+func (f *fakeDocumentService) ConvertVideo(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+	return f.convertVideo(ctx, input, outputFormat, params)
+}
+
+func newTestApp(service ports.DocumentService) (*fiber.App, *DocumentHandler) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	handler := NewDocumentHandler(service, nil, nil, UploadConfig{})
+	handler.SetupRoutes(app)
+	return app, handler
+}
+
+// newUploadRequest builds a multipart POST to target uploading content as
+// filename along with the form fields
+func newUploadRequest(t *testing.T, target, filename, content string, fields map[string]string) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest("POST", target, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestConvertImageCoalescesIdenticalRequests(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			data, _ := io.ReadAll(input)
+			return strings.NewReader("converted:" + string(data)), nil
+		},
+	}
+	app, handler := newTestApp(service)
+
+	const requests = 8
+	var wg sync.WaitGroup
+	bodies := make([]string, requests)
+	statuses := make([]int, requests)
+
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", "same-image", map[string]string{"output_format": "webp"})
+
+			resp, err := app.Test(req, -1)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+			data, _ := io.ReadAll(resp.Body)
+			statuses[i] = resp.StatusCode
+			bodies[i] = string(data)
+		}(i)
+	}
+
+	// Wait until every other request is parked on the in-flight conversion
+	require.Eventually(t, func() bool {
+		return handler.coalescer.waiting() == requests-1
+	}, 5*time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "identical requests should be processed once")
+	for i := 0; i < requests; i++ {
+		assert.Equal(t, fiber.StatusOK, statuses[i])
+		assert.Equal(t, "converted:same-image", bodies[i])
+	}
+}
+
+func TestConvertImageDoesNotCoalesceDifferentRequests(t *testing.T) {
+	var calls int32
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			atomic.AddInt32(&calls, 1)
+			return strings.NewReader(outputFormat), nil
+		},
+	}
+	app, _ := newTestApp(service)
+
+	for _, tc := range []struct{ content, format string }{
+		{"a", "webp"},
+		{"b", "webp"},
+		{"a", "png"},
+	} {
+		req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", tc.content, map[string]string{"output_format": tc.format})
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestConvertImageReportsTargetSize(t *testing.T) {
+	var got map[string]interface{}
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			got = params
+			domain.OutputDetailsFrom(ctx).Set(domain.OutputDetailTargetSize, map[string]interface{}{
+				"target_size":      50000,
+				"achieved_quality": 72,
+				"achieved_size":    int64(48210),
+				"iterations":       5,
+				"target_met":       true,
+			})
+			return strings.NewReader("converted"), nil
+		},
+	}
+	app, _ := newTestApp(service)
+
+	req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", "image",
+		map[string]string{"output_format": "jpg", "target_size": "50000"})
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, map[string]interface{}{"target_size": 50000}, got)
+	assert.Equal(t, "50000", resp.Header.Get("X-Target-Size"))
+	assert.Equal(t, "48210", resp.Header.Get("X-Achieved-Size"))
+	assert.Equal(t, "72", resp.Header.Get("X-Achieved-Quality"))
+	assert.Equal(t, "true", resp.Header.Get("X-Target-Met"))
+
+	req = newUploadRequest(t, "/api/v1/process/image/convert", "input.png", "image",
+		map[string]string{"output_format": "jpg", "target_size": "0"})
+	resp, err = app.Test(req, -1)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	data, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(data), `"field":"target_size"`)
+}
+
+func TestCoalesceKey(t *testing.T) {
+	params := map[string]interface{}{"width": 100, "quality": 80}
+	sameParams := map[string]interface{}{"quality": 80, "width": 100}
+
+	assert.Equal(t,
+		coalesceKey("image_convert", []byte("x"), "webp", params),
+		coalesceKey("image_convert", []byte("x"), "webp", sameParams))
+	assert.NotEqual(t,
+		coalesceKey("image_convert", []byte("x"), "webp", params),
+		coalesceKey("image_convert", []byte("y"), "webp", params))
+}
+
+func TestCoalescerCancelsOnlyWhenEveryCallerLeaves(t *testing.T) {
+	g := newRequestCoalescer()
+	started := make(chan struct{})
+	workCanceled := make(chan struct{})
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	waiterCtx, cancelWaiter := context.WithCancel(context.Background())
+
+	go g.Do(leaderCtx, "key", func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-ctx.Done()
+		close(workCanceled)
+		return nil, ctx.Err()
+	})
+	<-started
+
+	waiterErr := make(chan error, 1)
+	go func() {
+		_, err, shared := g.Do(waiterCtx, "key", func(ctx context.Context) ([]byte, error) {
+			return nil, nil
+		})
+		assert.True(t, shared)
+		waiterErr <- err
+	}()
+	require.Eventually(t, func() bool { return g.waiting() == 1 }, 5*time.Second, time.Millisecond)
+
+	// The waiter still wants the result, so the work continues
+	cancelLeader()
+	select {
+	case <-workCanceled:
+		t.Fatal("work canceled while a caller was still waiting")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancelWaiter()
+	select {
+	case <-workCanceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("work not canceled after every caller left")
+	}
+	assert.ErrorIs(t, <-waiterErr, context.Canceled)
+}
+
+func TestCoalescerSharesOutputDetails(t *testing.T) {
+	g := newRequestCoalescer()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	leader := domain.NewOutputDetails()
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		g.Do(domain.WithOutputDetails(context.Background(), leader), "key", func(ctx context.Context) ([]byte, error) {
+			close(started)
+			<-release
+			domain.OutputDetailsFrom(ctx).Set("achieved_quality", 72)
+			return []byte("converted"), nil
+		})
+	}()
+	<-started
+
+	waiter := domain.NewOutputDetails()
+	waiterDone := make(chan struct{})
+	go func() {
+		defer close(waiterDone)
+		_, err, shared := g.Do(domain.WithOutputDetails(context.Background(), waiter), "key", func(ctx context.Context) ([]byte, error) {
+			return nil, nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, shared)
+	}()
+	require.Eventually(t, func() bool { return g.waiting() == 1 }, 5*time.Second, time.Millisecond)
+	close(release)
+	<-leaderDone
+	<-waiterDone
+
+	for _, details := range []*domain.OutputDetails{leader, waiter} {
+		quality, ok := details.Get("achieved_quality")
+		assert.True(t, ok)
+		assert.Equal(t, 72, quality)
+	}
+}
+
+func TestCoalescerDoFileHandsEachCallerItsOwnHandle(t *testing.T) {
+	g := newRequestCoalescer()
+	release := make(chan struct{})
+	output := filepath.Join(t.TempDir(), "output.webp")
+
+	const callers = 4
+	var wg sync.WaitGroup
+	files := make([]*os.File, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			file, err, _ := g.DoFile(context.Background(), "key", func(ctx context.Context) (string, error) {
+				<-release
+				return output, os.WriteFile(output, []byte("converted"), 0644)
+			})
+			assert.NoError(t, err)
+			files[i] = file
+		}(i)
+	}
+	require.Eventually(t, func() bool { return g.waiting() == callers-1 }, 5*time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// The shared file is gone once every caller holds a handle
+	assert.NoFileExists(t, output)
+	for _, file := range files {
+		require.NotNil(t, file)
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+		assert.Equal(t, "converted", string(data))
+		file.Close()
+	}
+}
+
+func TestConvertImageCancelsWhenClientDisconnects(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("disconnect detection is not supported on " + runtime.GOOS)
+	}
+	interval := disconnectPollInterval
+	disconnectPollInterval = 10 * time.Millisecond
+	defer func() { disconnectPollInterval = interval }()
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			close(started)
+			select {
+			case <-ctx.Done():
+				close(canceled)
+				return nil, ctx.Err()
+			case <-time.After(10 * time.Second):
+				return strings.NewReader("too late"), nil
+			}
+		},
+	}
+	app, handler := newTestApp(service)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", "image", map[string]string{"output_format": "webp"})
+	require.NoError(t, req.Write(conn))
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("conversion never started")
+	}
+	conn.Close()
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("conversion not canceled after the client disconnected")
+	}
+	assert.Eventually(t, func() bool { return handler.CanceledByDisconnect() == 1 }, 5*time.Second, time.Millisecond)
+
+	var metrics bytes.Buffer
+	require.NoError(t, handler.WritePrometheus(&metrics))
+	assert.Contains(t, metrics.String(), `documents_worker_requests_canceled_total{reason="client_disconnect"} 1`)
+}
+
+func TestProcessDocumentReportsAllViolations(t *testing.T) {
+	app, _ := newTestApp(&fakeDocumentService{})
+
+	body := strings.NewReader(`{"type":"transcode","priority":-1}`)
+	req := httptest.NewRequest("POST", "/api/v1/documents/process", body)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	var payload struct {
+		Error      string `json:"error"`
+		Code       int    `json:"code"`
+		Success    bool   `json:"success"`
+		Violations []struct {
+			Field string      `json:"field"`
+			Rule  string      `json:"rule"`
+			Value interface{} `json:"value"`
+		} `json:"violations"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+
+	assert.Equal(t, "Validation failed", payload.Error)
+	assert.Equal(t, fiber.StatusBadRequest, payload.Code)
+	assert.False(t, payload.Success)
+	require.Len(t, payload.Violations, 3)
+	assert.Equal(t, "document_id", payload.Violations[0].Field)
+	assert.Equal(t, "required", payload.Violations[0].Rule)
+	assert.Equal(t, "type", payload.Violations[1].Field)
+	assert.Equal(t, "one_of", payload.Violations[1].Rule)
+	assert.Equal(t, "transcode", payload.Violations[1].Value)
+	assert.Equal(t, "priority", payload.Violations[2].Field)
+	assert.Equal(t, "min", payload.Violations[2].Rule)
+}
+
+func TestConvertImageRejectsUnknownFormat(t *testing.T) {
+	app, _ := newTestApp(&fakeDocumentService{})
+
+	req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", "image", map[string]string{"output_format": "bmp"})
+
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	data, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(data), `"field":"output_format"`)
+	assert.Contains(t, string(data), `"rule":"one_of"`)
+}
+
+func TestConvertImageSelectsTIFFPages(t *testing.T) {
+	var got map[string]interface{}
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			got = params
+			return strings.NewReader("converted"), nil
+		},
+	}
+	app, _ := newTestApp(service)
+
+	send := func(page string) *http.Response {
+		req := newUploadRequest(t, "/api/v1/process/image/convert", "fax.tif", "II*\x00",
+			map[string]string{"output_format": "png", "page": page})
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := send("2")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"page": 2}, got)
+
+	resp = send("ALL")
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]interface{}{"all_pages": true}, got)
+
+	resp = send("0")
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	data, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(data), `"field":"page"`)
+}
+
+func newStreamingTestApp(service ports.DocumentService, uploads UploadConfig) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler:                 ErrorHandler,
+		BodyLimit:                    64 * 1024,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	NewDocumentHandler(service, nil, nil, uploads).SetupRoutes(app)
+	return app
+}
+
+func TestConvertImageSpoolsLargeUploadToDisk(t *testing.T) {
+	tempDir := t.TempDir()
+	const size = 32 * 1024 * 1024
+	content := bytes.Repeat([]byte("0123456789abcdef"), size/16)
+
+	var received int64
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			file, ok := input.(*os.File)
+			require.True(t, ok, "upload should be handed over as a spooled file")
+			assert.Equal(t, tempDir, filepath.Dir(file.Name()))
+
+			hash := sha256.New()
+			n, err := io.Copy(hash, file)
+			require.NoError(t, err)
+			expected := sha256.Sum256(content)
+			assert.Equal(t, expected[:], hash.Sum(nil))
+			atomic.StoreInt64(&received, n)
+			return strings.NewReader("ok"), nil
+		},
+	}
+	app := newStreamingTestApp(service, UploadConfig{TempDir: tempDir, MaxFileSize: 64 * 1024 * 1024})
+
+	req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", string(content), map[string]string{"output_format": "webp"})
+
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, int64(size), atomic.LoadInt64(&received))
+
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "spooled upload should be removed after the request")
+}
+
+func TestConvertImageRejectsOversizedUpload(t *testing.T) {
+	tempDir := t.TempDir()
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			t.Fatal("oversized upload should not reach the service")
+			return nil, nil
+		},
+	}
+	app := newStreamingTestApp(service, UploadConfig{TempDir: tempDir, MaxFileSize: 1024 * 1024})
+
+	req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", strings.Repeat("x", 2*1024*1024), map[string]string{"output_format": "webp"})
+
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "partial upload should be removed on error")
+}
+
+func TestConvertImageRejectsSpoofedUpload(t *testing.T) {
+	tempDir := t.TempDir()
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			t.Fatal("spoofed upload should not reach the service")
+			return nil, nil
+		},
+	}
+	app := newStreamingTestApp(service, UploadConfig{TempDir: tempDir, VerifyContent: true})
+
+	req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", "%PDF-1.4 renamed to a PNG", map[string]string{"output_format": "webp"})
+
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	var apiErr apiError
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&apiErr))
+	require.Len(t, apiErr.Violations, 1)
+	assert.Equal(t, "content_type", apiErr.Violations[0].Rule)
+
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "rejected upload should be removed")
+}
+
+type fakeScanner struct {
+	result *validation.ScanResult
+	err    error
+}
+
+func (s fakeScanner) Scan(ctx context.Context, r io.Reader) (*validation.ScanResult, error) {
+	io.Copy(io.Discard, r)
+	return s.result, s.err
+}
+
+func TestConvertImageScansUploads(t *testing.T) {
+	var converted int
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			converted++
+			return strings.NewReader("ok"), nil
+		},
+	}
+	send := func(uploads UploadConfig) *http.Response {
+		req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", "image", map[string]string{"output_format": "webp"})
+		resp, err := newStreamingTestApp(service, uploads).Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	infected := send(UploadConfig{TempDir: t.TempDir(), Scanner: fakeScanner{result: &validation.ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}}})
+	assert.Equal(t, fiber.StatusBadRequest, infected.StatusCode)
+	var apiErr apiError
+	require.NoError(t, json.NewDecoder(infected.Body).Decode(&apiErr))
+	require.Len(t, apiErr.Violations, 1)
+	assert.Equal(t, "malware", apiErr.Violations[0].Rule)
+	assert.Equal(t, "Eicar-Test-Signature", apiErr.Violations[0].Value)
+
+	unavailable := fakeScanner{err: errors.New("failed to connect to clamd")}
+	assert.Equal(t, fiber.StatusServiceUnavailable, send(UploadConfig{TempDir: t.TempDir(), Scanner: unavailable}).StatusCode)
+	assert.Equal(t, 0, converted, "unscanned and infected uploads are not processed")
+
+	assert.Equal(t, fiber.StatusOK, send(UploadConfig{TempDir: t.TempDir(), Scanner: unavailable, ScanFailOpen: true}).StatusCode)
+	assert.Equal(t, fiber.StatusOK, send(UploadConfig{TempDir: t.TempDir(), Scanner: fakeScanner{result: &validation.ScanResult{}}}).StatusCode)
+	assert.Equal(t, 2, converted)
+}
+
+func TestConvertImageRejectsExcessiveFormFields(t *testing.T) {
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			t.Fatal("request with excessive fields should not reach the service")
+			return nil, nil
+		},
+	}
+	app := newStreamingTestApp(service, UploadConfig{TempDir: t.TempDir()})
+
+	for name, write := range map[string]func(*multipart.Writer){
+		"too many fields": func(writer *multipart.Writer) {
+			for i := 0; i <= maxFormFields; i++ {
+				writer.WriteField(fmt.Sprintf("field%d", i), "x")
+			}
+		},
+		"too many bytes": func(writer *multipart.Writer) {
+			value := strings.Repeat("x", maxFormValueSize)
+			for i := 0; i <= maxFormFieldsSize/maxFormValueSize; i++ {
+				writer.WriteField(fmt.Sprintf("field%d", i), value)
+			}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			writer := multipart.NewWriter(body)
+			write(writer)
+			part, err := writer.CreateFormFile("file", "input.png")
+			require.NoError(t, err)
+			part.Write([]byte("image"))
+			require.NoError(t, writer.Close())
+
+			req := httptest.NewRequest("POST", "/api/v1/process/image/convert", body)
+			req.Header.Set("Content-Type", writer.FormDataContentType())
+			resp, err := app.Test(req, -1)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+		})
+	}
+}
+
+func TestExtractTextPagesPackagesZip(t *testing.T) {
+	service := &fakeDocumentService{
+		extractTextPages: func(ctx context.Context, input io.Reader, mode domain.ExtractionMode) ([]domain.PageText, error) {
+			return []domain.PageText{{Page: 1, Text: "first"}, {Page: 2, Text: "second"}}, nil
+		},
+	}
+	app := newStreamingTestApp(service, UploadConfig{TempDir: t.TempDir()})
+
+	req := newUploadRequest(t, "/api/v1/process/text/pages", "input.pdf", "%PDF-1.4", map[string]string{"package": "zip"})
+
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/zip", resp.Header.Get("Content-Type"))
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	contents := make(map[string]string)
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[file.Name] = string(content)
+	}
+	assert.Equal(t, "first", contents["page_001.txt"])
+	assert.Equal(t, "second", contents["page_002.txt"])
+
+	var manifest packaging.Manifest
+	require.NoError(t, json.Unmarshal([]byte(contents[packaging.ManifestName]), &manifest))
+	assert.Equal(t, "text_pages", manifest.Operation)
+	require.Len(t, manifest.Files, 2)
+	assert.Equal(t, float64(2), manifest.Files[1].Metadata["page"])
+}
+
+func TestExtractTextPagesWithoutPackage(t *testing.T) {
+	service := &fakeDocumentService{
+		extractTextPages: func(ctx context.Context, input io.Reader, mode domain.ExtractionMode) ([]domain.PageText, error) {
+			return []domain.PageText{{Page: 1, Text: "only"}}, nil
+		},
+	}
+	app := newStreamingTestApp(service, UploadConfig{TempDir: t.TempDir()})
+
+	req := newUploadRequest(t, "/api/v1/process/text/pages", "input.pdf", "%PDF-1.4", nil)
+
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result struct {
+		Pages      []domain.PageText `json:"pages"`
+		TotalPages int               `json:"total_pages"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 1, result.TotalPages)
+	assert.Equal(t, "only", result.Pages[0].Text)
+
+	req = newUploadRequest(t, "/api/v1/process/text/pages", "input.pdf", "%PDF-1.4", map[string]string{"package": "tar"})
+	resp, err = app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestExtractTextPagesReportsScannedPages(t *testing.T) {
+	var modes []domain.ExtractionMode
+	service := &fakeDocumentService{
+		extractTextPages: func(ctx context.Context, input io.Reader, mode domain.ExtractionMode) ([]domain.PageText, error) {
+			modes = append(modes, mode)
+			return []domain.PageText{
+				{Page: 1, Text: "digital", Source: "extraction"},
+				{Page: 2, Source: "extraction", Scanned: true, RequiresOCR: true},
+			}, nil
+		},
+	}
+	app := newStreamingTestApp(service, UploadConfig{TempDir: t.TempDir()})
+
+	for _, query := range []string{"", "?mode=auto"} {
+		req := newUploadRequest(t, "/api/v1/process/text/pages"+query, "input.pdf", "%PDF-1.4", nil)
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result apiPages
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		resp.Body.Close()
+		assert.True(t, result.RequiresOCR)
+		assert.True(t, result.Pages[1].Scanned)
+	}
+	assert.Equal(t, []domain.ExtractionMode{domain.ExtractionModeText, domain.ExtractionModeAuto}, modes)
+
+	req := newUploadRequest(t, "/api/v1/process/text/pages?mode=ocr", "input.pdf", "%PDF-1.4", nil)
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestExtractPDFOutline(t *testing.T) {
+	outline := []domain.OutlineItem{}
+	service := &fakeDocumentService{
+		extractOutline: func(ctx context.Context, input io.Reader) ([]domain.OutlineItem, error) {
+			data, _ := io.ReadAll(input)
+			assert.Equal(t, "%PDF-1.4", string(data))
+			return outline, nil
+		},
+	}
+	app := newStreamingTestApp(service, UploadConfig{TempDir: t.TempDir()})
+
+	extract := func() apiOutline {
+		req := newUploadRequest(t, "/api/v1/process/pdf/outline", "input.pdf", "%PDF-1.4", nil)
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result apiOutline
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return result
+	}
+
+	// A PDF without bookmarks is not an error
+	result := extract()
+	assert.False(t, result.HasOutline)
+	assert.NotNil(t, result.Outline)
+	assert.Empty(t, result.Outline)
+
+	outline = []domain.OutlineItem{{
+		Title: "Chapter 1", Level: 1, Page: 2,
+		Children: []domain.OutlineItem{{Title: "Section 1.1", Level: 2, Page: 3}},
+	}}
+	result = extract()
+	assert.True(t, result.HasOutline)
+	assert.Equal(t, outline, result.Outline)
+}
+
+func TestGeneratePDFThumbnail(t *testing.T) {
+	var gotPage, gotSize int
+	service := &fakeDocumentService{
+		pdfThumbnail: func(ctx context.Context, input io.Reader, page, size int) (io.Reader, error) {
+			gotPage, gotSize = page, size
+			return strings.NewReader("png-bytes"), nil
+		},
+	}
+	app, _ := newTestApp(service)
+
+	for _, tc := range []struct {
+		fields     map[string]string
+		page, size int
+	}{
+		{nil, 1, 256},
+		{map[string]string{"page": "3", "size": "512"}, 3, 512},
+	} {
+		req := newUploadRequest(t, "/api/v1/process/pdf/thumbnail", "input.pdf", "%PDF-1.4", tc.fields)
+
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "png-bytes", string(data))
+		assert.Equal(t, tc.page, gotPage)
+		assert.Equal(t, tc.size, gotSize)
+	}
+}
+
+func TestGeneratePDFThumbnailValidatesOptions(t *testing.T) {
+	app, _ := newTestApp(&fakeDocumentService{})
+
+	req := newUploadRequest(t, "/api/v1/process/pdf/thumbnail", "input.pdf", "%PDF-1.4", map[string]string{"page": "0", "size": "huge"})
+
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	data, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(data), `"page"`)
+	assert.Contains(t, string(data), `"size"`)
+}
+
+func TestRequireAuthRejectsMissingAndInvalidTokens(t *testing.T) {
+	verifier := auth.NewVerifierWithKeys(&config.AuthConfig{Issuer: "https://idp.example.com/"}, nil)
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use("/api/v1/jobs", RequireAuth(verifier))
+	app.Get("/api/v1/jobs/:jobId", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	for _, header := range []string{"", "Basic dXNlcjpwYXNz", "Bearer not-a-token"} {
+		req := httptest.NewRequest("GET", "/api/v1/jobs/123", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode, header)
+		assert.NotEmpty(t, resp.Header.Get("WWW-Authenticate"))
+	}
+}
+
+func TestRequestLoggerSamplesSuccesses(t *testing.T) {
+	var out bytes.Buffer
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(RequestLogger(config.LoggingConfig{SuccessSampleRate: 10}, &out))
+	app.Get("/ok", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/fail", func(c *fiber.Ctx) error { return fiber.NewError(fiber.StatusBadGateway, "upstream down") })
+
+	for i := 0; i < 100; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/ok", nil), -1)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+	for i := 0; i < 5; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/fail", nil), -1)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadGateway, resp.StatusCode, "the error handler still sets the response")
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var successes, failures int
+	for _, line := range lines {
+		switch {
+		case strings.Contains(line, "GET /ok 200"):
+			successes++
+		case strings.Contains(line, "GET /fail 502"):
+			failures++
+		}
+	}
+	assert.Equal(t, 10, successes, "one in ten successes is logged")
+	assert.Equal(t, 5, failures, "every error is logged")
+}
+
+func TestRequestLoggerWithoutSamplingLogsEverything(t *testing.T) {
+	var out bytes.Buffer
+	app := fiber.New()
+	app.Use(RequestLogger(config.LoggingConfig{}, &out))
+	app.Get("/ok", func(c *fiber.Ctx) error { return c.SendString("ok") })
+
+	for i := 0; i < 3; i++ {
+		_, err := app.Test(httptest.NewRequest("GET", "/ok", nil), -1)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, strings.Count(out.String(), "GET /ok 200"))
+}
+
+func newCORSApp(t *testing.T, cfg config.SecurityConfig) *fiber.App {
+	t.Helper()
+
+	handler, err := CORS(cfg)
+	require.NoError(t, err)
+	app := fiber.New()
+	app.Use(handler)
+	app.Get("/resource", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	return app
+}
+
+func TestCORSAllowsListedAndMatchingOrigins(t *testing.T) {
+	app := newCORSApp(t, config.SecurityConfig{
+		AllowedOrigins:        []string{"https://app.example.com", "HTTPS://Admin.Example.com"},
+		AllowedOriginPatterns: []string{`https://[a-z0-9-]+\.preview\.example\.com`},
+		AllowCredentials:      true,
+	})
+
+	for _, tc := range []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://admin.example.com", true},
+		{"https://pr-42.preview.example.com", true},
+		{"https://evil.com", false},
+		{"http://app.example.com", false},
+		{"https://pr-42.preview.example.com.evil.com", false},
+	} {
+		req := httptest.NewRequest("GET", "/resource", nil)
+		req.Header.Set("Origin", tc.origin)
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		if tc.allowed {
+			assert.Equal(t, tc.origin, resp.Header.Get("Access-Control-Allow-Origin"), tc.origin)
+			assert.Equal(t, "true", resp.Header.Get("Access-Control-Allow-Credentials"), tc.origin)
+		} else {
+			assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"), tc.origin)
+			assert.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"), tc.origin)
+		}
+		assert.Contains(t, resp.Header.Get("Vary"), "Origin", tc.origin)
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	app := newCORSApp(t, config.SecurityConfig{
+		AllowedOrigins:  []string{"https://app.example.com"},
+		AllowedMethods:  []string{"GET", "POST"},
+		AllowedHeaders:  []string{"Content-Type", "Authorization"},
+		ExposedHeaders:  []string{"Content-Disposition"},
+		PreflightMaxAge: 10 * time.Minute,
+	})
+
+	preflight := func(origin string) *http.Response {
+		req := httptest.NewRequest("OPTIONS", "/resource", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "Authorization")
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := preflight("https://app.example.com")
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET,POST", resp.Header.Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type,Authorization", resp.Header.Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "Content-Disposition", resp.Header.Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "600", resp.Header.Get("Access-Control-Max-Age"))
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Credentials"))
+
+	resp = preflight("https://evil.com")
+	assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSDefaultsToAnyOrigin(t *testing.T) {
+	app := newCORSApp(t, config.SecurityConfig{})
+
+	req := httptest.NewRequest("GET", "/resource", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	assert.Equal(t, "*", resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSRejectsInvalidConfiguration(t *testing.T) {
+	for name, cfg := range map[string]config.SecurityConfig{
+		"credentials with wildcard": {AllowedOrigins: []string{"*"}, AllowCredentials: true},
+		"credentials by default":    {AllowCredentials: true},
+		"origin without scheme":     {AllowedOrigins: []string{"app.example.com"}},
+		"origin with path":          {AllowedOrigins: []string{"https://app.example.com/login"}},
+		"invalid pattern":           {AllowedOriginPatterns: []string{"https://(unclosed"}},
+	} {
+		_, err := CORS(cfg)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRecordFailuresKeepsFailedUploads(t *testing.T) {
+	rec, err := recorder.New(recorder.Config{Directory: t.TempDir(), MaxInputSize: 1024})
+	require.NoError(t, err)
+
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			data, _ := io.ReadAll(input)
+			if string(data) == "broken" {
+				return nil, errors.New("vips: corrupt header")
+			}
+			return strings.NewReader("converted"), nil
+		},
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use("/api/v1/process", RecordFailures(rec))
+	uploadDir := t.TempDir()
+	handler := NewDocumentHandler(service, nil, nil, UploadConfig{TempDir: uploadDir})
+	handler.SetupRoutes(app)
+	NewRecordingHandler(rec).SetupRoutes(app)
+
+	for _, content := range []string{"fine", "broken"} {
+		req := newUploadRequest(t, "/api/v1/process/image/convert?trace=1", "input.png", content, map[string]string{"output_format": "webp"})
+		req.Header.Set("Authorization", "Bearer secret-token")
+		_, err := app.Test(req, -1)
+		require.NoError(t, err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/recordings", nil), -1)
+	require.NoError(t, err)
+	var listed struct {
+		Recordings []*recorder.Recording `json:"recordings"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	require.Len(t, listed.Recordings, 1, "only the failed request is recorded")
+	leftovers, _ := os.ReadDir(uploadDir)
+	assert.Empty(t, leftovers, "kept uploads are removed once recorded")
+
+	recording := listed.Recordings[0]
+	assert.Equal(t, "/api/v1/process/image/convert", recording.Path)
+	assert.Equal(t, "trace=1", recording.Query)
+	assert.Equal(t, fiber.StatusInternalServerError, recording.Status)
+	assert.Equal(t, recorder.Redacted, recording.Headers["Authorization"])
+	assert.Equal(t, "webp", recording.Fields["output_format"])
+	assert.Contains(t, recording.Response, "corrupt header")
+	require.NotNil(t, recording.Input)
+	assert.Equal(t, "file", recording.Input.Field)
+	assert.Equal(t, "input.png", recording.Input.Filename)
+	assert.True(t, recording.Input.Stored)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/recordings/"+recording.ID, nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	contents := make(map[string]string)
+	for _, file := range archive.File {
+		r, err := file.Open()
+		require.NoError(t, err)
+		content, _ := io.ReadAll(r)
+		r.Close()
+		contents[file.Name] = string(content)
+	}
+	assert.Equal(t, "broken", contents["input"])
+	assert.Contains(t, contents["request.json"], recording.ID)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/recordings/..", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+func TestOpenAPIDescribesEveryRoute(t *testing.T) {
+	rec, err := recorder.New(recorder.Config{Directory: t.TempDir()})
+	require.NoError(t, err)
+	app, _ := newTestApp(&fakeDocumentService{})
+	NewRecordingHandler(rec).SetupRoutes(app)
+	NewQuotaHandler(quota.NewLimiter(quota.NewMemoryStore(), quota.Usage{}), "tenant").SetupRoutes(app)
+
+	spec := OpenAPISpec("test")
+	paths := spec["paths"].(map[string]interface{})
+
+	routes := make(map[string]bool)
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead {
+			continue
+		}
+		path := strings.TrimSuffix(route.Path, "/")
+		routes[route.Method+" "+path] = true
+
+		item, ok := paths[fiberParam.ReplaceAllString(path, "{$1}")].(map[string]interface{})
+		require.True(t, ok, "route %s %s is missing from the OpenAPI document", route.Method, path)
+		assert.Contains(t, item, strings.ToLower(route.Method), "route %s %s is missing from the OpenAPI document", route.Method, path)
+	}
+
+	for _, op := range apiOperations {
+		if strings.HasPrefix(op.path, "/api/") {
+			assert.True(t, routes[op.method+" "+op.path], "documented operation %s %s has no route", op.method, op.path)
+		}
+	}
+
+	_, err = json.Marshal(spec)
+	require.NoError(t, err)
+}
+
+func TestOpenAPIDescribesErrorsAndProcessingOptions(t *testing.T) {
+	app, _ := newTestApp(&fakeDocumentService{})
+	app.Get("/openapi.json", OpenAPIHandler("1.2.3"))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/openapi.json", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	assert.Equal(t, "1.2.3", spec.Info.Version)
+
+	// The Error schema matches what ErrorHandler writes for validation errors
+	body := bytes.NewBufferString(`{"document_id":"","processing_type":"nope"}`)
+	req := httptest.NewRequest("POST", "/api/v1/documents/process", body)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	var written map[string]json.RawMessage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&written))
+	errorSchema := spec.Components.Schemas["Error"]
+	for field := range written {
+		assert.Contains(t, errorSchema.Properties, field)
+	}
+	assert.ElementsMatch(t, []string{"code", "error", "success"}, errorSchema.Required)
+	assert.Contains(t, spec.Components.Schemas, "Violation")
+
+	request := spec.Components.Schemas["ProcessDocumentRequest"]
+	assert.JSONEq(t, `{"$ref":"#/components/schemas/ProcessingOptions"}`, string(request.Properties["parameters"]))
+	options := spec.Components.Schemas["ProcessingOptions"].Properties
+	for _, name := range []string{"quality", "width", "height", "target_size", "metadata_policy", "background_color", "page_size", "size"} {
+		assert.Contains(t, options, name)
+	}
+}
+
+// withPrincipal stands in for RequireAuth with a fixed caller
+func withPrincipal(principal *auth.Principal) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+func TestGetJobResult(t *testing.T) {
+	service := &fakeDocumentService{
+		getJobResult: func(ctx context.Context, jobID string) (*domain.JobOutput, error) {
+			switch jobID {
+			case "streamed":
+				return &domain.JobOutput{Key: "results/streamed.webp", Content: strings.NewReader("webp output")}, nil
+			case "presigned":
+				return &domain.JobOutput{Key: "results/presigned.webp", URL: "https://bucket.example.com/results/presigned.webp?X-Amz-Signature=abc"}, nil
+			case "running":
+				return nil, domain.ErrJobNotCompleted
+			case "unstored":
+				return nil, domain.ErrResultNotFound
+			}
+			return nil, fmt.Errorf("job %s: %w", jobID, domain.ErrJobNotFound)
+		},
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	NewDocumentHandler(service, nil, nil, UploadConfig{}).SetupRoutes(app)
+
+	get := func(jobID string) *http.Response {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/jobs/"+jobID+"/result", nil), -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := get("streamed")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/webp", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="streamed.webp"`, resp.Header.Get("Content-Disposition"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "webp output", string(body))
+
+	resp = get("presigned")
+	require.Equal(t, fiber.StatusFound, resp.StatusCode)
+	assert.Equal(t, "https://bucket.example.com/results/presigned.webp?X-Amz-Signature=abc", resp.Header.Get("Location"))
+
+	assert.Equal(t, fiber.StatusConflict, get("running").StatusCode)
+	assert.Equal(t, fiber.StatusNotFound, get("unstored").StatusCode)
+	assert.Equal(t, fiber.StatusNotFound, get("missing").StatusCode)
+}
+
+func TestRequirePermission(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		principal *auth.Principal
+		status    int
+	}{
+		{"granted", &auth.Principal{Subject: "user-1", Permissions: []string{"download", "convert"}}, fiber.StatusOK},
+		{"missing permission", &auth.Principal{Subject: "user-1", Roles: []string{"convert"}}, fiber.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+			app.Use(withPrincipal(tc.principal))
+			app.Get("/convert", RequirePermission("convert"), func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/convert", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tc.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestEnforceQuotaRejectsExhaustedTenants(t *testing.T) {
+	limiter := quota.NewLimiter(quota.NewMemoryStore(), quota.Usage{Requests: 2})
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			return strings.NewReader("converted"), nil
+		},
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(withPrincipal(&auth.Principal{Subject: "user-1", Claims: map[string]interface{}{"tenant": "acme"}}))
+	app.Use("/api/v1/process", EnforceQuota(limiter, "tenant"))
+	NewDocumentHandler(service, nil, nil, UploadConfig{}).SetupRoutes(app)
+	NewQuotaHandler(limiter, "tenant").SetupRoutes(app)
+
+	convert := func(format string) *http.Response {
+		req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", "image", map[string]string{"output_format": format})
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Rejected requests are not charged
+	assert.Equal(t, fiber.StatusBadRequest, convert("bmp").StatusCode)
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, fiber.StatusOK, convert("webp").StatusCode)
+	}
+
+	resp := convert("webp")
+	require.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+	var exceeded struct {
+		Limit string       `json:"limit"`
+		Quota quota.Status `json:"quota"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&exceeded))
+	assert.Equal(t, "requests", exceeded.Limit)
+	assert.Equal(t, "acme", exceeded.Quota.Tenant)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/quota", nil), -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var status quota.Status
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	assert.Equal(t, "acme", status.Tenant)
+	assert.Equal(t, int64(2), status.Usage.Requests)
+	assert.Positive(t, status.Usage.Bytes)
+
+	// Other tenants' usage is for administrators only
+	resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/quota/globex", nil), -1)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+}
+
+func TestEnforceQuotaChargesMeteredUsage(t *testing.T) {
+	limiter := quota.NewLimiter(quota.NewMemoryStore(), quota.Usage{VideoSeconds: 120})
+	service := &fakeDocumentService{
+		convertVideo: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			// The processor charges the length of the input it converted
+			quota.MeterFrom(ctx).AddVideo(90 * time.Second)
+			return strings.NewReader("converted"), nil
+		},
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(withPrincipal(&auth.Principal{Subject: "user-1", Claims: map[string]interface{}{"tenant": "acme"}}))
+	app.Use("/api/v1/process", EnforceQuota(limiter, "tenant"))
+	NewDocumentHandler(service, nil, nil, UploadConfig{}).SetupRoutes(app)
+
+	convert := func(content string) *http.Response {
+		req := newUploadRequest(t, "/api/v1/process/video/convert", "input.png", content, map[string]string{"output_format": "webm"})
+		// A chunked upload carries no Content-Length
+		req.ContentLength = -1
+		req.TransferEncoding = []string{"chunked"}
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := convert("first video")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "video/webm", resp.Header.Get("Content-Type"))
+
+	status, err := limiter.Status(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, quota.Usage{Requests: 1, Bytes: int64(len("first video") + len("webm")), VideoSeconds: 90}, status.Usage)
+
+	require.Equal(t, fiber.StatusOK, convert("second video").StatusCode)
+	resp = convert("third video")
+	require.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	var exceeded struct {
+		Limit string `json:"limit"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&exceeded))
+	assert.Equal(t, "video_seconds", exceeded.Limit)
+}
+
+func TestEnforceQuotaChargesAsyncVideoJobs(t *testing.T) {
+	// A fake ffprobe reports every video as 50.2 seconds long
+	bin := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "ffprobe"), []byte("#!/bin/sh\necho 50.2\n"), 0o755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	limiter := quota.NewLimiter(quota.NewMemoryStore(), quota.Usage{VideoSeconds: 120})
+	service := &fakeDocumentService{
+		processDocument: func(ctx context.Context, req *domain.ProcessingRequest) (*domain.ProcessingResult, error) {
+			return &domain.ProcessingResult{JobID: "job-" + req.DocumentID, Status: domain.JobStatusPending}, nil
+		},
+		getDocument: func(ctx context.Context, id string) (*domain.Document, error) {
+			if id != "doc-1" {
+				return nil, domain.ErrDocumentNotFound
+			}
+			return &domain.Document{ID: id, Path: "/data/doc-1.mp4"}, nil
+		},
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(withPrincipal(&auth.Principal{Subject: "user-1", Claims: map[string]interface{}{"tenant": "acme"}}))
+	app.Use("/api/v1/documents/process", EnforceQuota(limiter, "tenant"))
+	NewDocumentHandler(service, nil, nil, UploadConfig{}).SetupRoutes(app)
+
+	submit := func(body string) *http.Response {
+		req := httptest.NewRequest("POST", "/api/v1/documents/process", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// The stored document is probed
+	require.Equal(t, fiber.StatusAccepted, submit(`{"document_id":"doc-1","type":"video_convert"}`).StatusCode)
+	status, err := limiter.Status(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, int64(51), status.Usage.VideoSeconds)
+
+	// Other jobs are charged their body only
+	require.Equal(t, fiber.StatusAccepted, submit(`{"document_id":"doc-1","type":"image_convert"}`).StatusCode)
+	status, err = limiter.Status(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, int64(51), status.Usage.VideoSeconds)
+
+	// Without a stored document the worker's input path is probed
+	require.Equal(t, fiber.StatusAccepted, submit(`{"document_id":"doc-2","type":"video_convert","parameters":{"input_path":"/data/doc-2.mp4"}}`).StatusCode)
+	status, err = limiter.Status(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.Equal(t, int64(102), status.Usage.VideoSeconds)
+
+	require.Equal(t, fiber.StatusAccepted, submit(`{"document_id":"doc-1","type":"video_convert"}`).StatusCode)
+	resp := submit(`{"document_id":"doc-1","type":"video_convert"}`)
+	require.Equal(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	var exceeded struct {
+		Limit string `json:"limit"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&exceeded))
+	assert.Equal(t, "video_seconds", exceeded.Limit)
+}
+
+func TestMemoryShedderHoldsThenShedsUnderPressure(t *testing.T) {
+	previous := memoryPollInterval
+	memoryPollInterval = time.Millisecond
+	defer func() { memoryPollInterval = previous }()
+
+	var used atomic.Uint64
+	gauge := memory.NewGauge(func() (memory.Sample, error) {
+		return memory.Sample{Used: used.Load(), Limit: 100}, nil
+	}, 0.9)
+	shedder := NewMemoryShedder(gauge, 50*time.Millisecond)
+
+	var processed atomic.Int64
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use("/api/v1/process", shedder.Handler())
+	app.Post("/api/v1/process/image/convert", func(c *fiber.Ctx) error {
+		processed.Add(1)
+		return c.SendString("ok")
+	})
+	post := func() *http.Response {
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/process/image/convert", nil), -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	used.Store(50)
+	gauge.Refresh()
+	assert.Equal(t, fiber.StatusOK, post().StatusCode)
+
+	// Sustained pressure sheds the request
+	used.Store(95)
+	gauge.Refresh()
+	resp := post()
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "1", resp.Header.Get("Retry-After"))
+	assert.Equal(t, int64(1), processed.Load())
+
+	// Pressure easing while the request is held lets it through
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		used.Store(40)
+		gauge.Refresh()
+	}()
+	assert.Equal(t, fiber.StatusOK, post().StatusCode)
+	assert.Equal(t, int64(2), processed.Load())
+	assert.Equal(t, int64(1), shedder.Shed())
+	assert.Equal(t, int64(2), shedder.Delayed())
+
+	var metrics strings.Builder
+	require.NoError(t, shedder.WritePrometheus(&metrics))
+	assert.Contains(t, metrics.String(), `documents_worker_requests_shed_total{reason="memory_pressure"} 1`)
+	assert.Contains(t, metrics.String(), "documents_worker_memory_limit_bytes 100")
+}
+
+func TestProcessDocumentPassesMetadata(t *testing.T) {
+	var submitted *domain.ProcessingRequest
+	service := &fakeDocumentService{
+		processDocument: func(ctx context.Context, req *domain.ProcessingRequest) (*domain.ProcessingResult, error) {
+			submitted = req
+			return &domain.ProcessingResult{JobID: "job-1", Status: domain.JobStatusPending}, nil
+		},
+	}
+	app, _ := newTestApp(service)
+
+	submit := func(metadata string) *http.Response {
+		body := strings.NewReader(`{"document_id":"doc-1","type":"ocr","metadata":` + metadata + `}`)
+		req := httptest.NewRequest("POST", "/api/v1/documents/process", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := submit(`{" cms_asset_id ":"asset-42\n","source":"cms"}`)
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	require.NotNil(t, submitted)
+	assert.Equal(t, map[string]string{"cms_asset_id": "asset-42", "source": "cms"}, submitted.Metadata)
+
+	submitted = nil
+	resp = submit(`{"bad key":"x","long":"` + strings.Repeat("v", domain.MaxMetadataValueLen+1) + `"}`)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	assert.Nil(t, submitted)
+
+	var payload struct {
+		Violations []struct {
+			Field string `json:"field"`
+			Rule  string `json:"rule"`
+		} `json:"violations"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&payload))
+	rules := make([]string, len(payload.Violations))
+	for i, violation := range payload.Violations {
+		rules[i] = violation.Field + ":" + violation.Rule
+	}
+	assert.ElementsMatch(t, []string{"metadata:key", "metadata.long:max_length"}, rules)
+}
+
+func TestProcessDocumentWaitsForTheResult(t *testing.T) {
+	var waits []time.Duration
+	service := &fakeDocumentService{
+		processDocument: func(ctx context.Context, req *domain.ProcessingRequest) (*domain.ProcessingResult, error) {
+			return &domain.ProcessingResult{JobID: "job-" + req.DocumentID, DocumentID: req.DocumentID, Type: req.Type, Status: domain.JobStatusPending}, nil
+		},
+		waitForJob: func(ctx context.Context, jobID string, maxWait time.Duration) (*domain.ProcessingJob, error) {
+			waits = append(waits, maxWait)
+			if jobID == "job-fast" {
+				return &domain.ProcessingJob{ID: jobID, Status: domain.JobStatusCompleted, Result: map[string]interface{}{"pages": float64(3)}}, nil
+			}
+			return &domain.ProcessingJob{ID: jobID, Status: domain.JobStatusProcessing}, nil
+		},
+	}
+	app, handler := newTestApp(service)
+	handler.SetMaxJobWait(10 * time.Second)
+
+	submit := func(documentID, query string) *http.Response {
+		body := strings.NewReader(`{"document_id":"` + documentID + `","type":"ocr"}`)
+		req := httptest.NewRequest("POST", "/api/v1/documents/process"+query, body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Finished within the window: the job and its result come back directly
+	resp := submit("fast", "?wait=5s")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var job domain.ProcessingJob
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&job))
+	assert.Equal(t, domain.JobStatusCompleted, job.Status)
+	assert.Equal(t, float64(3), job.Result["pages"])
+
+	// Still running when the window closes: poll as usual
+	resp = submit("slow", "?wait=60")
+	require.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	var result domain.ProcessingResult
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, "job-slow", result.JobID)
+
+	// Without wait the job is not waited for
+	resp = submit("fast", "")
+	assert.Equal(t, fiber.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, []time.Duration{5 * time.Second, 10 * time.Second}, waits)
+
+	resp = submit("fast", "?wait=soon")
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+// newDebugCaptureApp serves image conversion with debug capture for a
+// caller holding the given roles
+func newDebugCaptureApp(cfg config.DebugCaptureConfig, out io.Writer, roles ...string) *fiber.App {
+	service := &fakeDocumentService{
+		convertImage: func(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+			return strings.NewReader("converted"), nil
+		},
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(withPrincipal(&auth.Principal{Subject: "user-1", Roles: roles}))
+	app.Use("/api/v1/process/image", NewDebugCapture(cfg, out).Handler())
+	NewDocumentHandler(service, nil, nil, UploadConfig{}).SetupRoutes(app)
+	return app
+}
+
+func debugEntries(t *testing.T, out *bytes.Buffer) []DebugEntry {
+	t.Helper()
+	var entries []DebugEntry
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry DebugEntry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestDebugCaptureRedactsRequests(t *testing.T) {
+	out := &bytes.Buffer{}
+	app := newDebugCaptureApp(config.DebugCaptureConfig{Role: "debug", RedactFields: []string{"customer"}}, out, "debug")
+
+	req := newUploadRequest(t, "/api/v1/process/image/convert?token=t-1&page=2", "scan.png", "secret file content", map[string]string{
+		"output_format": "webp",
+		"api_key":       "k-123",
+		"customer_id":   "c-42",
+		"note":          "ask jane@example.com",
+	})
+	req.Header.Set("Authorization", "Bearer abc")
+	resp, err := app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	assert.NotContains(t, out.String(), "secret file content")
+	entries := debugEntries(t, out)
+	require.Len(t, entries, 1)
+	entry := entries[0]
+	assert.Equal(t, "user-1", entry.Subject)
+	assert.Equal(t, fiber.StatusOK, entry.Status)
+	assert.Equal(t, "webp", entry.Fields["output_format"])
+	assert.Equal(t, recorder.Redacted, entry.Fields["api_key"])
+	assert.Equal(t, recorder.Redacted, entry.Fields["customer_id"])
+	assert.Equal(t, "ask "+recorder.Redacted, entry.Fields["note"])
+	assert.Equal(t, recorder.Redacted, entry.Headers["Authorization"])
+	assert.Contains(t, entry.Query, "page=2")
+	assert.NotContains(t, entry.Query, "t-1")
+	require.NotNil(t, entry.Upload)
+	assert.Equal(t, "scan.png", entry.Upload.Filename)
+	assert.Equal(t, int64(len("secret file content")), entry.Upload.Size)
+	assert.False(t, entry.Upload.Stored)
+	assert.Equal(t, len("converted"), entry.Response.Size)
+
+	// Failures keep the start of the error response
+	out.Reset()
+	req = newUploadRequest(t, "/api/v1/process/image/convert", "input.png", "image", map[string]string{"output_format": "bmp"})
+	resp, err = app.Test(req, -1)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	entries = debugEntries(t, out)
+	require.Len(t, entries, 1)
+	assert.Contains(t, entries[0].Response.Error, "output_format")
+}
+
+func TestDebugCaptureSamplesAndCapsEntries(t *testing.T) {
+	convert := func(app *fiber.App) {
+		req := newUploadRequest(t, "/api/v1/process/image/convert", "input.png", "image", map[string]string{"output_format": "webp"})
+		resp, err := app.Test(req, -1)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+
+	t.Run("role required", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		app := newDebugCaptureApp(config.DebugCaptureConfig{Role: "debug"}, out, "reader")
+		convert(app)
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("sampling", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		app := newDebugCaptureApp(config.DebugCaptureConfig{Role: "debug", SampleRate: 3}, out, "debug")
+		for i := 0; i < 9; i++ {
+			convert(app)
+		}
+		assert.Len(t, debugEntries(t, out), 3)
+	})
+
+	t.Run("per minute cap", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		app := newDebugCaptureApp(config.DebugCaptureConfig{Role: "debug", MaxPerMinute: 2}, out, "debug")
+		for i := 0; i < 5; i++ {
+			convert(app)
+		}
+		assert.Len(t, debugEntries(t, out), 2)
+	})
+
+	t.Run("size cap", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		app := newDebugCaptureApp(config.DebugCaptureConfig{Role: "debug", MaxEntrySize: 300}, out, "debug")
+		convert(app)
+		entries := debugEntries(t, out)
+		require.Len(t, entries, 1)
+		assert.True(t, entries[0].Truncated)
+		assert.Less(t, len(out.String()), 300)
+		assert.Equal(t, fiber.StatusOK, entries[0].Status)
+	})
+}
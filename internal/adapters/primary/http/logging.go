@@ -0,0 +1,44 @@
+package http
+
+import (
+	"documents-worker/config"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestLogger logs one line per request. Requests ending in a 4xx or 5xx
+// status are always logged; successful ones are sampled one in
+// SuccessSampleRate, which keeps log volume bounded at high request rates.
+func RequestLogger(cfg config.LoggingConfig, out io.Writer) fiber.Handler {
+	var successes uint64
+	var mu sync.Mutex
+
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		// Resolve handler errors here so the logged status is the one sent
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				c.Status(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		if status < fiber.StatusBadRequest && cfg.SuccessSampleRate > 1 {
+			if atomic.AddUint64(&successes, 1)%uint64(cfg.SuccessSampleRate) != 1 {
+				return nil
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(out, "%s %s %s %d %s\n",
+			start.Format("15:04:05"), c.Method(), c.Path(), status, time.Since(start).Round(time.Microsecond))
+		return nil
+	}
+}
@@ -0,0 +1,697 @@
+package http
+
+import (
+	"documents-worker/health"
+	"documents-worker/internal/core/domain"
+	"documents-worker/media"
+	"documents-worker/quota"
+	"documents-worker/recorder"
+	"documents-worker/types"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// jsonSchema is an OpenAPI schema object
+type jsonSchema = map[string]interface{}
+
+// apiFailure is the body handlers write when processing fails
+type apiFailure struct {
+	Error   string `json:"error"`
+	Details string `json:"details,omitempty"`
+}
+
+// apiOutputTooLarge is the body written when an output exceeds its limit
+type apiOutputTooLarge struct {
+	Error   string `json:"error"`
+	Details string `json:"details"`
+	Size    int64  `json:"size"`
+	Limit   int64  `json:"limit"`
+}
+
+// apiPages is the body of a text page extraction without packaging
+type apiPages struct {
+	Pages       []domain.PageText `json:"pages"`
+	TotalPages  int               `json:"total_pages"`
+	RequiresOCR bool              `json:"requires_ocr"`
+}
+
+// apiOutline is the body of a PDF outline extraction
+type apiOutline struct {
+	Outline    []domain.OutlineItem `json:"outline"`
+	HasOutline bool                 `json:"has_outline"`
+}
+
+// apiRecordings is the body of the recording list
+type apiRecordings struct {
+	Recordings []recorder.Recording `json:"recordings"`
+	Count      int                  `json:"count"`
+}
+
+// apiContent is a request or response body. Exactly one of value, a Go
+// value whose type is reflected into a schema, or schema is set.
+type apiContent struct {
+	contentType string
+	value       interface{}
+	schema      jsonSchema
+}
+
+type apiParam struct {
+	name        string
+	in          string
+	description string
+	schema      jsonSchema
+	required    bool
+}
+
+type apiResponse struct {
+	description string
+	content     []apiContent
+}
+
+// apiOperation describes one route. The tests check that every route the
+// handlers register is described here, so the document cannot drift.
+type apiOperation struct {
+	method      string
+	path        string // Fiber syntax, e.g. /api/v1/jobs/:jobId
+	tag         string
+	summary     string
+	description string
+	secured     bool
+	params      []apiParam
+	body        *apiContent
+	responses   map[int]apiResponse
+}
+
+// processingOptionsSchema describes the parameters object of processing
+// requests; which options apply depends on the processing type
+var processingOptionsSchema = jsonSchema{
+	"type":        "object",
+	"description": "Processing options. Options not used by the processing type are ignored.",
+	"properties": jsonSchema{
+		"quality":          jsonSchema{"type": "integer", "minimum": 1, "maximum": 100, "description": "Image encoding quality"},
+		"width":            jsonSchema{"type": "integer", "minimum": 1, "description": "Output width in pixels"},
+		"height":           jsonSchema{"type": "integer", "minimum": 1, "description": "Output height in pixels"},
+		"target_size":      jsonSchema{"type": "integer", "minimum": 1, "description": "Largest output size in bytes; the highest quality that fits is chosen"},
+		"progressive":      jsonSchema{"type": "boolean", "description": "Progressive JPEG or interlaced PNG output"},
+		"background_color": jsonSchema{"type": "string", "example": media.DefaultBackgroundColor, "description": "Opaque color transparent areas are flattened onto for formats without alpha"},
+		"metadata_policy": jsonSchema{"type": "string", "description": "What happens to input metadata",
+			"enum": []string{string(types.MetadataKeepAll), string(types.MetadataStripAll), string(types.MetadataAllowlist), string(types.MetadataDenylist)}},
+		"metadata_tags": jsonSchema{"description": "Tags for the allowlist and denylist policies",
+			"oneOf": []jsonSchema{{"type": "array", "items": jsonSchema{"type": "string"}}, {"type": "string", "description": "Comma separated"}}},
+		"color_primaries": jsonSchema{"type": "string", "example": "bt709", "description": "Video color primaries (FFmpeg names)"},
+		"color_trc":       jsonSchema{"type": "string", "example": "bt709", "description": "Video transfer characteristics (FFmpeg names)"},
+		"colorspace":      jsonSchema{"type": "string", "example": "bt709", "description": "Video matrix coefficients (FFmpeg names)"},
+		"gamma":           jsonSchema{"type": "number", "exclusiveMinimum": 0, "description": "Video gamma correction"},
+		"page_size":       jsonSchema{"type": "string", "example": "A4", "description": "PDF page size"},
+		"orientation":     jsonSchema{"type": "string", "enum": []string{"portrait", "landscape"}, "description": "PDF page orientation"},
+		"size":            jsonSchema{"type": "integer", "minimum": 1, "description": "Thumbnail size in pixels"},
+	},
+	"additionalProperties": true,
+}
+
+// apiEnums lists the values of string types used in the schemas
+var apiEnums = map[reflect.Type][]string{
+	reflect.TypeOf(domain.DocumentType("")): {
+		string(domain.DocumentTypePDF), string(domain.DocumentTypeImage), string(domain.DocumentTypeVideo),
+		string(domain.DocumentTypeOffice), string(domain.DocumentTypeText), string(domain.DocumentTypeArchive),
+	},
+	reflect.TypeOf(domain.DocumentStatus("")): {
+		string(domain.DocumentStatusPending), string(domain.DocumentStatusProcessing),
+		string(domain.DocumentStatusCompleted), string(domain.DocumentStatusFailed),
+	},
+	reflect.TypeOf(domain.ProcessingType("")): {
+		string(domain.ProcessingTypeOCR), string(domain.ProcessingTypeImageConvert), string(domain.ProcessingTypeVideoConvert),
+		string(domain.ProcessingTypePDFGenerate), string(domain.ProcessingTypeTextExtract), string(domain.ProcessingTypeThumbnail),
+	},
+	reflect.TypeOf(domain.JobStatus("")): {
+		string(domain.JobStatusPending), string(domain.JobStatusProcessing), string(domain.JobStatusCompleted),
+		string(domain.JobStatusFailed), string(domain.JobStatusRetrying), string(domain.JobStatusCanceled),
+	},
+}
+
+// extractionModeSchema describes the text page extraction mode
+var extractionModeSchema = jsonSchema{
+	"type":    "string",
+	"enum":    []string{string(domain.ExtractionModeText), string(domain.ExtractionModeAuto)},
+	"default": string(domain.ExtractionModeText),
+}
+
+// apiFieldSchemas replace the reflected schema of specific fields, keyed by
+// type name and JSON field name
+var apiFieldSchemas = map[string]jsonSchema{
+	"ProcessDocumentRequest.parameters": {"$ref": "#/components/schemas/ProcessingOptions"},
+	"ProcessingJob.parameters":          {"$ref": "#/components/schemas/ProcessingOptions"},
+}
+
+func jsonBody(value interface{}) []apiContent {
+	return []apiContent{{contentType: fiber.MIMEApplicationJSON, value: value}}
+}
+
+func binaryBody(contentTypes ...string) []apiContent {
+	content := make([]apiContent, len(contentTypes))
+	for i, contentType := range contentTypes {
+		content[i] = apiContent{contentType: contentType, schema: jsonSchema{"type": "string", "format": "binary"}}
+	}
+	return content
+}
+
+func textBody() []apiContent {
+	return []apiContent{{contentType: "text/plain", schema: jsonSchema{"type": "string"}}}
+}
+
+// multipartBody describes an upload in the file field with extra fields
+func multipartBody(fields jsonSchema, required ...string) *apiContent {
+	properties := jsonSchema{"file": jsonSchema{"type": "string", "format": "binary"}}
+	for name, schema := range fields {
+		properties[name] = schema
+	}
+	return &apiContent{
+		contentType: fiber.MIMEMultipartForm,
+		schema: jsonSchema{
+			"type":       "object",
+			"properties": properties,
+			"required":   append([]string{"file"}, required...),
+		},
+	}
+}
+
+var (
+	errorResponse      = func(description string) apiResponse { return apiResponse{description, jsonBody(apiError{})} }
+	failureResponse    = func(description string) apiResponse { return apiResponse{description, jsonBody(apiFailure{})} }
+	validationResponse = apiResponse{"Invalid request; every violated rule is listed", jsonBody(apiError{})}
+	pressureResponse   = apiResponse{"Server low on memory, with Retry-After set", jsonBody(apiError{})}
+	quotaResponse      = apiResponse{"Monthly quota used up, with Retry-After set to its reset (when quotas are enabled)", jsonBody(apiQuotaExceeded{})}
+)
+
+// apiOperations describes every HTTP endpoint of the server
+var apiOperations = []apiOperation{
+	{
+		method: fiber.MethodGet, path: "/api/v1/health", tag: "Health",
+		summary: "Service health with processor availability",
+		responses: map[int]apiResponse{
+			200: {"Healthy", jsonBody(domain.HealthStatus{})},
+			503: {"Degraded or unhealthy", jsonBody(domain.HealthStatus{})},
+			500: failureResponse("Health could not be determined"),
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/api/v1/stats/queue", tag: "Queue", secured: true,
+		summary: "Queue statistics",
+		responses: map[int]apiResponse{
+			200: {"Queue statistics", jsonBody(domain.QueueStats{})},
+			500: failureResponse("Statistics unavailable"),
+		},
+	},
+	{
+		method: fiber.MethodPost, path: "/api/v1/documents/process", tag: "Documents", secured: true,
+		summary:     "Queue a document for processing",
+		description: "The job runs asynchronously; poll the job endpoint for its result, or pass wait to receive the finished job directly when it completes in time.",
+		params:      []apiParam{{name: "wait", in: "query", description: "How long to wait for the job to finish, e.g. 5s or 5; capped by the server", schema: jsonSchema{"type": "string"}}},
+		body:        &apiContent{contentType: fiber.MIMEApplicationJSON, value: ProcessDocumentRequest{}},
+		responses: map[int]apiResponse{
+			200: {"The job finished within wait", jsonBody(domain.ProcessingJob{})},
+			202: {"Job queued", jsonBody(domain.ProcessingResult{})},
+			400: validationResponse,
+			429: quotaResponse,
+			503: pressureResponse,
+			500: failureResponse("The job could not be queued"),
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/api/v1/documents/:id", tag: "Documents", secured: true,
+		summary: "Get a document",
+		responses: map[int]apiResponse{
+			200: {"The document", jsonBody(domain.Document{})},
+			400: failureResponse("Missing document ID"),
+			404: failureResponse("Document not found"),
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/api/v1/documents/:id/jobs", tag: "Documents", secured: true,
+		summary: "List the processing jobs of a document",
+		responses: map[int]apiResponse{
+			200: {"The document's jobs", jsonBody([]domain.ProcessingJob{})},
+			400: failureResponse("Missing document ID"),
+			500: failureResponse("Jobs could not be listed"),
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/api/v1/jobs/:jobId", tag: "Jobs", secured: true,
+		summary: "Get a processing job with its status and result",
+		responses: map[int]apiResponse{
+			200: {"The job", jsonBody(domain.ProcessingJob{})},
+			400: failureResponse("Missing job ID"),
+			404: failureResponse("Job not found"),
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/api/v1/jobs/:jobId/result", tag: "Jobs", secured: true,
+		summary:     "Download the output of a completed job",
+		description: "Outputs are kept when a file storage is configured. Storages that issue presigned URLs redirect to one.",
+		responses: map[int]apiResponse{
+			200: {"The output", binaryBody("application/octet-stream")},
+			302: {"Redirect to a presigned download URL in the Location header", nil},
+			404: failureResponse("Job not found or without a stored output"),
+			409: failureResponse("Job has not completed"),
+			500: failureResponse("Reading the output from storage failed"),
+		},
+	},
+	{
+		method: fiber.MethodPost, path: "/api/v1/process/image/convert", tag: "Processing", secured: true,
+		summary:     "Convert an image",
+		description: "Identical concurrent requests share one conversion. Processing stops if the client disconnects.",
+		body: multipartBody(jsonSchema{
+			"output_format": jsonSchema{"type": "string", "enum": []string{"jpg", "jpeg", "png", "webp", "avif"}},
+			"page": jsonSchema{
+				"type":        "string",
+				"description": "Page of a multi-page TIFF, from 1, or all to stack every page into one image; ignored for other formats",
+				"pattern":     "^([1-9][0-9]*|all)$",
+			},
+			"target_size": jsonSchema{
+				"type":        "integer",
+				"minimum":     1,
+				"description": "Largest output size in bytes; the highest quality that fits is chosen",
+			},
+		}, "output_format"),
+		responses: map[int]apiResponse{
+			200: {"The converted image. With target_size, X-Target-Size, X-Achieved-Size, X-Achieved-Quality and X-Target-Met report the outcome of the search.",
+				binaryBody("image/jpeg", "image/png", "image/webp", "image/avif")},
+			400: validationResponse,
+			413: errorResponse("Upload exceeds the maximum file size"),
+			422: {"Output exceeds the maximum output size", jsonBody(apiOutputTooLarge{})},
+			429: quotaResponse,
+			503: pressureResponse,
+			500: failureResponse("Conversion failed"),
+		},
+	},
+	{
+		method: fiber.MethodPost, path: "/api/v1/process/video/convert", tag: "Processing", secured: true,
+		summary:     "Convert a video",
+		description: "Converts to webm, or to an animated gif or webp preview. The input's length is charged to the tenant's monthly video quota. Identical concurrent requests share one conversion.",
+		body: multipartBody(jsonSchema{
+			"output_format": jsonSchema{"type": "string", "enum": []string{"webm", "gif", "webp"}},
+		}, "output_format"),
+		responses: map[int]apiResponse{
+			200: {"The converted video", binaryBody("video/webm", "image/gif", "image/webp")},
+			400: validationResponse,
+			413: errorResponse("Upload exceeds the maximum file size"),
+			422: {"Output exceeds the maximum output size", jsonBody(apiOutputTooLarge{})},
+			429: quotaResponse,
+			503: pressureResponse,
+			500: failureResponse("Conversion failed"),
+		},
+	},
+	{
+		method: fiber.MethodPost, path: "/api/v1/process/text/pages", tag: "Processing", secured: true,
+		summary: "Extract the text of every page of a PDF",
+		params: []apiParam{
+			{name: "package", in: "query", description: "zip returns the pages as a zip of text files; also accepted as a form field", schema: jsonSchema{"type": "string", "enum": []string{"zip"}}},
+			{name: "mode", in: "query", description: "auto OCRs scanned pages, text flags them as requiring OCR; also accepted as a form field", schema: extractionModeSchema},
+		},
+		body: multipartBody(jsonSchema{
+			"package": jsonSchema{"type": "string", "enum": []string{"zip"}},
+			"mode":    extractionModeSchema,
+		}),
+		responses: map[int]apiResponse{
+			200: {"Page texts, or a zip archive with package=zip", append(jsonBody(apiPages{}), binaryBody("application/zip")...)},
+			400: validationResponse,
+			413: errorResponse("Upload exceeds the maximum file size"),
+			429: quotaResponse,
+			503: pressureResponse,
+			500: failureResponse("Extraction failed"),
+		},
+	},
+	{
+		method: fiber.MethodPost, path: "/api/v1/process/pdf/thumbnail", tag: "Processing", secured: true,
+		summary: "Render a page of a PDF as a PNG preview",
+		body: multipartBody(jsonSchema{
+			"page": jsonSchema{"type": "integer", "minimum": 1, "default": 1},
+			"size": jsonSchema{"type": "integer", "minimum": 1, "maximum": media.MaxPDFThumbnailSize, "default": media.DefaultPDFThumbnailSize},
+		}),
+		responses: map[int]apiResponse{
+			200: {"The rendered page", binaryBody("image/png")},
+			400: validationResponse,
+			413: errorResponse("Upload exceeds the maximum file size"),
+			429: quotaResponse,
+			503: pressureResponse,
+			500: failureResponse("Rendering failed"),
+		},
+	},
+	{
+		method: fiber.MethodPost, path: "/api/v1/process/pdf/outline", tag: "Processing", secured: true,
+		summary:     "Extract the outline (bookmarks) of a PDF",
+		description: "Titles, levels and target pages of the bookmark tree. PDFs without bookmarks return an empty outline.",
+		body:        multipartBody(jsonSchema{}),
+		responses: map[int]apiResponse{
+			200: {"The outline tree", jsonBody(apiOutline{})},
+			413: errorResponse("Upload exceeds the maximum file size"),
+			429: quotaResponse,
+			503: pressureResponse,
+			500: failureResponse("Extraction failed"),
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/api/v1/recordings", tag: "Recordings", secured: true,
+		summary:     "List recorded failed requests",
+		description: "Available to administrators when request recording and authentication are enabled.",
+		responses: map[int]apiResponse{
+			200: {"Recordings, newest first", jsonBody(apiRecordings{})},
+			403: errorResponse("Caller is not an administrator"),
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/api/v1/recordings/:id", tag: "Recordings", secured: true,
+		summary:     "Download a recording for replay",
+		description: "A zip holding request.json and the recorded input. Replay it with the CLI's replay command.",
+		responses: map[int]apiResponse{
+			200: {"The recording", binaryBody("application/zip")},
+			403: errorResponse("Caller is not an administrator"),
+			404: errorResponse("Recording not found"),
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/api/v1/quota", tag: "Quota", secured: true,
+		summary:     "The caller's usage against the monthly quota",
+		description: "Available when quotas are enabled. Usage resets at the start of each month (UTC); zero limits are unlimited.",
+		responses: map[int]apiResponse{
+			200: {"Usage in the current month", jsonBody(quota.Status{})},
+			401: errorResponse("Authentication required"),
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/api/v1/quota/:tenant", tag: "Quota", secured: true,
+		summary: "A tenant's usage against the monthly quota",
+		responses: map[int]apiResponse{
+			200: {"Usage in the current month", jsonBody(quota.Status{})},
+			403: errorResponse("Caller is not an administrator"),
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/health", tag: "Health",
+		summary: "Worker health for load balancers and orchestrators",
+		responses: map[int]apiResponse{
+			200: {"Healthy", jsonBody(health.HealthStatus{})},
+			503: {"Unhealthy", jsonBody(health.HealthStatus{})},
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/metrics/cache", tag: "Metrics",
+		summary:   "Cache metrics in the Prometheus text format",
+		responses: map[int]apiResponse{200: {"Metrics", textBody()}},
+	},
+	{
+		method: fiber.MethodGet, path: "/metrics/maintenance", tag: "Metrics",
+		summary: "Orphaned file cleanup metrics in the Prometheus text format",
+		responses: map[int]apiResponse{
+			200: {"Metrics", textBody()},
+			404: {"Cleanup is disabled", nil},
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/metrics/requests", tag: "Metrics",
+		summary:   "Request cancellation metrics in the Prometheus text format",
+		responses: map[int]apiResponse{200: {"Metrics", textBody()}},
+	},
+	{
+		method: fiber.MethodGet, path: "/metrics/memory", tag: "Metrics",
+		summary: "Memory pressure and shedding metrics in the Prometheus text format",
+		responses: map[int]apiResponse{
+			200: {"Metrics", textBody()},
+			404: {"Memory pressure shedding is disabled", nil},
+		},
+	},
+	{
+		method: fiber.MethodGet, path: "/openapi.json", tag: "Documentation",
+		summary:   "This OpenAPI document",
+		responses: map[int]apiResponse{200: {"The OpenAPI document", []apiContent{{contentType: fiber.MIMEApplicationJSON, schema: jsonSchema{"type": "object"}}}}},
+	},
+}
+
+// fiberParam matches Fiber path parameters such as :id
+var fiberParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// OpenAPISpec builds the OpenAPI 3 document describing the HTTP API
+func OpenAPISpec(version string) map[string]interface{} {
+	components := jsonSchema{"ProcessingOptions": processingOptionsSchema}
+	reflector := &schemaReflector{components: components}
+
+	paths := jsonSchema{}
+	for _, op := range apiOperations {
+		path := fiberParam.ReplaceAllString(op.path, "{$1}")
+		item, ok := paths[path].(jsonSchema)
+		if !ok {
+			item = jsonSchema{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.method)] = reflector.operation(op)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": jsonSchema{
+			"title":       "Documents Worker API",
+			"version":     version,
+			"description": "Document, image, video and PDF processing. Errors returned by handlers use the Error schema; processing failures use the Failure schema.",
+		},
+		"paths": paths,
+		"components": jsonSchema{
+			"schemas": components,
+			"securitySchemes": jsonSchema{
+				"bearerAuth": jsonSchema{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "Required when the server runs with AUTH_ENABLED",
+				},
+			},
+		},
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document as JSON
+func OpenAPIHandler(version string) fiber.Handler {
+	spec, err := json.Marshal(OpenAPISpec(version))
+	return func(c *fiber.Ctx) error {
+		if err != nil {
+			return fmt.Errorf("failed to encode OpenAPI document: %w", err)
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+		return c.Send(spec)
+	}
+}
+
+// swaggerUIPage loads Swagger UI from a CDN and points it at the document
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Documents Worker API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>window.ui = SwaggerUIBundle({url: %q, dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+// SwaggerUIHandler serves a Swagger UI page for the document at specURL
+func SwaggerUIHandler(specURL string) fiber.Handler {
+	page := fmt.Sprintf(swaggerUIPage, specURL)
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.SendString(page)
+	}
+}
+
+// schemaReflector turns Go types into schemas, registering named structs
+// as components
+type schemaReflector struct {
+	components jsonSchema
+}
+
+func (r *schemaReflector) operation(op apiOperation) jsonSchema {
+	operation := jsonSchema{
+		"summary":     op.summary,
+		"tags":        []string{op.tag},
+		"operationId": operationID(op),
+	}
+	if op.description != "" {
+		operation["description"] = op.description
+	}
+	if op.secured {
+		operation["security"] = []jsonSchema{{"bearerAuth": []string{}}}
+	}
+
+	var params []jsonSchema
+	for _, match := range fiberParam.FindAllStringSubmatch(op.path, -1) {
+		params = append(params, jsonSchema{"name": match[1], "in": "path", "required": true, "schema": jsonSchema{"type": "string"}})
+	}
+	for _, param := range op.params {
+		params = append(params, jsonSchema{"name": param.name, "in": param.in, "description": param.description, "required": param.required, "schema": param.schema})
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+
+	if op.body != nil {
+		operation["requestBody"] = jsonSchema{
+			"required": true,
+			"content":  r.content([]apiContent{*op.body}),
+		}
+	}
+
+	responses := jsonSchema{}
+	for status, response := range op.responses {
+		entry := jsonSchema{"description": response.description}
+		if len(response.content) > 0 {
+			entry["content"] = r.content(response.content)
+		}
+		responses[fmt.Sprint(status)] = entry
+	}
+	operation["responses"] = responses
+	return operation
+}
+
+func (r *schemaReflector) content(contents []apiContent) jsonSchema {
+	content := jsonSchema{}
+	for _, c := range contents {
+		schema := c.schema
+		if c.value != nil {
+			schema = r.schemaOf(reflect.TypeOf(c.value))
+		}
+		content[c.contentType] = jsonSchema{"schema": schema}
+	}
+	return content
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+func (r *schemaReflector) schemaOf(t reflect.Type) jsonSchema {
+	if enum, ok := apiEnums[t]; ok {
+		return jsonSchema{"type": "string", "enum": enum}
+	}
+
+	switch t {
+	case timeType:
+		return jsonSchema{"type": "string", "format": "date-time"}
+	case durationType:
+		return jsonSchema{"type": "integer", "format": "int64", "description": "Duration in nanoseconds"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := jsonSchema{}
+		for key, value := range r.schemaOf(t.Elem()) {
+			schema[key] = value
+		}
+		if _, isRef := schema["$ref"]; !isRef {
+			schema["nullable"] = true
+		}
+		return schema
+	case reflect.Struct:
+		return r.component(t)
+	case reflect.Slice, reflect.Array:
+		return jsonSchema{"type": "array", "items": r.schemaOf(t.Elem())}
+	case reflect.Map:
+		return jsonSchema{"type": "object", "additionalProperties": r.schemaOf(t.Elem())}
+	case reflect.Interface:
+		return jsonSchema{}
+	case reflect.String:
+		return jsonSchema{"type": "string"}
+	case reflect.Bool:
+		return jsonSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return jsonSchema{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return jsonSchema{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return jsonSchema{"type": "number"}
+	}
+	return jsonSchema{}
+}
+
+// component registers a struct schema under a component name derived from
+// the type name and returns a reference to it
+func (r *schemaReflector) component(t reflect.Type) jsonSchema {
+	name := componentName(t)
+	ref := jsonSchema{"$ref": "#/components/schemas/" + name}
+	if _, ok := r.components[name]; ok {
+		return ref
+	}
+	// Register before recursing so self-referencing types terminate
+	schema := jsonSchema{"type": "object"}
+	r.components[name] = schema
+
+	properties := jsonSchema{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, omitempty := jsonFieldName(field)
+		if name == "" {
+			continue
+		}
+		if override, ok := apiFieldSchemas[t.Name()+"."+name]; ok {
+			properties[name] = override
+		} else {
+			properties[name] = r.schemaOf(field.Type)
+		}
+		if !omitempty && field.Type.Kind() != reflect.Ptr {
+			required = append(required, name)
+		}
+	}
+	schema["properties"] = properties
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return ref
+}
+
+// componentName names schemas after their Go type, dropping the api prefix
+// of the types declared for the document
+func componentName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "api")
+	if t.PkgPath() == reflect.TypeOf(health.HealthStatus{}).PkgPath() {
+		name = "Worker" + name
+	}
+	return name
+}
+
+// jsonFieldName returns the JSON name of a struct field, or "" when the
+// field is not encoded
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(options, "omitempty")
+}
+
+// operationID derives a stable operation ID such as getApiV1JobsJobId
+func operationID(op apiOperation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(op.method))
+	for _, part := range strings.FieldsFunc(op.path, func(r rune) bool { return r == '/' || r == ':' || r == '.' || r == '_' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"bufio"
+	"documents-worker/packaging"
+	"documents-worker/validation"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// packageOption reads the package option from the form fields or the query
+// string and reports whether an archive was requested
+func packageOption(c *fiber.Ctx, fields map[string]string) (bool, error) {
+	option := strings.ToLower(fields["package"])
+	if option == "" {
+		option = strings.ToLower(c.Query("package"))
+	}
+
+	if err := validation.New().OneOf("package", option, packaging.FormatZip).Err(); err != nil {
+		return false, err
+	}
+	return packaging.Requested(option)
+}
+
+// sendPackage streams the entries to the client as a zip archive with a
+// manifest. The archive is written after the handler returns, so entry
+// sources must stay readable until then.
+func sendPackage(c *fiber.Ctx, filename, operation string, entries []packaging.Entry) error {
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if _, err := packaging.WriteZip(w, operation, entries); err != nil {
+			// Headers are already sent; the client sees a truncated archive
+			log.Errorf("Failed to stream %s archive: %v", operation, err)
+		}
+		w.Flush()
+	})
+	return nil
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"documents-worker/memory"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// memoryPollInterval is how often held requests check whether memory
+// pressure has eased
+var memoryPollInterval = 100 * time.Millisecond
+
+// MemoryShedder holds processing requests while memory is under pressure and
+// rejects them with 503 when it does not ease in time, so load is shed
+// before the worker or its vips and ffmpeg children are killed for OOM
+type MemoryShedder struct {
+	gauge   *memory.Gauge
+	maxWait time.Duration
+	shed    atomic.Int64
+	delayed atomic.Int64
+}
+
+// NewMemoryShedder creates a shedder holding requests for up to maxWait
+func NewMemoryShedder(gauge *memory.Gauge, maxWait time.Duration) *MemoryShedder {
+	return &MemoryShedder{gauge: gauge, maxWait: maxWait}
+}
+
+// Handler returns the middleware; apply it to expensive routes only
+func (s *MemoryShedder) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if s.gauge.UnderPressure() {
+			s.delayed.Add(1)
+			if !s.wait(c) {
+				s.shed.Add(1)
+				retryAfter := int(math.Ceil(math.Max(s.maxWait.Seconds(), 1)))
+				c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+				return fiber.NewError(fiber.StatusServiceUnavailable, "Server is low on memory, retry later")
+			}
+		}
+		return c.Next()
+	}
+}
+
+// wait reports whether pressure eased within maxWait
+func (s *MemoryShedder) wait(c *fiber.Ctx) bool {
+	ctx := c.UserContext()
+	deadline := time.NewTimer(s.maxWait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(memoryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !s.gauge.UnderPressure() {
+				return true
+			}
+		case <-deadline.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Shed returns the number of requests rejected under memory pressure
+func (s *MemoryShedder) Shed() int64 {
+	return s.shed.Load()
+}
+
+// Delayed returns the number of requests held under memory pressure,
+// including those eventually shed
+func (s *MemoryShedder) Delayed() int64 {
+	return s.delayed.Load()
+}
+
+// WritePrometheus writes memory pressure metrics in the Prometheus text format
+func (s *MemoryShedder) WritePrometheus(w io.Writer) error {
+	sample := s.gauge.Sample()
+	pressure := 0
+	if s.gauge.UnderPressure() {
+		pressure = 1
+	}
+	_, err := fmt.Fprintf(w, `# HELP documents_worker_memory_used_bytes Memory in use, including child processes when measured from the cgroup.
+# TYPE documents_worker_memory_used_bytes gauge
+documents_worker_memory_used_bytes %d
+# HELP documents_worker_memory_limit_bytes Memory limit pressure is measured against.
+# TYPE documents_worker_memory_limit_bytes gauge
+documents_worker_memory_limit_bytes %d
+# HELP documents_worker_memory_pressure Whether memory use is at or above the pressure threshold.
+# TYPE documents_worker_memory_pressure gauge
+documents_worker_memory_pressure %d
+# HELP documents_worker_requests_delayed_total Requests held because of memory pressure.
+# TYPE documents_worker_requests_delayed_total counter
+documents_worker_requests_delayed_total{reason="memory_pressure"} %d
+# HELP documents_worker_requests_shed_total Requests rejected because of memory pressure.
+# TYPE documents_worker_requests_shed_total counter
+documents_worker_requests_shed_total{reason="memory_pressure"} %d
+`, sample.Used, sample.Limit, pressure, s.Delayed(), s.Shed())
+	return err
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"context"
+	"documents-worker/auth"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
+	"documents-worker/media"
+	"documents-worker/quota"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// apiQuotaExceeded is the body written when a tenant's quota is used up
+type apiQuotaExceeded struct {
+	Error   string        `json:"error"`
+	Code    int           `json:"code"`
+	Success bool          `json:"success"`
+	Limit   string        `json:"limit"`
+	Quota   *quota.Status `json:"quota"`
+}
+
+// EnforceQuota rejects requests of tenants whose monthly quota is used up
+// with 429 and charges successful requests with what they used: the bytes
+// and video time recorded to the request's quota.Meter, or the body size
+// when nothing was recorded. It must run after RequireAuth; unauthenticated
+// requests pass through. Quota store failures are logged and do not block
+// processing.
+func EnforceQuota(limiter *quota.Limiter, tenantClaim string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := PrincipalFrom(c)
+		if principal == nil {
+			return c.Next()
+		}
+		tenant := tenantOf(principal, tenantClaim)
+
+		_, err := limiter.Check(c.UserContext(), tenant)
+		var exceeded *quota.ExceededError
+		if errors.As(err, &exceeded) {
+			retryAfter := time.Until(exceeded.Status.ResetsAt).Seconds()
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter)+1))
+			return c.Status(fiber.StatusTooManyRequests).JSON(apiQuotaExceeded{
+				Error: err.Error(),
+				Code:  fiber.StatusTooManyRequests,
+				Limit: exceeded.Limit,
+				Quota: exceeded.Status,
+			})
+		}
+		if err != nil {
+			log.Errorf("Quota check for tenant %s failed: %v", tenant, err)
+		}
+
+		meter := &quota.Meter{}
+		c.SetUserContext(quota.WithMeter(c.UserContext(), meter))
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() >= fiber.StatusBadRequest {
+			return nil
+		}
+
+		usage := meter.Usage()
+		usage.Requests = 1
+		if usage.Bytes == 0 {
+			usage.Bytes = bodySize(c)
+		}
+		// The response is complete, so the charge must not depend on the client
+		if _, err := limiter.Charge(context.Background(), tenant, usage); err != nil {
+			log.Errorf("Failed to charge quota of tenant %s: %v", tenant, err)
+		}
+		return nil
+	}
+}
+
+// meterVideoJob charges the length of an accepted async video job's input
+// to the request's quota meter. The worker converting it later has no
+// request to charge, so the job is charged when it is accepted. The input
+// is the stored document, or the input_path handed to the worker.
+func meterVideoJob(ctx context.Context, service ports.DocumentService, req *domain.ProcessingRequest) {
+	meter := quota.MeterFrom(ctx)
+	if meter == nil || req.Type != domain.ProcessingTypeVideoConvert {
+		return
+	}
+
+	inputPath, _ := req.Parameters["input_path"].(string)
+	if doc, err := service.GetDocument(ctx, req.DocumentID); err == nil && doc.Path != "" {
+		inputPath = doc.Path
+	}
+	if inputPath == "" {
+		return
+	}
+
+	seconds, err := media.ProbeDuration(inputPath)
+	if err != nil {
+		log.Errorf("Failed to read duration of video job input for quota: %v", err)
+		return
+	}
+	meter.AddVideo(time.Duration(seconds * float64(time.Second)))
+}
+
+// bodySize is the size of a request body that was not metered while it was
+// read. Chunked bodies have no Content-Length and are measured instead.
+func bodySize(c *fiber.Ctx) int64 {
+	if length := c.Request().Header.ContentLength(); length >= 0 {
+		return int64(length)
+	}
+	return int64(len(c.Body()))
+}
+
+// tenantOf identifies the caller's tenant by the tenant claim, falling back
+// to the token subject
+func tenantOf(principal *auth.Principal, tenantClaim string) string {
+	if tenant, ok := principal.Claims[tenantClaim].(string); ok && tenant != "" {
+		return tenant
+	}
+	return principal.Subject
+}
+
+// QuotaHandler reports quota usage. Its routes must run after RequireAuth.
+type QuotaHandler struct {
+	limiter     *quota.Limiter
+	tenantClaim string
+}
+
+// NewQuotaHandler creates a handler reporting the limiter's usage
+func NewQuotaHandler(limiter *quota.Limiter, tenantClaim string) *QuotaHandler {
+	return &QuotaHandler{limiter: limiter, tenantClaim: tenantClaim}
+}
+
+// SetupRoutes registers the quota routes; any tenant's usage is visible to
+// administrators only
+func (h *QuotaHandler) SetupRoutes(app *fiber.App) {
+	quotas := app.Group("/api/v1/quota")
+	quotas.Get("/", h.Own)
+	quotas.Get("/:tenant", RequireRole("admin"), h.Tenant)
+}
+
+// Own returns the caller's usage against the monthly limits
+func (h *QuotaHandler) Own(c *fiber.Ctx) error {
+	principal := PrincipalFrom(c)
+	if principal == nil {
+		return fiber.NewError(fiber.StatusUnauthorized, "Authentication required")
+	}
+	return h.status(c, tenantOf(principal, h.tenantClaim))
+}
+
+// Tenant returns a tenant's usage against the monthly limits
+func (h *QuotaHandler) Tenant(c *fiber.Ctx) error {
+	return h.status(c, c.Params("tenant"))
+}
+
+func (h *QuotaHandler) status(c *fiber.Ctx, tenant string) error {
+	status, err := h.limiter.Status(c.UserContext(), tenant)
+	if err != nil {
+		return err
+	}
+	return c.JSON(status)
+}
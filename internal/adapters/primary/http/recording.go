@@ -0,0 +1,213 @@
+package http
+
+import (
+	"bytes"
+	"documents-worker/packaging"
+	"documents-worker/recorder"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// captureKey is the c.Locals key of the capture filled by spoolUpload
+const captureKey = "recorder.capture"
+
+// maxRecordedResponse caps the error response kept with a recording
+const maxRecordedResponse = 4 * 1024
+
+// requestCapture holds what a handler consumed from the request, so it can
+// be recorded once the handler has failed
+type requestCapture struct {
+	maxSize int64
+	input   *recorder.Input
+	fields  map[string]string
+	path    string // hard link or copy of the spooled upload
+}
+
+// keep preserves a spooled upload beyond the handler's Release
+func (rc *requestCapture) keep(upload *spooledUpload, field string) {
+	rc.fields = upload.Fields
+	rc.input = &recorder.Input{Field: field, Filename: upload.Filename, Size: upload.Size}
+	if upload.Size > rc.maxSize {
+		return
+	}
+
+	path := upload.File.Name() + "-recording"
+	if err := os.Link(upload.File.Name(), path); err != nil {
+		if err := copyFile(upload.File.Name(), path); err != nil {
+			log.Errorf("Failed to keep upload for recording: %v", err)
+			return
+		}
+	}
+	rc.path = path
+	rc.input.Stored = true
+}
+
+func (rc *requestCapture) release() {
+	if rc.path != "" {
+		os.Remove(rc.path)
+	}
+}
+
+// RecordFailures stores requests that end in a server error, with their
+// uploaded file or JSON body, so they can be replayed with the CLI's replay
+// command. Credentials and e-mail addresses are redacted by the recorder.
+func RecordFailures(rec *recorder.Recorder) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		capture := &requestCapture{maxSize: rec.MaxInputSize()}
+		c.Locals(captureKey, capture)
+		defer capture.release()
+
+		// Resolve handler errors here so the recorded status is the one sent
+		if err := c.Next(); err != nil {
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				c.Status(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := c.Response().StatusCode()
+		if status < fiber.StatusInternalServerError {
+			return nil
+		}
+
+		recording, input := capturedRecording(c, capture)
+		if closer, ok := input.(io.Closer); ok {
+			defer closer.Close()
+		}
+		recording.Status = status
+		if err := rec.Save(recording, input); err != nil {
+			log.Errorf("Failed to record failed request: %v", err)
+			return nil
+		}
+		log.Infof("Recorded failed request %s %s as %s", recording.Method, recording.Path, recording.ID)
+		return nil
+	}
+}
+
+// capturedRecording describes the request and opens its payload: the kept
+// upload of a multipart request, or the body of a small JSON request
+func capturedRecording(c *fiber.Ctx, capture *requestCapture) (*recorder.Recording, io.Reader) {
+	recording := &recorder.Recording{
+		Method:  c.Method(),
+		Path:    c.Path(),
+		Query:   string(c.Request().URI().QueryString()),
+		Headers: make(map[string]string),
+		Fields:  capture.fields,
+		Input:   capture.input,
+	}
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		recording.Headers[string(key)] = string(value)
+	})
+
+	response := c.Response().Body()
+	if len(response) > maxRecordedResponse {
+		response = response[:maxRecordedResponse]
+	}
+	recording.Response = string(response)
+
+	if capture.path != "" {
+		file, err := os.Open(capture.path)
+		if err != nil {
+			recording.Input.Stored = false
+			return recording, nil
+		}
+		return recording, file
+	}
+
+	// Only small JSON bodies are read here; other bodies may still be
+	// streaming and were already consumed by the handler
+	mediaType, _, _ := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	length := int64(c.Request().Header.ContentLength())
+	if capture.input == nil && mediaType == fiber.MIMEApplicationJSON && length > 0 {
+		recording.Input = &recorder.Input{ContentType: mediaType, Size: length}
+		if length <= capture.maxSize {
+			recording.Input.Stored = true
+			return recording, bytes.NewReader(c.Body())
+		}
+	}
+	return recording, nil
+}
+
+// captureFrom returns the request's capture when recording is enabled
+func captureFrom(c *fiber.Ctx) *requestCapture {
+	capture, _ := c.Locals(captureKey).(*requestCapture)
+	return capture
+}
+
+// RecordingHandler serves stored recordings. Recordings may contain
+// customer documents, so its routes must be restricted to administrators.
+type RecordingHandler struct {
+	recorder *recorder.Recorder
+}
+
+// NewRecordingHandler creates a handler for the recorder's recordings
+func NewRecordingHandler(rec *recorder.Recorder) *RecordingHandler {
+	return &RecordingHandler{recorder: rec}
+}
+
+// SetupRoutes registers the recording routes
+func (h *RecordingHandler) SetupRoutes(app *fiber.App) {
+	recordings := app.Group("/api/v1/recordings")
+	recordings.Get("/", h.List)
+	recordings.Get("/:id", h.Download)
+}
+
+// List returns the stored recordings, newest first
+func (h *RecordingHandler) List(c *fiber.Ctx) error {
+	recordings, err := h.recorder.List()
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"recordings": recordings,
+		"count":      len(recordings),
+	})
+}
+
+// Download streams a recording as a zip holding request.json and the input,
+// ready to be extracted and replayed
+func (h *RecordingHandler) Download(c *fiber.Ctx) error {
+	recording, inputPath, err := h.recorder.Load(c.Params("id"))
+	if errors.Is(err, recorder.ErrNotFound) {
+		return fiber.NewError(fiber.StatusNotFound, "Recording not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(recording, "", "  ")
+	if err != nil {
+		return err
+	}
+	entries := []packaging.Entry{packaging.BytesEntry("request.json", data, fiber.MIMEApplicationJSON)}
+	if inputPath != "" {
+		entries = append(entries, packaging.FileEntry("input", inputPath, "application/octet-stream"))
+	}
+	return sendPackage(c, fmt.Sprintf("recording-%s.zip", strings.ReplaceAll(recording.ID, ".", "-")), "recording", entries)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
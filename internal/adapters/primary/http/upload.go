@@ -0,0 +1,234 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"documents-worker/maintenance"
+	"documents-worker/quota"
+	"documents-worker/validation"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Non-file multipart fields are kept in memory, so their size and number
+// are capped per field and per request
+const (
+	maxFormValueSize  = 1 << 20 // 1MB
+	maxFormFieldsSize = 4 << 20 // 4MB
+	maxFormFields     = 100
+)
+
+// UploadConfig controls how multipart uploads are spooled to disk
+type UploadConfig struct {
+	// TempDir receives spooled uploads; empty uses the system temp directory
+	TempDir string
+	// MaxFileSize rejects uploads larger than this many bytes; zero disables the check
+	MaxFileSize int64
+	// Tracker, when set, protects spooled files from temp cleanup while the
+	// request is in flight
+	Tracker *maintenance.Tracker
+	// VerifyContent rejects uploads whose magic number does not match their
+	// file extension or declared content type, before any processing
+	VerifyContent bool
+	// SniffSize is how many leading bytes are sniffed, bounded by
+	// validation.SniffSize
+	SniffSize int
+	// Scanner, when set, rejects uploads it finds malware in. Uploads that
+	// cannot be scanned are rejected with 503, or let through with
+	// ScanFailOpen.
+	Scanner      validation.MalwareScanner
+	ScanFailOpen bool
+}
+
+// spooledUpload is a multipart upload whose file part was streamed to disk
+type spooledUpload struct {
+	File        *os.File
+	Filename    string
+	ContentType string // declared by the client
+	Size        int64
+	Hash        []byte // sha256 of the file content
+	Fields      map[string]string
+
+	fieldCount int
+	fieldBytes int
+	untrack    func()
+}
+
+// Reader rewinds the spooled file and returns it for reading
+func (u *spooledUpload) Reader() (io.Reader, error) {
+	if _, err := u.File.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind upload: %w", err)
+	}
+	return u.File, nil
+}
+
+// Release closes and removes the spooled file
+func (u *spooledUpload) Release() {
+	u.File.Close()
+	os.Remove(u.File.Name())
+	if u.untrack != nil {
+		u.untrack()
+	}
+}
+
+// spoolUpload streams a multipart request body, writing the named file part
+// to a temp file instead of buffering it in memory. Form fields are
+// collected alongside. The temp file is removed on any error.
+func spoolUpload(c *fiber.Ctx, field string, cfg UploadConfig) (*spooledUpload, error) {
+	_, params, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil || params["boundary"] == "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Expected multipart/form-data request")
+	}
+
+	// With StreamRequestBody the body is read from the connection as we go;
+	// otherwise fall back to the buffered body
+	body := c.Context().RequestBodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+
+	upload := &spooledUpload{Fields: make(map[string]string)}
+	if err := upload.read(multipart.NewReader(body, params["boundary"]), field, cfg); err != nil {
+		if upload.File != nil {
+			upload.Release()
+		}
+		return nil, err
+	}
+
+	if upload.File == nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "No file provided")
+	}
+	if err := upload.scan(c.UserContext(), cfg); err != nil {
+		upload.Release()
+		return nil, err
+	}
+	// Chunked uploads carry no Content-Length, so quota is charged for what
+	// was actually read
+	quota.MeterFrom(c.UserContext()).AddBytes(upload.Size + int64(upload.fieldBytes))
+	if capture := captureFrom(c); capture != nil {
+		capture.keep(upload, field)
+	}
+	if entry := debugEntryFrom(c); entry != nil {
+		entry.upload(upload, field)
+	}
+	return upload, nil
+}
+
+func (u *spooledUpload) read(reader *multipart.Reader, field string, cfg UploadConfig) error {
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid multipart body: "+err.Error())
+		}
+
+		switch {
+		case part.FormName() == field && part.FileName() != "" && u.File == nil:
+			err = u.spoolFile(part, cfg)
+		case part.FileName() == "":
+			err = u.readField(part)
+		default:
+			// Ignore unexpected file parts without buffering them
+			_, err = io.Copy(io.Discard, part)
+		}
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (u *spooledUpload) spoolFile(part *multipart.Part, cfg UploadConfig) error {
+	file, err := os.CreateTemp(cfg.TempDir, "upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create upload temp file: %w", err)
+	}
+	u.File = file
+	u.Filename = part.FileName()
+	u.ContentType = part.Header.Get(fiber.HeaderContentType)
+	if cfg.Tracker != nil {
+		u.untrack = cfg.Tracker.Track(file.Name())
+	}
+
+	var src io.Reader = part
+	if cfg.MaxFileSize > 0 {
+		src = io.LimitReader(part, cfg.MaxFileSize+1)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), src)
+	if err != nil {
+		return fmt.Errorf("failed to spool upload: %w", err)
+	}
+	if cfg.MaxFileSize > 0 && size > cfg.MaxFileSize {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("File exceeds maximum size of %d bytes", cfg.MaxFileSize))
+	}
+
+	u.Size = size
+	u.Hash = hash.Sum(nil)
+	if cfg.VerifyContent {
+		return u.verifyContent(cfg.SniffSize)
+	}
+	return nil
+}
+
+// verifyContent checks the magic number of the spooled file against its
+// name and declared content type
+func (u *spooledUpload) verifyContent(sniffSize int) error {
+	head, err := validation.ReadHead(io.NewSectionReader(u.File, 0, u.Size), sniffSize)
+	if err != nil {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	return validation.New().Content("file", head, u.Filename, u.ContentType).Err()
+}
+
+// scan runs the spooled file past the malware scanner, if any
+func (u *spooledUpload) scan(ctx context.Context, cfg UploadConfig) error {
+	if cfg.Scanner == nil {
+		return nil
+	}
+	result, err := cfg.Scanner.Scan(ctx, io.NewSectionReader(u.File, 0, u.Size))
+	if err != nil {
+		if cfg.ScanFailOpen {
+			log.Warnf("Malware scan of %s skipped: %v", u.Filename, err)
+			return nil
+		}
+		return fiber.NewError(fiber.StatusServiceUnavailable, "Malware scan unavailable: "+err.Error())
+	}
+	return validation.New().Malware("file", result).Err()
+}
+
+func (u *spooledUpload) readField(part *multipart.Part) error {
+	if u.fieldCount++; u.fieldCount > maxFormFields {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Too many form fields: at most %d are allowed", maxFormFields))
+	}
+
+	limit := min(maxFormValueSize, maxFormFieldsSize-u.fieldBytes)
+	value, err := io.ReadAll(io.LimitReader(part, int64(limit)+1))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid multipart body: "+err.Error())
+	}
+	if len(value) > maxFormValueSize {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Form field %q is too large", part.FormName()))
+	}
+	if len(value) > limit {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Form fields exceed %d bytes in total", maxFormFieldsSize))
+	}
+	u.fieldBytes += len(value)
+	u.Fields[part.FormName()] = string(value)
+	return nil
+}
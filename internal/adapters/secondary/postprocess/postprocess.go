@@ -0,0 +1,90 @@
+// Package postprocess builds the post-processors named in the configuration.
+// Integrations register a factory under a name from an init function and are
+// enabled with POSTPROCESSORS, without changes to the processing pipeline.
+package postprocess
+
+import (
+	"context"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates a post-processor from its settings, the entries of
+// POSTPROCESS_SETTINGS prefixed with its name, e.g. cdn.bucket=assets
+type Factory func(settings map[string]string) (ports.PostProcessor, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{
+		"noop": func(map[string]string) (ports.PostProcessor, error) { return NoOp{}, nil },
+	}
+)
+
+// Register makes a post-processor available under name. Registering a name
+// twice panics, as with database/sql drivers.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, exists := factories[name]; exists {
+		panic("postprocess: Register called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// Build creates the named post-processors in order
+func Build(names []string, settings map[string]string) ([]ports.PostProcessor, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	postProcessors := make([]ports.PostProcessor, 0, len(names))
+	for _, name := range names {
+		factory, ok := factories[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown post-processor %q (available: %s)", name, strings.Join(available(), ", "))
+		}
+		postProcessor, err := factory(settingsFor(name, settings))
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure post-processor %s: %w", name, err)
+		}
+		postProcessors = append(postProcessors, postProcessor)
+	}
+	return postProcessors, nil
+}
+
+// settingsFor returns the settings prefixed with name, without the prefix
+func settingsFor(name string, settings map[string]string) map[string]string {
+	own := make(map[string]string)
+	for key, value := range settings {
+		if setting, ok := strings.CutPrefix(key, name+"."); ok {
+			own[setting] = value
+		}
+	}
+	return own
+}
+
+func available() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NoOp is the default post-processor; it leaves results untouched
+type NoOp struct{}
+
+// Name returns the registered name
+func (NoOp) Name() string {
+	return "noop"
+}
+
+// PostProcess does nothing
+func (NoOp) PostProcess(ctx context.Context, output *domain.ProcessingOutput) error {
+	return nil
+}
@@ -0,0 +1,47 @@
+package postprocess
+
+import (
+	"context"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type namedHook struct {
+	name     string
+	settings map[string]string
+}
+
+func (h *namedHook) Name() string { return h.name }
+
+func (h *namedHook) PostProcess(ctx context.Context, output *domain.ProcessingOutput) error {
+	return nil
+}
+
+func TestBuildCreatesRegisteredPostProcessorsInOrder(t *testing.T) {
+	Register("test-cdn", func(settings map[string]string) (ports.PostProcessor, error) {
+		return &namedHook{name: "test-cdn", settings: settings}, nil
+	})
+
+	postProcessors, err := Build([]string{"test-cdn", "noop"}, map[string]string{
+		"test-cdn.bucket": "assets",
+		"other.bucket":    "ignored",
+	})
+	require.NoError(t, err)
+	require.Len(t, postProcessors, 2)
+	assert.Equal(t, map[string]string{"bucket": "assets"}, postProcessors[0].(*namedHook).settings)
+	assert.Equal(t, "noop", postProcessors[1].Name())
+	assert.NoError(t, postProcessors[1].PostProcess(context.Background(), &domain.ProcessingOutput{}))
+
+	assert.Panics(t, func() {
+		Register("test-cdn", func(map[string]string) (ports.PostProcessor, error) { return NoOp{}, nil })
+	})
+}
+
+func TestBuildRejectsUnknownPostProcessors(t *testing.T) {
+	_, err := Build([]string{"dam"}, nil)
+	assert.ErrorContains(t, err, `unknown post-processor "dam"`)
+}
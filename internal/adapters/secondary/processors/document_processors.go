@@ -1,30 +1,69 @@
 package processors
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"documents-worker/cache"
 	"documents-worker/config"
+	"documents-worker/fetch"
+	"documents-worker/internal/core/domain"
 	"documents-worker/internal/core/ports"
+	"documents-worker/media"
 	"documents-worker/ocr"
 	"documents-worker/pdfgen"
 	"documents-worker/textextractor"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"strings"
 )
 
+// urlPDFContentTypes are the pages URL-PDF generation fetches when no
+// content types are configured
+var urlPDFContentTypes = []string{"text/html", "application/xhtml+xml"}
+
 // PlaywrightPDFProcessor implements the PDFProcessor port using Playwright
 type PlaywrightPDFProcessor struct {
 	generator *pdfgen.PDFGenerator
+	limits    *config.LimitsConfig
+	mutool    string
+	cache     *cache.CacheManager
+	fetcher   *fetch.Fetcher
 }
 
-// NewPlaywrightPDFProcessor creates a new Playwright PDF processor
-func NewPlaywrightPDFProcessor(externalConfig *config.ExternalConfig) ports.PDFProcessor {
+// NewPlaywrightPDFProcessor creates a new Playwright PDF processor. The
+// cache, which may be nil, stores rendered thumbnails by content hash.
+// With a fetch configuration, pages rendered from URLs and everything they
+// load are downloaded within its limits; with nil the browser loads them
+// unchecked.
+func NewPlaywrightPDFProcessor(externalConfig *config.ExternalConfig, limits *config.LimitsConfig, cacheManager *cache.CacheManager, fetchConfig *config.FetchConfig) ports.PDFProcessor {
 	generator := pdfgen.NewPDFGenerator(externalConfig)
 
-	return &PlaywrightPDFProcessor{
+	processor := &PlaywrightPDFProcessor{
 		generator: generator,
-	}
+		limits:    limits,
+		mutool:    externalConfig.MutoolPath,
+		cache:     cacheManager,
+	}
+	if fetchConfig != nil {
+		contentTypes := fetchConfig.AllowedContentTypes
+		if len(contentTypes) == 0 {
+			contentTypes = urlPDFContentTypes
+		}
+		processor.fetcher = fetch.New(fetch.Config{
+			ConnectTimeout:       fetchConfig.ConnectTimeout,
+			ReadTimeout:          fetchConfig.ReadTimeout,
+			Timeout:              fetchConfig.Timeout,
+			MaxSize:              fetchConfig.MaxSize,
+			AllowedContentTypes:  contentTypes,
+			Retries:              fetchConfig.Retries,
+			RetryBackoff:         fetchConfig.RetryBackoff,
+			AllowPrivateNetworks: fetchConfig.AllowPrivateNetworks,
+		})
+	}
+	return processor
 }
 
 // GenerateFromHTML generates a PDF from HTML content
@@ -75,9 +114,40 @@ func (p *PlaywrightPDFProcessor) GenerateFromHTML(ctx context.Context, html io.R
 		return nil, fmt.Errorf("failed to open generated PDF: %w", err)
 	}
 
+	if err := enforceOutputLimit(pdfFile, p.limits, "pdf"); err != nil {
+		return nil, err
+	}
+
 	return pdfFile, nil
 }
 
+// generateFromFetchedURL downloads the page within the fetch limits, then
+// has Playwright navigate to its URL. The browser is served the downloaded
+// copy for the page itself and fetches everything else the page loads
+// under the same limits, so the page keeps its own origin and never gains
+// access to local files.
+func (p *PlaywrightPDFProcessor) generateFromFetchedURL(ctx context.Context, url string, options *pdfgen.GenerationOptions) (*pdfgen.GenerationResult, error) {
+	page, err := p.fetcher.Fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(page.Path)
+
+	limits := p.fetcher.Config()
+	options.Fetch = &pdfgen.FetchLimits{
+		ConnectTimeout:       limits.ConnectTimeout,
+		ReadTimeout:          limits.ReadTimeout,
+		MaxSize:              limits.MaxSize,
+		AllowPrivateNetworks: limits.AllowPrivateNetworks,
+		Document: &pdfgen.FetchedDocument{
+			URL:         page.URL,
+			Path:        page.Path,
+			ContentType: page.ContentType,
+		},
+	}
+	return p.generator.GenerateFromURLWithPlaywright(page.URL, options)
+}
+
 // GenerateFromURL generates a PDF from a URL
 func (p *PlaywrightPDFProcessor) GenerateFromURL(ctx context.Context, url string, params map[string]interface{}) (io.Reader, error) {
 	// Prepare generation options
@@ -101,7 +171,13 @@ func (p *PlaywrightPDFProcessor) GenerateFromURL(ctx context.Context, url string
 	}
 
 	// Generate PDF from URL
-	result, err := p.generator.GenerateFromURLWithPlaywright(url, options)
+	var result *pdfgen.GenerationResult
+	var err error
+	if p.fetcher != nil {
+		result, err = p.generateFromFetchedURL(ctx, url, options)
+	} else {
+		result, err = p.generator.GenerateFromURLWithPlaywright(url, options)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate PDF from URL with Playwright: %w", err)
 	}
@@ -112,6 +188,10 @@ func (p *PlaywrightPDFProcessor) GenerateFromURL(ctx context.Context, url string
 		return nil, fmt.Errorf("failed to open generated PDF: %w", err)
 	}
 
+	if err := enforceOutputLimit(pdfFile, p.limits, "pdf"); err != nil {
+		return nil, err
+	}
+
 	return pdfFile, nil
 }
 
@@ -142,6 +222,54 @@ func (p *PlaywrightPDFProcessor) ExtractText(ctx context.Context, input io.Reade
 	return result.Text, nil
 }
 
+// GenerateThumbnail renders a single page of a PDF as a PNG fitting in a
+// size x size box. Results are cached by the hash of the PDF content.
+func (p *PlaywrightPDFProcessor) GenerateThumbnail(ctx context.Context, input io.Reader, page, size int) (io.Reader, error) {
+	// Create temporary PDF file, hashing the content on the way
+	pdfFile, err := os.CreateTemp("", "input-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp PDF file: %w", err)
+	}
+	defer os.Remove(pdfFile.Name())
+	defer pdfFile.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(pdfFile, hash), input); err != nil {
+		return nil, fmt.Errorf("failed to copy PDF content: %w", err)
+	}
+
+	var cacheKey string
+	if p.cache != nil {
+		cacheKey = p.cache.ContentKey("pdf_thumbnail", hash.Sum(nil), map[string]int{"page": page, "size": size})
+		if entry, err := p.cache.Get(cacheKey); err == nil {
+			if data, err := os.ReadFile(entry.OutputPath); err == nil {
+				return bytes.NewReader(data), nil
+			}
+		}
+	}
+
+	thumbnail, err := media.RenderPDFThumbnail(ctx, p.mutool, pdfFile.Name(), page, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render PDF thumbnail: %w", err)
+	}
+	defer os.Remove(thumbnail.Name())
+	defer thumbnail.Close()
+
+	data, err := io.ReadAll(thumbnail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF thumbnail: %w", err)
+	}
+
+	if cacheKey != "" {
+		metadata := map[string]interface{}{"page": page, "size": size}
+		if err := p.cache.Store(cacheKey, "pdf_thumbnail", thumbnail.Name(), metadata); err != nil {
+			log.Printf("Failed to cache PDF thumbnail: %v", err)
+		}
+	}
+
+	return bytes.NewReader(data), nil
+}
+
 // GetPageCount returns the number of pages in a PDF
 func (p *PlaywrightPDFProcessor) GetPageCount(ctx context.Context, input io.Reader) (int, error) {
 	// Create temporary PDF file
@@ -199,8 +327,7 @@ func (p *TesseractOCRProcessor) ProcessImage(ctx context.Context, input io.Reade
 		return "", fmt.Errorf("failed to copy image content: %w", err)
 	}
 
-	// Perform OCR (note: current API doesn't support language parameter)
-	result, err := p.processor.ProcessImage(imageFile.Name())
+	result, err := p.processor.WithLanguage(language).ProcessImage(imageFile.Name())
 	if err != nil {
 		return "", fmt.Errorf("failed to perform OCR on image: %w", err)
 	}
@@ -208,6 +335,27 @@ func (p *TesseractOCRProcessor) ProcessImage(ctx context.Context, input io.Reade
 	return result.Text, nil
 }
 
+// ProcessImageDetailed performs OCR on an image and returns every word
+// with its box and confidence
+func (p *TesseractOCRProcessor) ProcessImageDetailed(ctx context.Context, input io.Reader, language string) (*domain.OCRResult, error) {
+	imageFile, err := os.CreateTemp("", "input-*.png")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp image file: %w", err)
+	}
+	defer os.Remove(imageFile.Name())
+	defer imageFile.Close()
+
+	if _, err := io.Copy(imageFile, input); err != nil {
+		return nil, fmt.Errorf("failed to copy image content: %w", err)
+	}
+
+	result, err := p.processor.WithLanguage(language).ProcessImageDetailed(imageFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform OCR on image: %w", err)
+	}
+	return result, nil
+}
+
 // ProcessPDF performs OCR on a PDF
 func (p *TesseractOCRProcessor) ProcessPDF(ctx context.Context, input io.Reader, language string) (string, error) {
 	// Create temporary PDF file
@@ -225,7 +373,7 @@ func (p *TesseractOCRProcessor) ProcessPDF(ctx context.Context, input io.Reader,
 	}
 
 	// Perform OCR on PDF first page (note: current API expects page number)
-	result, err := p.processor.ProcessPDF(pdfFile.Name(), 1)
+	result, err := p.processor.WithLanguage(language).ProcessPDF(pdfFile.Name(), 1)
 	if err != nil {
 		return "", fmt.Errorf("failed to perform OCR on PDF: %w", err)
 	}
@@ -233,6 +381,40 @@ func (p *TesseractOCRProcessor) ProcessPDF(ctx context.Context, input io.Reader,
 	return result.Text, nil
 }
 
+// Render performs OCR on an image or, when the input starts with a PDF
+// header, on every page of a PDF
+func (p *TesseractOCRProcessor) Render(ctx context.Context, input io.Reader, language, outputFormat string) (io.Reader, error) {
+	format, err := ocr.ParseOutputFormat(outputFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	inputFile, err := os.CreateTemp("", "ocr-input-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp input file: %w", err)
+	}
+	defer os.Remove(inputFile.Name())
+	defer inputFile.Close()
+
+	if _, err := io.Copy(inputFile, input); err != nil {
+		return nil, fmt.Errorf("failed to copy input content: %w", err)
+	}
+	header := make([]byte, 5)
+	n, _ := inputFile.ReadAt(header, 0)
+
+	processor := p.processor.WithLanguage(language)
+	var output []byte
+	if bytes.Equal(header[:n], []byte("%PDF-")) {
+		output, err = processor.RenderPDF(inputFile.Name(), format)
+	} else {
+		output, err = processor.Render(inputFile.Name(), format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform OCR: %w", err)
+	}
+	return bytes.NewReader(output), nil
+}
+
 // GetSupportedLanguages returns the list of supported OCR languages
 func (p *TesseractOCRProcessor) GetSupportedLanguages() []string {
 	return []string{"eng", "tur", "fra", "deu", "spa", "ita", "por", "rus", "ara", "chi_sim", "chi_tra", "jpn", "kor"}
@@ -243,9 +425,11 @@ type MultiTextExtractor struct {
 	extractor *textextractor.TextExtractor
 }
 
-// NewMultiTextExtractor creates a new text extractor
-func NewMultiTextExtractor(externalConfig *config.ExternalConfig) ports.TextExtractor {
-	extractor := textextractor.NewTextExtractor(externalConfig)
+// NewMultiTextExtractor creates a new text extractor that OCRs scanned PDF
+// pages in auto mode
+func NewMultiTextExtractor(externalConfig *config.ExternalConfig, ocrConfig *config.OCRConfig) ports.TextExtractor {
+	extractor := textextractor.NewTextExtractor(externalConfig).
+		WithOCR(ocr.NewOCRProcessor(ocrConfig, externalConfig))
 
 	return &MultiTextExtractor{
 		extractor: extractor,
@@ -281,7 +465,7 @@ func (p *MultiTextExtractor) ExtractFromOffice(ctx context.Context, input io.Rea
 	}
 
 	// Extract text using the general ExtractFromFile method
-	result, err := p.extractor.ExtractFromFile(officeFile.Name())
+	result, err := p.extractor.WithContext(ctx).ExtractFromFile(officeFile.Name())
 	if err != nil {
 		return "", fmt.Errorf("failed to extract text from office document: %w", err)
 	}
@@ -306,14 +490,102 @@ func (p *MultiTextExtractor) ExtractFromPDF(ctx context.Context, input io.Reader
 	}
 
 	// Extract text using the general ExtractFromFile method
-	result, err := p.extractor.ExtractFromFile(pdfFile.Name())
+	result, err := p.extractor.WithContext(ctx).ExtractFromFile(pdfFile.Name())
 	if err != nil {
 		return "", fmt.Errorf("failed to extract text from PDF: %w", err)
 	}
+	// Scanned PDFs would otherwise come back as empty text
+	if result.RequiresOCR && strings.TrimSpace(result.Text) == "" {
+		return "", domain.ErrOCRRequired
+	}
 
 	return result.Text, nil
 }
 
+// ExtractPDFPages extracts the text of each PDF page separately. In auto
+// mode scanned pages are OCRed; otherwise they are flagged as requiring OCR.
+func (p *MultiTextExtractor) ExtractPDFPages(ctx context.Context, input io.Reader, mode domain.ExtractionMode) ([]domain.PageText, error) {
+	// Create temporary PDF file
+	pdfFile, err := os.CreateTemp("", "input-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp PDF file: %w", err)
+	}
+	defer os.Remove(pdfFile.Name())
+	defer pdfFile.Close()
+
+	// Copy content to temp file
+	_, err = io.Copy(pdfFile, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy PDF content: %w", err)
+	}
+
+	extractionMode, err := textextractor.ParseExtractionMode(string(mode))
+	if err != nil {
+		return nil, err
+	}
+	results, err := p.extractor.WithContext(ctx).WithMode(extractionMode).BatchExtractPDFPages(pdfFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract PDF pages: %w", err)
+	}
+
+	pages := make([]domain.PageText, 0, len(results))
+	for _, result := range results {
+		page, _ := result.Metadata["page_number"].(int)
+		source, _ := result.Metadata["text_source"].(string)
+		scanned, _ := result.Metadata["scanned"].(bool)
+		pages = append(pages, domain.PageText{
+			Page:        page,
+			Text:        result.Text,
+			Source:      source,
+			Scanned:     scanned,
+			RequiresOCR: result.RequiresOCR,
+		})
+	}
+	return pages, nil
+}
+
+// ExtractPDFOutline returns the outline (bookmark) tree of a PDF, empty when
+// the PDF has none
+func (p *MultiTextExtractor) ExtractPDFOutline(ctx context.Context, input io.Reader) ([]domain.OutlineItem, error) {
+	// Create temporary PDF file
+	pdfFile, err := os.CreateTemp("", "input-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp PDF file: %w", err)
+	}
+	defer os.Remove(pdfFile.Name())
+	defer pdfFile.Close()
+
+	// Copy content to temp file
+	_, err = io.Copy(pdfFile, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy PDF content: %w", err)
+	}
+
+	outline, err := p.extractor.WithContext(ctx).ExtractOutline(pdfFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract PDF outline: %w", err)
+	}
+	return outlineItems(outline), nil
+}
+
+// outlineItems converts an extracted outline tree to domain items
+func outlineItems(outline []*textextractor.OutlineItem) []domain.OutlineItem {
+	items := make([]domain.OutlineItem, 0, len(outline))
+	for _, entry := range outline {
+		item := domain.OutlineItem{
+			Title: entry.Title,
+			Level: entry.Level,
+			Page:  entry.Page,
+			URI:   entry.URI,
+		}
+		if len(entry.Children) > 0 {
+			item.Children = outlineItems(entry.Children)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
 // ExtractFromText extracts text from plain text files
 func (p *MultiTextExtractor) ExtractFromText(ctx context.Context, input io.Reader) (string, error) {
 	// Create temporary text file
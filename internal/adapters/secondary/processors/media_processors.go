@@ -2,20 +2,32 @@ package processors
 
 import (
 	"context"
+	"documents-worker/config"
+	"documents-worker/internal/core/domain"
 	"documents-worker/internal/core/ports"
 	"documents-worker/media"
+	"documents-worker/quota"
 	"documents-worker/types"
+	"documents-worker/utils"
 	"fmt"
 	"io"
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // VipsImageProcessor implements the ImageProcessor port using VIPS
-type VipsImageProcessor struct{}
+type VipsImageProcessor struct {
+	limits *config.LimitsConfig
+}
 
 // NewVipsImageProcessor creates a new VIPS image processor
-func NewVipsImageProcessor() ports.ImageProcessor {
-	return &VipsImageProcessor{}
+func NewVipsImageProcessor(limits *config.LimitsConfig) ports.ImageProcessor {
+	return &VipsImageProcessor{
+		limits: limits,
+	}
 }
 
 // Convert converts an image to the specified format
@@ -51,13 +63,67 @@ func (p *VipsImageProcessor) Convert(ctx context.Context, input io.Reader, outpu
 	if height, ok := params["height"].(int); ok {
 		converter.Search.Height = &height
 	}
+	if targetSize, ok := params["target_size"].(int); ok && targetSize > 0 {
+		converter.Search.TargetSize = &targetSize
+	}
+	if progressive, ok := params["progressive"].(bool); ok {
+		converter.Search.Progressive = &progressive
+	}
+	// Page selection applies to multi-page TIFF inputs
+	if page, ok := params["page"].(int); ok && page > 0 {
+		converter.Search.Page = &page
+	}
+	if allPages, ok := params["all_pages"].(bool); ok {
+		converter.Search.AllPages = allPages
+	}
+	if rotate, ok := params["rotate"].(int); ok {
+		if _, err := media.ParseRotation(strconv.Itoa(rotate)); err != nil {
+			return nil, err
+		}
+		converter.Search.Rotate = &rotate
+	}
+	if autoOrient, ok := params["auto_orient"].(bool); ok {
+		converter.Search.AutoOrient = autoOrient
+	}
+	if sharpen, ok := params["sharpen"].(float64); ok {
+		if _, err := media.ParseSharpen(strconv.FormatFloat(sharpen, 'f', -1, 64)); err != nil {
+			return nil, err
+		}
+		converter.Search.Sharpen = &sharpen
+	}
+	if effort, ok := params["compression_effort"].(int); ok {
+		converter.Search.CompressionEffort = &effort
+	}
+	if background, ok := params["background_color"].(string); ok && background != "" {
+		if _, err := media.ParseBackgroundColor(background); err != nil {
+			return nil, err
+		}
+		converter.Search.BackgroundColor = &background
+	}
+	if err := applyMetadataPolicy(converter, params); err != nil {
+		return nil, err
+	}
 
-	// Process with VIPS
-	outputFile, err := media.ExecCommand(true, inputFile.Name(), converter)
+	// Process with VIPS. Target size searches report the quality and size
+	// they settled on with the output.
+	var outputFile *os.File
+	if converter.Search.TargetSize != nil {
+		var result *media.TargetSizeResult
+		outputFile, result, err = media.ConvertToTargetSizeContext(ctx, true, inputFile.Name(), converter)
+		if err == nil {
+			domain.OutputDetailsFrom(ctx).Set(domain.OutputDetailTargetSize, result.Metadata())
+		}
+	} else {
+		outputFile, err = media.ExecCommandContext(ctx, true, inputFile.Name(), converter)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to process image with VIPS: %w", err)
 	}
 
+	if err := enforceOutputLimit(outputFile, p.limits, "image"); err != nil {
+		return nil, err
+	}
+
 	return outputFile, nil
 }
 
@@ -84,11 +150,15 @@ func (p *VipsImageProcessor) GenerateThumbnail(ctx context.Context, input io.Rea
 }
 
 // FFmpegVideoProcessor implements the VideoProcessor port using FFmpeg
-type FFmpegVideoProcessor struct{}
+type FFmpegVideoProcessor struct {
+	limits *config.LimitsConfig
+}
 
 // NewFFmpegVideoProcessor creates a new FFmpeg video processor
-func NewFFmpegVideoProcessor() ports.VideoProcessor {
-	return &FFmpegVideoProcessor{}
+func NewFFmpegVideoProcessor(limits *config.LimitsConfig) ports.VideoProcessor {
+	return &FFmpegVideoProcessor{
+		limits: limits,
+	}
 }
 
 // Convert converts a video to the specified format
@@ -124,13 +194,34 @@ func (p *FFmpegVideoProcessor) Convert(ctx context.Context, input io.Reader, out
 	if height, ok := params["height"].(int); ok {
 		converter.Search.Height = &height
 	}
+	// Animated gif and webp previews take a time window and frame rate
+	if clip, ok := params["clip"].(string); ok && clip != "" {
+		converter.Search.CutVideo = &clip
+	}
+	if fps, ok := params["fps"].(int); ok {
+		converter.Search.FPS = &fps
+	}
+	if effort, ok := params["compression_effort"].(int); ok {
+		converter.Search.CompressionEffort = &effort
+	}
+	if err := applyMetadataPolicy(converter, params); err != nil {
+		return nil, err
+	}
+	if err := applyVideoColor(converter, params); err != nil {
+		return nil, err
+	}
 
 	// Process with FFmpeg
-	outputFile, err := media.ExecCommand(false, inputFile.Name(), converter)
+	outputFile, err := media.ExecCommandContext(ctx, false, inputFile.Name(), converter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process video with FFmpeg: %w", err)
 	}
 
+	if err := enforceOutputLimit(outputFile, p.limits, "video"); err != nil {
+		return nil, err
+	}
+	recordVideoTime(ctx, inputFile.Name())
+
 	return outputFile, nil
 }
 
@@ -167,7 +258,7 @@ func (p *FFmpegVideoProcessor) GenerateThumbnail(ctx context.Context, input io.R
 	}
 
 	// Process with FFmpeg
-	outputFile, err := media.ExecCommand(false, inputFile.Name(), converter)
+	outputFile, err := media.ExecCommandContext(ctx, false, inputFile.Name(), converter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate video thumbnail with FFmpeg: %w", err)
 	}
@@ -186,6 +277,92 @@ func (p *FFmpegVideoProcessor) Compress(ctx context.Context, input io.Reader, qu
 }
 
 // Helper functions
+
+// recordVideoTime charges the length of a processed video to the request's
+// quota meter. Without a meter the video is not probed at all.
+func recordVideoTime(ctx context.Context, inputPath string) {
+	meter := quota.MeterFrom(ctx)
+	if meter == nil {
+		return
+	}
+	seconds, err := media.ProbeDuration(inputPath)
+	if err != nil {
+		log.Printf("Failed to read video duration for quota: %v", err)
+		return
+	}
+	meter.AddVideo(time.Duration(seconds * float64(time.Second)))
+}
+
+// enforceOutputLimit rejects outputs above the configured limit, removing
+// the oversized file so it is never cached or returned
+func enforceOutputLimit(outputFile *os.File, limits *config.LimitsConfig, operation string) error {
+	if limits == nil {
+		return nil
+	}
+
+	if err := utils.CheckOutputSize(outputFile.Name(), operation, limits.OutputLimit(operation)); err != nil {
+		outputFile.Close()
+		os.Remove(outputFile.Name())
+		return err
+	}
+	return nil
+}
+
+// applyMetadataPolicy reads the metadata_policy and metadata_tags params.
+// Tags may be a []string, []interface{} or a comma separated string.
+func applyMetadataPolicy(converter *types.MediaConverter, params map[string]interface{}) error {
+	mode, _ := params["metadata_policy"].(string)
+	if mode == "" {
+		return nil
+	}
+
+	var tags []string
+	switch v := params["metadata_tags"].(type) {
+	case []string:
+		tags = v
+	case []interface{}:
+		for _, tag := range v {
+			if s, ok := tag.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+	case string:
+		tags = strings.Split(v, ",")
+	}
+
+	policy, err := media.ParseMetadataPolicy(mode, tags)
+	if err != nil {
+		return err
+	}
+	converter.Search.Metadata = policy
+	return nil
+}
+
+// applyVideoColor reads the color_primaries, color_trc, colorspace and gamma
+// params. Without them the input's color information is passed through.
+func applyVideoColor(converter *types.MediaConverter, params map[string]interface{}) error {
+	primaries, _ := params["color_primaries"].(string)
+	transfer, _ := params["color_trc"].(string)
+	matrix, _ := params["colorspace"].(string)
+
+	var gamma string
+	switch v := params["gamma"].(type) {
+	case float64:
+		gamma = strconv.FormatFloat(v, 'g', -1, 64)
+	case int:
+		gamma = strconv.Itoa(v)
+	case string:
+		gamma = v
+	}
+
+	color, err := media.ParseVideoColor(primaries, transfer, matrix, gamma)
+	if err != nil {
+		return err
+	}
+	converter.Search.VideoColor = color
+	return nil
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -5,6 +5,8 @@ import (
 	"documents-worker/internal/core/domain"
 	"documents-worker/internal/core/ports"
 	"documents-worker/queue"
+	"errors"
+	"log"
 	"time"
 )
 
@@ -20,13 +22,41 @@ func NewQueueAdapter(redisQueue *queue.RedisQueue) ports.Queue {
 	}
 }
 
+// JobRecordListener returns a queue status listener that writes every
+// status change to the stored job record, so records are current without
+// anyone reading them. Jobs the repository does not hold, such as those
+// submitted straight to the queue, are skipped.
+func JobRecordListener(jobRepo ports.JobRepository) queue.StatusListener {
+	return func(ctx context.Context, job *queue.Job) {
+		stored, err := jobRepo.GetByID(ctx, job.ID)
+		if err != nil {
+			if !errors.Is(err, domain.ErrJobNotFound) {
+				log.Printf("Failed to read stored job %s: %v", job.ID, err)
+			}
+			return
+		}
+
+		stored.Status = job.Status
+		stored.Result = job.Result
+		stored.Error = job.Error
+		stored.RetryCount = job.RetryCount
+		stored.StartedAt = job.StartedAt
+		stored.CompletedAt = job.CompletedAt
+		if err := jobRepo.Update(ctx, stored); err != nil {
+			log.Printf("Failed to update stored job %s: %v", job.ID, err)
+		}
+	}
+}
+
 func (q *QueueAdapter) Enqueue(ctx context.Context, job *domain.ProcessingJob) error {
 	// Convert domain job to queue format
 	queueJob := &queue.Job{
 		ID:         job.ID,
 		Type:       string(job.Type),
 		Status:     queue.JobStatus(job.Status),
+		Priority:   job.Priority,
 		Payload:    job.Parameters, // Use Parameters as Payload
+		Metadata:   job.Metadata,
 		CreatedAt:  job.CreatedAt,
 		RetryCount: job.RetryCount,
 	}
@@ -40,6 +70,34 @@ func (q *QueueAdapter) Dequeue(ctx context.Context) (*domain.ProcessingJob, erro
 	return nil, nil
 }
 
+func (q *QueueAdapter) GetJob(ctx context.Context, jobID string) (*domain.ProcessingJob, error) {
+	job, err := q.redisQueue.GetJobStatus(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ProcessingJob{
+		ID:                   job.ID,
+		Type:                 domain.ProcessingType(job.Type),
+		Status:               domain.JobStatus(job.Status),
+		Priority:             job.Priority,
+		Parameters:           job.Payload,
+		Metadata:             job.Metadata,
+		Result:               job.Result,
+		Error:                job.Error,
+		RetryCount:           job.RetryCount,
+		CreatedAt:            job.CreatedAt,
+		StartedAt:            job.StartedAt,
+		CompletedAt:          job.CompletedAt,
+		QueuePosition:        job.QueuePosition,
+		EstimatedWaitSeconds: job.EstimatedWaitSeconds,
+	}, nil
+}
+
+func (q *QueueAdapter) WaitForJob(ctx context.Context, jobID string) error {
+	return q.redisQueue.WaitForJob(ctx, jobID)
+}
+
 func (q *QueueAdapter) Complete(ctx context.Context, jobID string, result map[string]interface{}) error {
 	// Update job status to completed with result
 	// This would need to be implemented based on your existing queue structure
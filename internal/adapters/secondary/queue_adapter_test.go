@@ -0,0 +1,69 @@
+package adapters
+
+import (
+	"context"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
+	"documents-worker/queue"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryJobRepository keeps jobs in a map
+type memoryJobRepository struct {
+	ports.JobRepository
+	jobs map[string]domain.ProcessingJob
+}
+
+func (r *memoryJobRepository) GetByID(ctx context.Context, id string) (*domain.ProcessingJob, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, domain.ErrJobNotFound
+	}
+	return &job, nil
+}
+
+func (r *memoryJobRepository) Update(ctx context.Context, job *domain.ProcessingJob) error {
+	if _, ok := r.jobs[job.ID]; !ok {
+		return domain.ErrJobNotFound
+	}
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func TestJobRecordListenerFollowsStatusChanges(t *testing.T) {
+	repo := &memoryJobRepository{jobs: map[string]domain.ProcessingJob{
+		"job-1": {ID: "job-1", DocumentID: "doc-1", Type: domain.ProcessingTypeOCR, Status: domain.JobStatusPending},
+		"job-2": {ID: "job-2", DocumentID: "doc-1", Type: domain.ProcessingTypeOCR, Status: domain.JobStatusPending},
+	}}
+	listener := JobRecordListener(repo)
+	ctx := context.Background()
+	started := time.Now()
+
+	listener(ctx, &queue.Job{ID: "job-1", Status: queue.StatusProcessing, StartedAt: &started})
+	assert.Equal(t, domain.JobStatusProcessing, repo.jobs["job-1"].Status)
+	assert.Equal(t, "doc-1", repo.jobs["job-1"].DocumentID, "the record keeps what the queue does not hold")
+
+	completed := started.Add(time.Second)
+	listener(ctx, &queue.Job{ID: "job-1", Status: queue.StatusCompleted, StartedAt: &started, CompletedAt: &completed,
+		Result: map[string]interface{}{"text": "hello"}})
+	stored := repo.jobs["job-1"]
+	assert.Equal(t, domain.JobStatusCompleted, stored.Status)
+	assert.Equal(t, "hello", stored.Result["text"])
+	require.NotNil(t, stored.CompletedAt)
+	assert.True(t, completed.Equal(*stored.CompletedAt))
+
+	listener(ctx, &queue.Job{ID: "job-2", Status: queue.StatusRetrying, RetryCount: 1, Error: "boom"})
+	listener(ctx, &queue.Job{ID: "job-2", Status: queue.StatusFailed, RetryCount: 2, Error: "boom again"})
+	stored = repo.jobs["job-2"]
+	assert.Equal(t, domain.JobStatusFailed, stored.Status)
+	assert.Equal(t, 2, stored.RetryCount)
+	assert.Equal(t, "boom again", stored.Error)
+
+	// Jobs submitted straight to the queue have no record
+	listener(ctx, &queue.Job{ID: "job-3", Status: queue.StatusProcessing})
+	assert.NotContains(t, repo.jobs, "job-3")
+}
@@ -0,0 +1,273 @@
+// Package repository persists domain records in Redis
+package repository
+
+import (
+	"context"
+	"documents-worker/config"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// maxUpdateAttempts bounds the retries of an update racing another
+	// writer
+	maxUpdateAttempts = 3
+	// listPageSize is the number of jobs read from an index at once
+	listPageSize = 100
+)
+
+// RedisJobRepository keeps job records in Redis hashes, indexed by status
+// and by document in sorted sets ordered by creation time. Records outlive
+// the queued jobs so finished jobs stay queryable for the retention period.
+//
+// Every key shares the hash tag of the queue name, so the multi-key
+// transactions are safe on cluster clients.
+type RedisJobRepository struct {
+	client    redis.UniversalClient
+	prefix    string
+	retention time.Duration
+}
+
+var _ ports.JobRepository = (*RedisJobRepository)(nil)
+
+// NewRedisJobRepository creates a repository on a shared Redis client
+func NewRedisJobRepository(client redis.UniversalClient, workerConfig *config.WorkerConfig) *RedisJobRepository {
+	retention := workerConfig.JobRetention
+	if retention <= 0 {
+		retention = 7 * 24 * time.Hour
+	}
+	return &RedisJobRepository{
+		client:    client,
+		prefix:    "{" + workerConfig.QueueName + ":jobs}",
+		retention: retention,
+	}
+}
+
+func (r *RedisJobRepository) jobKey(id string) string {
+	return r.prefix + ":job:" + id
+}
+
+func (r *RedisJobRepository) statusKey(status domain.JobStatus) string {
+	return r.prefix + ":status:" + string(status)
+}
+
+func (r *RedisJobRepository) documentKey(documentID string) string {
+	return r.prefix + ":document:" + documentID
+}
+
+// Save stores a new job. Saving an ID that exists fails.
+func (r *RedisJobRepository) Save(ctx context.Context, job *domain.ProcessingJob) error {
+	if !job.Status.IsValid() {
+		return fmt.Errorf("cannot save job %s: unknown status %q", job.ID, job.Status)
+	}
+	data, err := marshalJob(job)
+	if err != nil {
+		return err
+	}
+
+	key := r.jobKey(job.ID)
+	created, err := r.client.HSetNX(ctx, key, "job", data).Result()
+	if err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	if !created {
+		return fmt.Errorf("job %s already exists", job.ID)
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		r.index(ctx, pipe, job)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to index job: %w", err)
+	}
+	return nil
+}
+
+// Update replaces a stored job. Records follow the queue, which may pass
+// through statuses between two updates, so any change is accepted except
+// the status of a finished job, which fails wrapping
+// domain.ErrInvalidTransition.
+func (r *RedisJobRepository) Update(ctx context.Context, job *domain.ProcessingJob) error {
+	data, err := marshalJob(job)
+	if err != nil {
+		return err
+	}
+	key := r.jobKey(job.ID)
+
+	update := func(tx *redis.Tx) error {
+		stored, err := r.read(ctx, tx, job.ID)
+		if err != nil {
+			return err
+		}
+		if stored.Status != job.Status && (stored.Status.IsTerminal() || !job.Status.IsValid()) {
+			return fmt.Errorf("cannot update job %s: %w: %q to %q", job.ID, domain.ErrInvalidTransition, stored.Status, job.Status)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, "job", data)
+			if stored.Status != job.Status {
+				pipe.ZRem(ctx, r.statusKey(stored.Status), job.ID)
+			}
+			r.index(ctx, pipe, job)
+			return nil
+		})
+		return err
+	}
+
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		err = r.client.Watch(ctx, update, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+	if err != nil && !errors.Is(err, domain.ErrJobNotFound) && !errors.Is(err, domain.ErrInvalidTransition) {
+		return fmt.Errorf("failed to update job: %w", err)
+	}
+	return err
+}
+
+// index adds the job to its status and document indexes and renews the
+// retention of all three keys
+func (r *RedisJobRepository) index(ctx context.Context, pipe redis.Pipeliner, job *domain.ProcessingJob) {
+	member := redis.Z{Score: float64(job.CreatedAt.UnixNano()), Member: job.ID}
+	pipe.HSet(ctx, r.jobKey(job.ID), "status", string(job.Status), "document_id", job.DocumentID)
+	pipe.Expire(ctx, r.jobKey(job.ID), r.retention)
+	pipe.ZAdd(ctx, r.statusKey(job.Status), member)
+	pipe.Expire(ctx, r.statusKey(job.Status), r.retention)
+	if job.DocumentID != "" {
+		pipe.ZAdd(ctx, r.documentKey(job.DocumentID), member)
+		pipe.Expire(ctx, r.documentKey(job.DocumentID), r.retention)
+	}
+}
+
+// GetByID returns the job or an error wrapping domain.ErrJobNotFound
+func (r *RedisJobRepository) GetByID(ctx context.Context, id string) (*domain.ProcessingJob, error) {
+	return r.read(ctx, r.client, id)
+}
+
+func (r *RedisJobRepository) read(ctx context.Context, client redis.Cmdable, id string) (*domain.ProcessingJob, error) {
+	data, err := client.HGet(ctx, r.jobKey(id), "job").Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("job %s: %w", id, domain.ErrJobNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+
+	var job domain.ProcessingJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+	return &job, nil
+}
+
+// GetByDocumentID returns the jobs of a document, oldest first
+func (r *RedisJobRepository) GetByDocumentID(ctx context.Context, documentID string) ([]*domain.ProcessingJob, error) {
+	return r.list(ctx, r.documentKey(documentID), 0)
+}
+
+// ListPending returns up to limit pending jobs, oldest first
+func (r *RedisJobRepository) ListPending(ctx context.Context, limit int) ([]*domain.ProcessingJob, error) {
+	return r.ListByStatus(ctx, domain.JobStatusPending, limit)
+}
+
+// ListByStatus returns up to limit jobs with status, oldest first. A
+// non-positive limit returns all of them.
+func (r *RedisJobRepository) ListByStatus(ctx context.Context, status domain.JobStatus, limit int) ([]*domain.ProcessingJob, error) {
+	return r.list(ctx, r.statusKey(status), limit)
+}
+
+// list reads the jobs of an index a page at a time. IDs of expired jobs
+// are pruned from it.
+func (r *RedisJobRepository) list(ctx context.Context, index string, limit int) ([]*domain.ProcessingJob, error) {
+	jobs := make([]*domain.ProcessingJob, 0)
+	var expired []interface{}
+	for offset := int64(0); limit <= 0 || len(jobs) < limit; offset += listPageSize {
+		ids, err := r.client.ZRange(ctx, index, offset, offset+listPageSize-1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list jobs: %w", err)
+		}
+
+		cmds := make([]*redis.StringCmd, len(ids))
+		_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, id := range ids {
+				cmds[i] = pipe.HGet(ctx, r.jobKey(id), "job")
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("failed to list jobs: %w", err)
+		}
+
+		for i, cmd := range cmds {
+			data, err := cmd.Bytes()
+			if errors.Is(err, redis.Nil) {
+				expired = append(expired, ids[i])
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get job: %w", err)
+			}
+			var job domain.ProcessingJob
+			if err := json.Unmarshal(data, &job); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+			}
+			if limit <= 0 || len(jobs) < limit {
+				jobs = append(jobs, &job)
+			}
+		}
+		if len(ids) < listPageSize {
+			break
+		}
+	}
+
+	if len(expired) > 0 {
+		r.client.ZRem(ctx, index, expired...)
+	}
+	return jobs, nil
+}
+
+// Delete removes a job and its index entries. Deleting a missing job
+// succeeds.
+func (r *RedisJobRepository) Delete(ctx context.Context, id string) error {
+	job, err := r.GetByID(ctx, id)
+	if errors.Is(err, domain.ErrJobNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.jobKey(id))
+		pipe.ZRem(ctx, r.statusKey(job.Status), id)
+		if job.DocumentID != "" {
+			pipe.ZRem(ctx, r.documentKey(job.DocumentID), id)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete job: %w", err)
+	}
+	return nil
+}
+
+// marshalJob encodes the job without the queue state, which is never
+// stored
+func marshalJob(job *domain.ProcessingJob) ([]byte, error) {
+	stored := *job
+	stored.QueuePosition = nil
+	stored.EstimatedWaitSeconds = nil
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"documents-worker/config"
+	"documents-worker/internal/core/domain"
+	"documents-worker/redisclient"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepository(t *testing.T) *RedisJobRepository {
+	client, err := redisclient.New(&config.RedisConfig{Host: "localhost", Port: "6379", DB: 6})
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	client.FlushDB(context.Background())
+
+	return NewRedisJobRepository(client, &config.WorkerConfig{QueueName: "test_job_repository", JobRetention: time.Hour})
+}
+
+func TestJobLifecycle(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	job := &domain.ProcessingJob{
+		ID:         "job-1",
+		DocumentID: "doc-1",
+		Type:       domain.ProcessingTypeOCR,
+		Status:     domain.JobStatusPending,
+		CreatedAt:  time.Now(),
+	}
+	require.NoError(t, repo.Save(ctx, job))
+	assert.Error(t, repo.Save(ctx, job), "saving an existing job fails")
+
+	job.Status = domain.JobStatusProcessing
+	require.NoError(t, repo.Update(ctx, job))
+
+	job.Status = domain.JobStatusCompleted
+	job.Result = map[string]interface{}{"text": "hello"}
+	require.NoError(t, repo.Update(ctx, job))
+
+	stored, err := repo.GetByID(ctx, "job-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.JobStatusCompleted, stored.Status)
+	assert.Equal(t, "hello", stored.Result["text"])
+
+	job.Status = domain.JobStatusProcessing
+	assert.ErrorIs(t, repo.Update(ctx, job), domain.ErrInvalidTransition)
+
+	processing, err := repo.ListByStatus(ctx, domain.JobStatusProcessing, 0)
+	require.NoError(t, err)
+	assert.Empty(t, processing)
+
+	require.NoError(t, repo.Delete(ctx, "job-1"))
+	_, err = repo.GetByID(ctx, "job-1")
+	assert.ErrorIs(t, err, domain.ErrJobNotFound)
+	assert.ErrorIs(t, repo.Update(ctx, job), domain.ErrJobNotFound)
+	assert.NoError(t, repo.Delete(ctx, "job-1"))
+}
+
+func TestFailedJobIsFinal(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	job := &domain.ProcessingJob{ID: "job-1", Status: domain.JobStatusPending, CreatedAt: time.Now()}
+	require.NoError(t, repo.Save(ctx, job))
+
+	job.Status = domain.JobStatusFailed
+	job.Error = "tesseract not found"
+	require.NoError(t, repo.Update(ctx, job))
+
+	job.Status = domain.JobStatusPending
+	assert.ErrorIs(t, repo.Update(ctx, job), domain.ErrInvalidTransition)
+
+	failed, err := repo.ListByStatus(ctx, domain.JobStatusFailed, 10)
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.Equal(t, "tesseract not found", failed[0].Error)
+}
+
+func TestListJobs(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	created := time.Now()
+	for i, id := range []string{"a", "b", "c"} {
+		require.NoError(t, repo.Save(ctx, &domain.ProcessingJob{
+			ID:         id,
+			DocumentID: "doc-1",
+			Status:     domain.JobStatusPending,
+			CreatedAt:  created.Add(time.Duration(i) * time.Second),
+		}))
+	}
+	require.NoError(t, repo.Save(ctx, &domain.ProcessingJob{ID: "d", DocumentID: "doc-2", Status: domain.JobStatusPending, CreatedAt: created}))
+
+	pending, err := repo.ListPending(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+
+	// Expired records are skipped and pruned from the indexes
+	repo.client.Del(ctx, repo.jobKey("b"))
+	jobs, err := repo.GetByDocumentID(ctx, "doc-1")
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "a", jobs[0].ID)
+	assert.Equal(t, "c", jobs[1].ID)
+	assert.Equal(t, int64(2), repo.client.ZCard(ctx, repo.documentKey("doc-1")).Val())
+}
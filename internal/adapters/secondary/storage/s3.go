@@ -0,0 +1,248 @@
+// Package storage persists processing outputs in object storage
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"documents-worker/config"
+	"documents-worker/internal/core/ports"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrObjectNotFound is returned for keys that do not exist in the bucket
+var ErrObjectNotFound = errors.New("object not found")
+
+// S3Storage stores files in an S3-compatible bucket such as AWS S3 or
+// MinIO. Requests are signed with Signature Version 4.
+type S3Storage struct {
+	endpoint      *url.URL
+	bucket        string
+	prefix        string
+	pathStyle     bool
+	presignExpiry time.Duration
+	signer        *signer
+	client        *http.Client
+	now           func() time.Time
+}
+
+var _ ports.FileStorage = (*S3Storage)(nil)
+
+// NewS3Storage creates a storage for the configured bucket. Without an
+// endpoint the regional AWS endpoint is used.
+func NewS3Storage(cfg *config.StorageConfig) (*S3Storage, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("storage bucket is required")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("storage credentials are required")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid storage endpoint: %s", endpoint)
+	}
+	presignExpiry := cfg.PresignExpiry
+	if presignExpiry <= 0 {
+		presignExpiry = 15 * time.Minute
+	}
+
+	return &S3Storage{
+		endpoint:      parsed,
+		bucket:        cfg.Bucket,
+		prefix:        strings.Trim(cfg.Prefix, "/"),
+		pathStyle:     cfg.PathStyle,
+		presignExpiry: presignExpiry,
+		signer:        &signer{accessKey: cfg.AccessKeyID, secretKey: cfg.SecretAccessKey, region: region},
+		client:        &http.Client{Timeout: cfg.Timeout},
+		now:           time.Now,
+	}, nil
+}
+
+// objectURL returns the URL of the object at key, path style or virtual
+// hosted
+func (s *S3Storage) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	objectPath := "/" + s.objectKey(key)
+	if s.pathStyle {
+		objectPath = "/" + s.bucket + objectPath
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = strings.TrimSuffix(s.endpoint.Path, "/") + objectPath
+	u.RawPath = canonicalURI(&u)
+	return &u
+}
+
+// objectKey places key under the configured prefix. Keys are cleaned so
+// they cannot climb out of it.
+func (s *S3Storage) objectKey(key string) string {
+	key = strings.TrimPrefix(path.Clean("/"+key), "/")
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+// do signs and sends a request for the object at key
+func (s *S3Storage) do(ctx context.Context, method, key string, body io.Reader, size int64, payloadHash string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.signer.sign(req, payloadHash, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// Store uploads data to key. The data is spooled to a temp file first,
+// since S3 needs its length and hash before the upload.
+func (s *S3Storage) Store(ctx context.Context, key string, data io.Reader) error {
+	spool, err := os.CreateTemp("", "storage-upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(spool, hash), data)
+	if err != nil {
+		return fmt.Errorf("failed to read data for %s: %w", key, err)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind temp file: %w", err)
+	}
+
+	contentType := mime.TypeByExtension(strings.ToLower(path.Ext(key)))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := http.Header{"Content-Type": {contentType}}
+
+	resp, err := s.do(ctx, http.MethodPut, key, spool, size, hex.EncodeToString(hash.Sum(nil)), header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp, "store", key)
+	}
+	return nil
+}
+
+// Retrieve streams the object at key. The returned reader is an
+// io.ReadCloser the caller must close.
+func (s *S3Storage) Retrieve(ctx context.Context, key string) (io.Reader, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, responseError(resp, "retrieve", key)
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object at key. Deleting a missing object succeeds.
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return responseError(resp, "delete", key)
+	}
+	return nil
+}
+
+// Exists reports whether an object is stored at key
+func (s *S3Storage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.GetMetadata(ctx, key)
+	if errors.Is(err, ErrObjectNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetMetadata returns the size, content type, ETag and modification time
+// of the object at key
+func (s *S3Storage) GetMetadata(ctx context.Context, key string) (map[string]interface{}, error) {
+	resp, err := s.do(ctx, http.MethodHead, key, nil, 0, emptyPayloadHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp, "read metadata of", key)
+	}
+
+	metadata := map[string]interface{}{
+		"size":         resp.ContentLength,
+		"content_type": resp.Header.Get("Content-Type"),
+		"etag":         strings.Trim(resp.Header.Get("ETag"), `"`),
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		metadata["last_modified"] = modified
+	}
+	return metadata, nil
+}
+
+// URL returns a presigned URL that downloads the object at key without
+// credentials until it expires. A non-positive expiry uses the configured
+// one.
+func (s *S3Storage) URL(key string, expiry time.Duration) string {
+	if expiry <= 0 {
+		expiry = s.presignExpiry
+	}
+	return s.signer.presign(http.MethodGet, s.objectURL(key), expiry, s.now()).String()
+}
+
+// s3Error is the error document S3 returns
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// responseError describes a failed request, reading S3's error code when
+// the response has one
+func responseError(resp *http.Response, action, key string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("failed to %s %s: %w", action, key, ErrObjectNotFound)
+	}
+	var body s3Error
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(data, &body) == nil && body.Code != "" {
+		return fmt.Errorf("failed to %s %s: %s: %s", action, key, body.Code, body.Message)
+	}
+	return fmt.Errorf("failed to %s %s: status %d", action, key, resp.StatusCode)
+}
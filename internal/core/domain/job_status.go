@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidTransition is wrapped when a job status change is not allowed
+var ErrInvalidTransition = errors.New("invalid job status transition")
+
+// jobTransitions lists the statuses each status may change to. Completed,
+// failed and canceled jobs are final.
+var jobTransitions = map[JobStatus][]JobStatus{
+	JobStatusPending:    {JobStatusProcessing, JobStatusFailed, JobStatusCanceled},
+	JobStatusProcessing: {JobStatusCompleted, JobStatusFailed, JobStatusRetrying, JobStatusCanceled},
+	JobStatusRetrying:   {JobStatusPending, JobStatusFailed, JobStatusCanceled},
+	JobStatusCompleted:  nil,
+	JobStatusFailed:     nil,
+	JobStatusCanceled:   nil,
+}
+
+// IsValid reports whether s is a known job status
+func (s JobStatus) IsValid() bool {
+	_, ok := jobTransitions[s]
+	return ok
+}
+
+// IsTerminal reports whether a job with status s is finished and will not
+// change again
+func (s JobStatus) IsTerminal() bool {
+	return s == JobStatusCompleted || s == JobStatusFailed || s == JobStatusCanceled
+}
+
+// IsSuccessful reports whether the job finished with a result
+func (s JobStatus) IsSuccessful() bool {
+	return s == JobStatusCompleted
+}
+
+// CanTransitionTo reports whether a job may move from s to next
+func (s JobStatus) CanTransitionTo(next JobStatus) bool {
+	for _, allowed := range jobTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition returns next when a job may move from s to it, or an error
+// wrapping ErrInvalidTransition
+func (s JobStatus) Transition(next JobStatus) (JobStatus, error) {
+	if !s.CanTransitionTo(next) {
+		return s, fmt.Errorf("%w: %q to %q", ErrInvalidTransition, s, next)
+	}
+	return next, nil
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobStatusValidTransitions(t *testing.T) {
+	valid := []struct{ from, to JobStatus }{
+		{JobStatusPending, JobStatusProcessing},
+		{JobStatusPending, JobStatusFailed},
+		{JobStatusPending, JobStatusCanceled},
+		{JobStatusProcessing, JobStatusCompleted},
+		{JobStatusProcessing, JobStatusFailed},
+		{JobStatusProcessing, JobStatusRetrying},
+		{JobStatusProcessing, JobStatusCanceled},
+		{JobStatusRetrying, JobStatusPending},
+		{JobStatusRetrying, JobStatusFailed},
+		{JobStatusRetrying, JobStatusCanceled},
+	}
+	for _, tc := range valid {
+		next, err := tc.from.Transition(tc.to)
+		require.NoError(t, err, "%s -> %s", tc.from, tc.to)
+		assert.Equal(t, tc.to, next)
+	}
+}
+
+func TestJobStatusInvalidTransitions(t *testing.T) {
+	invalid := []struct{ from, to JobStatus }{
+		{JobStatusPending, JobStatusCompleted},
+		{JobStatusPending, JobStatusPending},
+		{JobStatusProcessing, JobStatusPending},
+		{JobStatusRetrying, JobStatusCompleted},
+		{JobStatusCompleted, JobStatusFailed},
+		{JobStatusCompleted, JobStatusProcessing},
+		{JobStatusFailed, JobStatusPending},
+		{JobStatusCanceled, JobStatusProcessing},
+		{JobStatus("success"), JobStatusCompleted},
+		{JobStatusPending, JobStatus("success")},
+	}
+	for _, tc := range invalid {
+		next, err := tc.from.Transition(tc.to)
+		assert.ErrorIs(t, err, ErrInvalidTransition, "%s -> %s", tc.from, tc.to)
+		assert.Equal(t, tc.from, next)
+	}
+}
+
+func TestJobStatusPredicates(t *testing.T) {
+	for _, status := range []JobStatus{JobStatusCompleted, JobStatusFailed, JobStatusCanceled} {
+		assert.True(t, status.IsTerminal(), status)
+		assert.True(t, status.IsValid(), status)
+	}
+	for _, status := range []JobStatus{JobStatusPending, JobStatusProcessing, JobStatusRetrying} {
+		assert.False(t, status.IsTerminal(), status)
+		assert.True(t, status.IsValid(), status)
+	}
+
+	assert.True(t, JobStatusCompleted.IsSuccessful())
+	assert.False(t, JobStatusFailed.IsSuccessful())
+	assert.False(t, JobStatus("success").IsValid())
+	assert.False(t, JobStatus("").IsValid())
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Limits of the caller metadata attached to a job. Metadata is echoed in
+// job statuses and events so callers can correlate jobs with their own
+// records, such as a CMS asset ID.
+const (
+	MaxMetadataEntries  = 20
+	MaxMetadataKeyLen   = 64
+	MaxMetadataValueLen = 256
+)
+
+// metadataKey is the form of a metadata key
+var metadataKey = regexp.MustCompile(`^[A-Za-z0-9_.:-]+$`)
+
+// ValidMetadataKey reports whether key may be used as a metadata key
+func ValidMetadataKey(key string) bool {
+	return len(key) <= MaxMetadataKeyLen && metadataKey.MatchString(key)
+}
+
+// CleanMetadata returns a copy of metadata with surrounding space trimmed
+// from keys and values and control characters removed from values. It
+// returns nil for empty metadata.
+func CleanMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	cleaned := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		value = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, value)
+		cleaned[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return cleaned
+}
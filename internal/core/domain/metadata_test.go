@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanMetadata(t *testing.T) {
+	assert.Nil(t, CleanMetadata(nil))
+	assert.Equal(t, map[string]string{
+		"asset_id": "cms-42",
+		"note":     "line one two",
+	}, CleanMetadata(map[string]string{
+		" asset_id ": " cms-42\n",
+		"note":       "line one\x00 two",
+	}))
+}
+
+func TestValidMetadataKey(t *testing.T) {
+	for _, key := range []string{"asset_id", "cms.asset-id", "tenant:42"} {
+		assert.True(t, ValidMetadataKey(key), key)
+	}
+	for _, key := range []string{"", "asset id", "path/to", "ключ", strings.Repeat("k", MaxMetadataKeyLen+1)} {
+		assert.False(t, ValidMetadataKey(key), key)
+	}
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"io"
 	"time"
 )
 
@@ -47,13 +48,52 @@ type ProcessingJob struct {
 	DocumentID  string                 `json:"document_id"`
 	Type        ProcessingType         `json:"type"`
 	Status      JobStatus              `json:"status"`
+	Priority    int                    `json:"priority,omitempty"`
 	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Metadata    map[string]string      `json:"metadata,omitempty"`
 	Result      map[string]interface{} `json:"result,omitempty"`
 	Error       string                 `json:"error,omitempty"`
 	RetryCount  int                    `json:"retry_count"`
 	CreatedAt   time.Time              `json:"created_at"`
 	StartedAt   *time.Time             `json:"started_at,omitempty"`
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+
+	// Set for pending jobs: jobs ahead in the queue and the expected wait
+	// based on recent processing durations
+	QueuePosition        *int     `json:"queue_position,omitempty"`
+	EstimatedWaitSeconds *float64 `json:"estimated_wait_seconds,omitempty"`
+}
+
+// ResultStorageKey is the job result entry holding the file storage key of
+// the job's output
+const ResultStorageKey = "storage_key"
+
+// JobOutput is the stored output of a completed job: a presigned URL when
+// the storage issues them, the content otherwise
+type JobOutput struct {
+	Key string
+	URL string
+	// Content is an io.ReadCloser when the storage returns one
+	Content io.Reader
+}
+
+// SecretParameters are job parameters the worker needs but that must never
+// be returned to clients, such as the password of an encrypted PDF
+var SecretParameters = []string{"password"}
+
+// Redacted returns a shallow copy of the job without secret parameters
+func (j *ProcessingJob) Redacted() *ProcessingJob {
+	redacted := *j
+	if j.Parameters != nil {
+		redacted.Parameters = make(map[string]interface{}, len(j.Parameters))
+		for key, value := range j.Parameters {
+			redacted.Parameters[key] = value
+		}
+		for _, key := range SecretParameters {
+			delete(redacted.Parameters, key)
+		}
+	}
+	return &redacted
 }
 
 // ProcessingType represents the type of processing
@@ -68,7 +108,8 @@ const (
 	ProcessingTypeThumbnail    ProcessingType = "thumbnail"
 )
 
-// JobStatus represents the job processing status
+// JobStatus represents the job processing status. Statuses only change
+// along the transitions in job_status.go.
 type JobStatus string
 
 const (
@@ -77,6 +118,7 @@ const (
 	JobStatusCompleted  JobStatus = "completed"
 	JobStatusFailed     JobStatus = "failed"
 	JobStatusRetrying   JobStatus = "retrying"
+	JobStatusCanceled   JobStatus = "canceled"
 )
 
 // ProcessingRequest represents a request for document processing
@@ -85,6 +127,18 @@ type ProcessingRequest struct {
 	Type       ProcessingType         `json:"type"`
 	Parameters map[string]interface{} `json:"parameters,omitempty"`
 	Priority   int                    `json:"priority,omitempty"`
+	// Metadata is opaque to the worker and echoed in the job and its events
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// ProcessingOutput is a successful synchronous result handed to post-processors
+type ProcessingOutput struct {
+	Type   ProcessingType
+	Format string
+	Params map[string]interface{}
+	Size   int64
+	// Data is read from the start for every post-processor
+	Data io.Reader
 }
 
 // ProcessingResult represents the result of document processing
@@ -101,6 +155,40 @@ type ProcessingResult struct {
 	CompletedAt time.Time              `json:"completed_at"`
 }
 
+// PageText is the text extracted from a single page of a document
+type PageText struct {
+	Page int    `json:"page"`
+	Text string `json:"text"`
+	// Source is where the text came from: extraction or ocr
+	Source string `json:"source,omitempty"`
+	// Scanned is set when the page has no usable text layer
+	Scanned bool `json:"scanned,omitempty"`
+	// RequiresOCR is set for scanned pages that were not OCRed
+	RequiresOCR bool `json:"requires_ocr,omitempty"`
+}
+
+// OutlineItem is an entry of a document's outline (bookmark) tree
+type OutlineItem struct {
+	Title string `json:"title"`
+	// Level is the depth in the tree, 1 for top-level entries
+	Level int `json:"level"`
+	// Page is the 1-based target page, 0 for entries not pointing to a page
+	Page int `json:"page,omitempty"`
+	// URI is the target of entries pointing outside the document
+	URI      string        `json:"uri,omitempty"`
+	Children []OutlineItem `json:"children,omitempty"`
+}
+
+// ExtractionMode selects how text is obtained from PDF pages
+type ExtractionMode string
+
+const (
+	// ExtractionModeText uses the embedded text only and flags scanned pages
+	ExtractionModeText ExtractionMode = "text"
+	// ExtractionModeAuto falls back to OCR for scanned pages
+	ExtractionModeAuto ExtractionMode = "auto"
+)
+
 // HealthStatus represents system health status
 type HealthStatus struct {
 	Status       string                 `json:"status"`
@@ -149,7 +237,10 @@ func (e DomainError) Error() string {
 var (
 	ErrDocumentNotFound    = DomainError{Code: "DOCUMENT_NOT_FOUND", Message: "Document not found"}
 	ErrJobNotFound         = DomainError{Code: "JOB_NOT_FOUND", Message: "Job not found"}
+	ErrJobNotCompleted     = DomainError{Code: "JOB_NOT_COMPLETED", Message: "Job has not completed"}
+	ErrResultNotFound      = DomainError{Code: "RESULT_NOT_FOUND", Message: "Job has no stored result"}
 	ErrInvalidDocumentType = DomainError{Code: "INVALID_DOCUMENT_TYPE", Message: "Invalid document type"}
 	ErrProcessingFailed    = DomainError{Code: "PROCESSING_FAILED", Message: "Document processing failed"}
 	ErrUnsupportedFormat   = DomainError{Code: "UNSUPPORTED_FORMAT", Message: "Unsupported file format"}
+	ErrOCRRequired         = DomainError{Code: "OCR_REQUIRED", Message: "Document has no text layer, OCR is required"}
 )
@@ -0,0 +1,75 @@
+package domain
+
+import "strings"
+
+// OCRWord is one recognized word and where it was found. Confidence is
+// between 0 and 1.
+type OCRWord struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+	Page       int     `json:"page"`
+	Block      int     `json:"block"`
+	Paragraph  int     `json:"paragraph"`
+	Line       int     `json:"line"`
+	Left       int     `json:"left"`
+	Top        int     `json:"top"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+}
+
+// OCRResult is recognized text with the words it was built from.
+// Confidence is the mean confidence of the words.
+type OCRResult struct {
+	Text       string    `json:"text"`
+	Confidence float64   `json:"confidence"`
+	Language   string    `json:"language,omitempty"`
+	Words      []OCRWord `json:"words"`
+}
+
+// NewOCRResult builds the text and mean confidence of words
+func NewOCRResult(words []OCRWord, language string) *OCRResult {
+	result := &OCRResult{Text: JoinOCRWords(words), Language: language, Words: words}
+	if result.Words == nil {
+		result.Words = []OCRWord{}
+	}
+	for _, word := range words {
+		result.Confidence += word.Confidence
+	}
+	if len(words) > 0 {
+		result.Confidence /= float64(len(words))
+	}
+	return result
+}
+
+// Filter returns the result without the words below minConfidence
+func (r *OCRResult) Filter(minConfidence float64) *OCRResult {
+	kept := make([]OCRWord, 0, len(r.Words))
+	for _, word := range r.Words {
+		if word.Confidence >= minConfidence {
+			kept = append(kept, word)
+		}
+	}
+	return NewOCRResult(kept, r.Language)
+}
+
+// JoinOCRWords lays words out as text: words of a line are separated by
+// spaces, lines by a newline, and paragraphs, blocks and pages by a blank
+// line
+func JoinOCRWords(words []OCRWord) string {
+	var text strings.Builder
+	for i, word := range words {
+		if i > 0 {
+			previous := words[i-1]
+			switch {
+			case word.Page != previous.Page || word.Block != previous.Block || word.Paragraph != previous.Paragraph:
+				text.WriteString("\n\n")
+			case word.Line != previous.Line:
+				text.WriteString("\n")
+			default:
+				text.WriteString(" ")
+			}
+		}
+		text.WriteString(word.Text)
+	}
+	return text.String()
+}
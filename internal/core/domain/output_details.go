@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"context"
+	"maps"
+	"sync"
+)
+
+// OutputDetailTargetSize holds the outcome of a target size search: the
+// target, the achieved size and quality, and whether the target was met
+const OutputDetailTargetSize = "target_size"
+
+type outputDetailsKey struct{}
+
+// OutputDetails collects what a synchronous conversion reports about its
+// output besides the bytes, such as the quality a target size search
+// settled on. A nil OutputDetails ignores everything recorded to it.
+type OutputDetails struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+// NewOutputDetails creates empty output details
+func NewOutputDetails() *OutputDetails {
+	return &OutputDetails{values: make(map[string]interface{})}
+}
+
+// WithOutputDetails returns a context carrying details
+func WithOutputDetails(ctx context.Context, details *OutputDetails) context.Context {
+	return context.WithValue(ctx, outputDetailsKey{}, details)
+}
+
+// OutputDetailsFrom returns the details attached to ctx, or nil
+func OutputDetailsFrom(ctx context.Context) *OutputDetails {
+	details, _ := ctx.Value(outputDetailsKey{}).(*OutputDetails)
+	return details
+}
+
+// Set records a detail under key
+func (d *OutputDetails) Set(key string, value interface{}) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.values[key] = value
+}
+
+// Get returns the detail recorded under key
+func (d *OutputDetails) Get(key string) (interface{}, bool) {
+	if d == nil {
+		return nil, false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	value, ok := d.values[key]
+	return value, ok
+}
+
+// Merge records every detail of other, for callers sharing its conversion
+func (d *OutputDetails) Merge(other *OutputDetails) {
+	if d == nil || other == nil || d == other {
+		return
+	}
+	other.mu.Lock()
+	values := maps.Clone(other.values)
+	other.mu.Unlock()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	maps.Copy(d.values, values)
+}
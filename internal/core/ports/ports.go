@@ -4,6 +4,7 @@ import (
 	"context"
 	"documents-worker/internal/core/domain"
 	"io"
+	"time"
 )
 
 // Primary Ports (inbound)
@@ -14,15 +15,29 @@ type DocumentService interface {
 	ProcessDocument(ctx context.Context, req *domain.ProcessingRequest) (*domain.ProcessingResult, error)
 	GetDocument(ctx context.Context, id string) (*domain.Document, error)
 	GetJob(ctx context.Context, jobID string) (*domain.ProcessingJob, error)
+	// WaitForJob returns the job once it finishes, or as it stands after
+	// maxWait
+	WaitForJob(ctx context.Context, jobID string, maxWait time.Duration) (*domain.ProcessingJob, error)
 	GetJobsByDocument(ctx context.Context, documentID string) ([]*domain.ProcessingJob, error)
+	// GetJobResult returns the stored output of a completed job
+	GetJobResult(ctx context.Context, jobID string) (*domain.JobOutput, error)
 
 	// Processing operations
 	ConvertImage(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error)
 	ConvertVideo(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error)
 	GeneratePDF(ctx context.Context, input io.Reader, params map[string]interface{}) (io.Reader, error)
 	ExtractText(ctx context.Context, input io.Reader, docType domain.DocumentType) (string, error)
+	ExtractTextPages(ctx context.Context, input io.Reader, mode domain.ExtractionMode) ([]domain.PageText, error)
+	ExtractPDFOutline(ctx context.Context, input io.Reader) ([]domain.OutlineItem, error)
 	PerformOCR(ctx context.Context, input io.Reader, language string) (string, error)
+	// PerformOCROutput returns the OCR of an image or PDF as text, hOCR or
+	// a searchable PDF
+	PerformOCROutput(ctx context.Context, input io.Reader, language, outputFormat string) (io.Reader, error)
+	// PerformOCRDetailed returns the OCR of an image with every word's box
+	// and confidence
+	PerformOCRDetailed(ctx context.Context, input io.Reader, language string) (*domain.OCRResult, error)
 	GenerateThumbnail(ctx context.Context, input io.Reader, params map[string]interface{}) (io.Reader, error)
+	GeneratePDFThumbnail(ctx context.Context, input io.Reader, page, size int) (io.Reader, error)
 }
 
 // HealthService defines health checking operations
@@ -59,6 +74,7 @@ type JobRepository interface {
 	Update(ctx context.Context, job *domain.ProcessingJob) error
 	Delete(ctx context.Context, id string) error
 	ListPending(ctx context.Context, limit int) ([]*domain.ProcessingJob, error)
+	ListByStatus(ctx context.Context, status domain.JobStatus, limit int) ([]*domain.ProcessingJob, error)
 }
 
 // FileStorage defines file storage operations
@@ -70,10 +86,20 @@ type FileStorage interface {
 	GetMetadata(ctx context.Context, path string) (map[string]interface{}, error)
 }
 
+// URLSigner is implemented by file storages that issue presigned download
+// URLs; a non-positive expiry uses the storage's default
+type URLSigner interface {
+	URL(path string, expiry time.Duration) string
+}
+
 // Queue defines queue operations
 type Queue interface {
 	Enqueue(ctx context.Context, job *domain.ProcessingJob) error
 	Dequeue(ctx context.Context) (*domain.ProcessingJob, error)
+	GetJob(ctx context.Context, jobID string) (*domain.ProcessingJob, error)
+	// WaitForJob blocks until the job finishes or ctx is done, in which
+	// case the context error is returned
+	WaitForJob(ctx context.Context, jobID string) error
 	Complete(ctx context.Context, jobID string, result map[string]interface{}) error
 	Fail(ctx context.Context, jobID string, errorMsg string) error
 	GetStats(ctx context.Context) (*domain.QueueStats, error)
@@ -111,12 +137,17 @@ type PDFProcessor interface {
 	GenerateFromURL(ctx context.Context, url string, params map[string]interface{}) (io.Reader, error)
 	ExtractText(ctx context.Context, input io.Reader) (string, error)
 	GetPageCount(ctx context.Context, input io.Reader) (int, error)
+	GenerateThumbnail(ctx context.Context, input io.Reader, page, size int) (io.Reader, error)
 }
 
 // OCRProcessor defines OCR processing operations
 type OCRProcessor interface {
 	ProcessImage(ctx context.Context, input io.Reader, language string) (string, error)
 	ProcessPDF(ctx context.Context, input io.Reader, language string) (string, error)
+	// Render recognizes an image or every page of a PDF and returns the
+	// output format: text, hocr or pdf
+	Render(ctx context.Context, input io.Reader, language, outputFormat string) (io.Reader, error)
+	ProcessImageDetailed(ctx context.Context, input io.Reader, language string) (*domain.OCRResult, error)
 	GetSupportedLanguages() []string
 }
 
@@ -124,9 +155,18 @@ type OCRProcessor interface {
 type TextExtractor interface {
 	ExtractFromOffice(ctx context.Context, input io.Reader, docType string) (string, error)
 	ExtractFromPDF(ctx context.Context, input io.Reader) (string, error)
+	ExtractPDFPages(ctx context.Context, input io.Reader, mode domain.ExtractionMode) ([]domain.PageText, error)
+	ExtractPDFOutline(ctx context.Context, input io.Reader) ([]domain.OutlineItem, error)
 	ExtractFromText(ctx context.Context, input io.Reader) (string, error)
 }
 
+// PostProcessor runs on successful conversion results, e.g. to upload them
+// to a CDN, notify another system or attach them to a DAM
+type PostProcessor interface {
+	Name() string
+	PostProcess(ctx context.Context, output *domain.ProcessingOutput) error
+}
+
 // EventPublisher defines event publishing operations
 type EventPublisher interface {
 	PublishDocumentProcessed(ctx context.Context, event *DocumentProcessedEvent) error
@@ -141,6 +181,7 @@ type DocumentProcessedEvent struct {
 	Status      domain.JobStatus       `json:"status"`
 	Result      map[string]interface{} `json:"result,omitempty"`
 	Error       string                 `json:"error,omitempty"`
+	Metadata    map[string]string      `json:"metadata,omitempty"`
 	ProcessedAt string                 `json:"processed_at"`
 }
 
@@ -149,6 +190,7 @@ type JobCompletedEvent struct {
 	DocumentID  string                 `json:"document_id"`
 	Type        domain.ProcessingType  `json:"type"`
 	Result      map[string]interface{} `json:"result"`
+	Metadata    map[string]string      `json:"metadata,omitempty"`
 	Duration    string                 `json:"duration"`
 	CompletedAt string                 `json:"completed_at"`
 }
@@ -159,5 +201,6 @@ type JobFailedEvent struct {
 	Type       domain.ProcessingType `json:"type"`
 	Error      string                `json:"error"`
 	RetryCount int                   `json:"retry_count"`
+	Metadata   map[string]string     `json:"metadata,omitempty"`
 	FailedAt   string                `json:"failed_at"`
 }
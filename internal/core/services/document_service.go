@@ -4,6 +4,7 @@ import (
 	"context"
 	"documents-worker/internal/core/domain"
 	"documents-worker/internal/core/ports"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -54,10 +55,11 @@ func NewDocumentService(
 
 // ProcessDocument handles document processing requests
 func (s *DocumentServiceImpl) ProcessDocument(ctx context.Context, req *domain.ProcessingRequest) (*domain.ProcessingResult, error) {
-	// Verify document exists
-	_, err := s.documentRepo.GetByID(ctx, req.DocumentID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get document: %w", err)
+	// Documents can only be verified where they are persisted
+	if s.documentRepo != nil {
+		if _, err := s.documentRepo.GetByID(ctx, req.DocumentID); err != nil {
+			return nil, fmt.Errorf("failed to get document: %w", err)
+		}
 	}
 
 	// Create processing job
@@ -66,17 +68,26 @@ func (s *DocumentServiceImpl) ProcessDocument(ctx context.Context, req *domain.P
 		DocumentID: req.DocumentID,
 		Type:       req.Type,
 		Status:     domain.JobStatusPending,
+		Priority:   req.Priority,
 		Parameters: req.Parameters,
+		Metadata:   req.Metadata,
 		CreatedAt:  time.Now(),
 	}
 
 	// Save job
-	if err := s.jobRepo.Save(ctx, job); err != nil {
-		return nil, fmt.Errorf("failed to save job: %w", err)
+	if s.jobRepo != nil {
+		if err := s.jobRepo.Save(ctx, job); err != nil {
+			return nil, fmt.Errorf("failed to save job: %w", err)
+		}
 	}
 
 	// Enqueue job for processing
 	if err := s.queue.Enqueue(ctx, job); err != nil {
+		if s.jobRepo != nil {
+			job.Status = domain.JobStatusFailed
+			job.Error = err.Error()
+			s.jobRepo.Update(ctx, job)
+		}
 		return nil, fmt.Errorf("failed to enqueue job: %w", err)
 	}
 
@@ -86,23 +97,118 @@ func (s *DocumentServiceImpl) ProcessDocument(ctx context.Context, req *domain.P
 		DocumentID: job.DocumentID,
 		Type:       job.Type,
 		Status:     domain.JobStatusPending,
+		Metadata:   resultMetadata(job.Metadata),
 		Duration:   0,
 	}, nil
 }
 
+// resultMetadata echoes the caller's metadata in a processing result
+func resultMetadata(metadata map[string]string) map[string]interface{} {
+	if len(metadata) == 0 {
+		return nil
+	}
+	echoed := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		echoed[key] = value
+	}
+	return echoed
+}
+
 // GetDocument retrieves a document by ID
 func (s *DocumentServiceImpl) GetDocument(ctx context.Context, id string) (*domain.Document, error) {
+	if s.documentRepo == nil {
+		return nil, domain.ErrDocumentNotFound
+	}
 	return s.documentRepo.GetByID(ctx, id)
 }
 
-// GetJob retrieves a job by ID
+// GetJob retrieves a job by ID. Stored records are kept current by the
+// queue as the job's status changes; pending jobs carry a live queue
+// position and wait estimate.
 func (s *DocumentServiceImpl) GetJob(ctx context.Context, jobID string) (*domain.ProcessingJob, error) {
-	return s.jobRepo.GetByID(ctx, jobID)
+	if s.jobRepo == nil {
+		job, err := s.queue.GetJob(ctx, jobID)
+		if err != nil {
+			return nil, err
+		}
+		return job.Redacted(), nil
+	}
+
+	stored, err := s.jobRepo.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return s.withQueuePosition(ctx, stored).Redacted(), nil
+}
+
+// withQueuePosition adds the live queue position and wait estimate to a
+// pending job. Jobs the queue no longer holds are returned as stored.
+func (s *DocumentServiceImpl) withQueuePosition(ctx context.Context, job *domain.ProcessingJob) *domain.ProcessingJob {
+	if job.Status != domain.JobStatusPending {
+		return job
+	}
+	queued, err := s.queue.GetJob(ctx, job.ID)
+	if err != nil {
+		return job
+	}
+
+	job.QueuePosition = queued.QueuePosition
+	job.EstimatedWaitSeconds = queued.EstimatedWaitSeconds
+	return job
+}
+
+// WaitForJob waits up to maxWait for the job to finish and returns it as it
+// stands then. Callers check the status to tell a finished job from one
+// still running.
+func (s *DocumentServiceImpl) WaitForJob(ctx context.Context, jobID string, maxWait time.Duration) (*domain.ProcessingJob, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, maxWait)
+	defer cancel()
+
+	if err := s.queue.WaitForJob(waitCtx, jobID); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	return s.GetJob(ctx, jobID)
+}
+
+// GetJobResult returns the stored output of a completed job: a presigned
+// URL when the storage issues them, the content otherwise
+func (s *DocumentServiceImpl) GetJobResult(ctx context.Context, jobID string) (*domain.JobOutput, error) {
+	job, err := s.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != domain.JobStatusCompleted {
+		return nil, domain.ErrJobNotCompleted
+	}
+	key, _ := job.Result[domain.ResultStorageKey].(string)
+	if key == "" || s.fileStorage == nil {
+		return nil, domain.ErrResultNotFound
+	}
+
+	if signer, ok := s.fileStorage.(ports.URLSigner); ok {
+		return &domain.JobOutput{Key: key, URL: signer.URL(key, 0)}, nil
+	}
+	content, err := s.fileStorage.Retrieve(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve job result: %w", err)
+	}
+	return &domain.JobOutput{Key: key, Content: content}, nil
 }
 
 // GetJobsByDocument retrieves all jobs for a document
 func (s *DocumentServiceImpl) GetJobsByDocument(ctx context.Context, documentID string) ([]*domain.ProcessingJob, error) {
-	return s.jobRepo.GetByDocumentID(ctx, documentID)
+	if s.jobRepo == nil {
+		return nil, errors.New("jobs are not persisted")
+	}
+	jobs, err := s.jobRepo.GetByDocumentID(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	redacted := make([]*domain.ProcessingJob, len(jobs))
+	for i, job := range jobs {
+		redacted[i] = s.withQueuePosition(ctx, job).Redacted()
+	}
+	return redacted, nil
 }
 
 // ConvertImage converts an image to the specified format
@@ -134,11 +240,33 @@ func (s *DocumentServiceImpl) ExtractText(ctx context.Context, input io.Reader,
 	}
 }
 
+// ExtractTextPages extracts the text of each page of a PDF
+func (s *DocumentServiceImpl) ExtractTextPages(ctx context.Context, input io.Reader, mode domain.ExtractionMode) ([]domain.PageText, error) {
+	return s.textExtractor.ExtractPDFPages(ctx, input, mode)
+}
+
+// ExtractPDFOutline returns the outline (bookmark) tree of a PDF
+func (s *DocumentServiceImpl) ExtractPDFOutline(ctx context.Context, input io.Reader) ([]domain.OutlineItem, error) {
+	return s.textExtractor.ExtractPDFOutline(ctx, input)
+}
+
 // PerformOCR performs OCR on an image or PDF
 func (s *DocumentServiceImpl) PerformOCR(ctx context.Context, input io.Reader, language string) (string, error) {
 	return s.ocrProcessor.ProcessImage(ctx, input, language)
 }
 
+// PerformOCROutput performs OCR on an image or PDF and returns the text,
+// hOCR or searchable PDF
+func (s *DocumentServiceImpl) PerformOCROutput(ctx context.Context, input io.Reader, language, outputFormat string) (io.Reader, error) {
+	return s.ocrProcessor.Render(ctx, input, language, outputFormat)
+}
+
+// PerformOCRDetailed performs OCR on an image and returns the words with
+// their boxes and confidence
+func (s *DocumentServiceImpl) PerformOCRDetailed(ctx context.Context, input io.Reader, language string) (*domain.OCRResult, error) {
+	return s.ocrProcessor.ProcessImageDetailed(ctx, input, language)
+}
+
 // GenerateThumbnail generates a thumbnail from an image or video
 func (s *DocumentServiceImpl) GenerateThumbnail(ctx context.Context, input io.Reader, params map[string]interface{}) (io.Reader, error) {
 	if size, ok := params["size"].(int); ok {
@@ -147,6 +275,11 @@ func (s *DocumentServiceImpl) GenerateThumbnail(ctx context.Context, input io.Re
 	return s.imageProcessor.GenerateThumbnail(ctx, input, 200) // default size
 }
 
+// GeneratePDFThumbnail renders a page of a PDF as a PNG preview
+func (s *DocumentServiceImpl) GeneratePDFThumbnail(ctx context.Context, input io.Reader, page, size int) (io.Reader, error) {
+	return s.pdfProcessor.GenerateThumbnail(ctx, input, page, size)
+}
+
 // HealthServiceImpl implements the HealthService port
 type HealthServiceImpl struct {
 	queue          ports.Queue
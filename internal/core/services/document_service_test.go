@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signalingQueue finishes jobs on request and wakes their waiters, like the
+// Redis queue's completion channel
+type signalingQueue struct {
+	ports.Queue
+	mu     sync.Mutex
+	status map[string]domain.JobStatus
+	done   map[string]chan struct{}
+}
+
+func newSignalingQueue(jobIDs ...string) *signalingQueue {
+	q := &signalingQueue{status: map[string]domain.JobStatus{}, done: map[string]chan struct{}{}}
+	for _, id := range jobIDs {
+		q.status[id] = domain.JobStatusProcessing
+		q.done[id] = make(chan struct{})
+	}
+	return q
+}
+
+func (q *signalingQueue) finish(jobID string) {
+	q.mu.Lock()
+	q.status[jobID] = domain.JobStatusCompleted
+	q.mu.Unlock()
+	close(q.done[jobID])
+}
+
+func (q *signalingQueue) GetJob(ctx context.Context, jobID string) (*domain.ProcessingJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return &domain.ProcessingJob{ID: jobID, Status: q.status[jobID]}, nil
+}
+
+func (q *signalingQueue) WaitForJob(ctx context.Context, jobID string) error {
+	select {
+	case <-q.done[jobID]:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestWaitForJobReturnsFinishedJob(t *testing.T) {
+	queue := newSignalingQueue("fast")
+	service := NewDocumentService(nil, nil, nil, queue, nil, nil, nil, nil, nil, nil)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		queue.finish("fast")
+	}()
+
+	start := time.Now()
+	job, err := service.WaitForJob(context.Background(), "fast", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, domain.JobStatusCompleted, job.Status)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestWaitForJobReturnsRunningJobAfterMaxWait(t *testing.T) {
+	queue := newSignalingQueue("slow")
+	service := NewDocumentService(nil, nil, nil, queue, nil, nil, nil, nil, nil, nil)
+
+	start := time.Now()
+	job, err := service.WaitForJob(context.Background(), "slow", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, domain.JobStatusProcessing, job.Status)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = service.WaitForJob(ctx, "slow", time.Second)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// memoryJobRepository keeps jobs in a map
+type memoryJobRepository struct {
+	ports.JobRepository
+	jobs    map[string]domain.ProcessingJob
+	updates int
+}
+
+func (r *memoryJobRepository) Save(ctx context.Context, job *domain.ProcessingJob) error {
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *memoryJobRepository) Update(ctx context.Context, job *domain.ProcessingJob) error {
+	r.updates++
+	r.jobs[job.ID] = *job
+	return nil
+}
+
+func (r *memoryJobRepository) GetByID(ctx context.Context, id string) (*domain.ProcessingJob, error) {
+	job, ok := r.jobs[id]
+	if !ok {
+		return nil, domain.ErrJobNotFound
+	}
+	return &job, nil
+}
+
+// positionQueue reports every queued job at the same position
+type positionQueue struct {
+	ports.Queue
+	position int
+	lookups  int
+}
+
+func (q *positionQueue) GetJob(ctx context.Context, jobID string) (*domain.ProcessingJob, error) {
+	q.lookups++
+	wait := float64(q.position) * 1.5
+	return &domain.ProcessingJob{ID: jobID, Status: domain.JobStatusPending, QueuePosition: &q.position, EstimatedWaitSeconds: &wait}, nil
+}
+
+func TestGetJobServesStoredJob(t *testing.T) {
+	queue := &positionQueue{position: 3}
+	repo := &memoryJobRepository{jobs: map[string]domain.ProcessingJob{}}
+	service := NewDocumentService(nil, repo, nil, queue, nil, nil, nil, nil, nil, nil)
+	ctx := context.Background()
+	require.NoError(t, repo.Save(ctx, &domain.ProcessingJob{ID: "job-1", DocumentID: "doc-1", Status: domain.JobStatusPending}))
+	require.NoError(t, repo.Save(ctx, &domain.ProcessingJob{ID: "job-2", DocumentID: "doc-1", Status: domain.JobStatusCompleted}))
+
+	// Pending jobs carry their live queue position
+	job, err := service.GetJob(ctx, "job-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.JobStatusPending, job.Status)
+	require.NotNil(t, job.QueuePosition)
+	assert.Equal(t, 3, *job.QueuePosition)
+	assert.Equal(t, 4.5, *job.EstimatedWaitSeconds)
+
+	// Other jobs are served from the repository alone
+	job, err = service.GetJob(ctx, "job-2")
+	require.NoError(t, err)
+	assert.Equal(t, domain.JobStatusCompleted, job.Status)
+	assert.Nil(t, job.QueuePosition)
+	assert.Equal(t, 1, queue.lookups)
+
+	// Reads never write the record; the queue keeps it current
+	assert.Zero(t, repo.updates)
+}
+
+// memoryStorage keeps files in a map
+type memoryStorage struct {
+	ports.FileStorage
+	files map[string]string
+}
+
+func (s *memoryStorage) Retrieve(ctx context.Context, path string) (io.Reader, error) {
+	data, ok := s.files[path]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+	return strings.NewReader(data), nil
+}
+
+// signingStorage issues presigned URLs like the S3 storage
+type signingStorage struct {
+	memoryStorage
+}
+
+func (s *signingStorage) URL(path string, expiry time.Duration) string {
+	return "https://bucket.example.com/" + path + "?X-Amz-Signature=abc"
+}
+
+func TestGetJobResult(t *testing.T) {
+	repo := &memoryJobRepository{jobs: map[string]domain.ProcessingJob{}}
+	ctx := context.Background()
+	for _, job := range []domain.ProcessingJob{
+		{ID: "done", Status: domain.JobStatusCompleted, Result: map[string]interface{}{domain.ResultStorageKey: "results/done.webp"}},
+		{ID: "unstored", Status: domain.JobStatusCompleted, Result: map[string]interface{}{"output_path": "/tmp/processed.webp"}},
+		{ID: "running", Status: domain.JobStatusProcessing},
+	} {
+		require.NoError(t, repo.Save(ctx, &job))
+	}
+	files := map[string]string{"results/done.webp": "webp output"}
+
+	service := NewDocumentService(nil, repo, nil, &positionQueue{}, nil, nil, nil, nil, nil, nil)
+	_, err := service.GetJobResult(ctx, "done")
+	assert.ErrorIs(t, err, domain.ErrResultNotFound, "nothing is stored without a storage")
+
+	service = NewDocumentService(nil, repo, &memoryStorage{files: files}, &positionQueue{}, nil, nil, nil, nil, nil, nil)
+	output, err := service.GetJobResult(ctx, "done")
+	require.NoError(t, err)
+	assert.Equal(t, "results/done.webp", output.Key)
+	assert.Empty(t, output.URL)
+	data, err := io.ReadAll(output.Content)
+	require.NoError(t, err)
+	assert.Equal(t, "webp output", string(data))
+
+	_, err = service.GetJobResult(ctx, "unstored")
+	assert.ErrorIs(t, err, domain.ErrResultNotFound)
+	_, err = service.GetJobResult(ctx, "running")
+	assert.ErrorIs(t, err, domain.ErrJobNotCompleted)
+	_, err = service.GetJobResult(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrJobNotFound)
+
+	service = NewDocumentService(nil, repo, &signingStorage{memoryStorage{files: files}}, &positionQueue{}, nil, nil, nil, nil, nil, nil)
+	output, err = service.GetJobResult(ctx, "done")
+	require.NoError(t, err)
+	assert.Equal(t, "https://bucket.example.com/results/done.webp?X-Amz-Signature=abc", output.URL)
+	assert.Nil(t, output.Content)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
+	"fmt"
+	"io"
+	"log"
+)
+
+// PostProcessingService runs post-processors on the results of synchronous
+// conversions before they are returned. Other operations pass through.
+type PostProcessingService struct {
+	ports.DocumentService
+	postProcessors []ports.PostProcessor
+	failOnError    bool
+}
+
+// WithPostProcessors wraps a document service so every successful
+// conversion result is handed to the post-processors in order. A failing
+// post-processor is logged, or fails the conversion when failOnError is set.
+func WithPostProcessors(service ports.DocumentService, failOnError bool, postProcessors ...ports.PostProcessor) ports.DocumentService {
+	if len(postProcessors) == 0 {
+		return service
+	}
+	return &PostProcessingService{
+		DocumentService: service,
+		postProcessors:  postProcessors,
+		failOnError:     failOnError,
+	}
+}
+
+// ConvertImage converts an image and post-processes the result
+func (s *PostProcessingService) ConvertImage(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+	output, err := s.DocumentService.ConvertImage(ctx, input, outputFormat, params)
+	if err != nil {
+		return nil, err
+	}
+	return s.postProcess(ctx, output, &domain.ProcessingOutput{Type: domain.ProcessingTypeImageConvert, Format: outputFormat, Params: params})
+}
+
+// ConvertVideo converts a video and post-processes the result
+func (s *PostProcessingService) ConvertVideo(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+	output, err := s.DocumentService.ConvertVideo(ctx, input, outputFormat, params)
+	if err != nil {
+		return nil, err
+	}
+	return s.postProcess(ctx, output, &domain.ProcessingOutput{Type: domain.ProcessingTypeVideoConvert, Format: outputFormat, Params: params})
+}
+
+// GeneratePDF generates a PDF and post-processes the result
+func (s *PostProcessingService) GeneratePDF(ctx context.Context, input io.Reader, params map[string]interface{}) (io.Reader, error) {
+	output, err := s.DocumentService.GeneratePDF(ctx, input, params)
+	if err != nil {
+		return nil, err
+	}
+	return s.postProcess(ctx, output, &domain.ProcessingOutput{Type: domain.ProcessingTypePDFGenerate, Format: "pdf", Params: params})
+}
+
+// GenerateThumbnail generates a thumbnail and post-processes the result
+func (s *PostProcessingService) GenerateThumbnail(ctx context.Context, input io.Reader, params map[string]interface{}) (io.Reader, error) {
+	output, err := s.DocumentService.GenerateThumbnail(ctx, input, params)
+	if err != nil {
+		return nil, err
+	}
+	return s.postProcess(ctx, output, &domain.ProcessingOutput{Type: domain.ProcessingTypeThumbnail, Format: "webp", Params: params})
+}
+
+// GeneratePDFThumbnail renders a PDF page and post-processes the result
+func (s *PostProcessingService) GeneratePDFThumbnail(ctx context.Context, input io.Reader, page, size int) (io.Reader, error) {
+	output, err := s.DocumentService.GeneratePDFThumbnail(ctx, input, page, size)
+	if err != nil {
+		return nil, err
+	}
+	params := map[string]interface{}{"page": page, "size": size}
+	return s.postProcess(ctx, output, &domain.ProcessingOutput{Type: domain.ProcessingTypeThumbnail, Format: "png", Params: params})
+}
+
+// postProcess hands the output to every post-processor, rewinding it for
+// each, and returns it rewound. Outputs that cannot seek, unlike the temp
+// files the processors return, are buffered in memory.
+func (s *PostProcessingService) postProcess(ctx context.Context, output io.Reader, result *domain.ProcessingOutput) (io.Reader, error) {
+	seeker, ok := output.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read output for post-processing: %w", err)
+		}
+		seeker = bytes.NewReader(data)
+		output = seeker
+	}
+
+	size, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure output for post-processing: %w", err)
+	}
+	result.Size = size
+	result.Data = seeker
+
+	for _, postProcessor := range s.postProcessors {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to rewind output for post-processing: %w", err)
+		}
+		if err := postProcessor.PostProcess(ctx, result); err != nil {
+			if s.failOnError {
+				return nil, fmt.Errorf("post-processor %s failed: %w", postProcessor.Name(), err)
+			}
+			log.Printf("Post-processor %s failed for %s output: %v", postProcessor.Name(), result.Type, err)
+		}
+	}
+
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to rewind output after post-processing: %w", err)
+	}
+	return output, nil
+}
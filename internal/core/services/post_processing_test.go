@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// convertingService returns a fixed conversion result
+type convertingService struct {
+	ports.DocumentService
+	output func() (io.Reader, error)
+}
+
+func (s *convertingService) ConvertImage(ctx context.Context, input io.Reader, outputFormat string, params map[string]interface{}) (io.Reader, error) {
+	return s.output()
+}
+
+// recordingHook records what it was given
+type recordingHook struct {
+	name    string
+	err     error
+	outputs []domain.ProcessingOutput
+	data    []string
+}
+
+func (h *recordingHook) Name() string { return h.name }
+
+func (h *recordingHook) PostProcess(ctx context.Context, output *domain.ProcessingOutput) error {
+	data, err := io.ReadAll(output.Data)
+	if err != nil {
+		return err
+	}
+	h.outputs = append(h.outputs, *output)
+	h.data = append(h.data, string(data))
+	return h.err
+}
+
+func TestPostProcessorsReceiveEveryResult(t *testing.T) {
+	for name, output := range map[string]func(t *testing.T) io.Reader{
+		"stream": func(t *testing.T) io.Reader {
+			return io.MultiReader(strings.NewReader("conv"), strings.NewReader("erted"))
+		},
+		"file": func(t *testing.T) io.Reader {
+			file, err := os.CreateTemp(t.TempDir(), "output-*")
+			require.NoError(t, err)
+			t.Cleanup(func() { file.Close() })
+			_, err = file.WriteString("converted")
+			require.NoError(t, err)
+			return file
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			first, second := &recordingHook{name: "first"}, &recordingHook{name: "second"}
+			service := WithPostProcessors(&convertingService{output: func() (io.Reader, error) { return output(t), nil }}, false, first, second)
+
+			params := map[string]interface{}{"quality": 80}
+			result, err := service.ConvertImage(context.Background(), strings.NewReader("input"), "webp", params)
+			require.NoError(t, err)
+
+			// Both hooks and the caller read the whole result
+			data, err := io.ReadAll(result)
+			require.NoError(t, err)
+			assert.Equal(t, "converted", string(data))
+			for _, hook := range []*recordingHook{first, second} {
+				require.Len(t, hook.outputs, 1)
+				assert.Equal(t, "converted", hook.data[0])
+				assert.Equal(t, domain.ProcessingTypeImageConvert, hook.outputs[0].Type)
+				assert.Equal(t, "webp", hook.outputs[0].Format)
+				assert.Equal(t, int64(9), hook.outputs[0].Size)
+				assert.Equal(t, params, hook.outputs[0].Params)
+			}
+		})
+	}
+}
+
+func TestPostProcessorFailures(t *testing.T) {
+	failing := &recordingHook{name: "cdn", err: errors.New("upload refused")}
+	inner := &convertingService{output: func() (io.Reader, error) { return strings.NewReader("converted"), nil }}
+
+	// By default the failure is logged and the result still returned
+	result, err := WithPostProcessors(inner, false, failing).ConvertImage(context.Background(), nil, "png", nil)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(result)
+	assert.Equal(t, "converted", string(data))
+
+	_, err = WithPostProcessors(inner, true, failing).ConvertImage(context.Background(), nil, "png", nil)
+	assert.ErrorContains(t, err, "post-processor cdn failed: upload refused")
+}
+
+func TestPostProcessorsSkipFailedConversions(t *testing.T) {
+	hook := &recordingHook{name: "hook"}
+	inner := &convertingService{output: func() (io.Reader, error) { return nil, errors.New("vips failed") }}
+
+	_, err := WithPostProcessors(inner, false, hook).ConvertImage(context.Background(), nil, "png", nil)
+	assert.EqualError(t, err, "vips failed")
+	assert.Empty(t, hook.outputs)
+}
+
+func TestWithoutPostProcessorsReturnsService(t *testing.T) {
+	inner := &convertingService{}
+	assert.Same(t, inner, WithPostProcessors(inner, false))
+}
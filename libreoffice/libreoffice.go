@@ -0,0 +1,169 @@
+// Package libreoffice runs soffice with bounded concurrency. Instances that
+// share a user profile lock each other out and fail intermittently, so each
+// concurrent slot gets its own profile directory. Runs that still fail, as
+// the first one after boot often does while the profile is created, are
+// retried with backoff.
+package libreoffice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxConcurrent is the number of simultaneous conversions allowed
+// when the pool is not configured
+const DefaultMaxConcurrent = 2
+
+// DefaultProfileDir holds the per-slot user profiles when none is configured
+var DefaultProfileDir = filepath.Join(os.TempDir(), "documents-worker-soffice")
+
+// RetryPolicy bounds how often a failed soffice run is repeated
+type RetryPolicy struct {
+	// Retries is the number of extra attempts after a failed run
+	Retries int
+	// Backoff is the wait before the first retry; it doubles on every retry
+	Backoff time.Duration
+	// RunTimeout kills a single run that hangs, for example on a stale
+	// profile lock, so it can be retried. Zero bounds runs only by ctx.
+	RunTimeout time.Duration
+}
+
+// Pool limits concurrent soffice processes. Every slot owns a user profile
+// that is only used by one process at a time.
+type Pool struct {
+	profiles chan string
+	active   atomic.Int64
+	retry    RetryPolicy
+}
+
+// NewPool creates a pool running at most maxConcurrent conversions, with
+// their profiles under profileDir
+func NewPool(maxConcurrent int, profileDir string) *Pool {
+	if maxConcurrent <= 0 {
+		maxConcurrent = DefaultMaxConcurrent
+	}
+	if profileDir == "" {
+		profileDir = DefaultProfileDir
+	}
+	if abs, err := filepath.Abs(profileDir); err == nil {
+		profileDir = abs
+	}
+
+	p := &Pool{profiles: make(chan string, maxConcurrent)}
+	for i := 0; i < maxConcurrent; i++ {
+		p.profiles <- filepath.Join(profileDir, "instance-"+strconv.Itoa(i))
+	}
+	return p
+}
+
+// WithRetry sets the retry policy of a new pool and returns it
+func (p *Pool) WithRetry(policy RetryPolicy) *Pool {
+	p.retry = policy
+	return p
+}
+
+// Run executes soffice at path with args once a slot is free and returns
+// its combined output. Failed runs are retried in the same slot according to
+// the pool's retry policy. Waiting for a slot or a retry stops when ctx is
+// done.
+func (p *Pool) Run(ctx context.Context, path string, args ...string) ([]byte, error) {
+	var profile string
+	select {
+	case profile = <-p.profiles:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	p.active.Add(1)
+	defer func() {
+		p.active.Add(-1)
+		p.profiles <- profile
+	}()
+
+	installation := (&url.URL{Scheme: "file", Path: filepath.ToSlash(profile)}).String()
+	args = append([]string{"-env:UserInstallation=" + installation}, args...)
+
+	backoff := p.retry.Backoff
+	for attempt := 0; ; attempt++ {
+		output, err := p.runOnce(ctx, path, args)
+		if err == nil || attempt >= p.retry.Retries || ctx.Err() != nil {
+			return output, err
+		}
+		log.Printf("soffice attempt %d/%d failed, retrying in %v: %v", attempt+1, p.retry.Retries+1, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return output, err
+		}
+		backoff *= 2
+	}
+}
+
+// runOnce executes a single soffice process, bounded by the run timeout
+func (p *Pool) runOnce(ctx context.Context, path string, args []string) ([]byte, error) {
+	if p.retry.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.retry.RunTimeout)
+		defer cancel()
+	}
+	return exec.CommandContext(ctx, path, args...).CombinedOutput()
+}
+
+// WarmUp starts and stops a headless soffice once in every slot so the user
+// profiles exist before the first conversion, which otherwise pays for the
+// cold start and is the one most likely to fail. It should run before the
+// pool takes traffic: slots are visited in turn, which only reaches each of
+// them when no other conversion is holding one.
+func (p *Pool) WarmUp(ctx context.Context, path string) error {
+	var errs []error
+	for i := 0; i < p.Capacity(); i++ {
+		if output, err := p.Run(ctx, path, "--headless", "--norestore", "--terminate_after_init"); err != nil {
+			errs = append(errs, fmt.Errorf("soffice warm-up failed: %w, output: %s", err, string(output)))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Active returns the number of conversions currently running
+func (p *Pool) Active() int {
+	return int(p.active.Load())
+}
+
+// Capacity returns the largest number of simultaneous conversions
+func (p *Pool) Capacity() int {
+	return cap(p.profiles)
+}
+
+var defaultPool atomic.Pointer[Pool]
+
+func init() {
+	defaultPool.Store(NewPool(DefaultMaxConcurrent, DefaultProfileDir))
+}
+
+// Configure replaces the process-wide pool used by Run. Conversions already
+// running finish in the previous pool.
+func Configure(maxConcurrent int, profileDir string, retry RetryPolicy) {
+	defaultPool.Store(NewPool(maxConcurrent, profileDir).WithRetry(retry))
+}
+
+// Default returns the process-wide pool
+func Default() *Pool {
+	return defaultPool.Load()
+}
+
+// Run executes soffice in the process-wide pool
+func Run(ctx context.Context, path string, args ...string) ([]byte, error) {
+	return Default().Run(ctx, path, args...)
+}
@@ -0,0 +1,203 @@
+package libreoffice
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSoffice fails when its profile is in use by another process or when
+// more than max processes run at once
+const fakeSoffice = `#!/bin/sh
+profile="${1#-env:UserInstallation=file://}"
+mkdir -p "$(dirname "$profile")"
+mkdir "$profile.lock" 2>/dev/null || { echo "profile $profile in use" >&2; exit 1; }
+touch "$ACTIVE/$$"
+count=$(ls "$ACTIVE" | wc -l)
+sleep 0.1
+rm "$ACTIVE/$$"
+rmdir "$profile.lock"
+if [ "$count" -gt "$MAX" ]; then echo "$count instances running" >&2; exit 2; fi
+echo "converted"
+`
+
+func writeFakeSoffice(t *testing.T, max int) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake soffice is a shell script")
+	}
+
+	dir := t.TempDir()
+	active := filepath.Join(dir, "active")
+	require.NoError(t, os.Mkdir(active, 0755))
+	t.Setenv("ACTIVE", active)
+	t.Setenv("MAX", strconv.Itoa(max))
+
+	path := filepath.Join(dir, "soffice")
+	require.NoError(t, os.WriteFile(path, []byte(fakeSoffice), 0755))
+	return path
+}
+
+func TestPoolLimitsConcurrencyWithSeparateProfiles(t *testing.T) {
+	soffice := writeFakeSoffice(t, 2)
+	pool := NewPool(2, t.TempDir())
+
+	var wg sync.WaitGroup
+	errs := make([]error, 6)
+	outputs := make([]string, 6)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			output, err := pool.Run(context.Background(), soffice, "--headless")
+			errs[i], outputs[i] = err, string(output)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		assert.NoError(t, err, outputs[i])
+		assert.Equal(t, "converted\n", outputs[i])
+	}
+	assert.Equal(t, 0, pool.Active())
+	assert.Equal(t, 2, pool.Capacity())
+}
+
+func TestPoolStopsWaitingWhenContextIsDone(t *testing.T) {
+	soffice := writeFakeSoffice(t, 1)
+	pool := NewPool(1, t.TempDir())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.Run(context.Background(), soffice)
+	}()
+	require.Eventually(t, func() bool { return pool.Active() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := pool.Run(ctx, soffice)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	<-done
+}
+
+func TestConfigureReplacesDefaultPool(t *testing.T) {
+	previous := Default()
+	defer defaultPool.Store(previous)
+
+	Configure(3, t.TempDir(), RetryPolicy{Retries: 1})
+	assert.Equal(t, 3, Default().Capacity())
+	assert.Equal(t, 1, Default().retry.Retries)
+
+	Configure(0, "", RetryPolicy{})
+	assert.Equal(t, DefaultMaxConcurrent, Default().Capacity())
+}
+
+// flakySoffice fails its first run, the way soffice does while a fresh
+// profile is still being created, and records the arguments of every run
+const flakySoffice = `#!/bin/sh
+echo "$@" >> "$CALLS"
+if [ ! -e "$CALLS.started" ]; then
+touch "$CALLS.started"
+echo "user installation could not be completed" >&2
+exit 81
+fi
+echo "converted"
+`
+
+func writeFlakySoffice(t *testing.T) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake soffice is a shell script")
+	}
+
+	dir := t.TempDir()
+	calls := filepath.Join(dir, "calls")
+	t.Setenv("CALLS", calls)
+
+	path := filepath.Join(dir, "soffice")
+	require.NoError(t, os.WriteFile(path, []byte(flakySoffice), 0755))
+	return path, calls
+}
+
+func TestPoolRetriesFailedFirstRun(t *testing.T) {
+	soffice, calls := writeFlakySoffice(t)
+	pool := NewPool(1, t.TempDir()).WithRetry(RetryPolicy{Retries: 2, Backoff: time.Millisecond})
+
+	output, err := pool.Run(context.Background(), soffice, "--headless")
+	require.NoError(t, err, string(output))
+	assert.Equal(t, "converted\n", string(output))
+
+	recorded, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(strings.TrimSpace(string(recorded)), "\n"), 2)
+}
+
+func TestPoolWithoutRetriesReturnsFirstFailure(t *testing.T) {
+	soffice, _ := writeFlakySoffice(t)
+	pool := NewPool(1, t.TempDir())
+
+	output, err := pool.Run(context.Background(), soffice, "--headless")
+	assert.Error(t, err)
+	assert.Contains(t, string(output), "could not be completed")
+}
+
+func TestPoolStopsRetryingWhenContextIsDone(t *testing.T) {
+	soffice, _ := writeFlakySoffice(t)
+	pool := NewPool(1, t.TempDir()).WithRetry(RetryPolicy{Retries: 3, Backoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	output, err := pool.Run(ctx, soffice)
+	assert.Error(t, err)
+	assert.Contains(t, string(output), "could not be completed")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestPoolRetriesHangingRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake soffice is a shell script")
+	}
+	dir := t.TempDir()
+	soffice := filepath.Join(dir, "soffice")
+	// The first run hangs on the profile lock, later ones convert
+	script := "#!/bin/sh\nif [ ! -e " + dir + "/started ]; then touch " + dir + "/started; exec sleep 5; fi\necho converted\n"
+	require.NoError(t, os.WriteFile(soffice, []byte(script), 0755))
+
+	pool := NewPool(1, t.TempDir()).WithRetry(RetryPolicy{Retries: 1, RunTimeout: 100 * time.Millisecond})
+	start := time.Now()
+	output, err := pool.Run(context.Background(), soffice)
+	require.NoError(t, err)
+	assert.Equal(t, "converted\n", string(output))
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestWarmUpStartsEveryProfile(t *testing.T) {
+	soffice, calls := writeFlakySoffice(t)
+	profiles := t.TempDir()
+	pool := NewPool(2, profiles).WithRetry(RetryPolicy{Retries: 1, Backoff: time.Millisecond})
+
+	require.NoError(t, pool.WarmUp(context.Background(), soffice))
+
+	recorded, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	runs := strings.Split(strings.TrimSpace(string(recorded)), "\n")
+	// The first run fails and is retried in its slot
+	require.Len(t, runs, 3)
+	for _, run := range runs {
+		assert.Contains(t, run, "--terminate_after_init")
+	}
+	assert.Contains(t, runs[0], filepath.Join(profiles, "instance-0"))
+	assert.Contains(t, runs[1], filepath.Join(profiles, "instance-0"))
+	assert.Contains(t, runs[2], filepath.Join(profiles, "instance-1"))
+}
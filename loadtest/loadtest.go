@@ -0,0 +1,213 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operation is a single request issued by the load generator
+type Operation func(ctx context.Context) error
+
+// Config holds load generator configuration
+type Config struct {
+	Concurrency int
+	Duration    time.Duration
+	// MaxRequests stops the run early once reached (0 = unlimited)
+	MaxRequests int
+}
+
+// Report summarizes a load test run
+type Report struct {
+	Requests   int            `json:"requests"`
+	Successes  int            `json:"successes"`
+	Failures   int            `json:"failures"`
+	ErrorRate  float64        `json:"error_rate"`
+	Elapsed    time.Duration  `json:"elapsed_ns"`
+	Throughput float64        `json:"throughput_rps"`
+	Latency    LatencyStats   `json:"latency"`
+	Errors     map[string]int `json:"errors,omitempty"`
+	Config     Config         `json:"-"`
+}
+
+// LatencyStats holds latency distribution in milliseconds
+type LatencyStats struct {
+	Min  float64 `json:"min_ms"`
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P90  float64 `json:"p90_ms"`
+	P95  float64 `json:"p95_ms"`
+	P99  float64 `json:"p99_ms"`
+	Max  float64 `json:"max_ms"`
+}
+
+type sample struct {
+	latency time.Duration
+	err     error
+}
+
+// Run drives op with the configured concurrency until the duration elapses,
+// MaxRequests is reached or ctx is cancelled
+func Run(ctx context.Context, cfg Config, op Operation) *Report {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = 10 * time.Second
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		samples []sample
+		issued  int
+		wg      sync.WaitGroup
+	)
+
+	// next reserves a request slot, honoring MaxRequests
+	next := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if cfg.MaxRequests > 0 && issued >= cfg.MaxRequests {
+			return false
+		}
+		issued++
+		return true
+	}
+
+	start := time.Now()
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for runCtx.Err() == nil && next() {
+				began := time.Now()
+				err := op(runCtx)
+				latency := time.Since(began)
+
+				// Requests interrupted by the end of the run are not counted
+				if err != nil && runCtx.Err() != nil {
+					return
+				}
+
+				mu.Lock()
+				samples = append(samples, sample{latency: latency, err: err})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return buildReport(cfg, samples, time.Since(start))
+}
+
+// buildReport aggregates raw samples into a report
+func buildReport(cfg Config, samples []sample, elapsed time.Duration) *Report {
+	report := &Report{
+		Requests: len(samples),
+		Elapsed:  elapsed,
+		Config:   cfg,
+		Errors:   make(map[string]int),
+	}
+
+	latencies := make([]float64, 0, len(samples))
+	for _, s := range samples {
+		if s.err != nil {
+			report.Failures++
+			report.Errors[s.err.Error()]++
+		} else {
+			report.Successes++
+		}
+		latencies = append(latencies, float64(s.latency)/float64(time.Millisecond))
+	}
+
+	if report.Requests > 0 {
+		report.ErrorRate = float64(report.Failures) / float64(report.Requests)
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	}
+	report.Latency = computeLatencyStats(latencies)
+
+	return report
+}
+
+// computeLatencyStats calculates min/mean/max and percentiles
+func computeLatencyStats(values []float64) LatencyStats {
+	if len(values) == 0 {
+		return LatencyStats{}
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+
+	return LatencyStats{
+		Min:  sorted[0],
+		Mean: sum / float64(len(sorted)),
+		P50:  percentile(sorted, 50),
+		P90:  percentile(sorted, 90),
+		P95:  percentile(sorted, 95),
+		P99:  percentile(sorted, 99),
+		Max:  sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// JSON returns the report encoded as indented JSON
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// String returns a human readable summary of the report
+func (r *Report) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Requests:    %d (%d ok, %d failed)\n", r.Requests, r.Successes, r.Failures)
+	fmt.Fprintf(&b, "Duration:    %s\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "Throughput:  %.2f req/s\n", r.Throughput)
+	fmt.Fprintf(&b, "Error rate:  %.2f%%\n", r.ErrorRate*100)
+	fmt.Fprintf(&b, "Latency (ms):\n")
+	fmt.Fprintf(&b, "  min %.2f  mean %.2f  max %.2f\n", r.Latency.Min, r.Latency.Mean, r.Latency.Max)
+	fmt.Fprintf(&b, "  p50 %.2f  p90 %.2f  p95 %.2f  p99 %.2f\n", r.Latency.P50, r.Latency.P90, r.Latency.P95, r.Latency.P99)
+
+	if len(r.Errors) > 0 {
+		messages := make([]string, 0, len(r.Errors))
+		for msg := range r.Errors {
+			messages = append(messages, msg)
+		}
+		sort.Strings(messages)
+
+		fmt.Fprintf(&b, "Errors:\n")
+		for _, msg := range messages {
+			fmt.Fprintf(&b, "  %5d  %s\n", r.Errors[msg], msg)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,92 @@
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMaxRequests(t *testing.T) {
+	var calls int64
+	op := func(ctx context.Context) error {
+		n := atomic.AddInt64(&calls, 1)
+		if n%4 == 0 {
+			return errors.New("boom")
+		}
+		return nil
+	}
+
+	report := Run(context.Background(), Config{Concurrency: 4, Duration: 5 * time.Second, MaxRequests: 20}, op)
+
+	require.NotNil(t, report)
+	assert.Equal(t, 20, report.Requests)
+	assert.Equal(t, 15, report.Successes)
+	assert.Equal(t, 5, report.Failures)
+	assert.InDelta(t, 0.25, report.ErrorRate, 0.0001)
+	assert.Equal(t, 5, report.Errors["boom"])
+	assert.Greater(t, report.Throughput, 0.0)
+}
+
+func TestRunStopsAtDuration(t *testing.T) {
+	op := func(ctx context.Context) error {
+		select {
+		case <-time.After(5 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	start := time.Now()
+	report := Run(context.Background(), Config{Concurrency: 2, Duration: 50 * time.Millisecond}, op)
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Greater(t, report.Requests, 0)
+	assert.Equal(t, 0, report.Failures, "requests cut off by the deadline must not count as failures")
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	tests := []struct {
+		p        float64
+		expected float64
+	}{
+		{50, 5},
+		{90, 9},
+		{99, 10},
+		{0, 1},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, percentile(values, tt.p))
+	}
+	assert.Equal(t, 0.0, percentile(nil, 50))
+}
+
+func TestReportOutput(t *testing.T) {
+	samples := []sample{
+		{latency: 10 * time.Millisecond},
+		{latency: 20 * time.Millisecond},
+		{latency: 30 * time.Millisecond, err: errors.New("server returned 500: failed")},
+	}
+	report := buildReport(Config{Concurrency: 1}, samples, time.Second)
+
+	assert.Equal(t, 10.0, report.Latency.Min)
+	assert.Equal(t, 20.0, report.Latency.Mean)
+	assert.Equal(t, 30.0, report.Latency.Max)
+
+	text := report.String()
+	assert.Contains(t, text, "Requests:    3 (2 ok, 1 failed)")
+	assert.Contains(t, text, "server returned 500: failed")
+
+	data, err := report.JSON()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"p99_ms": 30`)
+	assert.Contains(t, string(data), `"throughput_rps": 3`)
+}
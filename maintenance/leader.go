@@ -0,0 +1,87 @@
+package maintenance
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Elector decides which instance runs cluster-wide maintenance
+type Elector interface {
+	// Acquire claims or renews leadership and reports whether this
+	// instance is the leader
+	Acquire(ctx context.Context) (bool, error)
+	// Release gives up leadership if this instance holds it
+	Release(ctx context.Context) error
+}
+
+// renewScript extends the lease only while this instance still owns it
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseScript deletes the lease only while this instance still owns it
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// RedisLeader elects a leader with a lease key in Redis. The lease expires
+// after the TTL, so a crashed leader is replaced within one TTL.
+type RedisLeader struct {
+	client redis.UniversalClient
+	key    string
+	id     string
+	ttl    time.Duration
+}
+
+// NewRedisLeader creates an elector competing for the given lease key
+func NewRedisLeader(client redis.UniversalClient, key string, ttl time.Duration) *RedisLeader {
+	return &RedisLeader{
+		client: client,
+		key:    key,
+		id:     instanceID(),
+		ttl:    ttl,
+	}
+}
+
+// Acquire claims the lease if it is free, or renews it if already held
+func (l *RedisLeader) Acquire(ctx context.Context) (bool, error) {
+	acquired, err := l.client.SetNX(ctx, l.key, l.id, l.ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire maintenance lease: %w", err)
+	}
+	if acquired {
+		return true, nil
+	}
+
+	renewed, err := renewScript.Run(ctx, l.client, []string{l.key}, l.id, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew maintenance lease: %w", err)
+	}
+	return renewed == 1, nil
+}
+
+// Release deletes the lease if this instance holds it
+func (l *RedisLeader) Release(ctx context.Context) error {
+	if err := releaseScript.Run(ctx, l.client, []string{l.key}, l.id).Err(); err != nil {
+		return fmt.Errorf("failed to release maintenance lease: %w", err)
+	}
+	return nil
+}
+
+// instanceID identifies this process in the lease value
+func instanceID() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
@@ -0,0 +1,121 @@
+package maintenance
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TargetStats are the cumulative sweep counters of one target
+type TargetStats struct {
+	Runs    int64     `json:"runs"`
+	Removed int64     `json:"removed"`
+	Bytes   int64     `json:"bytes"`
+	Skipped int64     `json:"skipped"`
+	Errors  int64     `json:"errors"`
+	LastRun time.Time `json:"last_run"`
+}
+
+// Metrics tracks reclaimed space per target
+type Metrics struct {
+	mu      sync.Mutex
+	targets map[string]*TargetStats
+	leader  bool
+}
+
+// NewMetrics creates an empty metrics registry
+func NewMetrics() *Metrics {
+	return &Metrics{targets: make(map[string]*TargetStats)}
+}
+
+// Record adds the outcome of a sweep
+func (m *Metrics) Record(result SweepResult, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.targets[result.Target]
+	if !ok {
+		stats = &TargetStats{}
+		m.targets[result.Target] = stats
+	}
+	stats.Runs++
+	stats.Removed += int64(result.Removed)
+	stats.Bytes += result.Bytes
+	stats.Skipped += int64(result.Skipped)
+	stats.Errors += int64(result.Errors)
+	stats.LastRun = at
+}
+
+// SetLeader records whether this instance currently holds leadership
+func (m *Metrics) SetLeader(leader bool) {
+	m.mu.Lock()
+	m.leader = leader
+	m.mu.Unlock()
+}
+
+// Leader reports whether this instance held leadership at the last sweep
+func (m *Metrics) Leader() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.leader
+}
+
+// Snapshot returns a copy of the counters by target
+func (m *Metrics) Snapshot() map[string]TargetStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]TargetStats, len(m.targets))
+	for name, stats := range m.targets {
+		snapshot[name] = *stats
+	}
+	return snapshot
+}
+
+// WritePrometheus writes the counters in the Prometheus text format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	snapshot := m.Snapshot()
+	m.mu.Lock()
+	leader := m.leader
+	m.mu.Unlock()
+
+	targets := make([]string, 0, len(snapshot))
+	for target := range snapshot {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	series := []struct {
+		name  string
+		kind  string
+		help  string
+		value func(TargetStats) float64
+	}{
+		{"documents_worker_maintenance_runs_total", "counter", "Sweeps of the directory.", func(s TargetStats) float64 { return float64(s.Runs) }},
+		{"documents_worker_maintenance_removed_total", "counter", "Orphaned files and directories removed.", func(s TargetStats) float64 { return float64(s.Removed) }},
+		{"documents_worker_maintenance_reclaimed_bytes_total", "counter", "Bytes reclaimed by removing orphans.", func(s TargetStats) float64 { return float64(s.Bytes) }},
+		{"documents_worker_maintenance_skipped_total", "counter", "Old entries kept because they are in use.", func(s TargetStats) float64 { return float64(s.Skipped) }},
+		{"documents_worker_maintenance_errors_total", "counter", "Entries that could not be inspected or removed.", func(s TargetStats) float64 { return float64(s.Errors) }},
+		{"documents_worker_maintenance_last_run_timestamp_seconds", "gauge", "Time of the last sweep.", func(s TargetStats) float64 { return float64(s.LastRun.Unix()) }},
+	}
+
+	for _, metric := range series {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind); err != nil {
+			return err
+		}
+		for _, target := range targets {
+			if _, err := fmt.Fprintf(w, "%s{target=%q} %g\n", metric.name, target, metric.value(snapshot[target])); err != nil {
+				return err
+			}
+		}
+	}
+
+	leaderValue := 0
+	if leader {
+		leaderValue = 1
+	}
+	_, err := fmt.Fprintf(w, "# HELP documents_worker_maintenance_leader Whether this instance sweeps shared directories.\n# TYPE documents_worker_maintenance_leader gauge\ndocuments_worker_maintenance_leader %d\n", leaderValue)
+	return err
+}
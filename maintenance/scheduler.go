@@ -0,0 +1,144 @@
+package maintenance
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Scheduler sweeps its targets periodically. Shared targets are swept only
+// while this instance holds leadership.
+type Scheduler struct {
+	interval time.Duration
+	leader   Elector
+	sources  []PathSource
+	targets  []Target
+	metrics  *Metrics
+	now      func() time.Time
+
+	// BeforeSweep, when set, runs before each sweep on the leader, e.g. to
+	// expire cache entries so their files become orphans
+	BeforeSweep func()
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler. A nil leader makes this instance sweep
+// shared targets unconditionally, for single-instance deployments.
+func NewScheduler(interval time.Duration, leader Elector, sources []PathSource, targets ...Target) *Scheduler {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &Scheduler{
+		interval: interval,
+		leader:   leader,
+		sources:  sources,
+		targets:  targets,
+		metrics:  NewMetrics(),
+		now:      time.Now,
+	}
+}
+
+// Metrics returns the scheduler's sweep counters
+func (s *Scheduler) Metrics() *Metrics {
+	return s.metrics
+}
+
+// Start runs a sweep immediately and then every interval until Stop
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.RunOnce(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the schedule and gives up leadership
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+
+	if s.leader != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.leader.Release(ctx); err != nil {
+			log.Printf("Maintenance: %v", err)
+		}
+	}
+}
+
+// RunOnce performs a single sweep of every target this instance is
+// responsible for. Nothing is removed if the in-flight paths cannot be
+// determined.
+func (s *Scheduler) RunOnce(ctx context.Context) []SweepResult {
+	leader := s.leader == nil
+	if s.leader != nil {
+		var err error
+		if leader, err = s.leader.Acquire(ctx); err != nil {
+			log.Printf("Maintenance: %v", err)
+		}
+	}
+	s.metrics.SetLeader(leader)
+
+	active, err := s.activePaths(ctx)
+	if err != nil {
+		log.Printf("Maintenance: skipping sweep, in-flight paths unknown: %v", err)
+		return nil
+	}
+
+	if leader && s.BeforeSweep != nil {
+		s.BeforeSweep()
+	}
+
+	var results []SweepResult
+	for _, target := range s.targets {
+		if target.Shared && !leader {
+			continue
+		}
+		now := s.now()
+		result := Sweep(target, active, now)
+		s.metrics.Record(result, now)
+		results = append(results, result)
+
+		if result.Removed > 0 || result.Errors > 0 {
+			log.Printf("Maintenance: %s sweep removed %d entries (%d bytes), %d errors",
+				result.Target, result.Removed, result.Bytes, result.Errors)
+		}
+	}
+	return results
+}
+
+func (s *Scheduler) activePaths(ctx context.Context) (map[string]bool, error) {
+	active := make(map[string]bool)
+	for _, source := range s.sources {
+		paths, err := source.ActivePaths(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			if abs, err := filepath.Abs(path); err == nil {
+				active[abs] = true
+			}
+		}
+	}
+	return active, nil
+}
@@ -0,0 +1,90 @@
+package maintenance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeElector struct{ leader bool }
+
+func (f *fakeElector) Acquire(ctx context.Context) (bool, error) { return f.leader, nil }
+func (f *fakeElector) Release(ctx context.Context) error         { return nil }
+
+type failingSource struct{}
+
+func (failingSource) ActivePaths(ctx context.Context) ([]string, error) {
+	return nil, errors.New("redis unavailable")
+}
+
+func TestSchedulerSweepsSharedTargetsOnlyOnLeader(t *testing.T) {
+	tempDir, cacheDir := t.TempDir(), t.TempDir()
+	writeAged(t, filepath.Join(tempDir, "upload-1"), 10, 2*time.Hour)
+	writeAged(t, filepath.Join(cacheDir, "orphan.png"), 20, 2*time.Hour)
+
+	elector := &fakeElector{}
+	scheduler := NewScheduler(time.Hour, elector, nil,
+		Target{Name: "temp", Dir: tempDir, MaxAge: time.Hour},
+		Target{Name: "cache", Dir: cacheDir, MaxAge: time.Hour, Shared: true},
+	)
+	var beforeSweeps int
+	scheduler.BeforeSweep = func() { beforeSweeps++ }
+
+	results := scheduler.RunOnce(context.Background())
+	require.Len(t, results, 1)
+	assert.Equal(t, "temp", results[0].Target)
+	assert.FileExists(t, filepath.Join(cacheDir, "orphan.png"))
+	assert.Zero(t, beforeSweeps)
+
+	elector.leader = true
+	results = scheduler.RunOnce(context.Background())
+	require.Len(t, results, 2)
+	assert.Equal(t, 1, results[1].Removed)
+	assert.NoFileExists(t, filepath.Join(cacheDir, "orphan.png"))
+	assert.Equal(t, 1, beforeSweeps)
+
+	snapshot := scheduler.Metrics().Snapshot()
+	assert.Equal(t, int64(2), snapshot["temp"].Runs)
+	assert.Equal(t, int64(10), snapshot["temp"].Bytes)
+	assert.Equal(t, int64(20), snapshot["cache"].Bytes)
+
+	var buf bytes.Buffer
+	require.NoError(t, scheduler.Metrics().WritePrometheus(&buf))
+	assert.Contains(t, buf.String(), `documents_worker_maintenance_reclaimed_bytes_total{target="cache"} 20`)
+	assert.Contains(t, buf.String(), "documents_worker_maintenance_leader 1")
+}
+
+func TestSchedulerSkipsSweepWhenActivePathsUnknown(t *testing.T) {
+	dir := t.TempDir()
+	writeAged(t, filepath.Join(dir, "upload-1"), 10, 2*time.Hour)
+
+	scheduler := NewScheduler(time.Hour, nil, []PathSource{failingSource{}},
+		Target{Name: "temp", Dir: dir, MaxAge: time.Hour})
+
+	assert.Empty(t, scheduler.RunOnce(context.Background()))
+	assert.FileExists(t, filepath.Join(dir, "upload-1"))
+}
+
+func TestSchedulerProtectsTrackedPaths(t *testing.T) {
+	dir := t.TempDir()
+	upload := filepath.Join(dir, "upload-1")
+	writeAged(t, upload, 10, 2*time.Hour)
+
+	tracker := NewTracker()
+	release := tracker.Track(upload)
+	scheduler := NewScheduler(time.Hour, nil, []PathSource{tracker},
+		Target{Name: "temp", Dir: dir, MaxAge: time.Hour})
+
+	scheduler.RunOnce(context.Background())
+	assert.FileExists(t, upload)
+
+	release()
+	scheduler.RunOnce(context.Background())
+	assert.NoFileExists(t, upload)
+}
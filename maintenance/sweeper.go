@@ -0,0 +1,149 @@
+// Package maintenance removes files leaked by crashed or interrupted
+// processing from the temp and cache directories.
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultTempPatterns match the temp files and directories created by the
+// processors. The temp directory is often shared with other programs, so
+// only these names are ever swept there.
+var DefaultTempPatterns = []string{
+	"upload-*", "input-*", "processed-*", "generated-*", "filled-*",
+	"decrypted-*", "flatten-*", "compare-*", "pdf-*", "office-*",
+	"libreoffice-*", "html-*", "markdown-*", "ocr-*", "form-data-*",
+	"output-*", "fetch-*", "tiff-page-*", "orient-*", "resized-*",
+	"contact-sheet-*", "mutool-*", "watermarked-*", "storage-upload-*",
+	"stream-*",
+}
+
+// Target is a directory swept for orphaned files
+type Target struct {
+	// Name labels the target in logs and metrics, e.g. "temp" or "cache"
+	Name string
+	Dir  string
+	// MaxAge is how long an entry must be untouched before it is removed
+	MaxAge time.Duration
+	// Patterns are globs matched against top-level entry names; empty
+	// matches every entry
+	Patterns []string
+	// Shared targets live on storage shared by every instance and are swept
+	// only by the elected leader; other targets are swept by each instance
+	Shared bool
+	// Keep, when set, protects entries that are still in use
+	Keep func(path string) bool
+}
+
+// SweepResult summarizes one sweep of a target
+type SweepResult struct {
+	Target  string `json:"target"`
+	Removed int    `json:"removed"`
+	Bytes   int64  `json:"bytes"`
+	Skipped int    `json:"skipped"` // old entries protected as in use
+	Errors  int    `json:"errors"`
+}
+
+// activeSet holds the paths of in-flight jobs
+type activeSet map[string]bool
+
+// touches reports whether path is active, lies inside an active directory,
+// or is a directory containing an active path
+func (a activeSet) touches(path string) bool {
+	for active := range a {
+		if active == path ||
+			strings.HasPrefix(active, path+string(filepath.Separator)) ||
+			strings.HasPrefix(path, active+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sweep removes the top-level entries of the target directory that match
+// its patterns and have not been modified within MaxAge. A directory is
+// removed as a whole, and only when nothing inside it is recent. Entries
+// touching an active path or kept by the target are never removed.
+func Sweep(target Target, active map[string]bool, now time.Time) SweepResult {
+	result := SweepResult{Target: target.Name}
+	dir, err := filepath.Abs(target.Dir)
+	if err != nil {
+		result.Errors++
+		return result
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			result.Errors++
+		}
+		return result
+	}
+
+	cutoff := now.Add(-target.MaxAge)
+	for _, entry := range entries {
+		if !matches(entry.Name(), target.Patterns) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		newest, size, err := newestModTime(path)
+		if err != nil {
+			result.Errors++
+			continue
+		}
+		if newest.After(cutoff) {
+			continue
+		}
+		if activeSet(active).touches(path) || (target.Keep != nil && target.Keep(path)) {
+			result.Skipped++
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			result.Errors++
+			continue
+		}
+		result.Removed++
+		result.Bytes += size
+	}
+	return result
+}
+
+func matches(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// newestModTime returns the latest modification time and the total size of
+// path and everything below it. Symlinks are not followed.
+func newestModTime(path string) (time.Time, int64, error) {
+	var newest time.Time
+	var size int64
+	err := filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return newest, size, err
+}
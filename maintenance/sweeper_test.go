@@ -0,0 +1,149 @@
+package maintenance
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeAged creates a file whose modification time is age in the past
+func writeAged(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	old := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(path, old, old))
+}
+
+func TestSweepRemovesOldMatchingEntries(t *testing.T) {
+	dir := t.TempDir()
+	writeAged(t, filepath.Join(dir, "upload-old"), 100, 2*time.Hour)
+	writeAged(t, filepath.Join(dir, "upload-new"), 100, time.Minute)
+	writeAged(t, filepath.Join(dir, "someone-elses.tmp"), 100, 2*time.Hour)
+
+	// A directory is removed only when nothing inside is recent
+	writeAged(t, filepath.Join(dir, "pdf-convert-old", "page.png"), 50, 2*time.Hour)
+	writeAged(t, filepath.Join(dir, "pdf-convert-busy", "page_1.png"), 50, 2*time.Hour)
+	writeAged(t, filepath.Join(dir, "pdf-convert-busy", "page_2.png"), 50, time.Minute)
+	for _, sub := range []string{"pdf-convert-old", "pdf-convert-busy"} {
+		old := time.Now().Add(-2 * time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, sub), old, old))
+	}
+
+	result := Sweep(Target{Name: "temp", Dir: dir, MaxAge: time.Hour, Patterns: DefaultTempPatterns}, nil, time.Now())
+
+	assert.Equal(t, 2, result.Removed)
+	assert.Equal(t, int64(150), result.Bytes)
+	assert.Zero(t, result.Errors)
+	assert.NoFileExists(t, filepath.Join(dir, "upload-old"))
+	assert.NoDirExists(t, filepath.Join(dir, "pdf-convert-old"))
+	assert.FileExists(t, filepath.Join(dir, "upload-new"))
+	assert.FileExists(t, filepath.Join(dir, "someone-elses.tmp"))
+	assert.FileExists(t, filepath.Join(dir, "pdf-convert-busy", "page_1.png"))
+}
+
+// tempNamePattern finds the name patterns of the temp files and
+// directories the code creates
+var tempNamePattern = regexp.MustCompile(`os\.(?:CreateTemp|MkdirTemp)\([^,]+, (?:fmt\.Sprintf\()?"([^"]+)"`)
+
+// TestDefaultTempPatternsCoverProcessors keeps DefaultTempPatterns in step
+// with the temp names created across the module, so leaks from crashes are
+// always swept
+func TestDefaultTempPatternsCoverProcessors(t *testing.T) {
+	root, err := filepath.Abs("..")
+	require.NoError(t, err)
+
+	found := 0
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, match := range tempNamePattern.FindAllSubmatch(source, -1) {
+			pattern := string(match[1])
+			// Hidden names are created next to their input, not in the
+			// temp directory
+			if strings.HasPrefix(pattern, ".") {
+				continue
+			}
+			found++
+			name := strings.Replace(pattern, "*", "123456", 1)
+			assert.True(t, matches(name, DefaultTempPatterns), "%s creates %q, which no default pattern matches", path, pattern)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, found)
+}
+
+func TestSweepNeverRemovesActivePaths(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input-job.pdf")
+	jobDir := filepath.Join(dir, "pdf-convert-job")
+	writeAged(t, input, 10, 48*time.Hour)
+	writeAged(t, filepath.Join(jobDir, "page.png"), 10, 48*time.Hour)
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, os.Chtimes(jobDir, old, old))
+
+	active := map[string]bool{input: true, filepath.Join(jobDir, "page.png"): true}
+	result := Sweep(Target{Name: "temp", Dir: dir, MaxAge: time.Hour}, active, time.Now())
+
+	assert.Zero(t, result.Removed)
+	assert.Equal(t, 2, result.Skipped)
+	assert.FileExists(t, input)
+	assert.FileExists(t, filepath.Join(jobDir, "page.png"))
+}
+
+func TestSweepKeep(t *testing.T) {
+	dir := t.TempDir()
+	live := filepath.Join(dir, "ocr_abc.json")
+	orphan := filepath.Join(dir, "ocr_def.txt")
+	writeAged(t, live, 10, 2*time.Hour)
+	writeAged(t, orphan, 10, 2*time.Hour)
+
+	target := Target{Name: "cache", Dir: dir, MaxAge: time.Hour, Keep: func(path string) bool { return path == live }}
+	result := Sweep(target, nil, time.Now())
+
+	assert.Equal(t, 1, result.Removed)
+	assert.FileExists(t, live)
+	assert.NoFileExists(t, orphan)
+
+	missing := Sweep(Target{Name: "cache", Dir: filepath.Join(dir, "missing"), MaxAge: time.Hour}, nil, time.Now())
+	assert.Zero(t, missing.Errors, "a missing directory is not an error")
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker()
+	release := tracker.Track("/tmp/upload-1")
+	releaseAgain := tracker.Track("/tmp/upload-1")
+
+	paths, err := tracker.ActivePaths(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/tmp/upload-1"}, paths)
+
+	release()
+	release() // releasing twice has no further effect
+	paths, _ = tracker.ActivePaths(context.Background())
+	assert.Len(t, paths, 1)
+
+	releaseAgain()
+	paths, _ = tracker.ActivePaths(context.Background())
+	assert.Empty(t, paths)
+}
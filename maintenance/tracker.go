@@ -0,0 +1,57 @@
+package maintenance
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+)
+
+// PathSource reports the files in use by in-flight work
+type PathSource interface {
+	ActivePaths(ctx context.Context) ([]string, error)
+}
+
+// Tracker records paths in use by this instance, such as spooled uploads
+// still being processed
+type Tracker struct {
+	mu    sync.Mutex
+	paths map[string]int
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{paths: make(map[string]int)}
+}
+
+// Track marks path as in use until the returned release function is called.
+// A path tracked several times stays in use until every release.
+func (t *Tracker) Track(path string) func() {
+	path = filepath.Clean(path)
+
+	t.mu.Lock()
+	t.paths[path]++
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.paths[path]--; t.paths[path] <= 0 {
+				delete(t.paths, path)
+			}
+		})
+	}
+}
+
+// ActivePaths returns the tracked paths
+func (t *Tracker) ActivePaths(ctx context.Context) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	paths := make([]string, 0, len(t.paths))
+	for path := range t.paths {
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
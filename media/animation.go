@@ -0,0 +1,69 @@
+package media
+
+import (
+	"documents-worker/types"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Videolardan üretilen hareketli önizlemelerin kare hızı sınırları. Düşük kare
+// hızı GIF ve WebP çıktılarını makul boyutta tutar.
+const (
+	DefaultAnimationFPS = 10
+	MaxAnimationFPS     = 30
+)
+
+// defaultAnimationQuality, kalite verilmediğinde hareketli WebP için kullanılır
+const defaultAnimationQuality = 75
+
+// isAnimatedOutput, video girdisinden hareketli GIF veya WebP istenip istenmediğini belirtir
+func isAnimatedOutput(m *types.MediaConverter) bool {
+	if m.Kind != types.VideoKind || m.Format == nil {
+		return false
+	}
+	switch strings.ToLower(*m.Format) {
+	case "gif", "webp":
+		return true
+	default:
+		return false
+	}
+}
+
+// animationFPS istenen kare hızını varsayılan ve üst sınıra göre düzeltir
+func animationFPS(m *types.MediaConverter) int {
+	if m.Search.FPS == nil || *m.Search.FPS <= 0 {
+		return DefaultAnimationFPS
+	}
+	return min(*m.Search.FPS, MaxAnimationFPS)
+}
+
+// buildFFmpegAnimationArgs hareketli önizlemenin filtre zincirini ve kodlayıcı
+// seçeneklerini üretir. GIF için palet önce klipten üretilir, sonra uygulanır;
+// WebP libwebp ile kodlanır. İki format da sonsuz döngüyle oynatılır.
+func buildFFmpegAnimationArgs(m *types.MediaConverter, orientFilters []string) []string {
+	filters := append([]string{}, orientFilters...)
+	filters = append(filters, fmt.Sprintf("fps=%d", animationFPS(m)))
+	if m.Search.Width != nil || m.Search.Height != nil {
+		w, h := "-1", "-1"
+		if m.Search.Width != nil {
+			w = strconv.Itoa(*m.Search.Width)
+		}
+		if m.Search.Height != nil {
+			h = strconv.Itoa(*m.Search.Height)
+		}
+		filters = append(filters, fmt.Sprintf("scale=%s:%s:flags=lanczos", w, h))
+	}
+	graph := strings.Join(filters, ",")
+
+	if strings.ToLower(*m.Format) == "gif" {
+		graph += ",split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse"
+		return []string{"-vf", graph, "-loop", "0", "-an"}
+	}
+
+	quality := defaultAnimationQuality
+	if m.Search.Quality != nil {
+		quality = *m.Search.Quality
+	}
+	return []string{"-vf", graph, "-c:v", "libwebp", "-quality", strconv.Itoa(quality), "-loop", "0", "-an"}
+}
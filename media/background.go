@@ -0,0 +1,85 @@
+package media
+
+import (
+	"context"
+	"documents-worker/types"
+	"fmt"
+	"image/color"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// DefaultBackgroundColor saydam görüntüler alfa kanalı olmayan formatlara
+// dönüştürülürken kullanılan arka plan rengidir
+const DefaultBackgroundColor = "#ffffff"
+
+// ParseBackgroundColor "#rrggbb" biçimindeki arka plan rengini doğrular. Düzleştirilmiş
+// çıktı opak olduğundan yarı saydam renkler reddedilir.
+func ParseBackgroundColor(value string) (color.RGBA, error) {
+	c, err := ParseHexColor(value)
+	if err != nil {
+		return color.RGBA{}, err
+	}
+	if c.A != 255 {
+		return color.RGBA{}, fmt.Errorf("arka plan rengi opak olmalı: %q", value)
+	}
+	return c, nil
+}
+
+// supportsAlpha çıktı formatının saydamlığı saklayıp saklayamadığını belirtir.
+// Format verilmezse varsayılan çıktı olan webp kabul edilir.
+func supportsAlpha(format *string) bool {
+	if format == nil {
+		return true
+	}
+	switch strings.ToLower(*format) {
+	case "jpg", "jpeg", "bmp":
+		return false
+	default:
+		return true
+	}
+}
+
+// backgroundFor düzleştirmede kullanılacak rengi döndürür. Açıkça istenen renk her
+// zaman uygulanır; aksi halde yalnızca alfa desteklemeyen formatlar beyaza düzleştirilir.
+func backgroundFor(m *types.MediaConverter) (color.RGBA, bool, error) {
+	if m.Search.BackgroundColor != nil {
+		c, err := ParseBackgroundColor(*m.Search.BackgroundColor)
+		return c, err == nil, err
+	}
+	if supportsAlpha(m.Format) {
+		return color.RGBA{}, false, nil
+	}
+	c, _ := ParseBackgroundColor(DefaultBackgroundColor)
+	return c, true, nil
+}
+
+func vipsFlattenArgs(inputPath, outputPath string, background color.RGBA) []string {
+	return []string{
+		"flatten", inputPath, outputPath,
+		"--background", fmt.Sprintf("%d %d %d", background.R, background.G, background.B),
+	}
+}
+
+// flattenBackground saydam alanları arka plan rengiyle doldurulmuş ara bir VIPS
+// dosyası üretir. Alfa kanalı olmayan girdiler olduğu gibi kopyalanır.
+func flattenBackground(ctx context.Context, inputPath string, background color.RGBA) (string, func(), error) {
+	tempFile, err := os.CreateTemp("", "flatten-*.v")
+	if err != nil {
+		return "", func() {}, fmt.Errorf("geçici dosya oluşturulamadı: %w", err)
+	}
+	tempFile.Close()
+	cleanup := func() { os.Remove(tempFile.Name()) }
+
+	cmd := exec.CommandContext(ctx, "vips", vipsFlattenArgs(inputPath, tempFile.Name(), background)...)
+	log.Infof("Komut çalıştırılıyor: %s", cmd.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		cleanup()
+		log.Errorf("Komut Hatası: %v, Çıktı: %s", err, string(output))
+		return "", func() {}, fmt.Errorf("arka plan düzleştirme hatası: %w", err)
+	}
+	return tempFile.Name(), cleanup, nil
+}
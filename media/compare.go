@@ -0,0 +1,296 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	// DefaultCompareThreshold bir pikselin farklı sayılması için kanal farkının aşması gereken orandır
+	DefaultCompareThreshold = 0.1
+	// ssimWindow SSIM'in hesaplandığı kayan pencerenin kenar uzunluğudur
+	ssimWindow = 8
+)
+
+// DefaultHighlightColor fark görüntüsünde farklı pikselleri işaretler
+var DefaultHighlightColor = color.RGBA{R: 255, A: 255}
+
+// CompareOptions görüntü karşılaştırma ayarlarını taşır.
+type CompareOptions struct {
+	// Threshold 0-1 aralığında; en büyük kanal farkı bunu aşan pikseller farklı sayılır
+	Threshold float64
+	// HighlightColor farklı piksellerin fark görüntüsündeki rengidir; boşsa kırmızı kullanılır
+	HighlightColor color.RGBA
+}
+
+// CompareResult karşılaştırmanın benzerlik ölçülerini taşır.
+type CompareResult struct {
+	SSIM           float64 `json:"ssim"`
+	DiffPixels     int     `json:"diff_pixels"`
+	TotalPixels    int     `json:"total_pixels"`
+	DiffPercentage float64 `json:"diff_percentage"`
+	Width          int     `json:"width"`
+	Height         int     `json:"height"`
+	Identical      bool    `json:"identical"`
+	// Resized ikinci görüntünün ilkinin boyutlarına ölçeklendiğini belirtir
+	Resized bool `json:"resized"`
+}
+
+// Metadata sonucu iş metadatasına eklenecek biçimde döndürür.
+func (r *CompareResult) Metadata() map[string]interface{} {
+	return map[string]interface{}{
+		"ssim":            r.SSIM,
+		"diff_pixels":     r.DiffPixels,
+		"total_pixels":    r.TotalPixels,
+		"diff_percentage": r.DiffPercentage,
+		"width":           r.Width,
+		"height":          r.Height,
+		"identical":       r.Identical,
+		"resized":         r.Resized,
+	}
+}
+
+// ParseHexColor "#rrggbb" veya "#rrggbbaa" biçimindeki rengi çözer.
+func ParseHexColor(value string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.RGBA{}, fmt.Errorf("geçersiz renk: %q (beklenen #rrggbb veya #rrggbbaa)", value)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("geçersiz renk: %q", value)
+	}
+	return color.RGBA{R: uint8(n >> 24), G: uint8(n >> 16), B: uint8(n >> 8), A: uint8(n)}, nil
+}
+
+// CompareImages iki görüntüyü karşılaştırır; farklı pikselleri işaretleyen PNG fark
+// görüntüsünü ve benzerlik ölçülerini döndürür. Go'nun çözemediği biçimler ve farklı
+// boyutlu görüntüler önce VIPS ile PNG'ye normalize edilir.
+func CompareImages(pathA, pathB string, opts CompareOptions) (*os.File, *CompareResult, error) {
+	if opts.Threshold < 0 || opts.Threshold > 1 {
+		return nil, nil, fmt.Errorf("geçersiz eşik: %v (0 ile 1 arasında olmalı)", opts.Threshold)
+	}
+
+	imgA, err := loadComparableImage(pathA, 0, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	imgB, err := loadComparableImage(pathB, 0, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resized := false
+	if size := imgA.Bounds().Size(); imgB.Bounds().Size() != size {
+		imgB, err = loadComparableImage(pathB, size.X, size.Y)
+		if err != nil {
+			return nil, nil, err
+		}
+		resized = true
+	}
+
+	result, diff := compareImages(imgA, imgB, opts)
+	result.Resized = resized
+
+	outputFile, err := os.CreateTemp("", "compare-*.png")
+	if err != nil {
+		return nil, nil, fmt.Errorf("geçici çıktı dosyası oluşturulamadı: %w", err)
+	}
+	if err := png.Encode(outputFile, diff); err != nil {
+		outputFile.Close()
+		os.Remove(outputFile.Name())
+		return nil, nil, fmt.Errorf("fark görüntüsü yazılamadı: %w", err)
+	}
+	if _, err := outputFile.Seek(0, 0); err != nil {
+		outputFile.Close()
+		os.Remove(outputFile.Name())
+		return nil, nil, fmt.Errorf("fark görüntüsü okunamadı: %w", err)
+	}
+
+	log.Infof("Görüntü karşılaştırma: SSIM %.4f, farklı piksel %%%.2f", result.SSIM, result.DiffPercentage)
+	return outputFile, result, nil
+}
+
+// loadComparableImage görüntüyü çözer. width > 0 ise görüntü VIPS ile bu boyutlara
+// zorla ölçeklenir; Go'nun çözemediği biçimler de VIPS ile PNG'ye çevrilir.
+func loadComparableImage(path string, width, height int) (image.Image, error) {
+	if width == 0 {
+		if img, err := decodeImageFile(path); err == nil {
+			return img, nil
+		}
+	}
+
+	normalized, err := os.CreateTemp("", "compare-input-*.png")
+	if err != nil {
+		return nil, fmt.Errorf("geçici dosya oluşturulamadı: %w", err)
+	}
+	normalized.Close()
+	defer os.Remove(normalized.Name())
+
+	var cmd *exec.Cmd
+	if width > 0 {
+		cmd = exec.Command("vips", "thumbnail", path, normalized.Name(), strconv.Itoa(width),
+			"--height", strconv.Itoa(height), "--size", "force")
+	} else {
+		cmd = exec.Command("vips", "copy", path, normalized.Name())
+	}
+	log.Infof("Komut çalıştırılıyor: %s", cmd.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		log.Errorf("Komut Hatası: %v, Çıktı: %s", err, string(output))
+		return nil, fmt.Errorf("görüntü karşılaştırma için hazırlanamadı: %w", err)
+	}
+
+	return decodeImageFile(normalized.Name())
+}
+
+func decodeImageFile(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("görüntü açılamadı: %w", err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("görüntü çözülemedi: %w", err)
+	}
+	return img, nil
+}
+
+// compareImages aynı boyutlu iki görüntüyü karşılaştırır. Fark görüntüsünde aynı
+// pikseller ilk görüntünün soluk gri tonu, farklı olanlar vurgu rengiyle çizilir.
+func compareImages(a, b image.Image, opts CompareOptions) (*CompareResult, *image.RGBA) {
+	highlight := opts.HighlightColor
+	if highlight.A == 0 {
+		highlight = DefaultHighlightColor
+	}
+	limit := opts.Threshold * 255
+
+	bounds := a.Bounds()
+	offset := b.Bounds().Min.Sub(bounds.Min)
+	width, height := bounds.Dx(), bounds.Dy()
+
+	diff := image.NewRGBA(image.Rect(0, 0, width, height))
+	lumaA := make([]float64, width*height)
+	lumaB := make([]float64, width*height)
+	diffPixels := 0
+	identical := true
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pa := color.NRGBAModel.Convert(a.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			pb := color.NRGBAModel.Convert(b.At(bounds.Min.X+x+offset.X, bounds.Min.Y+y+offset.Y)).(color.NRGBA)
+
+			i := y*width + x
+			lumaA[i] = luma(pa)
+			lumaB[i] = luma(pb)
+
+			delta := maxChannelDelta(pa, pb)
+			if delta > 0 {
+				identical = false
+			}
+			if float64(delta) > limit {
+				diffPixels++
+				diff.SetRGBA(x, y, highlight)
+				continue
+			}
+			faded := uint8(255 - (255-lumaA[i])/4)
+			diff.SetRGBA(x, y, color.RGBA{R: faded, G: faded, B: faded, A: 255})
+		}
+	}
+
+	total := width * height
+	result := &CompareResult{
+		SSIM:        ssim(lumaA, lumaB, width, height),
+		DiffPixels:  diffPixels,
+		TotalPixels: total,
+		Width:       width,
+		Height:      height,
+		Identical:   identical,
+	}
+	if total > 0 {
+		result.DiffPercentage = math.Round(float64(diffPixels)/float64(total)*10000) / 100
+	}
+	return result, diff
+}
+
+func luma(c color.NRGBA) float64 {
+	return 0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)
+}
+
+func maxChannelDelta(a, b color.NRGBA) uint8 {
+	delta := func(x, y uint8) uint8 {
+		if x > y {
+			return x - y
+		}
+		return y - x
+	}
+	return max(delta(a.R, b.R), delta(a.G, b.G), delta(a.B, b.B), delta(a.A, b.A))
+}
+
+// ssim parlaklık kanalında kayan pencerelerle ortalama yapısal benzerliği hesaplar.
+// Pencereden küçük görüntülerde tüm görüntü tek pencere olarak kullanılır.
+func ssim(a, b []float64, width, height int) float64 {
+	if width == 0 || height == 0 {
+		return 1
+	}
+
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+	)
+
+	winW, winH := min(ssimWindow, width), min(ssimWindow, height)
+	stepX, stepY := max(winW/2, 1), max(winH/2, 1)
+
+	var total float64
+	windows := 0
+	for y := 0; y+winH <= height; y += stepY {
+		for x := 0; x+winW <= width; x += stepX {
+			var sumA, sumB float64
+			for j := y; j < y+winH; j++ {
+				for i := x; i < x+winW; i++ {
+					sumA += a[j*width+i]
+					sumB += b[j*width+i]
+				}
+			}
+			n := float64(winW * winH)
+			meanA, meanB := sumA/n, sumB/n
+
+			var varA, varB, cov float64
+			for j := y; j < y+winH; j++ {
+				for i := x; i < x+winW; i++ {
+					da := a[j*width+i] - meanA
+					db := b[j*width+i] - meanB
+					varA += da * da
+					varB += db * db
+					cov += da * db
+				}
+			}
+			varA /= n
+			varB /= n
+			cov /= n
+
+			total += ((2*meanA*meanB + c1) * (2*cov + c2)) /
+				((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+			windows++
+		}
+	}
+
+	return math.Round(total/float64(windows)*10000) / 10000
+}
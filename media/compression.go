@@ -0,0 +1,116 @@
+package media
+
+import (
+	"documents-worker/types"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// compressionEffort bir formatın sıkıştırma çabası ayarını tanımlar. Çaba ne kadar
+// yüksekse kodlama o kadar uzun sürer ve dosya o kadar küçülür.
+type compressionEffort struct {
+	min, max int
+	// option VIPS kaydetme seçeneğinin ya da FFmpeg argümanının adıdır
+	option string
+	// inverted, daha yüksek değerin daha hızlı kodlama anlamına geldiği FFmpeg
+	// -cpu-used ayarında çabanın max-çaba olarak çevrildiğini belirtir
+	inverted bool
+}
+
+// vipsCompressionEfforts VIPS kaydedicilerinin çaba ayarlarıdır
+var vipsCompressionEfforts = map[string]compressionEffort{
+	"webp": {0, 6, "effort", false},
+	"avif": {0, 9, "effort", false},
+	"heif": {0, 9, "effort", false},
+	"heic": {0, 9, "effort", false},
+	"jxl":  {1, 9, "effort", false},
+	"gif":  {1, 10, "effort", false},
+	"png":  {0, 9, "compression", false},
+}
+
+// ffmpegImageCompressionEfforts FFmpeg ile kodlanan görüntülerin çaba ayarlarıdır
+var ffmpegImageCompressionEfforts = map[string]compressionEffort{
+	"avif": {0, 8, "-cpu-used", true},
+	"webp": {0, 6, "-compression_level", false},
+	"png":  {0, 9, "-compression_level", false},
+}
+
+// Videolar WebM (VP9), hareketli WebP önizlemeler libwebp ile kodlanır
+var (
+	ffmpegVideoCompressionEffort = compressionEffort{0, 5, "-cpu-used", true}
+	ffmpegWebPCompressionEffort  = compressionEffort{0, 6, "-compression_level", false}
+)
+
+// compressionEffortFor çıktı formatının ve kodlayıcının çaba ayarını döndürür.
+// Çaba ayarı olmayan formatlarda (JPEG, GIF önizlemeler) ok false olur.
+func compressionEffortFor(vipsEnabled bool, m *types.MediaConverter) (compressionEffort, bool) {
+	format := "webp"
+	if m.Format != nil {
+		format = strings.ToLower(*m.Format)
+	}
+
+	switch {
+	case m.Kind == types.ImageKind && vipsEnabled:
+		effort, ok := vipsCompressionEfforts[format]
+		return effort, ok
+	case m.Kind == types.ImageKind:
+		effort, ok := ffmpegImageCompressionEfforts[format]
+		return effort, ok
+	case isAnimatedOutput(m):
+		return ffmpegWebPCompressionEffort, format == "webp"
+	case m.Kind == types.VideoKind:
+		return ffmpegVideoCompressionEffort, true
+	}
+	return compressionEffort{}, false
+}
+
+// ValidateCompressionEffort istenen sıkıştırma çabasının çıktı formatında
+// desteklendiğini ve formatın aralığında olduğunu denetler.
+func ValidateCompressionEffort(vipsEnabled bool, m *types.MediaConverter) error {
+	if m.Search.CompressionEffort == nil {
+		return nil
+	}
+	value := *m.Search.CompressionEffort
+	effort, ok := compressionEffortFor(vipsEnabled, m)
+	if !ok {
+		format := "webp"
+		if m.Format != nil {
+			format = *m.Format
+		}
+		return fmt.Errorf("%s çıktısı sıkıştırma çabası ayarını desteklemiyor", format)
+	}
+	if value < effort.min || value > effort.max {
+		return fmt.Errorf("geçersiz sıkıştırma çabası: %d (%d-%d arası olmalı)", value, effort.min, effort.max)
+	}
+	return nil
+}
+
+// vipsCompressionOption VIPS kaydetme seçeneklerine eklenecek çaba ayarını üretir
+func vipsCompressionOption(m *types.MediaConverter) (string, bool) {
+	if m.Search.CompressionEffort == nil {
+		return "", false
+	}
+	effort, ok := compressionEffortFor(true, m)
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("%s=%d", effort.option, *m.Search.CompressionEffort), true
+}
+
+// buildFFmpegCompressionArgs FFmpeg kodlayıcısının çaba argümanlarını üretir.
+// -cpu-used ters yönde çalıştığı için çaba max-çaba olarak çevrilir.
+func buildFFmpegCompressionArgs(m *types.MediaConverter) []string {
+	if m.Search.CompressionEffort == nil {
+		return nil
+	}
+	effort, ok := compressionEffortFor(false, m)
+	if !ok {
+		return nil
+	}
+	value := *m.Search.CompressionEffort
+	if effort.inverted {
+		value = effort.max - value
+	}
+	return []string{effort.option, strconv.Itoa(value)}
+}
@@ -0,0 +1,86 @@
+package media
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	// MaxContactSheetFrames bir kontak sayfasındaki en fazla kare sayısıdır
+	MaxContactSheetFrames = 100
+	// MaxContactSheetThumbWidth karelerin izin verilen en büyük genişliğidir (piksel)
+	MaxContactSheetThumbWidth = 1920
+)
+
+// contactSheetArgs videodan rows*cols kareyi eşit aralıklarla seçip tek bir JPEG
+// ızgarasında birleştiren ffmpeg argümanlarını üretir. İlk kare başta seçilir,
+// sonrakiler önceki seçimden en az interval saniye sonra gelen ilk karelerdir.
+func contactSheetArgs(inputPath, outputPath string, rows, cols, thumbWidth int, duration float64) []string {
+	interval := duration / float64(rows*cols)
+	filters := []string{
+		fmt.Sprintf("select='isnan(prev_selected_t)+gte(t-prev_selected_t,%s)'", strconv.FormatFloat(interval, 'f', 3, 64)),
+		fmt.Sprintf("scale=%d:-2", thumbWidth),
+		fmt.Sprintf("tile=%dx%d", cols, rows),
+	}
+	return []string{
+		"-i", inputPath,
+		"-vf", strings.Join(filters, ","),
+		"-frames:v", "1",
+		"-fps_mode", "vfr",
+		"-q:v", "3",
+		"-y", outputPath,
+	}
+}
+
+// ProbeDuration videonun süresini saniye olarak ffprobe ile okur.
+func ProbeDuration(inputPath string) (float64, error) {
+	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", inputPath)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("video süresi okunamadı: %w", err)
+	}
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("video süresi belirlenemedi: %q", strings.TrimSpace(string(output)))
+	}
+	return duration, nil
+}
+
+// GenerateContactSheet videonun tamamına eşit aralıklarla yayılmış rows*cols
+// kareden oluşan tek bir JPEG kontak sayfası üretir. Aralık video süresinden
+// hesaplandığı için kısa kliplerde de kareler tüm süreye dağılır. Çıktı
+// dosyasını çağıran siler.
+func GenerateContactSheet(inputPath string, rows, cols int, thumbWidth int) (*os.File, error) {
+	if rows < 1 || cols < 1 || rows*cols > MaxContactSheetFrames {
+		return nil, fmt.Errorf("geçersiz ızgara: %dx%d (en fazla %d kare)", cols, rows, MaxContactSheetFrames)
+	}
+	if thumbWidth < 1 || thumbWidth > MaxContactSheetThumbWidth {
+		return nil, fmt.Errorf("geçersiz kare genişliği: %d (1-%d)", thumbWidth, MaxContactSheetThumbWidth)
+	}
+
+	duration, err := ProbeDuration(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	outputFile, err := os.CreateTemp("", "contact-sheet-*.jpg")
+	if err != nil {
+		return nil, fmt.Errorf("geçici çıktı dosyası oluşturulamadı: %w", err)
+	}
+	outputFile.Close()
+
+	cmd := exec.Command("ffmpeg", contactSheetArgs(inputPath, outputFile.Name(), rows, cols, thumbWidth, duration)...)
+	log.Infof("Komut çalıştırılıyor: %s", cmd.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(outputFile.Name())
+		log.Errorf("Komut Hatası: %v, Çıktı: %s", err, string(output))
+		return nil, fmt.Errorf("kontak sayfası oluşturulamadı: %w", err)
+	}
+
+	return os.Open(outputFile.Name())
+}
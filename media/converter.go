@@ -2,7 +2,9 @@ package media
 
 import (
 	"documents-worker/types"
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
@@ -42,6 +44,70 @@ func NewMediaConverterFromFiber(c *fiber.Ctx) (*types.MediaConverter, error) {
 	if cutVideo := c.Query("clip"); cutVideo != "" {
 		media.Search.CutVideo = &cutVideo
 	}
+	if fps := c.Query("fps"); fps != "" {
+		f, _ := strconv.Atoi(fps)
+		media.Search.FPS = &f
+	}
+	if progressive := c.Query("progressive"); progressive != "" {
+		p, _ := strconv.ParseBool(progressive)
+		media.Search.Progressive = &p
+	}
+	if sharpen := c.Query("sharpen"); sharpen != "" {
+		sigma, err := ParseSharpen(sharpen)
+		if err != nil {
+			return nil, err
+		}
+		media.Search.Sharpen = &sigma
+	}
+	if effort := c.Query("effort"); effort != "" {
+		e, err := strconv.Atoi(effort)
+		if err != nil {
+			return nil, fmt.Errorf("geçersiz sıkıştırma çabası: %q", effort)
+		}
+		media.Search.CompressionEffort = &e
+	}
+	if rotate := c.Query("rotate"); rotate != "" {
+		degrees, err := ParseRotation(rotate)
+		if err != nil {
+			return nil, err
+		}
+		media.Search.Rotate = &degrees
+	}
+	if autoOrient := c.Query("autoOrient"); autoOrient != "" {
+		media.Search.AutoOrient, _ = strconv.ParseBool(autoOrient)
+	}
+	if background := c.Query("background"); background != "" {
+		if _, err := ParseBackgroundColor(background); err != nil {
+			return nil, err
+		}
+		media.Search.BackgroundColor = &background
+	}
+	videoColor, err := ParseVideoColor(c.Query("colorPrimaries"), c.Query("colorTrc"), c.Query("colorMatrix"), c.Query("gamma"))
+	if err != nil {
+		return nil, err
+	}
+	media.Search.VideoColor = videoColor
+	if targetSize := c.Query("targetSize"); targetSize != "" {
+		t, _ := strconv.Atoi(targetSize)
+		if t > 0 {
+			media.Search.TargetSize = &t
+		}
+	}
+	if mode := c.Query("metadata"); mode != "" {
+		var tags []string
+		if list := c.Query("metadataTags"); list != "" {
+			tags = strings.Split(list, ",")
+		}
+		policy, err := ParseMetadataPolicy(mode, tags)
+		if err != nil {
+			return nil, err
+		}
+		media.Search.Metadata = policy
+	}
+	// Parola URL'de loglanmasın diye sorgu parametresi yerine başlıktan okunur
+	if password := c.Get("X-PDF-Password"); password != "" {
+		media.Search.Password = &password
+	}
 	if page := c.Query("page"); page != "" {
 		p, _ := strconv.Atoi(page)
 		if p > 0 {
@@ -5,6 +5,7 @@ import (
 	"documents-worker/utils"
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 type DocumentProcessor struct {
@@ -25,7 +26,7 @@ func (p *DocumentProcessor) Process(inputPath string) (*os.File, error) {
 		if err != nil {
 			return nil, fmt.Errorf("libreoffice dönüştürme hatası: %w", err)
 		}
-		defer os.Remove(currentPath)
+		defer os.RemoveAll(filepath.Dir(currentPath))
 	}
 
 	// Adım 2: PDF ise resme dönüştür
@@ -34,11 +35,23 @@ func (p *DocumentProcessor) Process(inputPath string) (*os.File, error) {
 		if p.MediaConverter.Search.Page != nil {
 			page = *p.MediaConverter.Search.Page
 		}
-		currentPath, err = RunMutool(currentPath, page)
+		password := ""
+		if p.MediaConverter.Search.Password != nil {
+			password = *p.MediaConverter.Search.Password
+		}
+		pdfPath, cleanup, err := utils.PreparePDF("mutool", currentPath, password)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		currentPath = pdfPath
+
+		pages, err := RunMutool(currentPath, page, page, DefaultRenderDPI)
 		if err != nil {
 			return nil, fmt.Errorf("mutool ile sayfa çıkarma hatası: %w", err)
 		}
-		defer os.Remove(currentPath)
+		defer os.RemoveAll(filepath.Dir(pages[0]))
+		currentPath = pages[0]
 	}
 
 	// Doküman işlendikten sonra ImageProcessor'a devret
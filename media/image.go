@@ -8,9 +8,20 @@ import (
 
 type ImageProcessor struct {
 	MediaConverter *types.MediaConverter
+	// TargetSizeResult hedef boyut modunda ulaşılan kalite ve boyutu içerir
+	TargetSizeResult *TargetSizeResult
 }
 
 func (p *ImageProcessor) Process(inputPath string) (*os.File, error) {
+	if p.MediaConverter.Search.TargetSize != nil {
+		outputFile, result, err := ConvertToTargetSize(p.MediaConverter.VipsEnabled, inputPath, p.MediaConverter)
+		if err != nil {
+			return nil, fmt.Errorf("resim işleme hatası: %w", err)
+		}
+		p.TargetSizeResult = result
+		return outputFile, nil
+	}
+
 	outputFile, err := ExecCommand(p.MediaConverter.VipsEnabled, inputPath, p.MediaConverter)
 	if err != nil {
 		return nil, fmt.Errorf("resim işleme hatası: %w", err)
@@ -1,6 +1,8 @@
 package media
 
 import (
+	"context"
+	"documents-worker/libreoffice"
 	"documents-worker/types"
 	"fmt"
 	"os"
@@ -14,6 +16,31 @@ import (
 
 // ExecCommand, belirlenen işleyiciyi (VIPS veya FFMPEG) çalıştıran ana fonksiyondur.
 func ExecCommand(vipsEnabled bool, inputPath string, m *types.MediaConverter) (*os.File, error) {
+	return ExecCommandContext(context.Background(), vipsEnabled, inputPath, m)
+}
+
+// ExecCommandContext, ExecCommand gibidir; ancak ctx iptal edildiğinde çalışan
+// işlem sonlandırılır ve yarım kalan çıktı silinir.
+func ExecCommandContext(ctx context.Context, vipsEnabled bool, inputPath string, m *types.MediaConverter) (*os.File, error) {
+	if err := ValidateCompressionEffort(vipsEnabled, m); err != nil {
+		return nil, err
+	}
+
+	// Hedef boyut araması girdiyi kendisi hazırlar; denemeleri yeniden hazırlamaz
+	if m.Kind == types.ImageKind && m.Search.TargetSize != nil {
+		outputFile, _, err := convertToTargetSize(ctx, vipsEnabled, inputPath, m)
+		return outputFile, err
+	}
+
+	if vipsEnabled && m.Kind == types.ImageKind {
+		prepared, cleanup, err := prepareImage(ctx, inputPath, m)
+		defer cleanup()
+		if err != nil {
+			return nil, err
+		}
+		inputPath = prepared
+	}
+
 	var cmd *exec.Cmd
 	var extension string
 
@@ -23,6 +50,8 @@ func ExecCommand(vipsEnabled bool, inputPath string, m *types.MediaConverter) (*
 		} else {
 			extension = "webp"
 		}
+	} else if isAnimatedOutput(m) {
+		extension = strings.ToLower(*m.Format)
 	} else if m.Kind == types.VideoKind {
 		extension = "webm"
 	} else {
@@ -36,28 +65,82 @@ func ExecCommand(vipsEnabled bool, inputPath string, m *types.MediaConverter) (*
 	defer outputFile.Close()
 
 	if vipsEnabled && m.Kind == types.ImageKind {
+		// Saydam görüntüler kodlamadan önce arka plan rengine düzleştirilir
+		background, flatten, err := backgroundFor(m)
+		if err != nil {
+			return nil, err
+		}
+		if flatten {
+			flattened, cleanup, err := flattenBackground(ctx, inputPath, background)
+			defer cleanup()
+			if err != nil {
+				return nil, err
+			}
+			inputPath = flattened
+		}
 		args := buildVipsArgs(inputPath, outputFile.Name(), m)
-		cmd = exec.Command("vips", args...)
+		// Keskinleştirme boyutlandırılmış ara dosyaya, kodlamadan hemen önce uygulanır
+		if sharpens(m) {
+			resized, cleanup, err := resizeForSharpening(ctx, inputPath, m)
+			defer cleanup()
+			if err != nil {
+				return nil, err
+			}
+			args = buildVipsSharpenArgs(resized, outputFile.Name(), m)
+		}
+		cmd = exec.CommandContext(ctx, "vips", args...)
 	} else {
 		args := buildFFmpegArgs(inputPath, outputFile.Name(), m)
-		cmd = exec.Command("ffmpeg", args...)
+		if m.Kind == types.VideoKind && m.Search.Metadata != nil && m.Search.Metadata.Mode == types.MetadataAllowlist {
+			probed, err := probeMetadataTags(inputPath)
+			if err != nil {
+				return nil, err
+			}
+			// Çıktı argümanlarından ("-y", çıktı) önce eklenir
+			tail := append([]string{}, args[len(args)-2:]...)
+			args = append(args[:len(args)-2], allowlistMetadataArgs(m.Search.Metadata, probed)...)
+			args = append(args, tail...)
+		}
+		cmd = exec.CommandContext(ctx, "ffmpeg", args...)
 	}
 
 	log.Infof("Komut çalıştırılıyor: %s", cmd.String())
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		os.Remove(outputFile.Name())
+		if ctx.Err() != nil {
+			log.Infof("Komut iptal edildi: %s", cmd.Path)
+			return nil, fmt.Errorf("komut iptal edildi: %w", ctx.Err())
+		}
 		log.Errorf("Komut Hatası: %v, Çıktı: %s", err, string(output))
 		return nil, fmt.Errorf("komut çalıştırma hatası: %w", err)
 	}
 
+	// Görüntülerde etiket bazlı politika kodlamadan sonra exiftool ile uygulanır
+	if m.Kind == types.ImageKind && isSelectivePolicy(m.Search.Metadata) {
+		if err := applyImageMetadataPolicy(m.Search.Metadata, outputFile.Name()); err != nil {
+			os.Remove(outputFile.Name())
+			return nil, err
+		}
+	}
+
 	return os.OpenFile(outputFile.Name(), os.O_RDONLY, 0666)
 }
 
 func buildVipsArgs(inputPath string, outputPath string, m *types.MediaConverter) []string {
-	outputWithOpts := outputPath
-	if m.Search.Quality != nil {
-		outputWithOpts = fmt.Sprintf("%s[Q=%d]", outputPath, *m.Search.Quality)
+	return buildVipsOperationArgs(inputPath, vipsOutputWithOptions(outputPath, m), m)
+}
+
+// vipsOutputWithOptions, kaydetme seçeneklerini çıktı dosya adına ekler.
+func vipsOutputWithOptions(outputPath string, m *types.MediaConverter) string {
+	if saveOpts := buildVipsSaveOptions(m); len(saveOpts) > 0 {
+		return fmt.Sprintf("%s[%s]", outputPath, strings.Join(saveOpts, ","))
 	}
+	return outputPath
+}
+
+// buildVipsOperationArgs, kaydetme seçenekleri eklenmiş çıktıya yazan VIPS işlemini üretir.
+func buildVipsOperationArgs(inputPath string, outputWithOpts string, m *types.MediaConverter) []string {
 	if m.Search.ResizeScale != nil {
 		scaleFactor := float64(*m.Search.ResizeScale) / 100.0
 		return []string{"resize", inputPath, outputWithOpts, fmt.Sprintf("%f", scaleFactor)}
@@ -73,16 +156,54 @@ func buildVipsArgs(inputPath string, outputPath string, m *types.MediaConverter)
 		if m.Search.Height != nil {
 			args = append(args, "--height", strconv.Itoa(*m.Search.Height))
 		}
+		// Yön açıkça ele alındıysa thumbnail EXIF yönünü ikinci kez uygulamaz
+		if handlesOrientation(m) {
+			args = append(args, "--no-rotate")
+		}
 		return args
 	} else {
 		return []string{"copy", inputPath, outputWithOpts}
 	}
 }
 
+// buildVipsSaveOptions, çıktı dosya adına eklenecek kaydetme seçeneklerini üretir.
+func buildVipsSaveOptions(m *types.MediaConverter) []string {
+	var opts []string
+	if m.Search.Quality != nil {
+		opts = append(opts, fmt.Sprintf("Q=%d", *m.Search.Quality))
+	}
+	// Progressive JPEG ve interlaced PNG yalnızca istenirse; varsayılan baseline kalır
+	if m.Search.Progressive != nil && *m.Search.Progressive && supportsInterlace(m.Format) {
+		opts = append(opts, "interlace")
+	}
+	// VIPS varsayılan olarak metadatayı korur; strip-all tümünü atar
+	if m.Search.Metadata != nil && m.Search.Metadata.Mode == types.MetadataStripAll {
+		opts = append(opts, "strip")
+	}
+	if option, ok := vipsCompressionOption(m); ok {
+		opts = append(opts, option)
+	}
+	return opts
+}
+
+// supportsInterlace, VIPS kaydedicisinin interlace seçeneğini desteklediği formatları belirtir.
+func supportsInterlace(format *string) bool {
+	if format == nil {
+		return false
+	}
+	switch strings.ToLower(*format) {
+	case "jpg", "jpeg", "png":
+		return true
+	default:
+		return false
+	}
+}
+
 func buildFFmpegArgs(inputPath string, outputPath string, m *types.MediaConverter) []string {
-	args := []string{"-i", inputPath}
+	orientInput, orientFilters := ffmpegOrientArgs(m)
+	args := append(orientInput, "-i", inputPath)
 	if m.Kind == types.ImageKind {
-		vf := []string{}
+		vf := append([]string{}, orientFilters...)
 		if m.Search.ResizeScale != nil {
 			vf = append(vf, fmt.Sprintf("scale=iw*%d/100:ih*%d/100", *m.Search.ResizeScale, *m.Search.ResizeScale))
 		} else if m.Search.Width != nil || m.Search.Height != nil {
@@ -108,37 +229,102 @@ func buildFFmpegArgs(inputPath string, outputPath string, m *types.MediaConverte
 		if m.Format != nil && *m.Format == "avif" {
 			args = append(args, "-c:v", "libaom-av1", "-still-picture", "1")
 		}
-	} else if m.Kind == types.VideoKind && m.Search.CutVideo != nil {
-		parts := strings.Split(*m.Search.CutVideo, ":")
-		if len(parts) == 2 {
-			args = append(args, "-ss", parts[0], "-t", parts[1])
+		args = append(args, buildFFmpegCompressionArgs(m)...)
+	} else if m.Kind == types.VideoKind {
+		if m.Search.CutVideo != nil {
+			parts := strings.Split(*m.Search.CutVideo, ":")
+			if len(parts) == 2 {
+				args = append(args, "-ss", parts[0], "-t", parts[1])
+			}
+		}
+		if isAnimatedOutput(m) {
+			args = append(args, buildFFmpegAnimationArgs(m, orientFilters)...)
+		} else {
+			// Renk ayarları verilmezse girdinin renk bilgisi aynen korunur
+			filters, colorArgs := buildFFmpegColorArgs(m.Search.VideoColor)
+			filters = append(orientFilters, filters...)
+			if len(filters) > 0 {
+				args = append(args, "-vf", strings.Join(filters, ","))
+			}
+			args = append(args, colorArgs...)
 		}
+		args = append(args, buildFFmpegCompressionArgs(m)...)
+	}
+	// Görüntülerde seçici politikalar exiftool ile ayrıca uygulanır
+	if m.Kind == types.VideoKind || !isSelectivePolicy(m.Search.Metadata) {
+		args = append(args, buildFFmpegMetadataArgs(m.Search.Metadata)...)
 	}
 	args = append(args, "-y", outputPath)
 	return args
 }
 
+// RunLibreOffice belgeyi PDF'e dönüştürür. Aynı adlı belgeler eşzamanlı
+// dönüştürülebildiği için her dönüşüm kendi dizinine yazar; çağıran dizini
+// silmelidir.
 func RunLibreOffice(inputPath string) (string, error) {
-	outputDir := os.TempDir()
-	cmd := exec.Command("soffice", "--headless", "--convert-to", "pdf", inputPath, "--outdir", outputDir)
-	log.Infof("LibreOffice komutu: %s", cmd.String())
-	output, err := cmd.CombinedOutput()
+	outputDir, err := os.MkdirTemp("", "libreoffice-*")
+	if err != nil {
+		return "", err
+	}
+	args := []string{"--headless", "--convert-to", "pdf", inputPath, "--outdir", outputDir}
+	log.Infof("LibreOffice komutu: soffice %s", strings.Join(args, " "))
+	output, err := libreoffice.Run(context.Background(), "soffice", args...)
 	if err != nil {
 		log.Errorf("LibreOffice Hatası: %v, Çıktı: %s", err, string(output))
+		os.RemoveAll(outputDir)
 		return "", err
 	}
 	pdfPath := filepath.Join(outputDir, strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))+".pdf")
 	return pdfPath, nil
 }
 
-func RunMutool(inputPath string, page int) (string, error) {
-	outputFilePath := filepath.Join(os.TempDir(), "page.png")
-	cmd := exec.Command("mutool", "draw", "-o", outputFilePath, "-r", "150", inputPath, strconv.Itoa(page))
+// DefaultRenderDPI PDF sayfalarının varsayılan çizim çözünürlüğüdür
+const DefaultRenderDPI = 150
+
+// mutoolDrawArgs startPage-endPage aralığını dpi çözünürlüğünde çizen mutool draw
+// argümanlarını üretir. outputPattern içindeki %d sayfa numarasıyla değiştirilir.
+func mutoolDrawArgs(inputPath, outputPattern string, startPage, endPage, dpi int) []string {
+	pages := strconv.Itoa(startPage)
+	if endPage > startPage {
+		pages = fmt.Sprintf("%d-%d", startPage, endPage)
+	}
+	return []string{"draw", "-o", outputPattern, "-r", strconv.Itoa(dpi), inputPath, pages}
+}
+
+// RunMutool PDF'in startPage-endPage aralığındaki sayfalarını PNG olarak çizer ve
+// sayfa sırasıyla dosya yollarını döndürür. Eşzamanlı çağrılar çakışmasın diye her
+// çizim kendi geçici dizinine yazar; çağıran dizini silmelidir. dpi sıfırsa
+// DefaultRenderDPI kullanılır.
+func RunMutool(inputPath string, startPage, endPage, dpi int) ([]string, error) {
+	if startPage < 1 || endPage < startPage {
+		return nil, fmt.Errorf("geçersiz sayfa aralığı: %d-%d", startPage, endPage)
+	}
+	if dpi <= 0 {
+		dpi = DefaultRenderDPI
+	}
+
+	outputDir, err := os.MkdirTemp("", "mutool-*")
+	if err != nil {
+		return nil, fmt.Errorf("geçici dizin oluşturulamadı: %w", err)
+	}
+	cmd := exec.Command("mutool", mutoolDrawArgs(inputPath, filepath.Join(outputDir, "page-%d.png"), startPage, endPage, dpi)...)
 	log.Infof("MuPDF komutu: %s", cmd.String())
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Errorf("MuPDF Hatası: %v, Çıktı: %s", err, string(output))
-		return "", err
+		os.RemoveAll(outputDir)
+		return nil, err
+	}
+
+	// mutool belgede olmayan sayfaları yalnızca uyarıyla atlar
+	paths := make([]string, 0, endPage-startPage+1)
+	for page := startPage; page <= endPage; page++ {
+		path := filepath.Join(outputDir, fmt.Sprintf("page-%d.png", page))
+		if _, err := os.Stat(path); err != nil {
+			os.RemoveAll(outputDir)
+			return nil, fmt.Errorf("sayfa %d çizilemedi: %s", page, strings.TrimSpace(string(output)))
+		}
+		paths = append(paths, path)
 	}
-	return outputFilePath, nil
+	return paths, nil
 }
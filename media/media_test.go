@@ -1,9 +1,19 @@
 package media
 
 import (
+	"bytes"
+	"context"
 	"documents-worker/types"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -253,6 +263,51 @@ func TestBuildVipsArgs(t *testing.T) {
 			},
 			expected: []string{"thumbnail", "input.jpg", "output.webp", "200"},
 		},
+		{
+			name: "Progressive JPEG with quality",
+			converter: &types.MediaConverter{
+				Kind:   types.ImageKind,
+				Format: stringPtr("jpg"),
+				Search: types.MediaSearch{
+					Quality:     intPtr(80),
+					Progressive: boolPtr(true),
+				},
+			},
+			expected: []string{"copy", "input.jpg", "output.webp[Q=80,interlace]"},
+		},
+		{
+			name: "Progressive ignored for WEBP",
+			converter: &types.MediaConverter{
+				Kind:   types.ImageKind,
+				Format: stringPtr("webp"),
+				Search: types.MediaSearch{
+					Progressive: boolPtr(true),
+				},
+			},
+			expected: []string{"copy", "input.jpg", "output.webp"},
+		},
+		{
+			name: "Strip all metadata",
+			converter: &types.MediaConverter{
+				Kind:   types.ImageKind,
+				Format: stringPtr("webp"),
+				Search: types.MediaSearch{
+					Metadata: &types.MetadataPolicy{Mode: types.MetadataStripAll},
+				},
+			},
+			expected: []string{"copy", "input.jpg", "output.webp[strip]"},
+		},
+		{
+			name: "Baseline by default",
+			converter: &types.MediaConverter{
+				Kind:   types.ImageKind,
+				Format: stringPtr("png"),
+				Search: types.MediaSearch{
+					Progressive: boolPtr(false),
+				},
+			},
+			expected: []string{"copy", "input.jpg", "output.webp"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -290,6 +345,17 @@ func TestBuildFFmpegArgs(t *testing.T) {
 			},
 			contains: []string{"-i", "input.jpg", "-q:v"},
 		},
+		{
+			name: "Video dropping GPS",
+			converter: &types.MediaConverter{
+				Kind:   types.VideoKind,
+				Format: stringPtr("webm"),
+				Search: types.MediaSearch{
+					Metadata: &types.MetadataPolicy{Mode: types.MetadataDenylist, Tags: []string{"gps"}},
+				},
+			},
+			contains: []string{"-map_metadata", "0", "location=", "com.apple.quicktime.location.ISO6709="},
+		},
 	}
 
 	for _, tt := range tests {
@@ -444,6 +510,415 @@ func TestVideoProcessor(t *testing.T) {
 	t.Logf("Video processor successfully created thumbnail (size: %d bytes)", stat.Size())
 }
 
+func TestSearchQualityForSize(t *testing.T) {
+	// Fake encoder: output size grows linearly with quality (1000 bytes per step)
+	newEncoder := func(dir string, calls *[]int) qualityEncoder {
+		return func(quality int) (string, error) {
+			*calls = append(*calls, quality)
+			path := filepath.Join(dir, fmt.Sprintf("q%d.bin", quality))
+			return path, os.WriteFile(path, make([]byte, quality*1000), 0644)
+		}
+	}
+
+	t.Run("output within tolerance of target", func(t *testing.T) {
+		dir := t.TempDir()
+		var calls []int
+		target := int64(63_500)
+
+		path, result, err := searchQualityForSize(target, 1, 100, newEncoder(dir, &calls))
+		require.NoError(t, err)
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+
+		assert.True(t, result.TargetMet)
+		assert.LessOrEqual(t, info.Size(), target)
+		assert.GreaterOrEqual(t, float64(info.Size()), float64(target)*0.95)
+		assert.Equal(t, info.Size(), result.Size)
+		assert.LessOrEqual(t, result.Iterations, maxTargetSizeIterations)
+		assert.Len(t, calls, result.Iterations)
+
+		// Only the selected output is kept
+		entries, _ := os.ReadDir(dir)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("target below minimum quality returns smallest output", func(t *testing.T) {
+		dir := t.TempDir()
+		var calls []int
+
+		path, result, err := searchQualityForSize(500, 1, 100, newEncoder(dir, &calls))
+		require.NoError(t, err)
+
+		assert.False(t, result.TargetMet)
+		assert.Equal(t, 1, result.Quality)
+		assert.Equal(t, int64(1000), result.Size)
+		assert.FileExists(t, path)
+
+		entries, _ := os.ReadDir(dir)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("quality cap is respected", func(t *testing.T) {
+		dir := t.TempDir()
+		var calls []int
+
+		_, result, err := searchQualityForSize(1_000_000, 1, 80, newEncoder(dir, &calls))
+		require.NoError(t, err)
+
+		assert.True(t, result.TargetMet)
+		assert.Equal(t, 80, result.Quality)
+		for _, q := range calls {
+			assert.LessOrEqual(t, q, 80)
+		}
+	})
+
+	t.Run("encoder errors are returned", func(t *testing.T) {
+		_, _, err := searchQualityForSize(1000, 1, 100, func(int) (string, error) {
+			return "", fmt.Errorf("vips failed")
+		})
+		assert.Error(t, err)
+	})
+}
+
+// Test progressive/interlaced output with VIPS
+func TestProgressiveOutput(t *testing.T) {
+	if _, err := exec.LookPath("vips"); err != nil {
+		t.Skip("VIPS not available")
+	}
+
+	// Go's encoder always writes baseline JPEG
+	inputPath := filepath.Join(t.TempDir(), "input.jpg")
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 4), uint8(y * 4), 128, 255})
+		}
+	}
+	f, err := os.Create(inputPath)
+	require.NoError(t, err)
+	require.NoError(t, jpeg.Encode(f, img, nil))
+	f.Close()
+
+	tests := []struct {
+		format      string
+		progressive bool
+		check       func([]byte) bool
+	}{
+		{"jpg", true, isProgressiveJPEG},
+		{"jpg", false, func(data []byte) bool { return !isProgressiveJPEG(data) }},
+		{"png", true, isInterlacedPNG},
+		{"png", false, func(data []byte) bool { return !isInterlacedPNG(data) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s progressive=%t", tt.format, tt.progressive), func(t *testing.T) {
+			converter := createTestMediaConverter(types.ImageKind, stringPtr(tt.format))
+			converter.Search.Progressive = boolPtr(tt.progressive)
+
+			outputFile, err := ExecCommand(true, inputPath, converter)
+			require.NoError(t, err)
+			defer outputFile.Close()
+			defer os.Remove(outputFile.Name())
+
+			data, err := os.ReadFile(outputFile.Name())
+			require.NoError(t, err)
+			assert.True(t, tt.check(data))
+		})
+	}
+}
+
+// Test the JPEG/PNG header inspection used by TestProgressiveOutput
+func TestProgressiveDetection(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+
+	var jpg bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpg, img, nil))
+	assert.False(t, isProgressiveJPEG(jpg.Bytes()))
+
+	var pngData bytes.Buffer
+	require.NoError(t, png.Encode(&pngData, img))
+	assert.False(t, isInterlacedPNG(pngData.Bytes()))
+
+	interlaced := append([]byte(nil), pngData.Bytes()...)
+	interlaced[28] = 1
+	assert.True(t, isInterlacedPNG(interlaced))
+}
+
+// isProgressiveJPEG reports whether the JPEG uses a progressive (SOF2) frame
+func isProgressiveJPEG(data []byte) bool {
+	for i := 2; i+3 < len(data); {
+		if data[i] != 0xFF {
+			return false
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xC2:
+			return true
+		case marker == 0xC0 || marker == 0xC1 || marker == 0xDA:
+			return false
+		}
+		i += 2 + int(data[i+2])<<8 + int(data[i+3])
+	}
+	return false
+}
+
+// isInterlacedPNG reports whether the PNG IHDR declares Adam7 interlacing
+func isInterlacedPNG(data []byte) bool {
+	// 8 byte signature, 8 byte chunk header, interlace is the 13th IHDR byte
+	return len(data) > 28 && string(data[12:16]) == "IHDR" && data[28] == 1
+}
+
+// Test metadata policy parsing and tag validation
+func TestParseMetadataPolicy(t *testing.T) {
+	policy, err := ParseMetadataPolicy("", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, policy)
+
+	policy, err = ParseMetadataPolicy("Strip-All", nil)
+	require.NoError(t, err)
+	assert.Equal(t, types.MetadataStripAll, policy.Mode)
+
+	policy, err = ParseMetadataPolicy("allowlist", []string{" Copyright ", "artist", ""})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"copyright", "artist"}, policy.Tags)
+
+	_, err = ParseMetadataPolicy("allowlist", nil)
+	assert.Error(t, err, "selective policies need tags")
+
+	_, err = ParseMetadataPolicy("denylist", []string{"gps", "shoe-size"})
+	assert.ErrorContains(t, err, "shoe-size")
+
+	_, err = ParseMetadataPolicy("keep-some", nil)
+	assert.Error(t, err)
+}
+
+// Test that selective policies keep exactly the requested image tags
+func TestBuildExiftoolArgs(t *testing.T) {
+	allow := &types.MetadataPolicy{Mode: types.MetadataAllowlist, Tags: []string{"copyright"}}
+	assert.Equal(t,
+		[]string{"-overwrite_original", "-q", "-all=", "-tagsFromFile", "@", "-Copyright", "-XMP-dc:Rights", "out.jpg"},
+		buildExiftoolArgs(allow, "out.jpg"))
+
+	deny := &types.MetadataPolicy{Mode: types.MetadataDenylist, Tags: []string{"gps", "camera"}}
+	assert.Equal(t,
+		[]string{"-overwrite_original", "-q", "-GPS:all=", "-Make=", "-Model=", "-LensModel=", "out.jpg"},
+		buildExiftoolArgs(deny, "out.jpg"))
+}
+
+// Test video metadata arguments for every policy mode
+func TestBuildFFmpegMetadataArgs(t *testing.T) {
+	assert.Nil(t, buildFFmpegMetadataArgs(nil))
+	assert.Equal(t, []string{"-map_metadata", "0"},
+		buildFFmpegMetadataArgs(&types.MetadataPolicy{Mode: types.MetadataKeepAll}))
+	assert.Equal(t, []string{"-map_metadata", "-1", "-map_chapters", "-1"},
+		buildFFmpegMetadataArgs(&types.MetadataPolicy{Mode: types.MetadataStripAll}))
+
+	allow := &types.MetadataPolicy{Mode: types.MetadataAllowlist, Tags: []string{"copyright", "title"}}
+	assert.Equal(t, []string{"-map_metadata", "-1"}, buildFFmpegMetadataArgs(allow))
+
+	probed := map[string]string{
+		"COPYRIGHT": "(c) ACME",
+		"title":     "Demo",
+		"location":  "+41.0082+028.9784/",
+		"encoder":   "Lavf60",
+	}
+	assert.Equal(t,
+		[]string{"-metadata", "copyright=(c) ACME", "-metadata", "title=Demo"},
+		allowlistMetadataArgs(allow, probed))
+}
+
+// Test selective retention end to end: keep copyright, drop GPS
+func TestImageMetadataRetention(t *testing.T) {
+	if _, err := exec.LookPath("exiftool"); err != nil {
+		t.Skip("exiftool not available")
+	}
+	if _, err := exec.LookPath("vips"); err != nil {
+		t.Skip("VIPS not available")
+	}
+
+	inputPath := filepath.Join(t.TempDir(), "tagged.jpg")
+	var jpg bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpg, image.NewRGBA(image.Rect(0, 0, 16, 16)), nil))
+	require.NoError(t, os.WriteFile(inputPath, jpg.Bytes(), 0644))
+	require.NoError(t, exec.Command("exiftool", "-overwrite_original", "-Copyright=ACME",
+		"-Artist=Jane", "-GPSLatitude=41.0082", "-GPSLatitudeRef=N", inputPath).Run())
+
+	readTags := func(path string) string {
+		out, err := exec.Command("exiftool", "-s", "-Copyright", "-Artist", "-GPSLatitude", path).Output()
+		require.NoError(t, err)
+		return string(out)
+	}
+
+	tests := []struct {
+		name    string
+		policy  *types.MetadataPolicy
+		present []string
+		absent  []string
+	}{
+		{
+			name:    "Allowlist copyright",
+			policy:  &types.MetadataPolicy{Mode: types.MetadataAllowlist, Tags: []string{"copyright"}},
+			present: []string{"Copyright"},
+			absent:  []string{"Artist", "GPSLatitude"},
+		},
+		{
+			name:    "Denylist GPS",
+			policy:  &types.MetadataPolicy{Mode: types.MetadataDenylist, Tags: []string{"gps"}},
+			present: []string{"Copyright", "Artist"},
+			absent:  []string{"GPSLatitude"},
+		},
+		{
+			name:   "Strip all",
+			policy: &types.MetadataPolicy{Mode: types.MetadataStripAll},
+			absent: []string{"Copyright", "Artist", "GPSLatitude"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := createTestMediaConverter(types.ImageKind, stringPtr("jpg"))
+			converter.Search.Metadata = tt.policy
+
+			outputFile, err := ExecCommand(true, inputPath, converter)
+			require.NoError(t, err)
+			defer os.Remove(outputFile.Name())
+			outputFile.Close()
+
+			tags := readTags(outputFile.Name())
+			for _, tag := range tt.present {
+				assert.Contains(t, tags, tag)
+			}
+			for _, tag := range tt.absent {
+				assert.NotContains(t, tags, tag)
+			}
+		})
+	}
+}
+
+// writeTestPNG writes a gradient test image, optionally painting a square
+// of the given color over its top-left corner
+func writeTestPNG(t *testing.T, width, height, patch int, patchColor color.RGBA) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: 128, A: 255})
+			if x < patch && y < patch {
+				img.Set(x, y, patchColor)
+			}
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), fmt.Sprintf("image-%d-%d.png", patch, patchColor.R))
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	require.NoError(t, png.Encode(file, img))
+	return path
+}
+
+func TestCompareImages(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	base := writeTestPNG(t, 64, 64, 0, white)
+
+	t.Run("identical", func(t *testing.T) {
+		diffFile, result, err := CompareImages(base, base, CompareOptions{Threshold: DefaultCompareThreshold})
+		require.NoError(t, err)
+		defer os.Remove(diffFile.Name())
+		defer diffFile.Close()
+
+		assert.True(t, result.Identical)
+		assert.Equal(t, 1.0, result.SSIM)
+		assert.Zero(t, result.DiffPixels)
+		assert.Zero(t, result.DiffPercentage)
+		assert.Equal(t, 64*64, result.TotalPixels)
+		assert.False(t, result.Resized)
+
+		diff, err := png.Decode(diffFile)
+		require.NoError(t, err)
+		assert.Equal(t, image.Pt(64, 64), diff.Bounds().Size())
+	})
+
+	t.Run("differing", func(t *testing.T) {
+		changed := writeTestPNG(t, 64, 64, 16, white)
+		highlight := color.RGBA{G: 255, A: 255}
+
+		diffFile, result, err := CompareImages(base, changed, CompareOptions{
+			Threshold:      DefaultCompareThreshold,
+			HighlightColor: highlight,
+		})
+		require.NoError(t, err)
+		defer os.Remove(diffFile.Name())
+		defer diffFile.Close()
+
+		assert.False(t, result.Identical)
+		assert.Less(t, result.SSIM, 1.0)
+		assert.Greater(t, result.SSIM, 0.0)
+		assert.Equal(t, 16*16, result.DiffPixels)
+		assert.Equal(t, 6.25, result.DiffPercentage)
+
+		diff, err := png.Decode(diffFile)
+		require.NoError(t, err)
+		assert.Equal(t, highlight, color.RGBAModel.Convert(diff.At(0, 0)))
+		assert.NotEqual(t, highlight, color.RGBAModel.Convert(diff.At(40, 40)))
+	})
+
+	t.Run("threshold ignores small changes", func(t *testing.T) {
+		// The patch pixel at (0,0) differs from the gradient by only 8 per channel
+		slight := writeTestPNG(t, 64, 64, 1, color.RGBA{R: 8, G: 8, B: 128, A: 255})
+
+		diffFile, result, err := CompareImages(base, slight, CompareOptions{Threshold: 0.1})
+		require.NoError(t, err)
+		defer os.Remove(diffFile.Name())
+		diffFile.Close()
+
+		assert.False(t, result.Identical)
+		assert.Zero(t, result.DiffPixels)
+
+		diffFile, result, err = CompareImages(base, slight, CompareOptions{Threshold: 0})
+		require.NoError(t, err)
+		defer os.Remove(diffFile.Name())
+		diffFile.Close()
+		assert.Equal(t, 1, result.DiffPixels)
+	})
+
+	t.Run("invalid threshold", func(t *testing.T) {
+		_, _, err := CompareImages(base, base, CompareOptions{Threshold: 1.5})
+		assert.Error(t, err)
+	})
+
+	t.Run("different sizes", func(t *testing.T) {
+		if _, err := exec.LookPath("vips"); err != nil {
+			t.Skip("vips not available")
+		}
+		small := writeTestPNG(t, 32, 32, 0, white)
+
+		diffFile, result, err := CompareImages(base, small, CompareOptions{Threshold: DefaultCompareThreshold})
+		require.NoError(t, err)
+		defer os.Remove(diffFile.Name())
+		diffFile.Close()
+
+		assert.True(t, result.Resized)
+		assert.Equal(t, 64, result.Width)
+	})
+}
+
+func TestParseHexColor(t *testing.T) {
+	c, err := ParseHexColor("#ff8000")
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 255, G: 128, A: 255}, c)
+
+	c, err = ParseHexColor("00ff0080")
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{G: 255, A: 128}, c)
+
+	_, err = ParseHexColor("#fff")
+	assert.Error(t, err)
+	_, err = ParseHexColor("#gggggg")
+	assert.Error(t, err)
+}
+
 // Helper functions
 func stringPtr(s string) *string {
 	return &s
@@ -453,6 +928,10 @@ func intPtr(i int) *int {
 	return &i
 }
 
+func boolPtr(b bool) *bool {
+	return &b
+}
+
 // Benchmark Tests
 func BenchmarkImageConversionWithVips(b *testing.B) {
 	inputPath := getTestFilePath("test.webp")
@@ -520,3 +999,700 @@ func BenchmarkFFmpegArgs(b *testing.B) {
 		buildFFmpegArgs("input.mp4", "output.webm", converter)
 	}
 }
+
+func TestPDFThumbnailArgs(t *testing.T) {
+	args := pdfThumbnailArgs("in.pdf", "out.png", 3, 128)
+	assert.Equal(t, []string{"draw", "-o", "out.png", "-F", "png", "-w", "128", "-h", "128", "in.pdf", "3"}, args)
+}
+
+func TestRenderPDFThumbnail(t *testing.T) {
+	samplePDF := filepath.Join("testdata", "sample.pdf")
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := RenderPDFThumbnail(context.Background(), "mutool", samplePDF, 0, 128)
+		assert.Error(t, err)
+		_, err = RenderPDFThumbnail(context.Background(), "mutool", samplePDF, 1, MaxPDFThumbnailSize+1)
+		assert.Error(t, err)
+	})
+
+	mutoolPath, err := exec.LookPath("mutool")
+	if err != nil {
+		t.Skip("mutool not available")
+	}
+
+	t.Run("cover page", func(t *testing.T) {
+		thumbnail, err := RenderPDFThumbnail(context.Background(), mutoolPath, samplePDF, 1, 128)
+		require.NoError(t, err)
+		defer os.Remove(thumbnail.Name())
+		defer thumbnail.Close()
+
+		img, err := png.Decode(thumbnail)
+		require.NoError(t, err)
+		bounds := img.Bounds()
+		// The 300x400 page fits the 128px box with its aspect ratio kept
+		assert.Equal(t, 128, bounds.Dy())
+		assert.InDelta(t, 96, bounds.Dx(), 1)
+	})
+
+	t.Run("missing page", func(t *testing.T) {
+		_, err := RenderPDFThumbnail(context.Background(), mutoolPath, samplePDF, 5, 128)
+		assert.Error(t, err)
+	})
+}
+
+func TestBackgroundFor(t *testing.T) {
+	jpg, webp := "jpg", "webp"
+	red := "#ff0000"
+
+	_, flatten, err := backgroundFor(createTestMediaConverter(types.ImageKind, &webp))
+	require.NoError(t, err)
+	assert.False(t, flatten, "alpha-capable outputs keep transparency by default")
+
+	background, flatten, err := backgroundFor(createTestMediaConverter(types.ImageKind, &jpg))
+	require.NoError(t, err)
+	assert.True(t, flatten)
+	assert.Equal(t, color.RGBA{R: 255, G: 255, B: 255, A: 255}, background)
+
+	m := createTestMediaConverter(types.ImageKind, &webp)
+	m.Search.BackgroundColor = &red
+	background, flatten, err = backgroundFor(m)
+	require.NoError(t, err)
+	assert.True(t, flatten)
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, background)
+	assert.Equal(t, []string{"flatten", "in.png", "out.v", "--background", "255 0 0"}, vipsFlattenArgs("in.png", "out.v", background))
+
+	for _, invalid := range []string{"red", "#ff000080", "#12345"} {
+		m.Search.BackgroundColor = &invalid
+		_, _, err = backgroundFor(m)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestFlattenTransparentImage(t *testing.T) {
+	if _, err := exec.LookPath("vips"); err != nil {
+		t.Skip("vips not available")
+	}
+
+	// Fully transparent black pixels
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	inputPath := filepath.Join(t.TempDir(), "transparent.png")
+	file, err := os.Create(inputPath)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, img))
+	file.Close()
+
+	jpg := "jpg"
+	blue := "#0000ff"
+	for name, tc := range map[string]struct {
+		background *string
+		want       color.RGBA
+	}{
+		"default white": {nil, color.RGBA{R: 255, G: 255, B: 255, A: 255}},
+		"custom color":  {&blue, color.RGBA{B: 255, A: 255}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := createTestMediaConverter(types.ImageKind, &jpg)
+			m.Search.BackgroundColor = tc.background
+
+			output, err := ExecCommand(true, inputPath, m)
+			require.NoError(t, err)
+			defer os.Remove(output.Name())
+			defer output.Close()
+
+			decoded, err := jpeg.Decode(output)
+			require.NoError(t, err)
+			r, g, b, _ := decoded.At(8, 8).RGBA()
+			assert.InDelta(t, tc.want.R, r>>8, 8)
+			assert.InDelta(t, tc.want.G, g>>8, 8)
+			assert.InDelta(t, tc.want.B, b>>8, 8)
+		})
+	}
+}
+
+func TestBuildFFmpegColorArgs(t *testing.T) {
+	video := func(color *types.VideoColor) *types.MediaConverter {
+		return &types.MediaConverter{Kind: types.VideoKind, Format: stringPtr("mp4"), Search: types.MediaSearch{VideoColor: color}}
+	}
+
+	// Passthrough by default: no color flags or filters
+	args := buildFFmpegArgs("input.mp4", "output.mp4", video(nil))
+	for _, flag := range []string{"-color_primaries", "-color_trc", "-colorspace", "-vf"} {
+		assert.NotContains(t, args, flag)
+	}
+
+	color, err := ParseVideoColor("BT2020", "smpte2084", "bt2020", "1.2")
+	require.NoError(t, err)
+	args = buildFFmpegArgs("input.mp4", "output.mp4", video(color))
+	assert.Equal(t, []string{
+		"-i", "input.mp4",
+		"-vf", "eq=gamma=1.2",
+		"-color_primaries", "bt2020",
+		"-color_trc", "smpte2084",
+		"-colorspace", "bt2020nc",
+		"-y", "output.mp4",
+	}, args)
+
+	color, err = ParseVideoColor("bt709", "", "", "")
+	require.NoError(t, err)
+	args = buildFFmpegArgs("input.mp4", "output.mp4", video(color))
+	assert.Equal(t, []string{"-i", "input.mp4", "-color_primaries", "bt709", "-y", "output.mp4"}, args)
+}
+
+func TestParseVideoColor(t *testing.T) {
+	color, err := ParseVideoColor("", "", "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, color)
+
+	for _, tc := range [][4]string{
+		{"srgb", "", "", ""},
+		{"", "pq", "", ""},
+		{"", "", "bt601", ""},
+		{"", "", "", "bright"},
+		{"", "", "", "20"},
+	} {
+		_, err := ParseVideoColor(tc[0], tc[1], tc[2], tc[3])
+		assert.Error(t, err, tc)
+	}
+}
+
+func TestVipsTiffPageArgs(t *testing.T) {
+	page := 2
+	m := createTestMediaConverter(types.ImageKind, nil)
+	m.Search.Page = &page
+	assert.Equal(t, []string{"copy", "fax.tif[page=1]", "out.v"}, vipsTiffPageArgs("fax.tif", "out.v", m))
+
+	m.Search.AllPages = true
+	assert.Equal(t, []string{"copy", "fax.tif[n=-1]", "out.v"}, vipsTiffPageArgs("fax.tif", "out.v", m))
+}
+
+func TestSelectTiffPages(t *testing.T) {
+	sampleTIFF := filepath.Join("..", "utils", "testdata", "multipage.tif")
+	samplePDF := filepath.Join("testdata", "sample.pdf")
+
+	t.Run("no selection", func(t *testing.T) {
+		m := createTestMediaConverter(types.ImageKind, nil)
+		path, cleanup, err := selectTiffPages(context.Background(), sampleTIFF, m)
+		defer cleanup()
+		require.NoError(t, err)
+		assert.Equal(t, sampleTIFF, path)
+	})
+
+	t.Run("not a TIFF", func(t *testing.T) {
+		page := 1
+		m := createTestMediaConverter(types.ImageKind, nil)
+		m.Search.Page = &page
+		path, cleanup, err := selectTiffPages(context.Background(), samplePDF, m)
+		defer cleanup()
+		require.NoError(t, err)
+		assert.Equal(t, samplePDF, path)
+	})
+
+	t.Run("missing page", func(t *testing.T) {
+		page := 4
+		m := createTestMediaConverter(types.ImageKind, nil)
+		m.Search.Page = &page
+		_, cleanup, err := selectTiffPages(context.Background(), sampleTIFF, m)
+		defer cleanup()
+		assert.ErrorContains(t, err, "3 sayfa")
+	})
+
+	if _, err := exec.LookPath("vips"); err != nil {
+		t.Skip("VIPS not available")
+	}
+
+	t.Run("single page", func(t *testing.T) {
+		page := 2
+		format := "png"
+		m := createTestMediaConverter(types.ImageKind, &format)
+		m.Search.Page = &page
+		output, err := ExecCommand(true, sampleTIFF, m)
+		require.NoError(t, err)
+		defer os.Remove(output.Name())
+		defer output.Close()
+
+		img, err := png.Decode(output)
+		require.NoError(t, err)
+		assert.Equal(t, 8, img.Bounds().Dy())
+	})
+
+	t.Run("all pages", func(t *testing.T) {
+		format := "png"
+		m := createTestMediaConverter(types.ImageKind, &format)
+		m.Search.AllPages = true
+		output, err := ExecCommand(true, sampleTIFF, m)
+		require.NoError(t, err)
+		defer os.Remove(output.Name())
+		defer output.Close()
+
+		img, err := png.Decode(output)
+		require.NoError(t, err)
+		// The three 8px pages are stacked vertically
+		assert.Equal(t, 24, img.Bounds().Dy())
+	})
+}
+
+func TestOrientationArgs(t *testing.T) {
+	for _, tc := range []struct {
+		degrees int
+		vips    string
+		ffmpeg  string
+	}{
+		{90, "d90", "transpose=clock"},
+		{180, "d180", "hflip,vflip"},
+		{270, "d270", "transpose=cclock"},
+	} {
+		t.Run(fmt.Sprintf("rotate %d", tc.degrees), func(t *testing.T) {
+			m := createTestMediaConverter(types.ImageKind, stringPtr("webp"))
+			m.Search.Rotate = intPtr(tc.degrees)
+
+			assert.Equal(t, [][]string{{"rot", tc.vips}}, vipsOrientSteps(m))
+			assert.Equal(t, []string{"-noautorotate", "-i", "input.jpg", "-vf", tc.ffmpeg, "-y", "output.webp"},
+				buildFFmpegArgs("input.jpg", "output.webp", m))
+		})
+	}
+
+	t.Run("auto orient before rotation and thumbnail", func(t *testing.T) {
+		m := createTestMediaConverter(types.ImageKind, stringPtr("webp"))
+		m.Search.AutoOrient = true
+		m.Search.Rotate = intPtr(90)
+		m.Search.Width = intPtr(200)
+
+		assert.Equal(t, [][]string{{"autorot"}, {"rot", "d90"}}, vipsOrientSteps(m))
+		// The thumbnail runs on the oriented intermediate and must not rotate again
+		assert.Equal(t, []string{"thumbnail", "oriented.v", "output.webp", "200", "--no-rotate"},
+			buildVipsArgs("oriented.v", "output.webp", m))
+		assert.Equal(t, []string{"-autorotate", "-i", "input.jpg", "-vf", "transpose=clock,scale=200:-1", "-y", "output.webp"},
+			buildFFmpegArgs("input.jpg", "output.webp", m))
+	})
+
+	t.Run("rotation before crop", func(t *testing.T) {
+		m := createTestMediaConverter(types.ImageKind, stringPtr("webp"))
+		m.Search.Rotate = intPtr(270)
+		m.Search.Crop = stringPtr("10:10:100:100")
+
+		assert.Equal(t, []string{"extract_area", "oriented.v", "output.webp", "10", "10", "100", "100"},
+			buildVipsArgs("oriented.v", "output.webp", m))
+		assert.Equal(t, []string{"-noautorotate", "-i", "input.jpg", "-vf", "transpose=cclock,crop=10:10:100:100", "-y", "output.webp"},
+			buildFFmpegArgs("input.jpg", "output.webp", m))
+	})
+
+	t.Run("no orientation", func(t *testing.T) {
+		m := createTestMediaConverter(types.ImageKind, stringPtr("webp"))
+		m.Search.Width = intPtr(200)
+		assert.Empty(t, vipsOrientSteps(m))
+		assert.Equal(t, []string{"thumbnail", "input.jpg", "output.webp", "200"}, buildVipsArgs("input.jpg", "output.webp", m))
+	})
+
+	t.Run("parse rotation", func(t *testing.T) {
+		degrees, err := ParseRotation("180")
+		require.NoError(t, err)
+		assert.Equal(t, 180, degrees)
+		for _, invalid := range []string{"45", "-90", "360", "90deg", "left"} {
+			_, err := ParseRotation(invalid)
+			assert.Error(t, err, invalid)
+		}
+	})
+}
+
+// fakeVips logs the operation it runs and writes a small output, dropping
+// the save options VIPS takes after the output path
+const fakeVips = `#!/bin/sh
+echo "$1" >> "$VIPS_LOG"
+out="$3"
+printf 'vips-output' > "${out%%[*}"
+`
+
+func TestTargetSizeOrientsOnce(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vips"), []byte(fakeVips), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	logPath := filepath.Join(dir, "vips.log")
+	t.Setenv("VIPS_LOG", logPath)
+
+	input := filepath.Join(dir, "input.jpg")
+	require.NoError(t, os.WriteFile(input, []byte("jpeg"), 0644))
+
+	m := createTestMediaConverter(types.ImageKind, stringPtr("webp"))
+	m.Search.Rotate = intPtr(90)
+	m.Search.AutoOrient = true
+	m.Search.TargetSize = intPtr(1000)
+
+	file, err := ExecCommandContext(context.Background(), true, input, m)
+	require.NoError(t, err)
+	file.Close()
+	os.Remove(file.Name())
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	operations := strings.Fields(string(data))
+	// The input is oriented once before the search; its attempts only encode
+	require.Greater(t, len(operations), 3, "the search makes several attempts")
+	assert.Equal(t, []string{"autorot", "rot"}, operations[:2])
+	for _, operation := range operations[2:] {
+		assert.Equal(t, "copy", operation)
+	}
+	assert.Equal(t, 90, *m.Search.Rotate, "the request is left unchanged")
+
+	t.Run("ffmpeg attempts keep orientation", func(t *testing.T) {
+		attempt := targetSizeAttempt(false, m, 50)
+		assert.Equal(t, 90, *attempt.Search.Rotate)
+		assert.True(t, attempt.Search.AutoOrient)
+		assert.Nil(t, attempt.Search.TargetSize)
+		assert.Equal(t, 50, *attempt.Search.Quality)
+	})
+}
+
+func TestBuildFFmpegAnimationArgs(t *testing.T) {
+	t.Run("gif preview", func(t *testing.T) {
+		m := createTestMediaConverter(types.VideoKind, stringPtr("gif"))
+		m.Search.CutVideo = stringPtr("5:3")
+		m.Search.Width = intPtr(320)
+
+		args := buildFFmpegArgs("input.mp4", "output.gif", m)
+		assert.Equal(t, []string{
+			"-i", "input.mp4", "-ss", "5", "-t", "3",
+			"-vf", "fps=10,scale=320:-1:flags=lanczos,split[s0][s1];[s0]palettegen[p];[s1][p]paletteuse",
+			"-loop", "0", "-an", "-y", "output.gif",
+		}, args)
+	})
+
+	t.Run("webp preview", func(t *testing.T) {
+		m := createTestMediaConverter(types.VideoKind, stringPtr("webp"))
+		m.Search.Height = intPtr(180)
+		m.Search.FPS = intPtr(15)
+
+		args := buildFFmpegArgs("input.mp4", "output.webp", m)
+		assert.Equal(t, []string{
+			"-i", "input.mp4",
+			"-vf", "fps=15,scale=-1:180:flags=lanczos",
+			"-c:v", "libwebp", "-quality", "75", "-loop", "0", "-an", "-y", "output.webp",
+		}, args)
+		assert.NotContains(t, args, "palettegen")
+	})
+
+	t.Run("frame rate is capped", func(t *testing.T) {
+		m := createTestMediaConverter(types.VideoKind, stringPtr("gif"))
+		m.Search.FPS = intPtr(120)
+		assert.Equal(t, MaxAnimationFPS, animationFPS(m))
+		m.Search.FPS = intPtr(0)
+		assert.Equal(t, DefaultAnimationFPS, animationFPS(m))
+	})
+
+	t.Run("webm stays a video", func(t *testing.T) {
+		m := createTestMediaConverter(types.VideoKind, stringPtr("webm"))
+		args := buildFFmpegArgs("input.mp4", "output.webm", m)
+		assert.NotContains(t, args, "-loop")
+		assert.False(t, isAnimatedOutput(createTestMediaConverter(types.ImageKind, stringPtr("gif"))))
+	})
+}
+
+func TestBuildVipsSharpenArgs(t *testing.T) {
+	sigma := 0.8
+	m := createTestMediaConverter(types.ImageKind, stringPtr("jpg"))
+	m.Search.Width = intPtr(200)
+	m.Search.Quality = intPtr(85)
+	m.Search.Sharpen = &sigma
+
+	require.True(t, sharpens(m))
+	// The resize writes a plain intermediate; save options go on the sharpened output
+	assert.Equal(t, []string{"thumbnail", "input.jpg", "resized.v", "200"}, buildVipsOperationArgs("input.jpg", "resized.v", m))
+	assert.Equal(t, []string{"sharpen", "resized.v", "output.jpg[Q=85]", "--sigma", "0.8"}, buildVipsSharpenArgs("resized.v", "output.jpg", m))
+
+	t.Run("off by default", func(t *testing.T) {
+		m := createTestMediaConverter(types.ImageKind, stringPtr("jpg"))
+		m.Search.Width = intPtr(200)
+		assert.False(t, sharpens(m))
+	})
+
+	t.Run("only after resizing", func(t *testing.T) {
+		m := createTestMediaConverter(types.ImageKind, stringPtr("jpg"))
+		m.Search.Sharpen = &sigma
+		assert.False(t, sharpens(m))
+		m.Search.ResizeScale = intPtr(50)
+		assert.True(t, sharpens(m))
+	})
+
+	t.Run("parse strength", func(t *testing.T) {
+		value, err := ParseSharpen("1.5")
+		require.NoError(t, err)
+		assert.Equal(t, 1.5, value)
+		for _, invalid := range []string{"0", "11", "soft"} {
+			_, err := ParseSharpen(invalid)
+			assert.Error(t, err, invalid)
+		}
+	})
+}
+
+func TestCompressionEffortArgs(t *testing.T) {
+	vipsCases := []struct {
+		format string
+		effort int
+		output string
+	}{
+		{"webp", 6, "output.webp[effort=6]"},
+		{"avif", 9, "output.avif[effort=9]"},
+		{"heic", 4, "output.heic[effort=4]"},
+		{"jxl", 7, "output.jxl[effort=7]"},
+		{"gif", 10, "output.gif[effort=10]"},
+		{"png", 9, "output.png[compression=9]"},
+	}
+	for _, tc := range vipsCases {
+		t.Run("vips "+tc.format, func(t *testing.T) {
+			m := createTestMediaConverter(types.ImageKind, stringPtr(tc.format))
+			m.Search.CompressionEffort = intPtr(tc.effort)
+			require.NoError(t, ValidateCompressionEffort(true, m))
+			assert.Equal(t, []string{"copy", "input.png", tc.output}, buildVipsArgs("input.png", "output."+tc.format, m))
+		})
+	}
+
+	t.Run("vips with quality", func(t *testing.T) {
+		m := createTestMediaConverter(types.ImageKind, stringPtr("webp"))
+		m.Search.Quality = intPtr(80)
+		m.Search.CompressionEffort = intPtr(4)
+		assert.Equal(t, []string{"copy", "input.png", "output.webp[Q=80,effort=4]"}, buildVipsArgs("input.png", "output.webp", m))
+	})
+
+	ffmpegCases := []struct {
+		name   string
+		kind   types.MediaKind
+		format string
+		effort int
+		args   []string
+	}{
+		// -cpu-used runs the other way: more effort is a lower value
+		{"avif image", types.ImageKind, "avif", 6, []string{"-cpu-used", "2"}},
+		{"webp image", types.ImageKind, "webp", 5, []string{"-compression_level", "5"}},
+		{"png image", types.ImageKind, "png", 9, []string{"-compression_level", "9"}},
+		{"webm video", types.VideoKind, "webm", 5, []string{"-cpu-used", "0"}},
+		{"animated webp", types.VideoKind, "webp", 3, []string{"-compression_level", "3"}},
+	}
+	for _, tc := range ffmpegCases {
+		t.Run("ffmpeg "+tc.name, func(t *testing.T) {
+			m := createTestMediaConverter(tc.kind, stringPtr(tc.format))
+			m.Search.CompressionEffort = intPtr(tc.effort)
+			require.NoError(t, ValidateCompressionEffort(false, m))
+			args := buildFFmpegArgs("input", "output."+tc.format, m)
+			assert.Equal(t, tc.args, args[len(args)-4:len(args)-2])
+		})
+	}
+
+	t.Run("unset", func(t *testing.T) {
+		m := createTestMediaConverter(types.ImageKind, stringPtr("webp"))
+		assert.NoError(t, ValidateCompressionEffort(true, m))
+		assert.Equal(t, []string{"copy", "input.png", "output.webp"}, buildVipsArgs("input.png", "output.webp", m))
+		assert.Empty(t, buildFFmpegCompressionArgs(m))
+	})
+
+	t.Run("validated per format", func(t *testing.T) {
+		invalid := []struct {
+			vips   bool
+			kind   types.MediaKind
+			format string
+			effort int
+		}{
+			{true, types.ImageKind, "webp", 7},
+			{true, types.ImageKind, "avif", -1},
+			{true, types.ImageKind, "gif", 0},
+			{true, types.ImageKind, "jpg", 3},
+			{false, types.ImageKind, "avif", 9},
+			{false, types.ImageKind, "jpg", 1},
+			{false, types.VideoKind, "webm", 6},
+			{false, types.VideoKind, "gif", 1},
+		}
+		for _, tc := range invalid {
+			m := createTestMediaConverter(tc.kind, stringPtr(tc.format))
+			m.Search.CompressionEffort = intPtr(tc.effort)
+			assert.Error(t, ValidateCompressionEffort(tc.vips, m), "%s %d", tc.format, tc.effort)
+		}
+	})
+}
+
+func TestContactSheetArgs(t *testing.T) {
+	args := contactSheetArgs("input.mp4", "sheet.jpg", 3, 3, 160, 90)
+	assert.Equal(t, []string{
+		"-i", "input.mp4",
+		"-vf", "select='isnan(prev_selected_t)+gte(t-prev_selected_t,10.000)',scale=160:-2,tile=3x3",
+		"-frames:v", "1",
+		"-fps_mode", "vfr",
+		"-q:v", "3",
+		"-y", "sheet.jpg",
+	}, args)
+
+	// Short clips still spread the frames over their whole duration
+	short := contactSheetArgs("input.mp4", "sheet.jpg", 3, 3, 160, 1.8)
+	assert.Contains(t, short[3], "gte(t-prev_selected_t,0.200)")
+
+	t.Run("invalid grid", func(t *testing.T) {
+		_, err := GenerateContactSheet("input.mp4", 0, 3, 160)
+		assert.Error(t, err)
+		_, err = GenerateContactSheet("input.mp4", 11, 10, 160)
+		assert.Error(t, err)
+		_, err = GenerateContactSheet("input.mp4", 3, 3, MaxContactSheetThumbWidth+1)
+		assert.Error(t, err)
+	})
+}
+
+func TestMutoolDrawArgs(t *testing.T) {
+	assert.Equal(t, []string{"draw", "-o", "out/page-%d.png", "-r", "150", "in.pdf", "3"},
+		mutoolDrawArgs("in.pdf", "out/page-%d.png", 3, 3, DefaultRenderDPI))
+	assert.Equal(t, []string{"draw", "-o", "out/page-%d.png", "-r", "300", "in.pdf", "2-5"},
+		mutoolDrawArgs("in.pdf", "out/page-%d.png", 2, 5, 300))
+
+	_, err := RunMutool("in.pdf", 0, 1, 0)
+	assert.Error(t, err)
+	_, err = RunMutool("in.pdf", 3, 2, 0)
+	assert.Error(t, err)
+}
+
+func TestRunMutoolPageRange(t *testing.T) {
+	if _, err := exec.LookPath("mutool"); err != nil {
+		t.Skip("mutool not available")
+	}
+	samplePDF := filepath.Join("testdata", "sample.pdf")
+
+	first, err := RunMutool(samplePDF, 1, 1, 72)
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Dir(first[0]))
+	second, err := RunMutool(samplePDF, 1, 1, 72)
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Dir(second[0]))
+
+	// Concurrent renders of the same page never share a file
+	require.Len(t, first, 1)
+	assert.NotEqual(t, first[0], second[0])
+	assert.FileExists(t, first[0])
+}
+
+func TestParseStreamLadder(t *testing.T) {
+	ladder, err := ParseStreamLadder("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultStreamLadder, ladder)
+
+	ladder, err = ParseStreamLadder("720:2800k, 360:800")
+	require.NoError(t, err)
+	assert.Equal(t, []StreamRendition{{Height: 720, BitrateKbps: 2800}, {Height: 360, BitrateKbps: 800}}, ladder)
+
+	for _, value := range []string{"720", "abc:800", "720:fast", "721:800", "720:10", "4320:8000", "1:1,2:2,3:3,4:4,5:5,6:6,7:7"} {
+		_, err := ParseStreamLadder(value)
+		assert.Error(t, err, value)
+	}
+
+	format, err := ParseStreamFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, StreamFormatHLS, format)
+	_, err = ParseStreamFormat("smooth")
+	assert.Error(t, err)
+}
+
+func TestFitStreamLadder(t *testing.T) {
+	assert.Equal(t, DefaultStreamLadder[1:], fitStreamLadder(DefaultStreamLadder, 720))
+	assert.Equal(t, DefaultStreamLadder, fitStreamLadder(DefaultStreamLadder, 2160))
+
+	// Sources below every step keep the lowest bitrate at their own height
+	assert.Equal(t, []StreamRendition{{Height: 240, BitrateKbps: 800}}, fitStreamLadder(DefaultStreamLadder, 241))
+}
+
+func TestStreamArgs(t *testing.T) {
+	ladder := []StreamRendition{{Height: 720, BitrateKbps: 2800}, {Height: 360, BitrateKbps: 800}}
+
+	hls := streamArgs("in.mp4", "out", StreamOptions{Format: StreamFormatHLS, Ladder: ladder, SegmentSeconds: 4}, true)
+	assert.Equal(t, []string{"-i", "in.mp4", "-filter_complex", "[0:v]split=2[v0][v1];[v0]scale=-2:720[v0out];[v1]scale=-2:360[v1out]"}, hls[:4])
+	assert.Contains(t, strings.Join(hls, " "), "-map [v1out] -c:v:1 libx264 -b:v:1 800k -maxrate:v:1 856k -bufsize:v:1 1200k")
+	assert.Contains(t, strings.Join(hls, " "), "-map 0:a:0 -map 0:a:0 -c:a aac")
+	assert.Contains(t, strings.Join(hls, " "), "-force_key_frames expr:gte(t,n_forced*4)")
+	assert.Equal(t, []string{
+		"-f", "hls",
+		"-hls_time", "4",
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join("out", "stream_%v_%03d.ts"),
+		"-master_pl_name", "master.m3u8",
+		"-var_stream_map", "v:0,a:0 v:1,a:1",
+		"-y", filepath.Join("out", "stream_%v.m3u8"),
+	}, hls[len(hls)-14:])
+
+	silent := streamArgs("in.mp4", "out", StreamOptions{Format: StreamFormatHLS, Ladder: ladder, SegmentSeconds: 4}, false)
+	assert.NotContains(t, silent, "0:a:0")
+	assert.Contains(t, silent, "v:0 v:1")
+
+	dash := strings.Join(streamArgs("in.mp4", "out", StreamOptions{Format: StreamFormatDASH, Ladder: ladder, SegmentSeconds: 4}, true), " ")
+	assert.Equal(t, 1, strings.Count(dash, "-map 0:a:0"))
+	assert.Contains(t, dash, "-adaptation_sets id=0,streams=v id=1,streams=a")
+	assert.True(t, strings.HasSuffix(dash, filepath.Join("out", "manifest.mpd")))
+}
+
+// writeStreamFiles writes the named files with placeholder content
+func writeStreamFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func TestVerifyStreamPackage(t *testing.T) {
+	t.Run("hls", func(t *testing.T) {
+		dir := t.TempDir()
+		writeStreamFiles(t, dir, map[string]string{
+			"master.m3u8":     "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=2800000\nstream_0.m3u8\n#EXT-X-STREAM-INF:BANDWIDTH=800000\nstream_1.m3u8\n",
+			"stream_0.m3u8":   "#EXTM3U\n#EXTINF:4.0,\nstream_0_000.ts\n#EXTINF:2.0,\nstream_0_001.ts\n#EXT-X-ENDLIST\n",
+			"stream_1.m3u8":   "#EXTM3U\n#EXTINF:4.0,\nstream_1_000.ts\n#EXT-X-ENDLIST\n",
+			"stream_0_000.ts": "ts", "stream_0_001.ts": "ts", "stream_1_000.ts": "ts",
+		})
+
+		pkg, err := VerifyStreamPackage(dir, StreamFormatHLS)
+		require.NoError(t, err)
+		assert.Equal(t, "master.m3u8", pkg.Manifest)
+		assert.Equal(t, []string{"master.m3u8", "stream_0.m3u8", "stream_0_000.ts", "stream_0_001.ts", "stream_1.m3u8", "stream_1_000.ts"}, pkg.Files)
+
+		// A segment the playlist names but ffmpeg did not write
+		require.NoError(t, os.Remove(filepath.Join(dir, "stream_0_001.ts")))
+		_, err = VerifyStreamPackage(dir, StreamFormatHLS)
+		assert.ErrorContains(t, err, "stream_0_001.ts")
+	})
+
+	t.Run("hls outside the package", func(t *testing.T) {
+		dir := t.TempDir()
+		writeStreamFiles(t, dir, map[string]string{
+			"master.m3u8":   "#EXTM3U\nstream_0.m3u8\n",
+			"stream_0.m3u8": "#EXTM3U\n../secret.ts\n",
+		})
+		_, err := VerifyStreamPackage(dir, StreamFormatHLS)
+		assert.Error(t, err)
+	})
+
+	t.Run("dash", func(t *testing.T) {
+		dir := t.TempDir()
+		writeStreamFiles(t, dir, map[string]string{
+			"manifest.mpd": `<?xml version="1.0"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011"><Period>
+<AdaptationSet id="0" contentType="video">
+<Representation id="0"><SegmentTemplate initialization="init_$RepresentationID$.m4s" media="chunk_$RepresentationID$_$Number%05d$.m4s" startNumber="1">
+<SegmentTimeline><S t="0" d="4" r="1"/><S d="2"/></SegmentTimeline></SegmentTemplate></Representation>
+</AdaptationSet>
+<AdaptationSet id="1" contentType="audio">
+<SegmentTemplate initialization="init_$RepresentationID$.m4s" media="chunk_$RepresentationID$_$Number%05d$.m4s" startNumber="1">
+<SegmentTimeline><S t="0" d="6"/></SegmentTimeline></SegmentTemplate>
+<Representation id="1"/>
+</AdaptationSet>
+</Period></MPD>`,
+			"init_0.m4s": "i", "chunk_0_00001.m4s": "s", "chunk_0_00002.m4s": "s", "chunk_0_00003.m4s": "s",
+			"init_1.m4s": "i", "chunk_1_00001.m4s": "s",
+		})
+
+		pkg, err := VerifyStreamPackage(dir, StreamFormatDASH)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"manifest.mpd", "init_0.m4s", "chunk_0_00001.m4s", "chunk_0_00002.m4s", "chunk_0_00003.m4s", "init_1.m4s", "chunk_1_00001.m4s"}, pkg.Files)
+
+		require.NoError(t, os.Remove(filepath.Join(dir, "chunk_0_00003.m4s")))
+		_, err = VerifyStreamPackage(dir, StreamFormatDASH)
+		assert.ErrorContains(t, err, "chunk_0_00003.m4s")
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := GenerateStreamPackage(context.Background(), "in.mp4", StreamOptions{Format: "smooth"})
+		assert.Error(t, err)
+		_, err = GenerateStreamPackage(context.Background(), "in.mp4", StreamOptions{SegmentSeconds: 61})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,199 @@
+package media
+
+import (
+	"bytes"
+	"documents-worker/types"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// metadataTag, taşınabilir bir etiket adının görüntüde (exiftool) ve videoda
+// (ffmpeg) karşılık geldiği alanları tutar.
+type metadataTag struct {
+	exif   []string
+	ffmpeg []string
+}
+
+// metadataTags, politika içinde kullanılabilecek etiket adlarıdır:
+//
+//	copyright   telif hakkı (EXIF Copyright, XMP dc:rights)
+//	artist      yazar / sanatçı (EXIF Artist, XMP dc:creator)
+//	title       başlık ve açıklama
+//	comment     kullanıcı yorumu
+//	date        çekim / oluşturma tarihleri
+//	gps         konum bilgisi (tüm GPS grubu)
+//	camera      cihaz marka, model ve lens bilgisi
+//	software    üreten yazılım / kodlayıcı
+//	orientation döndürme bilgisi
+//	icc         renk profili (yalnızca görüntü)
+var metadataTags = map[string]metadataTag{
+	"copyright":   {exif: []string{"Copyright", "XMP-dc:Rights"}, ffmpeg: []string{"copyright"}},
+	"artist":      {exif: []string{"Artist", "XMP-dc:Creator"}, ffmpeg: []string{"artist", "author"}},
+	"title":       {exif: []string{"ImageDescription", "XMP-dc:Title", "XMP-dc:Description"}, ffmpeg: []string{"title", "description"}},
+	"comment":     {exif: []string{"UserComment", "XPComment"}, ffmpeg: []string{"comment"}},
+	"date":        {exif: []string{"DateTimeOriginal", "CreateDate", "ModifyDate"}, ffmpeg: []string{"creation_time", "date"}},
+	"gps":         {exif: []string{"GPS:all"}, ffmpeg: []string{"location", "location-eng", "com.apple.quicktime.location.ISO6709"}},
+	"camera":      {exif: []string{"Make", "Model", "LensModel"}, ffmpeg: []string{"make", "model", "com.apple.quicktime.make", "com.apple.quicktime.model"}},
+	"software":    {exif: []string{"Software"}, ffmpeg: []string{"encoder", "com.apple.quicktime.software"}},
+	"orientation": {exif: []string{"Orientation"}, ffmpeg: []string{"rotate"}},
+	"icc":         {exif: []string{"ICC_Profile:all"}},
+}
+
+// MetadataTagNames desteklenen etiket adlarını sıralı döndürür.
+func MetadataTagNames() []string {
+	names := make([]string, 0, len(metadataTags))
+	for name := range metadataTags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseMetadataPolicy mod ve etiket listesinden doğrulanmış bir politika oluşturur.
+// Boş mod nil döndürür; bu durumda araçların varsayılan davranışı korunur.
+func ParseMetadataPolicy(mode string, tags []string) (*types.MetadataPolicy, error) {
+	if mode == "" {
+		return nil, nil
+	}
+
+	policy := &types.MetadataPolicy{Mode: types.MetadataMode(strings.ToLower(mode))}
+	switch policy.Mode {
+	case types.MetadataKeepAll, types.MetadataStripAll:
+		return policy, nil
+	case types.MetadataAllowlist, types.MetadataDenylist:
+	default:
+		return nil, fmt.Errorf("geçersiz metadata politikası: %s (keep-all, strip-all, allowlist, denylist)", mode)
+	}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if _, ok := metadataTags[tag]; !ok {
+			return nil, fmt.Errorf("bilinmeyen metadata etiketi: %s (desteklenenler: %s)", tag, strings.Join(MetadataTagNames(), ", "))
+		}
+		policy.Tags = append(policy.Tags, tag)
+	}
+	if len(policy.Tags) == 0 {
+		return nil, fmt.Errorf("%s politikası en az bir etiket gerektirir", policy.Mode)
+	}
+	return policy, nil
+}
+
+// isSelectivePolicy, politikanın etiket bazında işlem gerektirip gerektirmediğini belirtir.
+func isSelectivePolicy(p *types.MetadataPolicy) bool {
+	return p != nil && (p.Mode == types.MetadataAllowlist || p.Mode == types.MetadataDenylist)
+}
+
+// buildExiftoolArgs, seçici politikayı çıktı görüntüsüne uygulayan exiftool argümanlarını üretir.
+func buildExiftoolArgs(p *types.MetadataPolicy, outputPath string) []string {
+	args := []string{"-overwrite_original", "-q"}
+
+	switch p.Mode {
+	case types.MetadataAllowlist:
+		// Önce her şeyi sil, ardından izin verilen etiketleri dosyanın kendisinden geri kopyala
+		args = append(args, "-all=", "-tagsFromFile", "@")
+		for _, tag := range p.Tags {
+			for _, field := range metadataTags[tag].exif {
+				args = append(args, "-"+field)
+			}
+		}
+	case types.MetadataDenylist:
+		for _, tag := range p.Tags {
+			for _, field := range metadataTags[tag].exif {
+				args = append(args, "-"+field+"=")
+			}
+		}
+	}
+
+	return append(args, outputPath)
+}
+
+// applyImageMetadataPolicy, seçici politikayı exiftool ile çıktı dosyasına uygular.
+func applyImageMetadataPolicy(p *types.MetadataPolicy, outputPath string) error {
+	cmd := exec.Command("exiftool", buildExiftoolArgs(p, outputPath)...)
+	log.Infof("Metadata komutu: %s", cmd.String())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Errorf("exiftool Hatası: %v, Çıktı: %s", err, string(output))
+		return fmt.Errorf("metadata politikası uygulanamadı: %w", err)
+	}
+	return nil
+}
+
+// buildFFmpegMetadataArgs, politikaya göre ffmpeg metadata argümanlarını üretir.
+// Allowlist tüm etiketleri düşürür; izin verilenler allowlistMetadataArgs ile geri eklenir.
+func buildFFmpegMetadataArgs(p *types.MetadataPolicy) []string {
+	if p == nil {
+		return nil
+	}
+
+	switch p.Mode {
+	case types.MetadataKeepAll:
+		return []string{"-map_metadata", "0"}
+	case types.MetadataStripAll:
+		return []string{"-map_metadata", "-1", "-map_chapters", "-1"}
+	case types.MetadataDenylist:
+		args := []string{"-map_metadata", "0"}
+		for _, tag := range p.Tags {
+			for _, key := range metadataTags[tag].ffmpeg {
+				// Boş değer anahtarı çıktıdan kaldırır
+				args = append(args, "-metadata", key+"=")
+			}
+		}
+		return args
+	case types.MetadataAllowlist:
+		return []string{"-map_metadata", "-1"}
+	}
+	return nil
+}
+
+// allowlistMetadataArgs, kaynaktan okunan (probed) etiketlerden izin verilenleri
+// yeniden yazan argümanları üretir; ffmpeg seçici eşleme yapamadığı için gereklidir.
+func allowlistMetadataArgs(p *types.MetadataPolicy, probed map[string]string) []string {
+	var args []string
+	for _, tag := range p.Tags {
+		for _, key := range metadataTags[tag].ffmpeg {
+			if value, ok := lookupTag(probed, key); ok {
+				args = append(args, "-metadata", key+"="+value)
+			}
+		}
+	}
+	return args
+}
+
+// lookupTag, ffprobe etiketlerinde büyük/küçük harf duyarsız arama yapar.
+func lookupTag(tags map[string]string, key string) (string, bool) {
+	for k, v := range tags {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// probeMetadataTags, kaynak dosyanın kapsayıcı etiketlerini ffprobe ile okur.
+func probeMetadataTags(inputPath string) (map[string]string, error) {
+	cmd := exec.Command("ffprobe", "-v", "quiet", "-print_format", "json", "-show_entries", "format_tags", inputPath)
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffprobe etiketleri okunamadı: %w", err)
+	}
+
+	var probe struct {
+		Format struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &probe); err != nil {
+		return nil, fmt.Errorf("ffprobe çıktısı çözümlenemedi: %w", err)
+	}
+	return probe.Format.Tags, nil
+}
@@ -0,0 +1,116 @@
+package media
+
+import (
+	"context"
+	"documents-worker/types"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// vipsRotateAngles, desteklenen döndürme açılarının VIPS rot karşılıklarıdır
+var vipsRotateAngles = map[int]string{90: "d90", 180: "d180", 270: "d270"}
+
+// ffmpegRotateFilters, döndürme açılarının saat yönündeki FFmpeg filtreleridir
+var ffmpegRotateFilters = map[int]string{90: "transpose=clock", 180: "hflip,vflip", 270: "transpose=cclock"}
+
+// ParseRotation döndürme açısını doğrular; yalnızca 90, 180 ve 270 derece desteklenir.
+func ParseRotation(value string) (int, error) {
+	degrees, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || vipsRotateAngles[degrees] == "" {
+		return 0, fmt.Errorf("geçersiz döndürme açısı: %q (90, 180 veya 270 olmalı)", value)
+	}
+	return degrees, nil
+}
+
+// handlesOrientation, yönlendirmenin açıkça istenip istenmediğini belirtir
+func handlesOrientation(m *types.MediaConverter) bool {
+	return m.Search.AutoOrient || m.Search.Rotate != nil
+}
+
+// vipsOrientSteps uygulanacak VIPS yönlendirme işlemlerini, girdi ve çıktı yolları
+// olmadan sırasıyla döndürür: önce EXIF yönü düzeltilir, ardından istenen açıyla
+// döndürülür. Böylece döndürme görsel olarak doğru görüntüye uygulanır.
+func vipsOrientSteps(m *types.MediaConverter) [][]string {
+	var steps [][]string
+	if m.Search.AutoOrient {
+		steps = append(steps, []string{"autorot"})
+	}
+	if m.Search.Rotate != nil {
+		steps = append(steps, []string{"rot", vipsRotateAngles[*m.Search.Rotate]})
+	}
+	return steps
+}
+
+// prepareImage VIPS girdisini dönüştürmeye hazırlar: çok sayfalı TIFF girdilerinde
+// istenen sayfalar ayrı bir görüntüye alınır, ardından görüntü yönlendirilir.
+// Böylece boyutlandırma ve kırpma görsel olarak doğru yönlü görüntüye uygulanır.
+func prepareImage(ctx context.Context, inputPath string, m *types.MediaConverter) (string, func(), error) {
+	selected, cleanupSelected, err := selectTiffPages(ctx, inputPath, m)
+	if err != nil {
+		return "", cleanupSelected, err
+	}
+	oriented, cleanupOriented, err := orientImage(ctx, selected, m)
+	cleanup := func() {
+		cleanupOriented()
+		cleanupSelected()
+	}
+	if err != nil {
+		return "", cleanup, err
+	}
+	return oriented, cleanup, nil
+}
+
+// orientImage yönlendirme adımlarını ara VIPS dosyalarına uygular; boyutlandırma
+// ve kırpma sonraki adımda doğru yönlü görüntü üzerinde yapılır. Adım yoksa
+// girdi olduğu gibi döner.
+func orientImage(ctx context.Context, inputPath string, m *types.MediaConverter) (string, func(), error) {
+	var temps []string
+	cleanup := func() {
+		for _, temp := range temps {
+			os.Remove(temp)
+		}
+	}
+
+	currentPath := inputPath
+	for _, step := range vipsOrientSteps(m) {
+		tempFile, err := os.CreateTemp("", "orient-*.v")
+		if err != nil {
+			return "", cleanup, fmt.Errorf("geçici dosya oluşturulamadı: %w", err)
+		}
+		tempFile.Close()
+		temps = append(temps, tempFile.Name())
+
+		args := append([]string{step[0], currentPath, tempFile.Name()}, step[1:]...)
+		cmd := exec.CommandContext(ctx, "vips", args...)
+		log.Infof("Komut çalıştırılıyor: %s", cmd.String())
+		if output, err := cmd.CombinedOutput(); err != nil {
+			log.Errorf("Yönlendirme hatası: %v, Çıktı: %s", err, string(output))
+			return "", cleanup, fmt.Errorf("görüntü yönlendirme hatası: %w", err)
+		}
+		currentPath = tempFile.Name()
+	}
+	return currentPath, cleanup, nil
+}
+
+// ffmpegOrientArgs, FFmpeg girdi seçeneklerini ve filtre zincirinin başına
+// eklenecek döndürme filtrelerini üretir. AutoOrient ile girdideki yön bilgisi
+// uygulanır; yalnızca Rotate verilmişse döndürme saklanan piksellere göre yapılır.
+func ffmpegOrientArgs(m *types.MediaConverter) (inputArgs []string, filters []string) {
+	if !handlesOrientation(m) {
+		return nil, nil
+	}
+	if m.Search.AutoOrient {
+		inputArgs = []string{"-autorotate"}
+	} else {
+		inputArgs = []string{"-noautorotate"}
+	}
+	if m.Search.Rotate != nil {
+		filters = append(filters, ffmpegRotateFilters[*m.Search.Rotate])
+	}
+	return inputArgs, filters
+}
@@ -0,0 +1,64 @@
+package media
+
+import (
+	"context"
+	"documents-worker/types"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Keskinleştirme gücü VIPS sharpen komutunun sigma değeridir; küçük değerler
+// ince ayrıntıları, büyükler kenarları belirginleştirir
+const (
+	MinSharpenSigma = 0.1
+	MaxSharpenSigma = 10
+)
+
+// ParseSharpen keskinleştirme gücünü doğrular
+func ParseSharpen(value string) (float64, error) {
+	sigma, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || sigma < MinSharpenSigma || sigma > MaxSharpenSigma {
+		return 0, fmt.Errorf("geçersiz keskinleştirme gücü: %q (%g-%g arası olmalı)", value, MinSharpenSigma, float64(MaxSharpenSigma))
+	}
+	return sigma, nil
+}
+
+// sharpens, keskinleştirmenin uygulanıp uygulanmayacağını belirtir. Yalnızca
+// küçültme görüntüyü yumuşattığı için boyutlandırma olmadan uygulanmaz.
+func sharpens(m *types.MediaConverter) bool {
+	resized := m.Search.ResizeScale != nil || (m.Search.Crop == nil && (m.Search.Width != nil || m.Search.Height != nil))
+	return m.Search.Sharpen != nil && resized
+}
+
+// buildVipsSharpenArgs boyutlandırılmış ara dosyayı keskinleştirip kaydetme
+// seçenekleriyle çıktıya yazan VIPS argümanlarını üretir.
+func buildVipsSharpenArgs(resizedPath string, outputPath string, m *types.MediaConverter) []string {
+	return []string{
+		"sharpen", resizedPath, vipsOutputWithOptions(outputPath, m),
+		"--sigma", strconv.FormatFloat(*m.Search.Sharpen, 'f', -1, 64),
+	}
+}
+
+// resizeForSharpening boyutlandırma işlemini kaydetme seçenekleri olmadan ara
+// bir VIPS dosyasına uygular.
+func resizeForSharpening(ctx context.Context, inputPath string, m *types.MediaConverter) (string, func(), error) {
+	tempFile, err := os.CreateTemp("", "resized-*.v")
+	if err != nil {
+		return "", func() {}, fmt.Errorf("geçici dosya oluşturulamadı: %w", err)
+	}
+	tempFile.Close()
+	cleanup := func() { os.Remove(tempFile.Name()) }
+
+	cmd := exec.CommandContext(ctx, "vips", buildVipsOperationArgs(inputPath, tempFile.Name(), m)...)
+	log.Infof("Komut çalıştırılıyor: %s", cmd.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		log.Errorf("Boyutlandırma hatası: %v, Çıktı: %s", err, string(output))
+		return "", cleanup, fmt.Errorf("boyutlandırma hatası: %w", err)
+	}
+	return tempFile.Name(), cleanup, nil
+}
@@ -0,0 +1,477 @@
+package media
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Uyarlanabilir yayın paket biçimleri
+const (
+	StreamFormatHLS  = "hls"
+	StreamFormatDASH = "dash"
+)
+
+const (
+	// DefaultStreamSegmentSeconds segmentlerin varsayılan süresidir
+	DefaultStreamSegmentSeconds = 6
+	// MaxStreamRenditions bir paketteki en fazla kalite basamağıdır
+	MaxStreamRenditions = 6
+	// MaxStreamHeight izin verilen en büyük kalite yüksekliğidir (piksel)
+	MaxStreamHeight = 2160
+
+	hlsMasterName    = "master.m3u8"
+	dashManifestName = "manifest.mpd"
+)
+
+// StreamRendition kalite merdiveninin bir basamağıdır: hedef yükseklik ve
+// video bit hızı (kbit/s)
+type StreamRendition struct {
+	Height      int
+	BitrateKbps int
+}
+
+// DefaultStreamLadder kaynaktan yüksek basamaklar atıldıktan sonra kullanılan
+// varsayılan merdivendir
+var DefaultStreamLadder = []StreamRendition{
+	{Height: 1080, BitrateKbps: 5000},
+	{Height: 720, BitrateKbps: 2800},
+	{Height: 480, BitrateKbps: 1400},
+	{Height: 360, BitrateKbps: 800},
+}
+
+// StreamOptions bir HLS/DASH paketinin ayarlarıdır
+type StreamOptions struct {
+	Format         string
+	Ladder         []StreamRendition
+	SegmentSeconds int
+	// AudioBitrateKbps ses izinin bit hızıdır; sıfır 128 kbit/s demektir
+	AudioBitrateKbps int
+}
+
+// StreamPackage üretilen paketi tanımlar. Files, manifest ve oynatma listeleri
+// dahil Dir içindeki tüm dosya adlarıdır.
+type StreamPackage struct {
+	Format   string
+	Dir      string
+	Manifest string
+	Files    []string
+}
+
+// ParseStreamFormat paket biçimini çözer; boş değer HLS demektir
+func ParseStreamFormat(value string) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(value)); format {
+	case "":
+		return StreamFormatHLS, nil
+	case StreamFormatHLS, StreamFormatDASH:
+		return format, nil
+	default:
+		return "", fmt.Errorf("geçersiz yayın biçimi: %s (hls veya dash)", value)
+	}
+}
+
+// ParseStreamLadder "1080:5000,720:2800" biçimindeki merdiveni çözer. Bit
+// hızları kbit/s'dir ve sonlarındaki "k" yok sayılır. Boş değer varsayılan
+// merdiveni döndürür.
+func ParseStreamLadder(value string) ([]StreamRendition, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultStreamLadder, nil
+	}
+	var ladder []StreamRendition
+	for _, step := range strings.Split(value, ",") {
+		height, bitrate, ok := strings.Cut(strings.TrimSpace(step), ":")
+		if !ok {
+			return nil, fmt.Errorf("geçersiz kalite basamağı: %q (yükseklik:kbit/s)", step)
+		}
+		h, err := strconv.Atoi(strings.TrimSpace(height))
+		if err != nil {
+			return nil, fmt.Errorf("geçersiz yükseklik: %q", height)
+		}
+		b, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(bitrate)), "k"))
+		if err != nil {
+			return nil, fmt.Errorf("geçersiz bit hızı: %q", bitrate)
+		}
+		ladder = append(ladder, StreamRendition{Height: h, BitrateKbps: b})
+	}
+	if err := validateStreamLadder(ladder); err != nil {
+		return nil, err
+	}
+	return ladder, nil
+}
+
+// validateStreamLadder basamak sayısını ve değer aralıklarını denetler
+func validateStreamLadder(ladder []StreamRendition) error {
+	if len(ladder) == 0 || len(ladder) > MaxStreamRenditions {
+		return fmt.Errorf("geçersiz basamak sayısı: %d (1-%d)", len(ladder), MaxStreamRenditions)
+	}
+	for _, rendition := range ladder {
+		if rendition.Height < 2 || rendition.Height > MaxStreamHeight || rendition.Height%2 != 0 {
+			return fmt.Errorf("geçersiz yükseklik: %d (2-%d, çift sayı)", rendition.Height, MaxStreamHeight)
+		}
+		if rendition.BitrateKbps < 64 || rendition.BitrateKbps > 100000 {
+			return fmt.Errorf("geçersiz bit hızı: %dk (64-100000)", rendition.BitrateKbps)
+		}
+	}
+	return nil
+}
+
+// fitStreamLadder kaynaktan yüksek basamakları atar; hepsi yüksekse en düşük
+// basamak kaynağın yüksekliğine indirilir, böylece görüntü hiç büyütülmez
+func fitStreamLadder(ladder []StreamRendition, sourceHeight int) []StreamRendition {
+	if sourceHeight <= 0 {
+		return ladder
+	}
+	var fitted []StreamRendition
+	lowest := ladder[0]
+	for _, rendition := range ladder {
+		if rendition.Height <= sourceHeight {
+			fitted = append(fitted, rendition)
+		}
+		if rendition.Height < lowest.Height {
+			lowest = rendition
+		}
+	}
+	if len(fitted) == 0 {
+		lowest.Height = sourceHeight &^ 1
+		fitted = []StreamRendition{lowest}
+	}
+	return fitted
+}
+
+// streamArgs kaynağı her basamak için ölçekleyip kodlayan ve HLS ya da DASH
+// paketi olarak outputDir'e yazan ffmpeg argümanlarını üretir. Anahtar
+// kareler segment sınırlarına zorlanır, böylece tüm basamaklar aynı
+// noktalarda bölünür. Dosya adları düzdür; paket tek dizinde durur.
+func streamArgs(inputPath, outputDir string, opts StreamOptions, hasAudio bool) []string {
+	count := len(opts.Ladder)
+	audioBitrate := opts.AudioBitrateKbps
+	if audioBitrate <= 0 {
+		audioBitrate = 128
+	}
+
+	splits := make([]string, count)
+	scales := make([]string, count)
+	for i, rendition := range opts.Ladder {
+		splits[i] = fmt.Sprintf("[v%d]", i)
+		scales[i] = fmt.Sprintf("[v%d]scale=-2:%d[v%dout]", i, rendition.Height, i)
+	}
+	filter := fmt.Sprintf("[0:v]split=%d%s;%s", count, strings.Join(splits, ""), strings.Join(scales, ";"))
+
+	args := []string{"-i", inputPath, "-filter_complex", filter}
+	for i, rendition := range opts.Ladder {
+		index := strconv.Itoa(i)
+		args = append(args,
+			"-map", fmt.Sprintf("[v%dout]", i),
+			"-c:v:"+index, "libx264",
+			"-b:v:"+index, fmt.Sprintf("%dk", rendition.BitrateKbps),
+			"-maxrate:v:"+index, fmt.Sprintf("%dk", rendition.BitrateKbps*107/100),
+			"-bufsize:v:"+index, fmt.Sprintf("%dk", rendition.BitrateKbps*3/2),
+		)
+	}
+
+	// HLS'te her basamak kendi sesini taşır; DASH tek ses izi paylaşır
+	audioStreams := 0
+	if hasAudio {
+		audioStreams = 1
+		if opts.Format == StreamFormatHLS {
+			audioStreams = count
+		}
+		for i := 0; i < audioStreams; i++ {
+			args = append(args, "-map", "0:a:0")
+		}
+		args = append(args, "-c:a", "aac", "-b:a", fmt.Sprintf("%dk", audioBitrate), "-ac", "2")
+	}
+
+	segment := strconv.Itoa(opts.SegmentSeconds)
+	args = append(args,
+		"-preset", "veryfast",
+		"-sc_threshold", "0",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%s)", segment),
+	)
+
+	if opts.Format == StreamFormatDASH {
+		adaptationSets := "id=0,streams=v"
+		if hasAudio {
+			adaptationSets += " id=1,streams=a"
+		}
+		return append(args,
+			"-f", "dash",
+			"-seg_duration", segment,
+			"-use_template", "1",
+			"-use_timeline", "1",
+			"-init_seg_name", "init_$RepresentationID$.m4s",
+			"-media_seg_name", "chunk_$RepresentationID$_$Number%05d$.m4s",
+			"-adaptation_sets", adaptationSets,
+			"-y", filepath.Join(outputDir, dashManifestName),
+		)
+	}
+
+	streamMap := make([]string, count)
+	for i := range opts.Ladder {
+		streamMap[i] = fmt.Sprintf("v:%d", i)
+		if hasAudio {
+			streamMap[i] += fmt.Sprintf(",a:%d", i)
+		}
+	}
+	return append(args,
+		"-f", "hls",
+		"-hls_time", segment,
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(outputDir, "stream_%v_%03d.ts"),
+		"-master_pl_name", hlsMasterName,
+		"-var_stream_map", strings.Join(streamMap, " "),
+		"-y", filepath.Join(outputDir, "stream_%v.m3u8"),
+	)
+}
+
+// probeStreamSource kaynağın video yüksekliğini ve ses izi olup olmadığını
+// ffprobe ile okur
+func probeStreamSource(inputPath string) (height int, hasAudio bool, err error) {
+	cmd := exec.Command("ffprobe", "-v", "error", "-show_entries", "stream=codec_type,height", "-of", "csv=p=0", inputPath)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, false, fmt.Errorf("video akışları okunamadı: %w", err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Split(strings.TrimSpace(line), ",")
+		switch fields[0] {
+		case "video":
+			if height == 0 && len(fields) > 1 {
+				height, _ = strconv.Atoi(fields[1])
+			}
+		case "audio":
+			hasAudio = true
+		}
+	}
+	if height == 0 {
+		return 0, false, fmt.Errorf("girdide video akışı yok")
+	}
+	return height, hasAudio, nil
+}
+
+// GenerateStreamPackage kaynaktan kalite merdivenindeki her basamak için bir
+// kodlama ve bunları listeleyen HLS oynatma listeleri ya da DASH manifesti
+// üretir. Kaynaktan yüksek basamaklar atlanır. Paket, manifestin andığı her
+// dosyanın üretildiği denetlendikten sonra döner; dizini çağıran siler.
+func GenerateStreamPackage(ctx context.Context, inputPath string, opts StreamOptions) (*StreamPackage, error) {
+	format, err := ParseStreamFormat(opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	opts.Format = format
+	if opts.Ladder == nil {
+		opts.Ladder = DefaultStreamLadder
+	}
+	if err := validateStreamLadder(opts.Ladder); err != nil {
+		return nil, err
+	}
+	if opts.SegmentSeconds == 0 {
+		opts.SegmentSeconds = DefaultStreamSegmentSeconds
+	}
+	if opts.SegmentSeconds < 1 || opts.SegmentSeconds > 60 {
+		return nil, fmt.Errorf("geçersiz segment süresi: %d (1-60 saniye)", opts.SegmentSeconds)
+	}
+
+	height, hasAudio, err := probeStreamSource(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	opts.Ladder = fitStreamLadder(opts.Ladder, height)
+
+	outputDir, err := os.MkdirTemp("", "stream-*")
+	if err != nil {
+		return nil, fmt.Errorf("geçici çıktı dizini oluşturulamadı: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", streamArgs(inputPath, outputDir, opts, hasAudio)...)
+	log.Infof("Komut çalıştırılıyor: %s", cmd.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.RemoveAll(outputDir)
+		log.Errorf("Komut Hatası: %v, Çıktı: %s", err, string(output))
+		return nil, fmt.Errorf("yayın paketi oluşturulamadı: %w", err)
+	}
+
+	pkg, err := VerifyStreamPackage(outputDir, format)
+	if err != nil {
+		os.RemoveAll(outputDir)
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// VerifyStreamPackage dizindeki paketin manifestini okur ve andığı her oynatma
+// listesinin ve segmentin dizinde bulunduğunu denetler. Dosya adları yalnızca
+// dizinin içini gösterebilir.
+func VerifyStreamPackage(dir, format string) (*StreamPackage, error) {
+	pkg := &StreamPackage{Format: format, Dir: dir}
+	var referenced []string
+	var err error
+	switch format {
+	case StreamFormatHLS:
+		pkg.Manifest = hlsMasterName
+		referenced, err = hlsReferences(dir)
+	case StreamFormatDASH:
+		pkg.Manifest = dashManifestName
+		referenced, err = dashReferences(dir)
+	default:
+		return nil, fmt.Errorf("geçersiz yayın biçimi: %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pkg.Files = []string{pkg.Manifest}
+	seen := map[string]bool{pkg.Manifest: true}
+	for _, name := range referenced {
+		if seen[name] {
+			continue
+		}
+		if name != filepath.Base(name) || name == ".." {
+			return nil, fmt.Errorf("manifest paket dışını gösteriyor: %s", name)
+		}
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || info.Size() == 0 {
+			return nil, fmt.Errorf("manifestteki dosya üretilmemiş: %s", name)
+		}
+		seen[name] = true
+		pkg.Files = append(pkg.Files, name)
+	}
+	return pkg, nil
+}
+
+// hlsReferences ana oynatma listesindeki basamak listelerini ve onların
+// segmentlerini döndürür
+func hlsReferences(dir string) ([]string, error) {
+	variants, err := playlistURIs(filepath.Join(dir, hlsMasterName))
+	if err != nil {
+		return nil, err
+	}
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("ana oynatma listesinde basamak yok")
+	}
+
+	var references []string
+	for _, variant := range variants {
+		if variant != filepath.Base(variant) {
+			return nil, fmt.Errorf("manifest paket dışını gösteriyor: %s", variant)
+		}
+		segments, err := playlistURIs(filepath.Join(dir, variant))
+		if err != nil {
+			return nil, err
+		}
+		if len(segments) == 0 {
+			return nil, fmt.Errorf("%s listesinde segment yok", variant)
+		}
+		references = append(references, variant)
+		references = append(references, segments...)
+	}
+	return references, nil
+}
+
+// playlistURIs bir m3u8 listesinin yorum ve etiket olmayan satırlarını döndürür
+func playlistURIs(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("oynatma listesi okunamadı: %w", err)
+	}
+	defer file.Close()
+
+	var uris []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			uris = append(uris, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("oynatma listesi okunamadı: %w", err)
+	}
+	return uris, nil
+}
+
+// dashManifest MPD'nin segment adlarını çıkarmak için gereken kısmıdır
+type dashManifest struct {
+	AdaptationSets []struct {
+		Template        *dashSegmentTemplate `xml:"SegmentTemplate"`
+		Representations []struct {
+			ID       string               `xml:"id,attr"`
+			Template *dashSegmentTemplate `xml:"SegmentTemplate"`
+		} `xml:"Representation"`
+	} `xml:"Period>AdaptationSet"`
+}
+
+type dashSegmentTemplate struct {
+	Initialization string `xml:"initialization,attr"`
+	Media          string `xml:"media,attr"`
+	StartNumber    *int   `xml:"startNumber,attr"`
+	Timeline       []struct {
+		Repeat int `xml:"r,attr"`
+	} `xml:"SegmentTimeline>S"`
+}
+
+// dashNumber $Number$ ve $Number%05d$ gibi şablon alanlarıdır
+var dashNumber = regexp.MustCompile(`\$Number(%0?\d*d)?\$`)
+
+// dashReferences manifestteki her gösterimin başlangıç ve ortam segmentlerini
+// şablon ve zaman çizelgesinden çıkarır
+func dashReferences(dir string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, dashManifestName))
+	if err != nil {
+		return nil, fmt.Errorf("manifest okunamadı: %w", err)
+	}
+	var manifest dashManifest
+	if err := xml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("manifest çözümlenemedi: %w", err)
+	}
+
+	var references []string
+	for _, set := range manifest.AdaptationSets {
+		for _, representation := range set.Representations {
+			template := representation.Template
+			if template == nil {
+				template = set.Template
+			}
+			if template == nil || template.Media == "" || len(template.Timeline) == 0 {
+				return nil, fmt.Errorf("%s gösteriminde segment şablonu yok", representation.ID)
+			}
+
+			expand := func(pattern string, number int) string {
+				name := strings.ReplaceAll(pattern, "$RepresentationID$", representation.ID)
+				return dashNumber.ReplaceAllStringFunc(name, func(field string) string {
+					verb := dashNumber.FindStringSubmatch(field)[1]
+					if verb == "" {
+						verb = "%d"
+					}
+					return fmt.Sprintf(verb, number)
+				})
+			}
+
+			if template.Initialization != "" {
+				references = append(references, expand(template.Initialization, 0))
+			}
+			number := 1
+			if template.StartNumber != nil {
+				number = *template.StartNumber
+			}
+			for _, segment := range template.Timeline {
+				for i := 0; i <= segment.Repeat; i++ {
+					references = append(references, expand(template.Media, number))
+					number++
+				}
+			}
+		}
+	}
+	if len(references) == 0 {
+		return nil, fmt.Errorf("manifestte gösterim yok")
+	}
+	return references, nil
+}
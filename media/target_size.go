@@ -0,0 +1,183 @@
+package media
+
+import (
+	"context"
+	"documents-worker/types"
+	"fmt"
+	"os"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	// maxTargetSizeIterations ikili aramadaki deneme sayısını sınırlar (log2(100) ≈ 7)
+	maxTargetSizeIterations = 8
+	// targetSizeTolerance hedefin bu oranına ulaşan çıktı yeterli kabul edilir
+	targetSizeTolerance = 0.95
+)
+
+// TargetSizeResult hedef boyut modunda ulaşılan kalite ve boyutu taşır.
+type TargetSizeResult struct {
+	TargetSize int64 `json:"target_size"`
+	Quality    int   `json:"achieved_quality"`
+	Size       int64 `json:"achieved_size"`
+	Iterations int   `json:"iterations"`
+	TargetMet  bool  `json:"target_met"`
+}
+
+// Metadata sonucu iş metadatasına eklenecek biçimde döndürür.
+func (r *TargetSizeResult) Metadata() map[string]interface{} {
+	return map[string]interface{}{
+		"target_size":      r.TargetSize,
+		"achieved_quality": r.Quality,
+		"achieved_size":    r.Size,
+		"iterations":       r.Iterations,
+		"target_met":       r.TargetMet,
+	}
+}
+
+// qualityEncoder girdiyi verilen kalitede kodlar ve çıktı dosyasının yolunu döndürür.
+type qualityEncoder func(quality int) (string, error)
+
+// ConvertToTargetSize görüntüyü, çıktı m.Search.TargetSize baytı geçmeyecek en yüksek
+// kaliteyi ikili aramayla bularak dönüştürür. Hedefe ulaşılamazsa en küçük çıktı döner.
+func ConvertToTargetSize(vipsEnabled bool, inputPath string, m *types.MediaConverter) (*os.File, *TargetSizeResult, error) {
+	return convertToTargetSize(context.Background(), vipsEnabled, inputPath, m)
+}
+
+// ConvertToTargetSizeContext ConvertToTargetSize gibidir; ancak ctx iptal
+// edildiğinde çalışan deneme sonlandırılır.
+func ConvertToTargetSizeContext(ctx context.Context, vipsEnabled bool, inputPath string, m *types.MediaConverter) (*os.File, *TargetSizeResult, error) {
+	return convertToTargetSize(ctx, vipsEnabled, inputPath, m)
+}
+
+func convertToTargetSize(ctx context.Context, vipsEnabled bool, inputPath string, m *types.MediaConverter) (*os.File, *TargetSizeResult, error) {
+	if m.Kind != types.ImageKind {
+		return nil, nil, fmt.Errorf("hedef boyut modu yalnızca görüntüler için desteklenir: %s", m.Kind)
+	}
+	if m.Search.TargetSize == nil || *m.Search.TargetSize <= 0 {
+		return nil, nil, fmt.Errorf("geçersiz hedef boyut")
+	}
+
+	maxQuality := 100
+	if m.Search.Quality != nil && *m.Search.Quality > 0 && *m.Search.Quality < maxQuality {
+		maxQuality = *m.Search.Quality
+	}
+
+	// Sayfa seçimi ve yönlendirme her denemede değil, aramadan önce bir kez yapılır
+	if vipsEnabled {
+		prepared, cleanup, err := prepareImage(ctx, inputPath, m)
+		defer cleanup()
+		if err != nil {
+			return nil, nil, err
+		}
+		inputPath = prepared
+	}
+
+	encode := func(quality int) (string, error) {
+		file, err := ExecCommandContext(ctx, vipsEnabled, inputPath, targetSizeAttempt(vipsEnabled, m, quality))
+		if err != nil {
+			return "", err
+		}
+		file.Close()
+		return file.Name(), nil
+	}
+
+	outputPath, result, err := searchQualityForSize(int64(*m.Search.TargetSize), 1, maxQuality, encode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Infof("Hedef boyut: %d bayt, ulaşılan: %d bayt (kalite %d, %d deneme)",
+		result.TargetSize, result.Size, result.Quality, result.Iterations)
+
+	file, err := os.Open(outputPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("çıktı dosyası açılamadı: %w", err)
+	}
+	return file, result, nil
+}
+
+// targetSizeAttempt bir arama denemesinin dönüştürme ayarlarını üretir. VIPS ile
+// sayfa seçimi ve yönlendirme aramadan önce hazırlanan girdiye uygulandığından,
+// ikinci kez uygulanmamaları için denemeden çıkarılır.
+func targetSizeAttempt(vipsEnabled bool, m *types.MediaConverter, quality int) *types.MediaConverter {
+	attempt := *m
+	attempt.Search.TargetSize = nil
+	attempt.Search.Quality = &quality
+	if vipsEnabled {
+		attempt.Search.Page = nil
+		attempt.Search.AllPages = false
+		attempt.Search.AutoOrient = false
+		attempt.Search.Rotate = nil
+	}
+	return &attempt
+}
+
+// searchQualityForSize hedef boyuta sığan en yüksek kaliteyi ikili aramayla bulur.
+// Seçilmeyen ara çıktılar silinir.
+func searchQualityForSize(target int64, minQuality, maxQuality int, encode qualityEncoder) (string, *TargetSizeResult, error) {
+	result := &TargetSizeResult{TargetSize: target}
+
+	var (
+		bestPath, smallestPath string
+		bestSize, smallestSize int64
+		bestQ, smallestQ       int
+	)
+
+	discard := func(path string) {
+		if path != "" {
+			os.Remove(path)
+		}
+	}
+
+	lo, hi := minQuality, maxQuality
+	for lo <= hi && result.Iterations < maxTargetSizeIterations {
+		quality := (lo + hi) / 2
+		result.Iterations++
+
+		path, err := encode(quality)
+		if err != nil {
+			discard(bestPath)
+			discard(smallestPath)
+			return "", nil, err
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			discard(path)
+			discard(bestPath)
+			discard(smallestPath)
+			return "", nil, fmt.Errorf("çıktı boyutu okunamadı: %w", err)
+		}
+		size := info.Size()
+
+		if size <= target {
+			// Sığan en yüksek kalite daha sonra denendiği için öncekinin yerini alır
+			discard(bestPath)
+			bestPath, bestSize, bestQ = path, size, quality
+			if float64(size) >= float64(target)*targetSizeTolerance {
+				break
+			}
+			lo = quality + 1
+			continue
+		}
+
+		if smallestPath == "" || size < smallestSize {
+			discard(smallestPath)
+			smallestPath, smallestSize, smallestQ = path, size, quality
+		} else {
+			discard(path)
+		}
+		hi = quality - 1
+	}
+
+	if bestPath != "" {
+		discard(smallestPath)
+		result.Quality, result.Size, result.TargetMet = bestQ, bestSize, true
+		return bestPath, result, nil
+	}
+
+	result.Quality, result.Size = smallestQ, smallestSize
+	return smallestPath, result, nil
+}
@@ -0,0 +1,77 @@
+package media
+
+import (
+	"context"
+	"documents-worker/utils"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	// DefaultPDFThumbnailSize kapak önizlemesinin varsayılan en uzun kenarıdır (piksel)
+	DefaultPDFThumbnailSize = 256
+	// MaxPDFThumbnailSize izin verilen en büyük önizleme kenarıdır
+	MaxPDFThumbnailSize = 2048
+)
+
+// pdfThumbnailArgs mutool draw argümanlarını oluşturur. -w ve -h birlikte verildiğinde
+// sayfa en-boy oranı korunarak kutuya sığdırılır.
+func pdfThumbnailArgs(inputPath, outputPath string, page, size int) []string {
+	return []string{
+		"draw",
+		"-o", outputPath,
+		"-F", "png",
+		"-w", strconv.Itoa(size),
+		"-h", strconv.Itoa(size),
+		inputPath,
+		strconv.Itoa(page),
+	}
+}
+
+// RenderPDFThumbnail PDF'in yalnızca istenen sayfasını size x size kutusuna sığacak
+// şekilde PNG olarak çizer; diğer sayfalar hiç işlenmez. ctx iptal edildiğinde mutool
+// sonlandırılır. Çıktı dosyasını çağıran siler.
+func RenderPDFThumbnail(ctx context.Context, mutoolPath, inputPath string, page, size int) (*os.File, error) {
+	if page < 1 {
+		return nil, fmt.Errorf("geçersiz sayfa: %d", page)
+	}
+	if size < 1 || size > MaxPDFThumbnailSize {
+		return nil, fmt.Errorf("geçersiz önizleme boyutu: %d (1-%d)", size, MaxPDFThumbnailSize)
+	}
+	if mutoolPath == "" {
+		mutoolPath = "mutool"
+	}
+
+	// Şifreli PDF'ler parola olmadan anlaşılır bir hatayla reddedilir
+	pdfPath, cleanup, err := utils.PreparePDF(mutoolPath, inputPath, "")
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	outputFile, err := os.CreateTemp("", "pdf-thumbnail-*.png")
+	if err != nil {
+		return nil, fmt.Errorf("geçici çıktı dosyası oluşturulamadı: %w", err)
+	}
+	outputFile.Close()
+
+	cmd := exec.CommandContext(ctx, mutoolPath, pdfThumbnailArgs(pdfPath, outputFile.Name(), page, size)...)
+	log.Infof("MuPDF komutu: %s", cmd.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(outputFile.Name())
+		log.Errorf("MuPDF Hatası: %v, Çıktı: %s", err, string(output))
+		return nil, fmt.Errorf("PDF önizlemesi oluşturulamadı: %w", err)
+	}
+
+	// Var olmayan sayfalar için mutool yalnızca uyarı verip boş çıktı bırakır
+	if info, err := os.Stat(outputFile.Name()); err != nil || info.Size() == 0 {
+		os.Remove(outputFile.Name())
+		return nil, fmt.Errorf("sayfa %d çizilemedi", page)
+	}
+
+	return os.Open(outputFile.Name())
+}
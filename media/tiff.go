@@ -0,0 +1,61 @@
+package media
+
+import (
+	"context"
+	"documents-worker/types"
+	"documents-worker/utils"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// vipsTiffPageArgs seçilen TIFF sayfasını, ya da AllPages ile tüm sayfaları
+// alt alta birleştirerek, tek bir VIPS görüntüsüne kopyalayan argümanları üretir.
+// VIPS sayfaları sıfırdan saydığı için sayfa numarası bir azaltılır.
+func vipsTiffPageArgs(inputPath, outputPath string, m *types.MediaConverter) []string {
+	loadOpts := "n=-1"
+	if !m.Search.AllPages {
+		loadOpts = fmt.Sprintf("page=%d", *m.Search.Page-1)
+	}
+	return []string{"copy", fmt.Sprintf("%s[%s]", inputPath, loadOpts), outputPath}
+}
+
+// selectTiffPages çok sayfalı TIFF girdilerinde istenen sayfaları ara bir VIPS
+// dosyasına çıkarır. Sayfa seçimi yoksa veya girdi TIFF değilse girdi olduğu gibi
+// döner; PDF sayfaları DocumentProcessor tarafından zaten seçilmiş olur.
+func selectTiffPages(ctx context.Context, inputPath string, m *types.MediaConverter) (string, func(), error) {
+	noop := func() {}
+	if m.Search.Page == nil && !m.Search.AllPages {
+		return inputPath, noop, nil
+	}
+	mimeType, err := utils.DetectMimeTypeFromFile(inputPath)
+	if err != nil || !utils.IsTiffImage(mimeType) {
+		return inputPath, noop, nil
+	}
+
+	pages, err := utils.TiffPageCount(inputPath)
+	if err != nil {
+		return "", noop, fmt.Errorf("TIFF sayfa sayısı okunamadı: %w", err)
+	}
+	if !m.Search.AllPages && (*m.Search.Page < 1 || *m.Search.Page > pages) {
+		return "", noop, fmt.Errorf("sayfa %d bulunamadı: TIFF %d sayfa içeriyor", *m.Search.Page, pages)
+	}
+
+	tempFile, err := os.CreateTemp("", "tiff-page-*.v")
+	if err != nil {
+		return "", noop, fmt.Errorf("geçici dosya oluşturulamadı: %w", err)
+	}
+	tempFile.Close()
+	cleanup := func() { os.Remove(tempFile.Name()) }
+
+	cmd := exec.CommandContext(ctx, "vips", vipsTiffPageArgs(inputPath, tempFile.Name(), m)...)
+	log.Infof("Komut çalıştırılıyor: %s", cmd.String())
+	if output, err := cmd.CombinedOutput(); err != nil {
+		cleanup()
+		log.Errorf("TIFF sayfa seçme hatası: %v, Çıktı: %s", err, string(output))
+		return "", noop, fmt.Errorf("TIFF sayfa seçme hatası: %w", err)
+	}
+	return tempFile.Name(), cleanup, nil
+}
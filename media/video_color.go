@@ -0,0 +1,116 @@
+package media
+
+import (
+	"documents-worker/types"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FFmpeg'in kabul ettiği renk sinyalizasyon değerleri
+var (
+	videoColorPrimaries = []string{
+		"bt709", "bt470m", "bt470bg", "smpte170m", "smpte240m", "film",
+		"bt2020", "smpte428", "smpte431", "smpte432", "jedec-p22",
+	}
+	videoColorTransfers = []string{
+		"bt709", "gamma22", "gamma28", "smpte170m", "smpte240m", "linear",
+		"log100", "log316", "iec61966-2-4", "bt1361e", "iec61966-2-1",
+		"bt2020-10", "bt2020-12", "smpte2084", "smpte428", "arib-std-b67",
+	}
+	videoColorMatrices = []string{
+		"rgb", "bt709", "fcc", "bt470bg", "smpte170m", "smpte240m", "ycgco",
+		"bt2020nc", "bt2020c", "smpte2085", "chroma-derived-nc", "chroma-derived-c", "ictcp",
+	}
+)
+
+const (
+	// minVideoGamma ve maxVideoGamma FFmpeg eq filtresinin gamma aralığıdır
+	minVideoGamma = 0.1
+	maxVideoGamma = 10.0
+)
+
+// ParseVideoColor sorgu/istek değerlerinden renk ayarlarını çözer ve doğrular.
+// Tüm değerler boşsa nil döner; bu durumda renk bilgisi girdiden aynen geçer.
+func ParseVideoColor(primaries, transfer, matrix, gamma string) (*types.VideoColor, error) {
+	c := &types.VideoColor{
+		Primaries: strings.ToLower(strings.TrimSpace(primaries)),
+		Transfer:  strings.ToLower(strings.TrimSpace(transfer)),
+		Matrix:    strings.ToLower(strings.TrimSpace(matrix)),
+	}
+	// "bt2020" matris için yaygın kısaltmadır
+	if c.Matrix == "bt2020" {
+		c.Matrix = "bt2020nc"
+	}
+	if gamma = strings.TrimSpace(gamma); gamma != "" {
+		g, err := strconv.ParseFloat(gamma, 64)
+		if err != nil {
+			return nil, fmt.Errorf("geçersiz gamma: %q", gamma)
+		}
+		c.Gamma = g
+	}
+
+	if *c == (types.VideoColor{}) {
+		return nil, nil
+	}
+	if err := ValidateVideoColor(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ValidateVideoColor renk ayarlarının FFmpeg tarafından desteklendiğini doğrular.
+func ValidateVideoColor(c *types.VideoColor) error {
+	if c == nil {
+		return nil
+	}
+	checks := []struct {
+		name    string
+		value   string
+		allowed []string
+	}{
+		{"color_primaries", c.Primaries, videoColorPrimaries},
+		{"color_trc", c.Transfer, videoColorTransfers},
+		{"colorspace", c.Matrix, videoColorMatrices},
+	}
+	for _, check := range checks {
+		if check.value != "" && !containsString(check.allowed, check.value) {
+			return fmt.Errorf("desteklenmeyen %s: %q (geçerli: %s)", check.name, check.value, strings.Join(check.allowed, ", "))
+		}
+	}
+	if c.Gamma != 0 && (c.Gamma < minVideoGamma || c.Gamma > maxVideoGamma) {
+		return fmt.Errorf("gamma %g-%g aralığında olmalı: %g", minVideoGamma, maxVideoGamma, c.Gamma)
+	}
+	return nil
+}
+
+// buildFFmpegColorArgs gamma düzeltmesi için filtreyi ve çıktı akışının renk
+// etiketlerini üretir. Etiketler pikselleri dönüştürmez; oynatıcıya akışın
+// nasıl yorumlanacağını bildirir.
+func buildFFmpegColorArgs(c *types.VideoColor) (filters []string, args []string) {
+	if c == nil {
+		return nil, nil
+	}
+	if c.Gamma != 0 {
+		filters = append(filters, fmt.Sprintf("eq=gamma=%s", strconv.FormatFloat(c.Gamma, 'g', -1, 64)))
+	}
+	if c.Primaries != "" {
+		args = append(args, "-color_primaries", c.Primaries)
+	}
+	if c.Transfer != "" {
+		args = append(args, "-color_trc", c.Transfer)
+	}
+	if c.Matrix != "" {
+		args = append(args, "-colorspace", c.Matrix)
+	}
+	return filters, args
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
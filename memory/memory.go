@@ -0,0 +1,199 @@
+// Package memory measures memory pressure so work can be shed before the
+// process, or the container holding it and its vips and ffmpeg children,
+// runs out of memory.
+package memory
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCgroupRoot is where cgroup v2 exposes the container's memory
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// Sample is the memory in use against the limit, in bytes. A zero limit
+// means there is no limit to measure against.
+type Sample struct {
+	Used  uint64
+	Limit uint64
+}
+
+// Ratio returns the used fraction of the limit, 0 without a limit
+func (s Sample) Ratio() float64 {
+	if s.Limit == 0 {
+		return 0
+	}
+	return float64(s.Used) / float64(s.Limit)
+}
+
+// Sampler reads the current memory use
+type Sampler func() (Sample, error)
+
+// CgroupSampler reads the cgroup v2 memory use, which includes child
+// processes. The cgroup's memory.max is the limit unless limit is set.
+func CgroupSampler(root string, limit uint64) Sampler {
+	return func() (Sample, error) {
+		used, err := readCgroupValue(filepath.Join(root, "memory.current"))
+		if err != nil {
+			return Sample{}, err
+		}
+		if limit == 0 {
+			limit, err = readCgroupValue(filepath.Join(root, "memory.max"))
+			if err != nil {
+				return Sample{}, err
+			}
+		}
+		return Sample{Used: used, Limit: limit}, nil
+	}
+}
+
+// RuntimeSampler reads the memory the Go runtime holds from the operating
+// system. Without a limit, the runtime's soft limit (GOMEMLIMIT) is used.
+func RuntimeSampler(limit uint64) Sampler {
+	if limit == 0 {
+		if soft := debug.SetMemoryLimit(-1); soft != math.MaxInt64 {
+			limit = uint64(soft)
+		}
+	}
+
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	var mu sync.Mutex
+	return func() (Sample, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		metrics.Read(samples)
+		total, released := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+		return Sample{Used: total - released, Limit: limit}, nil
+	}
+}
+
+// DetectSampler prefers the container's cgroup, which also accounts for
+// external tools, and falls back to the Go runtime
+func DetectSampler(limit uint64) Sampler {
+	cgroup := CgroupSampler(DefaultCgroupRoot, limit)
+	if _, err := cgroup(); err == nil {
+		return cgroup
+	}
+	return RuntimeSampler(limit)
+}
+
+// readCgroupValue reads a byte count; "max" means unlimited and reads as 0
+func readCgroupValue(path string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// Gauge keeps the latest sample, refreshed in the background, so readers on
+// the request path do not measure memory themselves
+type Gauge struct {
+	sampler   Sampler
+	threshold float64
+
+	sample atomic.Pointer[Sample]
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewGauge creates a gauge reporting pressure once the used fraction of the
+// limit reaches threshold, e.g. 0.9
+func NewGauge(sampler Sampler, threshold float64) *Gauge {
+	g := &Gauge{sampler: sampler, threshold: threshold}
+	g.sample.Store(&Sample{})
+	return g
+}
+
+// Refresh takes a sample now. Failed samples keep the previous value.
+func (g *Gauge) Refresh() (Sample, error) {
+	sample, err := g.sampler()
+	if err != nil {
+		return *g.sample.Load(), err
+	}
+	g.sample.Store(&sample)
+	return sample, nil
+}
+
+// Sample returns the latest sample
+func (g *Gauge) Sample() Sample {
+	return *g.sample.Load()
+}
+
+// Threshold returns the used fraction at which pressure is reported
+func (g *Gauge) Threshold() float64 {
+	return g.threshold
+}
+
+// UnderPressure reports whether the latest sample is at or above the
+// threshold. Without a known limit there is never pressure.
+func (g *Gauge) UnderPressure() bool {
+	sample := g.Sample()
+	return sample.Limit > 0 && g.threshold > 0 && sample.Ratio() >= g.threshold
+}
+
+// Start refreshes the gauge every interval until Stop
+func (g *Gauge) Start(interval time.Duration) {
+	if g.stop != nil {
+		return
+	}
+	g.Refresh()
+	g.stop = make(chan struct{})
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				g.Refresh()
+			case <-g.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends background refreshes
+func (g *Gauge) Stop() {
+	if g.stop == nil {
+		return
+	}
+	close(g.stop)
+	g.wg.Wait()
+	g.stop = nil
+}
+
+// ErrNoLimit is returned by Validate when no limit could be determined
+var ErrNoLimit = errors.New("no memory limit: set MEMORY_LIMIT, GOMEMLIMIT or run under a cgroup memory limit")
+
+// Validate takes a first sample and checks that there is a limit to measure
+// pressure against
+func (g *Gauge) Validate() error {
+	sample, err := g.Refresh()
+	if err != nil {
+		return err
+	}
+	if sample.Limit == 0 {
+		return ErrNoLimit
+	}
+	return nil
+}
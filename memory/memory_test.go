@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeCgroup(t *testing.T, current, max string) string {
+	t.Helper()
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "memory.current"), []byte(current+"\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "memory.max"), []byte(max+"\n"), 0644))
+	return root
+}
+
+func TestCgroupSamplerReadsUsageAndLimit(t *testing.T) {
+	sample, err := CgroupSampler(writeCgroup(t, "900", "1000"), 0)()
+	require.NoError(t, err)
+	assert.Equal(t, Sample{Used: 900, Limit: 1000}, sample)
+	assert.InDelta(t, 0.9, sample.Ratio(), 1e-9)
+
+	// A configured limit takes precedence over memory.max
+	sample, err = CgroupSampler(writeCgroup(t, "900", "max"), 3000)()
+	require.NoError(t, err)
+	assert.Equal(t, Sample{Used: 900, Limit: 3000}, sample)
+
+	// An unlimited cgroup has no pressure
+	sample, err = CgroupSampler(writeCgroup(t, "900", "max"), 0)()
+	require.NoError(t, err)
+	assert.Zero(t, sample.Ratio())
+
+	_, err = CgroupSampler(t.TempDir(), 0)()
+	assert.Error(t, err)
+}
+
+func TestRuntimeSamplerReportsHeldMemory(t *testing.T) {
+	sample, err := RuntimeSampler(1 << 40)()
+	require.NoError(t, err)
+	assert.Positive(t, sample.Used)
+	assert.Equal(t, uint64(1<<40), sample.Limit)
+}
+
+func TestGaugeReportsPressureAtThreshold(t *testing.T) {
+	current := Sample{Used: 50, Limit: 100}
+	var sampleErr error
+	gauge := NewGauge(func() (Sample, error) { return current, sampleErr }, 0.9)
+
+	assert.False(t, gauge.UnderPressure(), "no sample taken yet")
+	gauge.Refresh()
+	assert.False(t, gauge.UnderPressure())
+
+	current.Used = 90
+	gauge.Refresh()
+	assert.True(t, gauge.UnderPressure())
+
+	// Failed samples keep the last known value
+	sampleErr = errors.New("cgroup gone")
+	current.Used = 10
+	_, err := gauge.Refresh()
+	assert.Error(t, err)
+	assert.True(t, gauge.UnderPressure())
+
+	require.ErrorIs(t, NewGauge(func() (Sample, error) { return Sample{Used: 1}, nil }, 0.9).Validate(), ErrNoLimit)
+}
+
+func TestGaugeRefreshesInBackground(t *testing.T) {
+	used := make(chan uint64, 1)
+	used <- 10
+	last := uint64(10)
+	gauge := NewGauge(func() (Sample, error) {
+		select {
+		case last = <-used:
+		default:
+		}
+		return Sample{Used: last, Limit: 100}, nil
+	}, 0.9)
+
+	gauge.Start(time.Millisecond)
+	defer gauge.Stop()
+	assert.False(t, gauge.UnderPressure())
+
+	used <- 95
+	require.Eventually(t, gauge.UnderPressure, time.Second, time.Millisecond)
+}
@@ -1,8 +1,12 @@
 package ocr
 
 import (
+	"context"
 	"documents-worker/config"
+	"documents-worker/libreoffice"
+	"documents-worker/utils"
 	"fmt"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -20,6 +24,7 @@ type OCRResult struct {
 type OCRProcessor struct {
 	config   *config.OCRConfig
 	external *config.ExternalConfig
+	password string
 }
 
 func NewOCRProcessor(ocrConfig *config.OCRConfig, externalConfig *config.ExternalConfig) *OCRProcessor {
@@ -29,55 +34,76 @@ func NewOCRProcessor(ocrConfig *config.OCRConfig, externalConfig *config.Externa
 	}
 }
 
+// WithPassword returns a copy of the processor that opens encrypted PDFs
+// with the given password. The password is never logged.
+func (o *OCRProcessor) WithPassword(password string) *OCRProcessor {
+	clone := *o
+	clone.password = password
+	return &clone
+}
+
 func (o *OCRProcessor) ProcessImage(imagePath string) (*OCRResult, error) {
-	// Create temporary output file for text
-	outputFile, err := os.CreateTemp("", "ocr-output-*.txt")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
-	}
-	defer os.Remove(outputFile.Name())
-	defer outputFile.Close()
-
-	// Build tesseract command
-	args := []string{
-		imagePath,
-		strings.TrimSuffix(outputFile.Name(), ".txt"), // tesseract adds .txt automatically
-		"-l", o.config.Language,
-		"--psm", fmt.Sprintf("%d", o.config.PSM),
-		"-c", "tessedit_char_whitelist=abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789ğüşıöçĞÜŞİÖÇ .,!?:;()-",
-	}
+	sourcePath := imagePath
 
-	cmd := exec.Command(o.external.TesseractPath, args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("tesseract execution failed: %w, output: %s", err, string(output))
+	// Scans are turned upright and straightened first when enabled
+	var orientation *Orientation
+	if o.config.AutoRotate {
+		corrected, detected, err := o.CorrectOrientation(imagePath)
+		if err != nil {
+			log.Printf("Orientation correction skipped for %s: %v", filepath.Base(imagePath), err)
+		} else {
+			defer os.Remove(corrected)
+			imagePath = corrected
+			orientation = detected
+		}
 	}
 
-	// Read extracted text
-	textBytes, err := os.ReadFile(outputFile.Name())
+	text, err := o.recognize(imagePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read OCR output: %w", err)
+		return nil, err
 	}
-
-	text := strings.TrimSpace(string(textBytes))
+	// Pages of multi-page images are kept apart like paragraphs
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\f", "\n\n"))
 
 	// Calculate basic confidence (simplified)
 	confidence := o.calculateConfidence(text)
 
-	return &OCRResult{
+	result := &OCRResult{
 		Text:       text,
 		Confidence: confidence,
 		Language:   o.config.Language,
 		PageCount:  1,
 		Metadata: map[string]interface{}{
-			"input_file": filepath.Base(imagePath),
+			"input_file": filepath.Base(sourcePath),
 			"psm":        o.config.PSM,
 			"dpi":        o.config.DPI,
 		},
-	}, nil
+	}
+	if orientation != nil {
+		result.Metadata["rotation"] = orientation.Rotation
+		result.Metadata["skew"] = orientation.Skew
+	}
+	return result, nil
+}
+
+// recognize runs tesseract on an image and returns the raw text. Images
+// with several pages, such as multi-page TIFFs, have every page recognized
+// with a form feed after each.
+func (o *OCRProcessor) recognize(imagePath string) (string, error) {
+	text, err := o.run(imagePath, OutputText)
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
 }
 
-func (o *OCRProcessor) ProcessPDF(pdfPath string, pageNum int) (*OCRResult, error) {
+func (o *OCRProcessor) ProcessPDF(sourcePath string, pageNum int) (*OCRResult, error) {
+	pdfPath, cleanup, err := utils.PreparePDF(o.external.MutoolPath, sourcePath, o.password)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
 	// First convert PDF page to image
 	imagePath, err := o.convertPDFPageToImage(pdfPath, pageNum)
 	if err != nil {
@@ -94,7 +120,7 @@ func (o *OCRProcessor) ProcessPDF(pdfPath string, pageNum int) (*OCRResult, erro
 	// Update metadata
 	result.Metadata["source_type"] = "pdf"
 	result.Metadata["page_number"] = pageNum
-	result.Metadata["source_file"] = filepath.Base(pdfPath)
+	result.Metadata["source_file"] = filepath.Base(sourcePath)
 
 	return result, nil
 }
@@ -141,14 +167,12 @@ func (o *OCRProcessor) convertPDFPageToImage(pdfPath string, pageNum int) (strin
 func (o *OCRProcessor) convertDocumentToPDF(docPath string) (string, error) {
 	outputDir := os.TempDir()
 
-	cmd := exec.Command("soffice",
+	output, err := libreoffice.Run(context.Background(), "soffice",
 		"--headless",
 		"--convert-to", "pdf",
 		docPath,
 		"--outdir", outputDir,
 	)
-
-	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("libreoffice execution failed: %w, output: %s", err, string(output))
 	}
@@ -217,7 +241,15 @@ func (o *OCRProcessor) calculateConfidence(text string) float64 {
 }
 
 // BatchProcessPDF processes all pages of a PDF
-func (o *OCRProcessor) BatchProcessPDF(pdfPath string) ([]*OCRResult, error) {
+func (o *OCRProcessor) BatchProcessPDF(sourcePath string) ([]*OCRResult, error) {
+	// Decrypt once up front instead of for every page
+	pdfPath, cleanup, err := utils.PreparePDF(o.external.MutoolPath, sourcePath, o.password)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+	o = o.WithPassword("")
+
 	// Get page count first
 	pageCount, err := o.getPDFPageCount(pdfPath)
 	if err != nil {
@@ -261,3 +293,41 @@ func (o *OCRProcessor) getPDFPageCount(pdfPath string) (int, error) {
 
 	return 1, nil // Default to 1 page if can't determine
 }
+
+// BatchProcessTIFF processes all pages of a multi-page TIFF, such as a
+// scanned fax. Tesseract reads every page in one run and separates them
+// with form feeds.
+func (o *OCRProcessor) BatchProcessTIFF(tiffPath string) ([]*OCRResult, error) {
+	pageCount, err := utils.TiffPageCount(tiffPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TIFF page count: %w", err)
+	}
+
+	text, err := o.recognize(tiffPath)
+	if err != nil {
+		return nil, err
+	}
+	texts := strings.Split(strings.TrimRight(text, "\f\n"), "\f")
+
+	results := make([]*OCRResult, pageCount)
+	for i := range results {
+		pageText := ""
+		if i < len(texts) {
+			pageText = strings.TrimSpace(texts[i])
+		}
+		results[i] = &OCRResult{
+			Text:       pageText,
+			Confidence: o.calculateConfidence(pageText),
+			Language:   o.config.Language,
+			PageCount:  1,
+			Metadata: map[string]interface{}{
+				"source_type": "tiff",
+				"page_number": i + 1,
+				"source_file": filepath.Base(tiffPath),
+				"psm":         o.config.PSM,
+				"dpi":         o.config.DPI,
+			},
+		}
+	}
+	return results, nil
+}
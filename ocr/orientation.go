@@ -0,0 +1,222 @@
+package ocr
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxSkewDegrees is the largest skew searched for; scans are rarely off
+	// by more than a few degrees
+	MaxSkewDegrees = 10.0
+	// skewStep is the resolution of the skew search in degrees
+	skewStep = 0.1
+	// minSkewDegrees is the skew below which the page is left as it is
+	minSkewDegrees = 0.2
+	// minOSDConfidence is the orientation confidence below which tesseract's
+	// rotation is not trusted
+	minOSDConfidence = 2.0
+	// skewSampleSize bounds the pixels sampled per axis when estimating skew
+	skewSampleSize = 1000
+)
+
+// Orientation is the correction applied to a scanned page before OCR
+type Orientation struct {
+	// Rotation is the clockwise rotation in degrees (0, 90, 180 or 270)
+	// that turned the page upright
+	Rotation int `json:"rotation"`
+	// OrientationConfidence is tesseract's confidence in the rotation
+	OrientationConfidence float64 `json:"orientation_confidence"`
+	// Skew is the detected skew in degrees, clockwise positive; the page
+	// was rotated back by it
+	Skew float64 `json:"skew"`
+}
+
+// parseOSD reads the rotation and its confidence from tesseract's
+// orientation and script detection (--psm 0) output
+func parseOSD(output string) (int, float64, error) {
+	rotation, confidence := -1, 0.0
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "Rotate":
+			if parsed, err := strconv.Atoi(value); err == nil {
+				rotation = parsed
+			}
+		case "Orientation confidence":
+			confidence, _ = strconv.ParseFloat(value, 64)
+		}
+	}
+	if rotation != 0 && rotation != 90 && rotation != 180 && rotation != 270 {
+		return 0, 0, fmt.Errorf("no rotation in tesseract OSD output")
+	}
+	return rotation, confidence, nil
+}
+
+// detectRotation runs tesseract's orientation detection on an image
+func (o *OCRProcessor) detectRotation(imagePath string) (int, float64, error) {
+	cmd := exec.Command(o.external.TesseractPath, imagePath, "stdout", "--psm", "0")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, 0, fmt.Errorf("tesseract orientation detection failed: %w, output: %s", err, string(output))
+	}
+	return parseOSD(string(output))
+}
+
+// CorrectOrientation turns a scanned page upright and straightens it: 90°
+// multiples are detected with tesseract OSD, small skews from the angle at
+// which the rows of dark pixels line up best. The corrected page is written
+// to a PNG the caller removes. PNG and JPEG images are supported.
+func (o *OCRProcessor) CorrectOrientation(imagePath string) (string, *Orientation, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return "", nil, err
+	}
+	img, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode image for orientation correction: %w", err)
+	}
+
+	orientation := &Orientation{}
+	rotation, confidence, err := o.detectRotation(imagePath)
+	switch {
+	case err != nil:
+		// Without OSD data the page is still deskewed
+		log.Printf("Orientation detection failed for %s: %v", imagePath, err)
+	case confidence >= minOSDConfidence:
+		orientation.Rotation = rotation
+		orientation.OrientationConfidence = confidence
+		img = rotateQuarterTurns(img, rotation)
+	}
+
+	orientation.Skew = EstimateSkew(img)
+	if math.Abs(orientation.Skew) >= minSkewDegrees {
+		img = RotateImage(img, -orientation.Skew)
+	} else {
+		orientation.Skew = 0
+	}
+
+	output, err := os.CreateTemp("", "ocr-oriented-*.png")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer output.Close()
+	if err := png.Encode(output, img); err != nil {
+		os.Remove(output.Name())
+		return "", nil, fmt.Errorf("failed to write corrected image: %w", err)
+	}
+	return output.Name(), orientation, nil
+}
+
+// EstimateSkew returns the skew of the text lines in degrees, clockwise
+// positive. Dark pixels are projected onto rows along each candidate angle;
+// the angle along which the rows are most uneven lines up with the text.
+func EstimateSkew(img image.Image) float64 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	step := max(1, max(width, height)/skewSampleSize)
+
+	var xs, ys []float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+		for x := bounds.Min.X; x < bounds.Max.X; x += step {
+			if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y < 128 {
+				xs = append(xs, float64(x-bounds.Min.X))
+				ys = append(ys, float64(y-bounds.Min.Y))
+			}
+		}
+	}
+	if len(xs) == 0 {
+		return 0
+	}
+
+	bins := make([]int, height+2*width+1)
+	best, bestScore := 0.0, -1
+	steps := int(math.Round(MaxSkewDegrees / skewStep))
+	for i := -steps; i <= steps; i++ {
+		angle := float64(i) * skewStep
+		slope := math.Tan(angle * math.Pi / 180)
+		clear(bins)
+		for j := range xs {
+			bins[int(math.Round(ys[j]-xs[j]*slope))+width]++
+		}
+		score := 0
+		for _, count := range bins {
+			score += count * count
+		}
+		// Prefer the smallest angle among equally good ones
+		if score > bestScore || (score == bestScore && math.Abs(angle) < math.Abs(best)) {
+			best, bestScore = angle, score
+		}
+	}
+	return best
+}
+
+// RotateImage rotates an image clockwise by degrees around its center,
+// keeping its size. Uncovered corners are filled with white, the usual
+// background of a scan. The result is grayscale, which is all OCR needs.
+func RotateImage(img image.Image, degrees float64) image.Image {
+	bounds := img.Bounds()
+	rotated := image.NewGray(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	cx, cy := float64(bounds.Dx()-1)/2, float64(bounds.Dy()-1)/2
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			dx, dy := float64(x)-cx, float64(y)-cy
+			sx := int(math.Round(dx*cos+dy*sin+cx)) + bounds.Min.X
+			sy := int(math.Round(-dx*sin+dy*cos+cy)) + bounds.Min.Y
+			if image.Pt(sx, sy).In(bounds) {
+				rotated.Set(x, y, img.At(sx, sy))
+			} else {
+				rotated.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return rotated
+}
+
+// rotateQuarterTurns rotates an image clockwise by 90, 180 or 270 degrees
+func rotateQuarterTurns(img image.Image, degrees int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	var rotated *image.RGBA
+	var source func(x, y int) (int, int)
+
+	switch degrees {
+	case 90:
+		rotated = image.NewRGBA(image.Rect(0, 0, height, width))
+		source = func(x, y int) (int, int) { return y, height - 1 - x }
+	case 180:
+		rotated = image.NewRGBA(image.Rect(0, 0, width, height))
+		source = func(x, y int) (int, int) { return width - 1 - x, height - 1 - y }
+	case 270:
+		rotated = image.NewRGBA(image.Rect(0, 0, height, width))
+		source = func(x, y int) (int, int) { return width - 1 - y, x }
+	default:
+		return img
+	}
+
+	for y := 0; y < rotated.Bounds().Dy(); y++ {
+		for x := 0; x < rotated.Bounds().Dx(); x++ {
+			sx, sy := source(x, y)
+			rotated.Set(x, y, img.At(sx+bounds.Min.X, sy+bounds.Min.Y))
+		}
+	}
+	return rotated
+}
@@ -0,0 +1,135 @@
+package ocr
+
+import (
+	"documents-worker/config"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sampleOSD is tesseract --psm 0 output for a page turned a quarter
+// counterclockwise
+const sampleOSD = `Page number: 0
+Orientation in degrees: 270
+Rotate: 90
+Orientation confidence: 7.52
+Script: Latin
+Script confidence: 2.33
+`
+
+// fakeOSDTesseract answers orientation detection with sampleOSD and writes
+// recognized text otherwise
+const fakeOSDTesseract = `#!/bin/sh
+if [ "$2" = "stdout" ]; then
+cat <<'EOF'
+` + sampleOSD + `EOF
+exit 0
+fi
+echo "Upright text" > "$2.txt"
+`
+
+// textPage draws a white page with dark horizontal bars standing in for
+// lines of text
+func textPage(width, height int) *image.Gray {
+	page := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			page.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+	for top := 40; top+6 < height-40; top += 30 {
+		for y := top; y < top+6; y++ {
+			for x := 40; x < width-40; x++ {
+				page.SetGray(x, y, color.Gray{Y: 0})
+			}
+		}
+	}
+	return page
+}
+
+func TestParseOSD(t *testing.T) {
+	rotation, confidence, err := parseOSD(sampleOSD)
+	require.NoError(t, err)
+	assert.Equal(t, 90, rotation)
+	assert.Equal(t, 7.52, confidence)
+
+	_, _, err = parseOSD("Too few characters. Skipping this page\n")
+	assert.Error(t, err)
+}
+
+func TestEstimateSkew(t *testing.T) {
+	page := textPage(400, 300)
+	assert.Zero(t, EstimateSkew(page))
+
+	skewed := RotateImage(page, 3)
+	assert.InDelta(t, 3, EstimateSkew(skewed), 0.3)
+	assert.InDelta(t, -2, EstimateSkew(RotateImage(page, -2)), 0.3)
+
+	straightened := RotateImage(skewed, -EstimateSkew(skewed))
+	assert.InDelta(t, 0, EstimateSkew(straightened), 0.3)
+}
+
+func TestRotateQuarterTurns(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	img.SetGray(0, 0, color.Gray{Y: 200})
+
+	// The top left corner moves to the top right with a clockwise turn
+	rotated := rotateQuarterTurns(img, 90)
+	assert.Equal(t, image.Rect(0, 0, 2, 4), rotated.Bounds())
+	assert.Equal(t, uint8(200), color.GrayModel.Convert(rotated.At(1, 0)).(color.Gray).Y)
+
+	rotated = rotateQuarterTurns(img, 180)
+	assert.Equal(t, uint8(200), color.GrayModel.Convert(rotated.At(3, 1)).(color.Gray).Y)
+
+	rotated = rotateQuarterTurns(img, 270)
+	assert.Equal(t, uint8(200), color.GrayModel.Convert(rotated.At(0, 3)).(color.Gray).Y)
+}
+
+func TestCorrectOrientationOfRotatedScan(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+
+	dir := t.TempDir()
+	tesseract := filepath.Join(dir, "tesseract")
+	require.NoError(t, os.WriteFile(tesseract, []byte(fakeOSDTesseract), 0755))
+
+	// A page skewed by 2 degrees, then turned a quarter counterclockwise
+	scan := rotateQuarterTurns(RotateImage(textPage(400, 300), 2), 270)
+	samplePath := filepath.Join(dir, "scan.png")
+	file, err := os.Create(samplePath)
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(file, scan))
+	require.NoError(t, file.Close())
+
+	ocrConfig := &config.OCRConfig{Language: "eng", DPI: 300, PSM: 3, AutoRotate: true}
+	processor := NewOCRProcessor(ocrConfig, &config.ExternalConfig{TesseractPath: tesseract})
+
+	corrected, orientation, err := processor.CorrectOrientation(samplePath)
+	require.NoError(t, err)
+	defer os.Remove(corrected)
+	assert.Equal(t, 90, orientation.Rotation)
+	assert.Equal(t, 7.52, orientation.OrientationConfidence)
+	assert.InDelta(t, 2, orientation.Skew, 0.3)
+
+	file, err = os.Open(corrected)
+	require.NoError(t, err)
+	upright, err := png.Decode(file)
+	file.Close()
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 400, 300), upright.Bounds())
+	assert.InDelta(t, 0, EstimateSkew(upright), 0.3)
+
+	result, err := processor.ProcessImage(samplePath)
+	require.NoError(t, err)
+	assert.Equal(t, "Upright text", result.Text)
+	assert.Equal(t, 90, result.Metadata["rotation"])
+	assert.Equal(t, "scan.png", result.Metadata["input_file"])
+}
@@ -0,0 +1,180 @@
+package ocr
+
+import (
+	"documents-worker/utils"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// OutputFormat is the kind of document tesseract writes
+type OutputFormat string
+
+const (
+	// OutputText is the recognized plain text
+	OutputText OutputFormat = "text"
+	// OutputHOCR is an HTML page positioning every recognized word
+	OutputHOCR OutputFormat = "hocr"
+	// OutputPDF is a searchable PDF: the page images with the recognized
+	// text laid invisibly over them
+	OutputPDF OutputFormat = "pdf"
+
+	// outputTSV lists every recognized word with its box and confidence;
+	// it is parsed into words rather than returned
+	outputTSV OutputFormat = "tsv"
+)
+
+// ParseOutputFormat parses a format name; empty means text
+func ParseOutputFormat(value string) (OutputFormat, error) {
+	switch format := OutputFormat(strings.ToLower(strings.TrimSpace(value))); format {
+	case "", OutputText:
+		return OutputText, nil
+	case OutputHOCR, OutputPDF:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported OCR output format: %s (text, hocr, pdf)", value)
+	}
+}
+
+// extension is the suffix tesseract adds to the output base name
+func (f OutputFormat) extension() string {
+	switch f {
+	case OutputHOCR:
+		return ".hocr"
+	case OutputPDF:
+		return ".pdf"
+	case outputTSV:
+		return ".tsv"
+	default:
+		return ".txt"
+	}
+}
+
+// ContentType is the MIME type of the output
+func (f OutputFormat) ContentType() string {
+	switch f {
+	case OutputHOCR:
+		return "text/html; charset=utf-8"
+	case OutputPDF:
+		return "application/pdf"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// WithLanguage returns a copy of the processor recognizing language, such
+// as "tur" or "eng+tur". An empty language keeps the configured one.
+func (o *OCRProcessor) WithLanguage(language string) *OCRProcessor {
+	if language == "" {
+		return o
+	}
+	clone := *o
+	ocrConfig := *o.config
+	ocrConfig.Language = language
+	clone.config = &ocrConfig
+	return &clone
+}
+
+// tesseractArgs builds the arguments that recognize input and write
+// outputBase plus the format's extension. Input is an image or a text file
+// listing one image per line, which tesseract reads as the pages of one
+// document.
+func (o *OCRProcessor) tesseractArgs(input, outputBase string, format OutputFormat) []string {
+	args := []string{
+		input,
+		outputBase,
+		"-l", o.config.Language,
+		"--psm", fmt.Sprintf("%d", o.config.PSM),
+	}
+	// The whitelist drops characters from the text layer too, so it is
+	// only applied to plain text and words
+	if format == OutputText || format == outputTSV {
+		args = append(args, "-c", "tessedit_char_whitelist=abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789ğüşıöçĞÜŞİÖÇ .,!?:;()-")
+	}
+	if o.config.DPI > 0 && (format == OutputHOCR || format == OutputPDF) {
+		args = append(args, "--dpi", fmt.Sprintf("%d", o.config.DPI))
+	}
+	switch format {
+	case OutputHOCR:
+		args = append(args, "hocr")
+	case OutputPDF:
+		args = append(args, "pdf")
+	case outputTSV:
+		args = append(args, "tsv")
+	}
+	return args
+}
+
+// run runs tesseract on input and returns the output in format
+func (o *OCRProcessor) run(input string, format OutputFormat) ([]byte, error) {
+	outputFile, err := os.CreateTemp("", "ocr-output-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	outputFile.Close()
+	outputBase := outputFile.Name()
+	outputPath := outputBase + format.extension()
+	defer os.Remove(outputBase)
+	defer os.Remove(outputPath)
+
+	cmd := exec.Command(o.external.TesseractPath, o.tesseractArgs(input, outputBase, format)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("tesseract execution failed: %w, output: %s", err, string(output))
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCR output: %w", err)
+	}
+	return data, nil
+}
+
+// Render recognizes an image and returns it in format. Multi-page images
+// such as TIFFs produce one hOCR page or PDF page per image page.
+func (o *OCRProcessor) Render(imagePath string, format OutputFormat) ([]byte, error) {
+	return o.run(imagePath, format)
+}
+
+// RenderPDF recognizes every page of a PDF and returns the document in
+// format. Pages are rasterized at the configured DPI, so a searchable PDF
+// output replaces the original page content with its scan.
+func (o *OCRProcessor) RenderPDF(sourcePath string, format OutputFormat) ([]byte, error) {
+	pdfPath, cleanup, err := utils.PreparePDF(o.external.MutoolPath, sourcePath, o.password)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
+	pageCount, err := o.getPDFPageCount(pdfPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PDF page count: %w", err)
+	}
+
+	pages := make([]string, 0, pageCount)
+	defer func() {
+		for _, page := range pages {
+			os.Remove(page)
+		}
+	}()
+	for i := 1; i <= pageCount; i++ {
+		page, err := o.convertPDFPageToImage(pdfPath, i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert PDF page %d to image: %w", i, err)
+		}
+		pages = append(pages, page)
+	}
+
+	list, err := os.CreateTemp("", "ocr-pages-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(list.Name())
+	_, err = list.WriteString(strings.Join(pages, "\n") + "\n")
+	list.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write page list: %w", err)
+	}
+
+	return o.run(list.Name(), format)
+}
@@ -0,0 +1,51 @@
+package ocr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	for value, expected := range map[string]OutputFormat{"": OutputText, "text": OutputText, "HOCR": OutputHOCR, " pdf ": OutputPDF} {
+		format, err := ParseOutputFormat(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, expected, format, value)
+	}
+
+	_, err := ParseOutputFormat("docx")
+	assert.Error(t, err)
+}
+
+func TestTesseractArgs(t *testing.T) {
+	ocrConfig, externalConfig := getTestOCRConfig()
+	processor := NewOCRProcessor(ocrConfig, externalConfig).WithLanguage("eng+tur")
+
+	text := processor.tesseractArgs("page.png", "/tmp/out", OutputText)
+	assert.Equal(t, []string{"page.png", "/tmp/out", "-l", "eng+tur", "--psm", "3"}, text[:6])
+	assert.Equal(t, "-c", text[6])
+	assert.Len(t, text, 8)
+
+	hocr := processor.tesseractArgs("page.png", "/tmp/out", OutputHOCR)
+	assert.Equal(t, []string{"page.png", "/tmp/out", "-l", "eng+tur", "--psm", "3", "--dpi", "300", "hocr"}, hocr)
+
+	pdf := processor.tesseractArgs("pages.txt", "/tmp/out", OutputPDF)
+	assert.Equal(t, []string{"pages.txt", "/tmp/out", "-l", "eng+tur", "--psm", "3", "--dpi", "300", "pdf"}, pdf)
+}
+
+func TestWithLanguageKeepsConfig(t *testing.T) {
+	ocrConfig, externalConfig := getTestOCRConfig()
+	processor := NewOCRProcessor(ocrConfig, externalConfig)
+
+	assert.Same(t, processor, processor.WithLanguage(""))
+	assert.Equal(t, "deu", processor.WithLanguage("deu").config.Language)
+	assert.Equal(t, "tur", ocrConfig.Language)
+}
+
+func TestOutputFormatFiles(t *testing.T) {
+	assert.Equal(t, ".txt", OutputText.extension())
+	assert.Equal(t, ".hocr", OutputHOCR.extension())
+	assert.Equal(t, ".pdf", OutputPDF.extension())
+	assert.Equal(t, "application/pdf", OutputPDF.ContentType())
+}
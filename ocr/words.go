@@ -0,0 +1,98 @@
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"documents-worker/internal/core/domain"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Word is a recognized word with its box and confidence
+type Word = domain.OCRWord
+
+// tsvWordLevel is the level of word rows in tesseract's TSV output
+const tsvWordLevel = 5
+
+// ProcessImageDetailed recognizes an image and returns every word with its
+// box and confidence. Unlike ProcessImage the image is not turned upright
+// first, so the boxes match the image as given.
+func (o *OCRProcessor) ProcessImageDetailed(imagePath string) (*domain.OCRResult, error) {
+	data, err := o.run(imagePath, outputTSV)
+	if err != nil {
+		return nil, err
+	}
+	words, err := ParseTSV(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return domain.NewOCRResult(words, o.config.Language), nil
+}
+
+// ParseTSV reads the words of tesseract's TSV output. Rows of pages,
+// blocks and lines, and words recognized as blanks, are skipped. Tesseract
+// scores confidence from 0 to 100; words carry it from 0 to 1.
+func ParseTSV(r io.Reader) ([]Word, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var columns map[string]int
+	words := make([]Word, 0)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Split(strings.TrimRight(scanner.Text(), "\r"), "\t")
+		if columns == nil {
+			columns = make(map[string]int, len(fields))
+			for i, name := range fields {
+				columns[name] = i
+			}
+			for _, name := range []string{"level", "page_num", "block_num", "par_num", "line_num", "left", "top", "width", "height", "conf", "text"} {
+				if _, ok := columns[name]; !ok {
+					return nil, fmt.Errorf("TSV header has no %s column", name)
+				}
+			}
+			continue
+		}
+		if len(fields) < len(columns) {
+			continue
+		}
+
+		text := strings.TrimSpace(fields[columns["text"]])
+		if text == "" {
+			continue
+		}
+		numbers := make(map[string]int, 8)
+		for _, name := range []string{"level", "page_num", "block_num", "par_num", "line_num", "left", "top", "width", "height"} {
+			value, err := strconv.Atoi(fields[columns[name]])
+			if err != nil {
+				return nil, fmt.Errorf("TSV line %d: invalid %s: %q", line, name, fields[columns[name]])
+			}
+			numbers[name] = value
+		}
+		confidence, err := strconv.ParseFloat(fields[columns["conf"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("TSV line %d: invalid conf: %q", line, fields[columns["conf"]])
+		}
+		if numbers["level"] != tsvWordLevel || confidence < 0 {
+			continue
+		}
+
+		words = append(words, Word{
+			Text:       text,
+			Confidence: confidence / 100,
+			Page:       numbers["page_num"],
+			Block:      numbers["block_num"],
+			Paragraph:  numbers["par_num"],
+			Line:       numbers["line_num"],
+			Left:       numbers["left"],
+			Top:        numbers["top"],
+			Width:      numbers["width"],
+			Height:     numbers["height"],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read TSV: %w", err)
+	}
+	return words, nil
+}
@@ -0,0 +1,54 @@
+package ocr
+
+import (
+	"documents-worker/internal/core/domain"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTSV(t *testing.T) {
+	file, err := os.Open("testdata/words.tsv")
+	require.NoError(t, err)
+	defer file.Close()
+
+	words, err := ParseTSV(file)
+	require.NoError(t, err)
+	require.Len(t, words, 7, "structure rows and blank words are skipped")
+
+	assert.Equal(t, Word{
+		Text: "Invoice", Confidence: 0.96571396,
+		Page: 1, Block: 1, Paragraph: 1, Line: 1,
+		Left: 120, Top: 98, Width: 142, Height: 34,
+	}, words[0])
+	assert.Equal(t, "Teşekkürler", words[6].Text)
+
+	result := domain.NewOCRResult(words, "tur")
+	assert.Equal(t, "Invoice No: 2024-118\nTotal: 1.2S0,00 |\n\nTeşekkürler", result.Text)
+	assert.InDelta(t, 0.7419, result.Confidence, 0.0001)
+
+	filtered := result.Filter(0.5)
+	assert.Len(t, filtered.Words, 5)
+	assert.Equal(t, "Invoice No: 2024-118\nTotal:\n\nTeşekkürler", filtered.Text)
+	assert.Greater(t, filtered.Confidence, result.Confidence)
+}
+
+func TestParseTSVRejectsOtherInput(t *testing.T) {
+	_, err := ParseTSV(strings.NewReader("plain text output\n"))
+	assert.Error(t, err)
+
+	words, err := ParseTSV(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Empty(t, words)
+}
+
+func TestTSVArgs(t *testing.T) {
+	ocrConfig, externalConfig := getTestOCRConfig()
+	args := NewOCRProcessor(ocrConfig, externalConfig).tesseractArgs("page.png", "/tmp/out", outputTSV)
+	assert.Equal(t, "tsv", args[len(args)-1])
+	assert.Contains(t, args, "-c")
+	assert.NotContains(t, args, "--dpi")
+}
@@ -0,0 +1,180 @@
+// Package packaging bundles the outputs of multi-result operations into a
+// single archive that can be streamed to a client.
+package packaging
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// FormatZip is the value of the package option that selects a zip archive
+	FormatZip = "zip"
+	// ManifestName is the archive entry describing every packaged file
+	ManifestName = "manifest.json"
+)
+
+// Entry is one output to package. Open is called once, while the archive
+// is written, so large outputs are streamed rather than held in memory.
+type Entry struct {
+	Name        string
+	ContentType string
+	Metadata    map[string]interface{}
+	Open        func() (io.ReadCloser, error)
+}
+
+// FileEntry packages the file at path under the given archive name
+func FileEntry(name, filePath, contentType string) Entry {
+	return Entry{
+		Name:        name,
+		ContentType: contentType,
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(filePath)
+		},
+	}
+}
+
+// BytesEntry packages in-memory data under the given archive name
+func BytesEntry(name string, data []byte, contentType string) Entry {
+	return Entry{
+		Name:        name,
+		ContentType: contentType,
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+	}
+}
+
+// Manifest describes the contents of an archive
+type Manifest struct {
+	Operation string         `json:"operation"`
+	CreatedAt time.Time      `json:"created_at"`
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one packaged output
+type ManifestFile struct {
+	Name        string                 `json:"name"`
+	Size        int64                  `json:"size"`
+	SHA256      string                 `json:"sha256"`
+	ContentType string                 `json:"content_type,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Requested reports whether a package option asks for an archive. An empty
+// value means the operation's regular response.
+func Requested(option string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(option)) {
+	case "":
+		return false, nil
+	case FormatZip:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unsupported package format: %s", option)
+	}
+}
+
+// WriteZip streams the entries into a zip archive on w, followed by a
+// manifest. Entry names are made safe and unique inside the archive.
+func WriteZip(w io.Writer, operation string, entries []Entry) (*Manifest, error) {
+	archive := zip.NewWriter(w)
+	manifest := &Manifest{
+		Operation: operation,
+		CreatedAt: time.Now().UTC(),
+		Files:     make([]ManifestFile, 0, len(entries)),
+	}
+
+	used := map[string]bool{ManifestName: true}
+	for i, entry := range entries {
+		name := uniqueName(sanitizeName(entry.Name, i), used)
+
+		file, err := writeEntry(archive, name, entry)
+		if err != nil {
+			archive.Close()
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, *file)
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	writer, err := archive.Create(ManifestName)
+	if err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("failed to add manifest: %w", err)
+	}
+	if _, err := writer.Write(manifestJSON); err != nil {
+		archive.Close()
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return manifest, nil
+}
+
+func writeEntry(archive *zip.Writer, name string, entry Entry) (*ManifestFile, error) {
+	source, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer source.Close()
+
+	writer, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(writer, hash), source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return &ManifestFile{
+		Name:        name,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		ContentType: entry.ContentType,
+		Metadata:    entry.Metadata,
+	}, nil
+}
+
+// sanitizeName keeps only the base name so entries cannot escape the
+// archive root, falling back to a positional name
+func sanitizeName(name string, index int) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." || name == "" {
+		return fmt.Sprintf("output_%03d", index+1)
+	}
+	return name
+}
+
+// uniqueName appends a counter before the extension until the name is unused
+func uniqueName(name string, used map[string]bool) string {
+	candidate := name
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 2; used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s_%d%s", base, n, ext)
+	}
+	used[candidate] = true
+	return candidate
+}
@@ -0,0 +1,90 @@
+package packaging
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readArchive(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		files[file.Name] = content
+	}
+	return files
+}
+
+func TestWriteZip(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "page.png")
+	require.NoError(t, os.WriteFile(filePath, []byte("png-bytes"), 0644))
+
+	entries := []Entry{
+		BytesEntry("page_001.txt", []byte("first page"), "text/plain"),
+		FileEntry("page.png", filePath, "image/png"),
+		BytesEntry("page_001.txt", []byte("duplicate name"), "text/plain"),
+		BytesEntry("../../etc/passwd", []byte("escaped"), ""),
+		BytesEntry(ManifestName, []byte("reserved"), ""),
+	}
+	entries[0].Metadata = map[string]interface{}{"page": 1}
+
+	var buf bytes.Buffer
+	manifest, err := WriteZip(&buf, "text_pages", entries)
+	require.NoError(t, err)
+
+	files := readArchive(t, buf.Bytes())
+	assert.Equal(t, "first page", string(files["page_001.txt"]))
+	assert.Equal(t, "png-bytes", string(files["page.png"]))
+	assert.Equal(t, "duplicate name", string(files["page_001_2.txt"]))
+	assert.Equal(t, "escaped", string(files["passwd"]))
+	assert.Equal(t, "reserved", string(files["manifest_2.json"]))
+	assert.Len(t, files, 6)
+
+	var written Manifest
+	require.NoError(t, json.Unmarshal(files[ManifestName], &written))
+	assert.Equal(t, "text_pages", written.Operation)
+	require.Len(t, written.Files, 5)
+	assert.Equal(t, manifest.Files[0].Name, written.Files[0].Name)
+
+	sum := sha256.Sum256([]byte("first page"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), written.Files[0].SHA256)
+	assert.Equal(t, int64(len("first page")), written.Files[0].Size)
+	assert.Equal(t, "text/plain", written.Files[0].ContentType)
+	assert.Equal(t, float64(1), written.Files[0].Metadata["page"])
+}
+
+func TestWriteZipOpenError(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := WriteZip(&buf, "test", []Entry{FileEntry("missing.txt", "/nonexistent/file", "")})
+	assert.Error(t, err)
+}
+
+func TestRequested(t *testing.T) {
+	ok, err := Requested("")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = Requested("ZIP")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = Requested("tar")
+	assert.Error(t, err)
+}
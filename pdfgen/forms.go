@@ -0,0 +1,248 @@
+package pdfgen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormField describes an AcroForm field in a PDF
+type FormField struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"` // Text, Button, Choice, Signature
+	Value   string   `json:"value,omitempty"`
+	Flags   int      `json:"flags,omitempty"`
+	Options []string `json:"options,omitempty"` // checkbox states or choice values
+}
+
+// FillFormOptions controls how a form is filled
+type FillFormOptions struct {
+	// Flatten merges field values into the page content so the output is no
+	// longer editable
+	Flatten bool `json:"flatten"`
+	// AllowUnknownFields skips the check that every value targets an
+	// existing field
+	AllowUnknownFields bool `json:"allow_unknown_fields"`
+}
+
+// ListFormFields returns the AcroForm fields of a PDF
+func (pg *PDFGenerator) ListFormFields(pdfPath string) ([]FormField, error) {
+	cmd := exec.Command(pg.pdftkPath(), pdfPath, "dump_data_fields_utf8")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pdftk field dump failed: %w, output: %s", err, stderr.String())
+	}
+
+	return parseFormFieldDump(string(output)), nil
+}
+
+// FillForm fills the AcroForm fields of a PDF from a name/value map and
+// writes the result to a new PDF
+func (pg *PDFGenerator) FillForm(pdfPath string, values map[string]string, options *FillFormOptions) (*GenerationResult, error) {
+	startTime := time.Now()
+	if options == nil {
+		options = &FillFormOptions{}
+	}
+
+	if !options.AllowUnknownFields {
+		fields, err := pg.ListFormFields(pdfPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkFormValues(fields, values); err != nil {
+			return nil, err
+		}
+	}
+
+	xfdf, err := buildXFDF(values)
+	if err != nil {
+		return nil, err
+	}
+
+	dataFile, err := os.CreateTemp("", "form-data-*.xfdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp XFDF file: %w", err)
+	}
+	defer os.Remove(dataFile.Name())
+
+	if _, err := dataFile.Write(xfdf); err != nil {
+		dataFile.Close()
+		return nil, fmt.Errorf("failed to write XFDF data: %w", err)
+	}
+	dataFile.Close()
+
+	outputFile, err := os.CreateTemp("", "filled-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp PDF file: %w", err)
+	}
+	outputFile.Close()
+
+	args := []string{pdfPath, "fill_form", dataFile.Name(), "output", outputFile.Name()}
+	if options.Flatten {
+		args = append(args, "flatten")
+	}
+
+	cmd := exec.Command(pg.pdftkPath(), args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(outputFile.Name())
+		return nil, fmt.Errorf("pdftk fill_form failed: %w, output: %s", err, string(output))
+	}
+
+	fileInfo, err := os.Stat(outputFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	pageCount, _ := pg.getPDFPageCount(outputFile.Name())
+
+	return &GenerationResult{
+		OutputPath:  outputFile.Name(),
+		InputType:   "pdf_form",
+		GeneratedAt: time.Now(),
+		Duration:    time.Since(startTime),
+		FileSize:    fileInfo.Size(),
+		PageCount:   pageCount,
+		Metadata: map[string]interface{}{
+			"generator":     "pdftk",
+			"fields_filled": len(values),
+			"flattened":     options.Flatten,
+		},
+	}, nil
+}
+
+// pdftkPath returns the configured pdftk binary
+func (pg *PDFGenerator) pdftkPath() string {
+	if pg.config != nil && pg.config.PdftkPath != "" {
+		return pg.config.PdftkPath
+	}
+	return "pdftk"
+}
+
+// parseFormFieldDump parses the output of pdftk dump_data_fields, where
+// fields are separated by "---" lines and attributes are "Key: Value" pairs
+func parseFormFieldDump(dump string) []FormField {
+	var fields []FormField
+	var current *FormField
+
+	flush := func() {
+		if current != nil && current.Name != "" {
+			fields = append(fields, *current)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(dump))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "---" {
+			flush()
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		if current == nil {
+			current = &FormField{}
+		}
+
+		switch key {
+		case "FieldType":
+			current.Type = value
+		case "FieldName":
+			current.Name = value
+		case "FieldValue":
+			current.Value = value
+		case "FieldFlags":
+			current.Flags, _ = strconv.Atoi(value)
+		case "FieldStateOption":
+			current.Options = append(current.Options, value)
+		}
+	}
+	flush()
+
+	return fields
+}
+
+// checkFormValues rejects values that do not target an existing field or
+// that are not a valid state of a checkbox or choice field
+func checkFormValues(fields []FormField, values map[string]string) error {
+	byName := make(map[string]FormField, len(fields))
+	for _, field := range fields {
+		byName[field.Name] = field
+	}
+
+	var unknown, invalid []string
+	for name, value := range values {
+		field, ok := byName[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if len(field.Options) > 0 && field.Type != "Text" && !containsString(field.Options, value) {
+			invalid = append(invalid, fmt.Sprintf("%s=%q (allowed: %s)", name, value, strings.Join(field.Options, ", ")))
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown form fields: %s", strings.Join(unknown, ", "))
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("invalid form field values: %s", strings.Join(invalid, "; "))
+	}
+	return nil
+}
+
+type xfdfDocument struct {
+	XMLName xml.Name    `xml:"xfdf"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Fields  []xfdfField `xml:"fields>field"`
+}
+
+type xfdfField struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// buildXFDF encodes field values as an XFDF document. XFDF is used rather
+// than FDF because it handles UTF-8 values without extra encoding.
+func buildXFDF(values map[string]string) ([]byte, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	doc := xfdfDocument{XMLNS: "http://ns.adobe.com/xfdf/"}
+	for _, name := range names {
+		doc.Fields = append(doc.Fields, xfdfField{Name: name, Value: values[name]})
+	}
+
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode XFDF: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
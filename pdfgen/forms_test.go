@@ -0,0 +1,135 @@
+package pdfgen
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleFormPDF = "testdata/form.pdf"
+
+func TestParseFormFieldDump(t *testing.T) {
+	dump := `---
+FieldType: Text
+FieldName: full_name
+FieldFlags: 0
+FieldValue: Ayşe Yılmaz
+FieldJustification: Left
+---
+FieldType: Button
+FieldName: subscribe
+FieldFlags: 0
+FieldValue: Off
+FieldJustification: Left
+FieldStateOption: Off
+FieldStateOption: Yes
+---
+FieldType: Choice
+FieldName: country
+FieldFlags: 131072
+FieldValue: TR
+FieldJustification: Left
+FieldStateOption: DE
+FieldStateOption: TR
+FieldStateOption: US
+`
+
+	fields := parseFormFieldDump(dump)
+	require.Len(t, fields, 3)
+
+	assert.Equal(t, FormField{Name: "full_name", Type: "Text", Value: "Ayşe Yılmaz"}, fields[0])
+	assert.Equal(t, "Button", fields[1].Type)
+	assert.Equal(t, []string{"Off", "Yes"}, fields[1].Options)
+	assert.Equal(t, 131072, fields[2].Flags)
+	assert.Equal(t, []string{"DE", "TR", "US"}, fields[2].Options)
+}
+
+func TestCheckFormValues(t *testing.T) {
+	fields := []FormField{
+		{Name: "full_name", Type: "Text"},
+		{Name: "subscribe", Type: "Button", Options: []string{"Off", "Yes"}},
+	}
+
+	assert.NoError(t, checkFormValues(fields, map[string]string{"full_name": "x", "subscribe": "Yes"}))
+
+	err := checkFormValues(fields, map[string]string{"missing": "x", "also_missing": "y"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown form fields: also_missing, missing")
+
+	err = checkFormValues(fields, map[string]string{"subscribe": "On"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "subscribe")
+}
+
+func TestBuildXFDF(t *testing.T) {
+	data, err := buildXFDF(map[string]string{
+		"full_name": "Tom & Jerry <co>",
+		"country":   "TR",
+	})
+	require.NoError(t, err)
+
+	xfdf := string(data)
+	assert.Contains(t, xfdf, `<xfdf xmlns="http://ns.adobe.com/xfdf/">`)
+	assert.Contains(t, xfdf, `<field name="full_name">`)
+	assert.Contains(t, xfdf, `Tom &amp; Jerry &lt;co&gt;`)
+	// Fields are written in a stable order
+	assert.Less(t, strings.Index(xfdf, `name="country"`), strings.Index(xfdf, `name="full_name"`))
+}
+
+func TestFillSampleForm(t *testing.T) {
+	if _, err := exec.LookPath("pdftk"); err != nil {
+		t.Skip("pdftk not available")
+	}
+
+	config := getTestPDFConfig()
+	config.PdftkPath = "pdftk"
+	generator := NewPDFGenerator(config)
+
+	fields, err := generator.ListFormFields(sampleFormPDF)
+	require.NoError(t, err)
+
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = field.Name
+	}
+	assert.ElementsMatch(t, []string{"full_name", "subscribe", "country"}, names)
+
+	values := map[string]string{
+		"full_name": "Ada Lovelace",
+		"subscribe": "Yes",
+		"country":   "US",
+	}
+
+	result, err := generator.FillForm(sampleFormPDF, values, nil)
+	require.NoError(t, err)
+	defer os.Remove(result.OutputPath)
+
+	filled, err := generator.ListFormFields(result.OutputPath)
+	require.NoError(t, err)
+	for _, field := range filled {
+		assert.Equal(t, values[field.Name], field.Value, field.Name)
+	}
+
+	// Flattening removes the interactive fields
+	flat, err := generator.FillForm(sampleFormPDF, values, &FillFormOptions{Flatten: true})
+	require.NoError(t, err)
+	defer os.Remove(flat.OutputPath)
+	assert.Equal(t, true, flat.Metadata["flattened"])
+
+	flatFields, err := generator.ListFormFields(flat.OutputPath)
+	require.NoError(t, err)
+	assert.Empty(t, flatFields)
+
+	_, err = generator.FillForm(sampleFormPDF, map[string]string{"nope": "x"}, nil)
+	assert.Error(t, err)
+}
+
+func TestSampleFormFixture(t *testing.T) {
+	data, err := os.ReadFile(sampleFormPDF)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "/AcroForm")
+}
@@ -1,7 +1,9 @@
 package pdfgen
 
 import (
+	"context"
 	"documents-worker/config"
+	"documents-worker/libreoffice"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -32,6 +34,36 @@ type GenerationOptions struct {
 	Metadata    map[string]string `json:"metadata"`    // PDF metadata
 	Watermark   string            `json:"watermark"`   // Watermark text
 	Quality     int               `json:"quality"`     // Image quality 1-100
+	// WatermarkPosition is center or diagonal (the default)
+	WatermarkPosition string `json:"watermark_position"`
+	// WatermarkOpacity is between 0 and 1, DefaultWatermarkOpacity when unset
+	WatermarkOpacity float64 `json:"watermark_opacity"`
+	// Fetch, when set, makes Playwright download everything a URL page
+	// loads itself, within these limits
+	Fetch *FetchLimits `json:"-"`
+}
+
+// FetchLimits bound the requests of a page rendered from a URL. Playwright
+// intercepts every request and performs it under these limits.
+type FetchLimits struct {
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds the wait for headers and between reads
+	ReadTimeout time.Duration
+	// MaxSize caps the bytes of all the resources the page loads together
+	MaxSize int64
+	// AllowPrivateNetworks lets the page reach loopback, private and
+	// link-local addresses
+	AllowPrivateNetworks bool
+	// Document, when set, is served for the page itself instead of
+	// downloading it again
+	Document *FetchedDocument
+}
+
+// FetchedDocument is a page already downloaded from URL to Path
+type FetchedDocument struct {
+	URL         string
+	Path        string
+	ContentType string
 }
 
 type GenerationResult struct {
@@ -81,9 +113,19 @@ func (pg *PDFGenerator) GenerateFromHTMLFile(htmlPath string, options *Generatio
 	}
 	outputFile.Close()
 
+	if options != nil && options.Watermark != "" {
+		watermarkedPath, err := writeWatermarkedHTML(htmlPath, options)
+		if err != nil {
+			os.Remove(outputFile.Name())
+			return nil, err
+		}
+		defer os.Remove(watermarkedPath)
+		htmlPath = watermarkedPath
+	}
+
 	// Build wkhtmltopdf command
 	args := pg.buildWkhtmltopdfArgs(htmlPath, outputFile.Name(), options)
-	cmd := exec.Command("wkhtmltopdf", args...)
+	cmd := exec.Command(pg.wkhtmltopdfPath(), args...)
 
 	// Execute command
 	output, err := cmd.CombinedOutput()
@@ -151,26 +193,37 @@ func (pg *PDFGenerator) GenerateFromOfficeDocument(docPath string, options *Gene
 	}
 	outputFile.Close()
 
-	// Convert using LibreOffice
-	outputDir := filepath.Dir(outputFile.Name())
-	cmd := exec.Command(pg.config.LibreOfficePath,
+	// Convert in a private directory so documents with the same name can
+	// be converted concurrently
+	outputDir, err := os.MkdirTemp("", "libreoffice-*")
+	if err != nil {
+		os.Remove(outputFile.Name())
+		return nil, fmt.Errorf("failed to create conversion directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	output, err := libreoffice.Run(context.Background(), pg.config.LibreOfficePath,
 		"--headless",
 		"--convert-to", "pdf",
 		"--outdir", outputDir,
 		docPath,
 	)
-
-	output, err := cmd.CombinedOutput()
 	if err != nil {
+		os.Remove(outputFile.Name())
 		return nil, fmt.Errorf("libreoffice conversion failed: %w, output: %s", err, string(output))
 	}
 
-	// LibreOffice creates file with original name + .pdf
-	originalName := strings.TrimSuffix(filepath.Base(docPath), filepath.Ext(docPath))
-	libreOfficePDF := filepath.Join(outputDir, originalName+".pdf")
+	// The directory is ours alone, so the one PDF in it is the output
+	// whatever name LibreOffice derived from the document
+	produced, _ := filepath.Glob(filepath.Join(outputDir, "*.pdf"))
+	if len(produced) != 1 {
+		os.Remove(outputFile.Name())
+		return nil, fmt.Errorf("libreoffice produced %d PDFs for %s, output: %s", len(produced), filepath.Base(docPath), string(output))
+	}
 
 	// Move to our expected location
-	if err := os.Rename(libreOfficePDF, outputFile.Name()); err != nil {
+	if err := os.Rename(produced[0], outputFile.Name()); err != nil {
+		os.Remove(outputFile.Name())
 		return nil, fmt.Errorf("failed to move generated PDF: %w", err)
 	}
 
@@ -202,6 +255,19 @@ func (pg *PDFGenerator) GenerateFromOfficeDocument(docPath string, options *Gene
 
 // buildWkhtmltopdfArgs builds command arguments for wkhtmltopdf
 func (pg *PDFGenerator) buildWkhtmltopdfArgs(inputPath, outputPath string, options *GenerationOptions) []string {
+	args := pg.buildWkhtmltopdfOptionArgs(options)
+
+	// Enable local file access
+	args = append(args, "--enable-local-file-access")
+
+	// Input and output
+	args = append(args, inputPath, outputPath)
+
+	return args
+}
+
+// buildWkhtmltopdfOptionArgs builds the page layout arguments for wkhtmltopdf
+func (pg *PDFGenerator) buildWkhtmltopdfOptionArgs(options *GenerationOptions) []string {
 	args := []string{}
 
 	if options == nil {
@@ -269,15 +335,17 @@ func (pg *PDFGenerator) buildWkhtmltopdfArgs(inputPath, outputPath string, optio
 		}
 	}
 
-	// Enable local file access
-	args = append(args, "--enable-local-file-access")
-
-	// Input and output
-	args = append(args, inputPath, outputPath)
-
 	return args
 }
 
+// wkhtmltopdfPath returns the configured wkhtmltopdf binary
+func (pg *PDFGenerator) wkhtmltopdfPath() string {
+	if pg.config != nil && pg.config.WkHtmlToPdfPath != "" {
+		return pg.config.WkHtmlToPdfPath
+	}
+	return "wkhtmltopdf"
+}
+
 // convertMarkdownToHTML converts markdown to HTML using a markdown processor
 func (pg *PDFGenerator) convertMarkdownToHTML(markdownContent string) (string, error) {
 	// Create temporary markdown file
@@ -327,7 +395,7 @@ func (pg *PDFGenerator) convertMarkdownToHTML(markdownContent string) (string, e
 
 // getPDFPageCount gets the number of pages in a PDF
 func (pg *PDFGenerator) getPDFPageCount(pdfPath string) (int, error) {
-	cmd := exec.Command(pg.config.MutoolPath, "info", pdfPath)
+	cmd := exec.Command(pg.mutoolPath(), "info", pdfPath)
 	output, err := cmd.Output()
 	if err != nil {
 		return 0, err
@@ -500,10 +568,16 @@ func (pg *PDFGenerator) GenerateFromHTMLFileWithPlaywright(htmlPath string, opti
 	return result, nil
 }
 
-// GenerateFromURLWithPlaywright creates PDF from URL using Playwright
+// GenerateFromURLWithPlaywright creates PDF from URL using Playwright. Only
+// http and https URLs are accepted.
 func (pg *PDFGenerator) GenerateFromURLWithPlaywright(url string, options *GenerationOptions) (*GenerationResult, error) {
 	startTime := time.Now()
 
+	// The script treats anything else as a file path or HTML content
+	if _, err := ValidatePDFURL(url); err != nil {
+		return nil, err
+	}
+
 	// Create output PDF file
 	outputFile, err := os.CreateTemp("", "generated-*.pdf")
 	if err != nil {
@@ -600,6 +674,18 @@ func (pg *PDFGenerator) buildPlaywrightOptions(options *GenerationOptions) strin
 		}
 	}
 
+	// The script draws the watermark on every page
+	if options.Watermark != "" {
+		position, opacity := watermarkStyle(options)
+		playwrightOpts["watermark"] = options.Watermark
+		playwrightOpts["watermarkPosition"] = position
+		playwrightOpts["watermarkOpacity"] = opacity
+	}
+
+	if options.Fetch != nil {
+		playwrightOpts["fetch"] = playwrightFetchOptions(options.Fetch)
+	}
+
 	// Convert to JSON
 	jsonBytes, err := json.Marshal(playwrightOpts)
 	if err != nil {
@@ -609,6 +695,25 @@ func (pg *PDFGenerator) buildPlaywrightOptions(options *GenerationOptions) strin
 	return string(jsonBytes)
 }
 
+// playwrightFetchOptions converts fetch limits to the script's options,
+// with durations in milliseconds
+func playwrightFetchOptions(limits *FetchLimits) map[string]interface{} {
+	fetch := map[string]interface{}{
+		"connectTimeout":       limits.ConnectTimeout.Milliseconds(),
+		"readTimeout":          limits.ReadTimeout.Milliseconds(),
+		"maxSize":              limits.MaxSize,
+		"allowPrivateNetworks": limits.AllowPrivateNetworks,
+	}
+	if limits.Document != nil {
+		fetch["document"] = map[string]string{
+			"url":         limits.Document.URL,
+			"path":        limits.Document.Path,
+			"contentType": limits.Document.ContentType,
+		}
+	}
+	return fetch
+}
+
 // parseJSONOutput parses JSON output from Playwright script
 func parseJSONOutput(output string, target interface{}) error {
 	// Find the last JSON object in output (in case there are logs before it)
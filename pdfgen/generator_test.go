@@ -2,11 +2,16 @@ package pdfgen
 
 import (
 	"documents-worker/config"
+	"documents-worker/libreoffice"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Helper function to get test PDF generator config
@@ -415,6 +420,94 @@ func TestOfficeDocumentToPDFGeneration(t *testing.T) {
 }
 
 // Test Error Handling
+// fakeSoffice converts by copying the input into --outdir as a PDF. It fails
+// when another process holds its profile or too many run at once, as real
+// LibreOffice instances do when they share a profile.
+const fakeSoffice = `#!/bin/sh
+profile="${1#-env:UserInstallation=file://}"
+mkdir -p "$(dirname "$profile")"
+mkdir "$profile.lock" 2>/dev/null || { echo "profile in use" >&2; exit 1; }
+touch "$ACTIVE/$$"
+count=$(ls "$ACTIVE" | wc -l)
+while [ $# -gt 0 ]; do
+	case "$1" in --outdir) outdir="$2"; shift ;; esac
+	doc="$1"
+	shift
+done
+sleep 0.05
+name=$(basename "$doc")
+cp "$doc" "$outdir/${name%.*}.pdf"
+rm "$ACTIVE/$$"
+rmdir "$profile.lock"
+[ "$count" -le 2 ] || { echo "$count instances running" >&2; exit 2; }
+`
+
+func TestConcurrentOfficeDocumentConversions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake soffice is a shell script")
+	}
+
+	dir := t.TempDir()
+	active := filepath.Join(dir, "active")
+	require.NoError(t, os.Mkdir(active, 0755))
+	t.Setenv("ACTIVE", active)
+	soffice := filepath.Join(dir, "soffice")
+	require.NoError(t, os.WriteFile(soffice, []byte(fakeSoffice), 0755))
+
+	libreoffice.Configure(2, filepath.Join(dir, "profiles"), libreoffice.RetryPolicy{})
+	defer libreoffice.Configure(libreoffice.DefaultMaxConcurrent, libreoffice.DefaultProfileDir, libreoffice.RetryPolicy{})
+
+	generator := NewPDFGenerator(&config.ExternalConfig{LibreOfficePath: soffice})
+
+	// Documents share a name so their outputs would collide in one directory
+	const conversions = 6
+	var wg sync.WaitGroup
+	results := make([]*GenerationResult, conversions)
+	errs := make([]error, conversions)
+	for i := 0; i < conversions; i++ {
+		docDir := filepath.Join(dir, fmt.Sprintf("doc-%d", i))
+		require.NoError(t, os.Mkdir(docDir, 0755))
+		docPath := filepath.Join(docDir, "report.docx")
+		require.NoError(t, os.WriteFile(docPath, []byte(fmt.Sprintf("document %d", i)), 0644))
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = generator.GenerateFromOfficeDocument(docPath, &GenerationOptions{})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < conversions; i++ {
+		require.NoError(t, errs[i])
+		data, err := os.ReadFile(results[i].OutputPath)
+		require.NoError(t, err)
+		os.Remove(results[i].OutputPath)
+		assert.Equal(t, fmt.Sprintf("document %d", i), string(data))
+	}
+}
+
+func TestOfficeDocumentConversionWithoutOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake soffice is a shell script")
+	}
+
+	dir := t.TempDir()
+	soffice := filepath.Join(dir, "soffice")
+	require.NoError(t, os.WriteFile(soffice, []byte("#!/bin/sh\necho 'Error: source file could not be loaded'\n"), 0755))
+	docPath := filepath.Join(dir, "report.docx")
+	require.NoError(t, os.WriteFile(docPath, []byte("document"), 0644))
+
+	libreoffice.Configure(1, filepath.Join(dir, "profiles"), libreoffice.RetryPolicy{})
+	defer libreoffice.Configure(libreoffice.DefaultMaxConcurrent, libreoffice.DefaultProfileDir, libreoffice.RetryPolicy{})
+
+	generator := NewPDFGenerator(&config.ExternalConfig{LibreOfficePath: soffice})
+	_, err := generator.GenerateFromOfficeDocument(docPath, &GenerationOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "produced 0 PDFs")
+	assert.Contains(t, err.Error(), "could not be loaded")
+}
+
 func TestPDFGenerationErrorHandling(t *testing.T) {
 	config := getTestPDFConfig()
 	generator := NewPDFGenerator(config)
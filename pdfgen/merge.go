@@ -0,0 +1,91 @@
+package pdfgen
+
+import (
+	"documents-worker/utils"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// MergePDFs concatenates PDFs in order into outputPath with mutool merge.
+// The page count of the result is the sum of the input page counts.
+func (pg *PDFGenerator) MergePDFs(inputPaths []string, outputPath string) (*GenerationResult, error) {
+	startTime := time.Now()
+
+	if len(inputPaths) == 0 {
+		return nil, fmt.Errorf("no PDFs to merge")
+	}
+	if outputPath == "" {
+		return nil, fmt.Errorf("no output path for merged PDF")
+	}
+
+	pageCount := 0
+	for _, inputPath := range inputPaths {
+		info, err := os.Stat(inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot merge %s: %w", inputPath, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("cannot merge %s: is a directory", inputPath)
+		}
+		mimeType, err := utils.DetectMimeTypeFromFile(inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot merge %s: %w", inputPath, err)
+		}
+		if !utils.IsPdfDocument(mimeType) {
+			return nil, fmt.Errorf("cannot merge %s: not a PDF (%s)", inputPath, mimeType)
+		}
+
+		pages, err := pg.getPDFPageCount(inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count pages of %s: %w", inputPath, err)
+		}
+		pageCount += pages
+	}
+
+	cmd := exec.Command(pg.mutoolPath(), mutoolMergeArgs(inputPaths, outputPath)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(outputPath)
+		return nil, fmt.Errorf("mutool merge failed: %w, output: %s", err, string(output))
+	}
+
+	fileInfo, err := os.Stat(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	sources := make([]string, len(inputPaths))
+	for i, inputPath := range inputPaths {
+		sources[i] = filepath.Base(inputPath)
+	}
+
+	return &GenerationResult{
+		OutputPath:  outputPath,
+		InputType:   "pdf",
+		GeneratedAt: time.Now(),
+		Duration:    time.Since(startTime),
+		FileSize:    fileInfo.Size(),
+		PageCount:   pageCount,
+		Metadata: map[string]interface{}{
+			"generator":    "mutool",
+			"operation":    "merge",
+			"source_files": sources,
+		},
+	}, nil
+}
+
+// mutoolMergeArgs builds the mutool merge arguments writing the inputs, all
+// of their pages in order, to outputPath
+func mutoolMergeArgs(inputPaths []string, outputPath string) []string {
+	return append([]string{"merge", "-o", outputPath}, inputPaths...)
+}
+
+// mutoolPath returns the configured mutool binary
+func (pg *PDFGenerator) mutoolPath() string {
+	if pg.config != nil && pg.config.MutoolPath != "" {
+		return pg.config.MutoolPath
+	}
+	return "mutool"
+}
@@ -0,0 +1,74 @@
+package pdfgen
+
+import (
+	"documents-worker/config"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMergeMutool reports two pages per PDF and merges by concatenating
+const fakeMergeMutool = `#!/bin/sh
+case "$1" in
+info) echo "Pages: 2" ;;
+merge) out=$3; shift 3; cat "$@" > "$out" ;;
+*) exit 1 ;;
+esac
+`
+
+func TestMutoolMergeArgs(t *testing.T) {
+	assert.Equal(t,
+		[]string{"merge", "-o", "out.pdf", "a.pdf", "b.pdf", "c.pdf"},
+		mutoolMergeArgs([]string{"a.pdf", "b.pdf", "c.pdf"}, "out.pdf"))
+}
+
+func TestMergePDFs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake mutool is a shell script")
+	}
+
+	dir := t.TempDir()
+	mutool := filepath.Join(dir, "mutool")
+	require.NoError(t, os.WriteFile(mutool, []byte(fakeMergeMutool), 0755))
+	generator := NewPDFGenerator(&config.ExternalConfig{MutoolPath: mutool})
+
+	first := filepath.Join(dir, "first.pdf")
+	second := filepath.Join(dir, "second.pdf")
+	require.NoError(t, os.WriteFile(first, []byte("%PDF-1.4\n% first\n%%EOF\n"), 0644))
+	require.NoError(t, os.WriteFile(second, []byte("%PDF-1.4\n% second\n%%EOF\n"), 0644))
+
+	output := filepath.Join(dir, "merged.pdf")
+	result, err := generator.MergePDFs([]string{second, first}, output)
+	require.NoError(t, err)
+	assert.Equal(t, output, result.OutputPath)
+	assert.Equal(t, 4, result.PageCount)
+	assert.Equal(t, []string{"second.pdf", "first.pdf"}, result.Metadata["source_files"])
+
+	merged, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4\n% second\n%%EOF\n%PDF-1.4\n% first\n%%EOF\n", string(merged))
+	assert.Equal(t, int64(len(merged)), result.FileSize)
+}
+
+func TestMergePDFsRejectsInvalidInput(t *testing.T) {
+	dir := t.TempDir()
+	generator := NewPDFGenerator(&config.ExternalConfig{MutoolPath: filepath.Join(dir, "missing-mutool")})
+	output := filepath.Join(dir, "merged.pdf")
+
+	_, err := generator.MergePDFs(nil, output)
+	assert.EqualError(t, err, "no PDFs to merge")
+
+	_, err = generator.MergePDFs([]string{filepath.Join(dir, "missing.pdf")}, output)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	text := filepath.Join(dir, "notes.pdf")
+	require.NoError(t, os.WriteFile(text, []byte("just some text"), 0644))
+	_, err = generator.MergePDFs([]string{text}, output)
+	assert.ErrorContains(t, err, "not a PDF")
+
+	assert.NoFileExists(t, output)
+}
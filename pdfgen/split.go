@@ -0,0 +1,131 @@
+package pdfgen
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PageRange is an inclusive, 1-based range of pages
+type PageRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// String formats the range the way mutool page lists write it
+func (r PageRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// ParsePageRanges parses comma separated ranges such as "1-3,4-6,9"
+func ParsePageRanges(value string) ([]PageRange, error) {
+	var ranges []PageRange
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		start, end, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(start))
+		if err != nil {
+			return nil, fmt.Errorf("invalid page range %q", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(strings.TrimSpace(end)); err != nil {
+				return nil, fmt.Errorf("invalid page range %q", part)
+			}
+		}
+		if first < 1 || last < first {
+			return nil, fmt.Errorf("invalid page range %q: pages start at 1 and ranges must not be reversed", part)
+		}
+		ranges = append(ranges, PageRange{Start: first, End: last})
+	}
+
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no page ranges given")
+	}
+	return ranges, nil
+}
+
+// SplitPDF extracts each page range of a PDF into its own file in outputDir,
+// named after the input and the range, e.g. report_pages_1-3.pdf. Ranges
+// must lie within the document.
+func (pg *PDFGenerator) SplitPDF(inputPath string, ranges []PageRange, outputDir string) ([]*GenerationResult, error) {
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no page ranges given")
+	}
+
+	pageCount, err := pg.getPDFPageCount(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pages of %s: %w", inputPath, err)
+	}
+	for _, r := range ranges {
+		if r.Start < 1 || r.End < r.Start {
+			return nil, fmt.Errorf("invalid page range %s", r)
+		}
+		if r.End > pageCount {
+			return nil, fmt.Errorf("page range %s is out of bounds: %s has %d pages", r, filepath.Base(inputPath), pageCount)
+		}
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	results := make([]*GenerationResult, 0, len(ranges))
+	// A failed range removes the files already written
+	fail := func(err error) ([]*GenerationResult, error) {
+		for _, result := range results {
+			os.Remove(result.OutputPath)
+		}
+		return nil, err
+	}
+	for _, r := range ranges {
+		startTime := time.Now()
+		outputPath := filepath.Join(outputDir, fmt.Sprintf("%s_pages_%s.pdf", baseName, r))
+
+		cmd := exec.Command(pg.mutoolPath(), mutoolSplitArgs(inputPath, r, outputPath)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			os.Remove(outputPath)
+			return fail(fmt.Errorf("mutool failed to extract pages %s: %w, output: %s", r, err, string(output)))
+		}
+
+		fileInfo, err := os.Stat(outputPath)
+		if err != nil {
+			return fail(fmt.Errorf("failed to get file info: %w", err))
+		}
+
+		results = append(results, &GenerationResult{
+			OutputPath:  outputPath,
+			InputType:   "pdf",
+			GeneratedAt: time.Now(),
+			Duration:    time.Since(startTime),
+			FileSize:    fileInfo.Size(),
+			PageCount:   r.End - r.Start + 1,
+			Metadata: map[string]interface{}{
+				"generator":   "mutool",
+				"operation":   "split",
+				"source_file": filepath.Base(inputPath),
+				"pages":       r.String(),
+			},
+		})
+	}
+
+	return results, nil
+}
+
+// mutoolSplitArgs builds the mutool arguments copying the pages of r into
+// outputPath; mutool merge takes a page list after each input
+func mutoolSplitArgs(inputPath string, r PageRange, outputPath string) []string {
+	return []string{"merge", "-o", outputPath, inputPath, r.String()}
+}
@@ -0,0 +1,84 @@
+package pdfgen
+
+import (
+	"documents-worker/config"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSplitMutool reports six pages and writes the requested page list
+// into each extracted file
+const fakeSplitMutool = `#!/bin/sh
+case "$1" in
+info) echo "Pages: 6" ;;
+merge) echo "pages $5 of $4" > "$3" ;;
+*) exit 1 ;;
+esac
+`
+
+func TestParsePageRanges(t *testing.T) {
+	ranges, err := ParsePageRanges("1-3, 4-6,9")
+	require.NoError(t, err)
+	assert.Equal(t, []PageRange{{1, 3}, {4, 6}, {9, 9}}, ranges)
+
+	for _, invalid := range []string{"", ",", "0-2", "3-1", "a-b", "2-", "1-3-5"} {
+		_, err := ParsePageRanges(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMutoolSplitArgs(t *testing.T) {
+	assert.Equal(t,
+		[]string{"merge", "-o", "out/doc_pages_1-3.pdf", "doc.pdf", "1-3"},
+		mutoolSplitArgs("doc.pdf", PageRange{1, 3}, "out/doc_pages_1-3.pdf"))
+	assert.Equal(t,
+		[]string{"merge", "-o", "page.pdf", "doc.pdf", "5"},
+		mutoolSplitArgs("doc.pdf", PageRange{5, 5}, "page.pdf"))
+}
+
+func writeSplitSample(t *testing.T) (*PDFGenerator, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake mutool is a shell script")
+	}
+
+	dir := t.TempDir()
+	mutool := filepath.Join(dir, "mutool")
+	require.NoError(t, os.WriteFile(mutool, []byte(fakeSplitMutool), 0755))
+	input := filepath.Join(dir, "report.pdf")
+	require.NoError(t, os.WriteFile(input, []byte("%PDF-1.4\n%%EOF\n"), 0644))
+	return NewPDFGenerator(&config.ExternalConfig{MutoolPath: mutool}), input
+}
+
+func TestSplitPDF(t *testing.T) {
+	generator, input := writeSplitSample(t)
+	outputDir := filepath.Join(t.TempDir(), "parts")
+
+	results, err := generator.SplitPDF(input, []PageRange{{1, 3}, {4, 6}}, outputDir)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, filepath.Join(outputDir, "report_pages_1-3.pdf"), results[0].OutputPath)
+	assert.Equal(t, 3, results[0].PageCount)
+	assert.Equal(t, "4-6", results[1].Metadata["pages"])
+	content, err := os.ReadFile(results[1].OutputPath)
+	require.NoError(t, err)
+	assert.Equal(t, "pages 4-6 of "+input+"\n", string(content))
+}
+
+func TestSplitPDFRejectsOutOfBoundsRange(t *testing.T) {
+	generator, input := writeSplitSample(t)
+	outputDir := filepath.Join(t.TempDir(), "parts")
+
+	_, err := generator.SplitPDF(input, []PageRange{{1, 3}, {5, 8}}, outputDir)
+	assert.EqualError(t, err, "page range 5-8 is out of bounds: report.pdf has 6 pages")
+	assert.NoDirExists(t, outputDir)
+
+	_, err = generator.SplitPDF(input, nil, outputDir)
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package pdfgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultURLTimeout bounds rendering a URL when no timeout is configured
+const DefaultURLTimeout = time.Minute
+
+// allowedURLSchemes are the schemes URLs may be rendered from. Anything
+// else, file: in particular, could read local files into the PDF.
+var allowedURLSchemes = map[string]bool{"http": true, "https": true}
+
+// ValidatePDFURL checks that a URL can be rendered to PDF: it must be an
+// absolute http or https URL with a host
+func ValidatePDFURL(rawURL string) (*url.URL, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if !allowedURLSchemes[strings.ToLower(parsed.Scheme)] {
+		return nil, fmt.Errorf("unsupported URL scheme %q: only http and https are allowed", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: missing host", rawURL)
+	}
+	return parsed, nil
+}
+
+// GenerateFromURL renders a web page to PDF with wkhtmltopdf. Only http and
+// https URLs are accepted, and the page may not read local files. Rendering
+// stops after the configured PDFURLTimeout.
+func (pg *PDFGenerator) GenerateFromURL(rawURL string, options *GenerationOptions) (*GenerationResult, error) {
+	startTime := time.Now()
+
+	target, err := ValidatePDFURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create output PDF file
+	outputFile, err := os.CreateTemp("", "generated-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp PDF file: %w", err)
+	}
+	outputFile.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), pg.urlTimeout())
+	defer cancel()
+
+	args := pg.buildWkhtmltopdfURLArgs(target.String(), outputFile.Name(), options)
+	cmd := exec.CommandContext(ctx, pg.wkhtmltopdfPath(), args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		os.Remove(outputFile.Name())
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("wkhtmltopdf timed out after %v rendering %s", pg.urlTimeout(), target.Redacted())
+		}
+		return nil, fmt.Errorf("wkhtmltopdf URL generation failed: %w, output: %s", err, string(output))
+	}
+
+	// Get file info
+	fileInfo, err := os.Stat(outputFile.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	// Get page count
+	pageCount, _ := pg.getPDFPageCount(outputFile.Name())
+
+	return &GenerationResult{
+		OutputPath:  outputFile.Name(),
+		InputType:   "url",
+		GeneratedAt: startTime,
+		Duration:    time.Since(startTime),
+		FileSize:    fileInfo.Size(),
+		PageCount:   pageCount,
+		Metadata: map[string]interface{}{
+			"generator":  "wkhtmltopdf",
+			"source_url": target.Redacted(),
+		},
+	}, nil
+}
+
+// buildWkhtmltopdfURLArgs builds wkhtmltopdf arguments for a remote page.
+// Local file access stays disabled so the page cannot pull in files from
+// the worker.
+func (pg *PDFGenerator) buildWkhtmltopdfURLArgs(pageURL, outputPath string, options *GenerationOptions) []string {
+	args := pg.buildWkhtmltopdfOptionArgs(options)
+	args = append(args, "--disable-local-file-access")
+	// A remote page cannot be edited before rendering, so the watermark is
+	// added by a script run once the page has loaded
+	if watermark := watermarkHTML(options); watermark != "" {
+		args = append(args, "--run-script", watermarkScript(watermark))
+	}
+	return append(args, pageURL, outputPath)
+}
+
+// watermarkScript returns JavaScript appending the watermark element to
+// the page body
+func watermarkScript(watermark string) string {
+	quoted, _ := json.Marshal(watermark)
+	return "document.body.insertAdjacentHTML('beforeend', " + string(quoted) + ");"
+}
+
+// urlTimeout returns the configured URL rendering timeout
+func (pg *PDFGenerator) urlTimeout() time.Duration {
+	if pg.config != nil && pg.config.PDFURLTimeout > 0 {
+		return pg.config.PDFURLTimeout
+	}
+	return DefaultURLTimeout
+}
@@ -0,0 +1,153 @@
+package pdfgen
+
+import (
+	"documents-worker/config"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePDFURL(t *testing.T) {
+	for _, valid := range []string{"https://example.com/report", "http://intranet:8080/page?id=1", " HTTPS://example.com "} {
+		_, err := ValidatePDFURL(valid)
+		assert.NoError(t, err, valid)
+	}
+
+	for _, invalid := range []string{
+		"file:///etc/passwd",
+		"ftp://example.com/doc.html",
+		"javascript:alert(1)",
+		"example.com/page",
+		"/tmp/page.html",
+		"https://",
+		"http://[::1",
+	} {
+		_, err := ValidatePDFURL(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestBuildWkhtmltopdfURLArgs(t *testing.T) {
+	pg := NewPDFGenerator(&config.ExternalConfig{})
+	args := pg.buildWkhtmltopdfURLArgs("https://example.com", "out.pdf", &GenerationOptions{
+		PageSize:    "A4",
+		Orientation: "landscape",
+		Margins:     map[string]string{"top": "10mm"},
+	})
+
+	assert.Equal(t, []string{
+		"--page-size", "A4",
+		"--orientation", "landscape",
+		"--margin-top", "10mm",
+		"--disable-local-file-access",
+		"https://example.com", "out.pdf",
+	}, args)
+	assert.NotContains(t, args, "--enable-local-file-access")
+	assert.NotContains(t, args, "--run-script")
+
+	args = pg.buildWkhtmltopdfURLArgs("https://example.com", "out.pdf", &GenerationOptions{
+		PageSize:          "A4",
+		Watermark:         `<Draft> "v2"`,
+		WatermarkPosition: WatermarkCenter,
+		WatermarkOpacity:  0.4,
+	})
+	require.Len(t, args, 7)
+	assert.Equal(t, []string{"--page-size", "A4", "--disable-local-file-access", "--run-script"}, args[:4])
+	assert.Equal(t, []string{"https://example.com", "out.pdf"}, args[5:])
+
+	// The script carries the watermark element as a JSON string literal
+	script, ok := strings.CutPrefix(args[4], "document.body.insertAdjacentHTML('beforeend', ")
+	require.True(t, ok, args[4])
+	var watermark string
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSuffix(script, ");")), &watermark))
+	assert.Contains(t, watermark, `class="documents-worker-watermark"`)
+	assert.Contains(t, watermark, "opacity: 0.4")
+	assert.Contains(t, watermark, "translate(-50%, -50%);")
+	assert.Contains(t, watermark, "&lt;Draft&gt; &#34;v2&#34;")
+
+	// Local HTML files keep access to their own resources
+	assert.Contains(t, pg.buildWkhtmltopdfArgs("page.html", "out.pdf", nil), "--enable-local-file-access")
+}
+
+func TestGenerateFromURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake wkhtmltopdf is a shell script")
+	}
+
+	dir := t.TempDir()
+	wkhtmltopdf := filepath.Join(dir, "wkhtmltopdf")
+	// Writes its arguments to the output so the test can inspect them
+	require.NoError(t, os.WriteFile(wkhtmltopdf, []byte("#!/bin/sh\nfor last; do :; done\necho \"$@\" > \"$last\"\n"), 0755))
+	pg := NewPDFGenerator(&config.ExternalConfig{WkHtmlToPdfPath: wkhtmltopdf, MutoolPath: filepath.Join(dir, "mutool")})
+
+	result, err := pg.GenerateFromURL("https://example.com/report", nil)
+	require.NoError(t, err)
+	defer os.Remove(result.OutputPath)
+	assert.Equal(t, "url", result.InputType)
+	assert.Equal(t, "https://example.com/report", result.Metadata["source_url"])
+	content, err := os.ReadFile(result.OutputPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "--disable-local-file-access https://example.com/report")
+
+	_, err = pg.GenerateFromURL("file:///etc/passwd", nil)
+	assert.ErrorContains(t, err, "unsupported URL scheme")
+}
+
+func TestGenerateFromURLTimesOut(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake wkhtmltopdf is a shell script")
+	}
+
+	dir := t.TempDir()
+	wkhtmltopdf := filepath.Join(dir, "wkhtmltopdf")
+	require.NoError(t, os.WriteFile(wkhtmltopdf, []byte("#!/bin/sh\nexec sleep 5\n"), 0755))
+	pg := NewPDFGenerator(&config.ExternalConfig{WkHtmlToPdfPath: wkhtmltopdf, PDFURLTimeout: 100 * time.Millisecond})
+
+	start := time.Now()
+	_, err := pg.GenerateFromURL("https://example.com", nil)
+	assert.ErrorContains(t, err, "timed out")
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestGenerateFromURLWithPlaywrightRejectsNonHTTPURLs(t *testing.T) {
+	pg := NewPDFGenerator(&config.ExternalConfig{})
+	for _, rawURL := range []string{"/etc/passwd", "file:///etc/passwd", "<iframe src=file:///etc/passwd>"} {
+		_, err := pg.GenerateFromURLWithPlaywright(rawURL, &GenerationOptions{})
+		assert.Error(t, err, rawURL)
+	}
+}
+
+func TestPlaywrightOptionsCarryFetchLimits(t *testing.T) {
+	pg := NewPDFGenerator(nil)
+
+	var opts map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(pg.buildPlaywrightOptions(&GenerationOptions{PageSize: "A4"})), &opts))
+	assert.NotContains(t, opts, "fetch")
+
+	require.NoError(t, json.Unmarshal([]byte(pg.buildPlaywrightOptions(&GenerationOptions{
+		Fetch: &FetchLimits{
+			ConnectTimeout: 2 * time.Second,
+			ReadTimeout:    5 * time.Second,
+			MaxSize:        1024,
+			Document:       &FetchedDocument{URL: "https://example.com/", Path: "/tmp/fetch-1", ContentType: "text/html"},
+		},
+	})), &opts))
+	assert.Equal(t, map[string]interface{}{
+		"connectTimeout":       float64(2000),
+		"readTimeout":          float64(5000),
+		"maxSize":              float64(1024),
+		"allowPrivateNetworks": false,
+		"document": map[string]interface{}{
+			"url":         "https://example.com/",
+			"path":        "/tmp/fetch-1",
+			"contentType": "text/html",
+		},
+	}, opts["fetch"])
+}
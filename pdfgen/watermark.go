@@ -0,0 +1,100 @@
+package pdfgen
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Watermark positions
+const (
+	WatermarkCenter   = "center"
+	WatermarkDiagonal = "diagonal"
+)
+
+// DefaultWatermarkOpacity is used when no opacity between 0 and 1 is given
+const DefaultWatermarkOpacity = 0.15
+
+// watermarkStyle returns the position and opacity of the watermark, falling
+// back to a diagonal mark at the default opacity
+func watermarkStyle(options *GenerationOptions) (string, float64) {
+	position := options.WatermarkPosition
+	if position != WatermarkCenter {
+		position = WatermarkDiagonal
+	}
+	opacity := options.WatermarkOpacity
+	if opacity <= 0 || opacity > 1 {
+		opacity = DefaultWatermarkOpacity
+	}
+	return position, opacity
+}
+
+// watermarkHTML returns a fixed, semi-transparent element holding the
+// watermark text, or "" when no watermark is set. wkhtmltopdf repeats fixed
+// elements on every page.
+func watermarkHTML(options *GenerationOptions) string {
+	if options == nil || options.Watermark == "" {
+		return ""
+	}
+
+	position, opacity := watermarkStyle(options)
+	transform := "translate(-50%, -50%)"
+	if position == WatermarkDiagonal {
+		transform += " rotate(-45deg)"
+	}
+
+	style := []string{
+		"position: fixed",
+		"top: 50%",
+		"left: 50%",
+		"-webkit-transform: " + transform,
+		"transform: " + transform,
+		"opacity: " + strconv.FormatFloat(opacity, 'f', -1, 64),
+		"color: #000",
+		"font-family: Arial, sans-serif",
+		"font-size: 72px",
+		"font-weight: bold",
+		"white-space: nowrap",
+		"pointer-events: none",
+		"z-index: 9999",
+	}
+	return fmt.Sprintf(`<div class="documents-worker-watermark" style="%s">%s</div>`,
+		strings.Join(style, "; "), html.EscapeString(options.Watermark))
+}
+
+// injectWatermark inserts the watermark element at the end of the body, or
+// at the end of the document when it has no closing body tag
+func injectWatermark(htmlContent, watermark string) string {
+	if i := strings.LastIndex(strings.ToLower(htmlContent), "</body>"); i >= 0 {
+		return htmlContent[:i] + watermark + htmlContent[i:]
+	}
+	return htmlContent + watermark
+}
+
+// writeWatermarkedHTML copies an HTML file with the watermark injected. The
+// copy sits next to the original when possible so relative links still
+// resolve; the caller removes it.
+func writeWatermarkedHTML(htmlPath string, options *GenerationOptions) (string, error) {
+	content, err := os.ReadFile(htmlPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read HTML for watermark: %w", err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(htmlPath), ".watermarked-*.html")
+	if err != nil {
+		file, err = os.CreateTemp("", "watermarked-*.html")
+		if err != nil {
+			return "", fmt.Errorf("failed to create watermarked HTML file: %w", err)
+		}
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(injectWatermark(string(content), watermarkHTML(options))); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to write watermarked HTML: %w", err)
+	}
+	return file.Name(), nil
+}
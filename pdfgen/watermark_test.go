@@ -0,0 +1,65 @@
+package pdfgen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatermarkHTML(t *testing.T) {
+	assert.Empty(t, watermarkHTML(nil))
+	assert.Empty(t, watermarkHTML(&GenerationOptions{PageSize: "A4"}))
+
+	mark := watermarkHTML(&GenerationOptions{Watermark: "DRAFT <internal>"})
+	assert.Contains(t, mark, "DRAFT &lt;internal&gt;")
+	assert.Contains(t, mark, "position: fixed")
+	assert.Contains(t, mark, "rotate(-45deg)")
+	assert.Contains(t, mark, "opacity: 0.15")
+
+	mark = watermarkHTML(&GenerationOptions{Watermark: "DRAFT", WatermarkPosition: WatermarkCenter, WatermarkOpacity: 0.4})
+	assert.NotContains(t, mark, "rotate")
+	assert.Contains(t, mark, "opacity: 0.4")
+}
+
+func TestInjectWatermark(t *testing.T) {
+	assert.Equal(t, "<html><BODY><p>Hi</p>MARK</BODY></html>", injectWatermark("<html><BODY><p>Hi</p></BODY></html>", "MARK"))
+	assert.Equal(t, "<p>Hi</p>MARK", injectWatermark("<p>Hi</p>", "MARK"))
+}
+
+func TestWriteWatermarkedHTMLStaysNextToOriginal(t *testing.T) {
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "page.html")
+	require.NoError(t, os.WriteFile(htmlPath, []byte(`<body><img src="logo.png"></body>`), 0644))
+
+	watermarked, err := writeWatermarkedHTML(htmlPath, &GenerationOptions{Watermark: "CONFIDENTIAL"})
+	require.NoError(t, err)
+	defer os.Remove(watermarked)
+
+	assert.Equal(t, dir, filepath.Dir(watermarked))
+	content, err := os.ReadFile(watermarked)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `<img src="logo.png"><div class="documents-worker-watermark"`)
+	assert.Contains(t, string(content), "CONFIDENTIAL</div></body>")
+}
+
+func TestPlaywrightOptionsCarryWatermark(t *testing.T) {
+	pg := NewPDFGenerator(nil)
+
+	var opts map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(pg.buildPlaywrightOptions(&GenerationOptions{PageSize: "A4"})), &opts))
+	assert.NotContains(t, opts, "watermark")
+	assert.NotContains(t, opts, "watermarkPosition")
+
+	require.NoError(t, json.Unmarshal([]byte(pg.buildPlaywrightOptions(&GenerationOptions{
+		Watermark:         "DRAFT",
+		WatermarkPosition: WatermarkCenter,
+		WatermarkOpacity:  0.3,
+	})), &opts))
+	assert.Equal(t, "DRAFT", opts["watermark"])
+	assert.Equal(t, WatermarkCenter, opts["watermarkPosition"])
+	assert.Equal(t, 0.3, opts["watermarkOpacity"])
+}
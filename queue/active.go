@@ -0,0 +1,46 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// activeSetName is the set of IDs of jobs that are pending or processing.
+// Their payload paths must survive temp file cleanup.
+func (q *RedisQueue) activeSetName() string {
+	return q.config.QueueName + ":active"
+}
+
+// ActivePaths returns the file paths referenced by the payloads of jobs
+// that have not finished yet. Finished or expired jobs found in the active
+// set are pruned from it.
+func (q *RedisQueue) ActivePaths(ctx context.Context) ([]string, error) {
+	ids, err := q.client.SMembers(ctx, q.activeSetName()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active jobs: %w", err)
+	}
+
+	var paths []string
+	for _, id := range ids {
+		job, err := q.GetJob(ctx, id)
+		if err != nil || job.Status.IsTerminal() {
+			q.client.SRem(ctx, q.activeSetName(), id)
+			continue
+		}
+		paths = append(paths, payloadPaths(job.Payload)...)
+	}
+	return paths, nil
+}
+
+// payloadPaths collects the values of payload keys naming files, such as
+// input_path or file_path
+func payloadPaths(payload map[string]interface{}) []string {
+	var paths []string
+	for key, value := range payload {
+		if path, ok := value.(string); ok && path != "" && (key == "path" || strings.HasSuffix(key, "_path")) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,20 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayloadPaths(t *testing.T) {
+	paths := payloadPaths(map[string]interface{}{
+		"input_path":  "/tmp/upload-1",
+		"file_path":   "/tmp/input-2.pdf",
+		"output_path": "",
+		"format":      "webp",
+		"page_count":  3,
+		"xpath":       "/not/a/file/key",
+	})
+	assert.ElementsMatch(t, []string{"/tmp/upload-1", "/tmp/input-2.pdf"}, paths)
+	assert.Empty(t, payloadPaths(nil))
+}
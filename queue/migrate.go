@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// legacyQueueKeys returns the lists used before the queue name became a
+// hash tag, paired with the lists that replace them. They held full job
+// JSON rather than queue entries.
+func (q *RedisQueue) legacyQueueKeys() map[string]string {
+	return map[string]string{
+		q.config.QueueName + ":priority": q.priorityQueueName(),
+		q.config.QueueName:               q.queueName(),
+	}
+}
+
+// MigrateLegacyQueues moves jobs left in the lists of earlier versions into
+// the current ones, oldest at the front, and returns how many were moved.
+// It is safe to run on every start: once the old lists are empty it does
+// nothing.
+func (q *RedisQueue) MigrateLegacyQueues(ctx context.Context) (int, error) {
+	moved := 0
+	for legacy, current := range q.legacyQueueKeys() {
+		for {
+			// Taking the newest job and appending it behind the jobs
+			// already moved keeps the oldest job nearest the tail
+			data, err := q.client.LPop(ctx, legacy).Result()
+			if errors.Is(err, redis.Nil) {
+				break
+			}
+			if err != nil {
+				return moved, fmt.Errorf("failed to read legacy queue %s: %w", legacy, err)
+			}
+
+			entry, err := legacyEntry(data)
+			if err != nil {
+				// Keep the job where it was rather than lose it
+				q.client.LPush(ctx, legacy, data)
+				return moved, err
+			}
+			if err := q.client.RPush(ctx, current, entry).Err(); err != nil {
+				q.client.LPush(ctx, legacy, data)
+				return moved, fmt.Errorf("failed to move job to %s: %w", current, err)
+			}
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// legacyEntry converts a job stored in a legacy list into a queue entry
+func legacyEntry(data string) (string, error) {
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return "", fmt.Errorf("failed to unmarshal legacy queued job: %w", err)
+	}
+	if job.ID == "" {
+		return "", fmt.Errorf("legacy queued job has no ID")
+	}
+	return queueEntry(&job)
+}
@@ -0,0 +1,282 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// durationSamples is the number of recent processing durations kept per job type
+const durationSamples = 100
+
+// maxAheadSample bounds how many of the jobs ahead are read to estimate the
+// wait. Longer queues extrapolate from the jobs nearest the front.
+const maxAheadSample = 1000
+
+// queuedJob is a queue list entry. The full job is stored under its job key;
+// the list only holds what is needed to find a job and estimate waits.
+type queuedJob struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// queueEntry returns the list entry of a job. Entries are encoded the same
+// way every time, so a job can be found in the list with LPOS.
+func queueEntry(job *Job) (string, error) {
+	data, err := json.Marshal(queuedJob{ID: job.ID, Type: job.Type})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal queue entry: %w", err)
+	}
+	return string(data), nil
+}
+
+// GetJobStatus returns a job like GetJob, with secret payload entries
+// redacted, and for pending jobs fills in its queue position and estimated
+// wait. Both are computed on read, so they follow the queue as it drains.
+// The position is found with LPOS, so no queued job is decoded for it.
+func (q *RedisQueue) GetJobStatus(ctx context.Context, jobID string) (*Job, error) {
+	job, err := q.GetJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = redactedPayload(job.Payload)
+	if job.Status != StatusPending {
+		return job, nil
+	}
+
+	entry, err := queueEntry(job)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both lists share a hash slot, so this is one round trip in every
+	// Redis topology
+	keys := q.queueKeys()
+	lengths := make([]*redis.IntCmd, len(keys))
+	indexes := make([]*redis.IntCmd, len(keys))
+	pipe := q.client.TxPipeline()
+	for i, key := range keys {
+		lengths[i] = pipe.LLen(ctx, key)
+		indexes[i] = pipe.LPos(ctx, key, entry, redis.LPosArgs{})
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read queue: %w", err)
+	}
+
+	tiers := make([]queueTier, len(keys))
+	for i := range keys {
+		tiers[i] = queueTier{key: keys[i], length: lengths[i].Val(), index: -1}
+		if index, err := indexes[i].Result(); err == nil {
+			tiers[i].index = index
+		}
+	}
+
+	position, ranges, found := jobsAhead(tiers, maxAheadSample)
+	if !found {
+		// Waiting for a retry delay or already being dequeued
+		return job, nil
+	}
+	job.QueuePosition = &position
+
+	sample, err := q.readEntries(ctx, ranges)
+	if err != nil {
+		return nil, err
+	}
+	averages, err := q.averageDurations(ctx, sample)
+	if err != nil {
+		return nil, err
+	}
+	if wait, ok := estimateWait(sample, position, averages, q.config.MaxConcurrency); ok {
+		seconds := math.Round(wait.Seconds()*10) / 10
+		job.EstimatedWaitSeconds = &seconds
+	}
+
+	return job, nil
+}
+
+// queueTier is the state of one queue list as seen by a job: its length and
+// the job's LRANGE index in it, -1 when the job is not in this list
+type queueTier struct {
+	key    string
+	length int64
+	index  int64
+}
+
+// entryRange is an LRANGE range of list entries
+type entryRange struct {
+	key         string
+	start, stop int64
+}
+
+// readEntries reads and decodes the entries in ranges
+func (q *RedisQueue) readEntries(ctx context.Context, ranges []entryRange) ([]queuedJob, error) {
+	var jobs []queuedJob
+	for _, r := range ranges {
+		entries, err := q.client.LRange(ctx, r.key, r.start, r.stop).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queue: %w", err)
+		}
+		jobs = append(jobs, decodeQueuedJobs(entries)...)
+	}
+	return jobs, nil
+}
+
+// recordDuration stores how long a job of the given type took to process
+func (q *RedisQueue) recordDuration(ctx context.Context, jobType string, d time.Duration) error {
+	key := durationKey(jobType)
+	pipe := q.client.TxPipeline()
+	pipe.LPush(ctx, key, d.Milliseconds())
+	pipe.LTrim(ctx, key, 0, durationSamples-1)
+	pipe.Expire(ctx, key, 7*24*time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record job duration: %w", err)
+	}
+	return nil
+}
+
+// averageDurations returns the mean recent processing duration for each job
+// type present in jobs. Types without samples are omitted.
+func (q *RedisQueue) averageDurations(ctx context.Context, jobs []queuedJob) (map[string]time.Duration, error) {
+	averages := make(map[string]time.Duration)
+	for _, job := range jobs {
+		if _, seen := averages[job.Type]; seen {
+			continue
+		}
+
+		samples, err := q.client.LRange(ctx, durationKey(job.Type), 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read job durations: %w", err)
+		}
+		if avg, ok := meanMillis(samples); ok {
+			averages[job.Type] = avg
+		}
+	}
+	return averages, nil
+}
+
+// queueKeys returns the queue lists in the order workers dequeue them
+func (q *RedisQueue) queueKeys() []string {
+	return []string{q.priorityQueueName(), q.queueName()}
+}
+
+// queueName is the list for normal jobs. The queue name is a hash tag, so
+// both lists share a Redis Cluster slot and can be popped together.
+func (q *RedisQueue) queueName() string {
+	return "{" + q.config.QueueName + "}"
+}
+
+// priorityQueueName is the list for jobs with a positive priority; it is
+// always drained before the normal queue
+func (q *RedisQueue) priorityQueueName() string {
+	return q.queueName() + ":priority"
+}
+
+// queueFor returns the list a job is pushed to
+func (q *RedisQueue) queueFor(job *Job) string {
+	if job.Priority > 0 {
+		return q.priorityQueueName()
+	}
+	return q.queueName()
+}
+
+func durationKey(jobType string) string {
+	return fmt.Sprintf("job_durations:%s", jobType)
+}
+
+func decodeQueuedJobs(entries []string) []queuedJob {
+	jobs := make([]queuedJob, 0, len(entries))
+	for _, entry := range entries {
+		var job queuedJob
+		if err := json.Unmarshal([]byte(entry), &job); err == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// jobsAhead counts the jobs that will be dequeued before a job. tiers are
+// in dequeue priority order: every job in a higher tier is ahead, and within
+// the job's own tier the jobs nearer the tail are, since lists are pushed at
+// the head and popped from the tail. ranges covers at most limit of the jobs
+// ahead, those nearest the front of the queue first.
+func jobsAhead(tiers []queueTier, limit int) (int, []entryRange, bool) {
+	var position int64
+	var ranges []entryRange
+	remaining := int64(limit)
+	for _, tier := range tiers {
+		first, last := int64(0), tier.length-1
+		if tier.index >= 0 {
+			first = tier.index + 1
+		}
+		if count := last - first + 1; count > 0 {
+			position += count
+			if remaining > 0 {
+				start := max(first, last-remaining+1)
+				ranges = append(ranges, entryRange{key: tier.key, start: start, stop: last})
+				remaining -= last - start + 1
+			}
+		}
+		if tier.index >= 0 {
+			return int(position), ranges, true
+		}
+	}
+	return 0, nil, false
+}
+
+// estimateWait sums the average duration of the sampled jobs ahead, scales
+// it up to all ahead jobs when only some were sampled, and spreads it over
+// the worker pool. Types without history use the mean of the known types;
+// with no history at all there is no estimate.
+func estimateWait(sample []queuedJob, ahead int, averages map[string]time.Duration, workers int) (time.Duration, bool) {
+	if ahead == 0 {
+		return 0, true
+	}
+	if len(averages) == 0 || len(sample) == 0 {
+		return 0, false
+	}
+
+	var fallback time.Duration
+	for _, avg := range averages {
+		fallback += avg
+	}
+	fallback /= time.Duration(len(averages))
+
+	var total time.Duration
+	for _, job := range sample {
+		if avg, ok := averages[job.Type]; ok {
+			total += avg
+		} else {
+			total += fallback
+		}
+	}
+	if len(sample) < ahead {
+		total = time.Duration(float64(total) * float64(ahead) / float64(len(sample)))
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+	return total / time.Duration(workers), true
+}
+
+func meanMillis(samples []string) (time.Duration, bool) {
+	var sum, count int64
+	for _, sample := range samples {
+		ms, err := strconv.ParseInt(sample, 10, 64)
+		if err != nil {
+			continue
+		}
+		sum += ms
+		count++
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return time.Duration(sum/count) * time.Millisecond, true
+}
@@ -0,0 +1,204 @@
+package queue
+
+import (
+	"context"
+	"documents-worker/config"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobsAhead(t *testing.T) {
+	// Priority list of 2 and normal list of 3; index is the LRANGE index,
+	// newest first, and workers pop from the tail
+	tiers := func(priorityIndex, normalIndex int64) []queueTier {
+		return []queueTier{
+			{key: "{q}:priority", length: 2, index: priorityIndex},
+			{key: "{q}", length: 3, index: normalIndex},
+		}
+	}
+
+	position, ranges, found := jobsAhead(tiers(-1, 2), 10)
+	require.True(t, found)
+	assert.Equal(t, 2, position, "only priority jobs are ahead of the oldest normal job")
+	assert.Equal(t, []entryRange{{key: "{q}:priority", start: 0, stop: 1}}, ranges)
+
+	position, ranges, found = jobsAhead(tiers(-1, 0), 10)
+	require.True(t, found)
+	assert.Equal(t, 4, position)
+	assert.Equal(t, []entryRange{{key: "{q}:priority", start: 0, stop: 1}, {key: "{q}", start: 1, stop: 2}}, ranges)
+
+	position, ranges, found = jobsAhead(tiers(1, -1), 10)
+	require.True(t, found)
+	assert.Zero(t, position)
+	assert.Empty(t, ranges)
+
+	position, ranges, found = jobsAhead(tiers(0, -1), 10)
+	require.True(t, found)
+	assert.Equal(t, 1, position)
+	assert.Equal(t, []entryRange{{key: "{q}:priority", start: 1, stop: 1}}, ranges)
+
+	// Only the jobs nearest the front are sampled
+	position, ranges, found = jobsAhead(tiers(-1, 0), 3)
+	require.True(t, found)
+	assert.Equal(t, 4, position)
+	assert.Equal(t, []entryRange{{key: "{q}:priority", start: 0, stop: 1}, {key: "{q}", start: 2, stop: 2}}, ranges)
+
+	_, _, found = jobsAhead(tiers(-1, -1), 10)
+	assert.False(t, found)
+}
+
+func TestEstimateWait(t *testing.T) {
+	ahead := []queuedJob{
+		{ID: "a", Type: "video_convert"},
+		{ID: "b", Type: "image_convert"},
+		{ID: "c", Type: "ocr"},
+	}
+	averages := map[string]time.Duration{
+		"video_convert": 10 * time.Second,
+		"image_convert": 2 * time.Second,
+	}
+
+	// ocr has no history and falls back to the mean of known types (6s)
+	wait, ok := estimateWait(ahead, 3, averages, 1)
+	require.True(t, ok)
+	assert.Equal(t, 18*time.Second, wait)
+
+	wait, ok = estimateWait(ahead, 3, averages, 3)
+	require.True(t, ok)
+	assert.Equal(t, 6*time.Second, wait)
+
+	// A sample stands in for a longer queue
+	wait, ok = estimateWait(ahead, 30, averages, 3)
+	require.True(t, ok)
+	assert.Equal(t, 60*time.Second, wait)
+
+	wait, ok = estimateWait(nil, 0, nil, 2)
+	assert.True(t, ok, "nothing ahead means no wait")
+	assert.Zero(t, wait)
+
+	_, ok = estimateWait(ahead, 3, nil, 2)
+	assert.False(t, ok, "no history means no estimate")
+}
+
+func TestQueueKeysShareHashSlot(t *testing.T) {
+	queue := &RedisQueue{config: &config.WorkerConfig{QueueName: "documents"}}
+	assert.Equal(t, []string{"{documents}:priority", "{documents}"}, queue.queueKeys())
+
+	entry, err := queueEntry(&Job{ID: "job-1", Type: "ocr", Payload: map[string]interface{}{"input_path": "/tmp/a"}})
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"job-1","type":"ocr"}`, entry)
+
+	legacy, err := legacyEntry(`{"id":"job-1","type":"ocr","status":"pending","payload":{"input_path":"/tmp/a"}}`)
+	require.NoError(t, err)
+	assert.Equal(t, entry, legacy)
+
+	_, err = legacyEntry(`{"type":"ocr"}`)
+	assert.Error(t, err)
+}
+
+func TestMeanMillis(t *testing.T) {
+	avg, ok := meanMillis([]string{"1000", "3000", "bogus"})
+	require.True(t, ok)
+	assert.Equal(t, 2*time.Second, avg)
+
+	_, ok = meanMillis(nil)
+	assert.False(t, ok)
+}
+
+func TestGetJobStatusPosition(t *testing.T) {
+	redisConfig, workerConfig := getTestQueueConfig()
+	workerConfig.MaxConcurrency = 2
+
+	queue, err := NewRedisQueue(redisConfig, workerConfig)
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.client.FlushDB(ctx)
+
+	require.NoError(t, queue.recordDuration(ctx, "image_convert", 4*time.Second))
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, queue.Enqueue(ctx, &Job{ID: fmt.Sprintf("job-%d", i), Type: "image_convert"}))
+	}
+	require.NoError(t, queue.Enqueue(ctx, &Job{ID: "urgent", Type: "image_convert", Priority: 1}))
+
+	status, err := queue.GetJobStatus(ctx, "job-3")
+	require.NoError(t, err)
+	require.NotNil(t, status.QueuePosition)
+	assert.Equal(t, 3, *status.QueuePosition, "two older jobs and the priority job are ahead")
+	require.NotNil(t, status.EstimatedWaitSeconds)
+	assert.Equal(t, 6.0, *status.EstimatedWaitSeconds)
+
+	status, err = queue.GetJobStatus(ctx, "urgent")
+	require.NoError(t, err)
+	assert.Equal(t, 0, *status.QueuePosition)
+
+	// Positions follow the queue as it drains
+	dequeued, err := queue.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "urgent", dequeued.ID)
+
+	status, err = queue.GetJobStatus(ctx, "job-3")
+	require.NoError(t, err)
+	assert.Equal(t, 2, *status.QueuePosition)
+
+	status, err = queue.GetJobStatus(ctx, "urgent")
+	require.NoError(t, err)
+	assert.Nil(t, status.QueuePosition, "processing jobs have no position")
+}
+
+func TestRedactedPayload(t *testing.T) {
+	payload := map[string]interface{}{"input_path": "/tmp/in.pdf", "password": "secret"}
+
+	redacted := redactedPayload(payload)
+	assert.Equal(t, map[string]interface{}{"input_path": "/tmp/in.pdf"}, redacted)
+	assert.Equal(t, "secret", payload["password"], "the stored payload is left intact")
+	assert.Nil(t, redactedPayload(nil))
+
+	job := &Job{Payload: payload}
+	stripSecrets(job)
+	assert.NotContains(t, job.Payload, "password")
+}
+
+func TestMigrateLegacyQueues(t *testing.T) {
+	redisConfig, workerConfig := getTestQueueConfig()
+	queue, err := NewRedisQueue(redisConfig, workerConfig)
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.client.FlushDB(ctx)
+
+	// Earlier versions pushed full jobs to the unprefixed list
+	for i := 1; i <= 3; i++ {
+		job := &Job{ID: fmt.Sprintf("legacy-%d", i), Type: "ocr", Status: StatusPending}
+		data, err := json.Marshal(job)
+		require.NoError(t, err)
+		require.NoError(t, queue.client.Set(ctx, "job:"+job.ID, data, time.Hour).Err())
+		require.NoError(t, queue.client.LPush(ctx, workerConfig.QueueName, data).Err())
+	}
+
+	moved, err := queue.MigrateLegacyQueues(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, moved)
+
+	for i := 1; i <= 3; i++ {
+		job, err := queue.Dequeue(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("legacy-%d", i), job.ID, "the oldest job stays at the front")
+	}
+
+	moved, err = queue.MigrateLegacyQueues(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, moved)
+}
@@ -3,6 +3,8 @@ package queue
 import (
 	"context"
 	"documents-worker/config"
+	"documents-worker/internal/core/domain"
+	"documents-worker/redisclient"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -11,82 +13,134 @@ import (
 )
 
 type RedisQueue struct {
-	client *redis.Client
-	config *config.WorkerConfig
+	client     redis.UniversalClient
+	config     *config.WorkerConfig
+	ownsClient bool
+	listener   StatusListener
 }
 
-type JobStatus string
+// StatusListener is called with a job after the queue stored a change of
+// its status
+type StatusListener func(ctx context.Context, job *Job)
+
+// JobStatus is the canonical job status; changes are checked against its
+// transitions
+type JobStatus = domain.JobStatus
 
 const (
-	StatusPending    JobStatus = "pending"
-	StatusProcessing JobStatus = "processing"
-	StatusCompleted  JobStatus = "completed"
-	StatusFailed     JobStatus = "failed"
+	StatusPending    = domain.JobStatusPending
+	StatusProcessing = domain.JobStatusProcessing
+	StatusCompleted  = domain.JobStatusCompleted
+	StatusFailed     = domain.JobStatusFailed
+	StatusRetrying   = domain.JobStatusRetrying
+	StatusCanceled   = domain.JobStatusCanceled
 )
 
 type Job struct {
 	ID          string                 `json:"id"`
 	Type        string                 `json:"type"`
 	Status      JobStatus              `json:"status"`
+	Priority    int                    `json:"priority,omitempty"`
 	Payload     map[string]interface{} `json:"payload"`
+	Metadata    map[string]string      `json:"metadata,omitempty"`
 	Result      map[string]interface{} `json:"result,omitempty"`
 	Error       string                 `json:"error,omitempty"`
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
+	StartedAt   *time.Time             `json:"started_at,omitempty"`
 	CompletedAt *time.Time             `json:"completed_at,omitempty"`
 	RetryCount  int                    `json:"retry_count"`
 	MaxRetries  int                    `json:"max_retries"`
+
+	// Filled in by GetJobStatus for pending jobs; never stored
+	QueuePosition        *int     `json:"queue_position,omitempty"`
+	EstimatedWaitSeconds *float64 `json:"estimated_wait_seconds,omitempty"`
 }
 
 func NewRedisQueue(redisConfig *config.RedisConfig, workerConfig *config.WorkerConfig) (*RedisQueue, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", redisConfig.Host, redisConfig.Port),
-		Password: redisConfig.Password,
-		DB:       redisConfig.DB,
-	})
-
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("redis connection failed: %w", err)
+	client, err := redisclient.New(redisConfig)
+	if err != nil {
+		return nil, err
 	}
 
+	queue := NewRedisQueueWithClient(client, workerConfig)
+	queue.ownsClient = true
+	return queue, nil
+}
+
+// NewRedisQueueWithClient creates a queue on top of an existing, shared
+// Redis client. Closing the queue does not close a shared client.
+// The only multi-key operations are on the two queue lists, which share a
+// hash slot, so cluster clients are safe.
+func NewRedisQueueWithClient(client redis.UniversalClient, workerConfig *config.WorkerConfig) *RedisQueue {
 	return &RedisQueue{
 		client: client,
 		config: workerConfig,
-	}, nil
+	}
+}
+
+// WithStatusListener registers a listener told about every status change
+// this queue makes: a job queued or requeued for a retry, started,
+// completed or failed
+func (q *RedisQueue) WithStatusListener(listener StatusListener) *RedisQueue {
+	q.listener = listener
+	return q
+}
+
+// notify tells the listener about a stored status change
+func (q *RedisQueue) notify(ctx context.Context, job *Job) {
+	if q.listener != nil {
+		q.listener(ctx, job)
+	}
 }
 
 func (q *RedisQueue) Enqueue(ctx context.Context, job *Job) error {
+	// New jobs and jobs waiting for a retry may be queued
+	if job.Status != "" && job.Status != StatusPending && !job.Status.CanTransitionTo(StatusPending) {
+		return fmt.Errorf("cannot enqueue job %s: %w: %q to %q", job.ID, domain.ErrInvalidTransition, job.Status, StatusPending)
+	}
 	job.Status = StatusPending
 	job.CreatedAt = time.Now()
 	job.UpdatedAt = time.Now()
 	job.MaxRetries = q.config.RetryCount
+	job.StartedAt = nil
+	job.QueuePosition = nil
+	job.EstimatedWaitSeconds = nil
 
 	jobData, err := json.Marshal(job)
 	if err != nil {
 		return fmt.Errorf("failed to marshal job: %w", err)
 	}
-
-	// Add to processing queue
-	if err := q.client.LPush(ctx, q.config.QueueName, jobData).Err(); err != nil {
-		return fmt.Errorf("failed to enqueue job: %w", err)
+	entry, err := queueEntry(job)
+	if err != nil {
+		return err
 	}
 
-	// Store job details with expiration (24 hours)
+	// Store job details with expiration (24 hours) before the job can be
+	// dequeued, since the queue only holds its ID
 	jobKey := fmt.Sprintf("job:%s", job.ID)
 	if err := q.client.Set(ctx, jobKey, jobData, 24*time.Hour).Err(); err != nil {
 		return fmt.Errorf("failed to store job details: %w", err)
 	}
 
+	// Protect the job's files from temp cleanup until it finishes
+	if err := q.client.SAdd(ctx, q.activeSetName(), job.ID).Err(); err != nil {
+		return fmt.Errorf("failed to track active job: %w", err)
+	}
+
+	// Add to processing queue
+	if err := q.client.LPush(ctx, q.queueFor(job), entry).Err(); err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	q.notify(ctx, job)
 	return nil
 }
 
 func (q *RedisQueue) Dequeue(ctx context.Context) (*Job, error) {
-	// Use a timeout for BRPOP to allow graceful shutdown
-	result, err := q.client.BRPop(ctx, 5*time.Second, q.config.QueueName).Result()
+	// Use a timeout for BRPOP to allow graceful shutdown. BRPOP checks the
+	// keys in order, so the priority queue is always drained first.
+	result, err := q.client.BRPop(ctx, 5*time.Second, q.queueKeys()...).Result()
 	if err != nil {
 		// Check if it's a timeout or context cancellation
 		if err == redis.Nil || ctx.Err() != nil {
@@ -99,20 +153,30 @@ func (q *RedisQueue) Dequeue(ctx context.Context) (*Job, error) {
 		return nil, fmt.Errorf("invalid queue result")
 	}
 
-	var job Job
-	if err := json.Unmarshal([]byte(result[1]), &job); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	var entry queuedJob
+	if err := json.Unmarshal([]byte(result[1]), &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal queue entry: %w", err)
+	}
+	job, err := q.GetJob(ctx, entry.ID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Update status to processing
-	job.Status = StatusProcessing
-	job.UpdatedAt = time.Now()
+	status, err := job.Status.Transition(StatusProcessing)
+	if err != nil {
+		return nil, fmt.Errorf("cannot start job %s: %w", job.ID, err)
+	}
+	now := time.Now()
+	job.Status = status
+	job.UpdatedAt = now
+	job.StartedAt = &now
 
-	if err := q.updateJob(ctx, &job); err != nil {
+	if err := q.updateJob(ctx, job); err != nil {
 		return nil, fmt.Errorf("failed to update job status: %w", err)
 	}
 
-	return &job, nil
+	return job, nil
 }
 
 func (q *RedisQueue) CompleteJob(ctx context.Context, jobID string, result map[string]interface{}) error {
@@ -121,11 +185,23 @@ func (q *RedisQueue) CompleteJob(ctx context.Context, jobID string, result map[s
 		return err
 	}
 
+	status, err := job.Status.Transition(StatusCompleted)
+	if err != nil {
+		return fmt.Errorf("cannot complete job %s: %w", jobID, err)
+	}
+
 	now := time.Now()
-	job.Status = StatusCompleted
+	job.Status = status
 	job.Result = result
 	job.UpdatedAt = now
 	job.CompletedAt = &now
+	stripSecrets(job)
+	q.client.SRem(ctx, q.activeSetName(), job.ID)
+
+	// Feed the wait estimates of jobs still in the queue
+	if job.StartedAt != nil {
+		q.recordDuration(ctx, job.Type, now.Sub(*job.StartedAt))
+	}
 
 	return q.updateJob(ctx, job)
 }
@@ -136,18 +212,28 @@ func (q *RedisQueue) FailJob(ctx context.Context, jobID string, errorMsg string)
 		return err
 	}
 
+	// If max retries reached, mark as failed
+	next := StatusRetrying
+	if job.RetryCount+1 >= job.MaxRetries {
+		next = StatusFailed
+	}
+	status, err := job.Status.Transition(next)
+	if err != nil {
+		return fmt.Errorf("cannot fail job %s: %w", jobID, err)
+	}
+
 	job.RetryCount++
 	job.Error = errorMsg
 	job.UpdatedAt = time.Now()
+	job.Status = status
 
-	// If max retries reached, mark as failed
-	if job.RetryCount >= job.MaxRetries {
-		job.Status = StatusFailed
+	if status == StatusFailed {
+		stripSecrets(job)
+		q.client.SRem(ctx, q.activeSetName(), job.ID)
 		return q.updateJob(ctx, job)
 	}
 
 	// Otherwise, retry after delay
-	job.Status = StatusPending
 	if err := q.updateJob(ctx, job); err != nil {
 		return err
 	}
@@ -176,14 +262,46 @@ func (q *RedisQueue) GetJob(ctx context.Context, jobID string) (*Job, error) {
 	return &job, nil
 }
 
+// secretPayloadKeys are payload entries a worker needs but nobody else
+// should read back, such as the password of an encrypted PDF
+var secretPayloadKeys = []string{"password"}
+
+// stripSecrets removes secret payload entries once a job is finished
+func stripSecrets(job *Job) {
+	for _, key := range secretPayloadKeys {
+		delete(job.Payload, key)
+	}
+}
+
+// redactedPayload returns a copy of the payload without secret entries
+func redactedPayload(payload map[string]interface{}) map[string]interface{} {
+	if payload == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(payload))
+	for key, value := range payload {
+		redacted[key] = value
+	}
+	for _, key := range secretPayloadKeys {
+		delete(redacted, key)
+	}
+	return redacted
+}
+
 func (q *RedisQueue) GetQueueStats(ctx context.Context) (map[string]int64, error) {
-	queueLength, err := q.client.LLen(ctx, q.config.QueueName).Result()
+	queueLength, err := q.client.LLen(ctx, q.queueName()).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get queue length: %w", err)
 	}
 
+	priorityLength, err := q.client.LLen(ctx, q.priorityQueueName()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get priority queue length: %w", err)
+	}
+
 	return map[string]int64{
-		"pending": queueLength,
+		"pending":          queueLength + priorityLength,
+		"pending_priority": priorityLength,
 	}, nil
 }
 
@@ -198,9 +316,21 @@ func (q *RedisQueue) updateJob(ctx context.Context, job *Job) error {
 		return fmt.Errorf("failed to update job: %w", err)
 	}
 
+	// Wake up requests waiting for the job; pollers still see the stored
+	// status if nobody is listening
+	if job.Status.IsTerminal() {
+		if event, err := json.Marshal(newJobEvent(job)); err == nil {
+			q.client.Publish(ctx, jobDoneChannel(job.ID), event)
+		}
+	}
+
+	q.notify(ctx, job)
 	return nil
 }
 
 func (q *RedisQueue) Close() error {
+	if !q.ownsClient {
+		return nil
+	}
 	return q.client.Close()
 }
@@ -3,6 +3,7 @@ package queue
 import (
 	"context"
 	"documents-worker/config"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -128,6 +129,48 @@ func TestJobCompletion(t *testing.T) {
 	assert.NotNil(t, completedJob.CompletedAt)
 }
 
+// Caller metadata comes back in the job status and the completion event
+func TestJobMetadataRoundTrip(t *testing.T) {
+	redisConfig, workerConfig := getTestQueueConfig()
+
+	queue, err := NewRedisQueue(redisConfig, workerConfig)
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.client.FlushDB(ctx)
+
+	metadata := map[string]string{"cms_asset_id": "asset-42"}
+	require.NoError(t, queue.Enqueue(ctx, &Job{ID: "metadata-job", Type: "ocr", Metadata: metadata}))
+
+	events := queue.client.Subscribe(ctx, jobDoneChannel("metadata-job"))
+	defer events.Close()
+	_, err = events.Receive(ctx)
+	require.NoError(t, err)
+
+	dequeued, err := queue.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, metadata, dequeued.Metadata)
+	require.NoError(t, queue.CompleteJob(ctx, dequeued.ID, map[string]interface{}{"pages": 1}))
+
+	status, err := queue.GetJobStatus(ctx, "metadata-job")
+	require.NoError(t, err)
+	assert.Equal(t, metadata, status.Metadata)
+
+	select {
+	case message := <-events.Channel():
+		var event JobEvent
+		require.NoError(t, json.Unmarshal([]byte(message.Payload), &event))
+		assert.Equal(t, "metadata-job", event.JobID)
+		assert.Equal(t, StatusCompleted, event.Status)
+		assert.Equal(t, metadata, event.Metadata)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no completion event")
+	}
+}
+
 // Test Job Failure and Retry
 func TestJobFailureAndRetry(t *testing.T) {
 	redisConfig, workerConfig := getTestQueueConfig()
@@ -178,6 +221,39 @@ func TestJobFailureAndRetry(t *testing.T) {
 	assert.Equal(t, 2, finalJob.RetryCount)
 }
 
+func TestStatusListenerSeesEveryChange(t *testing.T) {
+	redisConfig, workerConfig := getTestQueueConfig()
+	workerConfig.RetryCount = 1
+
+	queue, err := NewRedisQueue(redisConfig, workerConfig)
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	defer queue.Close()
+
+	ctx := context.Background()
+	queue.client.FlushDB(ctx)
+
+	changes := map[string][]JobStatus{}
+	queue.WithStatusListener(func(ctx context.Context, job *Job) {
+		changes[job.ID] = append(changes[job.ID], job.Status)
+	})
+
+	for _, id := range []string{"listened-done", "listened-failed"} {
+		require.NoError(t, queue.Enqueue(ctx, &Job{ID: id, Type: "test_type"}))
+		job, err := queue.Dequeue(ctx)
+		require.NoError(t, err)
+		if id == "listened-done" {
+			require.NoError(t, queue.CompleteJob(ctx, job.ID, map[string]interface{}{"ok": true}))
+		} else {
+			require.NoError(t, queue.FailJob(ctx, job.ID, "Test error"))
+		}
+	}
+
+	assert.Equal(t, []JobStatus{StatusPending, StatusProcessing, StatusCompleted}, changes["listened-done"])
+	assert.Equal(t, []JobStatus{StatusPending, StatusProcessing, StatusFailed}, changes["listened-failed"])
+}
+
 // Test Queue Stats
 func TestQueueStats(t *testing.T) {
 	redisConfig, workerConfig := getTestQueueConfig()
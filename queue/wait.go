@@ -0,0 +1,62 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+)
+
+// jobDoneChannel is the pub/sub channel announcing that a job finished
+func jobDoneChannel(jobID string) string {
+	return fmt.Sprintf("job-done:%s", jobID)
+}
+
+// JobEvent is published as JSON on a job's done channel when it finishes
+type JobEvent struct {
+	JobID    string            `json:"job_id"`
+	Type     string            `json:"type"`
+	Status   JobStatus         `json:"status"`
+	Error    string            `json:"error,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// newJobEvent describes a finished job
+func newJobEvent(job *Job) *JobEvent {
+	return &JobEvent{
+		JobID:    job.ID,
+		Type:     job.Type,
+		Status:   job.Status,
+		Error:    job.Error,
+		Metadata: job.Metadata,
+	}
+}
+
+// WaitForJob blocks until the job is completed, failed or canceled, or ctx
+// is done, in which case the context error is returned
+func (q *RedisQueue) WaitForJob(ctx context.Context, jobID string) error {
+	pubsub := q.client.Subscribe(ctx, jobDoneChannel(jobID))
+	defer pubsub.Close()
+
+	// Subscribe before reading the job, so a job finishing in between is
+	// not missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to subscribe to job %s: %w", jobID, err)
+	}
+
+	job, err := q.GetJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if job.Status.IsTerminal() {
+		return nil
+	}
+
+	select {
+	case <-pubsub.Channel():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
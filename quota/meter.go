@@ -0,0 +1,57 @@
+package quota
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+type meterKey struct{}
+
+// Meter collects the usage of one request while it is processed, so work
+// whose size is only known late (a chunked upload, the length of a video)
+// can be charged. A nil meter ignores everything recorded to it.
+type Meter struct {
+	bytes atomic.Int64
+	video atomic.Int64
+}
+
+// WithMeter returns a context carrying meter
+func WithMeter(ctx context.Context, meter *Meter) context.Context {
+	return context.WithValue(ctx, meterKey{}, meter)
+}
+
+// MeterFrom returns the meter attached to ctx, or nil
+func MeterFrom(ctx context.Context) *Meter {
+	meter, _ := ctx.Value(meterKey{}).(*Meter)
+	return meter
+}
+
+// AddBytes records n bytes of input
+func (m *Meter) AddBytes(n int64) {
+	if m == nil || n <= 0 {
+		return
+	}
+	m.bytes.Add(n)
+}
+
+// AddVideo records d of processed video
+func (m *Meter) AddVideo(d time.Duration) {
+	if m == nil || d <= 0 {
+		return
+	}
+	m.video.Add(int64(d))
+}
+
+// Usage returns what was recorded. Video time is rounded up to whole
+// seconds so short clips are not free.
+func (m *Meter) Usage() Usage {
+	if m == nil {
+		return Usage{}
+	}
+	video := time.Duration(m.video.Load())
+	return Usage{
+		Bytes:        m.bytes.Load(),
+		VideoSeconds: int64((video + time.Second - 1) / time.Second),
+	}
+}
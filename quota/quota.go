@@ -0,0 +1,133 @@
+// Package quota enforces monthly processing limits per tenant. Usage is
+// counted per calendar month (UTC) so counters reset at the start of each
+// month without a cleanup job.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrExceeded is wrapped by ExceededError
+var ErrExceeded = errors.New("quota exceeded")
+
+// Usage is an amount of processing. Limits use the same type, where zero
+// means unlimited.
+type Usage struct {
+	Requests     int64 `json:"requests"`
+	Bytes        int64 `json:"bytes"`
+	VideoSeconds int64 `json:"video_seconds"`
+}
+
+// Status is a tenant's usage in the current period
+type Status struct {
+	Tenant   string    `json:"tenant"`
+	Period   string    `json:"period"`
+	Usage    Usage     `json:"usage"`
+	Limits   Usage     `json:"limits"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+// ExceededError reports the first exhausted limit
+type ExceededError struct {
+	Status *Status
+	// Limit names the exhausted dimension: requests, bytes or video_seconds
+	Limit string
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("monthly %s quota of tenant %s exhausted until %s",
+		e.Limit, e.Status.Tenant, e.Status.ResetsAt.Format(time.RFC3339))
+}
+
+func (e *ExceededError) Unwrap() error {
+	return ErrExceeded
+}
+
+// Store keeps usage counters
+type Store interface {
+	// Add increments the counters under key, expiring them at expireAt,
+	// and returns the new totals
+	Add(ctx context.Context, key string, delta Usage, expireAt time.Time) (Usage, error)
+	// Get returns the counters under key, zero when absent
+	Get(ctx context.Context, key string) (Usage, error)
+}
+
+// counterRetention keeps a finished period's counters for late inspection
+const counterRetention = 7 * 24 * time.Hour
+
+// Limiter checks and charges tenant usage against monthly limits
+type Limiter struct {
+	store  Store
+	limits Usage
+	now    func() time.Time
+}
+
+// NewLimiter creates a limiter applying limits to every tenant
+func NewLimiter(store Store, limits Usage) *Limiter {
+	return &Limiter{store: store, limits: limits, now: time.Now}
+}
+
+// Status returns the tenant's usage in the current period
+func (l *Limiter) Status(ctx context.Context, tenant string) (*Status, error) {
+	status, key := l.period(tenant)
+	usage, err := l.store.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quota usage: %w", err)
+	}
+	status.Usage = usage
+	return status, nil
+}
+
+// Check returns the tenant's status, with an *ExceededError when any limit
+// is already used up
+func (l *Limiter) Check(ctx context.Context, tenant string) (*Status, error) {
+	status, err := l.Status(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	if limit := exhausted(status.Usage, l.limits); limit != "" {
+		return status, &ExceededError{Status: status, Limit: limit}
+	}
+	return status, nil
+}
+
+// Charge adds usage to the tenant's current period. Requests already
+// admitted are charged in full, so usage may end slightly above a limit.
+func (l *Limiter) Charge(ctx context.Context, tenant string, delta Usage) (*Status, error) {
+	status, key := l.period(tenant)
+	usage, err := l.store.Add(ctx, key, delta, status.ResetsAt.Add(counterRetention))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record quota usage: %w", err)
+	}
+	status.Usage = usage
+	return status, nil
+}
+
+// period describes the tenant's current month and its counter key
+func (l *Limiter) period(tenant string) (*Status, string) {
+	now := l.now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	period := start.Format("2006-01")
+	return &Status{
+		Tenant:   tenant,
+		Period:   period,
+		Limits:   l.limits,
+		ResetsAt: start.AddDate(0, 1, 0),
+	}, "quota:" + tenant + ":" + period
+}
+
+// exhausted names the first limit the usage has reached
+func exhausted(usage, limits Usage) string {
+	switch {
+	case limits.Requests > 0 && usage.Requests >= limits.Requests:
+		return "requests"
+	case limits.Bytes > 0 && usage.Bytes >= limits.Bytes:
+		return "bytes"
+	case limits.VideoSeconds > 0 && usage.VideoSeconds >= limits.VideoSeconds:
+		return "video_seconds"
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(limits Usage, now *time.Time) (*Limiter, *MemoryStore) {
+	store := NewMemoryStore()
+	store.now = func() time.Time { return *now }
+	limiter := NewLimiter(store, limits)
+	limiter.now = func() time.Time { return *now }
+	return limiter, store
+}
+
+func TestCheckRejectsOnceRequestQuotaIsUsedUp(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	limiter, _ := newTestLimiter(Usage{Requests: 2}, &now)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := limiter.Check(ctx, "acme")
+		require.NoError(t, err)
+		_, err = limiter.Charge(ctx, "acme", Usage{Requests: 1})
+		require.NoError(t, err)
+	}
+
+	status, err := limiter.Check(ctx, "acme")
+	var exceeded *ExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.ErrorIs(t, err, ErrExceeded)
+	assert.Equal(t, "requests", exceeded.Limit)
+	assert.Equal(t, int64(2), status.Usage.Requests)
+	assert.Equal(t, "2026-10", status.Period)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), status.ResetsAt)
+
+	// Other tenants are counted separately
+	_, err = limiter.Check(ctx, "globex")
+	assert.NoError(t, err)
+}
+
+func TestCheckReportsExhaustedByteAndVideoQuotas(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	limiter, _ := newTestLimiter(Usage{Bytes: 100, VideoSeconds: 60}, &now)
+	ctx := context.Background()
+
+	_, err := limiter.Charge(ctx, "acme", Usage{Requests: 1, Bytes: 150})
+	require.NoError(t, err)
+	_, err = limiter.Check(ctx, "acme")
+	var exceeded *ExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, "bytes", exceeded.Limit)
+
+	_, err = limiter.Charge(ctx, "initech", Usage{VideoSeconds: 60})
+	require.NoError(t, err)
+	_, err = limiter.Check(ctx, "initech")
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, "video_seconds", exceeded.Limit)
+}
+
+func TestUsageResetsAtMonthBoundary(t *testing.T) {
+	now := time.Date(2026, 10, 31, 23, 59, 59, 0, time.UTC)
+	limiter, _ := newTestLimiter(Usage{Requests: 1}, &now)
+	ctx := context.Background()
+
+	_, err := limiter.Charge(ctx, "acme", Usage{Requests: 1})
+	require.NoError(t, err)
+	_, err = limiter.Check(ctx, "acme")
+	require.ErrorIs(t, err, ErrExceeded)
+
+	now = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	status, err := limiter.Check(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "2026-11", status.Period)
+	assert.Zero(t, status.Usage.Requests)
+
+	// Periods follow UTC regardless of the caller's zone
+	now = time.Date(2026, 11, 30, 20, 0, 0, 0, time.FixedZone("UTC-5", -5*3600))
+	status, err = limiter.Status(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "2026-12", status.Period)
+}
+
+func TestUnlimitedQuotaNeverRejects(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	limiter, _ := newTestLimiter(Usage{}, &now)
+
+	_, err := limiter.Charge(context.Background(), "acme", Usage{Requests: 1000, Bytes: 1 << 40})
+	require.NoError(t, err)
+	_, err = limiter.Check(context.Background(), "acme")
+	assert.NoError(t, err)
+}
+
+func TestMemoryStoreExpiresCounters(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	usage, err := store.Add(ctx, "k", Usage{Requests: 2, Bytes: 10}, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, Usage{Requests: 2, Bytes: 10}, usage)
+
+	now = now.Add(time.Hour)
+	usage, err = store.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Zero(t, usage)
+}
+
+func TestMeterRecordsRequestUsage(t *testing.T) {
+	var missing *Meter
+	missing.AddBytes(10)
+	assert.Equal(t, Usage{}, missing.Usage())
+	assert.Nil(t, MeterFrom(context.Background()))
+
+	meter := &Meter{}
+	ctx := WithMeter(context.Background(), meter)
+	MeterFrom(ctx).AddBytes(1024)
+	MeterFrom(ctx).AddBytes(-5)
+	MeterFrom(ctx).AddVideo(61500 * time.Millisecond)
+	MeterFrom(ctx).AddVideo(time.Second)
+
+	assert.Equal(t, Usage{Bytes: 1024, VideoSeconds: 63}, meter.Usage())
+}
@@ -0,0 +1,104 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps counters in Redis hashes shared by every instance
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore creates a store on the shared Redis client
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Add increments the counters atomically and sets their expiry
+func (s *RedisStore) Add(ctx context.Context, key string, delta Usage, expireAt time.Time) (Usage, error) {
+	var requests, bytes, videoSeconds *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		requests = pipe.HIncrBy(ctx, key, "requests", delta.Requests)
+		bytes = pipe.HIncrBy(ctx, key, "bytes", delta.Bytes)
+		videoSeconds = pipe.HIncrBy(ctx, key, "video_seconds", delta.VideoSeconds)
+		pipe.ExpireAt(ctx, key, expireAt)
+		return nil
+	})
+	if err != nil {
+		return Usage{}, err
+	}
+	return Usage{Requests: requests.Val(), Bytes: bytes.Val(), VideoSeconds: videoSeconds.Val()}, nil
+}
+
+// Get reads the counters
+func (s *RedisStore) Get(ctx context.Context, key string) (Usage, error) {
+	values, err := s.client.HMGet(ctx, key, "requests", "bytes", "video_seconds").Result()
+	if err != nil {
+		return Usage{}, err
+	}
+
+	counters := make([]int64, len(values))
+	for i, value := range values {
+		if text, ok := value.(string); ok {
+			if counters[i], err = strconv.ParseInt(text, 10, 64); err != nil {
+				return Usage{}, fmt.Errorf("invalid counter in %s: %w", key, err)
+			}
+		}
+	}
+	return Usage{Requests: counters[0], Bytes: counters[1], VideoSeconds: counters[2]}, nil
+}
+
+// MemoryStore keeps counters in process memory, for single instances and
+// tests
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]memoryCounter
+	now      func() time.Time
+}
+
+type memoryCounter struct {
+	usage    Usage
+	expireAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: make(map[string]memoryCounter), now: time.Now}
+}
+
+// Add increments the counters
+func (s *MemoryStore) Add(ctx context.Context, key string, delta Usage, expireAt time.Time) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter := s.live(key)
+	counter.usage.Requests += delta.Requests
+	counter.usage.Bytes += delta.Bytes
+	counter.usage.VideoSeconds += delta.VideoSeconds
+	counter.expireAt = expireAt
+	s.counters[key] = counter
+	return counter.usage, nil
+}
+
+// Get reads the counters
+func (s *MemoryStore) Get(ctx context.Context, key string) (Usage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.live(key).usage, nil
+}
+
+// live returns the counter under key, dropping it once expired
+func (s *MemoryStore) live(key string) memoryCounter {
+	counter, ok := s.counters[key]
+	if ok && !s.now().Before(counter.expireAt) {
+		delete(s.counters, key)
+		return memoryCounter{}
+	}
+	return counter
+}
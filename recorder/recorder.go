@@ -0,0 +1,290 @@
+// Package recorder keeps failed requests, with their inputs, so they can be
+// replayed against a local server when diagnosing processing failures.
+package recorder
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	metadataFile = "request.json"
+	inputFile    = "input"
+
+	// idTimeLayout prefixes recording IDs so they sort chronologically
+	idTimeLayout = "20060102T150405.000000"
+)
+
+// ErrNotFound is returned for unknown recording IDs
+var ErrNotFound = errors.New("recording not found")
+
+// Recording describes a failed request
+type Recording struct {
+	ID      string            `json:"id"`
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Fields are the non-file fields of a multipart request
+	Fields map[string]string `json:"fields,omitempty"`
+	Input  *Input            `json:"input,omitempty"`
+
+	Status int `json:"status"`
+	// Response is the start of the error response body
+	Response   string    `json:"response,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Input describes the recorded request payload: the uploaded file of a
+// multipart request, or the raw body of any other request
+type Input struct {
+	// Field is the multipart file field; empty for a raw body
+	Field       string `json:"field,omitempty"`
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+	// Stored is false when the input exceeded the size limit and only its
+	// description was kept
+	Stored bool `json:"stored"`
+}
+
+// Config holds recorder limits
+type Config struct {
+	Directory string
+	// MaxInputSize is the largest input kept; larger inputs are described
+	// but not stored
+	MaxInputSize int64
+	// Retention is how long recordings are kept
+	Retention time.Duration
+	// MaxRecordings caps the number of recordings; the oldest are removed first
+	MaxRecordings int
+	// RedactFields are additional field, header and query names whose values
+	// are never stored
+	RedactFields []string
+}
+
+// Recorder stores recordings in a directory, one subdirectory each
+type Recorder struct {
+	config   Config
+	redactor *Redactor
+	now      func() time.Time
+
+	// mu serializes pruning with saving
+	mu sync.Mutex
+}
+
+// New creates a recorder, creating its directory if needed
+func New(cfg Config) (*Recorder, error) {
+	if cfg.Directory == "" {
+		return nil, fmt.Errorf("recorder directory is required")
+	}
+	if err := os.MkdirAll(cfg.Directory, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create recorder directory: %w", err)
+	}
+	return &Recorder{
+		config:   cfg,
+		redactor: NewRedactor(cfg.RedactFields),
+		now:      time.Now,
+	}, nil
+}
+
+// MaxInputSize returns the largest input the recorder stores
+func (r *Recorder) MaxInputSize() int64 {
+	return r.config.MaxInputSize
+}
+
+// Save redacts and stores a recording. input is the payload described by
+// rec.Input; it is ignored when the input is not to be stored. Old
+// recordings are pruned afterwards.
+func (r *Recorder) Save(rec *Recording, input io.Reader) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	rec.ID = newID(now)
+	rec.RecordedAt = now
+	rec.Headers = r.redactor.Headers(rec.Headers)
+	rec.Fields = r.redactor.Fields(rec.Fields)
+	rec.Query = r.redactor.Query(rec.Query)
+	rec.Response = r.redactor.Text(rec.Response)
+
+	dir := filepath.Join(r.config.Directory, rec.ID)
+	if err := os.Mkdir(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	if rec.Input != nil && rec.Input.Stored && input != nil {
+		if err := r.writeInput(filepath.Join(dir, inputFile), rec.Input, input); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("failed to encode recording: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, metadataFile), data, 0600); err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("failed to write recording: %w", err)
+	}
+
+	r.prune(now)
+	return nil
+}
+
+// writeInput stores the payload, redacting JSON bodies. An input found to
+// exceed the size limit is dropped and marked as not stored.
+func (r *Recorder) writeInput(path string, desc *Input, input io.Reader) error {
+	limited := io.LimitReader(input, r.config.MaxInputSize+1)
+
+	if desc.Field == "" && isJSON(desc.ContentType) {
+		data, err := io.ReadAll(limited)
+		if err != nil {
+			return fmt.Errorf("failed to read recorded input: %w", err)
+		}
+		if int64(len(data)) > r.config.MaxInputSize {
+			desc.Stored = false
+			return nil
+		}
+		limited = bytes.NewReader(r.redactor.JSON(data))
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create recorded input: %w", err)
+	}
+	defer file.Close()
+
+	written, err := io.Copy(file, limited)
+	if err != nil {
+		return fmt.Errorf("failed to write recorded input: %w", err)
+	}
+	if written > r.config.MaxInputSize {
+		desc.Stored = false
+		file.Close()
+		return os.Remove(path)
+	}
+	return nil
+}
+
+// List returns the stored recordings, newest first
+func (r *Recorder) List() ([]*Recording, error) {
+	ids, err := r.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	recordings := make([]*Recording, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		rec, _, err := Open(filepath.Join(r.config.Directory, ids[i]))
+		if err != nil {
+			// Skip recordings being written or removed concurrently
+			continue
+		}
+		recordings = append(recordings, rec)
+	}
+	return recordings, nil
+}
+
+// Load returns a recording and the path of its stored input, which is empty
+// when the input was not stored
+func (r *Recorder) Load(id string) (*Recording, string, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return nil, "", ErrNotFound
+	}
+	return Open(filepath.Join(r.config.Directory, id))
+}
+
+// Open reads a recording directory, such as one copied from a server
+func Open(dir string) (*Recording, string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metadataFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, "", fmt.Errorf("failed to decode recording: %w", err)
+	}
+
+	var inputPath string
+	if rec.Input != nil && rec.Input.Stored {
+		inputPath = filepath.Join(dir, inputFile)
+	}
+	return &rec, inputPath, nil
+}
+
+// Prune removes recordings past the retention period or over the count limit
+func (r *Recorder) Prune() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.prune(r.now())
+}
+
+func (r *Recorder) prune(now time.Time) int {
+	ids, err := r.ids()
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	for i, id := range ids {
+		expired := false
+		if r.config.Retention > 0 {
+			if recordedAt, err := time.Parse(idTimeLayout, id[:len(idTimeLayout)]); err == nil {
+				expired = now.Sub(recordedAt) > r.config.Retention
+			}
+		}
+		overLimit := r.config.MaxRecordings > 0 && len(ids)-i > r.config.MaxRecordings
+		if !expired && !overLimit {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(r.config.Directory, id)); err == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// ids returns the recording IDs, oldest first
+func (r *Recorder) ids() ([]string, error) {
+	entries, err := os.ReadDir(r.config.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() && len(entry.Name()) > len(idTimeLayout) {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func newID(now time.Time) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return now.UTC().Format(idTimeLayout) + "-" + hex.EncodeToString(suffix)
+}
+
+func isJSON(contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "json")
+}
@@ -0,0 +1,195 @@
+package recorder
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRecorder(t *testing.T, cfg Config) *Recorder {
+	t.Helper()
+
+	cfg.Directory = t.TempDir()
+	if cfg.MaxInputSize == 0 {
+		cfg.MaxInputSize = 1024
+	}
+	rec, err := New(cfg)
+	require.NoError(t, err)
+	return rec
+}
+
+func TestSaveRedactsCredentialsAndEmails(t *testing.T) {
+	rec := newTestRecorder(t, Config{RedactFields: []string{"customer_ref"}})
+
+	recording := &Recording{
+		Method: "POST",
+		Path:   "/api/v1/process/image/convert",
+		Query:  "format=webp&access_token=abc&note=mail+jane%40example.com",
+		Headers: map[string]string{
+			"Authorization": "Bearer abc",
+			"Cookie":        "session=abc",
+			"Content-Type":  "multipart/form-data; boundary=x",
+		},
+		Fields: map[string]string{
+			"output_format": "webp",
+			"pdf_password":  "hunter2",
+			"customer_ref":  "ACME-42",
+			"comment":       "from jane.doe@example.com",
+		},
+		Input:    &Input{Field: "file", Filename: "input.png", Size: 5, Stored: true},
+		Status:   500,
+		Response: `{"error":"failed for jane.doe@example.com"}`,
+	}
+	require.NoError(t, rec.Save(recording, strings.NewReader("image")))
+
+	loaded, inputPath, err := rec.Load(recording.ID)
+	require.NoError(t, err)
+	assert.Equal(t, Redacted, loaded.Headers["Authorization"])
+	assert.Equal(t, Redacted, loaded.Headers["Cookie"])
+	assert.Equal(t, "multipart/form-data; boundary=x", loaded.Headers["Content-Type"])
+	assert.Equal(t, "webp", loaded.Fields["output_format"])
+	assert.Equal(t, Redacted, loaded.Fields["pdf_password"])
+	assert.Equal(t, Redacted, loaded.Fields["customer_ref"])
+	assert.Equal(t, "from "+Redacted, loaded.Fields["comment"])
+	assert.NotContains(t, loaded.Query, "abc")
+	assert.NotContains(t, loaded.Query, "jane")
+	assert.Contains(t, loaded.Query, "format=webp")
+	assert.NotContains(t, loaded.Response, "jane")
+	assert.Equal(t, []string{"customer_ref", "pdf_password"}, loaded.RedactedFields())
+
+	input, err := os.ReadFile(inputPath)
+	require.NoError(t, err)
+	assert.Equal(t, "image", string(input))
+}
+
+func TestSaveRedactsJSONBodies(t *testing.T) {
+	rec := newTestRecorder(t, Config{})
+
+	body := `{"document_id":"d1","parameters":{"password":"hunter2","owner":"jane@example.com"}}`
+	recording := &Recording{
+		Method: "POST",
+		Path:   "/api/v1/documents/process",
+		Input:  &Input{ContentType: "application/json", Size: int64(len(body)), Stored: true},
+		Status: 500,
+	}
+	require.NoError(t, rec.Save(recording, strings.NewReader(body)))
+
+	_, inputPath, err := rec.Load(recording.ID)
+	require.NoError(t, err)
+	stored, err := os.ReadFile(inputPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(stored), `"document_id":"d1"`)
+	assert.NotContains(t, string(stored), "hunter2")
+	assert.NotContains(t, string(stored), "jane@example.com")
+}
+
+func TestSaveDropsOversizedInput(t *testing.T) {
+	rec := newTestRecorder(t, Config{MaxInputSize: 4})
+
+	recording := &Recording{
+		Method: "POST",
+		Path:   "/api/v1/process/image/convert",
+		Input:  &Input{Field: "file", Size: 10, Stored: true},
+		Status: 500,
+	}
+	require.NoError(t, rec.Save(recording, strings.NewReader("0123456789")))
+
+	loaded, inputPath, err := rec.Load(recording.ID)
+	require.NoError(t, err)
+	assert.False(t, loaded.Input.Stored)
+	assert.Empty(t, inputPath)
+	assert.NoFileExists(t, filepath.Join(rec.config.Directory, recording.ID, inputFile))
+}
+
+func TestPruneEnforcesRetentionAndCount(t *testing.T) {
+	rec := newTestRecorder(t, Config{Retention: time.Hour, MaxRecordings: 2})
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	var ids []string
+	for _, age := range []time.Duration{3 * time.Hour, 30 * time.Minute, 20 * time.Minute, 10 * time.Minute} {
+		rec.now = func() time.Time { return now.Add(-age) }
+		recording := &Recording{Method: "GET", Path: "/x", Status: 500}
+		require.NoError(t, rec.Save(recording, nil))
+		ids = append(ids, recording.ID)
+	}
+
+	rec.now = func() time.Time { return now }
+	rec.Prune()
+
+	recordings, err := rec.List()
+	require.NoError(t, err)
+	require.Len(t, recordings, 2)
+	assert.Equal(t, ids[3], recordings[0].ID, "newest first")
+	assert.Equal(t, ids[2], recordings[1].ID)
+}
+
+func TestLoadRejectsPathTraversal(t *testing.T) {
+	rec := newTestRecorder(t, Config{})
+
+	for _, id := range []string{"", "..", "../etc", "a/b", ".hidden"} {
+		_, _, err := rec.Load(id)
+		assert.ErrorIs(t, err, ErrNotFound, id)
+	}
+}
+
+func TestReplayResubmitsMultipartRequest(t *testing.T) {
+	rec := newTestRecorder(t, Config{})
+
+	recording := &Recording{
+		Method:  "POST",
+		Path:    "/api/v1/process/image/convert",
+		Query:   "quality=80",
+		Headers: map[string]string{"Authorization": "Bearer old", "X-Request-Id": "r1"},
+		Fields:  map[string]string{"output_format": "webp", "password": "secret"},
+		Input:   &Input{Field: "file", Filename: "input.png", Size: 5, Stored: true},
+		Status:  500,
+	}
+	require.NoError(t, rec.Save(recording, strings.NewReader("image")))
+	loaded, inputPath, err := rec.Load(recording.ID)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/process/image/convert", r.URL.Path)
+		assert.Equal(t, "80", r.URL.Query().Get("quality"))
+		assert.Equal(t, "Bearer new", r.Header.Get("Authorization"))
+		assert.Equal(t, "r1", r.Header.Get("X-Request-Id"))
+
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "webp", r.FormValue("output_format"))
+		assert.Equal(t, "supplied", r.FormValue("password"))
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		defer file.Close()
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "input.png", header.Filename)
+		assert.Equal(t, "image", string(data))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := Replay(context.Background(), server.Client(), server.URL, loaded, inputPath,
+		map[string]string{"password": "supplied"}, map[string]string{"Authorization": "Bearer new"})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestReplayWithoutStoredInputFails(t *testing.T) {
+	recording := &Recording{
+		ID:     "r",
+		Method: "POST",
+		Path:   "/api/v1/process/image/convert",
+		Input:  &Input{Field: "file", Size: 1 << 30},
+	}
+	_, err := Replay(context.Background(), http.DefaultClient, "http://127.0.0.1:0", recording, "", nil, nil)
+	assert.ErrorContains(t, err, "no stored input")
+}
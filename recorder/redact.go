@@ -0,0 +1,145 @@
+package recorder
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces values that are never stored
+const Redacted = "[REDACTED]"
+
+// defaultSensitiveNames match, as substrings, the names of fields, headers
+// and query parameters carrying credentials
+var defaultSensitiveNames = []string{
+	"password", "passwd", "secret", "token", "api_key", "apikey", "api-key",
+	"authorization", "cookie", "credential", "signature", "session",
+}
+
+// emailPattern finds e-mail addresses in free text
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// Redactor removes credentials and personal data before anything is stored
+// or logged
+type Redactor struct {
+	names []string
+}
+
+// NewRedactor creates a redactor for the built-in credential names and
+// extra names
+func NewRedactor(extra []string) *Redactor {
+	names := append([]string{}, defaultSensitiveNames...)
+	for _, name := range extra {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return &Redactor{names: names}
+}
+
+func (r *Redactor) sensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, candidate := range r.names {
+		if strings.Contains(name, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text masks e-mail addresses
+func (r *Redactor) Text(value string) string {
+	return emailPattern.ReplaceAllString(value, Redacted)
+}
+
+// Fields redacts form fields by name and masks e-mail addresses in the rest
+func (r *Redactor) Fields(fields map[string]string) map[string]string {
+	if fields == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(fields))
+	for name, value := range fields {
+		if r.sensitive(name) {
+			redacted[name] = Redacted
+		} else {
+			redacted[name] = r.Text(value)
+		}
+	}
+	return redacted
+}
+
+// Headers redacts credentials by header name
+func (r *Redactor) Headers(headers map[string]string) map[string]string {
+	if headers == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if r.sensitive(name) {
+			redacted[name] = Redacted
+		} else {
+			redacted[name] = value
+		}
+	}
+	return redacted
+}
+
+// Query redacts an encoded query string
+func (r *Redactor) Query(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		// Never keep what cannot be inspected
+		return Redacted
+	}
+	for name, list := range values {
+		for i := range list {
+			if r.sensitive(name) {
+				list[i] = Redacted
+			} else {
+				list[i] = r.Text(list[i])
+			}
+		}
+	}
+	return values.Encode()
+}
+
+// JSON redacts a JSON document by key, at any depth. Documents that do not
+// parse are masked as text.
+func (r *Redactor) JSON(data []byte) []byte {
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return []byte(r.Text(string(data)))
+	}
+	redacted, err := json.Marshal(r.value(document))
+	if err != nil {
+		return []byte(Redacted)
+	}
+	return redacted
+}
+
+func (r *Redactor) value(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if r.sensitive(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = r.value(item)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.value(item)
+		}
+		return v
+	case string:
+		return r.Text(v)
+	default:
+		return v
+	}
+}
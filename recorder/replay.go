@@ -0,0 +1,132 @@
+package recorder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// skippedHeaders are recorded headers that describe the original transport
+// rather than the request and are never replayed
+var skippedHeaders = map[string]bool{
+	"content-type": true, "content-length": true, "host": true,
+	"connection": true, "transfer-encoding": true, "accept-encoding": true,
+}
+
+// RedactedFields returns the names of fields whose values were not stored.
+// They must be supplied again for a faithful replay.
+func (rec *Recording) RedactedFields() []string {
+	var names []string
+	for name, value := range rec.Fields {
+		if value == Redacted {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Replay re-submits a recording to the server at baseURL. fields override
+// recorded form fields, for example to supply a redacted password; redacted
+// fields without an override are left out. headers are added to the
+// request, e.g. an Authorization header for the target server.
+func Replay(ctx context.Context, client *http.Client, baseURL string, rec *Recording, inputPath string, fields, headers map[string]string) (*http.Response, error) {
+	target := strings.TrimRight(baseURL, "/") + rec.Path
+	if rec.Query != "" {
+		target += "?" + rec.Query
+	}
+
+	body, contentType, err := replayBody(rec, inputPath, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, rec.Method, target, body)
+	if err != nil {
+		if body != nil {
+			body.Close()
+		}
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range rec.Headers {
+		if value == Redacted || skippedHeaders[strings.ToLower(name)] {
+			continue
+		}
+		req.Header.Set(name, value)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("replay failed: %w", err)
+	}
+	return resp, nil
+}
+
+// replayBody rebuilds the request body: a multipart form around the stored
+// upload, the stored raw body, or nothing
+func replayBody(rec *Recording, inputPath string, overrides map[string]string) (io.ReadCloser, string, error) {
+	if rec.Input == nil {
+		return nil, "", nil
+	}
+	if inputPath == "" {
+		return nil, "", fmt.Errorf("recording %s has no stored input (%d bytes exceeded the size limit)", rec.ID, rec.Input.Size)
+	}
+
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open recorded input: %w", err)
+	}
+	if rec.Input.Field == "" {
+		return input, rec.Input.ContentType, nil
+	}
+
+	fields := make(map[string]string, len(rec.Fields)+len(overrides))
+	for name, value := range rec.Fields {
+		if value != Redacted {
+			fields[name] = value
+		}
+	}
+	for name, value := range overrides {
+		fields[name] = value
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Stream the form so large inputs are not buffered
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		defer input.Close()
+		err := func() error {
+			for _, name := range names {
+				if err := form.WriteField(name, fields[name]); err != nil {
+					return err
+				}
+			}
+			part, err := form.CreateFormFile(rec.Input.Field, rec.Input.Filename)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(part, input); err != nil {
+				return err
+			}
+			return form.Close()
+		}()
+		writer.CloseWithError(err)
+	}()
+	return reader, form.FormDataContentType(), nil
+}
@@ -0,0 +1,121 @@
+package redisclient
+
+import (
+	"context"
+	"documents-worker/config"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Supported Redis topologies
+const (
+	ModeStandalone = "standalone"
+	ModeSentinel   = "sentinel"
+	ModeCluster    = "cluster"
+)
+
+// Options builds go-redis options for a standalone server from the shared
+// Redis configuration. Zero pool values fall back to the go-redis defaults.
+func Options(cfg *config.RedisConfig) *redis.Options {
+	return &redis.Options{
+		Addr:            fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+		Password:        cfg.Password,
+		DB:              cfg.DB,
+		PoolSize:        cfg.PoolSize,
+		MinIdleConns:    cfg.MinIdleConns,
+		PoolTimeout:     cfg.PoolTimeout,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		DialTimeout:     cfg.DialTimeout,
+		ReadTimeout:     cfg.ReadTimeout,
+		WriteTimeout:    cfg.WriteTimeout,
+	}
+}
+
+// FailoverOptions builds go-redis options for a Sentinel-managed master
+func FailoverOptions(cfg *config.RedisConfig) *redis.FailoverOptions {
+	return &redis.FailoverOptions{
+		MasterName:       cfg.MasterName,
+		SentinelAddrs:    cfg.SentinelAddrs,
+		SentinelPassword: cfg.SentinelPassword,
+		Password:         cfg.Password,
+		DB:               cfg.DB,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		PoolTimeout:      cfg.PoolTimeout,
+		ConnMaxIdleTime:  cfg.ConnMaxIdleTime,
+		DialTimeout:      cfg.DialTimeout,
+		ReadTimeout:      cfg.ReadTimeout,
+		WriteTimeout:     cfg.WriteTimeout,
+	}
+}
+
+// ClusterOptions builds go-redis options for a Redis Cluster. PoolSize and
+// MinIdleConns apply per cluster node.
+func ClusterOptions(cfg *config.RedisConfig) *redis.ClusterOptions {
+	return &redis.ClusterOptions{
+		Addrs:           cfg.ClusterAddrs,
+		Password:        cfg.Password,
+		PoolSize:        cfg.PoolSize,
+		MinIdleConns:    cfg.MinIdleConns,
+		PoolTimeout:     cfg.PoolTimeout,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
+		DialTimeout:     cfg.DialTimeout,
+		ReadTimeout:     cfg.ReadTimeout,
+		WriteTimeout:    cfg.WriteTimeout,
+	}
+}
+
+// NewClient creates a Redis client for the configured topology without
+// connecting. Standalone and Sentinel modes return a *redis.Client, cluster
+// mode returns a *redis.ClusterClient.
+func NewClient(cfg *config.RedisConfig) (redis.UniversalClient, error) {
+	switch cfg.Mode {
+	case "", ModeStandalone:
+		return redis.NewClient(Options(cfg)), nil
+
+	case ModeSentinel:
+		if cfg.MasterName == "" {
+			return nil, fmt.Errorf("redis sentinel mode requires a master name")
+		}
+		if len(cfg.SentinelAddrs) == 0 {
+			return nil, fmt.Errorf("redis sentinel mode requires at least one sentinel address")
+		}
+		return redis.NewFailoverClient(FailoverOptions(cfg)), nil
+
+	case ModeCluster:
+		if len(cfg.ClusterAddrs) == 0 {
+			return nil, fmt.Errorf("redis cluster mode requires at least one cluster address")
+		}
+		// Redis Cluster only supports database 0
+		if cfg.DB != 0 {
+			return nil, fmt.Errorf("redis cluster mode does not support selecting database %d", cfg.DB)
+		}
+		return redis.NewClusterClient(ClusterOptions(cfg)), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported redis mode: %s", cfg.Mode)
+	}
+}
+
+// New creates a Redis client for the configured topology with the configured
+// pool settings and verifies the connection. Subsystems should share the
+// returned client instead of creating their own.
+func New(cfg *config.RedisConfig) (redis.UniversalClient, error) {
+	client, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test connection
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis connection failed: %w", err)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,141 @@
+package redisclient
+
+import (
+	"documents-worker/config"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions(t *testing.T) {
+	cfg := &config.RedisConfig{
+		Host:            "redis.internal",
+		Port:            "6380",
+		Password:        "secret",
+		DB:              3,
+		PoolSize:        50,
+		MinIdleConns:    5,
+		PoolTimeout:     4 * time.Second,
+		ConnMaxIdleTime: 10 * time.Minute,
+		DialTimeout:     2 * time.Second,
+		ReadTimeout:     3 * time.Second,
+		WriteTimeout:    3 * time.Second,
+	}
+
+	opts := Options(cfg)
+
+	assert.Equal(t, "redis.internal:6380", opts.Addr)
+	assert.Equal(t, "secret", opts.Password)
+	assert.Equal(t, 3, opts.DB)
+	assert.Equal(t, 50, opts.PoolSize)
+	assert.Equal(t, 5, opts.MinIdleConns)
+	assert.Equal(t, 4*time.Second, opts.PoolTimeout)
+	assert.Equal(t, 10*time.Minute, opts.ConnMaxIdleTime)
+	assert.Equal(t, 2*time.Second, opts.DialTimeout)
+	assert.Equal(t, 3*time.Second, opts.ReadTimeout)
+	assert.Equal(t, 3*time.Second, opts.WriteTimeout)
+}
+
+func TestNewClientModes(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.RedisConfig
+		expectError bool
+		check       func(t *testing.T, client redis.UniversalClient)
+	}{
+		{
+			name: "default mode is standalone",
+			cfg:  config.RedisConfig{Host: "localhost", Port: "6379", PoolSize: 7},
+			check: func(t *testing.T, client redis.UniversalClient) {
+				c, ok := client.(*redis.Client)
+				require.True(t, ok)
+				assert.Equal(t, "localhost:6379", c.Options().Addr)
+				assert.Equal(t, 7, c.Options().PoolSize)
+			},
+		},
+		{
+			name: "sentinel",
+			cfg: config.RedisConfig{
+				Mode:          ModeSentinel,
+				MasterName:    "mymaster",
+				SentinelAddrs: []string{"s1:26379", "s2:26379"},
+				DB:            1,
+			},
+			check: func(t *testing.T, client redis.UniversalClient) {
+				c, ok := client.(*redis.Client)
+				require.True(t, ok)
+				assert.Equal(t, "FailoverClient", c.Options().Addr)
+				assert.Equal(t, 1, c.Options().DB)
+			},
+		},
+		{
+			name: "cluster",
+			cfg: config.RedisConfig{
+				Mode:         ModeCluster,
+				ClusterAddrs: []string{"n1:6379", "n2:6379", "n3:6379"},
+				PoolSize:     15,
+			},
+			check: func(t *testing.T, client redis.UniversalClient) {
+				c, ok := client.(*redis.ClusterClient)
+				require.True(t, ok)
+				assert.Equal(t, []string{"n1:6379", "n2:6379", "n3:6379"}, c.Options().Addrs)
+				assert.Equal(t, 15, c.Options().PoolSize)
+			},
+		},
+		{
+			name:        "sentinel without master name",
+			cfg:         config.RedisConfig{Mode: ModeSentinel, SentinelAddrs: []string{"s1:26379"}},
+			expectError: true,
+		},
+		{
+			name:        "sentinel without addresses",
+			cfg:         config.RedisConfig{Mode: ModeSentinel, MasterName: "mymaster"},
+			expectError: true,
+		},
+		{
+			name:        "cluster without addresses",
+			cfg:         config.RedisConfig{Mode: ModeCluster},
+			expectError: true,
+		},
+		{
+			name:        "cluster with non-zero database",
+			cfg:         config.RedisConfig{Mode: ModeCluster, ClusterAddrs: []string{"n1:6379"}, DB: 2},
+			expectError: true,
+		},
+		{
+			name:        "unknown mode",
+			cfg:         config.RedisConfig{Mode: "ring"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(&tt.cfg)
+			if tt.expectError {
+				assert.Error(t, err)
+				assert.Nil(t, client)
+				return
+			}
+
+			require.NoError(t, err)
+			defer client.Close()
+			tt.check(t, client)
+		})
+	}
+}
+
+func TestNewUnreachable(t *testing.T) {
+	cfg := &config.RedisConfig{
+		Host:        "127.0.0.1",
+		Port:        "1",
+		DialTimeout: 200 * time.Millisecond,
+	}
+
+	client, err := New(cfg)
+	assert.Error(t, err)
+	assert.Nil(t, client)
+}
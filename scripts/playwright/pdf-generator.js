@@ -1,9 +1,148 @@
 #!/usr/bin/env node
 
 const { chromium } = require('playwright');
+const dns = require('dns');
 const fs = require('fs');
+const http = require('http');
+const https = require('https');
+const net = require('net');
 const path = require('path');
 
+/**
+ * Reports addresses a rendered page may not reach: loopback, private,
+ * link-local (cloud metadata), shared address space, multicast and
+ * unspecified
+ */
+function blockedAddress(address) {
+    if (net.isIPv6(address)) {
+        const lower = address.toLowerCase();
+        const mapped = lower.match(/^::ffff:(\d+\.\d+\.\d+\.\d+)$/);
+        if (mapped) {
+            return blockedAddress(mapped[1]);
+        }
+        return lower === '::' || lower === '::1' || /^f[cd]/.test(lower) ||
+            /^fe[89ab]/.test(lower) || /^ff/.test(lower);
+    }
+    const [a, b] = address.split('.').map(Number);
+    return a === 0 || a === 10 || a === 127 || a >= 224 ||
+        (a === 100 && b >= 64 && b <= 127) ||
+        (a === 169 && b === 254) ||
+        (a === 172 && b >= 16 && b <= 31) ||
+        (a === 192 && b === 168);
+}
+
+/**
+ * DNS lookup for outgoing requests that drops internal addresses, so the
+ * check applies to the address actually connected to
+ */
+function guardedLookup(hostname, options, callback) {
+    dns.lookup(hostname, { ...options, all: true }, (err, addresses) => {
+        if (err) {
+            return callback(err);
+        }
+        const allowed = addresses.filter((entry) => !blockedAddress(entry.address));
+        if (allowed.length === 0) {
+            return callback(new Error(`${hostname} resolves to an internal address`));
+        }
+        if (options.all) {
+            return callback(null, allowed);
+        }
+        callback(null, allowed[0].address, allowed[0].family);
+    });
+}
+
+/**
+ * Performs a request of the page under the fetch limits. The bytes of all
+ * responses are counted against budget.remaining.
+ */
+function fetchResource(request, limits, budget) {
+    return new Promise((resolve, reject) => {
+        const url = new URL(request.url());
+        const host = url.hostname.replace(/^\[|\]$/g, '');
+        if (!limits.allowPrivateNetworks && net.isIP(host) && blockedAddress(host)) {
+            return reject(new Error(`${host} is an internal address`));
+        }
+
+        // The body is handed to the browser as received, so it must not
+        // be compressed
+        const headers = { ...request.headers() };
+        delete headers['accept-encoding'];
+
+        const client = url.protocol === 'https:' ? https : http;
+        const req = client.request(url, {
+            method: request.method(),
+            headers,
+            lookup: limits.allowPrivateNetworks ? undefined : guardedLookup,
+            timeout: limits.connectTimeout || 10000
+        }, (res) => {
+            req.setTimeout(limits.readTimeout || 30000);
+            const declared = Number(res.headers['content-length']);
+            if (declared > budget.remaining) {
+                budget.exceeded = true;
+                return req.destroy(new Error(`${url} exceeds the download limit`));
+            }
+            const chunks = [];
+            res.on('data', (chunk) => {
+                budget.remaining -= chunk.length;
+                if (budget.remaining < 0) {
+                    budget.exceeded = true;
+                    return req.destroy(new Error(`${url} exceeds the download limit`));
+                }
+                chunks.push(chunk);
+            });
+            res.on('end', () => {
+                const responseHeaders = {};
+                for (const [name, value] of Object.entries(res.headers)) {
+                    if (!['content-length', 'content-encoding', 'transfer-encoding', 'connection', 'keep-alive'].includes(name)) {
+                        responseHeaders[name] = Array.isArray(value) ? value.join('\n') : value;
+                    }
+                }
+                resolve({ status: res.statusCode, headers: responseHeaders, body: Buffer.concat(chunks) });
+            });
+        });
+        req.on('timeout', () => req.destroy(new Error(`no data from ${url} in time`)));
+        req.on('error', reject);
+
+        const body = request.postDataBuffer();
+        if (body) {
+            req.write(body);
+        }
+        req.end();
+    });
+}
+
+/**
+ * Routes every request of the page through fetchResource. The already
+ * downloaded document is served for the page itself. Other schemes than
+ * http and https are refused.
+ */
+async function applyFetchLimits(page, limits) {
+    const budget = { remaining: limits.maxSize || 50 * 1024 * 1024, exceeded: false };
+    const document = limits.document;
+
+    await page.route('**/*', async (route) => {
+        const request = route.request();
+        const protocol = new URL(request.url()).protocol;
+        if (protocol === 'data:' || protocol === 'blob:') {
+            return route.continue();
+        }
+        if (protocol !== 'http:' && protocol !== 'https:') {
+            return route.abort('accessdenied');
+        }
+        if (document && request.url() === document.url && request.isNavigationRequest() &&
+            request.frame() === page.mainFrame()) {
+            return route.fulfill({ status: 200, path: document.path, contentType: document.contentType });
+        }
+        try {
+            await route.fulfill(await fetchResource(request, limits, budget));
+        } catch (error) {
+            await route.abort(budget.exceeded ? 'failed' : 'blockedbyclient');
+        }
+    });
+
+    return budget;
+}
+
 /**
  * Playwright PDF Generator
  * Usage: node pdf-generator.js <inputFile> <outputFile> [options]
@@ -54,7 +193,12 @@ async function generatePDF() {
             args: ['--no-sandbox', '--disable-dev-shm-usage']
         });
 
-        const page = await browser.newPage();
+        // Service workers would fetch past the request interception
+        const context = await browser.newContext({
+            serviceWorkers: options.fetch ? 'block' : 'allow'
+        });
+        const page = await context.newPage();
+        const budget = options.fetch ? await applyFetchLimits(page, options.fetch) : null;
         
         // Set viewport for consistent rendering
         await page.setViewportSize({ 
@@ -95,11 +239,46 @@ async function generatePDF() {
             await page.waitForTimeout(options.waitTime);
         }
 
+        if (budget && budget.exceeded) {
+            throw new Error(`page resources exceed the download limit of ${options.fetch.maxSize} bytes`);
+        }
+
         // Add custom CSS if provided
         if (options.css) {
             await page.addStyleTag({ content: options.css });
         }
 
+        // Draw the watermark; Chromium repeats fixed elements on every printed page
+        if (options.watermark) {
+            await page.evaluate(({ text, position, opacity }) => {
+                const mark = document.createElement('div');
+                mark.className = 'documents-worker-watermark';
+                mark.textContent = text;
+                const transform = position === 'center'
+                    ? 'translate(-50%, -50%)'
+                    : 'translate(-50%, -50%) rotate(-45deg)';
+                Object.assign(mark.style, {
+                    position: 'fixed',
+                    top: '50%',
+                    left: '50%',
+                    transform: transform,
+                    opacity: String(opacity),
+                    color: '#000',
+                    fontFamily: 'Arial, sans-serif',
+                    fontSize: '72px',
+                    fontWeight: 'bold',
+                    whiteSpace: 'nowrap',
+                    pointerEvents: 'none',
+                    zIndex: '9999'
+                });
+                document.body.appendChild(mark);
+            }, {
+                text: options.watermark,
+                position: options.watermarkPosition,
+                opacity: options.watermarkOpacity
+            });
+        }
+
         // Generate PDF
         await page.pdf(pdfOptions);
 
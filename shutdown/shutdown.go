@@ -0,0 +1,84 @@
+// Package shutdown stops the server's components in order within one
+// overall deadline.
+package shutdown
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Stage stops one component. It should return once ctx is done even if the
+// component has not drained.
+type Stage struct {
+	Name string
+	Stop func(ctx context.Context) error
+}
+
+// Result is the outcome of one stage
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// Sequence runs its stages one after another, in the order they were
+// added: first the stages that stop accepting work, then those draining
+// it, then the connections they used.
+type Sequence struct {
+	timeout time.Duration
+	stages  []Stage
+}
+
+// NewSequence creates a sequence that must finish within timeout. A zero
+// timeout waits for every stage.
+func NewSequence(timeout time.Duration) *Sequence {
+	return &Sequence{timeout: timeout}
+}
+
+// Add appends a stage
+func (s *Sequence) Add(name string, stop func(ctx context.Context) error) *Sequence {
+	s.stages = append(s.stages, Stage{Name: name, Stop: stop})
+	return s
+}
+
+// AddFunc appends a stage stopping a component that cannot be interrupted.
+// Once the deadline passes the sequence moves on and leaves it running.
+func (s *Sequence) AddFunc(name string, stop func()) *Sequence {
+	return s.Add(name, func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			stop()
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// Run stops every stage and returns their outcomes. A stage that fails does
+// not stop the sequence; once the deadline has passed the remaining stages
+// still run, each with an expired context, so connections are closed.
+func (s *Sequence) Run(ctx context.Context) []Result {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	results := make([]Result, 0, len(s.stages))
+	for _, stage := range s.stages {
+		started := time.Now()
+		err := stage.Stop(ctx)
+		result := Result{Name: stage.Name, Err: err, Duration: time.Since(started)}
+		if err != nil {
+			log.Printf("Shutdown: %s: %v", stage.Name, err)
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,73 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequenceRunsStagesInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	failure := errors.New("close failed")
+	results := NewSequence(time.Second).
+		Add("http", func(ctx context.Context) error {
+			record("http")
+			return nil
+		}).
+		AddFunc("maintenance", func() { record("maintenance") }).
+		Add("queue", func(ctx context.Context) error {
+			record("queue")
+			return failure
+		}).
+		Add("redis", func(ctx context.Context) error {
+			record("redis")
+			return nil
+		}).
+		Run(context.Background())
+
+	assert.Equal(t, []string{"http", "maintenance", "queue", "redis"}, order)
+	require.Len(t, results, 4)
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+	assert.ErrorIs(t, results[2].Err, failure)
+	assert.NoError(t, results[3].Err)
+}
+
+func TestSequenceFinishesWithinDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	closed := false
+	started := time.Now()
+	results := NewSequence(100*time.Millisecond).
+		Add("http", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).
+		AddFunc("consumers", func() { <-release }).
+		Add("redis", func(ctx context.Context) error {
+			closed = true
+			return nil
+		}).
+		Run(context.Background())
+
+	assert.Less(t, time.Since(started), time.Second)
+	require.Len(t, results, 3)
+	assert.ErrorIs(t, results[0].Err, context.DeadlineExceeded)
+	assert.ErrorIs(t, results[1].Err, context.DeadlineExceeded)
+	// Connections are closed even after the deadline
+	assert.True(t, closed)
+	assert.NoError(t, results[2].Err)
+}
@@ -1,7 +1,10 @@
 package textextractor
 
 import (
+	"context"
 	"documents-worker/config"
+	"documents-worker/libreoffice"
+	"documents-worker/ocr"
 	"documents-worker/utils"
 	"fmt"
 	"os"
@@ -13,7 +16,11 @@ import (
 )
 
 type TextExtractor struct {
-	config *config.ExternalConfig
+	config   *config.ExternalConfig
+	password string
+	ctx      context.Context
+	mode     ExtractionMode
+	ocr      *ocr.OCRProcessor
 }
 
 type ExtractionResult struct {
@@ -26,6 +33,11 @@ type ExtractionResult struct {
 	Metadata    map[string]interface{} `json:"metadata"`
 	ExtractedAt time.Time              `json:"extracted_at"`
 	Duration    time.Duration          `json:"duration"`
+	// RequiresOCR is set when PDF pages have no text layer and were not
+	// OCRed; the text is then incomplete or empty
+	RequiresOCR bool `json:"requires_ocr,omitempty"`
+	// Pages holds the per-page text and its source in ModeAuto
+	Pages []PageResult `json:"pages,omitempty"`
 }
 
 type DocumentInfo struct {
@@ -46,6 +58,54 @@ func NewTextExtractor(externalConfig *config.ExternalConfig) *TextExtractor {
 	}
 }
 
+// WithPassword returns a copy of the extractor that opens encrypted PDFs
+// with the given password. The password is never logged.
+func (te *TextExtractor) WithPassword(password string) *TextExtractor {
+	clone := *te
+	clone.password = password
+	return &clone
+}
+
+// WithContext returns a copy of the extractor whose external tools are
+// killed when ctx is canceled
+func (te *TextExtractor) WithContext(ctx context.Context) *TextExtractor {
+	clone := *te
+	clone.ctx = ctx
+	return &clone
+}
+
+// command builds an external tool invocation bound to the extractor's context
+func (te *TextExtractor) command(name string, args ...string) *exec.Cmd {
+	ctx := te.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return exec.CommandContext(ctx, name, args...)
+}
+
+// runLibreOffice runs LibreOffice in the shared pool under the extractor's
+// context
+func (te *TextExtractor) runLibreOffice(args ...string) ([]byte, error) {
+	ctx := te.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return libreoffice.Run(ctx, te.config.LibreOfficePath, args...)
+}
+
+// canceled returns the context error once the extractor's context is done
+func (te *TextExtractor) canceled() error {
+	if te.ctx == nil {
+		return nil
+	}
+	return te.ctx.Err()
+}
+
+// preparePDF decrypts the PDF when needed; cleanup must always be called
+func (te *TextExtractor) preparePDF(pdfPath string) (string, func(), error) {
+	return utils.PreparePDF(te.config.MutoolPath, pdfPath, te.password)
+}
+
 // ExtractFromFile determines file type and extracts text accordingly
 func (te *TextExtractor) ExtractFromFile(filePath string) (*ExtractionResult, error) {
 	startTime := time.Now()
@@ -63,6 +123,8 @@ func (te *TextExtractor) ExtractFromFile(filePath string) (*ExtractionResult, er
 		result, err = te.extractFromPDF(filePath)
 	case utils.IsOfficeDocument(mimeType):
 		result, err = te.extractFromOfficeDocument(filePath)
+	case utils.IsTiffImage(mimeType):
+		result, err = te.extractFromTIFF(filePath)
 	case strings.Contains(mimeType, "text/"):
 		result, err = te.extractFromTextFile(filePath)
 	default:
@@ -83,36 +145,55 @@ func (te *TextExtractor) ExtractFromFile(filePath string) (*ExtractionResult, er
 }
 
 // extractFromPDF extracts text from PDF using MuPDF
-func (te *TextExtractor) extractFromPDF(pdfPath string) (*ExtractionResult, error) {
-	// First get PDF info
-	info, err := te.getPDFInfo(pdfPath)
+func (te *TextExtractor) extractFromPDF(sourcePath string) (*ExtractionResult, error) {
+	pdfPath, cleanup, err := te.preparePDF(sourcePath)
+	defer cleanup()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get PDF info: %w", err)
+		return nil, err
 	}
 
-	// Extract text using mutool
-	cmd := exec.Command(te.config.MutoolPath, "draw", "-F", "txt", pdfPath)
-	output, err := cmd.Output()
+	// First get PDF info
+	info, err := te.getPDFInfo(pdfPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract text with mutool: %w", err)
+		return nil, fmt.Errorf("failed to get PDF info: %w", err)
 	}
 
-	text := string(output)
-
-	// Clean up the text
-	text = te.cleanExtractedText(text)
-
 	result := &ExtractionResult{
-		Text:       text,
 		SourceType: "pdf",
 		PageCount:  info.Pages,
 		Metadata: map[string]interface{}{
-			"source_file": filepath.Base(pdfPath),
+			"source_file": filepath.Base(sourcePath),
 			"pdf_info":    info,
 			"extractor":   "mutool",
 		},
 	}
 
+	// In auto mode every page is checked, so scanned pages can be OCRed
+	if te.mode == ModeAuto {
+		pages, err := te.extractPages(pdfPath, 1, info.Pages)
+		if err != nil {
+			return nil, err
+		}
+		var ocred bool
+		result.Pages = pages
+		result.Text, result.RequiresOCR, ocred = joinPages(pages)
+		if ocred {
+			result.Metadata["extractor"] = "mutool+tesseract"
+		}
+		return result, nil
+	}
+
+	// Extract text using mutool
+	cmd := te.command(te.config.MutoolPath, "draw", "-F", "txt", pdfPath)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text with mutool: %w", err)
+	}
+
+	// Clean up the text
+	result.Text = te.cleanExtractedText(string(output))
+	result.RequiresOCR = IsScanned(result.Text, info.Pages)
+
 	return result, nil
 }
 
@@ -161,14 +242,12 @@ func (te *TextExtractor) extractTextWithLibreOffice(docPath string) (string, err
 	defer os.RemoveAll(outputDir)
 
 	// Convert to plain text
-	cmd := exec.Command(te.config.LibreOfficePath,
+	output, err := te.runLibreOffice(
 		"--headless",
 		"--convert-to", "txt:Text",
 		"--outdir", outputDir,
 		docPath,
 	)
-
-	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("libreoffice text extraction failed: %w, output: %s", err, string(output))
 	}
@@ -209,7 +288,7 @@ func (te *TextExtractor) extractFromTextFile(textPath string) (*ExtractionResult
 
 // getPDFInfo extracts metadata from PDF using mutool
 func (te *TextExtractor) getPDFInfo(pdfPath string) (*DocumentInfo, error) {
-	cmd := exec.Command(te.config.MutoolPath, "info", pdfPath)
+	cmd := te.command(te.config.MutoolPath, "info", pdfPath)
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get PDF info: %w", err)
@@ -260,14 +339,12 @@ func (te *TextExtractor) convertToPDF(docPath string) (string, error) {
 		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
-	cmd := exec.Command(te.config.LibreOfficePath,
+	output, err := te.runLibreOffice(
 		"--headless",
 		"--convert-to", "pdf",
 		"--outdir", outputDir,
 		docPath,
 	)
-
-	output, err := cmd.CombinedOutput()
 	if err != nil {
 		os.RemoveAll(outputDir)
 		return "", fmt.Errorf("libreoffice PDF conversion failed: %w, output: %s", err, string(output))
@@ -315,13 +392,19 @@ func (te *TextExtractor) countWords(text string) int {
 }
 
 // ExtractByPages extracts text from specific PDF pages
-func (te *TextExtractor) ExtractByPages(pdfPath string, startPage, endPage int) (*ExtractionResult, error) {
+func (te *TextExtractor) ExtractByPages(sourcePath string, startPage, endPage int) (*ExtractionResult, error) {
 	if endPage < startPage {
 		return nil, fmt.Errorf("end page cannot be less than start page")
 	}
 
 	startTime := time.Now()
 
+	pdfPath, cleanup, err := te.preparePDF(sourcePath)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+
 	// Get PDF info first
 	info, err := te.getPDFInfo(pdfPath)
 	if err != nil {
@@ -334,14 +417,26 @@ func (te *TextExtractor) ExtractByPages(pdfPath string, startPage, endPage int)
 
 	// Extract text from specific pages
 	pageRange := fmt.Sprintf("%d-%d", startPage, endPage)
-	cmd := exec.Command(te.config.MutoolPath, "draw", "-F", "txt", pdfPath, pageRange)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract text from pages %s: %w", pageRange, err)
+	var (
+		text        string
+		requiresOCR bool
+		pages       []PageResult
+	)
+	if te.mode == ModeAuto {
+		if pages, err = te.extractPages(pdfPath, startPage, endPage); err != nil {
+			return nil, err
+		}
+		text, requiresOCR, _ = joinPages(pages)
+	} else {
+		cmd := te.command(te.config.MutoolPath, "draw", "-F", "txt", pdfPath, pageRange)
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract text from pages %s: %w", pageRange, err)
+		}
+		text = te.cleanExtractedText(string(output))
+		requiresOCR = IsScanned(text, endPage-startPage+1)
 	}
 
-	text := te.cleanExtractedText(string(output))
-
 	result := &ExtractionResult{
 		Text:        text,
 		SourceType:  "pdf_pages",
@@ -350,8 +445,10 @@ func (te *TextExtractor) ExtractByPages(pdfPath string, startPage, endPage int)
 		CharCount:   len(text),
 		ExtractedAt: time.Now(),
 		Duration:    time.Since(startTime),
+		RequiresOCR: requiresOCR,
+		Pages:       pages,
 		Metadata: map[string]interface{}{
-			"source_file": filepath.Base(pdfPath),
+			"source_file": filepath.Base(sourcePath),
 			"page_range":  pageRange,
 			"start_page":  startPage,
 			"end_page":    endPage,
@@ -364,8 +461,23 @@ func (te *TextExtractor) ExtractByPages(pdfPath string, startPage, endPage int)
 	return result, nil
 }
 
-// BatchExtractPDFPages extracts text from each page separately
-func (te *TextExtractor) BatchExtractPDFPages(pdfPath string) ([]*ExtractionResult, error) {
+// BatchExtractPDFPages extracts text from each page separately. Multi-page
+// TIFFs are accepted too and handled as scanned documents.
+func (te *TextExtractor) BatchExtractPDFPages(sourcePath string) ([]*ExtractionResult, error) {
+	if mimeType, err := utils.DetectMimeTypeFromFile(sourcePath); err == nil && utils.IsTiffImage(mimeType) {
+		return te.batchExtractTIFFPages(sourcePath)
+	}
+
+	// Decrypt once up front instead of for every page
+	pdfPath, cleanup, err := te.preparePDF(sourcePath)
+	defer cleanup()
+	if err != nil {
+		return nil, err
+	}
+	// Pages are extracted as text first and resolved below, so scanned
+	// pages are OCRed once
+	pages := te.WithPassword("").WithMode(ModeText)
+
 	// Get PDF info
 	info, err := te.getPDFInfo(pdfPath)
 	if err != nil {
@@ -375,7 +487,10 @@ func (te *TextExtractor) BatchExtractPDFPages(pdfPath string) ([]*ExtractionResu
 	var results []*ExtractionResult
 
 	for page := 1; page <= info.Pages; page++ {
-		result, err := te.ExtractByPages(pdfPath, page, page)
+		result, err := pages.ExtractByPages(pdfPath, page, page)
+		if cancelErr := te.canceled(); cancelErr != nil {
+			return nil, cancelErr
+		}
 		if err != nil {
 			// Log error but continue with other pages
 			fmt.Printf("Failed to extract page %d: %v\n", page, err)
@@ -383,7 +498,15 @@ func (te *TextExtractor) BatchExtractPDFPages(pdfPath string) ([]*ExtractionResu
 		}
 
 		// Update metadata for individual page
+		resolved := te.resolvePage(pdfPath, page, result.Text)
+		result.Text = resolved.Text
+		result.RequiresOCR = resolved.RequiresOCR
+		result.WordCount = te.countWords(resolved.Text)
+		result.CharCount = len(resolved.Text)
 		result.Metadata["page_number"] = page
+		result.Metadata["source_file"] = filepath.Base(sourcePath)
+		result.Metadata["text_source"] = resolved.Source
+		result.Metadata["scanned"] = resolved.Scanned
 		result.SourceType = "pdf_page"
 
 		results = append(results, result)
@@ -0,0 +1,135 @@
+package textextractor
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OutlineItem is an entry of a PDF's outline (bookmark) tree
+type OutlineItem struct {
+	Title string `json:"title"`
+	// Level is the depth in the tree, 1 for top-level entries
+	Level int `json:"level"`
+	// Page is the 1-based target page, 0 when the entry does not point to a
+	// page of the document
+	Page int `json:"page,omitempty"`
+	// URI is the target of entries pointing outside the document
+	URI      string         `json:"uri,omitempty"`
+	Children []*OutlineItem `json:"children,omitempty"`
+}
+
+// ExtractOutline returns the outline tree of a PDF. Documents without an
+// outline return an empty tree.
+func (te *TextExtractor) ExtractOutline(sourcePath string) ([]*OutlineItem, error) {
+	pdfPath, cleanup, err := te.preparePDF(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	cmd := te.command(te.config.MutoolPath, "show", pdfPath, "outline")
+	output, err := cmd.Output()
+	if err != nil {
+		if cancelErr := te.canceled(); cancelErr != nil {
+			return nil, cancelErr
+		}
+		return nil, fmt.Errorf("failed to read outline with mutool: %w", err)
+	}
+
+	return parseOutline(string(output)), nil
+}
+
+// parseOutline builds the outline tree from mutool show outline output. Each
+// entry is a line indented by a tab per level, holding the title and the
+// link target separated by a tab. Newer mutool versions prefix lines with a
+// +, - or | marker and quote the title.
+func parseOutline(output string) []*OutlineItem {
+	var roots []*OutlineItem
+	// parents[i] is the last entry seen at level i+1
+	var parents []*OutlineItem
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 1 && strings.ContainsRune("+-|", rune(line[0])) && (line[1] == '\t' || line[1] == '"') {
+			line = line[1:]
+		}
+		depth := len(line) - len(strings.TrimLeft(line, "\t"))
+		line = line[depth:]
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		title, target := line, ""
+		if strings.HasPrefix(line, `"`) {
+			if end := closingQuote(line); end > 0 {
+				if unquoted, err := strconv.Unquote(line[:end+1]); err == nil {
+					title = unquoted
+				} else {
+					title = line[1:end]
+				}
+				target = strings.TrimSpace(line[end+1:])
+			}
+		} else if i := strings.LastIndex(line, "\t"); i >= 0 {
+			title, target = line[:i], strings.TrimSpace(line[i+1:])
+		}
+
+		item := &OutlineItem{Title: strings.TrimSpace(title)}
+		if page, ok := outlineTargetPage(target); ok {
+			item.Page = page
+		} else if target != "" && !strings.HasPrefix(target, "#") {
+			item.URI = target
+		}
+
+		// An entry can only be one level deeper than its predecessor
+		depth = min(depth, len(parents))
+		item.Level = depth + 1
+		parents = append(parents[:depth], item)
+		if depth == 0 {
+			roots = append(roots, item)
+		} else {
+			parent := parents[depth-1]
+			parent.Children = append(parent.Children, item)
+		}
+	}
+
+	if roots == nil {
+		roots = []*OutlineItem{}
+	}
+	return roots
+}
+
+// closingQuote returns the index of the quote ending the quoted string at
+// the start of s, or -1
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// outlineTargetPage reads the page number of an internal link, written as
+// #page=N&... by newer mutool versions and #N,x,y by older ones
+func outlineTargetPage(target string) (int, bool) {
+	fragment, ok := strings.CutPrefix(target, "#")
+	if !ok {
+		return 0, false
+	}
+	for _, param := range strings.Split(fragment, "&") {
+		if value, ok := strings.CutPrefix(param, "page="); ok {
+			page, err := strconv.Atoi(value)
+			return page, err == nil && page > 0
+		}
+	}
+	number, _, _ := strings.Cut(fragment, ",")
+	page, err := strconv.Atoi(number)
+	return page, err == nil && page > 0
+}
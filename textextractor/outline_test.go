@@ -0,0 +1,89 @@
+package textextractor
+
+import (
+	"documents-worker/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// outlineSamplePDF has three pages bookmarked as Introduction, Chapter 1
+// and its Section 1.1
+const outlineSamplePDF = "testdata/outline.pdf"
+
+// sampleOutline is mutool show outline output for outlineSamplePDF
+const sampleOutline = "|\"Introduction\"\t#page=1&view=Fit\n" +
+	"-\"Chapter 1\"\t#page=2&view=Fit\n" +
+	"|\t\"Section 1.1\"\t#page=3&view=Fit\n"
+
+// fakeOutlineMutool prints sampleOutline for PDFs with an outline and
+// nothing for others, like mutool does
+const fakeOutlineMutool = `#!/bin/sh
+[ "$1 $3" = "show outline" ] || exit 1
+grep -q Outlines "$2" || exit 0
+cat <<'EOF'
+` + sampleOutline + `EOF
+`
+
+func assertSampleOutline(t *testing.T, outline []*OutlineItem) {
+	t.Helper()
+	require.Len(t, outline, 2)
+	assert.Equal(t, &OutlineItem{Title: "Introduction", Level: 1, Page: 1}, outline[0])
+	assert.Equal(t, "Chapter 1", outline[1].Title)
+	assert.Equal(t, 2, outline[1].Page)
+	require.Len(t, outline[1].Children, 1)
+	assert.Equal(t, &OutlineItem{Title: "Section 1.1", Level: 2, Page: 3}, outline[1].Children[0])
+}
+
+func TestParseOutline(t *testing.T) {
+	assertSampleOutline(t, parseOutline(sampleOutline))
+
+	// Older mutool versions neither mark nor quote entries
+	legacy := "Introduction\t#1,0,200\nChapter 1\t#2,0,200\n\tSection 1.1\t#3,0,200\n"
+	assertSampleOutline(t, parseOutline(legacy))
+
+	outline := parseOutline("|\"Say \\\"hi\\\"\"\t#nameddest=greeting\n|\"Website\"\thttps://example.com/docs\n")
+	require.Len(t, outline, 2)
+	assert.Equal(t, &OutlineItem{Title: `Say "hi"`, Level: 1}, outline[0])
+	assert.Equal(t, &OutlineItem{Title: "Website", Level: 1, URI: "https://example.com/docs"}, outline[1])
+
+	assert.Empty(t, parseOutline(""))
+	assert.NotNil(t, parseOutline(""))
+}
+
+func TestExtractOutline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake mutool is a shell script")
+	}
+
+	dir := t.TempDir()
+	mutool := filepath.Join(dir, "mutool")
+	require.NoError(t, os.WriteFile(mutool, []byte(fakeOutlineMutool), 0755))
+	extractor := NewTextExtractor(&config.ExternalConfig{MutoolPath: mutool})
+
+	outline, err := extractor.ExtractOutline(outlineSamplePDF)
+	require.NoError(t, err)
+	assertSampleOutline(t, outline)
+
+	// PDFs without bookmarks have an empty outline
+	plain := filepath.Join(dir, "plain.pdf")
+	require.NoError(t, os.WriteFile(plain, []byte("%PDF-1.4\n%%EOF\n"), 0644))
+	outline, err = extractor.ExtractOutline(plain)
+	require.NoError(t, err)
+	assert.Empty(t, outline)
+}
+
+func TestExtractOutlineWithMutool(t *testing.T) {
+	if _, err := exec.LookPath("mutool"); err != nil {
+		t.Skip("mutool not installed")
+	}
+
+	outline, err := NewTextExtractor(&config.ExternalConfig{MutoolPath: "mutool"}).ExtractOutline(outlineSamplePDF)
+	require.NoError(t, err)
+	assertSampleOutline(t, outline)
+}
@@ -0,0 +1,148 @@
+package textextractor
+
+import (
+	"documents-worker/ocr"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+)
+
+// ExtractionMode selects how text is obtained from PDF pages
+type ExtractionMode string
+
+const (
+	// ModeText returns the embedded text only and flags scanned pages as
+	// requiring OCR
+	ModeText ExtractionMode = "text"
+	// ModeAuto falls back to OCR for scanned pages
+	ModeAuto ExtractionMode = "auto"
+)
+
+// ParseExtractionMode parses a mode name; empty means ModeText
+func ParseExtractionMode(name string) (ExtractionMode, error) {
+	switch ExtractionMode(strings.ToLower(strings.TrimSpace(name))) {
+	case "", ModeText:
+		return ModeText, nil
+	case ModeAuto:
+		return ModeAuto, nil
+	default:
+		return "", fmt.Errorf("unknown extraction mode %q (supported: text, auto)", name)
+	}
+}
+
+// Where the text of a page came from
+const (
+	PageSourceExtraction = "extraction"
+	PageSourceOCR        = "ocr"
+)
+
+// MinPageTextChars is the number of non-space characters below which a page
+// is treated as scanned, i.e. an image without a text layer. Scanned pages
+// often still carry a page number or a stray header.
+const MinPageTextChars = 16
+
+// PageResult is the text of a single PDF page and where it came from
+type PageResult struct {
+	Page   int    `json:"page"`
+	Text   string `json:"text"`
+	Source string `json:"source"`
+	// Scanned is set when the page had too little embedded text
+	Scanned bool `json:"scanned"`
+	// RequiresOCR is set for scanned pages that were not OCRed
+	RequiresOCR bool    `json:"requires_ocr,omitempty"`
+	Confidence  float64 `json:"confidence,omitempty"`
+}
+
+// WithMode returns a copy of the extractor using the given extraction mode
+func (te *TextExtractor) WithMode(mode ExtractionMode) *TextExtractor {
+	clone := *te
+	clone.mode = mode
+	return &clone
+}
+
+// WithOCR returns a copy of the extractor that OCRs scanned pages with
+// processor in ModeAuto
+func (te *TextExtractor) WithOCR(processor *ocr.OCRProcessor) *TextExtractor {
+	clone := *te
+	clone.ocr = processor
+	return &clone
+}
+
+// IsScanned reports whether text extracted from pages PDF pages is too
+// sparse to be anything but scanned images
+func IsScanned(text string, pages int) bool {
+	if pages < 1 {
+		pages = 1
+	}
+	return textChars(text) < MinPageTextChars*pages
+}
+
+// textChars counts the non-space characters of text
+func textChars(text string) int {
+	count := 0
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			count++
+		}
+	}
+	return count
+}
+
+// resolvePage classifies the extracted text of a page and, in ModeAuto,
+// replaces the text of a scanned page with its OCR result. pdfPath must
+// already be decrypted.
+func (te *TextExtractor) resolvePage(pdfPath string, page int, text string) PageResult {
+	result := PageResult{Page: page, Text: text, Source: PageSourceExtraction}
+	if textChars(text) >= MinPageTextChars {
+		return result
+	}
+
+	result.Scanned = true
+	if te.mode != ModeAuto || te.ocr == nil {
+		result.RequiresOCR = true
+		return result
+	}
+
+	recognized, err := te.ocr.WithPassword("").ProcessPDF(pdfPath, page)
+	if err != nil {
+		log.Printf("OCR fallback failed for page %d: %v", page, err)
+		result.RequiresOCR = true
+		return result
+	}
+	result.Text = recognized.Text
+	result.Source = PageSourceOCR
+	result.Confidence = recognized.Confidence
+	return result
+}
+
+// extractPages extracts the pages first to last of a decrypted PDF
+// separately
+func (te *TextExtractor) extractPages(pdfPath string, first, last int) ([]PageResult, error) {
+	pages := make([]PageResult, 0, last-first+1)
+	for page := first; page <= last; page++ {
+		if err := te.canceled(); err != nil {
+			return nil, err
+		}
+		output, err := te.command(te.config.MutoolPath, "draw", "-F", "txt", pdfPath, fmt.Sprintf("%d", page)).Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract text from page %d: %w", page, err)
+		}
+		pages = append(pages, te.resolvePage(pdfPath, page, te.cleanExtractedText(string(output))))
+	}
+	return pages, nil
+}
+
+// joinPages joins the text of the pages and reports whether any page still
+// requires OCR and whether any was OCRed
+func joinPages(pages []PageResult) (text string, requiresOCR, ocred bool) {
+	texts := make([]string, 0, len(pages))
+	for _, page := range pages {
+		if page.Text != "" {
+			texts = append(texts, page.Text)
+		}
+		requiresOCR = requiresOCR || page.RequiresOCR
+		ocred = ocred || page.Source == PageSourceOCR
+	}
+	return strings.Join(texts, "\n\n"), requiresOCR, ocred
+}
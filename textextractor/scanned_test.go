@@ -0,0 +1,148 @@
+package textextractor
+
+import (
+	"documents-worker/config"
+	"documents-worker/ocr"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMutool reports two pages. Digital samples have a text layer on every
+// page; scanned samples only carry a page number.
+const fakeMutool = `#!/bin/sh
+cmd=$1; shift
+case "$cmd" in
+info) echo "Pages: 2" ;;
+draw)
+	if [ "$1" = "-o" ]; then : > "$2"; exit 0; fi
+	pdf=$3; pages=${4:-1-2}
+	if grep -q scanned "$pdf"; then echo "  1"; exit 0; fi
+	echo "Pages $pages have an embedded digital text layer."
+	;;
+esac
+`
+
+// fakeTesseract writes the recognized text to the output base name + .txt
+const fakeTesseract = `#!/bin/sh
+echo "Text recognized from the scanned image" > "$2.txt"
+`
+
+func newFakeExtractor(t *testing.T) *TextExtractor {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+
+	dir := t.TempDir()
+	mutool := filepath.Join(dir, "mutool")
+	tesseract := filepath.Join(dir, "tesseract")
+	require.NoError(t, os.WriteFile(mutool, []byte(fakeMutool), 0755))
+	require.NoError(t, os.WriteFile(tesseract, []byte(fakeTesseract), 0755))
+	// OCR renders pages with the mutool found on PATH
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	external := &config.ExternalConfig{MutoolPath: mutool, TesseractPath: tesseract}
+	ocrConfig := &config.OCRConfig{Language: "eng", DPI: 300, PSM: 3}
+	return NewTextExtractor(external).WithOCR(ocr.NewOCRProcessor(ocrConfig, external))
+}
+
+func writeSample(t *testing.T, kind string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), kind+".pdf")
+	require.NoError(t, os.WriteFile(path, []byte("%PDF-1.4\n% "+kind+" sample\n%%EOF\n"), 0644))
+	return path
+}
+
+func TestDigitalPDFNeedsNoOCR(t *testing.T) {
+	extractor := newFakeExtractor(t)
+	sample := writeSample(t, "digital")
+
+	result, err := extractor.ExtractFromFile(sample)
+	require.NoError(t, err)
+	assert.False(t, result.RequiresOCR)
+	assert.Contains(t, result.Text, "digital text layer")
+
+	result, err = extractor.WithMode(ModeAuto).ExtractFromFile(sample)
+	require.NoError(t, err)
+	assert.False(t, result.RequiresOCR)
+	require.Len(t, result.Pages, 2)
+	for _, page := range result.Pages {
+		assert.Equal(t, PageSourceExtraction, page.Source)
+		assert.False(t, page.Scanned)
+	}
+	assert.Equal(t, "mutool", result.Metadata["extractor"])
+}
+
+func TestScannedPDFIsFlaggedInTextMode(t *testing.T) {
+	extractor := newFakeExtractor(t)
+	sample := writeSample(t, "scanned")
+
+	result, err := extractor.ExtractFromFile(sample)
+	require.NoError(t, err)
+	assert.True(t, result.RequiresOCR)
+	assert.NotContains(t, result.Text, "recognized")
+
+	pages, err := extractor.BatchExtractPDFPages(sample)
+	require.NoError(t, err)
+	require.Len(t, pages, 2)
+	assert.True(t, pages[0].RequiresOCR)
+	assert.Equal(t, PageSourceExtraction, pages[0].Metadata["text_source"])
+	assert.Equal(t, true, pages[0].Metadata["scanned"])
+}
+
+func TestScannedPDFFallsBackToOCRInAutoMode(t *testing.T) {
+	extractor := newFakeExtractor(t).WithMode(ModeAuto)
+	sample := writeSample(t, "scanned")
+
+	result, err := extractor.ExtractFromFile(sample)
+	require.NoError(t, err)
+	assert.False(t, result.RequiresOCR)
+	assert.Contains(t, result.Text, "Text recognized from the scanned image")
+	require.Len(t, result.Pages, 2)
+	for _, page := range result.Pages {
+		assert.Equal(t, PageSourceOCR, page.Source)
+		assert.True(t, page.Scanned)
+	}
+	assert.Equal(t, "mutool+tesseract", result.Metadata["extractor"])
+
+	pages, err := extractor.BatchExtractPDFPages(sample)
+	require.NoError(t, err)
+	require.Len(t, pages, 2)
+	assert.False(t, pages[1].RequiresOCR)
+	assert.Equal(t, PageSourceOCR, pages[1].Metadata["text_source"])
+	assert.Contains(t, pages[1].Text, "recognized")
+}
+
+func TestScannedPDFWithoutOCRStillRequiresOCR(t *testing.T) {
+	extractor := newFakeExtractor(t).WithMode(ModeAuto).WithOCR(nil)
+
+	result, err := extractor.ExtractFromFile(writeSample(t, "scanned"))
+	require.NoError(t, err)
+	assert.True(t, result.RequiresOCR)
+	assert.True(t, result.Pages[0].RequiresOCR)
+}
+
+func TestIsScanned(t *testing.T) {
+	assert.True(t, IsScanned("", 1))
+	assert.True(t, IsScanned(" 1 \n 2 ", 2))
+	assert.False(t, IsScanned("A page with a real text layer on it.", 1))
+	assert.True(t, IsScanned("A page with a real text layer on it.", 10))
+}
+
+func TestParseExtractionMode(t *testing.T) {
+	mode, err := ParseExtractionMode("")
+	require.NoError(t, err)
+	assert.Equal(t, ModeText, mode)
+
+	mode, err = ParseExtractionMode("AUTO")
+	require.NoError(t, err)
+	assert.Equal(t, ModeAuto, mode)
+
+	_, err = ParseExtractionMode("ocr")
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package textextractor
 
 import (
 	"documents-worker/config"
+	"documents-worker/utils"
 	"os"
 	"path/filepath"
 	"testing"
@@ -233,6 +234,24 @@ func TestPDFPageRangeExtraction(t *testing.T) {
 		result.PageCount, len(result.Text))
 }
 
+// Test Encrypted PDF Handling
+func TestEncryptedPDFRequiresPassword(t *testing.T) {
+	extractor := NewTextExtractor(getTestExtractorConfig())
+	pdfPath := filepath.Join("..", "utils", "testdata", "encrypted.pdf")
+
+	// Encryption is detected before any external tool runs
+	_, err := extractor.ExtractFromFile(pdfPath)
+	assert.ErrorIs(t, err, utils.ErrPDFPasswordRequired)
+
+	_, err = extractor.ExtractByPages(pdfPath, 1, 1)
+	assert.ErrorIs(t, err, utils.ErrPDFPasswordRequired)
+
+	// WithPassword must not change the shared extractor
+	withPassword := extractor.WithPassword("secret")
+	assert.Equal(t, "secret", withPassword.password)
+	assert.Empty(t, extractor.password)
+}
+
 // Test Error Handling
 func TestExtractionErrorHandling(t *testing.T) {
 	config := getTestExtractorConfig()
@@ -0,0 +1,93 @@
+package textextractor
+
+import (
+	"documents-worker/utils"
+	"fmt"
+	"log"
+	"path/filepath"
+)
+
+// extractFromTIFF treats a multi-page TIFF as a scanned document. TIFFs
+// have no text layer, so in ModeAuto every page is OCRed; otherwise every
+// page is flagged as requiring OCR.
+func (te *TextExtractor) extractFromTIFF(tiffPath string) (*ExtractionResult, error) {
+	pages, err := te.tiffPages(tiffPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ExtractionResult{
+		SourceType: "tiff",
+		PageCount:  len(pages),
+		Pages:      pages,
+		Metadata: map[string]interface{}{
+			"source_file": filepath.Base(tiffPath),
+			"extractor":   "tesseract",
+		},
+	}
+	result.Text, result.RequiresOCR, _ = joinPages(pages)
+	return result, nil
+}
+
+// batchExtractTIFFPages returns the text of each TIFF page separately, like
+// BatchExtractPDFPages does for PDFs
+func (te *TextExtractor) batchExtractTIFFPages(tiffPath string) ([]*ExtractionResult, error) {
+	pages, err := te.tiffPages(tiffPath)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*ExtractionResult, len(pages))
+	for i, page := range pages {
+		results[i] = &ExtractionResult{
+			Text:        page.Text,
+			SourceType:  "tiff_page",
+			PageCount:   1,
+			WordCount:   te.countWords(page.Text),
+			CharCount:   len(page.Text),
+			RequiresOCR: page.RequiresOCR,
+			Metadata: map[string]interface{}{
+				"page_number": page.Page,
+				"source_file": filepath.Base(tiffPath),
+				"text_source": page.Source,
+				"scanned":     page.Scanned,
+			},
+		}
+	}
+	return results, nil
+}
+
+// tiffPages returns one scanned page per TIFF page, OCRed in ModeAuto
+func (te *TextExtractor) tiffPages(tiffPath string) ([]PageResult, error) {
+	count, err := utils.TiffPageCount(tiffPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TIFF page count: %w", err)
+	}
+
+	pages := make([]PageResult, count)
+	for i := range pages {
+		pages[i] = PageResult{Page: i + 1, Source: PageSourceExtraction, Scanned: true, RequiresOCR: true}
+	}
+	if te.mode != ModeAuto || te.ocr == nil {
+		return pages, nil
+	}
+	if err := te.canceled(); err != nil {
+		return nil, err
+	}
+
+	recognized, err := te.ocr.BatchProcessTIFF(tiffPath)
+	if err != nil {
+		log.Printf("OCR failed for TIFF %s: %v", filepath.Base(tiffPath), err)
+		return pages, nil
+	}
+	for i, result := range recognized {
+		if i >= len(pages) {
+			break
+		}
+		pages[i].Text = result.Text
+		pages[i].Source = PageSourceOCR
+		pages[i].RequiresOCR = false
+		pages[i].Confidence = result.Confidence
+	}
+	return pages, nil
+}
@@ -0,0 +1,71 @@
+package textextractor
+
+import (
+	"documents-worker/config"
+	"documents-worker/ocr"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multiPageSampleTIFF holds three 8x8 grayscale pages
+const multiPageSampleTIFF = "../utils/testdata/multipage.tif"
+
+// fakeFaxTesseract recognizes every page of a TIFF, separated by form feeds
+const fakeFaxTesseract = `#!/bin/sh
+printf 'First fax page\fSecond fax page\fThird fax page\f' > "$2.txt"
+`
+
+func newFakeTIFFExtractor(t *testing.T) *TextExtractor {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+
+	tesseract := filepath.Join(t.TempDir(), "tesseract")
+	require.NoError(t, os.WriteFile(tesseract, []byte(fakeFaxTesseract), 0755))
+
+	external := &config.ExternalConfig{TesseractPath: tesseract}
+	ocrConfig := &config.OCRConfig{Language: "eng", DPI: 300, PSM: 3}
+	return NewTextExtractor(external).WithOCR(ocr.NewOCRProcessor(ocrConfig, external))
+}
+
+func TestMultiPageTIFFRequiresOCRInTextMode(t *testing.T) {
+	extractor := newFakeTIFFExtractor(t)
+
+	result, err := extractor.ExtractFromFile(multiPageSampleTIFF)
+	require.NoError(t, err)
+	assert.Equal(t, "tiff", result.SourceType)
+	assert.Equal(t, 3, result.PageCount)
+	assert.True(t, result.RequiresOCR)
+	assert.Empty(t, result.Text)
+	require.Len(t, result.Pages, 3)
+	for _, page := range result.Pages {
+		assert.True(t, page.Scanned)
+		assert.True(t, page.RequiresOCR)
+	}
+}
+
+func TestMultiPageTIFFIsOCREdPerPageInAutoMode(t *testing.T) {
+	extractor := newFakeTIFFExtractor(t).WithMode(ModeAuto)
+
+	result, err := extractor.ExtractFromFile(multiPageSampleTIFF)
+	require.NoError(t, err)
+	assert.False(t, result.RequiresOCR)
+	assert.Equal(t, "First fax page\n\nSecond fax page\n\nThird fax page", result.Text)
+
+	pages, err := extractor.BatchExtractPDFPages(multiPageSampleTIFF)
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+	for i, want := range []string{"First fax page", "Second fax page", "Third fax page"} {
+		assert.Equal(t, want, pages[i].Text)
+		assert.Equal(t, "tiff_page", pages[i].SourceType)
+		assert.Equal(t, i+1, pages[i].Metadata["page_number"])
+		assert.Equal(t, PageSourceOCR, pages[i].Metadata["text_source"])
+		assert.False(t, pages[i].RequiresOCR)
+	}
+}
@@ -15,7 +15,51 @@ type MediaSearch struct {
 	Quality     *int
 	ResizeScale *int
 	CutVideo    *string
+	FPS         *int // hareketli GIF/WebP önizlemelerin kare hızı
 	Page        *int
+	AllPages    bool  // çok sayfalı TIFF girdilerinde tüm sayfalar alt alta birleştirilir
+	Rotate      *int  // saat yönünde 90, 180 veya 270 derece
+	AutoOrient  bool  // EXIF yönü boyutlandırma ve kırpmadan önce düzeltilir
+	TargetSize  *int  // bytes; quality is searched to fit this budget
+	Progressive *bool // progressive JPEG / interlaced PNG output
+	Metadata    *MetadataPolicy
+	Password    *string `json:"-"` // şifreli PDF girdileri için; loglanmaz
+	// BackgroundColor "#rrggbb"; saydam alanlar bu renge düzleştirilir. Boşsa yalnızca
+	// alfa desteklemeyen formatlar (JPEG) beyaza düzleştirilir.
+	BackgroundColor *string
+	// VideoColor video çıktısının renk sinyalizasyonu; nil ise girdiden aynen geçer
+	VideoColor *VideoColor
+	// Sharpen boyutlandırmadan sonra uygulanan keskinleştirmenin (unsharp mask)
+	// sigma değeridir; nil ise keskinleştirme yapılmaz
+	Sharpen *float64
+	// CompressionEffort kodlayıcının hız/boyut dengesidir; yüksek değerler daha
+	// fazla CPU harcayıp daha küçük dosya üretir. Aralığı formata göre değişir.
+	CompressionEffort *int
+}
+
+// VideoColor hedef ekran için renk uzayı etiketlerini ve gamma düzeltmesini taşır.
+// Boş alanlar girdideki değeri korur.
+type VideoColor struct {
+	Primaries string  // -color_primaries, örn. bt709, bt2020
+	Transfer  string  // -color_trc, örn. bt709, smpte2084
+	Matrix    string  // -colorspace, örn. bt709, bt2020nc
+	Gamma     float64 // eq filtresi; 0 değişiklik yapmaz
+}
+
+type MetadataMode string
+
+const (
+	MetadataKeepAll   MetadataMode = "keep-all"
+	MetadataStripAll  MetadataMode = "strip-all"
+	MetadataAllowlist MetadataMode = "allowlist"
+	MetadataDenylist  MetadataMode = "denylist"
+)
+
+// MetadataPolicy selects which metadata survives a conversion. Tags are the
+// portable names documented in media.MetadataTagNames.
+type MetadataPolicy struct {
+	Mode MetadataMode
+	Tags []string
 }
 
 type MediaConverter struct {
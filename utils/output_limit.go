@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+)
+
+// OutputTooLargeError, dönüşüm çıktısı yapılandırılmış sınırı aştığında döner.
+type OutputTooLargeError struct {
+	Operation string
+	Size      int64
+	Limit     int64
+}
+
+func (e *OutputTooLargeError) Error() string {
+	return fmt.Sprintf("%s output is %d bytes, exceeding the maximum of %d bytes", e.Operation, e.Size, e.Limit)
+}
+
+// CheckOutputSize, dosya boyutunu sınırla karşılaştırır. Sınır sıfır veya negatifse kontrol yapılmaz.
+func CheckOutputSize(path string, operation string, limit int64) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat output file: %w", err)
+	}
+
+	if info.Size() > limit {
+		return &OutputTooLargeError{Operation: operation, Size: info.Size(), Limit: limit}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckOutputSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.bin")
+	require.NoError(t, os.WriteFile(path, make([]byte, 2048), 0644))
+
+	assert.NoError(t, CheckOutputSize(path, "image", 0), "zero limit disables the check")
+	assert.NoError(t, CheckOutputSize(path, "image", 2048))
+
+	err := CheckOutputSize(path, "image", 1024)
+	var tooLarge *OutputTooLargeError
+	require.True(t, errors.As(err, &tooLarge))
+	assert.Equal(t, int64(2048), tooLarge.Size)
+	assert.Equal(t, int64(1024), tooLarge.Limit)
+	assert.Contains(t, err.Error(), "2048 bytes")
+
+	assert.Error(t, CheckOutputSize(filepath.Join(t.TempDir(), "missing"), "image", 1))
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrPDFPasswordRequired, şifreli bir PDF parola verilmeden işlenmek istendiğinde döner.
+	ErrPDFPasswordRequired = errors.New("PDF is encrypted: password required")
+	// ErrPDFPasswordIncorrect, verilen parola PDF'i açmadığında döner.
+	ErrPDFPasswordIncorrect = errors.New("PDF is encrypted: incorrect password")
+)
+
+// encryptEntry, trailer veya xref stream sözlüğündeki /Encrypt girdisini yakalar.
+var encryptEntry = regexp.MustCompile(`/Encrypt\s*(?:\d+\s+\d+\s+R|<<)`)
+
+// IsPDFEncrypted, PDF'in şifreleme sözlüğü içerip içermediğini harici araç kullanmadan denetler.
+func IsPDFEncrypted(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return encryptEntry.Match(data), nil
+}
+
+// PreparePDF, şifreli PDF'i verilen parolayla mutool kullanarak çözer ve işlenecek yolu döner.
+// Şifresiz dosyalarda yol aynen döner. cleanup her durumda çağrılmalıdır.
+// Parola hiçbir log veya hata mesajına yazılmaz.
+func PreparePDF(mutoolPath, path, password string) (string, func(), error) {
+	noop := func() {}
+
+	encrypted, err := IsPDFEncrypted(path)
+	if err != nil {
+		return "", noop, err
+	}
+	if !encrypted {
+		return path, noop, nil
+	}
+	if password == "" {
+		return "", noop, ErrPDFPasswordRequired
+	}
+
+	decrypted, err := DecryptPDF(mutoolPath, path, password)
+	if err != nil {
+		return "", noop, err
+	}
+	return decrypted, func() { os.Remove(decrypted) }, nil
+}
+
+// DecryptPDF, PDF'in şifresiz bir kopyasını geçici dosyaya yazar.
+func DecryptPDF(mutoolPath, path, password string) (string, error) {
+	if mutoolPath == "" {
+		mutoolPath = "mutool"
+	}
+
+	outputFile, err := os.CreateTemp("", "decrypted-*.pdf")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp PDF file: %w", err)
+	}
+	outputFile.Close()
+
+	// -D şifrelemeyi kaldırır. Komut satırı parolayı içerdiği için loglanmaz.
+	cmd := exec.Command(mutoolPath, "clean", "-D", "-p", password, path, outputFile.Name())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		os.Remove(outputFile.Name())
+		message := strings.ReplaceAll(stderr.String(), password, "***")
+		if strings.Contains(message, "password") || strings.Contains(message, "authenticate") {
+			return "", ErrPDFPasswordIncorrect
+		}
+		return "", fmt.Errorf("failed to decrypt PDF: %w, output: %s", err, message)
+	}
+
+	return outputFile.Name(), nil
+}
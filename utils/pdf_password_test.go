@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encryptedSamplePDF is RC4 encrypted with user password "secret"
+const encryptedSamplePDF = "testdata/encrypted.pdf"
+
+func TestIsPDFEncrypted(t *testing.T) {
+	encrypted, err := IsPDFEncrypted(encryptedSamplePDF)
+	require.NoError(t, err)
+	assert.True(t, encrypted)
+
+	plainPath := filepath.Join(t.TempDir(), "plain.pdf")
+	require.NoError(t, os.WriteFile(plainPath, []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n"), 0644))
+
+	encrypted, err = IsPDFEncrypted(plainPath)
+	require.NoError(t, err)
+	assert.False(t, encrypted)
+}
+
+func TestPreparePDFRequiresPassword(t *testing.T) {
+	_, cleanup, err := PreparePDF("mutool", encryptedSamplePDF, "")
+	defer cleanup()
+	assert.True(t, errors.Is(err, ErrPDFPasswordRequired))
+}
+
+func TestPreparePDFPassesThroughPlainFiles(t *testing.T) {
+	plainPath := filepath.Join(t.TempDir(), "plain.pdf")
+	require.NoError(t, os.WriteFile(plainPath, []byte("%PDF-1.4\n%%EOF\n"), 0644))
+
+	path, cleanup, err := PreparePDF("mutool", plainPath, "ignored")
+	defer cleanup()
+	require.NoError(t, err)
+	assert.Equal(t, plainPath, path)
+}
+
+func TestDecryptPDF(t *testing.T) {
+	if _, err := exec.LookPath("mutool"); err != nil {
+		t.Skip("mutool not available")
+	}
+
+	_, _, err := PreparePDF("mutool", encryptedSamplePDF, "wrong")
+	assert.True(t, errors.Is(err, ErrPDFPasswordIncorrect))
+	assert.NotContains(t, err.Error(), "wrong")
+
+	path, cleanup, err := PreparePDF("mutool", encryptedSamplePDF, "secret")
+	require.NoError(t, err)
+	defer cleanup()
+
+	encrypted, err := IsPDFEncrypted(path)
+	require.NoError(t, err)
+	assert.False(t, encrypted)
+
+	output, err := exec.Command("mutool", "draw", "-F", "txt", path).Output()
+	require.NoError(t, err)
+	assert.Contains(t, string(output), "Secret text")
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNotTiff, dosya geçerli bir TIFF başlığı taşımadığında döner
+var ErrNotTiff = errors.New("dosya bir TIFF görüntüsü değil")
+
+// maxTiffPages, bozuk veya kötü niyetli dosyalarda IFD zincirinin sonsuza
+// kadar izlenmesini önler
+const maxTiffPages = 10000
+
+// IsTiffImage, verilen MIME türünün bir TIFF görüntüsü olup olmadığını kontrol eder.
+func IsTiffImage(mimeType string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	return mimeType == "image/tiff" || mimeType == "image/tiff-fx"
+}
+
+// TiffPageCount, TIFF dosyasındaki sayfa sayısını IFD zincirini izleyerek
+// bulur. Harici araç gerektirmez; klasik TIFF ve BigTIFF desteklenir.
+func TiffPageCount(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(file, header[:8]); err != nil {
+		return 0, ErrNotTiff
+	}
+
+	var order binary.ByteOrder
+	switch string(header[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, ErrNotTiff
+	}
+
+	// Klasik TIFF 4 baytlık, BigTIFF 8 baytlık ofsetler kullanır
+	var offset uint64
+	countSize, entrySize, offsetSize := int64(2), int64(12), int64(4)
+	switch order.Uint16(header[2:4]) {
+	case 42:
+		offset = uint64(order.Uint32(header[4:8]))
+	case 43:
+		if _, err := io.ReadFull(file, header[8:16]); err != nil {
+			return 0, ErrNotTiff
+		}
+		countSize, entrySize, offsetSize = 8, 20, 8
+		offset = order.Uint64(header[8:16])
+	default:
+		return 0, ErrNotTiff
+	}
+
+	seen := make(map[uint64]bool)
+	pages := 0
+	buf := make([]byte, 8)
+	for offset != 0 {
+		if seen[offset] || pages >= maxTiffPages {
+			return 0, fmt.Errorf("TIFF sayfa zinciri bozuk (%d. sayfadan sonra)", pages)
+		}
+		seen[offset] = true
+
+		if _, err := file.ReadAt(buf[:countSize], int64(offset)); err != nil {
+			return 0, fmt.Errorf("TIFF sayfa dizini okunamadı: %w", err)
+		}
+		var entries uint64
+		if countSize == 2 {
+			entries = uint64(order.Uint16(buf))
+		} else {
+			entries = order.Uint64(buf)
+		}
+
+		next := int64(offset) + countSize + int64(entries)*entrySize
+		if _, err := file.ReadAt(buf[:offsetSize], next); err != nil {
+			return 0, fmt.Errorf("TIFF sayfa dizini okunamadı: %w", err)
+		}
+		if offsetSize == 4 {
+			offset = uint64(order.Uint32(buf))
+		} else {
+			offset = order.Uint64(buf)
+		}
+		pages++
+	}
+
+	if pages == 0 {
+		return 0, fmt.Errorf("TIFF dosyasında sayfa yok")
+	}
+	return pages, nil
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// multiPageSampleTIFF holds three 8x8 grayscale pages
+const multiPageSampleTIFF = "testdata/multipage.tif"
+
+func TestTiffPageCount(t *testing.T) {
+	pages, err := TiffPageCount(multiPageSampleTIFF)
+	require.NoError(t, err)
+	assert.Equal(t, 3, pages)
+
+	mimeType, err := DetectMimeTypeFromFile(multiPageSampleTIFF)
+	require.NoError(t, err)
+	assert.True(t, IsTiffImage(mimeType))
+	assert.False(t, IsTiffImage("image/png"))
+}
+
+func TestTiffPageCountBigTIFF(t *testing.T) {
+	// Big-endian BigTIFF with two empty directories
+	data := make([]byte, 16)
+	copy(data, "MM")
+	binary.BigEndian.PutUint16(data[2:], 43)
+	binary.BigEndian.PutUint16(data[4:], 8)
+	binary.BigEndian.PutUint64(data[8:], 16)
+	for _, next := range []uint64{32, 0} {
+		dir := make([]byte, 16)
+		binary.BigEndian.PutUint64(dir[8:], next)
+		data = append(data, dir...)
+	}
+	path := filepath.Join(t.TempDir(), "big.tif")
+	require.NoError(t, os.WriteFile(path, data, 0644))
+
+	pages, err := TiffPageCount(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, pages)
+}
+
+func TestTiffPageCountRejectsInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	notTiff := filepath.Join(dir, "plain.pdf")
+	require.NoError(t, os.WriteFile(notTiff, []byte("%PDF-1.4\n%%EOF\n"), 0644))
+	_, err := TiffPageCount(notTiff)
+	assert.True(t, errors.Is(err, ErrNotTiff))
+
+	// The only directory points back at itself
+	loop := []byte{'I', 'I', 42, 0, 8, 0, 0, 0, 0, 0, 8, 0, 0, 0}
+	loopPath := filepath.Join(dir, "loop.tif")
+	require.NoError(t, os.WriteFile(loopPath, loop, 0644))
+	_, err = TiffPageCount(loopPath)
+	assert.Error(t, err)
+}
@@ -0,0 +1,275 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Bounds of the buffer content is sniffed from. Office documents are ZIP
+// files whose entry names identify them, so a few KB are read by default.
+const (
+	DefaultSniffSize = 4096
+	MinSniffSize     = 512
+	MaxSniffSize     = 64 * 1024
+)
+
+// SniffSize clamps a configured sniffing buffer size to its bounds; zero or
+// less means DefaultSniffSize
+func SniffSize(size int) int {
+	switch {
+	case size <= 0:
+		return DefaultSniffSize
+	case size < MinSniffSize:
+		return MinSniffSize
+	case size > MaxSniffSize:
+		return MaxSniffSize
+	default:
+		return size
+	}
+}
+
+// ReadHead reads up to size bytes from the start of r, clamped by SniffSize
+func ReadHead(r io.Reader, size int) ([]byte, error) {
+	head := make([]byte, SniffSize(size))
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// Containers reported when the document inside is not identified
+const (
+	typeZip = "application/zip"
+	// typeOLE is the container of legacy Office documents
+	typeOLE = "application/x-ole-storage"
+)
+
+var extensionTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".bmp":  "image/bmp",
+	".avif": "image/avif",
+	".heic": "image/heic",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	".odp":  "application/vnd.oasis.opendocument.presentation",
+	".doc":  "application/msword",
+	".xls":  "application/vnd.ms-excel",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".zip":  typeZip,
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".mkv":  "video/x-matroska",
+	".avi":  "video/x-msvideo",
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+	".csv":  "text/csv",
+	".html": "text/html",
+	".htm":  "text/html",
+	".json": "application/json",
+	".xml":  "application/xml",
+	".svg":  "image/svg+xml",
+}
+
+var typeAliases = map[string]string{
+	"image/jpg":                "image/jpeg",
+	"image/pjpeg":              "image/jpeg",
+	"image/x-png":              "image/png",
+	"image/heif":               "image/heic",
+	"application/x-pdf":        "application/pdf",
+	"application/x-zip":        typeZip,
+	"video/x-m4v":              "video/mp4",
+	"application/x-msvideo":    "video/x-msvideo",
+	"text/xml":                 "application/xml",
+	"application/octet-stream": "",
+}
+
+// odfTypePattern matches the content of the mimetype entry, which
+// writers may not give the size of
+var odfTypePattern = regexp.MustCompile(`^application/vnd\.oasis\.opendocument\.[a-z.-]+`)
+
+// ftyp brands of ISO media files that are not MP4 video
+var ftypBrands = map[string]string{
+	"avif": "image/avif",
+	"avis": "image/avif",
+	"heic": "image/heic",
+	"heix": "image/heic",
+	"mif1": "image/heic",
+	"msf1": "image/heic",
+	"qt  ": "video/quicktime",
+}
+
+// SniffContentType returns the type of head, the first bytes of a file,
+// from its magic number. ZIP files are told apart by their entries, so a
+// document whose entries lie past head is reported as application/zip.
+// Empty means no magic number was recognized, as with text.
+func SniffContentType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return "application/pdf"
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(head, []byte("\xff\xd8\xff")):
+		return "image/jpeg"
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return "image/gif"
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return "image/tiff"
+	case isBMP(head):
+		return "image/bmp"
+	case len(head) >= 12 && bytes.HasPrefix(head, []byte("RIFF")):
+		switch string(head[8:12]) {
+		case "WEBP":
+			return "image/webp"
+		case "AVI ":
+			return "video/x-msvideo"
+		}
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		if kind, ok := ftypBrands[string(head[8:12])]; ok {
+			return kind
+		}
+		return "video/mp4"
+	case bytes.HasPrefix(head, []byte("\x1a\x45\xdf\xa3")):
+		if bytes.Contains(head, []byte("webm")) {
+			return "video/webm"
+		}
+		return "video/x-matroska"
+	case bytes.HasPrefix(head, []byte("\xd0\xcf\x11\xe0\xa1\xb1\x1a\xe1")):
+		return typeOLE
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return zipContentType(head)
+	}
+	return ""
+}
+
+// isBMP checks the signature and the DIB header size, as "BM" alone also
+// starts text
+func isBMP(head []byte) bool {
+	if len(head) < 18 || !bytes.HasPrefix(head, []byte("BM")) {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(head[14:18]) {
+	case 12, 40, 52, 56, 64, 108, 124:
+		return true
+	}
+	return false
+}
+
+// zipContentType identifies OpenDocument files by their first entry, an
+// uncompressed file named mimetype holding the type, and Office Open XML
+// files by their part names
+func zipContentType(head []byte) string {
+	if len(head) > 38 && binary.LittleEndian.Uint16(head[26:28]) == 8 && string(head[30:38]) == "mimetype" {
+		start := 38 + int(binary.LittleEndian.Uint16(head[28:30]))
+		if start < len(head) {
+			if kind := odfTypePattern.Find(head[start:]); kind != nil {
+				return string(kind)
+			}
+		}
+	}
+	for _, part := range []struct{ prefix, ext string }{{"word/", ".docx"}, {"xl/", ".xlsx"}, {"ppt/", ".pptx"}} {
+		if bytes.Contains(head, []byte(part.prefix)) {
+			return extensionTypes[part.ext]
+		}
+	}
+	return typeZip
+}
+
+// knownType tells whether a type is one Content can check
+func knownType(kind string) bool {
+	for _, known := range extensionTypes {
+		if kind == known {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeType strips parameters and aliases from a declared type; empty
+// means no type was declared
+func normalizeType(declared string) string {
+	mediaType, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(declared))
+	}
+	if alias, ok := typeAliases[mediaType]; ok {
+		return alias
+	}
+	return mediaType
+}
+
+// isTextType tells whether a type is text, which has no magic number
+func isTextType(kind string) bool {
+	return strings.HasPrefix(kind, "text/") || kind == "application/json" ||
+		kind == "application/xml" || kind == "image/svg+xml"
+}
+
+// zipBased tells whether documents of a type are ZIP files
+func zipBased(kind string) bool {
+	return strings.HasPrefix(kind, "application/vnd.openxmlformats-officedocument.") ||
+		strings.HasPrefix(kind, "application/vnd.oasis.opendocument.") ||
+		kind == "application/epub+zip"
+}
+
+// contentMatches tells whether sniffed content can be of the expected type
+func contentMatches(expected, sniffed string, head []byte) bool {
+	switch {
+	case isTextType(expected):
+		return sniffed == "" && bytes.IndexByte(head, 0) < 0
+	case expected == sniffed:
+		return true
+	case sniffed == typeZip:
+		// The entries naming the document may lie past the sniffed bytes
+		return zipBased(expected)
+	case expected == typeZip:
+		return zipBased(sniffed)
+	case sniffed == typeOLE:
+		return expected == "application/msword" || strings.HasPrefix(expected, "application/vnd.ms-")
+	case sniffed == "video/webm", sniffed == "video/x-matroska":
+		return expected == "video/webm" || expected == "video/x-matroska"
+	}
+	return false
+}
+
+// Content checks that head, the first bytes of a file, is not empty and
+// that its magic number matches the type named by filename's extension and
+// the declared content type. Unknown extensions and types, and generic
+// types such as application/octet-stream, are not checked.
+func (v *Validator) Content(field string, head []byte, filename, declared string) *Validator {
+	if len(head) == 0 {
+		return v.Check(false, field, "empty", nil, "must not be empty")
+	}
+
+	sniffed := SniffContentType(head)
+	described := sniffed
+	if described == "" {
+		described = "unrecognized content"
+	}
+	if ext := strings.ToLower(filepath.Ext(filename)); extensionTypes[ext] != "" {
+		v.Check(contentMatches(extensionTypes[ext], sniffed, head), field, "content_type", described,
+			fmt.Sprintf("content is %s, which does not match the %s extension", described, ext))
+	}
+	if expected := normalizeType(declared); knownType(expected) {
+		v.Check(contentMatches(expected, sniffed, head), field, "content_type", described,
+			fmt.Sprintf("content is %s, which does not match the declared type %s", described, expected))
+	}
+	return v
+}
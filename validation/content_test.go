@@ -0,0 +1,108 @@
+package validation
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeImage(t *testing.T, encode func(*bytes.Buffer, image.Image) error) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+	return buf.Bytes()
+}
+
+func testPNG(t *testing.T) []byte {
+	return encodeImage(t, func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) })
+}
+
+func testZip(t *testing.T, entries ...string) []byte {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for i := 0; i < len(entries); i += 2 {
+		header := &zip.FileHeader{Name: entries[i], Method: zip.Store}
+		part, err := writer.CreateHeader(header)
+		require.NoError(t, err)
+		_, err = part.Write([]byte(entries[i+1]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+func TestContentAcceptsMatchingFiles(t *testing.T) {
+	jpg := encodeImage(t, func(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) })
+	docx := testZip(t, "[Content_Types].xml", "<Types/>", "word/document.xml", "<w:document/>")
+	odt := testZip(t, "mimetype", "application/vnd.oasis.opendocument.text", "content.xml", "<office/>")
+	mp4 := append([]byte("\x00\x00\x00\x18ftypisom\x00\x00\x02\x00"), make([]byte, 16)...)
+
+	tests := []struct {
+		filename, declared string
+		content            []byte
+	}{
+		{"scan.pdf", "application/pdf", []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")},
+		{"photo.png", "image/png", testPNG(t)},
+		{"photo.JPG", "image/jpg", jpg},
+		{"report.docx", "", docx},
+		{"report.odt", "application/vnd.oasis.opendocument.text", odt},
+		{"archive.zip", "application/zip", docx},
+		{"clip.mp4", "video/mp4", mp4},
+		{"notes.txt", "text/plain; charset=utf-8", []byte("BMW notes, not a bitmap")},
+		{"upload.bin", "application/octet-stream", []byte("anything")},
+		{"scan.pdf", "application/octet-stream", []byte("%PDF-1.4")},
+	}
+	for _, tt := range tests {
+		assert.NoError(t, New().Content("file", tt.content, tt.filename, tt.declared).Err(), tt.filename)
+	}
+
+	assert.Equal(t, "application/vnd.oasis.opendocument.text", SniffContentType(odt))
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", SniffContentType(docx))
+}
+
+func TestContentRejectsSpoofedFiles(t *testing.T) {
+	err := New().Content("file", testPNG(t), "invoice.pdf", "application/pdf").Err()
+
+	var validationErr *Error
+	require.True(t, errors.As(err, &validationErr))
+	require.Len(t, validationErr.Violations, 2, "both the extension and the declared type are checked")
+	assert.Equal(t, "content_type", validationErr.Violations[0].Rule)
+	assert.Equal(t, "image/png", validationErr.Violations[0].Value)
+	assert.Contains(t, validationErr.Violations[0].Message, ".pdf extension")
+	assert.Contains(t, validationErr.Violations[1].Message, "declared type application/pdf")
+
+	assert.Error(t, New().Content("file", []byte("plain text"), "photo.png", "").Err(), "unrecognized content")
+	assert.Error(t, New().Content("file", testPNG(t), "photo.png", "video/mp4").Err(), "declared type")
+	assert.Error(t, New().Content("file", []byte("%PDF-1.4"), "notes.txt", "").Err(), "binary as text")
+}
+
+func TestContentRejectsEmptyFiles(t *testing.T) {
+	err := New().Content("file", nil, "scan.pdf", "application/pdf").Err()
+
+	var validationErr *Error
+	require.True(t, errors.As(err, &validationErr))
+	require.Len(t, validationErr.Violations, 1)
+	assert.Equal(t, "empty", validationErr.Violations[0].Rule)
+}
+
+func TestReadHeadIsBounded(t *testing.T) {
+	assert.Equal(t, DefaultSniffSize, SniffSize(0))
+	assert.Equal(t, MinSniffSize, SniffSize(16))
+	assert.Equal(t, MaxSniffSize, SniffSize(1<<30))
+	assert.Equal(t, 8192, SniffSize(8192))
+
+	head, err := ReadHead(strings.NewReader(strings.Repeat("a", 2*MaxSniffSize)), 1<<30)
+	require.NoError(t, err)
+	assert.Len(t, head, MaxSniffSize)
+
+	head, err = ReadHead(strings.NewReader("short"), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "short", string(head))
+}
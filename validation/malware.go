@@ -0,0 +1,153 @@
+package validation
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ScanResult is the verdict of a malware scan
+type ScanResult struct {
+	Infected bool
+	// Signature names the malware found
+	Signature string
+}
+
+// MalwareScanner scans content for malware. An error means the content
+// could not be scanned, not that it is infected.
+type MalwareScanner interface {
+	Scan(ctx context.Context, r io.Reader) (*ScanResult, error)
+}
+
+// clamAVChunkSize is the size of the INSTREAM chunks sent to clamd,
+// well below its default StreamMaxLength
+const clamAVChunkSize = 64 * 1024
+
+// ClamAVScanner scans content with a clamd daemon, streaming it over the
+// INSTREAM command
+type ClamAVScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner creates a scanner for the clamd at address, either
+// host:port, tcp://host:port or unix:///path/to/clamd.sock. A scan fails
+// when it takes longer than timeout; zero means no limit beyond the context.
+func NewClamAVScanner(address string, timeout time.Duration) *ClamAVScanner {
+	network := "tcp"
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network, address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "/"):
+		network = "unix"
+	default:
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+	return &ClamAVScanner{network: network, address: address, timeout: timeout}
+}
+
+// Scan implements MalwareScanner
+func (s *ClamAVScanner) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	// Unblock reads and writes once ctx is done, so its error is set by
+	// the time they fail
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if err := s.stream(conn, r); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("clamd scan aborted: %w", ctx.Err())
+		}
+		return nil, err
+	}
+
+	// Replies end with a NUL, or with the connection
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && (!errors.Is(err, io.EOF) || reply == "") {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("clamd scan aborted: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamAVReply(reply)
+}
+
+// stream sends r as INSTREAM chunks, each prefixed by its length, and the
+// empty chunk ending the stream
+func (s *ClamAVScanner) stream(conn net.Conn, r io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("failed to send to clamd: %w", err)
+	}
+
+	buf := make([]byte, 4+clamAVChunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				// clamd closes the connection once the stream exceeds
+				// its limit, replying with the reason
+				if reply, _ := bufio.NewReader(conn).ReadString(0); reply != "" {
+					if _, replyErr := parseClamAVReply(reply); replyErr != nil {
+						return replyErr
+					}
+				}
+				return fmt.Errorf("failed to send to clamd: %w", err)
+			}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read content to scan: %w", readErr)
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to send to clamd: %w", err)
+	}
+	return nil
+}
+
+// parseClamAVReply parses "stream: OK", "stream: <signature> FOUND" and
+// "<reason> ERROR"
+func parseClamAVReply(reply string) (*ScanResult, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	verdict := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case verdict == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case strings.HasSuffix(verdict, " ERROR"):
+		return nil, fmt.Errorf("clamd error: %s", strings.TrimSuffix(verdict, " ERROR"))
+	default:
+		return nil, fmt.Errorf("unexpected clamd reply: %q", reply)
+	}
+}
+
+// Malware checks that a scan found no malware, naming the signature found
+func (v *Validator) Malware(field string, result *ScanResult) *Validator {
+	if result == nil || !result.Infected {
+		return v
+	}
+	return v.Check(false, field, "malware", result.Signature, "contains malware: "+result.Signature)
+}
@@ -0,0 +1,139 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eicar is the standard antivirus test file, detected by every scanner
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd serves INSTREAM scans, answering each with reply(content)
+func fakeClamd(t *testing.T, reply func(content []byte) string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				command := make([]byte, len("zINSTREAM\x00"))
+				if _, err := io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var content bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&content, conn, int64(size)); err != nil {
+						return
+					}
+				}
+				conn.Write([]byte(reply(content.Bytes()) + "\x00"))
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func detectEICAR(content []byte) string {
+	if bytes.Contains(content, []byte(eicar)) {
+		return "stream: Eicar-Test-Signature FOUND"
+	}
+	return "stream: OK"
+}
+
+func TestClamAVScannerDetectsEICAR(t *testing.T) {
+	scanner := NewClamAVScanner("tcp://"+fakeClamd(t, detectEICAR), time.Second)
+
+	result, err := scanner.Scan(context.Background(), strings.NewReader(eicar))
+	require.NoError(t, err)
+	assert.True(t, result.Infected)
+	assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+
+	err = New().Malware("file", result).Err()
+	var validationErr *Error
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, "malware", validationErr.Violations[0].Rule)
+	assert.Equal(t, "Eicar-Test-Signature", validationErr.Violations[0].Value)
+	assert.Contains(t, err.Error(), "contains malware: Eicar-Test-Signature")
+}
+
+func TestClamAVScannerStreamsLargeContent(t *testing.T) {
+	var received []byte
+	scanner := NewClamAVScanner(fakeClamd(t, func(content []byte) string {
+		received = content
+		return "stream: OK"
+	}), time.Second)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 3*clamAVChunkSize/16+7)
+	result, err := scanner.Scan(context.Background(), bytes.NewReader(content))
+	require.NoError(t, err)
+	assert.False(t, result.Infected)
+	assert.Equal(t, content, received)
+	assert.NoError(t, New().Malware("file", result).Err())
+}
+
+func TestClamAVScannerErrors(t *testing.T) {
+	limited := NewClamAVScanner(fakeClamd(t, func([]byte) string { return "INSTREAM size limit exceeded. ERROR" }), time.Second)
+	_, err := limited.Scan(context.Background(), strings.NewReader("content"))
+	assert.ErrorContains(t, err, "size limit exceeded")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		// Read until the scanner gives up, never answering
+		conn, err := listener.Accept()
+		if err == nil {
+			io.Copy(io.Discard, conn)
+			conn.Close()
+		}
+	}()
+	_, err = NewClamAVScanner(listener.Addr().String(), 100*time.Millisecond).Scan(context.Background(), strings.NewReader("content"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	unreachable := NewClamAVScanner("unix://"+t.TempDir()+"/clamd.sock", time.Second)
+	assert.Equal(t, "unix", unreachable.network)
+	_, err = unreachable.Scan(context.Background(), strings.NewReader("content"))
+	assert.ErrorContains(t, err, "failed to connect to clamd")
+}
+
+func TestClamAVScannerWithClamd(t *testing.T) {
+	address := os.Getenv("CLAMD_ADDRESS")
+	if address == "" {
+		t.Skip("CLAMD_ADDRESS not set")
+	}
+	scanner := NewClamAVScanner(address, 10*time.Second)
+
+	result, err := scanner.Scan(context.Background(), strings.NewReader(eicar))
+	require.NoError(t, err)
+	assert.True(t, result.Infected)
+	assert.Contains(t, result.Signature, "Eicar")
+
+	result, err = scanner.Scan(context.Background(), strings.NewReader("plain text"))
+	require.NoError(t, err)
+	assert.False(t, result.Infected)
+}
@@ -0,0 +1,98 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Violation describes a single failed validation rule
+type Violation struct {
+	Field   string      `json:"field"`
+	Rule    string      `json:"rule"`
+	Value   interface{} `json:"value,omitempty"`
+	Message string      `json:"message"`
+}
+
+// Error is returned when one or more validation rules fail
+type Error struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = fmt.Sprintf("%s: %s", v.Field, v.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Validator collects violations so that every failed rule is reported at once
+type Validator struct {
+	violations []Violation
+}
+
+// New creates an empty validator
+func New() *Validator {
+	return &Validator{}
+}
+
+// Check records a violation when ok is false
+func (v *Validator) Check(ok bool, field, rule string, value interface{}, message string) *Validator {
+	if !ok {
+		v.violations = append(v.violations, Violation{
+			Field:   field,
+			Rule:    rule,
+			Value:   value,
+			Message: message,
+		})
+	}
+	return v
+}
+
+// Required checks that a string value is not empty
+func (v *Validator) Required(field, value string) *Validator {
+	return v.Check(strings.TrimSpace(value) != "", field, "required", nil, "is required")
+}
+
+// OneOf checks that a value is one of the allowed values. Empty values are
+// left to Required.
+func (v *Validator) OneOf(field, value string, allowed ...string) *Validator {
+	if value == "" {
+		return v
+	}
+	for _, a := range allowed {
+		if value == a {
+			return v
+		}
+	}
+	return v.Check(false, field, "one_of", value, "must be one of: "+strings.Join(allowed, ", "))
+}
+
+// Min checks that a value is at least min
+func (v *Validator) Min(field string, value, min int) *Validator {
+	return v.Check(value >= min, field, "min", value, fmt.Sprintf("must be at least %d", min))
+}
+
+// Range checks that a value is within [min, max]
+func (v *Validator) Range(field string, value, min, max int) *Validator {
+	return v.Check(value >= min && value <= max, field, "range", value, fmt.Sprintf("must be between %d and %d", min, max))
+}
+
+// MaxSize checks that a size in bytes does not exceed max. A non-positive
+// max disables the check.
+func (v *Validator) MaxSize(field string, size, max int64) *Validator {
+	return v.Check(max <= 0 || size <= max, field, "max_size", size, fmt.Sprintf("must not exceed %d bytes", max))
+}
+
+// Violations returns the recorded violations
+func (v *Validator) Violations() []Violation {
+	return v.violations
+}
+
+// Err returns an *Error listing every violation, or nil if there are none
+func (v *Validator) Err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &Error{Violations: v.violations}
+}
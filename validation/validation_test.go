@@ -0,0 +1,51 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatorCollectsAllViolations(t *testing.T) {
+	err := New().
+		Required("document_id", "").
+		OneOf("output_format", "bmp", "jpg", "png", "webp").
+		Range("quality", 150, 1, 100).
+		Min("overlap", -1, 0).
+		MaxSize("file", 2048, 1024).
+		Err()
+
+	var validationErr *Error
+	require.True(t, errors.As(err, &validationErr))
+	require.Len(t, validationErr.Violations, 5)
+
+	assert.Equal(t, Violation{Field: "document_id", Rule: "required", Message: "is required"}, validationErr.Violations[0])
+
+	assert.Equal(t, "output_format", validationErr.Violations[1].Field)
+	assert.Equal(t, "one_of", validationErr.Violations[1].Rule)
+	assert.Equal(t, "bmp", validationErr.Violations[1].Value)
+
+	assert.Equal(t, "range", validationErr.Violations[2].Rule)
+	assert.Equal(t, 150, validationErr.Violations[2].Value)
+
+	assert.Equal(t, "min", validationErr.Violations[3].Rule)
+	assert.Equal(t, "max_size", validationErr.Violations[4].Rule)
+	assert.Equal(t, int64(2048), validationErr.Violations[4].Value)
+
+	assert.Contains(t, err.Error(), "document_id: is required")
+	assert.Contains(t, err.Error(), "quality: must be between 1 and 100")
+}
+
+func TestValidatorPasses(t *testing.T) {
+	err := New().
+		Required("document_id", "doc-1").
+		OneOf("output_format", "png", "jpg", "png").
+		OneOf("optional", "", "a", "b").
+		Range("quality", 80, 1, 100).
+		MaxSize("file", 2048, 0).
+		Err()
+
+	assert.NoError(t, err)
+}
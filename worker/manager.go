@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"documents-worker/config"
+	"documents-worker/internal/core/ports"
 	"documents-worker/queue"
 	"log"
 	"sync"
@@ -13,6 +14,7 @@ import (
 type WorkerManager struct {
 	queue         *queue.RedisQueue
 	config        *config.Config
+	storage       ports.FileStorage
 	workers       map[string]*Worker
 	workersMutex  sync.RWMutex
 	ctx           context.Context
@@ -80,6 +82,13 @@ func NewWorkerManager(queue *queue.RedisQueue, config *config.Config) *WorkerMan
 	}
 }
 
+// WithStorage keeps the outputs of jobs completed by every worker in
+// storage. It must be called before Start.
+func (wm *WorkerManager) WithStorage(storage ports.FileStorage) *WorkerManager {
+	wm.storage = storage
+	return wm
+}
+
 // Start initializes the worker manager and starts the minimum number of workers
 func (wm *WorkerManager) Start() {
 	log.Printf("Worker Manager starting with %d min workers, %d max workers", wm.minWorkers, wm.maxWorkers)
@@ -145,7 +154,7 @@ func (wm *WorkerManager) addWorker() {
 		return
 	}
 
-	worker := NewWorker(wm.queue, wm.config)
+	worker := NewWorker(wm.queue, wm.config).WithStorage(wm.storage)
 	wm.workers[worker.id] = worker
 	worker.Start()
 
@@ -246,5 +255,6 @@ func (wm *WorkerManager) GetStats() map[string]interface{} {
 		"scale_down_threshold": wm.scaleDownThreshold,
 		"check_interval":       wm.checkInterval.String(),
 		"scale_delay":          wm.scaleDelay.String(),
+		"job_panics":           PanicCount(),
 	}
 }
@@ -3,15 +3,23 @@ package worker
 import (
 	"context"
 	"documents-worker/config"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
 	"documents-worker/media"
+	"documents-worker/ocr"
 	"documents-worker/queue"
 	"documents-worker/textextractor"
 	"documents-worker/types"
+	"documents-worker/utils"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"path"
+	"path/filepath"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,6 +30,7 @@ type Worker struct {
 	queue         *queue.RedisQueue
 	config        *config.Config
 	textExtractor *textextractor.TextExtractor
+	storage       ports.FileStorage
 	ctx           context.Context
 	cancel        context.CancelFunc
 	wg            sync.WaitGroup
@@ -29,6 +38,36 @@ type Worker struct {
 	runningMutex  sync.RWMutex
 }
 
+// jobPanics counts recovered processor panics across all workers
+var jobPanics int64
+
+// PanicCount returns the number of job panics recovered since startup
+func PanicCount() int64 {
+	return atomic.LoadInt64(&jobPanics)
+}
+
+// PanicError wraps a value recovered from a panicking job
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic during job processing: %v", e.Value)
+}
+
+// runSafely executes fn and converts a panic into a PanicError
+func runSafely(fn func()) (panicErr *PanicError) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	fn()
+	return nil
+}
+
 type ProcessingJob struct {
 	ID           string                 `json:"id"`
 	InputPath    string                 `json:"input_path"`
@@ -37,11 +76,13 @@ type ProcessingJob struct {
 	Format       *string                `json:"format,omitempty"`
 	VipsEnabled  bool                   `json:"vips_enabled"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	Password     string                 `json:"password,omitempty"` // encrypted PDF input
 }
 
 func NewWorker(queue *queue.RedisQueue, config *config.Config) *Worker {
 	ctx, cancel := context.WithCancel(context.Background())
-	textExtractor := textextractor.NewTextExtractor(&config.External)
+	textExtractor := textextractor.NewTextExtractor(&config.External).
+		WithOCR(ocr.NewOCRProcessor(&config.OCR, &config.External))
 
 	return &Worker{
 		id:            uuid.New().String(),
@@ -53,6 +94,13 @@ func NewWorker(queue *queue.RedisQueue, config *config.Config) *Worker {
 	}
 }
 
+// WithStorage keeps the outputs of completed jobs in storage, where they
+// outlive the worker's temp files
+func (w *Worker) WithStorage(storage ports.FileStorage) *Worker {
+	w.storage = storage
+	return w
+}
+
 func (w *Worker) Start() {
 	w.runningMutex.Lock()
 	defer w.runningMutex.Unlock()
@@ -130,6 +178,29 @@ func (w *Worker) processJob(job *queue.Job) {
 
 	startTime := time.Now()
 
+	// A panicking processor must not take the worker goroutine down with it;
+	// FailJob requeues the job if it still has retries left
+	handled := false
+	if panicErr := runSafely(func() { handled = w.dispatchJob(job) }); panicErr != nil {
+		atomic.AddInt64(&jobPanics, 1)
+		log.Printf("Worker %s: Job %s panicked: %v\n%s", w.id, job.ID, panicErr.Value, panicErr.Stack)
+
+		if failErr := w.queue.FailJob(context.Background(), job.ID, panicErr.Error()); failErr != nil {
+			log.Printf("Worker %s: Failed to mark panicked job %s as failed: %v", w.id, job.ID, failErr)
+		}
+		return
+	}
+	if !handled {
+		return
+	}
+
+	duration := time.Since(startTime)
+	log.Printf("Worker %s: Job %s completed in %v", w.id, job.ID, duration)
+}
+
+// dispatchJob routes a job to its type-specific handler. Jobs of an unknown
+// type are failed as unsupported and reported as not handled.
+func (w *Worker) dispatchJob(job *queue.Job) bool {
 	switch job.Type {
 	case "media_processing":
 		w.processMediaJob(job)
@@ -143,11 +214,9 @@ func (w *Worker) processJob(job *queue.Job) {
 		err := fmt.Sprintf("Unknown job type: %s", job.Type)
 		w.queue.FailJob(context.Background(), job.ID, err)
 		log.Printf("Worker %s: %s", w.id, err)
-		return
+		return false
 	}
-
-	duration := time.Since(startTime)
-	log.Printf("Worker %s: Job %s completed in %v", w.id, job.ID, duration)
+	return true
 }
 
 func (w *Worker) processMediaJob(job *queue.Job) {
@@ -171,6 +240,9 @@ func (w *Worker) processMediaJob(job *queue.Job) {
 		Format:      processingJob.Format,
 		VipsEnabled: processingJob.VipsEnabled,
 	}
+	if processingJob.Password != "" {
+		mediaConverter.Search.Password = &processingJob.Password
+	}
 
 	// Create processor
 	processor, err := media.NewProcessor(mediaConverter)
@@ -188,6 +260,22 @@ func (w *Worker) processMediaJob(job *queue.Job) {
 	defer outputFile.Close()
 	defer os.Remove(outputFile.Name())
 
+	// Reject oversized outputs before they are returned
+	operation := "image"
+	if processingJob.MediaKind == types.VideoKind {
+		operation = "video"
+	}
+	if err := utils.CheckOutputSize(outputFile.Name(), operation, w.config.Limits.OutputLimit(operation)); err != nil {
+		w.queue.FailJob(context.Background(), job.ID, err.Error())
+		return
+	}
+
+	storageKey, err := w.storeOutput(job.ID, outputFile.Name())
+	if err != nil {
+		w.queue.FailJob(context.Background(), job.ID, err.Error())
+		return
+	}
+
 	// Prepare result
 	result := map[string]interface{}{
 		"output_path":  outputFile.Name(),
@@ -195,18 +283,44 @@ func (w *Worker) processMediaJob(job *queue.Job) {
 		"input_path":   processingJob.InputPath,
 		"media_kind":   processingJob.MediaKind,
 	}
+	if storageKey != "" {
+		result[domain.ResultStorageKey] = storageKey
+	}
 
 	// Add metadata if available
 	if processingJob.Metadata != nil {
 		result["metadata"] = processingJob.Metadata
 	}
 
+	if imageProcessor, ok := processor.(*media.ImageProcessor); ok && imageProcessor.TargetSizeResult != nil {
+		result["target_size"] = imageProcessor.TargetSizeResult.Metadata()
+	}
+
 	// Complete job
 	if err := w.queue.CompleteJob(context.Background(), job.ID, result); err != nil {
 		log.Printf("Failed to complete job %s: %v", job.ID, err)
 	}
 }
 
+// storeOutput uploads a job's output file to the storage and returns its
+// key. Without a storage nothing is stored and the key is empty.
+func (w *Worker) storeOutput(jobID, outputPath string) (string, error) {
+	if w.storage == nil {
+		return "", nil
+	}
+	file, err := os.Open(outputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open output: %w", err)
+	}
+	defer file.Close()
+
+	key := path.Join("results", jobID+filepath.Ext(outputPath))
+	if err := w.storage.Store(w.ctx, key, file); err != nil {
+		return "", fmt.Errorf("failed to store output: %w", err)
+	}
+	return key, nil
+}
+
 func (w *Worker) processOCRJob(job *queue.Job) {
 	// TODO: Implement OCR processing
 	// This will be implemented when we add OCR functionality
@@ -227,6 +341,8 @@ func (w *Worker) processTextExtractionJob(job *queue.Job) {
 		StartPage *int                   `json:"start_page,omitempty"`
 		EndPage   *int                   `json:"end_page,omitempty"`
 		Metadata  map[string]interface{} `json:"metadata,omitempty"`
+		Password  string                 `json:"password,omitempty"`
+		Mode      string                 `json:"mode,omitempty"` // "text" (default) or "auto"
 	}
 
 	payloadBytes, err := json.Marshal(job.Payload)
@@ -240,11 +356,22 @@ func (w *Worker) processTextExtractionJob(job *queue.Job) {
 		return
 	}
 
+	mode, err := textextractor.ParseExtractionMode(textExtractionJob.Mode)
+	if err != nil {
+		w.queue.FailJob(context.Background(), job.ID, err.Error())
+		return
+	}
+
+	extractor := w.textExtractor.WithMode(mode)
+	if textExtractionJob.Password != "" {
+		extractor = extractor.WithPassword(textExtractionJob.Password)
+	}
+
 	var result map[string]interface{}
 
 	switch textExtractionJob.JobType {
 	case "full":
-		extractionResult, err := w.textExtractor.ExtractFromFile(textExtractionJob.InputPath)
+		extractionResult, err := extractor.ExtractFromFile(textExtractionJob.InputPath)
 		if err != nil {
 			w.queue.FailJob(context.Background(), job.ID, fmt.Sprintf("Text extraction failed: %v", err))
 			return
@@ -255,7 +382,7 @@ func (w *Worker) processTextExtractionJob(job *queue.Job) {
 		}
 
 	case "pages":
-		extractionResults, err := w.textExtractor.BatchExtractPDFPages(textExtractionJob.InputPath)
+		extractionResults, err := extractor.BatchExtractPDFPages(textExtractionJob.InputPath)
 		if err != nil {
 			w.queue.FailJob(context.Background(), job.ID, fmt.Sprintf("PDF pages extraction failed: %v", err))
 			return
@@ -271,7 +398,7 @@ func (w *Worker) processTextExtractionJob(job *queue.Job) {
 			w.queue.FailJob(context.Background(), job.ID, "Range extraction requires start_page and end_page")
 			return
 		}
-		extractionResult, err := w.textExtractor.ExtractByPages(
+		extractionResult, err := extractor.ExtractByPages(
 			textExtractionJob.InputPath,
 			*textExtractionJob.StartPage,
 			*textExtractionJob.EndPage,
@@ -3,10 +3,19 @@ package worker
 import (
 	"context"
 	"documents-worker/config"
+	"documents-worker/internal/core/domain"
+	"documents-worker/internal/core/ports"
 	"documents-worker/queue"
+	"documents-worker/types"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -247,3 +256,121 @@ func BenchmarkWorkerManagerStats(b *testing.B) {
 		_ = stats
 	}
 }
+
+// Test panic recovery around job processing
+func TestRunSafely(t *testing.T) {
+	panicErr := runSafely(func() {
+		panic("processor exploded")
+	})
+	require.NotNil(t, panicErr)
+	assert.Equal(t, "processor exploded", panicErr.Value)
+	assert.Contains(t, panicErr.Error(), "processor exploded")
+	assert.NotEmpty(t, panicErr.Stack)
+
+	called := false
+	assert.Nil(t, runSafely(func() { called = true }))
+	assert.True(t, called)
+}
+
+// Jobs of an unknown type are failed, not reported as completed
+func TestDispatchUnknownJobType(t *testing.T) {
+	cfg := getTestWorkerConfig()
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	redisQueue := queue.NewRedisQueueWithClient(client, &cfg.Worker)
+	defer redisQueue.Close()
+
+	w := NewWorker(redisQueue, cfg)
+	assert.False(t, w.dispatchJob(&queue.Job{ID: "job-1", Type: "unsupported"}))
+}
+
+// memoryStorage keeps stored files in a map
+type memoryStorage struct {
+	ports.FileStorage
+	mu    sync.Mutex
+	files map[string]string
+}
+
+func (s *memoryStorage) Store(ctx context.Context, path string, data io.Reader) error {
+	content, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path] = string(content)
+	return nil
+}
+
+// useFakeVips puts a vips on PATH that writes a fixed output
+func useFakeVips(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tools are shell scripts")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\nout=\"$3\"\nprintf 'vips-output' > \"${out%%[*}\"\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "vips"), []byte(script), 0755))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	input := filepath.Join(dir, "input.png")
+	require.NoError(t, os.WriteFile(input, []byte("png"), 0644))
+	return input
+}
+
+func TestStoreOutput(t *testing.T) {
+	cfg := getTestWorkerConfig()
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	redisQueue := queue.NewRedisQueueWithClient(client, &cfg.Worker)
+	defer redisQueue.Close()
+
+	output := filepath.Join(t.TempDir(), "processed-1.webp")
+	require.NoError(t, os.WriteFile(output, []byte("webp output"), 0644))
+
+	key, err := NewWorker(redisQueue, cfg).storeOutput("job-1", output)
+	require.NoError(t, err)
+	assert.Empty(t, key, "nothing is stored without a storage")
+
+	storage := &memoryStorage{files: map[string]string{}}
+	key, err = NewWorker(redisQueue, cfg).WithStorage(storage).storeOutput("job-1", output)
+	require.NoError(t, err)
+	assert.Equal(t, "results/job-1.webp", key)
+	assert.Equal(t, "webp output", storage.files[key])
+}
+
+// A completed media job's output is kept in storage under the key its
+// result records
+func TestMediaJobOutputIsStored(t *testing.T) {
+	input := useFakeVips(t)
+	cfg := getTestWorkerConfig()
+	redisQueue, err := queue.NewRedisQueue(&cfg.Redis, &cfg.Worker)
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	defer redisQueue.Close()
+
+	storage := &memoryStorage{files: map[string]string{}}
+	w := NewWorker(redisQueue, cfg).WithStorage(storage)
+
+	format := "webp"
+	submitted, err := SubmitMediaJob(redisQueue, input, types.ImageKind, types.MediaSearch{}, &format, true, nil)
+	require.NoError(t, err)
+
+	// Other tests share the queue, so dequeue until this job comes up
+	ctx := context.Background()
+	for {
+		job, err := redisQueue.Dequeue(ctx)
+		require.NoError(t, err)
+		if job.ID == submitted.ID {
+			w.processJob(job)
+			break
+		}
+	}
+
+	completed, err := redisQueue.GetJob(ctx, submitted.ID)
+	require.NoError(t, err)
+	require.Equal(t, queue.StatusCompleted, completed.Status, completed.Error)
+	key, ok := completed.Result[domain.ResultStorageKey].(string)
+	require.True(t, ok, "the result records the storage key")
+	assert.Equal(t, "results/"+submitted.ID+".webp", key)
+	assert.Equal(t, "vips-output", storage.files[key])
+}